@@ -17,6 +17,12 @@ GIT_DIRTY  = $(shell test -n "`git status --porcelain`" && echo "dirty" || echo
 
 GOARCH ?= amd64
 
+# FIPS=1 builds the agent with the FIPS 140 validated BoringCrypto backend, see internal/fips.
+ifeq ($(FIPS),1)
+GO_BUILD_ENV  = CGO_ENABLED=1 GOEXPERIMENT=boringcrypto
+GO_BUILD_TAGS = -tags fips
+endif
+
 LDFLAGS += -X main.buildVersion=$(VERSION)
 LDFLAGS += -X main.gitCommit=${GIT_COMMIT}
 LDFLAGS += -X main.buildDate=${BUILD_DATE}
@@ -128,7 +134,7 @@ dist-for-os:
 	@for main_package in $(MAIN_PACKAGES);\
 	do\
 		echo "[dist] Creating executable: `basename $$main_package`";\
-		$(GO_BIN) build -gcflags '-N -l' -ldflags '$(LDFLAGS)' -o $(DIST_DIR)/$(GOOS)-`basename $$main_package`_$(GOOS)_$(GOARCH)/`basename $$main_package` $$main_package || exit 1 ;\
+		$(GO_BUILD_ENV) $(GO_BIN) build $(GO_BUILD_TAGS) -gcflags '-N -l' -ldflags '$(LDFLAGS)' -o $(DIST_DIR)/$(GOOS)-`basename $$main_package`_$(GOOS)_$(GOARCH)/`basename $$main_package` $$main_package || exit 1 ;\
 	done
 
 .PHONY: debug-for-os
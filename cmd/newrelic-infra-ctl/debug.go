@@ -0,0 +1,71 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/debug"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/ctl/sender"
+	"github.com/newrelic/infrastructure-agent/pkg/ipc"
+	"github.com/sirupsen/logrus"
+)
+
+// extra time given to the agent on top of the requested sampling window
+const debugRequestTimeout = 30 * time.Second
+
+// runDebug handles "debug stacks" and "debug mutex" subcommands, storing the agent response into a file.
+func runDebug(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing debug subcommand, expected 'stacks' or 'mutex'")
+	}
+
+	var command, ext string
+	switch args[0] {
+	case "stacks":
+		command, ext = ipc.DebugStacks, "txt"
+	case "mutex":
+		command, ext = ipc.DebugMutex, "pprof"
+	default:
+		return fmt.Errorf("unknown debug subcommand: %s", args[0])
+	}
+
+	fs := flag.NewFlagSet("debug "+args[0], flag.ExitOnError)
+	socket := fs.String("socket", config.DefaultControlSocket, "Agent control socket address")
+	output := fs.String("output", "", "Output file [Optional] (defaults to a timestamped file in the current directory)")
+	duration := fs.Duration("duration", debug.DefaultMutexProfileDuration, "Mutex contention sampling window (mutex only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		*output = fmt.Sprintf("newrelic-infra-%s-%s.%s", args[0], time.Now().Format("20060102-150405"), ext)
+	}
+
+	var reqArgs []string
+	timeout := debugRequestTimeout
+	if command == ipc.DebugMutex {
+		reqArgs = append(reqArgs, duration.String())
+		timeout += *duration
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	payload, err := sender.NewControlClient(*socket).Request(ctx, command, reqArgs...)
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(*output, payload, 0600); err != nil {
+		return fmt.Errorf("cannot write debug output: %w", err)
+	}
+
+	logrus.Infof("Debug %s written to '%s'", args[0], *output)
+	return nil
+}
@@ -0,0 +1,23 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/newrelic/infrastructure-agent/cmd/newrelic-infra/diagnose"
+)
+
+// runDiagnose handles the "diagnose" subcommand, also available as the agent -diagnose flag.
+func runDiagnose(args []string) error {
+	fs := flag.NewFlagSet("diagnose", flag.ExitOnError)
+	configFile := fs.String("config", "", "Agent configuration file [Optional] (defaults to the agent default locations)")
+	output := fs.String("output", "", "Support bundle file [Optional] (defaults to a timestamped zip file in the current directory)")
+	skipNetwork := fs.Bool("skip-network", false, "Skip the network connectivity checks [Optional]")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return diagnose.Run(os.Stdout, *configFile, *output, !*skipNetwork)
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/ctl/sender"
+	"github.com/newrelic/infrastructure-agent/pkg/ipc"
+)
+
+const maintenanceRequestTimeout = 30 * time.Second
+
+// runMaintenance handles the "maintenance start", "maintenance stop" and "maintenance status" subcommands,
+// printing the resulting maintenance window.
+func runMaintenance(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing maintenance subcommand, expected 'start', 'stop' or 'status'")
+	}
+
+	var command string
+	switch args[0] {
+	case "start":
+		command = ipc.MaintenanceStart
+	case "stop":
+		command = ipc.MaintenanceStop
+	case "status":
+		command = ipc.MaintenanceStatus
+	default:
+		return fmt.Errorf("unknown maintenance subcommand: %s", args[0])
+	}
+
+	fs := flag.NewFlagSet("maintenance "+args[0], flag.ExitOnError)
+	socket := fs.String("socket", config.DefaultControlSocket, "Agent control socket address")
+	duration := fs.Duration("duration", time.Hour, "Maintenance window duration (start only)")
+	reason := fs.String("reason", "", "Maintenance reason, logged by the agent [Optional] (start only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var reqArgs []string
+	if command == ipc.MaintenanceStart {
+		reqArgs = append(reqArgs, duration.String())
+		// the request is a single line
+		reqArgs = append(reqArgs, strings.Fields(*reason)...)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, maintenanceRequestTimeout)
+	defer cancel()
+
+	payload, err := sender.NewControlClient(*socket).Request(ctx, command, reqArgs...)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(payload)
+	return err
+}
@@ -77,6 +77,41 @@ func main() {
 		cancel()
 	}()
 
+	if flag.Arg(0) == "debug" {
+		if err := runDebug(ctx, flag.Args()[1:]); err != nil {
+			logrus.WithError(err).Fatal("Failed to retrieve debug information from the NRI Agent.")
+		}
+		return
+	}
+
+	if flag.Arg(0) == "diagnose" {
+		if err := runDiagnose(flag.Args()[1:]); err != nil {
+			logrus.WithError(err).Fatal("Failed to diagnose the NRI Agent.")
+		}
+		return
+	}
+
+	if flag.Arg(0) == "maintenance" {
+		if err := runMaintenance(ctx, flag.Args()[1:]); err != nil {
+			logrus.WithError(err).Fatal("Failed to manage the NRI Agent maintenance mode.")
+		}
+		return
+	}
+
+	if flag.Arg(0) == "status" {
+		if err := runStatus(ctx, flag.Args()[1:]); err != nil {
+			logrus.WithError(err).Fatal("Failed to retrieve the NRI Agent status.")
+		}
+		return
+	}
+
+	if flag.Arg(0) == "replay" {
+		if err := runReplay(ctx, flag.Args()[1:]); err != nil {
+			logrus.WithError(err).Fatal("Failed to replay the offline archive.")
+		}
+		return
+	}
+
 	client, err := getClient()
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to initialize the notification client.")
@@ -0,0 +1,68 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/offline"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// runReplay handles the "replay" subcommand. It uploads the archive files exported by an air-gapped agent with the
+// offline_export_dir option, with the license key and connection settings of the configuration of this host.
+func runReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configFile := fs.String("config", "", "Agent configuration file with the license key and connection settings [Optional] (defaults to the agent default locations)")
+	dir := fs.String("dir", "", "Directory with the archive files exported by the agent")
+	publicKey := fs.String("public-key", "", "PEM file of the ed25519 public key verifying the archive signatures")
+	allowUnsigned := fs.Bool("allow-unsigned", false, "Replay the archive without verifying its signatures, when -public-key is not set [Optional]")
+	remove := fs.Bool("remove", false, "Remove the archive files once replayed, instead of appending the .replayed extension [Optional]")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return errors.New("missing -dir with the archive files")
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		return fmt.Errorf("cannot load configuration: %w", err)
+	}
+	if cfg.License == "" {
+		return errors.New("missing license key in the configuration")
+	}
+	// the archived requests are sent, even if this host exports its own data
+	cfg.OfflineExportDir = ""
+
+	replayer := &offline.Replayer{
+		Do:     backendhttp.GetHttpClient(backendhttp.ClientTimeout, backendhttp.BuildTransport(cfg, backendhttp.ClientTimeout)).Do,
+		Hosts:  backendhttp.EndpointHosts(cfg),
+		Header: http.Header{backendhttp.LicenseHeader: []string{cfg.License}},
+		Remove: *remove,
+	}
+	switch {
+	case *publicKey != "":
+		if replayer.PublicKey, err = offline.LoadPublicKey(*publicKey); err != nil {
+			return err
+		}
+	case *allowUnsigned:
+		replayer.AllowUnsigned = true
+		logrus.Warn("Replaying the archive without verifying its signatures, set -public-key to verify them.")
+	default:
+		return errors.New("missing -public-key verifying the archive signatures, set -allow-unsigned to replay it without verifying them")
+	}
+
+	stats, err := replayer.Replay(ctx, *dir)
+	logrus.WithFields(logrus.Fields{
+		"files":    stats.Files,
+		"requests": stats.Requests,
+	}).Info("Archive replayed.")
+	return err
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/ipc"
+)
+
+const statusRequestTimeout = 30 * time.Second
+
+// runStatus handles the "status [report]" subcommand, printing the response of the status API served on the agent
+// status_server_socket, and failing when it's not successful. The report defaults to the full status report, ie:
+// "health" requests /v1/status/health.
+func runStatus(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	socket := fs.String("socket", "", "Agent status_server_socket address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *socket == "" {
+		return fmt.Errorf("missing -socket, the status_server_socket of the agent")
+	}
+
+	path := "/v1/status"
+	if report := strings.Trim(fs.Arg(0), "/"); report != "" {
+		path += "/" + strings.TrimPrefix(report, "v1/status/")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, statusRequestTimeout)
+	defer cancel()
+
+	client := http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return ipc.Dial(ctx, *socket)
+		},
+	}}
+	// the host is ignored by the dialer
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot request the agent status at %s: %w", *socket, err)
+	}
+	defer resp.Body.Close()
+
+	if _, err = io.Copy(os.Stdout, resp.Body); err != nil {
+		return err
+	}
+	// the body is printed anyway, ie: the health report of an unhealthy agent
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status API response: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package configcheck validates the agent and integrations configuration files, locating the problems found in
+// them so typos and wrong values are not silently ignored.
+package configcheck
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/files"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/config/envvar"
+	config_loader "github.com/newrelic/infrastructure-agent/pkg/config/loader"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/legacy"
+	integrationsConfig "github.com/newrelic/infrastructure-agent/pkg/integrations/v4/config"
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+var (
+	// yaml.v2 errors, ie: "yaml: line 3: mapping values are not allowed in this context" or
+	// "line 5: cannot unmarshal !!str `abc` into int".
+	yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	unknownField  = regexp.MustCompile(`^field (\S+) not found in type (\S+)$`)
+)
+
+// ErrNoConfigFile is returned when there is no agent configuration file to check.
+var ErrNoConfigFile = errors.New("no configuration file found")
+
+// Problem is an issue found in a configuration file, at the given line when it's known.
+type Problem struct {
+	File string
+	Line int
+	// Key is the path of the option, ie: log.level or integrations[0].interval.
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	location := p.File
+	if p.Line > 0 {
+		location = fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	if p.Key == "" {
+		return fmt.Sprintf("%s: %s", location, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, p.Key, p.Message)
+}
+
+// legacyIntegrationConfig is the format of the v3 integrations configuration files.
+type legacyIntegrationConfig struct {
+	Databind                     databind.YAMLConfig `yaml:",inline"`
+	legacy.PluginInstanceWrapper `yaml:",inline"`
+}
+
+// Check validates the agent configuration file, the first of the default ones when it's empty, and the
+// integrations configuration files found in the integrations directories it defines.
+func Check(configFile string) ([]Problem, error) {
+	file, err := agentConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	problems, err := AgentConfig(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// the integrations directories are only known once the configuration is normalized
+	cfg, err := config.LoadConfig(file)
+	if err != nil {
+		return problems, nil
+	}
+	for _, dir := range cfg.PluginInstanceDirs {
+		dirProblems, err := IntegrationConfigs(dir)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, dirProblems...)
+	}
+	return problems, nil
+}
+
+func agentConfigFile(configFile string) (string, error) {
+	if configFile != "" {
+		if _, err := os.Stat(configFile); err != nil {
+			return "", err
+		}
+		return configFile, nil
+	}
+	if file := config.LoadedConfigFile(""); file != "" {
+		return file, nil
+	}
+	return "", ErrNoConfigFile
+}
+
+// AgentConfig validates the agent configuration file: YAML syntax, unknown options, values not matching the type
+// of their option, deprecated options, integers out of range and invalid durations.
+func AgentConfig(file string) ([]Problem, error) {
+	content, err := readConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	locations := locate(content)
+
+	var problems []Problem
+	for _, p := range unmarshalStrict(file, content, config.NewConfig(), locations) {
+		// unknown top level options are reported by the registry, suggesting the closest one
+		if p.Message == unknownOptionMessage && isTopLevel(p.Key) {
+			continue
+		}
+		problems = append(problems, p)
+	}
+
+	// the values matching their type are still loaded, as the type errors are already reported
+	cfg := config.NewConfig()
+	var typeErr *yaml.TypeError
+	if err = yaml.Unmarshal(content, cfg); err != nil && !errors.As(err, &typeErr) {
+		return problems, nil
+	}
+	metadata := config_loader.YAMLMetadata{}
+	for key := range locations.lines {
+		if isTopLevel(key) {
+			metadata[key] = true
+		}
+	}
+
+	registry, err := config.NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range registry.Validate(cfg, metadata) {
+		problems = append(problems, Problem{File: file, Line: locations.lines[w.Key], Key: w.Key, Message: w.Message})
+	}
+	return problems, nil
+}
+
+// IntegrationConfigs validates the integrations configuration files in the directory, both the current and the
+// v3 legacy formats: YAML syntax, unknown keys, values not matching the type of their key, invalid intervals and
+// incomplete entries. A missing directory has no problems.
+func IntegrationConfigs(dir string) ([]Problem, error) {
+	yamlFiles, err := files.AllYAMLs(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	for _, f := range yamlFiles {
+		fileProblems, err := IntegrationConfig(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, fileProblems...)
+	}
+	return problems, nil
+}
+
+// IntegrationConfig validates an integrations configuration file.
+func IntegrationConfig(file string) ([]Problem, error) {
+	content, err := readConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	locations := locate(content)
+
+	var keys map[string]interface{}
+	if err = yaml.Unmarshal(content, &keys); err != nil {
+		return unmarshalStrict(file, content, &keys, locations), nil
+	}
+	if _, ok := keys[integrationsConfig.LegacyInstancesField]; ok {
+		return unmarshalStrict(file, content, &legacyIntegrationConfig{}, locations), nil
+	}
+	if _, ok := keys["integrations"]; !ok {
+		return []Problem{{File: file, Message: "missing 'integrations' field, the file is ignored"}}, nil
+	}
+
+	var cfg integrationsConfig.YAML
+	problems := unmarshalStrict(file, content, &cfg, locations)
+	for i, entry := range cfg.Integrations {
+		path := fmt.Sprintf("integrations[%d]", i)
+		if err := entry.Sanitize(); err != nil {
+			problems = append(problems, Problem{File: file, Line: locations.lines[path], Key: path, Message: err.Error()})
+		}
+		if msg := checkInterval(entry.Interval); msg != "" {
+			key := path + ".interval"
+			problems = append(problems, Problem{File: file, Line: locations.lines[key], Key: key, Message: msg})
+		}
+	}
+	return problems, nil
+}
+
+// checkInterval returns why the integration interval is invalid, empty when it's valid. As for running the
+// integrations, 0 disables the interval and values with discovery variables are resolved at runtime.
+func checkInterval(interval string) string {
+	if interval == "" || interval == "0" || strings.Contains(interval, "${") {
+		return ""
+	}
+	if _, err := time.ParseDuration(interval); err != nil {
+		return fmt.Sprintf("invalid duration %q, use a number and a unit, ie: 30s, 5m or 1h. The default interval would be used", interval)
+	}
+	return ""
+}
+
+// readConfig reads the file expanding the environment variables, as they are when loaded by the agent.
+func readConfig(file string) ([]byte, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return envvar.ExpandInContent(content)
+}
+
+const unknownOptionMessage = "unknown option, it will be ignored"
+
+// unmarshalStrict unmarshals the content into the object, returning the syntax errors, unknown keys and values not
+// matching the type of their key.
+func unmarshalStrict(file string, content []byte, object interface{}, locations locations) []Problem {
+	err := yaml.UnmarshalStrict(content, object)
+	if err == nil {
+		return nil
+	}
+
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	problems := make([]Problem, 0, len(messages))
+	for _, msg := range messages {
+		p := Problem{File: file, Message: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+			p.Line, _ = strconv.Atoi(m[1])
+			p.Key, p.Message = locations.keys[p.Line], m[2]
+		}
+		if unknownField.MatchString(p.Message) {
+			p.Message = unknownOptionMessage
+		}
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+func isTopLevel(key string) bool {
+	return !strings.ContainsAny(key, ".[")
+}
+
+// locations maps the keys of a YAML document, by their path, to the line they're defined at, and the other way
+// around to the innermost key of each line.
+type locations struct {
+	lines map[string]int
+	keys  map[int]string
+}
+
+func locate(content []byte) locations {
+	l := locations{lines: map[string]int{}, keys: map[int]string{}}
+
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(content, &doc); err != nil || len(doc.Content) == 0 {
+		return l
+	}
+	l.walk(doc.Content[0], "")
+	return l
+}
+
+func (l locations) walk(node *yaml3.Node, path string) {
+	switch node.Kind {
+	case yaml3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			l.add(key, node.Content[i].Line)
+			l.walk(node.Content[i+1], key)
+		}
+	case yaml3.SequenceNode:
+		for i, item := range node.Content {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			l.add(itemPath, item.Line)
+			l.walk(item, itemPath)
+		}
+	}
+}
+
+func (l locations) add(path string, line int) {
+	if _, ok := l.lines[path]; !ok {
+		l.lines[path] = line
+	}
+	l.keys[line] = path
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package configcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestAgentConfig(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "newrelic-infra.yml", `license_key: abc123
+licence_key: abc123
+metrics_system_sample_rate: fast
+log:
+  level: info
+  levl: debug
+startup_connection_timeout: 10
+payload_compression_level: 12
+`)
+
+	problems, err := AgentConfig(file)
+	require.NoError(t, err)
+
+	var lines []string
+	for _, p := range problems {
+		lines = append(lines, p.String())
+	}
+	assert.ElementsMatch(t, []string{
+		file + ":3: metrics_system_sample_rate: cannot unmarshal !!str `fast` into int",
+		file + ":6: log.levl: unknown option, it will be ignored",
+		file + ":2: licence_key: unknown configuration option, it will be ignored, did you mean license_key?",
+		file + `:7: startup_connection_timeout: invalid duration "10", use a number and a unit, ie: 30s, 5m or 1h`,
+		file + ":8: payload_compression_level: value 12 out of range [0, 9]",
+	}, lines)
+}
+
+func TestAgentConfig_SyntaxError(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "newrelic-infra.yml", "license_key: abc123\n  log: info\n")
+
+	problems, err := AgentConfig(file)
+	require.NoError(t, err)
+
+	require.Len(t, problems, 1)
+	assert.Equal(t, 2, problems[0].Line)
+	assert.Equal(t, "mapping values are not allowed in this context", problems[0].Message)
+}
+
+func TestAgentConfig_Valid(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "newrelic-infra.yml", `license_key: abc123
+custom_attributes:
+  team: ops
+log:
+  level: debug
+startup_connection_timeout: 30s
+`)
+
+	problems, err := AgentConfig(file)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestIntegrationConfigs(t *testing.T) {
+	dir := t.TempDir()
+	v4 := writeFile(t, dir, "nri-redis.yml", `integrations:
+  - name: nri-redis
+    interval: 15
+    env:
+      HOSTNAME: localhost
+  - name: nri-mysql
+    interval: ${discovery.interval}
+    timeot: 20s
+  - exec: /usr/bin/script
+    interval: 30s
+`)
+	legacy := writeFile(t, dir, "nginx-config.yaml", `integration_name: com.newrelic.nginx
+instances:
+  - name: nginx
+    command: metrics
+    argumnts:
+      status_url: http://127.0.0.1/status
+`)
+	writeFile(t, dir, "valid.yml", `integrations:
+  - name: nri-flex
+    interval: 1m
+    timeout: 30s
+    config:
+      name: custom
+`)
+	empty := writeFile(t, dir, "empty.yml", "name: nri-redis\n")
+	writeFile(t, dir, "README.md", "integrations: [")
+
+	problems, err := IntegrationConfigs(dir)
+	require.NoError(t, err)
+
+	var lines []string
+	for _, p := range problems {
+		lines = append(lines, p.String())
+	}
+	assert.ElementsMatch(t, []string{
+		empty + ": missing 'integrations' field, the file is ignored",
+		legacy + ":5: instances[0].argumnts: unknown option, it will be ignored",
+		v4 + ":8: integrations[1].timeot: unknown option, it will be ignored",
+		v4 + `:3: integrations[0].interval: invalid duration "15", use a number and a unit, ie: 30s, 5m or 1h. The default interval would be used`,
+		v4 + ":9: integrations[2]: integration entry requires a non-empty 'name' field",
+	}, lines)
+}
+
+func TestIntegrationConfigs_MissingDir(t *testing.T) {
+	problems, err := IntegrationConfigs(filepath.Join(t.TempDir(), "integrations.d"))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "integrations.d"), 0o755))
+	writeFile(t, filepath.Join(dir, "integrations.d"), "nri-redis.yml", "integrations:\n  - name: nri-redis\n    intervl: 15s\n")
+	file := writeFile(t, dir, "newrelic-infra.yml", "license_key: abc123\nplugin_dir: "+filepath.Join(dir, "integrations.d")+"\n")
+
+	problems, err := Check(file)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "integrations[0].intervl", problems[0].Key)
+
+	_, err = Check(filepath.Join(dir, "missing.yml"))
+	assert.Error(t, err)
+}
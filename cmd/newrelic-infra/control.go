@@ -0,0 +1,68 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	agentDebug "github.com/newrelic/infrastructure-agent/internal/agent/debug"
+	"github.com/newrelic/infrastructure-agent/internal/agent/maintenance"
+	"github.com/newrelic/infrastructure-agent/pkg/ctl"
+	"github.com/newrelic/infrastructure-agent/pkg/ipc"
+)
+
+// newControlServer creates the control server serving newrelic-infra-ctl debug and maintenance commands.
+func newControlServer(address string, window *maintenance.Window) *ctl.ControlServer {
+	srv := ctl.NewControlServer(address)
+
+	srv.RegisterHandler(ipc.DebugStacks, func(_ context.Context, w io.Writer, _ []string) error {
+		return agentDebug.WriteStacks(w)
+	})
+
+	srv.RegisterHandler(ipc.DebugMutex, func(ctx context.Context, w io.Writer, args []string) error {
+		duration := agentDebug.DefaultMutexProfileDuration
+		if len(args) > 0 {
+			var err error
+			if duration, err = time.ParseDuration(args[0]); err != nil || duration <= 0 {
+				return fmt.Errorf("invalid sampling duration: %s", args[0])
+			}
+		}
+		return agentDebug.WriteMutexProfile(ctx, w, duration)
+	})
+
+	srv.RegisterHandler(ipc.MaintenanceStart, func(_ context.Context, w io.Writer, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("missing maintenance duration")
+		}
+		duration, err := time.ParseDuration(args[0])
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("invalid maintenance duration: %s", args[0])
+		}
+		if err = window.Start(duration, strings.Join(args[1:], " ")); err != nil {
+			return err
+		}
+		return writeMaintenanceStatus(w, window)
+	})
+
+	srv.RegisterHandler(ipc.MaintenanceStop, func(_ context.Context, w io.Writer, _ []string) error {
+		window.Stop()
+		return writeMaintenanceStatus(w, window)
+	})
+
+	srv.RegisterHandler(ipc.MaintenanceStatus, func(_ context.Context, w io.Writer, _ []string) error {
+		return writeMaintenanceStatus(w, window)
+	})
+
+	return srv
+}
+
+func writeMaintenanceStatus(w io.Writer, window *maintenance.Window) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(window.Status())
+}
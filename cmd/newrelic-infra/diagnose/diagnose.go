@@ -0,0 +1,334 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package diagnose checks the agent configuration, license key, directories permissions and network connectivity,
+// storing the results into a support bundle with sensitive data redacted.
+package diagnose
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/cmd/newrelic-infra/dnschecks"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/license"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"gopkg.in/yaml.v2"
+)
+
+// licenseKeyLength is the length of New Relic license and ingest keys.
+const licenseKeyLength = 40
+
+// sensitiveKeyRegex matches the configuration options whose values are removed from the support bundle.
+var sensitiveKeyRegex = regexp.MustCompile(`(?i)(license|password|passwd|secret|token|key$|credentials)`)
+
+// diagnostics gathers the results of the checks.
+type diagnostics struct {
+	Timestamp time.Time         `json:"timestamp"`
+	OS        string            `json:"os"`
+	Arch      string            `json:"arch"`
+	Config    configCheck       `json:"config"`
+	License   licenseCheck      `json:"license"`
+	Paths     []pathCheck       `json:"paths,omitempty"`
+	Network   *dnschecks.Report `json:"network,omitempty"`
+	Problems  []string          `json:"problems,omitempty"`
+	cfg       *config.Config
+}
+
+type configCheck struct {
+	File     string   `json:"file,omitempty"`
+	Valid    bool     `json:"valid"`
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type licenseCheck struct {
+	Valid  bool   `json:"valid"`
+	Region string `json:"region,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// pathCheck reports whether the agent can use a file or directory it needs.
+type pathCheck struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Exists   bool   `json:"exists"`
+	Mode     string `json:"mode,omitempty"`
+	Writable bool   `json:"writable,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Run runs the checks for the agent configuration file, the default one when empty, storing the results into the
+// output support bundle, a timestamped zip file in the current directory when empty. The problems found are
+// reported to w. Network checks are skipped unless network is set.
+func Run(w io.Writer, configFile, output string, network bool) error {
+	if output == "" {
+		output = fmt.Sprintf("newrelic-infra-diagnostics-%s.zip", time.Now().Format("20060102-150405"))
+	}
+
+	d := diagnose(configFile, network)
+	if err := writeBundle(output, d); err != nil {
+		return fmt.Errorf("cannot write support bundle: %w", err)
+	}
+
+	for _, p := range d.Problems {
+		fmt.Fprintln(w, p)
+	}
+	if len(d.Problems) == 0 {
+		fmt.Fprintln(w, "No problems found")
+	}
+	fmt.Fprintf(w, "Support bundle written to '%s'\n", output)
+	return nil
+}
+
+// diagnose runs all the checks.
+func diagnose(configFile string, network bool) *diagnostics {
+	d := &diagnostics{
+		Timestamp: time.Now(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+
+	d.checkConfig(configFile)
+	d.checkLicense()
+	if d.Config.Valid {
+		d.checkPaths()
+		if network {
+			d.checkNetwork()
+		}
+	}
+	return d
+}
+
+func (d *diagnostics) problem(format string, args ...interface{}) {
+	d.Problems = append(d.Problems, fmt.Sprintf(format, args...))
+}
+
+func (d *diagnostics) checkConfig(configFile string) {
+	d.Config.File = config.LoadedConfigFile(configFile)
+
+	cfg, err := config.LoadConfig(configFile)
+	d.cfg = cfg
+	if cfg != nil {
+		for _, w := range cfg.Warnings() {
+			d.Config.Warnings = append(d.Config.Warnings, w.String())
+		}
+	}
+	if err != nil {
+		// invalid license errors include the key
+		d.Config.Error = redactLicense(err.Error(), cfg)
+		d.problem("Invalid configuration: %s", d.Config.Error)
+		return
+	}
+	d.Config.Valid = true
+	for _, w := range d.Config.Warnings {
+		d.problem("Configuration warning: %s", w)
+	}
+}
+
+func (d *diagnostics) checkLicense() {
+	key := ""
+	if d.cfg != nil {
+		key = strings.TrimSpace(d.cfg.License)
+	}
+	switch {
+	case key == "":
+		d.License.Error = "no license key, add it to the config file or NRIA_LICENSE_KEY environment variable"
+	case !license.IsValid(key):
+		d.License.Error = "license key contains characters other than letters and digits"
+	case len(key) != licenseKeyLength:
+		d.License.Error = fmt.Sprintf("license key is %d characters long, expected %d", len(key), licenseKeyLength)
+	}
+	if d.License.Error != "" {
+		d.problem("Invalid license key: %s", d.License.Error)
+		return
+	}
+	d.License.Valid = true
+	d.License.Region = license.GetRegion(key)
+}
+
+func (d *diagnostics) checkPaths() {
+	c := d.cfg
+	d.Paths = append(d.Paths, checkPath("agent_dir", c.AgentDir, true))
+	if c.Log.File != "" {
+		d.Paths = append(d.Paths, checkPath("log_file directory", filepath.Dir(c.GetLogFile()), true))
+	}
+	d.Paths = append(d.Paths, checkPath("plugin_dir", c.PluginDir, false))
+	if c.LoggingConfigsDir != "" {
+		d.Paths = append(d.Paths, checkPath("logging_configs_dir", c.LoggingConfigsDir, false))
+	}
+	if c.AgentTempDir != "" {
+		d.Paths = append(d.Paths, checkPath("agent temporary directory", c.AgentTempDir, true))
+	}
+
+	for _, p := range d.Paths {
+		if p.Error != "" {
+			d.problem("Cannot use %s %s: %s", p.Name, p.Path, p.Error)
+		}
+	}
+}
+
+// checkPath verifies the directory exists, and the agent can write into it when required.
+func checkPath(name, path string, needsWrite bool) pathCheck {
+	p := pathCheck{Name: name, Path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	p.Exists = true
+	p.Mode = info.Mode().String()
+	if !info.IsDir() {
+		p.Error = "not a directory"
+		return p
+	}
+	if _, err = os.ReadDir(path); err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	if !needsWrite {
+		return p
+	}
+
+	f, err := os.CreateTemp(path, ".nria-diagnose-")
+	if err != nil {
+		p.Error = fmt.Sprintf("not writable: %s", err)
+		return p
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	p.Writable = true
+	return p
+}
+
+func (d *diagnostics) checkNetwork() {
+	c := d.cfg
+	timeout, err := time.ParseDuration(c.StartupConnectionTimeout)
+	if err != nil {
+		d.problem("Cannot run network checks: %s", err)
+		return
+	}
+
+	transport := backendhttp.BuildTransport(c, timeout)
+	report, err := dnschecks.RunChecks(c.CollectorURL, c.StartupConnectionTimeout, transport, log.WithComponent("Diagnose"),
+		dnschecks.WithProxy(c.Proxy, c.IgnoreSystemProxy, c.ProxyValidateCerts),
+		dnschecks.WithCABundle(c.CABundleFile, c.CABundleDir),
+		dnschecks.WithResolvers(c.NetworkChecksResolvers),
+		dnschecks.WithTraceroute(c.NetworkChecksTraceroute),
+		dnschecks.WithBenchmark(c.NetworkChecksBenchmarkRequests))
+	if err != nil {
+		d.problem("Cannot run network checks: %s", err)
+		return
+	}
+	d.Network = report
+	for _, f := range report.Failed() {
+		d.problem("Network check '%s' failed: %s", f.Name, f.Error)
+	}
+}
+
+func redactLicense(s string, cfg *config.Config) string {
+	if cfg == nil || strings.TrimSpace(cfg.License) == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, strings.TrimSpace(cfg.License), helpers.HiddenField)
+}
+
+// writeBundle stores the diagnostics, the public configuration values and the redacted config file in a zip file.
+func writeBundle(path string, d *diagnostics) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	if err = addJSON(zw, "diagnostics.json", d); err != nil {
+		return err
+	}
+	if d.cfg != nil {
+		fields, err := d.cfg.PublicFields()
+		if err != nil {
+			return err
+		}
+		if err = addJSON(zw, "effective-config.json", fields); err != nil {
+			return err
+		}
+	}
+	if d.Config.File != "" {
+		content, err := redactedConfigFile(d.Config.File)
+		if err != nil {
+			content = []byte(fmt.Sprintf("# cannot include config file: %s\n", err))
+		}
+		if err = addFile(zw, filepath.Base(d.Config.File), content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func addJSON(zw *zip.Writer, name string, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return addFile(zw, name, content)
+}
+
+func addFile(zw *zip.Writer, name string, content []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// redactedConfigFile returns the config file content with the sensitive values and proxy credentials removed.
+// Comments are dropped, as they could contain secrets too.
+func redactedConfigFile(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var content yaml.MapSlice
+	if err = yaml.Unmarshal(raw, &content); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	return yaml.Marshal(redact(content))
+}
+
+func redact(v interface{}) interface{} {
+	switch value := v.(type) {
+	case yaml.MapSlice:
+		for i, item := range value {
+			if key, ok := item.Key.(string); ok && sensitiveKeyRegex.MatchString(key) {
+				value[i].Value = helpers.HiddenField
+				continue
+			}
+			value[i].Value = redact(item.Value)
+		}
+		return value
+	case []interface{}:
+		for i := range value {
+			value[i] = redact(value[i])
+		}
+		return value
+	case string:
+		// proxy URLs credentials
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			return u.Redacted()
+		}
+	}
+	return v
+}
@@ -0,0 +1,185 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"time"
+
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+)
+
+// latency phases of a request.
+const (
+	phaseDNS     = "dns"
+	phaseConnect = "connect"
+	phaseTLS     = "tls"
+	phaseServer  = "server"
+	phaseTotal   = "total"
+)
+
+var latencyPhases = []string{phaseDNS, phaseConnect, phaseTLS, phaseServer, phaseTotal}
+
+// requestTiming is the latency breakdown of a benchmark request. Phases not happening, like TLS for plain HTTP
+// collectors or DNS for IP addresses, are left empty.
+type requestTiming struct {
+	DNSMs      float64 `json:"dnsMs,omitempty"`
+	ConnectMs  float64 `json:"connectMs,omitempty"`
+	TLSMs      float64 `json:"tlsMs,omitempty"`
+	ServerMs   float64 `json:"serverMs,omitempty"`
+	TotalMs    float64 `json:"totalMs"`
+	StatusCode int     `json:"statusCode,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// latencyStats summarizes the latencies of a phase across the benchmark requests.
+type latencyStats struct {
+	Samples int     `json:"samples"`
+	MinMs   float64 `json:"minMs"`
+	P50Ms   float64 `json:"p50Ms"`
+	P95Ms   float64 `json:"p95Ms"`
+	MaxMs   float64 `json:"maxMs"`
+}
+
+// WithBenchmark enables the latency benchmark, sending the given amount of requests to the collector.
+func WithBenchmark(requests int) Option {
+	return func(t *target) {
+		t.benchmarkRequests = requests
+	}
+}
+
+// checkLatencyBenchmark sends timed requests to the collector through the agent HTTP client, reporting the
+// percentiles of the DNS, TCP connect, TLS handshake and server response times. Every request opens a new
+// connection, so each of them measures the whole breakdown. It gives a baseline when the agent is slow to report.
+func checkLatencyBenchmark(t target) CheckResult {
+	return runCheck(t, "latency benchmark", func(result *CheckResult) error {
+		client := backendhttp.GetHttpClient(t.timeout, t.transport)
+
+		timings := make([]requestTiming, 0, t.benchmarkRequests)
+		failures := 0
+		var lastErr error
+		for i := 0; i < t.benchmarkRequests; i++ {
+			client.CloseIdleConnections()
+			timing, err := timedRequest(client, t.url)
+			if err != nil {
+				failures++
+				lastErr = err
+			}
+			timings = append(timings, timing)
+		}
+		client.CloseIdleConnections()
+
+		result.AddDetail("requests", t.benchmarkRequests)
+		result.AddDetail("failures", failures)
+		result.AddDetail("latency", latencySummary(timings))
+		result.AddDetail("timings", timings)
+
+		switch {
+		case failures == 0:
+			return nil
+		case failures == t.benchmarkRequests:
+			return fmt.Errorf("all the %d benchmark requests failed: %w", failures, lastErr)
+		}
+		return fmt.Errorf("%d out of %d benchmark requests failed: %w", failures, t.benchmarkRequests, lastErr)
+	})
+}
+
+// timedRequest sends a HEAD request to the collector tracing the time spent on every phase.
+func timedRequest(client *http.Client, collectorURL string) (requestTiming, error) {
+	var timing requestTiming
+
+	req, err := http.NewRequest(http.MethodHead, collectorURL, nil)
+	if err != nil {
+		timing.Error = err.Error()
+		return timing, err
+	}
+
+	var dnsStart, connectStart, tlsStart, wroteRequest time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			timing.DNSMs = msSince(dnsStart)
+		},
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(string, string, error) {
+			timing.ConnectMs = msSince(connectStart)
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			timing.TLSMs = msSince(tlsStart)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { wroteRequest = time.Now() },
+		GotFirstResponseByte: func() {
+			timing.ServerMs = msSince(wroteRequest)
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	timing.TotalMs = msSince(start)
+	if err != nil {
+		timing.Error = err.Error()
+		return timing, err
+	}
+	_ = resp.Body.Close()
+	timing.StatusCode = resp.StatusCode
+	return timing, nil
+}
+
+// latencySummary returns the latency statistics per phase, only taking into account successful requests.
+func latencySummary(timings []requestTiming) map[string]latencyStats {
+	samples := map[string][]float64{}
+	for _, t := range timings {
+		if t.Error != "" {
+			continue
+		}
+		for phase, ms := range map[string]float64{
+			phaseDNS:     t.DNSMs,
+			phaseConnect: t.ConnectMs,
+			phaseTLS:     t.TLSMs,
+			phaseServer:  t.ServerMs,
+			phaseTotal:   t.TotalMs,
+		} {
+			if ms > 0 {
+				samples[phase] = append(samples[phase], ms)
+			}
+		}
+	}
+
+	summary := map[string]latencyStats{}
+	for _, phase := range latencyPhases {
+		values := samples[phase]
+		if len(values) == 0 {
+			continue
+		}
+		sort.Float64s(values)
+		summary[phase] = latencyStats{
+			Samples: len(values),
+			MinMs:   values[0],
+			P50Ms:   percentile(values, 50),
+			P95Ms:   percentile(values, 95),
+			MaxMs:   values[len(values)-1],
+		}
+	}
+	return summary
+}
+
+// percentile returns the nearest-rank percentile of the sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func msSince(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
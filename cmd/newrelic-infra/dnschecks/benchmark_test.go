@@ -0,0 +1,69 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLatencyBenchmark(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tgt := target{url: srv.URL, timeout: time.Second, transport: srv.Client().Transport, logger: testLogger, benchmarkRequests: 5}
+	result := checkLatencyBenchmark(tgt)
+	require.True(t, result.Success, result.Error)
+	assert.Equal(t, 0, result.Details["failures"])
+
+	timings := result.Details["timings"].([]requestTiming)
+	require.Len(t, timings, 5)
+	for _, timing := range timings {
+		assert.Equal(t, http.StatusNoContent, timing.StatusCode)
+		// a new connection per request
+		assert.NotZero(t, timing.ConnectMs)
+		assert.NotZero(t, timing.TLSMs)
+	}
+
+	latency := result.Details["latency"].(map[string]latencyStats)
+	for _, phase := range []string{phaseConnect, phaseTLS, phaseServer, phaseTotal} {
+		assert.Equal(t, 5, latency[phase].Samples, phase)
+		assert.LessOrEqual(t, latency[phase].P50Ms, latency[phase].P95Ms, phase)
+	}
+	// the collector URL host is an IP address
+	assert.NotContains(t, latency, phaseDNS)
+}
+
+func TestCheckLatencyBenchmark_Failures(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1)%2 == 0 {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	tgt := target{url: srv.URL, timeout: 100 * time.Millisecond, transport: http.DefaultTransport, logger: testLogger, benchmarkRequests: 4}
+	result := checkLatencyBenchmark(tgt)
+	assert.False(t, result.Success)
+	assert.True(t, result.TimedOut)
+	assert.Contains(t, result.Error, "2 out of 4 benchmark requests failed")
+	assert.Equal(t, 2, result.Details["failures"])
+	assert.Equal(t, 2, result.Details["latency"].(map[string]latencyStats)[phaseTotal].Samples)
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+	assert.Equal(t, 10.0, percentile(values, 50))
+	assert.Equal(t, 19.0, percentile(values, 95))
+	assert.Equal(t, 7.0, percentile([]float64{7}, 95))
+}
@@ -4,7 +4,7 @@
 package dnschecks
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -15,25 +15,55 @@ import (
 	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
 	http2 "github.com/newrelic/infrastructure-agent/pkg/http"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
-	"github.com/sirupsen/logrus"
 )
 
 const (
 	logPrefix = " ====== "
 )
 
+// target is the checked endpoint along with the settings shared by all the checks.
+type target struct {
+	url       string
+	timeout   time.Duration
+	transport http.RoundTripper
+	logger    log.Entry
+	// agent proxy configuration
+	proxy              string
+	ignoreSystemProxy  bool
+	proxyValidateCerts bool
+	caBundleFile       string
+	caBundleDir        string
+	resolvers          []string
+	traceroute         bool
+	benchmarkRequests  int
+}
+
+// Option provides additional settings to the checks.
+type Option func(t *target)
+
+// check runs a single network check against the target.
+type check func(t target) CheckResult
+
+// RunChecks runs all the network checks against the collector URL and returns their results. Checks
+// failures are logged and reported, an error is only returned when checks cannot be run.
 func RunChecks(
 	url string,
 	timeout string,
 	transport http.RoundTripper,
 	logger log.Entry,
-) error {
-	networkChecks := []func(string, time.Duration, http.RoundTripper, log.Entry) (bool, error){
+	opts ...Option,
+) (*Report, error) {
+	reachabilityChecks := []check{
 		checkEndpointReachable,
 		checkEndpointReachableDefaultTransport,
 		checkEndpointReachableDefaultHTTPHeadClient,
-		checkEndpointReachableCustomDNS,
-		checkEndpointReachableGoResolverCustom,
+	}
+	networkChecks := []check{
+		checkResolvers,
+		checkDualStack,
+		checkTLSHandshake,
+		checkHTTPVersions,
+		checkPayloadSizes,
 	}
 
 	startupConnectionTimeoutDuration, err := time.ParseDuration(timeout)
@@ -41,17 +71,68 @@ func RunChecks(
 		// This should never happen, as the correct format is checked
 		// during NormalizeConfig.
 		logger.WithError(err).Error("Wrong startup_connection_timeout format")
-		return err
+		return nil, err
+	}
+
+	t := target{
+		url:       url,
+		timeout:   startupConnectionTimeoutDuration,
+		transport: transport,
+		logger:    logger,
+	}
+	for _, opt := range opts {
+		opt(&t)
 	}
 
+	report := &Report{
+		CollectorURL: url,
+		Timestamp:    time.Now(),
+	}
+	reachable := false
+	for _, reachabilityCheck := range reachabilityChecks {
+		result := reachabilityCheck(t)
+		reachable = reachable || result.Success
+		report.Checks = append(report.Checks, result)
+	}
 	for _, networkCheck := range networkChecks {
-		_, testErr := networkCheck(url, startupConnectionTimeoutDuration, transport, logger)
+		report.Checks = append(report.Checks, networkCheck(t))
+	}
+	report.Checks = append(report.Checks, runProxyChecks(t)...)
+	if t.benchmarkRequests > 0 {
+		report.Checks = append(report.Checks, checkLatencyBenchmark(t))
+	}
+	if t.traceroute && !reachable {
+		report.Checks = append(report.Checks, checkTraceroute(t))
+	}
 
-		if testErr != nil {
-			logger.Error(testErr.Error())
-		}
+	return report, nil
+}
+
+// runCheck wraps a check execution, logging and timing it. The check can decorate the result with details.
+func runCheck(t target, testName string, fn func(result *CheckResult) error) CheckResult {
+	startLogMessage(t.logger, testName)
+
+	result := CheckResult{Name: testName}
+	start := time.Now()
+	err := fn(&result)
+	result.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+
+	if err != nil {
+		result.Error = err.Error()
+		result.TimedOut = isTimeout(err)
+		result.Intercepted = errors.Is(err, errIntercepted)
+	} else {
+		result.Success = true
 	}
-	return nil
+
+	endLogMessage(t.logger, testName, err)
+
+	return result
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
 
 func startLogMessage(logger log.Entry, testName string) {
@@ -66,167 +147,58 @@ func endLogMessage(logger log.Entry, testName string, err error) {
 	}
 }
 
-func checkEndpointReachable(
-	collectorURL string,
-	timeout time.Duration,
-	transport http.RoundTripper,
-	logger log.Entry,
-) (timedOut bool, err error) {
-
-	startLogMessage(logger, "configured agent's HTTP client")
-	var request *http.Request
-	if request, err = http.NewRequest("HEAD", collectorURL, nil); err != nil {
-		return false, fmt.Errorf("unable to prepare reachability request: %v, error: %s", request, err)
-	}
-	request = http2.WithTracer(request, "checkEndpointReachable")
-	client := backendhttp.GetHttpClient(timeout, transport)
-	var resp *http.Response
-
-	if resp, err = client.Do(request); err != nil {
-		if e2, ok := err.(net.Error); ok && (e2.Timeout() || e2.Temporary()) {
-			timedOut = true
-		}
-		if errURL, ok := err.(*url.Error); ok {
-			err = fmt.Errorf("URL error detected. May be a configuration problem or a network connectivity issue.: %w", errURL)
-			timedOut = true
-		}
+// head sends a traced HEAD request to the collector, recording the response status code.
+func head(client *http.Client, collectorURL string, result *CheckResult) error {
+	req, err := http.NewRequest(http.MethodHead, collectorURL, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create request for %s: %w", collectorURL, err)
 	}
+	req = http2.WithTracer(req, "checkEndpointReachable")
 
-	resp.Body.Close()
-
-	endLogMessage(logger, "configured agent's HTTP client", err)
-
-	return
-}
-
-func checkEndpointReachableDefaultTransport(
-	collectorURL string,
-	timeout time.Duration,
-	transport http.RoundTripper,
-	logger log.Entry,
-) (timedOut bool, err error) {
-
-	startLogMessage(logger, "plain HTTP transport")
-	var req *http.Request
-	client := backendhttp.GetHttpClient(timeout, http.DefaultTransport)
-	req, err = http.NewRequest("HEAD", collectorURL, nil)
+	resp, err := client.Do(req)
 	if err != nil {
-		logrus.WithError(err).Error(fmt.Sprintf("cannot Create request for %s", collectorURL))
-	} else {
-		req = http2.WithTracer(req, "checkEndpointReachable")
-		var resp *http.Response
-		resp, err = client.Do(req)
-		resp.Body.Close()
+		return err
 	}
+	_ = resp.Body.Close()
 
-	endLogMessage(logger, "plain HTTP transport", err)
-
-	return
+	result.AddDetail("statusCode", resp.StatusCode)
+	addProxyHeaders(resp, result)
+	return DetectInterception(req.URL, resp)
 }
 
-func checkEndpointReachableDefaultHTTPHeadClient(
-	collectorURL string,
-	timeout time.Duration,
-	transport http.RoundTripper,
-	logger log.Entry,
-) (timedOut bool, err error) {
-
-	startLogMessage(logger, "plain HEAD request")
-	_, err = http.Head(collectorURL) //nolint
+func checkEndpointReachable(t target) CheckResult {
+	return runCheck(t, "configured agent's HTTP client", func(result *CheckResult) error {
+		client := backendhttp.GetHttpClient(t.timeout, t.transport)
+		err := head(client, t.url, result)
 
-	endLogMessage(logger, "plain HEAD request", err)
-	return
-}
-
-func checkEndpointReachableGoResolverCustom(
-	collectorURL string,
-	timeout time.Duration,
-	transport http.RoundTripper,
-	logger log.Entry,
-) (timedOut bool, err error) {
-
-	startLogMessage(logger, "Golang DNS custom resolver")
-	var req *http.Request
-	req, err = http.NewRequest("HEAD", collectorURL, nil)
-	if err != nil {
-		logrus.WithError(err).Error(fmt.Sprintf("cannot Create request for %s", collectorURL))
-	} else {
-		resolver := net.DefaultResolver
-		resolver.PreferGo = true
-		dialer := &net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-			Resolver:  resolver,
-		}
-		customTransport := &http.Transport{
-			Proxy:                 http.ProxyFromEnvironment,
-			DialContext:           dialer.DialContext,
-			ForceAttemptHTTP2:     true,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
+		var errURL *url.Error
+		if errors.As(err, &errURL) {
+			return fmt.Errorf("URL error detected. May be a configuration problem or a network connectivity issue.: %w", errURL)
 		}
-		client := http.Client{}
-		client.Transport = customTransport
-		req = http2.WithTracer(req, "checkEndpointReachable")
-		var resp *http.Response
-		resp, err = http.DefaultClient.Do(req)
-		resp.Body.Close()
-	}
-
-	endLogMessage(logger, "Golang DNS custom resolver", err)
-	return
+		return err
+	})
 }
 
-func checkEndpointReachableCustomDNS(
-	collectorURL string,
-	timeout time.Duration,
-	transport http.RoundTripper,
-	logger log.Entry,
-) (timedOut bool, err error) {
+func checkEndpointReachableDefaultTransport(t target) CheckResult {
+	return runCheck(t, "plain HTTP transport", func(result *CheckResult) error {
+		return head(backendhttp.GetHttpClient(t.timeout, http.DefaultTransport), t.url, result)
+	})
+}
 
-	startLogMessage(logger, "public DNS server")
-	var req *http.Request
-	req, err = http.NewRequest("HEAD", collectorURL, nil)
-	if err != nil {
-		logrus.WithError(err).Error(fmt.Sprintf("cannot Create request for %s", collectorURL))
-	} else {
-		resolver := net.DefaultResolver
-		resolver.PreferGo = true
-		resolver.Dial = func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: time.Millisecond * time.Duration(10000),
-			}
-
-			return d.DialContext(ctx, network, "1.1.1.1:53")
-		}
-		dialer := &net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-			Resolver:  resolver,
-		}
-		customTransport := &http.Transport{
-			Proxy:                 http.ProxyFromEnvironment,
-			DialContext:           dialer.DialContext,
-			ForceAttemptHTTP2:     true,
-			MaxIdleConns:          1,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
+func checkEndpointReachableDefaultHTTPHeadClient(t target) CheckResult {
+	return runCheck(t, "plain HEAD request", func(result *CheckResult) error {
+		requested, err := url.Parse(t.url)
+		if err != nil {
+			return err
 		}
-		client := backendhttp.GetHttpClient(timeout, customTransport)
-		req, err = http.NewRequest("HEAD", collectorURL, nil)
+		resp, err := http.Head(t.url) //nolint
 		if err != nil {
-			logrus.WithError(err).Error(fmt.Sprintf("cannot Create request for %s", collectorURL))
-		} else {
-			req = http2.WithTracer(req, "testing")
-			var resp *http.Response
-			resp, err = client.Do(req)
-			resp.Body.Close()
+			return err
 		}
-	}
+		_ = resp.Body.Close()
 
-	endLogMessage(logger, "public DNS server", err)
-	return
+		result.AddDetail("statusCode", resp.StatusCode)
+		addProxyHeaders(resp, result)
+		return DetectInterception(requested, resp)
+	})
 }
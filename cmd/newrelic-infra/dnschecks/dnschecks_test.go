@@ -0,0 +1,89 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+var testLogger = log.WithComponent("dnschecks_test")
+
+func TestRunChecks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	clearProxyEnv(t)
+	report, err := RunChecks(srv.URL, "5s", http.DefaultTransport, testLogger)
+	require.NoError(t, err)
+
+	assert.Equal(t, srv.URL, report.CollectorURL)
+	assert.Len(t, report.Checks, 8)
+	assert.Empty(t, report.Failed())
+	// DNS, TLS and HTTP/2 checks are skipped for plain http collectors reached by IP
+	for _, c := range report.Checks[:3] {
+		assert.NotEmpty(t, c.Name)
+		assert.Equal(t, http.StatusNoContent, c.Details["statusCode"], c.Name)
+	}
+}
+
+func TestRunChecks_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	// closed server refuses connections
+	srv.Close()
+
+	clearProxyEnv(t)
+	report, err := RunChecks(srv.URL, "1s", http.DefaultTransport, testLogger)
+	require.NoError(t, err)
+
+	require.Len(t, report.Failed(), 5)
+	for _, c := range report.Failed() {
+		assert.NotEmpty(t, c.Error)
+	}
+}
+
+func TestRunChecks_InvalidTimeout(t *testing.T) {
+	report, err := RunChecks("http://localhost", "foo", http.DefaultTransport, testLogger)
+	assert.Error(t, err)
+	assert.Nil(t, report)
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestRunCheck(t *testing.T) {
+	tgt := target{logger: testLogger}
+
+	result := runCheck(tgt, "test", func(result *CheckResult) error {
+		result.AddDetail("foo", "bar")
+		return nil
+	})
+	assert.True(t, result.Success)
+	assert.Equal(t, "test", result.Name)
+	assert.Equal(t, "bar", result.Details["foo"])
+
+	result = runCheck(tgt, "test", func(*CheckResult) error {
+		return errors.New("failure")
+	})
+	assert.False(t, result.Success)
+	assert.False(t, result.TimedOut)
+	assert.Equal(t, "failure", result.Error)
+
+	result = runCheck(tgt, "test", func(*CheckResult) error {
+		return timeoutErr{}
+	})
+	assert.True(t, result.TimedOut)
+}
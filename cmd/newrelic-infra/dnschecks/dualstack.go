@@ -0,0 +1,113 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// maxAddressesPerFamily limits the connection attempts per address family, as collectors resolve into many.
+const maxAddressesPerFamily = 2
+
+// lookupIP resolves the host addresses of a family ("ip4" or "ip6"), replaceable for testing.
+var lookupIP = net.DefaultResolver.LookupIP
+
+// familyResult is the outcome of connecting to the collector over an address family.
+type familyResult struct {
+	Family      string        `json:"family"`
+	Addresses   []string      `json:"addresses,omitempty"`
+	Connections []dialAttempt `json:"connections,omitempty"`
+	Reachable   bool          `json:"reachable"`
+	Error       string        `json:"error,omitempty"`
+}
+
+type dialAttempt struct {
+	Address    string  `json:"address"`
+	Success    bool    `json:"success"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// checkDualStack resolves the A and AAAA records of the collector separately and connects through each of them,
+// reporting which address family works. On dual-stack hosts with a broken IPv6 route connections wait for the
+// IPv6 attempt before falling back to IPv4, causing intermittent timeouts.
+func checkDualStack(t target) CheckResult {
+	return runCheck(t, "IPv4 and IPv6 connectivity", func(result *CheckResult) error {
+		addr, err := hostPort(t.url)
+		if err != nil {
+			return err
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+
+		v4 := connectFamily("ip4", host, port, t.timeout)
+		v6 := connectFamily("ip6", host, port, t.timeout)
+		result.AddDetail("ipv4", v4)
+		result.AddDetail("ipv6", v6)
+
+		switch {
+		case len(v4.Addresses) > 0 && len(v6.Addresses) > 0 && v4.Reachable != v6.Reachable:
+			working, broken := "IPv4", "IPv6"
+			if v6.Reachable {
+				working, broken = broken, working
+			}
+			return fmt.Errorf("collector is reachable over %s but not over %s, check the %s default route or "+
+				"disable %s on the host, connections might time out before falling back", working, broken, broken, broken)
+		case !v4.Reachable && !v6.Reachable:
+			return fmt.Errorf("collector is not reachable over IPv4 (%s) nor IPv6 (%s)", v4.Error, v6.Error)
+		}
+		return nil
+	})
+}
+
+// connectFamily resolves the host addresses of the family and opens TCP connections to them.
+func connectFamily(family, host, port string, timeout time.Duration) familyResult {
+	r := familyResult{Family: family}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ips, err := lookupIP(ctx, family, host)
+	cancel()
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if len(ips) == 0 {
+		r.Error = "no addresses found"
+		return r
+	}
+	for _, ip := range ips {
+		r.Addresses = append(r.Addresses, ip.String())
+	}
+
+	network := "tcp4"
+	if family == "ip6" {
+		network = "tcp6"
+	}
+	for i, ip := range ips {
+		if i == maxAddressesPerFamily {
+			break
+		}
+		a := dialAttempt{Address: net.JoinHostPort(ip.String(), port)}
+		start := time.Now()
+		conn, err := net.DialTimeout(network, a.Address, timeout)
+		a.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+		if err != nil {
+			a.Error = err.Error()
+		} else {
+			_ = conn.Close()
+			a.Success = true
+			r.Reachable = true
+		}
+		r.Connections = append(r.Connections, a)
+	}
+	if !r.Reachable {
+		r.Error = r.Connections[0].Error
+	}
+	return r
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withLookupIP(t *testing.T, ips map[string][]net.IP) {
+	t.Helper()
+	prev := lookupIP
+	lookupIP = func(_ context.Context, family, _ string) ([]net.IP, error) {
+		return ips[family], nil
+	}
+	t.Cleanup(func() { lookupIP = prev })
+}
+
+func TestCheckDualStack_BrokenIPv6(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	// test server only listens on IPv4 loopback
+	withLookupIP(t, map[string][]net.IP{
+		"ip4": {net.ParseIP("127.0.0.1")},
+		"ip6": {net.ParseIP("::1")},
+	})
+
+	result := checkDualStack(target{url: "http://collector.test:" + port, timeout: time.Second, logger: testLogger})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "collector is reachable over IPv4 but not over IPv6")
+
+	v4 := result.Details["ipv4"].(familyResult)
+	assert.True(t, v4.Reachable)
+	assert.Equal(t, []string{"127.0.0.1"}, v4.Addresses)
+	require.Len(t, v4.Connections, 1)
+	assert.True(t, v4.Connections[0].Success)
+
+	v6 := result.Details["ipv6"].(familyResult)
+	assert.False(t, v6.Reachable)
+	assert.NotEmpty(t, v6.Error)
+}
+
+func TestCheckDualStack_IPv4Only(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	result := checkDualStack(target{url: srv.URL, timeout: time.Second, logger: testLogger})
+	assert.True(t, result.Success, result.Error)
+	assert.True(t, result.Details["ipv4"].(familyResult).Reachable)
+	assert.Empty(t, result.Details["ipv6"].(familyResult).Addresses)
+}
+
+func TestCheckDualStack_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	result := checkDualStack(target{url: srv.URL, timeout: time.Second, logger: testLogger})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "collector is not reachable over IPv4")
+}
+
+func TestConnectFamily_MaxAddresses(t *testing.T) {
+	withLookupIP(t, map[string][]net.IP{
+		"ip4": {net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.3")},
+	})
+
+	r := connectFamily("ip4", "collector.test", "1", 100*time.Millisecond)
+	assert.Len(t, r.Addresses, 3)
+	assert.Len(t, r.Connections, maxAddressesPerFamily)
+	assert.False(t, r.Reachable)
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"time"
+)
+
+const (
+	protocolHTTP2 = "HTTP/2"
+	protocolHTTP1 = "HTTP/1.1"
+)
+
+// protocolResult is the outcome of requesting the collector offering a single HTTP version.
+type protocolResult struct {
+	Offered    string  `json:"offered"`
+	ALPN       string  `json:"alpn,omitempty"`
+	Protocol   string  `json:"protocol,omitempty"`
+	StatusCode int     `json:"statusCode,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// checkHTTPVersions requests the collector negotiating HTTP/2 through ALPN and then only offering HTTP/1.1, through
+// the same proxy the agent uses. Some proxies and TLS inspection appliances negotiate HTTP/2 but break the connections
+// afterwards, in which case the agent must be forced to use HTTP/1.1.
+func checkHTTPVersions(t target) CheckResult {
+	return runCheck(t, "HTTP/2 and HTTP/1.1 negotiation", func(result *CheckResult) error {
+		u, err := url.Parse(t.url)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "https" {
+			result.AddDetail("skipped", "collector URL is not https, HTTP/2 is only negotiated over TLS")
+			return nil
+		}
+
+		h2, h2Err := requestWithProtocol(t, protocolHTTP2)
+		h1, h1Err := requestWithProtocol(t, protocolHTTP1)
+		result.AddDetail("http2", h2)
+		result.AddDetail("http1", h1)
+		result.AddDetail("http2Negotiated", h2Err == nil && h2.ALPN == "h2")
+
+		switch {
+		case h2Err != nil && h1Err != nil:
+			return fmt.Errorf("neither HTTP/2 nor HTTP/1.1 requests reach the collector: %w", h1Err)
+		case h2Err != nil:
+			return fmt.Errorf("HTTP/2 requests fail but HTTP/1.1 ones work, a proxy in the path might not support "+
+				"HTTP/2, set force_http1 to true in the agent config: %w", h2Err)
+		case h1Err != nil:
+			return fmt.Errorf("HTTP/1.1 requests fail but HTTP/2 ones work: %w", h1Err)
+		}
+		return nil
+	})
+}
+
+// requestWithProtocol sends a HEAD request to the collector through a new connection negotiating the protocol.
+func requestWithProtocol(t target, protocol string) (protocolResult, error) {
+	r := protocolResult{Offered: protocol}
+
+	transport, err := protocolTransport(t, protocol)
+	if err != nil {
+		r.Error = err.Error()
+		return r, err
+	}
+	defer transport.CloseIdleConnections()
+
+	req, err := http.NewRequest(http.MethodHead, t.url, nil)
+	if err != nil {
+		r.Error = err.Error()
+		return r, err
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		TLSHandshakeDone: func(state tls.ConnectionState, _ error) {
+			r.ALPN = state.NegotiatedProtocol
+		},
+	}))
+
+	start := time.Now()
+	resp, err := (&http.Client{Timeout: t.timeout, Transport: transport}).Do(req)
+	r.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	if err != nil {
+		r.Error = err.Error()
+		return r, err
+	}
+	_ = resp.Body.Close()
+	r.Protocol = resp.Proto
+	r.StatusCode = resp.StatusCode
+	return r, nil
+}
+
+// protocolTransport returns a transport using the agent proxy and CA bundle, negotiating the protocol.
+func protocolTransport(t target, protocol string) (*http.Transport, error) {
+	transport, err := agentTransport(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if protocol == protocolHTTP2 {
+		// the collector or a proxy might pick HTTP/1.1, the same as the agent does
+		transport.ForceAttemptHTTP2 = true
+		transport.TLSClientConfig.NextProtos = []string{"h2", "http/1.1"}
+	} else {
+		// an empty TLSNextProto disables HTTP/2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		transport.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	}
+	return transport, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// http2Target returns a target trusting the test server certificate through the CA bundle.
+func http2Target(t *testing.T, srv *httptest.Server) target {
+	t.Helper()
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(bundle, pemCert, 0o600))
+	return target{url: srv.URL, timeout: time.Second, logger: testLogger, caBundleFile: bundle}
+}
+
+func TestCheckHTTPVersions(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	clearProxyEnv(t)
+	result := checkHTTPVersions(http2Target(t, srv))
+	require.True(t, result.Success, result.Error)
+	assert.Equal(t, true, result.Details["http2Negotiated"])
+
+	h2 := result.Details["http2"].(protocolResult)
+	assert.Equal(t, "h2", h2.ALPN)
+	assert.Equal(t, "HTTP/2.0", h2.Protocol)
+	assert.Equal(t, http.StatusNoContent, h2.StatusCode)
+
+	h1 := result.Details["http1"].(protocolResult)
+	assert.NotEqual(t, "h2", h1.ALPN)
+	assert.Equal(t, "HTTP/1.1", h1.Protocol)
+}
+
+func TestCheckHTTPVersions_HTTP2Broken(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 {
+			// resets the stream, like proxies mangling HTTP/2 frames
+			panic(http.ErrAbortHandler)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	clearProxyEnv(t)
+	result := checkHTTPVersions(http2Target(t, srv))
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "HTTP/2 requests fail but HTTP/1.1 ones work")
+	assert.Contains(t, result.Error, "force_http1")
+	assert.Empty(t, result.Details["http1"].(protocolResult).Error)
+}
+
+func TestCheckHTTPVersions_HTTP1Only(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	clearProxyEnv(t)
+	result := checkHTTPVersions(http2Target(t, srv))
+	require.True(t, result.Success, result.Error)
+	assert.Equal(t, false, result.Details["http2Negotiated"])
+	assert.Equal(t, "HTTP/1.1", result.Details["http2"].(protocolResult).Protocol)
+}
+
+func TestCheckHTTPVersions_NotHTTPS(t *testing.T) {
+	result := checkHTTPVersions(target{url: "http://localhost", timeout: time.Second, logger: testLogger})
+	assert.True(t, result.Success)
+	assert.NotEmpty(t, result.Details["skipped"])
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var errIntercepted = errors.New("captive portal / transparent proxy detected")
+
+// proxyHeaders are usually added by proxies along the path. They're reported for troubleshooting, but aren't
+// conclusive, as CDNs add them too.
+var proxyHeaders = []string{"Via", "Proxy-Agent", "X-Squid-Error", "X-Cache"}
+
+// DetectInterception returns an error when the collector response was not sent by the collector, but by a captive
+// portal or a proxy intercepting the connection: redirections to other hosts, downgrades to plain HTTP, HTML
+// pages or network authentication requests.
+func DetectInterception(requested *url.URL, resp *http.Response) error {
+	var indicators []string
+
+	if resp.StatusCode == http.StatusNetworkAuthenticationRequired {
+		indicators = append(indicators, "network authentication required (511)")
+	}
+
+	if resp.Request != nil && resp.Request.URL != nil {
+		final := resp.Request.URL
+		if !strings.EqualFold(final.Hostname(), requested.Hostname()) {
+			indicators = append(indicators, fmt.Sprintf("redirected to %s", final.Host))
+		}
+		if requested.Scheme == "https" && final.Scheme == "http" {
+			indicators = append(indicators, "downgraded to plain HTTP")
+		}
+	}
+
+	if location := resp.Header.Get("Location"); location != "" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if u, err := url.Parse(location); err == nil && u.Host != "" && !strings.EqualFold(u.Hostname(), requested.Hostname()) {
+			indicators = append(indicators, fmt.Sprintf("redirection to %s", u.Host))
+		}
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType == "text/html" {
+		indicators = append(indicators, "HTML page returned")
+	}
+
+	if len(indicators) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errIntercepted, strings.Join(indicators, ", "))
+}
+
+// addProxyHeaders reports the response headers revealing proxies in the path.
+func addProxyHeaders(resp *http.Response, result *CheckResult) {
+	found := map[string]string{}
+	for _, h := range proxyHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			found[h] = v
+		}
+	}
+	if len(found) > 0 {
+		result.AddDetail("proxyHeaders", found)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectInterception(t *testing.T) {
+	requested, _ := url.Parse("https://infra-api.newrelic.com/infra/v2/metrics")
+	response := func(status int, finalURL string, headers map[string]string) *http.Response {
+		final, _ := url.Parse(finalURL)
+		resp := &http.Response{StatusCode: status, Header: http.Header{}, Request: &http.Request{URL: final}}
+		for k, v := range headers {
+			resp.Header.Set(k, v)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		name      string
+		resp      *http.Response
+		indicator string
+	}{
+		{"collector reply", response(http.StatusNotFound, requested.String(), map[string]string{"Content-Type": "application/json"}), ""},
+		{"redirected to portal", response(http.StatusOK, "http://portal.hotel.test/login", nil), "redirected to portal.hotel.test"},
+		{"not followed redirection", response(http.StatusFound, requested.String(), map[string]string{"Location": "https://portal.test/"}), "redirection to portal.test"},
+		{"same host redirection", response(http.StatusFound, requested.String(), map[string]string{"Location": "/infra/v3/metrics"}), ""},
+		{"downgrade", response(http.StatusOK, "http://infra-api.newrelic.com/infra/v2/metrics", nil), "downgraded to plain HTTP"},
+		{"html page", response(http.StatusOK, requested.String(), map[string]string{"Content-Type": "text/html; charset=utf-8"}), "HTML page returned"},
+		{"network authentication", response(http.StatusNetworkAuthenticationRequired, requested.String(), nil), "network authentication required (511)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DetectInterception(requested, tt.resp)
+			if tt.indicator == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errIntercepted))
+			assert.Contains(t, err.Error(), tt.indicator)
+		})
+	}
+}
+
+func TestCheckEndpointReachable_CaptivePortal(t *testing.T) {
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Via", "1.1 branch-proxy")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer portal.Close()
+	portalURL, _ := url.Parse(portal.URL)
+
+	// the collector host is resolved to the portal, which redirects to its login page
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+portalURL.Port()+"/login", http.StatusFound)
+	}))
+	defer collector.Close()
+
+	result := checkEndpointReachable(target{url: collector.URL, timeout: time.Second, transport: http.DefaultTransport, logger: testLogger})
+	assert.False(t, result.Success)
+	assert.True(t, result.Intercepted)
+	assert.False(t, result.TimedOut)
+	assert.Contains(t, result.Error, "captive portal / transparent proxy detected")
+	assert.Contains(t, result.Error, "redirected to localhost:"+portalURL.Port())
+	assert.Contains(t, result.Error, "HTML page returned")
+	assert.Equal(t, map[string]string{"Via": "1.1 branch-proxy"}, result.Details["proxyHeaders"])
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// payloadSizes are the POST body sizes sent to the collector, around the common MTUs and up to usual batch sizes.
+var payloadSizes = []int{256, 1024, 1400, 1500, 4096, 16384, 65536}
+
+// payloadResult is the outcome of posting a payload of the given size.
+type payloadResult struct {
+	Size       int     `json:"size"`
+	Success    bool    `json:"success"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// checkPayloadSizes posts progressively larger payloads to the collector with fragmentation disabled, reporting
+// the largest one reaching it. Small requests working while large ones hang points to a path MTU black hole, where
+// the ICMP messages needed to discover the MTU are dropped.
+func checkPayloadSizes(t target) CheckResult {
+	return runCheck(t, "payload sizes with don't fragment", func(result *CheckResult) error {
+		dialer := &net.Dialer{
+			Timeout:   t.timeout,
+			KeepAlive: 30 * time.Second,
+			Control:   setDontFragment,
+		}
+		// a new connection per payload, so a stalled one doesn't affect the next sizes
+		transport, err := agentTransport(t)
+		if err != nil {
+			return err
+		}
+		// through a proxy, fragmentation is only disabled on the hop to it
+		transport.DialContext = dialer.DialContext
+		client := &http.Client{Timeout: t.timeout, Transport: transport}
+
+		maxWorking := 0
+		failedSize := 0
+		var failedErr error
+		results := make([]payloadResult, 0, len(payloadSizes))
+		for _, size := range payloadSizes {
+			r, err := postPayload(client, t.url, size)
+			results = append(results, r)
+			if err == nil {
+				maxWorking = size
+			} else if failedErr == nil {
+				failedSize, failedErr = size, err
+			}
+		}
+		result.AddDetail("payloads", results)
+		result.AddDetail("maxWorkingSize", maxWorking)
+
+		switch {
+		case failedErr == nil:
+			return nil
+		case maxWorking == 0:
+			return fmt.Errorf("no payload reached the collector: %w", failedErr)
+		case maxWorking > failedSize:
+			return fmt.Errorf("intermittent failures, a %d bytes payload failed but a %d bytes one worked: %w",
+				failedSize, maxWorking, failedErr)
+		}
+		return fmt.Errorf("payloads of %d bytes or larger don't reach the collector, path MTU black hole suspected: %w",
+			failedSize, failedErr)
+	})
+}
+
+// postPayload sends a POST with a body of the given size. Any response is taken as the whole body went through.
+func postPayload(client *http.Client, collectorURL string, size int) (payloadResult, error) {
+	r := payloadResult{Size: size}
+
+	start := time.Now()
+	resp, err := client.Post(collectorURL, "application/octet-stream", bytes.NewReader(bytes.Repeat([]byte{'x'}, size)))
+	r.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	if err != nil {
+		r.Error = err.Error()
+		return r, err
+	}
+	_ = resp.Body.Close()
+
+	r.Success = true
+	return r, nil
+}
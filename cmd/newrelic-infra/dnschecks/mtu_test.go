@@ -0,0 +1,77 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPayloadSizes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	result := checkPayloadSizes(target{url: srv.URL, timeout: time.Second, logger: testLogger})
+	assert.True(t, result.Success, result.Error)
+	assert.Equal(t, payloadSizes[len(payloadSizes)-1], result.Details["maxWorkingSize"])
+	assert.Len(t, result.Details["payloads"], len(payloadSizes))
+}
+
+func TestCheckPayloadSizes_BlackHole(t *testing.T) {
+	// large payloads never get an answer, as when their packets are dropped
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength >= 4096 {
+			<-release
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	result := checkPayloadSizes(target{url: srv.URL, timeout: 100 * time.Millisecond, logger: testLogger})
+	assert.False(t, result.Success)
+	assert.True(t, result.TimedOut)
+	assert.Equal(t, 1500, result.Details["maxWorkingSize"])
+	assert.Contains(t, result.Error, "payloads of 4096 bytes or larger don't reach the collector, path MTU black hole suspected")
+}
+
+func TestCheckPayloadSizes_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	result := checkPayloadSizes(target{url: srv.URL, timeout: time.Second, logger: testLogger})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "no payload reached the collector")
+	assert.Equal(t, 0, result.Details["maxWorkingSize"])
+}
+
+func TestCheckPayloadSizes_ThroughProxy(t *testing.T) {
+	clearProxyEnv(t)
+	// the collector is only reachable through the proxy the agent uses
+	var proxied int32
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host == "collector.invalid" {
+			atomic.AddInt32(&proxied, 1)
+		}
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer proxySrv.Close()
+
+	tgt := target{url: "http://collector.invalid/", timeout: time.Second, logger: testLogger, proxy: proxySrv.URL}
+	result := checkPayloadSizes(tgt)
+	assert.True(t, result.Success, result.Error)
+	assert.Equal(t, int32(len(payloadSizes)), atomic.LoadInt32(&proxied))
+}
@@ -0,0 +1,336 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Proxy sources, named as in the agent proxy configuration.
+const (
+	sourceConfig     = "proxy configuration option"
+	sourceHTTPSProxy = "HTTPS_PROXY environment variable"
+	sourceHTTPProxy  = "HTTP_PROXY environment variable"
+	pathDirect       = "direct connection"
+)
+
+var errProxyAuth = errors.New("proxy authentication required or rejected (407)")
+
+// proxyCandidate is a proxy the agent or other processes on the host might use.
+type proxyCandidate struct {
+	source string
+	raw    string
+	// usedByAgent is false for proxies ignored by the agent, but used by other tools (ie: lower case env vars).
+	usedByAgent bool
+}
+
+// WithProxy provides the agent proxy configuration, so the checks can validate the proxy paths.
+func WithProxy(proxy string, ignoreSystemProxy, proxyValidateCerts bool) Option {
+	return func(t *target) {
+		t.proxy = proxy
+		t.ignoreSystemProxy = ignoreSystemProxy
+		t.proxyValidateCerts = proxyValidateCerts
+	}
+}
+
+// proxyCandidates returns the configured proxies, in the same priority the agent uses them.
+func proxyCandidates(t target) []proxyCandidate {
+	var candidates []proxyCandidate
+	if t.proxy != "" {
+		candidates = append(candidates, proxyCandidate{source: sourceConfig, raw: t.proxy, usedByAgent: true})
+	}
+	for _, env := range []struct{ name, source string }{
+		{"HTTPS_PROXY", sourceHTTPSProxy},
+		{"HTTP_PROXY", sourceHTTPProxy},
+	} {
+		// Go HTTP clients (ie: integrations) also accept lower case variables, but the agent doesn't
+		if raw := os.Getenv(env.name); raw != "" {
+			candidates = append(candidates, proxyCandidate{source: env.source, raw: raw, usedByAgent: !t.ignoreSystemProxy})
+		} else if raw = os.Getenv(strings.ToLower(env.name)); raw != "" {
+			candidates = append(candidates, proxyCandidate{source: strings.ToLower(env.source), raw: raw})
+		}
+	}
+	return candidates
+}
+
+// effectivePath returns the path the agent uses to reach the collector.
+func effectivePath(candidates []proxyCandidate) string {
+	for _, c := range candidates {
+		if c.usedByAgent {
+			return c.source
+		}
+	}
+	return pathDirect
+}
+
+// parseProxyURL parses the proxy address the same way the agent transport does.
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		u, err = url.Parse("http://" + raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address: %w", err)
+	}
+	return u, nil
+}
+
+func redact(raw string) string {
+	u, err := parseProxyURL(raw)
+	if err != nil {
+		return "<invalid>"
+	}
+	return u.Redacted()
+}
+
+// runProxyChecks validates the proxy paths to the collector, reporting which of them work.
+func runProxyChecks(t target) []CheckResult {
+	candidates := proxyCandidates(t)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	results := []CheckResult{checkProxyConsistency(t, candidates)}
+
+	working := map[string]bool{}
+	for _, c := range candidates {
+		r := checkProxyTunnel(t, c)
+		working[c.source] = r.Success
+		results = append(results, r)
+	}
+
+	direct := checkDirectConnection(t)
+	working[pathDirect] = direct.Success
+	results = append(results, direct)
+
+	return append(results, checkConnectionPath(t, candidates, working))
+}
+
+// checkProxyConsistency flags proxies from the environment that differ from the configured one, as only one of
+// them is used by the agent, while integrations and other tools might use the other.
+func checkProxyConsistency(t target, candidates []proxyCandidate) CheckResult {
+	return runCheck(t, "proxy configuration consistency", func(result *CheckResult) error {
+		for _, c := range candidates {
+			result.AddDetail(c.source, redact(c.raw))
+		}
+		effective := effectivePath(candidates)
+		result.AddDetail("effectivePath", effective)
+		result.AddDetail("ignoreSystemProxy", t.ignoreSystemProxy)
+
+		if t.proxy == "" {
+			return nil
+		}
+
+		var mismatches []string
+		for _, c := range candidates {
+			if c.source != sourceConfig && redact(c.raw) != redact(t.proxy) {
+				mismatches = append(mismatches, c.source)
+			}
+		}
+		if len(mismatches) > 0 {
+			return fmt.Errorf("%s differs from %s, the agent uses %s while integrations and other tools might use the environment one",
+				sourceConfig, strings.Join(mismatches, " and "), effective)
+		}
+		return nil
+	})
+}
+
+// checkProxyTunnel establishes a CONNECT tunnel to the collector through the proxy, using the credentials in the
+// proxy URL, if any.
+func checkProxyTunnel(t target, c proxyCandidate) CheckResult {
+	return runCheck(t, "CONNECT tunnel through "+c.source, func(result *CheckResult) error {
+		proxyURL, err := parseProxyURL(c.raw)
+		if err != nil {
+			return err
+		}
+		result.AddDetail("proxy", proxyURL.Redacted())
+
+		_, hasAuth := proxyURL.User.Password()
+		result.AddDetail("proxyAuth", hasAuth)
+
+		collectorAddr, err := hostPort(t.url)
+		if err != nil {
+			return err
+		}
+
+		statusCode, err := connectTunnel(t, proxyURL, collectorAddr, result)
+		if statusCode != 0 {
+			result.AddDetail("statusCode", statusCode)
+		}
+		return err
+	})
+}
+
+// agentTransport returns a transport reaching the collector as the agent does: through the proxy it uses and
+// trusting the CA bundle, which extends the system CAs. Connections aren't reused, so each request tests the path.
+func agentTransport(t target) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+
+	transport.Proxy = nil
+	for _, c := range proxyCandidates(t) {
+		if !c.usedByAgent {
+			continue
+		}
+		proxyURL, err := parseProxyURL(c.raw)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+		break
+	}
+
+	transport.TLSClientConfig = &tls.Config{}
+	if t.caBundleFile != "" || t.caBundleDir != "" {
+		roots, err := agentRoots(t)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.RootCAs = roots
+	}
+	return transport, nil
+}
+
+// checkDirectConnection tries reaching the collector without any proxy, to know whether a proxy is needed at all.
+func checkDirectConnection(t target) CheckResult {
+	return runCheck(t, "direct connection without proxy", func(result *CheckResult) error {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		return head(&http.Client{Timeout: t.timeout, Transport: transport}, t.url, result)
+	})
+}
+
+// checkConnectionPath verifies the path used by the agent works, suggesting a working one otherwise.
+func checkConnectionPath(t target, candidates []proxyCandidate, working map[string]bool) CheckResult {
+	return runCheck(t, "connection path", func(result *CheckResult) error {
+		effective := effectivePath(candidates)
+		result.AddDetail("effectivePath", effective)
+
+		var workingPaths []string
+		for _, c := range candidates {
+			if working[c.source] {
+				workingPaths = append(workingPaths, c.source)
+			}
+		}
+		if working[pathDirect] {
+			workingPaths = append(workingPaths, pathDirect)
+		}
+		result.AddDetail("workingPaths", workingPaths)
+
+		if working[effective] {
+			return nil
+		}
+		if len(workingPaths) == 0 {
+			return fmt.Errorf("the collector is not reachable through any path, agent uses %s", effective)
+		}
+		return fmt.Errorf("agent uses %s which fails, but %s works", effective, strings.Join(workingPaths, ", "))
+	})
+}
+
+// connectTunnel sends a CONNECT request for addr to the proxy, returning the proxy response status code.
+func connectTunnel(t target, proxyURL *url.URL, addr string, result *CheckResult) (int, error) {
+	conn, err := dialProxy(t, proxyURL, result)
+	if err != nil {
+		return 0, fmt.Errorf("cannot connect to proxy: %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(t.timeout))
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err = req.Write(conn); err != nil {
+		return 0, fmt.Errorf("cannot send CONNECT request: %w", err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read CONNECT response: %w", err)
+	}
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		return resp.StatusCode, errProxyAuth
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return resp.StatusCode, fmt.Errorf("proxy refused the tunnel: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// dialProxy connects to the proxy the same way the agent transport does: https proxies are verified against the
+// agent CAs, falling back to a plain connection when they don't speak TLS. Unless proxy_validate_certificates is
+// set, the agent also accepts proxy certificates issued by unknown authorities.
+func dialProxy(t target, proxyURL *url.URL, result *CheckResult) (net.Conn, error) {
+	proxyAddr, err := hostPort(proxyURL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: t.timeout}
+	if proxyURL.Scheme != "https" {
+		return dialer.Dial("tcp", proxyAddr)
+	}
+
+	roots, err := agentRoots(t)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{ServerName: proxyURL.Hostname(), RootCAs: roots}
+	conn, err := tls.DialWithDialer(dialer, "tcp", proxyAddr, tlsConfig)
+	if err == nil {
+		result.AddDetail("proxyCertificateVerified", true)
+		return conn, nil
+	}
+
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		recordHeader     tls.RecordHeaderError
+	)
+	switch {
+	case errors.As(err, &unknownAuthority) && !t.proxyValidateCerts:
+		result.AddDetail("proxyCertificateVerified", false)
+		tlsConfig.InsecureSkipVerify = true //nolint:gosec
+		return tls.DialWithDialer(dialer, "tcp", proxyAddr, tlsConfig)
+	case errors.As(err, &recordHeader):
+		result.AddDetail("proxyTLS", false)
+		return dialer.Dial("tcp", proxyAddr)
+	}
+	return nil, err
+}
+
+// hostPort returns the host:port of the URL, using the scheme default port when missing.
+func hostPort(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
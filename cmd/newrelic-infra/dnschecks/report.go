@@ -0,0 +1,99 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const checkEventType = "AgentNetworkCheck"
+
+// Report gathers the results of all the network checks, so they can be attached to support tickets.
+type Report struct {
+	CollectorURL string        `json:"collectorUrl"`
+	Timestamp    time.Time     `json:"timestamp"`
+	Checks       []CheckResult `json:"checks"`
+}
+
+// CheckResult is the outcome of a single check. Intercepted is set when the response came from a captive portal
+// or proxy rather than the collector. Details holds check specific information.
+type CheckResult struct {
+	Name        string                 `json:"name"`
+	Success     bool                   `json:"success"`
+	TimedOut    bool                   `json:"timedOut,omitempty"`
+	Intercepted bool                   `json:"intercepted,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	DurationMs  float64                `json:"durationMs"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+}
+
+// AddDetail attaches check specific information to the result.
+func (r *CheckResult) AddDetail(key string, value interface{}) {
+	if r.Details == nil {
+		r.Details = make(map[string]interface{})
+	}
+	r.Details[key] = value
+}
+
+// Failed returns the checks that didn't succeed.
+func (r *Report) Failed() []CheckResult {
+	var failed []CheckResult
+	for _, c := range r.Checks {
+		if !c.Success {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// JSON returns the report as indented JSON.
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// WriteFile stores the JSON report into path.
+func (r *Report) WriteFile(path string) error {
+	content, err := r.JSON()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o600)
+}
+
+// CheckEvent is the agent event reporting a check result.
+type CheckEvent struct {
+	sample.BaseEvent
+
+	CollectorURL string  `json:"collectorUrl"`
+	CheckName    string  `json:"checkName"`
+	Success      bool    `json:"success"`
+	TimedOut     bool    `json:"timedOut"`
+	Intercepted  bool    `json:"intercepted"`
+	Error        string  `json:"error,omitempty"`
+	DurationMs   float64 `json:"durationMs"`
+}
+
+// Events returns an agent event per check result.
+func (r *Report) Events() []sample.Event {
+	events := make([]sample.Event, 0, len(r.Checks))
+	for _, c := range r.Checks {
+		e := &CheckEvent{
+			CollectorURL: r.CollectorURL,
+			CheckName:    c.Name,
+			Success:      c.Success,
+			TimedOut:     c.TimedOut,
+			Intercepted:  c.Intercepted,
+			Error:        c.Error,
+			DurationMs:   c.DurationMs,
+		}
+		e.Type(checkEventType)
+		e.Timestamp(r.Timestamp.Unix())
+		events = append(events, e)
+	}
+	return events
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() *Report {
+	return &Report{
+		CollectorURL: "https://infra-api.newrelic.com",
+		Timestamp:    time.Unix(1600000000, 0),
+		Checks: []CheckResult{
+			{Name: "ok", Success: true, DurationMs: 10},
+			{Name: "ko", TimedOut: true, Error: "i/o timeout", DurationMs: 1000},
+		},
+	}
+}
+
+func TestReport_WriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, testReport().WriteFile(path))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var read Report
+	require.NoError(t, json.Unmarshal(content, &read))
+	assert.Equal(t, testReport().Checks, read.Checks)
+	assert.Equal(t, "https://infra-api.newrelic.com", read.CollectorURL)
+}
+
+func TestReport_Failed(t *testing.T) {
+	failed := testReport().Failed()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "ko", failed[0].Name)
+}
+
+func TestReport_Events(t *testing.T) {
+	events := testReport().Events()
+	require.Len(t, events, 2)
+
+	e, ok := events[1].(*CheckEvent)
+	require.True(t, ok)
+	assert.Equal(t, checkEventType, e.EventType)
+	assert.Equal(t, int64(1600000000), e.Timestmp)
+	assert.Equal(t, "https://infra-api.newrelic.com", e.CollectorURL)
+	assert.Equal(t, "ko", e.CheckName)
+	assert.False(t, e.Success)
+	assert.True(t, e.TimedOut)
+	assert.Equal(t, "i/o timeout", e.Error)
+}
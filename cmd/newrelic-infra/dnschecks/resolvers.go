@@ -0,0 +1,250 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	resolverSystem = "system"
+	resolverGo     = "go"
+
+	dohContentType = "application/dns-message"
+)
+
+// defaultResolvers are compared when no resolvers are configured.
+var defaultResolvers = []string{resolverSystem, "1.1.1.1", "8.8.8.8"}
+
+// resolverResult is the outcome of resolving the collector host with a resolver.
+type resolverResult struct {
+	Resolver   string   `json:"resolver"`
+	Addresses  []string `json:"addresses,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	DurationMs float64  `json:"durationMs"`
+}
+
+// lookupFn resolves a host into its IP addresses.
+type lookupFn func(ctx context.Context, host string) ([]string, error)
+
+// WithResolvers sets the resolvers compared by the DNS check. Supported values are "system" for the host
+// configured resolver, "go" for the Go built-in resolver, DNS server addresses (ie: 1.1.1.1 or 10.0.0.2:53) and
+// DNS over HTTPS URLs (ie: https://cloudflare-dns.com/dns-query).
+func WithResolvers(resolvers []string) Option {
+	return func(t *target) {
+		if len(resolvers) > 0 {
+			t.resolvers = resolvers
+		}
+	}
+}
+
+// checkResolvers resolves the collector host with every resolver, summarizing the outcome in a verdict.
+func checkResolvers(t target) CheckResult {
+	return runCheck(t, "DNS resolvers comparison", func(result *CheckResult) error {
+		u, err := url.Parse(t.url)
+		if err != nil {
+			return err
+		}
+		host := u.Hostname()
+		if net.ParseIP(host) != nil {
+			result.AddDetail("skipped", "collector URL host is an IP address")
+			return nil
+		}
+
+		resolvers := t.resolvers
+		if len(resolvers) == 0 {
+			resolvers = defaultResolvers
+		}
+
+		results := make([]resolverResult, 0, len(resolvers))
+		for _, r := range resolvers {
+			results = append(results, resolve(r, host, t.timeout))
+		}
+		result.AddDetail("host", host)
+		result.AddDetail("resolvers", results)
+
+		verdict, ok := resolversVerdict(results)
+		result.AddDetail("verdict", verdict)
+		if !ok {
+			return errors.New(verdict)
+		}
+		return nil
+	})
+}
+
+func resolve(resolver, host string, timeout time.Duration) resolverResult {
+	r := resolverResult{Resolver: resolver}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := newLookup(resolver, timeout)(ctx, host)
+	r.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	if err == nil && len(addrs) == 0 {
+		err = errors.New("no addresses found")
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	r.Addresses = addrs
+	return r
+}
+
+// resolversVerdict summarizes the comparison into an actionable message, not ok when the system resolver, which
+// is the one used by the agent, doesn't work.
+func resolversVerdict(results []resolverResult) (verdict string, ok bool) {
+	var working, failing []string
+	systemFails := false
+	for _, r := range results {
+		if r.Error == "" {
+			working = append(working, r.Resolver)
+			continue
+		}
+		failing = append(failing, r.Resolver)
+		if r.Resolver == resolverSystem {
+			systemFails = true
+		}
+	}
+
+	switch {
+	case len(failing) == 0:
+		return "all resolvers work", true
+	case len(working) == 0:
+		return "no resolver can resolve the collector host, check the collector URL and the network connectivity", false
+	case systemFails:
+		return fmt.Sprintf("system DNS fails but %s works, check %s", strings.Join(working, ", "), systemDNSSettings()), false
+	}
+	return fmt.Sprintf("%s work but %s fail, expected when outbound DNS is restricted",
+		strings.Join(working, ", "), strings.Join(failing, ", ")), true
+}
+
+func systemDNSSettings() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "the network adapter DNS servers"
+	case "darwin":
+		return "the DNS servers in the network settings (scutil --dns)"
+	}
+	return "/etc/resolv.conf"
+}
+
+// newLookup returns the lookup function for the resolver.
+func newLookup(resolver string, timeout time.Duration) lookupFn {
+	switch {
+	case resolver == resolverSystem:
+		return net.DefaultResolver.LookupHost
+	case resolver == resolverGo:
+		return (&net.Resolver{PreferGo: true}).LookupHost
+	case strings.HasPrefix(resolver, "https://"):
+		return dohLookup(resolver, &http.Client{Timeout: timeout})
+	}
+
+	server := resolver
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, network, server)
+		},
+	}
+	return r.LookupHost
+}
+
+// dohLookup resolves hosts through DNS over HTTPS (RFC 8484), querying both A and AAAA records.
+func dohLookup(dohURL string, client *http.Client) lookupFn {
+	return func(ctx context.Context, host string) ([]string, error) {
+		var addrs []string
+		var errs []string
+		for _, qType := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			found, err := dohQuery(ctx, client, dohURL, host, qType)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			addrs = append(addrs, found...)
+		}
+		if len(addrs) == 0 && len(errs) > 0 {
+			return nil, errors.New(strings.Join(errs, "; "))
+		}
+		return addrs, nil
+	}
+}
+
+func dohQuery(ctx context.Context, client *http.Client, dohURL, host string, qType dnsmessage.Type) ([]string, error) {
+	name, err := dnsmessage.NewName(fqdn(host))
+	if err != nil {
+		return nil, err
+	}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qType, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dohURL, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohContentType)
+	req.Header.Set("Accept", dohContentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS over HTTPS query failed: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	var answer dnsmessage.Message
+	if err = answer.Unpack(body); err != nil {
+		return nil, fmt.Errorf("invalid DNS over HTTPS response: %w", err)
+	}
+	if answer.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("DNS over HTTPS query failed: %s", answer.RCode)
+	}
+
+	var addrs []string
+	for _, a := range answer.Answers {
+		switch r := a.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, net.IP(r.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, net.IP(r.AAAA[:]).String())
+		}
+	}
+	return addrs, nil
+}
+
+// fqdn returns the fully qualified name for the host.
+func fqdn(host string) string {
+	if strings.HasSuffix(host, ".") {
+		return host
+	}
+	return host + "."
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var testAddr = [4]byte{10, 0, 0, 1}
+
+// answer replies A queries with testAddr and any other query with no records.
+func answer(t *testing.T, query []byte) []byte {
+	t.Helper()
+
+	var msg dnsmessage.Message
+	require.NoError(t, msg.Unpack(query))
+	msg.Header.Response = true
+	for _, q := range msg.Questions {
+		if q.Type == dnsmessage.TypeA {
+			msg.Answers = append(msg.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+				Body:   &dnsmessage.AResource{A: testAddr},
+			})
+		}
+	}
+	packed, err := msg.Pack()
+	require.NoError(t, err)
+	return packed
+}
+
+// dnsServer serves DNS queries over UDP, returning its address.
+func dnsServer(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(answer(t, buf[:n]), addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func dohServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != dohContentType {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		query, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", dohContentType)
+		_, _ = w.Write(answer(t, query))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNewLookup_DNSServer(t *testing.T) {
+	addrs, err := newLookup(dnsServer(t), time.Second)(context.Background(), "collector.test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+}
+
+func TestNewLookup_DoH(t *testing.T) {
+	srv := dohServer(t)
+	// DoH URLs are https only, so the plain http test server is queried directly
+	addrs, err := dohLookup(srv.URL, srv.Client())(context.Background(), "collector.test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	_, err = dohLookup(notFound.URL, notFound.Client())(context.Background(), "collector.test")
+	assert.EqualError(t, err, "DNS over HTTPS query failed: 404 Not Found; DNS over HTTPS query failed: 404 Not Found")
+}
+
+func TestCheckResolvers(t *testing.T) {
+	working := dnsServer(t)
+
+	// nothing listening on a closed UDP port
+	closed, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	failing := closed.LocalAddr().String()
+	require.NoError(t, closed.Close())
+
+	tgt := target{url: "https://collector.test", timeout: time.Second, logger: testLogger}
+	WithResolvers([]string{working, failing})(&tgt)
+
+	result := checkResolvers(tgt)
+	assert.True(t, result.Success, result.Error)
+	assert.Equal(t, "collector.test", result.Details["host"])
+	assert.Equal(t, working+" work but "+failing+" fail, expected when outbound DNS is restricted", result.Details["verdict"])
+
+	results := result.Details["resolvers"].([]resolverResult)
+	require.Len(t, results, 2)
+	assert.Equal(t, []string{"10.0.0.1"}, results[0].Addresses)
+	assert.NotEmpty(t, results[1].Error)
+}
+
+func TestCheckResolvers_SkipIP(t *testing.T) {
+	result := checkResolvers(target{url: "http://127.0.0.1:8080", timeout: time.Second, logger: testLogger})
+	assert.True(t, result.Success)
+	assert.NotEmpty(t, result.Details["skipped"])
+}
+
+func TestResolversVerdict(t *testing.T) {
+	ok := resolverResult{Addresses: []string{"10.0.0.1"}}
+	ko := resolverResult{Error: "no such host"}
+	with := func(r resolverResult, name string) resolverResult {
+		r.Resolver = name
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		results []resolverResult
+		verdict string
+		ok      bool
+	}{
+		{"all work", []resolverResult{with(ok, "system"), with(ok, "1.1.1.1")}, "all resolvers work", true},
+		{"none work", []resolverResult{with(ko, "system"), with(ko, "1.1.1.1")}, "no resolver can resolve the collector host, check the collector URL and the network connectivity", false},
+		{"system fails", []resolverResult{with(ko, "system"), with(ok, "1.1.1.1")}, "system DNS fails but 1.1.1.1 works, check " + systemDNSSettings(), false},
+		{"public fail", []resolverResult{with(ok, "system"), with(ko, "1.1.1.1"), with(ko, "8.8.8.8")}, "system work but 1.1.1.1, 8.8.8.8 fail, expected when outbound DNS is restricted", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, ok := resolversVerdict(tt.results)
+			assert.Equal(t, tt.verdict, verdict)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"errors"
+	"syscall"
+)
+
+const (
+	// ipDontFragment is the IP_DONTFRAGMENT socket option, not exposed by syscall nor x/sys/windows.
+	ipDontFragment = 14
+	// wsaeConnRefused is the WSAECONNREFUSED Winsock error, syscall.ECONNREFUSED is not returned on Windows.
+	wsaeConnRefused = syscall.Errno(10061)
+)
+
+// setDontFragment forbids IPv4 fragmentation, so packets larger than the path MTU are dropped instead of split.
+// IPv6 routers never fragment, so nothing is needed there.
+func setDontFragment(network, _ string, c syscall.RawConn) error {
+	if network != "tcp4" {
+		return nil
+	}
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(syscall.Handle(fd), syscall.IPPROTO_IP, ipDontFragment, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// setTTL sets the IPv4 time to live of the connection packets, so they expire after ttl hops.
+func setTTL(ttl int) func(network, address string, c syscall.RawConn) error {
+	return func(_, _ string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = syscall.SetsockoptInt(syscall.Handle(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
+
+// isConnRefused returns whether the destination refused the connection.
+func isConnRefused(err error) bool {
+	return errors.Is(err, wsaeConnRefused)
+}
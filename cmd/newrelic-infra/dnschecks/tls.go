@@ -0,0 +1,203 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// expiryWarningDays is the remaining validity below which the collector certificate is reported as close to expire.
+const expiryWarningDays = 14
+
+// systemRoots returns the host trusted CAs, replaceable for testing.
+var systemRoots = x509.SystemCertPool
+
+// certInfo describes a certificate of the chain presented by the collector endpoint.
+type certInfo struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	IsCA        bool      `json:"isCA"`
+	Fingerprint string    `json:"sha256Fingerprint"`
+}
+
+// WithCABundle provides the agent CA bundle configuration, so the TLS check can tell whether the collector
+// certificate is only trusted because of the custom CAs.
+func WithCABundle(caBundleFile, caBundleDir string) Option {
+	return func(t *target) {
+		t.caBundleFile = caBundleFile
+		t.caBundleDir = caBundleDir
+	}
+}
+
+// checkTLSHandshake performs a bare TLS handshake against the collector, reporting the negotiated parameters and
+// the certificate chain. Collector certificates are issued by public CAs, so a chain not trusted by the system
+// CAs usually means a TLS inspection proxy re-signed it.
+func checkTLSHandshake(t target) CheckResult {
+	return runCheck(t, "TLS handshake", func(result *CheckResult) error {
+		u, err := url.Parse(t.url)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "https" {
+			result.AddDetail("skipped", "collector URL is not https")
+			return nil
+		}
+		addr, err := hostPort(t.url)
+		if err != nil {
+			return err
+		}
+
+		// certificates are verified below, to report why they're not trusted instead of failing the handshake
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: t.timeout}, "tcp", addr, &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: true, //nolint:gosec
+		})
+		if err != nil {
+			return fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		state := conn.ConnectionState()
+		_ = conn.Close()
+
+		result.AddDetail("tlsVersion", tls.VersionName(state.Version))
+		result.AddDetail("cipherSuite", tls.CipherSuiteName(state.CipherSuite))
+		if len(state.PeerCertificates) == 0 {
+			return errors.New("no certificates presented by the collector")
+		}
+
+		chain := make([]certInfo, 0, len(state.PeerCertificates))
+		for _, cert := range state.PeerCertificates {
+			chain = append(chain, newCertInfo(cert))
+		}
+		result.AddDetail("certificateChain", chain)
+
+		leaf := state.PeerCertificates[0]
+		daysToExpire := int(time.Until(leaf.NotAfter).Hours() / 24)
+		result.AddDetail("daysToExpire", daysToExpire)
+		result.AddDetail("expiresSoon", daysToExpire < expiryWarningDays)
+
+		return verifyChain(t, u.Hostname(), state.PeerCertificates, result)
+	})
+}
+
+// verifyChain verifies the chain against the system CAs and, otherwise, against the agent CA bundle.
+func verifyChain(t target, host string, certs []*x509.Certificate, result *CheckResult) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	leaf := certs[0]
+
+	var systemErr error
+	roots, err := systemRoots()
+	if err != nil {
+		systemErr = fmt.Errorf("cannot load system CAs: %w", err)
+	} else {
+		_, systemErr = leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots, Intermediates: intermediates})
+	}
+	result.AddDetail("trustedBySystem", systemErr == nil)
+	if systemErr == nil {
+		result.AddDetail("interceptionSuspected", false)
+		return nil
+	}
+
+	// a hostname mismatch or an expired certificate is not solved by trusting other CAs
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(systemErr, &hostErr) || errors.As(systemErr, &invalidErr) {
+		return systemErr
+	}
+
+	result.AddDetail("interceptionSuspected", true)
+	result.AddDetail("issuer", leaf.Issuer.String())
+
+	customRoots, err := caBundlePool(t.caBundleFile, t.caBundleDir)
+	if err != nil {
+		return err
+	}
+	if customRoots == nil {
+		return fmt.Errorf("certificate not trusted, TLS interception suspected (issued by %q): %w", leaf.Issuer.String(), systemErr)
+	}
+	if _, err = leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: customRoots, Intermediates: intermediates}); err != nil {
+		return fmt.Errorf("certificate not trusted by system CAs nor CA bundle, TLS interception suspected (issued by %q): %w", leaf.Issuer.String(), err)
+	}
+	result.AddDetail("trustedByCABundle", true)
+	return nil
+}
+
+// caBundlePool loads the agent CA bundle, returning nil when no bundle is configured.
+func caBundlePool(caBundleFile, caBundleDir string) (*x509.CertPool, error) {
+	if caBundleFile == "" && caBundleDir == "" {
+		return nil, nil
+	}
+	pool := x509.NewCertPool()
+	if err := appendCABundle(pool, caBundleFile, caBundleDir); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// agentRoots returns the CAs trusted by the agent: the system ones along with the agent CA bundle.
+func agentRoots(t target) (*x509.CertPool, error) {
+	pool, err := systemRoots()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if err = appendCABundle(pool, t.caBundleFile, t.caBundleDir); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// appendCABundle adds the agent CA bundle certificates to the pool.
+func appendCABundle(pool *x509.CertPool, caBundleFile, caBundleDir string) error {
+	files := []string{}
+	if caBundleFile != "" {
+		files = append(files, caBundleFile)
+	}
+	if caBundleDir != "" {
+		entries, err := os.ReadDir(caBundleDir)
+		if err != nil {
+			return fmt.Errorf("cannot read CA bundle directory: %w", err)
+		}
+		for _, e := range entries {
+			if strings.Contains(e.Name(), ".pem") {
+				files = append(files, filepath.Join(caBundleDir, e.Name()))
+			}
+		}
+	}
+
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("cannot read CA bundle file: %w", err)
+		}
+		pool.AppendCertsFromPEM(content)
+	}
+	return nil
+}
+
+func newCertInfo(cert *x509.Certificate) certInfo {
+	fingerprint := sha256.Sum256(cert.Raw)
+	return certInfo{
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		IsCA:        cert.IsCA,
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+	}
+}
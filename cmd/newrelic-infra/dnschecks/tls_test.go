@@ -0,0 +1,73 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withSystemRoots(t *testing.T, pool *x509.CertPool) {
+	t.Helper()
+	prev := systemRoots
+	systemRoots = func() (*x509.CertPool, error) { return pool, nil }
+	t.Cleanup(func() { systemRoots = prev })
+}
+
+func TestCheckTLSHandshake_TrustedBySystem(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	withSystemRoots(t, pool)
+
+	result := checkTLSHandshake(target{url: srv.URL, timeout: time.Second, logger: testLogger})
+	assert.True(t, result.Success, result.Error)
+	assert.Equal(t, true, result.Details["trustedBySystem"])
+	assert.Equal(t, false, result.Details["interceptionSuspected"])
+	assert.NotEmpty(t, result.Details["tlsVersion"])
+	assert.NotEmpty(t, result.Details["cipherSuite"])
+	require.Len(t, result.Details["certificateChain"], 1)
+	chain := result.Details["certificateChain"].([]certInfo)
+	assert.Equal(t, srv.Certificate().NotAfter, chain[0].NotAfter)
+	assert.Len(t, chain[0].Fingerprint, 64)
+}
+
+func TestCheckTLSHandshake_Intercepted(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	withSystemRoots(t, x509.NewCertPool())
+
+	result := checkTLSHandshake(target{url: srv.URL, timeout: time.Second, logger: testLogger})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "TLS interception suspected")
+	assert.Equal(t, false, result.Details["trustedBySystem"])
+	assert.Equal(t, true, result.Details["interceptionSuspected"])
+
+	// the re-signing CA is trusted through the agent CA bundle
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(bundle, pemCert, 0o600))
+
+	result = checkTLSHandshake(target{url: srv.URL, timeout: time.Second, logger: testLogger, caBundleFile: bundle})
+	assert.True(t, result.Success, result.Error)
+	assert.Equal(t, true, result.Details["trustedByCABundle"])
+	assert.Equal(t, true, result.Details["interceptionSuspected"])
+}
+
+func TestCheckTLSHandshake_NotHTTPS(t *testing.T) {
+	result := checkTLSHandshake(target{url: "http://localhost", timeout: time.Second, logger: testLogger})
+	assert.True(t, result.Success)
+	assert.NotEmpty(t, result.Details["skipped"])
+}
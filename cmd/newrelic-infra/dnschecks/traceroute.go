@@ -0,0 +1,208 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	tracerouteMaxHops    = 30
+	tracerouteHopTimeout = 2 * time.Second
+
+	protocolICMP = 1
+	protocolTCP  = 6
+)
+
+// listenICMP opens the socket receiving the ICMP replies from the routers, replaceable for testing.
+var listenICMP = func() (net.PacketConn, error) {
+	return icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+}
+
+// hop is a router, or the collector itself, in the path to the collector.
+type hop struct {
+	TTL int `json:"ttl"`
+	// Address is empty when the hop didn't reply
+	Address     string  `json:"address,omitempty"`
+	RTTMs       float64 `json:"rttMs,omitempty"`
+	Unreachable bool    `json:"unreachable,omitempty"`
+	Reached     bool    `json:"reached,omitempty"`
+}
+
+// hopReply is the router ICMP reply to an expired connection attempt.
+type hopReply struct {
+	from        string
+	unreachable bool
+}
+
+// WithTraceroute enables tracing the route to the collector when it's not reachable.
+func WithTraceroute(enabled bool) Option {
+	return func(t *target) {
+		t.traceroute = enabled
+	}
+}
+
+// checkTraceroute opens TCP connections to the collector with increasing TTLs, recording the routers replying with
+// ICMP time exceeded, to find where packets die. Reading ICMP messages requires administrator privileges.
+func checkTraceroute(t target) CheckResult {
+	return runCheck(t, "TCP traceroute", func(result *CheckResult) error {
+		addr, err := hostPort(t.url)
+		if err != nil {
+			return err
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+		ips, err := lookupIP(ctx, "ip4", host)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("cannot resolve IPv4 address: %w", err)
+		}
+		if len(ips) == 0 {
+			return errors.New("no IPv4 address to trace the route to")
+		}
+		result.AddDetail("destination", net.JoinHostPort(ips[0].String(), port))
+
+		icmpConn, err := listenICMP()
+		if err != nil {
+			return fmt.Errorf("cannot read ICMP messages, traceroute requires administrator privileges: %w", err)
+		}
+		defer icmpConn.Close()
+
+		hops := traceroute(icmpConn, ips[0], port, tracerouteMaxHops, tracerouteHopTimeout)
+		result.AddDetail("hops", hops)
+
+		last := hops[len(hops)-1]
+		if last.Reached {
+			return nil
+		}
+		for i := len(hops) - 1; i >= 0; i-- {
+			if hops[i].Address == "" {
+				continue
+			}
+			if hops[i].Unreachable {
+				return fmt.Errorf("collector not reached, hop %d (%s) reported it as unreachable", hops[i].TTL, hops[i].Address)
+			}
+			return fmt.Errorf("collector not reached, packets die after hop %d (%s)", hops[i].TTL, hops[i].Address)
+		}
+		return errors.New("collector not reached, no hop replied")
+	})
+}
+
+// traceroute probes the hops to the destination, until it's reached, a router reports it unreachable or
+// maxHops are probed.
+func traceroute(icmpConn net.PacketConn, ip net.IP, port string, maxHops int, hopTimeout time.Duration) []hop {
+	dst := net.JoinHostPort(ip.String(), port)
+	dstPort, _ := strconv.Atoi(port)
+
+	var hops []hop
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		h := hop{TTL: ttl}
+
+		ctx, cancel := context.WithTimeout(context.Background(), hopTimeout)
+		_ = icmpConn.SetReadDeadline(time.Now().Add(hopTimeout))
+		replies := make(chan hopReply, 1)
+		go func() {
+			r := readHopReply(icmpConn, ip, dstPort)
+			if r.from != "" {
+				// no need to wait for the connection timeout
+				cancel()
+			}
+			replies <- r
+		}()
+
+		start := time.Now()
+		dialer := &net.Dialer{Control: setTTL(ttl)}
+		conn, err := dialer.DialContext(ctx, "tcp4", dst)
+		rtt := float64(time.Since(start)) / float64(time.Millisecond)
+		// a refused connection was also replied by the destination
+		if err == nil || isConnRefused(err) {
+			if conn != nil {
+				_ = conn.Close()
+			}
+			h.Address = ip.String()
+			h.RTTMs = rtt
+			h.Reached = true
+		}
+		// stop waiting for ICMP replies
+		_ = icmpConn.SetReadDeadline(time.Now())
+		reply := <-replies
+		_ = icmpConn.SetReadDeadline(time.Time{})
+		cancel()
+
+		if !h.Reached && reply.from != "" {
+			h.Address = reply.from
+			h.RTTMs = rtt
+			h.Unreachable = reply.unreachable
+		}
+		hops = append(hops, h)
+		if h.Reached || h.Unreachable {
+			break
+		}
+	}
+	return hops
+}
+
+// readHopReply waits until the connection read deadline for an ICMP time exceeded or destination unreachable
+// message about a packet to the destination.
+func readHopReply(icmpConn net.PacketConn, ip net.IP, port int) hopReply {
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := icmpConn.ReadFrom(buf)
+		if err != nil {
+			return hopReply{}
+		}
+		unreachable, ok := matchICMPReply(buf[:n], ip, port)
+		if !ok {
+			continue
+		}
+		r := hopReply{from: from.String(), unreachable: unreachable}
+		if ipAddr, ok := from.(*net.IPAddr); ok {
+			r.from = ipAddr.IP.String()
+		}
+		return r
+	}
+}
+
+// matchICMPReply returns whether the ICMP message replies a TCP packet sent to ip:port, and whether it reports
+// the destination as unreachable, rather than the packet expired.
+func matchICMPReply(b []byte, ip net.IP, port int) (unreachable bool, ok bool) {
+	msg, err := icmp.ParseMessage(protocolICMP, b)
+	if err != nil {
+		return false, false
+	}
+
+	var original []byte
+	switch body := msg.Body.(type) {
+	case *icmp.TimeExceeded:
+		original = body.Data
+	case *icmp.DstUnreach:
+		original, unreachable = body.Data, true
+	default:
+		return false, false
+	}
+
+	// the original IP header and the first 8 bytes of its payload
+	header, err := ipv4.ParseHeader(original)
+	if err != nil || header.Protocol != protocolTCP || !header.Dst.Equal(ip) {
+		return false, false
+	}
+	if len(original) < header.Len+4 {
+		return false, false
+	}
+	dstPort := binary.BigEndian.Uint16(original[header.Len+2 : header.Len+4])
+	return unreachable, int(dstPort) == port
+}
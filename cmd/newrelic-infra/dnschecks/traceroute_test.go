@@ -0,0 +1,217 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dnschecks
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+type icmpPacket struct {
+	data []byte
+	from net.Addr
+}
+
+// fakeICMPConn returns the queued packets, honoring the read deadline.
+type fakeICMPConn struct {
+	net.PacketConn
+
+	lock     sync.Mutex
+	deadline time.Time
+	packets  chan icmpPacket
+}
+
+func newFakeICMPConn(packets ...icmpPacket) *fakeICMPConn {
+	c := &fakeICMPConn{packets: make(chan icmpPacket, len(packets))}
+	for _, p := range packets {
+		c.packets <- p
+	}
+	return c
+}
+
+func (c *fakeICMPConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		select {
+		case p := <-c.packets:
+			return copy(b, p.data), p.from, nil
+		default:
+		}
+		c.lock.Lock()
+		deadline := c.deadline
+		c.lock.Unlock()
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return 0, nil, os.ErrDeadlineExceeded
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *fakeICMPConn) SetReadDeadline(t time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *fakeICMPConn) Close() error {
+	return nil
+}
+
+// icmpReply builds the ICMP message a router sends when a TCP packet to dst:port expires or can't be delivered.
+func icmpReply(t *testing.T, icmpType ipv4.ICMPType, dst net.IP, port int) []byte {
+	t.Helper()
+
+	header := &ipv4.Header{
+		Version:  ipv4.Version,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + 8,
+		TTL:      1,
+		Protocol: protocolTCP,
+		Src:      net.ParseIP("192.168.1.10"),
+		Dst:      dst,
+	}
+	original, err := header.Marshal()
+	require.NoError(t, err)
+	tcp := make([]byte, 8)
+	binary.BigEndian.PutUint16(tcp[0:2], 50000)
+	binary.BigEndian.PutUint16(tcp[2:4], uint16(port))
+	original = append(original, tcp...)
+
+	var body icmp.MessageBody = &icmp.TimeExceeded{Data: original}
+	if icmpType == ipv4.ICMPTypeDestinationUnreachable {
+		body = &icmp.DstUnreach{Data: original}
+	}
+	msg, err := (&icmp.Message{Type: icmpType, Body: body}).Marshal(nil)
+	require.NoError(t, err)
+	return msg
+}
+
+func TestMatchICMPReply(t *testing.T) {
+	dst := net.ParseIP("10.0.0.1")
+
+	unreachable, ok := matchICMPReply(icmpReply(t, ipv4.ICMPTypeTimeExceeded, dst, 443), dst, 443)
+	assert.True(t, ok)
+	assert.False(t, unreachable)
+
+	unreachable, ok = matchICMPReply(icmpReply(t, ipv4.ICMPTypeDestinationUnreachable, dst, 443), dst, 443)
+	assert.True(t, ok)
+	assert.True(t, unreachable)
+
+	_, ok = matchICMPReply(icmpReply(t, ipv4.ICMPTypeTimeExceeded, dst, 80), dst, 443)
+	assert.False(t, ok, "other destination port")
+
+	_, ok = matchICMPReply(icmpReply(t, ipv4.ICMPTypeTimeExceeded, net.ParseIP("10.0.0.2"), 443), dst, 443)
+	assert.False(t, ok, "other destination address")
+
+	echo, err := (&icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: &icmp.Echo{ID: 1, Seq: 1}}).Marshal(nil)
+	require.NoError(t, err)
+	_, ok = matchICMPReply(echo, dst, 443)
+	assert.False(t, ok, "not a reply to the connection packets")
+}
+
+func TestReadHopReply(t *testing.T) {
+	dst := net.ParseIP("10.0.0.1")
+	router := &net.IPAddr{IP: net.ParseIP("192.168.1.1")}
+	conn := newFakeICMPConn(
+		icmpPacket{data: icmpReply(t, ipv4.ICMPTypeTimeExceeded, dst, 80), from: &net.IPAddr{IP: net.ParseIP("192.168.1.2")}},
+		icmpPacket{data: icmpReply(t, ipv4.ICMPTypeTimeExceeded, dst, 443), from: router},
+	)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	assert.Equal(t, hopReply{from: "192.168.1.1"}, readHopReply(conn, dst, 443))
+	assert.Equal(t, hopReply{}, readHopReply(conn, dst, 443), "no more replies before the deadline")
+}
+
+func TestTraceroute_Reached(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	hops := traceroute(newFakeICMPConn(), net.ParseIP("127.0.0.1"), port, 5, time.Second)
+	require.Len(t, hops, 1, "loopback is one hop away")
+	assert.True(t, hops[0].Reached)
+	assert.Equal(t, "127.0.0.1", hops[0].Address)
+
+	// refused connections are replied by the destination too
+	srv.Close()
+	hops = traceroute(newFakeICMPConn(), net.ParseIP("127.0.0.1"), port, 5, time.Second)
+	require.Len(t, hops, 1)
+	assert.True(t, hops[0].Reached)
+}
+
+func TestTraceroute_Unreachable(t *testing.T) {
+	// the router reply arrives before the connection attempt, which expires
+	dst := net.ParseIP("192.0.2.1")
+	router := &net.IPAddr{IP: net.ParseIP("192.168.1.1")}
+	conn := newFakeICMPConn(icmpPacket{data: icmpReply(t, ipv4.ICMPTypeDestinationUnreachable, dst, 443), from: router})
+
+	hops := traceroute(conn, dst, "443", 5, 200*time.Millisecond)
+	require.NotEmpty(t, hops)
+	last := hops[len(hops)-1]
+	if last.Address == "" {
+		t.Skip("no route to the test network, connection failed before reading the router reply")
+	}
+	assert.Equal(t, "192.168.1.1", last.Address)
+	assert.True(t, last.Unreachable)
+	assert.False(t, last.Reached)
+}
+
+func TestCheckTraceroute(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	prev := listenICMP
+	t.Cleanup(func() { listenICMP = prev })
+
+	listenICMP = func() (net.PacketConn, error) {
+		return nil, errors.New("operation not permitted")
+	}
+	result := checkTraceroute(target{url: srv.URL, timeout: time.Second, logger: testLogger})
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "traceroute requires administrator privileges")
+
+	listenICMP = func() (net.PacketConn, error) {
+		return newFakeICMPConn(), nil
+	}
+	result = checkTraceroute(target{url: srv.URL, timeout: time.Second, logger: testLogger})
+	assert.True(t, result.Success, result.Error)
+	assert.Equal(t, srv.Listener.Addr().String(), result.Details["destination"])
+	assert.Len(t, result.Details["hops"], 1)
+}
+
+func TestRunChecks_Traceroute(t *testing.T) {
+	clearProxyEnv(t)
+	prev := listenICMP
+	t.Cleanup(func() { listenICMP = prev })
+	listenICMP = func() (net.PacketConn, error) {
+		return newFakeICMPConn(), nil
+	}
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	report, err := RunChecks(srv.URL, "1s", http.DefaultTransport, testLogger, WithTraceroute(true))
+	require.NoError(t, err)
+	assert.NotEqual(t, "TCP traceroute", report.Checks[len(report.Checks)-1].Name, "only traced when unreachable")
+
+	srv.Close()
+	report, err = RunChecks("http://127.0.0.1:"+strconv.Itoa(port), "1s", http.DefaultTransport, testLogger, WithTraceroute(true))
+	require.NoError(t, err)
+	assert.Equal(t, "TCP traceroute", report.Checks[len(report.Checks)-1].Name)
+}
@@ -23,21 +23,36 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/newrelic/infrastructure-agent/cmd/newrelic-infra/configcheck"
+	"github.com/newrelic/infrastructure-agent/cmd/newrelic-infra/diagnose"
 	"github.com/newrelic/infrastructure-agent/cmd/newrelic-infra/dnschecks"
 	"github.com/newrelic/infrastructure-agent/cmd/newrelic-infra/initialize"
 	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/agent/capabilities"
 	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel"
 	ccBackoff "github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel/backoff"
 	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel/fflag"
+	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel/remotecmd"
+	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel/rundiagnostics"
 	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel/runintegration"
 	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel/service"
 	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel/stopintegration"
+	"github.com/newrelic/infrastructure-agent/internal/agent/customattributes"
+	"github.com/newrelic/infrastructure-agent/internal/agent/delta"
+	"github.com/newrelic/infrastructure-agent/internal/agent/entityname"
+	"github.com/newrelic/infrastructure-agent/internal/agent/governor"
+	"github.com/newrelic/infrastructure-agent/internal/agent/id"
 	selfInstrumentation "github.com/newrelic/infrastructure-agent/internal/agent/instrumentation"
+	"github.com/newrelic/infrastructure-agent/internal/agent/leader"
+	"github.com/newrelic/infrastructure-agent/internal/agent/metadata"
+	"github.com/newrelic/infrastructure-agent/internal/agent/remoteconfig"
 	"github.com/newrelic/infrastructure-agent/internal/agent/status"
 	"github.com/newrelic/infrastructure-agent/internal/feature_flags"
+	"github.com/newrelic/infrastructure-agent/internal/fips"
 	"github.com/newrelic/infrastructure-agent/internal/httpapi"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/files"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/integration"
+	v4runner "github.com/newrelic/infrastructure-agent/internal/integrations/v4/runner"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/v3legacy"
 	"github.com/newrelic/infrastructure-agent/internal/socketapi"
 	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
@@ -45,10 +60,13 @@ import (
 	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
 	"github.com/newrelic/infrastructure-agent/pkg/backend/identityapi"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
+	ctlsender "github.com/newrelic/infrastructure-agent/pkg/ctl/sender"
 	"github.com/newrelic/infrastructure-agent/pkg/disk"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	"github.com/newrelic/infrastructure-agent/pkg/fs/systemd"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers/recover"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/redact"
 	http2 "github.com/newrelic/infrastructure-agent/pkg/http"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/legacy"
@@ -58,25 +76,35 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/dm"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/emitter"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/logs"
+	"github.com/newrelic/infrastructure-agent/pkg/ipc"
 	wlog "github.com/newrelic/infrastructure-agent/pkg/log"
 	logFilter "github.com/newrelic/infrastructure-agent/pkg/log/filter"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sampler"
+	metricsSender "github.com/newrelic/infrastructure-agent/pkg/metrics/sender"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
 	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
 	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/hostname"
 )
 
 var (
 	dryRun bool
+	// Runs the samplers one time when running in dry-run mode, instead of the integrations.
+	once bool
 
 	// Specifies the path to look for integrations config files when running in dry-run mode.
 	integrationConfigPath string
 
-	configFile  string
-	validate    bool
-	showVersion bool
-	debug       bool
-	cpuprofile  string
-	memprofile  string
+	configFile     string
+	validate       bool
+	checkConfig    bool
+	resetID        bool
+	diagnoseAgent  bool
+	diagnoseOutput string
+	showVersion    bool
+	debug          bool
+	cpuprofile     string
+	memprofile     string
 	// v3tov4       string # v3tov4 disabled.
 	verbose      int
 	startTime    time.Time
@@ -92,10 +120,16 @@ func elapsedTime() time.Duration {
 
 func init() {
 	flag.BoolVar(&dryRun, "dry_run", false, "Run the NR Infrastructure agent in dry_run mode.")
+	flag.BoolVar(&dryRun, "dry-run", false, "Same as -dry_run.")
+	flag.BoolVar(&once, "once", false, "In dry-run mode, run all the enabled samplers one time and print their events to stdout as JSON, one per line, without contacting New Relic")
 
 	flag.StringVar(&integrationConfigPath, "integration_config_path", "", "Path of the newrelic integrations configuration files when running in dry-run mode. Can be a file or a directory. (Default: plugin_dir)")
 	flag.StringVar(&configFile, "config", "", "Overrides default configuration file")
 	flag.BoolVar(&validate, "validate", false, "Validate agent config and exit")
+	flag.BoolVar(&checkConfig, "validate-config", false, "Validate the agent and integrations configuration files, report the problems found and exit non-zero if any")
+	flag.BoolVar(&diagnoseAgent, "diagnose", false, "Check the configuration, license key, directories permissions and network connectivity, write a support bundle with sensitive data redacted and exit. Same as newrelic-infra-ctl diagnose")
+	flag.StringVar(&diagnoseOutput, "diagnose-output", "", "Support bundle file written by -diagnose (Default: a timestamped zip file in the current directory)")
+	flag.BoolVar(&resetID, "reset-identity", false, "Remove the agent identity stored in the data directory, ie: after cloning a VM image, so a new one is registered on the next start, and exit. The agent must be stopped")
 	flag.BoolVar(&showVersion, "version", false, "Shows version details")
 	flag.BoolVar(&debug, "debug", false, "Enables agent debugging functionality")
 	flag.StringVar(&cpuprofile, "cpuprofile", "", "Writes cpu profile to `file`")
@@ -118,8 +152,28 @@ func main() {
 	wlog.SetOutput(memLog)
 
 	if showVersion {
-		fmt.Printf("New Relic Infrastructure Agent version: %s, GoVersion: %s, GitCommit: %s, BuildDate: %s\n",
+		fmt.Printf("New Relic Infrastructure Agent version: %s, GoVersion: %s, GitCommit: %s, BuildDate: %s",
 			buildVersion, runtime.Version(), gitCommit, buildDate)
+		if status := fips.Status(); status.Build {
+			fmt.Printf(", FIPS: %s (enabled: %t)", status.Backend, status.Enabled)
+		}
+		fmt.Println()
+		os.Exit(0)
+	}
+
+	if checkConfig {
+		os.Exit(validateConfig(os.Stdout, configFile))
+	}
+
+	if resetID {
+		os.Exit(resetIdentity(os.Stdout, configFile))
+	}
+
+	if diagnoseAgent {
+		if err := diagnose.Run(os.Stdout, configFile, diagnoseOutput, true); err != nil {
+			fmt.Fprintf(os.Stdout, "cannot diagnose the agent: %s\n", err)
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
@@ -196,20 +250,34 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = fips.Verify(cfg.FIPSMode); err != nil {
+		alog.WithError(err).Error("can't verify FIPS mode")
+		os.Exit(1)
+	}
+	if status := fips.Status(); status.Enabled {
+		alog.WithField("backend", status.Backend).Info("Running in FIPS mode.")
+	}
+
+	if once && !dryRun {
+		alog.Error("-once requires -dry-run")
+		os.Exit(1)
+	}
+
+	if dryRun && once {
+		os.Exit(executeSamplersDryRunMode(os.Stdout, cfg))
+	}
+
 	if dryRun {
 		executeIntegrationsDryRunMode(integrationConfigPath, cfg)
 		os.Exit(0)
 	}
 
-	// override YAML with CLI flags
-	if verbose > config.NonVerboseLogging {
-		cfg.Verbose = verbose
-	}
-	if cpuprofile != "" {
-		cfg.CPUProfile = cpuprofile
-	}
-	if memprofile != "" {
-		cfg.MemProfile = memprofile
+	overrideConfig(cfg)
+
+	reloader, err := config.NewReloader(cfg, reloadConfig)
+	if err != nil {
+		alog.WithError(err).Error("can't create configuration reloader")
+		os.Exit(1)
 	}
 
 	if cfg.Log.IsSmartLogging() {
@@ -223,10 +291,21 @@ func main() {
 		})
 	}
 
+	if cfg.RedactionEnabled {
+		redactor, err := redact.New(cfg.License, cfg.RedactionPatterns)
+		if err != nil {
+			alog.WithError(err).Error("can't configure the redaction of secrets")
+			os.Exit(1)
+		}
+		redact.SetDefault(redactor)
+	}
+	v4runner.ConfigureQuarantine(cfg.QuarantineThreshold, time.Duration(cfg.QuarantineSec)*time.Second)
+
 	configureLogFormat(cfg.Log)
 
 	// Send logging where it's supposed to go.
 	agentLogsToFile := configureLogRedirection(&cfg.Log, memLog)
+	configureLogSyslog(cfg.Log)
 
 	// Runtime config setup.
 	troubleCfg := config.NewTroubleshootCfg(cfg.Log.IsTroubleshootMode(), agentLogsToFile, cfg.GetLogFile())
@@ -247,17 +326,135 @@ func main() {
 		os.Exit(1)
 	}
 
-	err = initializeAgentAndRun(cfg, logFwCfg)
+	err = initializeAgentAndRun(cfg, logFwCfg, reloader)
 	if err != nil {
 		timedLog.WithError(err).Error("Agent run returned an error.")
 		os.Exit(1)
 	}
 }
 
+// validateConfig prints the problems found in the configuration files, returning the exit code.
+func validateConfig(w io.Writer, configFile string) int {
+	problems, err := configcheck.Check(configFile)
+	if err != nil {
+		fmt.Fprintf(w, "cannot validate the configuration: %s\n", err)
+		return 1
+	}
+	for _, p := range problems {
+		fmt.Fprintln(w, p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "%d configuration problems found\n", len(problems))
+		return 1
+	}
+	fmt.Fprintln(w, "configuration is valid")
+	return 0
+}
+
+// newDMRoutes creates the dimensional metrics senders of the submission routes, indexed by the integrations they
+// route, with their own license key and proxy.
+func newDMRoutes(c *config.Config, idProvide id.Provide) (map[string]dm.MetricsSender, error) {
+	routes := map[string]dm.MetricsSender{}
+	for _, r := range c.SubmissionRoutes {
+		if len(r.Integrations) == 0 {
+			continue
+		}
+		transport := backendhttp.NewReloadableProxyTransport(c, backendhttp.ClientTimeout, r.Proxy)
+		transport = backendhttp.NewRequestDecoratorTransport(c, transport)
+		senderConfig := dm.NewConfig(c.DMIngestURL(), c.Fedramp, r.License, time.Duration(c.DMSubmissionPeriod)*time.Second, c.MaxMetricBatchEntitiesCount, c.MaxMetricBatchEntitiesQueue)
+		sender, err := dm.NewDMSender(senderConfig, transport, idProvide)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create the metrics sender of submission route %s: %w", r.Name, err)
+		}
+		for _, name := range r.Integrations {
+			// the first route matching an integration prevails
+			if _, ok := routes[name]; !ok {
+				routes[name] = sender
+			}
+		}
+	}
+	return routes, nil
+}
+
+// resetIdentityTimeout bounds checking whether the agent is running through its control socket.
+const resetIdentityTimeout = 5 * time.Second
+
+// resetIdentity removes the agent identity stored in the data directory, returning the exit code. It refuses to
+// run while the agent is running, as it would store its identity again.
+func resetIdentity(w io.Writer, configFile string) int {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintf(w, "cannot load the configuration: %s\n", err)
+		return 1
+	}
+
+	if cfg.ControlSocketEnabled {
+		ctx, cancel := context2.WithTimeout(context2.Background(), resetIdentityTimeout)
+		running := ctlsender.NewControlClient(cfg.ControlSocket).Reachable(ctx)
+		cancel()
+		if running {
+			fmt.Fprintln(w, "the agent is running, stop it before resetting its identity")
+			return 1
+		}
+	}
+
+	dataDir := agent.DataDir(cfg)
+	if storedID, err := delta.StoredLocalEntityID(dataDir); err == nil && !storedID.IsEmpty() {
+		fmt.Fprintf(w, "stored agent ID: %s\n", storedID)
+	}
+
+	removed, err := delta.ResetIdentity(dataDir, cfg.PayloadSpoolDir)
+	for _, path := range removed {
+		fmt.Fprintf(w, "removed %s\n", path)
+	}
+	if err != nil {
+		fmt.Fprintf(w, "cannot reset the agent identity: %s\n", err)
+		return 1
+	}
+	nameFile, err := entityname.ResetState(dataDir)
+	if err != nil {
+		fmt.Fprintf(w, "cannot reset the entity name: %s\n", err)
+		return 1
+	}
+	if nameFile != "" {
+		fmt.Fprintf(w, "removed %s\n", nameFile)
+	}
+
+	fmt.Fprintln(w, "agent identity reset, a new one will be registered on the next start")
+	return 0
+}
+
+// overrideConfig overrides the YAML with the CLI flags.
+func overrideConfig(cfg *config.Config) {
+	if verbose > config.NonVerboseLogging {
+		cfg.Verbose = verbose
+	}
+	if cpuprofile != "" {
+		cfg.CPUProfile = cpuprofile
+	}
+	if memprofile != "" {
+		cfg.MemProfile = memprofile
+	}
+}
+
+// reloadConfig loads the configuration file again, overridden with the CLI flags as on startup.
+func reloadConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	overrideConfig(cfg)
+	return cfg, nil
+}
+
 func logConfig(c *config.Config) {
 	// Log the configuration.
 	c.LogInfo()
 
+	for _, w := range c.Warnings() {
+		alog.WithField("option", w.Key).Warn(w.Message)
+	}
+
 	// Runtime evaluated.
 	alog.WithFieldsF(func() logrus.Fields {
 		fields := logrus.Fields{
@@ -277,7 +474,7 @@ var aslog = wlog.WithComponent("AgentService").WithFields(logrus.Fields{
 	"service": svcName,
 })
 
-func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
+func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward, reloader *config.Reloader) error {
 	pluginSourceDirs := getPluginSourceDirs(c)
 
 	v4ManagerConfig := v4.NewManagerConfig(
@@ -289,7 +486,7 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 	)
 
 	userAgent := agent.GenerateUserAgent("New Relic Infrastructure Agent", buildVersion)
-	transport := backendhttp.BuildTransport(c, backendhttp.ClientTimeout)
+	transport := backendhttp.NewReloadableTransport(c, backendhttp.ClientTimeout)
 	transport = backendhttp.NewRequestDecoratorTransport(c, transport)
 
 	httpClient := backendhttp.GetHttpClient(backendhttp.ClientTimeout, transport)
@@ -297,7 +494,7 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 	cmdChannelURL := strings.TrimSuffix(c.CommandChannelURL, "/")
 	ccSvcURL := fmt.Sprintf("%s%s", cmdChannelURL, c.CommandChannelEndpoint)
 	caClient := commandapi.NewClient(ccSvcURL, c.License, userAgent, httpClient.Do)
-	ffManager := feature_flags.NewManager(c.Features)
+	ffManager := feature_flags.NewManager(c.Features, feature_flags.WithEnvironment(os.Environ()), feature_flags.WithOverrideFile(c.FeatureFlagsFile))
 	il := newInstancesLookup(v4ManagerConfig)
 
 	fatal := func(err error, message string) {
@@ -305,16 +502,50 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 		os.Exit(1)
 	}
 
+	// the custom attributes from commands and files are resolved before they're first reported
+	customAttrs, err := customattributes.NewRefresher(c)
+	if err != nil {
+		fatal(err, "Can't create custom attributes refresher.")
+	}
+	if c.HostEnvironment == "" {
+		c.HostEnvironment = helpers.GetEnvironment()
+	}
+	if c.HostEnvironment != "" {
+		aslog.WithField("environment", c.HostEnvironment).Info("Unsupported host metrics won't be reported in this environment.")
+	}
+	discovered := make(map[string]string)
+	if c.K8sMetadataEnabled && metadata.InKubernetes() {
+		for name, value := range k8sNodeAttributes(c) {
+			discovered[name] = value
+		}
+	}
+	if c.HostEnvironment != "" {
+		discovered[hostEnvironmentAttr] = c.HostEnvironment
+	}
+	customAttrs.SetDiscovered(discovered)
+	customAttrs.Refresh(context2.Background())
+
 	aslog.Info("Checking network connectivity...")
 
+	var networkReport *dnschecks.Report
 	if c.Log.HasIncludeFilter(config.TracesFieldComponent, config.HttpTracer) {
-		err := dnschecks.RunChecks(c.CollectorURL, c.StartupConnectionTimeout, transport, aslog)
+		networkReport, err = dnschecks.RunChecks(c.CollectorURL, c.StartupConnectionTimeout, transport, aslog,
+			dnschecks.WithProxy(c.Proxy, c.IgnoreSystemProxy, c.ProxyValidateCerts),
+			dnschecks.WithCABundle(c.CABundleFile, c.CABundleDir),
+			dnschecks.WithResolvers(c.NetworkChecksResolvers),
+			dnschecks.WithTraceroute(c.NetworkChecksTraceroute),
+			dnschecks.WithBenchmark(c.NetworkChecksBenchmarkRequests))
 		if err != nil {
 			os.Exit(1)
 		}
+		if c.NetworkChecksReportFile != "" {
+			if err = networkReport.WriteFile(c.NetworkChecksReportFile); err != nil {
+				aslog.WithError(err).WithField("file", c.NetworkChecksReportFile).Warn("Cannot write network checks report.")
+			}
+		}
 	}
 
-	err := waitForNetwork(c.CollectorURL, c.StartupConnectionTimeout, c.StartupConnectionRetries, transport)
+	err = waitForNetwork(c.CollectorURL, c.StartupConnectionTimeout, c.StartupConnectionRetries, transport)
 	if err != nil {
 		fatal(err, "Can't reach the New Relic collector.")
 	}
@@ -349,9 +580,20 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 	}
 
 	selfInstrumentation.InitSelfInstrumentation(c, agt.Context.HostnameResolver())
+	if prometheus := agt.Prometheus(); prometheus != nil {
+		selfInstrumentation.ExposeMetrics(prometheus)
+		wlog.Instrument(prometheus.Measure)
+	}
 
 	defer agt.Terminate()
 
+	if c.LeaderElectionDir != "" {
+		hostname, _, _ := agt.Context.HostnameResolver().Query()
+		elector := leader.NewElector(c.LeaderElectionDir, time.Duration(c.LeaderElectionIntervalSec)*time.Second, fmt.Sprintf("%s (pid %d)", hostname, os.Getpid()))
+		leader.SetDefault(elector)
+		defer elector.Close()
+	}
+
 	if err := initialize.AgentService(c); err != nil {
 		fatal(err, "Can't complete platform specific initialization.")
 	}
@@ -367,7 +609,11 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 	// queues config entries requests
 	configEntryQ := make(chan configrequest.Entry, 100)
 
-	dmEmitter := dm.NewEmitter(agt.GetContext(), dmSender, registerClient, ffManager)
+	dmRoutes, err := newDMRoutes(c, agt.Context.IdContext().AgentIdentity)
+	if err != nil {
+		return err
+	}
+	dmEmitter := dm.NewEmitter(agt.GetContext(), dmSender, registerClient, ffManager, dm.WithMetricsRoutes(dmRoutes))
 
 	// track stoppable integrations
 	tracker := track.NewTracker(dmEmitter)
@@ -392,15 +638,32 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 	ffHandler := cmdchannel.NewCmdHandler("set_feature_flag", ffHandle.Handle)
 	riHandler := runintegration.NewHandler(definitionQ, il, dmEmitter, wlog.WithComponent("runintegration.Handler"))
 	siHandler := stopintegration.NewHandler(tracker, il, dmEmitter, wlog.WithComponent("stopintegration.Handler"))
+	ccHandlers := []*cmdchannel.CmdHandler{boHandler, ffHandler, riHandler, siHandler}
+	if c.CommandChannelDiagnosticsEnabled {
+		ccHandlers = append(ccHandlers, newDiagnosticsHandler(c, caClient, agt, transport))
+	}
+	if len(c.CommandChannelAllowedCommands) > 0 {
+		rcHandler, err := remotecmd.NewHandler(c, remotecmd.Actions{
+			ReloadConfig: reloader.Reload,
+			RestartIntegrations: func() int {
+				return integrationManager.Restart(agt.Context.Ctx)
+			},
+			SetLogLevel: wlog.SetLevelFor,
+		}, func(e sample.Event) {
+			agt.Context.SendEvent(e, "")
+		}, wlog.WithComponent("remotecmd.Handler"))
+		if err != nil {
+			aslog.WithError(err).Error("Cannot enable the commands allowed by command_channel_allowed_commands.")
+		} else {
+			ccHandlers = append(ccHandlers, rcHandler.CmdHandlers()...)
+		}
+	}
 	// Command channel service
 	ccService := service.NewService(
 		caClient,
 		c.CommandChannelIntervalSec,
 		backoffSecsC,
-		boHandler,
-		ffHandler,
-		riHandler,
-		siHandler,
+		ccHandlers...,
 	)
 	initCmdResponse, err := ccService.InitialFetch(agt.Context.Ctx)
 	if err != nil {
@@ -410,14 +673,62 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 	// Initialise the agent after fetching FF.
 	agt.Init()
 
-	if c.StatusServerEnabled || c.HTTPServerEnabled {
+	if networkReport != nil && c.NetworkChecksReportEvents {
+		for _, e := range networkReport.Events() {
+			agt.Context.SendEvent(e, "")
+		}
+	}
+
+	if c.StatusServerEnabled || c.HTTPServerEnabled || c.WebhookEnabled || c.PrometheusExporterEnabled ||
+		c.StatusServerSocket != "" || c.HTTPServerSocket != "" {
 		rlog := wlog.WithComponent("status.Reporter")
 		timeoutD, err := time.ParseDuration(c.StartupConnectionTimeout)
 		if err != nil {
 			// This should never happen, as the correct format is checked during NormalizeConfig.
 			aslog.WithError(err).Error("invalid startup_connection_timeout value, cannot run status server")
 		} else {
-			rep := status.NewReporter(agt.Context.Ctx, rlog, c.StatusEndpoints, timeoutD, transport, agt.Context.AgentIdnOrEmpty, agt.Context.EntityKey, c.License, userAgent)
+			var configWarnings []string
+			for _, w := range c.Warnings() {
+				configWarnings = append(configWarnings, w.String())
+			}
+			var reporterOpts []status.ReporterOption
+			if c.ConnectivityProbeIntervalSec > 0 {
+				prober := status.NewProber(wlog.WithComponent("status.Prober"), c.StatusEndpoints, time.Duration(c.ConnectivityProbeIntervalSec)*time.Second, timeoutD, transport, agt.Context.AgentIdnOrEmpty, c.License, userAgent)
+				go prober.Run(agt.Context.Ctx)
+				reporterOpts = append(reporterOpts, status.WithProber(prober))
+			}
+			if c.DNSCacheTTLSec > 0 {
+				reporterOpts = append(reporterOpts, status.WithDNSCache(backendhttp.DNSCacheReports))
+			}
+			reporterOpts = append(reporterOpts,
+				status.WithBackendRequests(backendhttp.BackendReports),
+				status.WithSamplers(sampler.SamplerReports),
+				status.WithIntegrations(v4runner.IntegrationReports),
+				status.WithQuarantine(v4runner.QuarantineReports),
+				status.WithFeatureFlags(ffManager.Flags),
+				status.WithCapabilities(capabilities.Report),
+				status.WithCloudMetadata(func() (cloud.MetadataReport, bool) {
+					detector, ok := agt.GetCloudHarvester().(*cloud.Detector)
+					if !ok {
+						return cloud.MetadataReport{}, false
+					}
+					return detector.Report(), true
+				}),
+				status.WithStoredIdentity(func() (entity.ID, error) {
+					return delta.StoredLocalEntityID(agent.DataDir(c))
+				}),
+				status.WithEventBus(agt.EventBus().Stats),
+				status.WithSenderQueues(func() (status.QueuesReport, bool) {
+					stats, ok := agt.SenderStats()
+					return status.QueuesReport{
+						EventQueueSize:     stats.EventQueueSize,
+						EventQueueCapacity: stats.EventQueueCapacity,
+						BatchQueueSize:     stats.BatchQueueSize,
+						BatchQueueCapacity: stats.BatchQueueCapacity,
+					}, ok
+				}),
+			)
+			rep := status.NewReporter(agt.Context.Ctx, rlog, c.StatusEndpoints, timeoutD, transport, agt.Context.AgentIdnOrEmpty, agt.Context.EntityKey, c.License, userAgent, configWarnings, reporterOpts...)
 
 			apiSrv, err := httpapi.NewServer(rep, integrationEmitter)
 			if c.HTTPServerEnabled {
@@ -432,10 +743,28 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 				apiSrv.Ingest.VerifyTLSClient(c.HTTPServerCA)
 			}
 
+			if c.HTTPServerSocket != "" {
+				apiSrv.Ingest.EnableSocket(c.HTTPServerSocket)
+			}
+
 			if c.StatusServerEnabled {
 				apiSrv.Status.Enable("localhost", c.StatusServerPort)
 			}
 
+			if c.StatusServerSocket != "" {
+				apiSrv.Status.EnableSocket(c.StatusServerSocket)
+			}
+
+			if c.WebhookEnabled {
+				apiSrv.Webhook.Enable("localhost", c.WebhookPort)
+				apiSrv.Webhook.Token(c.WebhookToken)
+			}
+
+			if c.PrometheusExporterEnabled {
+				apiSrv.Metrics.Enable(c.PrometheusExporterHost, c.PrometheusExporterPort)
+				apiSrv.MetricsHandler(agt.Prometheus().GetHandler())
+			}
+
 			if err != nil {
 				aslog.WithError(err).Error("cannot run api server")
 			} else {
@@ -448,6 +777,17 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 		go socketapi.NewServer(integrationEmitter, c.TCPServerPort).Serve(agt.Context.Ctx)
 	}
 
+	if c.ControlSocketEnabled {
+		go func() {
+			if err := newControlServer(c.ControlSocket, agt.Context.Maintenance()).Serve(agt.Context.Ctx); err != nil {
+				aslog.WithError(err).Warn("control socket stopped, newrelic-infra-ctl debug and maintenance commands won't be available")
+			}
+		}()
+	}
+
+	// Probe the data the agent can access, so plugins and samplers skip what it cannot.
+	capabilities.Probe(c)
+
 	// Start all plugins we want the agent to run.
 	if err = plugins.RegisterPlugins(agt); err != nil {
 		aslog.WithError(err).Error("fatal error while registering plugins")
@@ -508,6 +848,60 @@ func initializeAgentAndRun(c *config.Config, logFwCfg config.LogForward) error {
 		aslog.WithError(err).Debug("Can't configure integrations.")
 	}
 
+	// the options supporting it are applied to the running agent, keeping its identity and queued data
+	applyConfig := func(applied []string) {
+		backendhttp.ReloadTransports(applied)
+		if len(applied) > 0 {
+			// the inventory plugins report the reloaded options, ie: custom attributes
+			agt.Context.Reconnect()
+		}
+	}
+	reloader.OnReload(func(applied []string) {
+		// the reloaded custom attributes are resolved again from their sources
+		customAttrs.Refresh(agt.Context.Ctx)
+		applyConfig(applied)
+		integrationManager.Reload(agt.Context.Ctx)
+	})
+	agt.RegisterNotificationHandler(ipc.ReloadConfig, func() error {
+		_, err := reloader.Reload()
+		return err
+	})
+	if c.ConfigWatchEnabled {
+		if err := reloader.Watch(agt.Context.Ctx, configFile); err != nil {
+			aslog.WithError(err).Warn("Cannot watch configuration file, it won't be reloaded when modified.")
+		}
+	}
+
+	customAttrs.OnApply(applyConfig)
+	go customAttrs.Run(agt.Context.Ctx)
+
+	if c.RemoteConfigEnabled {
+		remoteConfig, err := remoteconfig.NewChannel(c, userAgent, httpClient.Do)
+		if err != nil {
+			aslog.WithError(err).Error("Cannot enable remote configuration.")
+		} else {
+			remoteConfig.OnApply(applyConfig)
+			go remoteConfig.Run(agt.Context.Ctx, agt.Context.AgentIdnOrEmpty)
+		}
+	}
+
+	if c.IntegrationsRunSamples {
+		v4runner.ConfigureRunSamples(func(e sample.Event) {
+			agt.Context.SendEvent(e, "")
+		})
+	}
+
+	if c.GovernorCPUPercentLimit > 0 || c.GovernorMemoryLimitMB > 0 {
+		gov, err := governor.New(c, agt.ShedEventQueues, func(e sample.Event) {
+			agt.Context.SendEvent(e, "")
+		})
+		if err != nil {
+			aslog.WithError(err).Error("Cannot enable the agent resources governor.")
+		} else {
+			go gov.Run(agt.Context.Ctx)
+		}
+	}
+
 	timedLog.Info("New Relic infrastructure agent is running.")
 
 	return agt.Run()
@@ -534,6 +928,26 @@ func newInstancesLookup(cfg v4.ManagerConfig) integration.InstancesLookup {
 	}
 }
 
+// newDiagnosticsHandler creates the command channel handler running the diagnostics checks requested by New Relic.
+func newDiagnosticsHandler(c *config.Config, client commandapi.Client, agt *agent.Agent, transport http.RoundTripper) *cmdchannel.CmdHandler {
+	checks := map[string]rundiagnostics.Check{
+		"dnschecks": func(context2.Context) (interface{}, error) {
+			return dnschecks.RunChecks(c.CollectorURL, c.StartupConnectionTimeout, transport, wlog.WithComponent("rundiagnostics.Handler"),
+				dnschecks.WithProxy(c.Proxy, c.IgnoreSystemProxy, c.ProxyValidateCerts),
+				dnschecks.WithCABundle(c.CABundleFile, c.CABundleDir),
+				dnschecks.WithResolvers(c.NetworkChecksResolvers))
+		},
+		"config": rundiagnostics.ConfigCheck(c),
+		"samplers": func(context2.Context) (interface{}, error) {
+			return sampler.SamplerReports(), nil
+		},
+	}
+	agentID := func() entity.ID {
+		return agt.Context.AgentIdnOrEmpty().ID
+	}
+	return rundiagnostics.NewHandler(client, agentID, agt.Context.Version(), checks, wlog.WithComponent("rundiagnostics.Handler"))
+}
+
 // configureLogFormat checks the config and sets the log format accordingly.
 func configureLogFormat(cfg config.LogConfig) {
 	// get default logrus formatter
@@ -555,6 +969,14 @@ func configureLogFormat(cfg config.LogConfig) {
 	}
 
 	formatter = logFilter.NewFilteringFormatter(logFilterCfg, formatter)
+	formatter = logFilter.NewRedactingFormatter(formatter)
+
+	// Summarize the warnings and errors repeated on every harvest instead of flooding the log.
+	if window := cfg.DedupeInterval(); window > 0 {
+		deduping := logFilter.NewDedupingFormatter(window, formatter)
+		go deduping.Run(context2.Background())
+		formatter = deduping
+	}
 
 	wlog.SetFormatter(formatter)
 }
@@ -592,6 +1014,43 @@ func configureLogRedirection(config *config.LogConfig, memLog *wlog.MemLogger) (
 	return
 }
 
+// configureLogSyslog ships the agent logs to a remote syslog endpoint, in addition to file/stdout.
+// Log filters are applied to the shipped entries as well.
+func configureLogSyslog(cfg config.LogConfig) {
+	if !cfg.Syslog.IsEnabled() {
+		return
+	}
+
+	syslogFormatter, err := wlog.NewSyslogFormatter(cfg.Syslog.Facility, cfg.Syslog.AppName)
+	if err != nil {
+		alog.WithField("action", "configureLogSyslog").WithError(err).Error("Can't ship logs to remote syslog.")
+		return
+	}
+
+	formatter := logFilter.NewFilteringFormatter(logFilter.FilteringFormatterConfig{
+		IncludeFilters: cfg.IncludeFilters,
+		ExcludeFilters: cfg.ExcludeFilters,
+	}, syslogFormatter)
+
+	hook, err := wlog.NewSyslogHook(wlog.SyslogConfig{
+		Address:            cfg.Syslog.Address,
+		Protocol:           cfg.Syslog.Protocol,
+		CAFile:             cfg.Syslog.CAFile,
+		InsecureSkipVerify: cfg.Syslog.InsecureSkipVerify,
+	}, logFilter.NewRedactingFormatter(formatter))
+	if err != nil {
+		alog.WithField("action", "configureLogSyslog").WithError(err).Error("Can't ship logs to remote syslog.")
+		return
+	}
+
+	wlog.AddHook(hook)
+	alog.WithFields(logrus.Fields{
+		"action":   "configureLogSyslog",
+		"address":  cfg.Syslog.Address,
+		"protocol": cfg.Syslog.Protocol,
+	}).Debug("Shipping logs to remote syslog.")
+}
+
 // newLogWriter returns an io.Writer to be used by the logger as an output.
 func newLogWriter(config *config.LogConfig) (io.Writer, error) {
 	logRotateConfig := config.Rotate
@@ -605,6 +1064,7 @@ func newLogWriter(config *config.LogConfig) (io.Writer, error) {
 		MaxSizeInBytes:  int64(*logRotateConfig.MaxSizeMb) << 20,
 		MaxFiles:        logRotateConfig.MaxFiles,
 		Compress:        logRotateConfig.CompressionEnabled,
+		MaxAge:          time.Duration(logRotateConfig.MaxAgeDays) * 24 * time.Hour,
 	}
 	return wlog.NewFileWithRotation(rotateCfg).Open()
 }
@@ -668,7 +1128,8 @@ func checkEndpointReachable(
 		request = http2.WithTracer(request, "checkEndpointReachable")
 	}
 	client := backendhttp.GetHttpClient(timeout, transport)
-	if _, err = client.Do(request); err != nil {
+	var resp *http.Response
+	if resp, err = client.Do(request); err != nil {
 		if e2, ok := err.(net.Error); ok && (e2.Timeout() || e2.Temporary()) {
 			timedOut = true
 		}
@@ -676,6 +1137,13 @@ func checkEndpointReachable(
 			aslog.WithError(errURL).Warn("URL error detected. May be a configuration problem or a network connectivity issue.")
 			timedOut = true
 		}
+		return
+	}
+	_ = resp.Body.Close()
+
+	if errIntercepted := dnschecks.DetectInterception(request.URL, resp); errIntercepted != nil {
+		aslog.WithError(errIntercepted).WithField("collector_url", collectorURL).
+			Warn("Collector response was not sent by New Relic, data might not be delivered.")
 	}
 
 	return
@@ -694,6 +1162,33 @@ func getPluginSourceDirs(ac *config.Config) []string {
 	return helpers.RemoveEmptyAndDuplicateEntries(pluginSourceDirs)
 }
 
+// executeSamplersDryRunMode is used for dry-run mode with -once. It runs all the enabled samplers one time and
+// writes their events to w, returning the exit code. The logs go to stderr so w only gets the events.
+func executeSamplersDryRunMode(w io.Writer, c *config.Config) int {
+	wlog.SetOutput(os.Stderr)
+
+	if c.IsForwardOnly {
+		aslog.Warn("No samplers run in forward only mode.")
+		return 0
+	}
+
+	ffManager := feature_flags.NewManager(c.Features, feature_flags.WithEnvironment(os.Environ()), feature_flags.WithOverrideFile(c.FeatureFlagsFile))
+	ctx, err := agent.NewDryRunContext(c, buildVersion, ffManager, w)
+	if err != nil {
+		aslog.WithError(err).Error("Can't create the dry-run agent context.")
+		return 1
+	}
+
+	capabilities.Probe(c)
+	sender := metricsSender.NewSender(ctx)
+	plugins.RegisterSamplers(ctx, sender, nil)
+	if failed := sender.SampleOnce(); failed > 0 {
+		aslog.WithField("failed", failed).Error("Some samplers failed.")
+		return 1
+	}
+	return 0
+}
+
 // executeIntegrationsDryRunMode is used for dry-run mode. It will read the integration config files,
 // execute all the integrations and print the output to stdout.
 func executeIntegrationsDryRunMode(configPath string, ac *config.Config) {
@@ -721,7 +1216,7 @@ func executeIntegrationsDryRunMode(configPath string, ac *config.Config) {
 
 	// Initialize the cloudDetector.
 	cloudHarvester := cloud.NewDetector(ac.DisableCloudMetadata, ac.CloudMaxRetryCount, ac.CloudRetryBackOffSec, ac.CloudMetadataExpiryInSec, ac.CloudMetadataDisableKeepAlive)
-	cloudHarvester.Initialize()
+	cloudHarvester.Initialize(cloud.WithIMDSv2Only(ac.AWSIMDSv2Only))
 
 	agentIDLookup := agent.NewIdLookup(hostnameResolver, cloudHarvester, ac.DisplayName)
 
@@ -746,3 +1241,20 @@ func executeIntegrationsDryRunMode(configPath string, ac *config.Config) {
 	)
 	integrationManager.RunOnce(context2.Background())
 }
+
+// hostEnvironmentAttr reports the environment the agent runs in when it doesn't expose all the host metrics, ie: wsl2.
+const hostEnvironmentAttr = "environment"
+
+// k8sNodeAttributes returns the attributes linking the host to the Kubernetes cluster and node the agent runs in,
+// taking the names from the environment when they're not configured.
+func k8sNodeAttributes(c *config.Config) map[string]string {
+	clusterName := c.K8sClusterName
+	if clusterName == "" {
+		clusterName = os.Getenv(metadata.K8sClusterNameEnv)
+	}
+	nodeName := c.K8sNodeName
+	if nodeName == "" {
+		nodeName = os.Getenv(metadata.K8sNodeNameEnv)
+	}
+	return metadata.K8sNodeAttributes(context2.Background(), clusterName, nodeName, c.K8sNodeLabels, metadata.APIServerNodeLabels)
+}
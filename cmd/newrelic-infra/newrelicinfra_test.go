@@ -3,12 +3,16 @@
 package main
 
 import (
+	"bytes"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/newrelic/infrastructure-agent/internal/agent/delta"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 )
@@ -35,3 +39,45 @@ func Test_configureLogRedirection(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "example logs here", string(dat))
 }
+
+func Test_validateConfig(t *testing.T) {
+	dir := t.TempDir()
+	pluginDir := filepath.Join(dir, "integrations.d")
+	valid := filepath.Join(dir, "valid.yml")
+	require.NoError(t, os.WriteFile(valid, []byte("license_key: abc123\nplugin_dir: "+pluginDir+"\n"), 0o600))
+	invalid := filepath.Join(dir, "invalid.yml")
+	require.NoError(t, os.WriteFile(invalid, []byte("license_key: abc123\nplugin_dir: "+pluginDir+"\nlicence_key: abc\n"), 0o600))
+
+	var out bytes.Buffer
+	assert.Equal(t, 0, validateConfig(&out, valid))
+	assert.Equal(t, "configuration is valid\n", out.String())
+
+	out.Reset()
+	assert.Equal(t, 1, validateConfig(&out, invalid))
+	assert.Contains(t, out.String(), invalid+":3: licence_key: unknown configuration option")
+	assert.Contains(t, out.String(), "1 configuration problems found")
+
+	out.Reset()
+	assert.Equal(t, 1, validateConfig(&out, filepath.Join(dir, "missing.yml")))
+	assert.Contains(t, out.String(), "cannot validate the configuration")
+}
+
+func Test_resetIdentity(t *testing.T) {
+	dir := t.TempDir()
+	cfgFile := filepath.Join(dir, "newrelic-infra.yml")
+	require.NoError(t, os.WriteFile(cfgFile, []byte("license_key: abc123\nagent_dir: "+dir+"\ncontrol_socket: "+filepath.Join(dir, "ctl.sock")+"\n"), 0o600))
+	dataDir := filepath.Join(dir, "data")
+	require.NoError(t, delta.NewEntityIDFilePersist(dataDir, "__nria_localentity").UpdateEntityID(123))
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "spool"), 0o755))
+
+	var out bytes.Buffer
+	assert.Equal(t, 0, resetIdentity(&out, cfgFile))
+	assert.Contains(t, out.String(), "stored agent ID: 123")
+	assert.Contains(t, out.String(), "removed "+filepath.Join(dataDir, "last_entityID"))
+	assert.NoDirExists(t, filepath.Join(dataDir, "last_entityID"))
+	assert.DirExists(t, filepath.Join(dataDir, "spool"))
+
+	out.Reset()
+	assert.Equal(t, 1, resetIdentity(&out, filepath.Join(dir, "missing.yml")))
+	assert.Contains(t, out.String(), "cannot load the configuration")
+}
@@ -4,6 +4,7 @@ package agent
 
 import (
 	context2 "context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -19,6 +20,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/internal/agent/instrumentation"
 	"github.com/newrelic/infrastructure-agent/internal/agent/inventory"
 	"github.com/newrelic/infrastructure-agent/internal/agent/types"
+	promInstrumentation "github.com/newrelic/infrastructure-agent/internal/instrumentation"
 
 	"github.com/newrelic/infrastructure-agent/internal/feature_flags"
 	"github.com/newrelic/infrastructure-agent/pkg/entity/host"
@@ -37,9 +39,12 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
 	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/hostname"
 
+	"github.com/newrelic/infrastructure-agent/internal/agent/bus"
 	"github.com/newrelic/infrastructure-agent/internal/agent/debug"
 	"github.com/newrelic/infrastructure-agent/internal/agent/delta"
+	"github.com/newrelic/infrastructure-agent/internal/agent/entityname"
 	"github.com/newrelic/infrastructure-agent/internal/agent/id"
+	"github.com/newrelic/infrastructure-agent/internal/agent/maintenance"
 	"github.com/newrelic/infrastructure-agent/pkg/disk"
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
@@ -47,7 +52,6 @@ import (
 
 	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
 	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
-	"github.com/newrelic/infrastructure-agent/pkg/backend/inventoryapi"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers"
 )
@@ -92,11 +96,15 @@ type Agent struct {
 	agentID             *entity.ID                               // pointer as it's referred from several points
 	mtx                 sync.Mutex                               // Protect plugins
 	notificationHandler *ctl.NotificationHandlerWithCancellation // Handle ipc messaging.
+	prometheus          *promInstrumentation.Prometheus          // Prometheus metrics, nil when the exporter is disabled.
+	otlpExporter        *otlpExporter                            // OTLP export of the host samples, nil when it's disabled.
+	flushRequests       chan struct{}                            // Requests sending the inventory deltas right away.
+	senderMtx           sync.Mutex                               // Serializes flushing and stopping the event sender.
 }
 
 type inventoryState struct {
-	readyToReap    bool
-	sendErrorCount uint32
+	readyToReap bool
+	sendBackoff *backoff.EndpointBackoff
 }
 
 var (
@@ -151,6 +159,9 @@ type context struct {
 	activeEntities        chan string              // Channel will be reported about the local/remote entities that are active
 	version               string
 	eventSender           eventSender
+	eventBus              *bus.Bus
+	busOnlyEventTypes     map[string]bool // Event types only published to the event bus, not sent to New Relic
+	eventDeduper          *eventDeduper
 
 	servicePidLock     *sync.RWMutex
 	servicePids        map[string]map[int]string // Map of plugin -> (map of pid -> service)
@@ -158,6 +169,7 @@ type context struct {
 	EntityMap          entity.KnownIDs
 	idLookup           host.IDLookup
 	shouldIncludeEvent sampler.IncludeSampleMatchFn
+	maintenance        *maintenance.Window
 }
 
 func (c *context) Context() context2.Context {
@@ -197,6 +209,11 @@ func (c *context) IDLookup() host.IDLookup {
 	return c.idLookup
 }
 
+// Maintenance returns the maintenance window of the agent, tagging or suppressing its events while active.
+func (c *context) Maintenance() *maintenance.Window {
+	return c.maintenance
+}
+
 // NewContext creates a new context.
 func NewContext(
 	cfg *config.Config,
@@ -222,6 +239,8 @@ func NewContext(
 		idLookup:           lookup,
 		shouldIncludeEvent: sampleMatchFn,
 		agentKey:           agentKey,
+		eventBus:           bus.New(),
+		eventDeduper:       newEventDeduper(cfg),
 	}
 }
 
@@ -263,6 +282,14 @@ func checkCollectorConnectivity(ctx context2.Context, cfg *config.Config, retrie
 	return
 }
 
+// DataDir returns the directory where the agent stores its state, ie: the inventory deltas and the agent identity.
+func DataDir(cfg *config.Config) string {
+	if cfg.AppDataDir != "" {
+		return filepath.Join(cfg.AppDataDir, "data")
+	}
+	return filepath.Join(cfg.AgentDir, "data")
+}
+
 // NewAgent returns a new instance of an agent built from the config.
 func NewAgent(
 	cfg *config.Config,
@@ -275,11 +302,28 @@ func NewAgent(
 
 	// Initialize the cloudDetector.
 	cloudHarvester := cloud.NewDetector(cfg.DisableCloudMetadata, cfg.CloudMaxRetryCount, cfg.CloudRetryBackOffSec, cfg.CloudMetadataExpiryInSec, cfg.CloudMetadataDisableKeepAlive)
-	cloudHarvester.Initialize(cloud.WithProvider(cloud.Type(cfg.CloudProvider)))
+	cloudHarvester.Initialize(cloud.WithIMDSv2Only(cfg.AWSIMDSv2Only), cloud.WithProvider(cloud.Type(cfg.CloudProvider)))
+
+	dataDir := DataDir(cfg)
+	resetClonedIdentity(cfg, dataDir, cloudHarvester)
+
+	// the resolved entity name is reported as the display name, taking precedence over the hostname
+	if cfg.DisplayName == "" && len(cfg.EntityNameStrategies) > 0 {
+		nameResolver, resolverErr := entityname.NewResolver(cfg, hostnameResolver, cloudHarvester, dataDir)
+		if resolverErr != nil {
+			return nil, resolverErr
+		}
+		if name, resolveErr := nameResolver.Resolve(); resolveErr != nil {
+			alog.WithError(resolveErr).Warn("Cannot resolve the entity name, using the hostname.")
+		} else {
+			cfg.DisplayName = name
+		}
+	}
 
 	idLookupTable := NewIdLookup(hostnameResolver, cloudHarvester, cfg.DisplayName)
 	sampleMatchFn := sampler.NewSampleMatchFn(cfg.EnableProcessMetrics, cfg.IncludeMetricsMatchers, ffRetriever)
 	ctx := NewContext(cfg, buildVersion, hostnameResolver, idLookupTable, sampleMatchFn)
+	ctx.maintenance = maintenance.NewWindow(cfg, dataDir)
 
 	agentKey, err := idLookupTable.AgentKey()
 	if err != nil {
@@ -287,21 +331,16 @@ func NewAgent(
 	}
 	ctx.setAgentKey(agentKey)
 
-	var dataDir string
-	if cfg.AppDataDir != "" {
-		dataDir = filepath.Join(cfg.AppDataDir, "data")
-	} else {
-		dataDir = filepath.Join(cfg.AgentDir, "data")
-	}
-
 	maxInventorySize := cfg.MaxInventorySize
 	if cfg.DisableInventorySplit {
 		maxInventorySize = delta.DisableInventorySplit
 	}
 
 	s := delta.NewStore(dataDir, ctx.EntityKey(), maxInventorySize, cfg.InventoryArchiveEnabled)
+	// large deltas are only submitted in chunks by the ingest patch sender
+	s.SetChunkingEnabled(cfg.InventoryChunkingEnabled && !cfg.DisableInventorySplit && !cfg.RegisterEnabled)
 
-	transport := backendhttp.BuildTransport(cfg, backendhttp.ClientTimeout)
+	transport := backendhttp.NewReloadableTransport(cfg, backendhttp.ClientTimeout)
 	transport = backendhttp.NewRequestDecoratorTransport(cfg, transport)
 
 	httpClient := backendhttp.GetHttpClient(backendhttp.ClientTimeout, transport)
@@ -378,6 +417,7 @@ func New(
 ) (*Agent, error) {
 	a := &Agent{
 		Context:             ctx,
+		flushRequests:       make(chan struct{}, 1),
 		debugProvide:        debug.ProvideFn,
 		userAgent:           userAgent,
 		store:               s,
@@ -439,6 +479,20 @@ func New(
 	} else {
 		a.Context.eventSender = newMetricsIngestSender(a.Context, cfg.License, a.userAgent, a.httpClient, cfg.ConnectEnabled)
 	}
+	if len(cfg.SubmissionRoutes) > 0 {
+		a.Context.eventSender = newRoutingSender(a.Context, a.Context.eventSender, a.userAgent)
+	}
+	if cfg.OTLPExport.IsEnabled() {
+		if err := a.exportOTLP(); err != nil {
+			alog.WithError(err).Error("Cannot export the samples through OTLP.")
+		}
+	}
+	if cfg.PrometheusExporterEnabled {
+		a.prometheus = promInstrumentation.NewPrometheus(prometheusTTL(cfg.GetMetricsSystemSampleRate(), cfg.GetMetricsStorageSampleRate(), cfg.GetMetricsProcessSampleRate()))
+		if err := subscribePrometheus(a.Context, a.Context.eventBus, a.prometheus); err != nil {
+			alog.WithError(err).Error("Cannot expose the samples through Prometheus.")
+		}
+	}
 
 	return a, nil
 }
@@ -504,8 +558,9 @@ func (a *Agent) newPatchSender(entity entity.Entity) (inventory.PatchSender, err
 	fileName := a.store.EntityFolder(entity.Key.String())
 	lastSubmission := delta.NewLastSubmissionStore(a.store.DataDir, fileName)
 	lastEntityID := delta.NewEntityIDFilePersist(a.store.DataDir, fileName)
+	sentChunks := delta.NewSentChunksStore(a.store.DataDir, fileName)
 
-	return newPatchSender(entity, a.Context, a.store, lastSubmission, lastEntityID, a.userAgent, a.Context.Identity, a.httpClient)
+	return newPatchSender(entity, a.Context, a.store, lastSubmission, lastEntityID, sentChunks, a.userAgent, a.Context.Identity, a.httpClient)
 }
 
 // removes the inventory object references to free the memory, and the respective directories
@@ -539,6 +594,39 @@ func (a *Agent) Terminate() {
 	}
 }
 
+// FlushQueuedData sends the queued events and the inventory deltas right away, ie: when the host is about to be
+// terminated. The events are flushed within shutdown_flush_timeout_sec and the event sender is started again, so the
+// samples taken until the agent stops are still sent.
+func (a *Agent) FlushQueuedData() {
+	select {
+	case a.flushRequests <- struct{}{}:
+	default:
+		// a flush is already pending
+	}
+
+	if a.otlpExporter != nil {
+		a.otlpExporter.export()
+	}
+
+	if a.Context.eventSender == nil {
+		return
+	}
+	a.senderMtx.Lock()
+	defer a.senderMtx.Unlock()
+	if a.Context.Ctx.Err() != nil {
+		// the agent is exiting, so the events are flushed while stopping the sender
+		return
+	}
+	alog.Info("Flushing queued data.")
+	timeout := time.Duration(a.Context.Config().GetShutdownFlushTimeoutSec()) * time.Second
+	if err := stopEventSender(a.Context.eventSender, timeout); err != nil {
+		alog.WithError(err).Warn("Cannot flush queued events.")
+	}
+	if err := a.Context.eventSender.Start(); err != nil {
+		alog.WithError(err).Error("failed to start event sender")
+	}
+}
+
 func (a *Agent) RegisterMetricsSender(s registerableSender) {
 	a.metricsSender = s
 }
@@ -562,6 +650,46 @@ func (a *Agent) GetContext() AgentContext {
 	return a.Context
 }
 
+// SenderStats returns the event sender queues usage and backend latency, false if the sender doesn't provide them.
+func (a *Agent) SenderStats() (SenderStats, bool) {
+	if s, ok := a.agentEventSender().(senderStatsProvider); ok {
+		return s.Stats(), true
+	}
+	return SenderStats{}, false
+}
+
+// ShedEventQueues discards the events queued by the agent sender, returning how many, ie: to release memory when the
+// agent exceeds its limit.
+func (a *Agent) ShedEventQueues() int {
+	if s, ok := a.agentEventSender().(queueShedder); ok {
+		return s.shedQueues()
+	}
+	return 0
+}
+
+// agentEventSender returns the sender of the events to the agent account, unwrapping the decorating senders.
+func (a *Agent) agentEventSender() eventSender {
+	sender := a.Context.eventSender
+	for {
+		switch s := sender.(type) {
+		case *routingSender:
+			sender = s.eventSender
+		default:
+			return sender
+		}
+	}
+}
+
+// EventBus returns the bus the events are published to once they are queued for New Relic.
+func (a *Agent) EventBus() *bus.Bus {
+	return a.Context.eventBus
+}
+
+// Prometheus returns the metrics exposed in the Prometheus format, nil when the exporter is disabled.
+func (a *Agent) Prometheus() *promInstrumentation.Prometheus {
+	return a.prometheus
+}
+
 // GetCloudHarvester will return the CloudHarvester service.
 func (a *Agent) GetCloudHarvester() cloud.Harvester {
 	return a.cloudHarvester
@@ -769,6 +897,16 @@ func (a *Agent) Run() (err error) {
 		}
 	}
 
+	if a.otlpExporter != nil {
+		a.otlpExporter.start()
+	}
+
+	if a.Context.eventDeduper != nil {
+		go a.Context.eventDeduper.run(a.Context.Ctx, func(event sample.Event, entityKey entity.Key) {
+			_ = a.Context.queueEvent(event, entityKey)
+		})
+	}
+
 	if a.metricsSender != nil {
 		if err := a.metricsSender.Start(); err != nil {
 			alog.WithError(err).Error("failed to start metrics subsystem")
@@ -926,6 +1064,10 @@ func (a *Agent) handleInventory(exit chan struct{}) {
 			}
 		case <-sendInventoryTimer.C:
 			a.sendInventory(sendInventoryTimer)
+		case <-a.flushRequests:
+			if a.shouldSendInventory() {
+				a.flushInventory(sendInventoryTimer)
+			}
 		case <-removeEntitiesTicker.C:
 			pastPeriodReportedEntities := reportedEntities
 			reportedEntities = map[string]bool{} // reset the set of reporting entities the next period
@@ -1025,16 +1167,30 @@ func (a *Agent) dumpMemoryProfile(agentRuntimeMark int) {
 func (a *Agent) exitGracefully() {
 	log.Info("Gracefully Exiting")
 
-	if a.Context.eventSender != nil {
-		if err := a.Context.eventSender.Stop(); err != nil {
-			log.WithError(err).Error("failed to stop event sender")
-		}
-	}
+	// samplers are stopped first so their last samples are flushed by the event sender
 	if a.metricsSender != nil {
 		if err := a.metricsSender.Stop(); err != nil {
 			log.WithError(err).Error("failed to stop metrics subsystem")
 		}
 	}
+	if a.Context.eventBus != nil {
+		busCtx, cancel := context2.WithTimeout(context2.Background(), time.Duration(a.Context.Config().GetShutdownFlushTimeoutSec())*time.Second)
+		if err := a.Context.eventBus.Close(busCtx); err != nil {
+			log.WithError(err).Warn("event bus subscribers didn't handle all the queued events")
+		}
+		cancel()
+	}
+	if a.otlpExporter != nil {
+		a.otlpExporter.stop()
+	}
+	if a.Context.eventSender != nil {
+		a.senderMtx.Lock()
+		timeout := time.Duration(a.Context.Config().GetShutdownFlushTimeoutSec()) * time.Second
+		if err := stopEventSender(a.Context.eventSender, timeout); err != nil {
+			log.WithError(err).Error("failed to stop event sender")
+		}
+		a.senderMtx.Unlock()
+	}
 
 	if a.inventoryHandler != nil {
 		a.inventoryHandler.Stop()
@@ -1046,32 +1202,52 @@ func (a *Agent) exitGracefully() {
 }
 
 func (a *Agent) sendInventory(sendTimer *time.Timer) {
-	backoffMax := config.MAX_BACKOFF
+	if a.inv.sendBackoff == nil {
+		a.inv.sendBackoff = inventory.NewSendBackoff(a.Context.cfg.SendInterval)
+	}
+
+	sendTimerVal := a.Context.cfg.SendInterval
 	for _, i := range a.inventories {
 		err := i.sender.Process()
 		if err != nil {
-			if ingestError, ok := err.(*inventoryapi.IngestError); ok &&
-				ingestError.StatusCode == http.StatusTooManyRequests {
-				alog.Warn("server is rate limiting inventory submission")
-				backoffMax = config.RATE_LIMITED_BACKOFF
-				a.inv.sendErrorCount = helpers.MaxBackoffErrorCount
-			} else {
-				a.inv.sendErrorCount++
-			}
-			alog.WithError(err).WithField("errorCount", a.inv.sendErrorCount).
+			sendTimerVal = a.inv.sendBackoff.Next(inventory.RetryPolicy(err))
+			stats := a.inv.sendBackoff.Stats()
+			instrumentation.RecordRetryStats(a.Context.Ctx, stats)
+
+			alog.WithError(err).WithField("errorCount", stats.ConsecutiveFailures).
+				WithField("retryAfter", sendTimerVal).
 				Debug("Inventory sender can't process after retrying.")
 			// Assuming break will try to send later the data from the missing inventory senders
 			break
 		} else {
-			a.inv.sendErrorCount = 0
+			a.inv.sendBackoff.Success()
 		}
 	}
-	sendTimerVal := helpers.ExpBackoff(a.Context.cfg.SendInterval,
-		time.Duration(backoffMax)*time.Second,
-		a.inv.sendErrorCount)
 	sendTimer.Reset(sendTimerVal)
 }
 
+// flushInventory reaps and sends the inventory deltas, without waiting for the plugins which haven't reported yet.
+func (a *Agent) flushInventory(sendTimer *time.Timer) {
+	if !a.inv.readyToReap {
+		a.inv.readyToReap = true
+		for _, inventory := range a.inventories {
+			inventory.needsCleanup = true
+		}
+	}
+	for _, inventory := range a.inventories {
+		if !inventory.needsReaping {
+			continue
+		}
+		inventory.reaper.Reap()
+		if inventory.needsCleanup {
+			inventory.reaper.CleanupOldPlugins(a.oldPlugins)
+			inventory.needsCleanup = false
+		}
+		inventory.needsReaping = false
+	}
+	a.sendInventory(sendTimer)
+}
+
 func (a *Agent) removeOutdatedEntities(reportedEntities map[string]bool) {
 	alog.Debug("Triggered periodic removal of outdated entities.")
 	// The entities to remove are those entities that haven't reported activity in the last period and
@@ -1163,14 +1339,63 @@ func (c *context) SendEvent(event sample.Event, entityKey entity.Key) {
 		return
 	}
 
-	if err := c.eventSender.QueueEvent(event, entityKey); err != nil {
+	switch c.maintenance.Policy() {
+	case config.MaintenancePolicySuppress:
+		aclog.
+			WithField("entity_key", entityKey.String()).
+			Trace("event suppressed by maintenance window")
+		return
+	case config.MaintenancePolicyTag:
+		event = maintenance.Tag(event)
+	}
+
+	if c.eventDeduper != nil && !c.eventDeduper.shouldSend(event, entityKey) {
+		return
+	}
+
+	if err := c.queueEvent(event, entityKey); err != nil {
 		txn.NoticeError(err)
-		alog.WithField(
-			"entityKey", entityKey,
-		).WithError(err).Error("could not queue event")
 	}
 }
 
+// queueEvent queues the event to be sent and publishes it to the event bus, unless it cannot be queued. The event
+// types only published to the event bus aren't queued.
+func (c *context) queueEvent(event sample.Event, entityKey entity.Key) error {
+	if !c.isBusOnly(event) {
+		if err := c.eventSender.QueueEvent(event, entityKey); err != nil {
+			alog.WithField(
+				"entityKey", entityKey,
+			).WithError(err).Error("could not queue event")
+			return err
+		}
+	}
+
+	// published once queued, as the event isn't modified by the sender afterwards
+	if c.eventBus != nil {
+		if entityKey == "" {
+			entityKey = entity.Key(c.EntityKey())
+		}
+		c.eventBus.Publish(bus.Message{Event: event, EntityKey: entityKey})
+	}
+	return nil
+}
+
+// isBusOnly returns whether the event type is only published to the event bus, ie: the host samples exported
+// exclusively through OTLP.
+func (c *context) isBusOnly(event sample.Event) bool {
+	if len(c.busOnlyEventTypes) == 0 {
+		return false
+	}
+	var typed struct {
+		EventType string `json:"eventType"`
+	}
+	data, err := json.Marshal(event)
+	if err != nil || json.Unmarshal(data, &typed) != nil {
+		return false
+	}
+	return c.busOnlyEventTypes[typed.EventType]
+}
+
 func (c *context) Unregister(id ids.PluginID) {
 	c.ch <- types.NewNotApplicableOutput(id)
 }
@@ -1286,6 +1511,12 @@ func (a *Agent) enableVerboseLogging() error {
 	return nil
 }
 
+// RegisterNotificationHandler registers the handler of an ipc message, ie: the configuration reload. It must be
+// invoked before running the agent.
+func (a *Agent) RegisterNotificationHandler(message ipc.Message, handler func() error) {
+	a.notificationHandler.RegisterHandler(message, handler)
+}
+
 // this function is only called in the Windows implementation when the agent service is being
 // stopped from Services management console
 func (a *Agent) gracefulStop() error {
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	context2 "context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
 	"io/ioutil"
@@ -20,7 +21,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/newrelic/infrastructure-agent/internal/agent/bus"
 	"github.com/newrelic/infrastructure-agent/internal/agent/delta"
+	"github.com/newrelic/infrastructure-agent/internal/agent/maintenance"
 	agentTypes "github.com/newrelic/infrastructure-agent/internal/agent/types"
 	"github.com/newrelic/infrastructure-agent/internal/feature_flags"
 	"github.com/newrelic/infrastructure-agent/internal/feature_flags/test"
@@ -954,6 +957,74 @@ func TestContext_SendEvent_LogTruncatedEvent(t *testing.T) {
 	assert.Contains(t, written, fmt.Sprintf("truncated=\"+map[key:%s]", truncated))
 }
 
+type queuedEventSender struct {
+	fakeEventSender
+	events []sample.Event
+}
+
+func (r *queuedEventSender) QueueEvent(event sample.Event, _ entity.Key) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestContext_SendEvent_Maintenance(t *testing.T) {
+	cfg := config.Config{MaintenancePolicy: config.MaintenancePolicyTag}
+	c := NewContext(
+		&cfg,
+		"0.0.0",
+		testhelpers.NewFakeHostnameResolver("foobar", "foo", nil),
+		NilIDLookup,
+		func(sample interface{}) bool { return true },
+	)
+	sender := &queuedEventSender{}
+	c.eventSender = sender
+	c.maintenance = maintenance.NewWindow(&cfg, "")
+
+	c.SendEvent(mapEvent(map[string]interface{}{"key": "value"}), "some key")
+	require.NoError(t, c.maintenance.Start(time.Hour, "patching"))
+	c.SendEvent(mapEvent(map[string]interface{}{"key": "value"}), "some key")
+	cfg.MaintenancePolicy = config.MaintenancePolicySuppress
+	c.SendEvent(mapEvent(map[string]interface{}{"key": "value"}), "some key")
+
+	require.Len(t, sender.events, 2)
+	data, err := json.Marshal(sender.events[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "maintenance")
+	data, err = json.Marshal(sender.events[1])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"maintenance":true`)
+}
+
+type failingEventSender struct {
+	fakeEventSender
+}
+
+func (f *failingEventSender) QueueEvent(sample.Event, entity.Key) error {
+	return errors.New("queue is full")
+}
+
+func TestContext_SendEvent_NotQueuedNotPublished(t *testing.T) {
+	c := NewContext(
+		&config.Config{},
+		"0.0.0",
+		testhelpers.NewFakeHostnameResolver("foobar", "foo", nil),
+		NilIDLookup,
+		func(sample interface{}) bool { return true },
+	)
+	c.eventSender = &failingEventSender{}
+	published := make(chan bus.Message, 1)
+	require.NoError(t, c.eventBus.Subscribe(bus.SubscriberConfig{Name: "test"}, func(msg bus.Message) error {
+		published <- msg
+		return nil
+	}))
+
+	c.SendEvent(mapEvent(map[string]interface{}{"key": "value"}), "some key")
+	require.NoError(t, c.eventBus.Close(context2.Background()))
+
+	assert.Empty(t, published)
+	assert.Equal(t, uint64(0), c.eventBus.Stats()[0].Published)
+}
+
 func TestRunsWithCloudProvider(t *testing.T) {
 	t.Parallel()
 
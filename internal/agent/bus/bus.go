@@ -0,0 +1,328 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package bus provides the in-process publish/subscribe bus the agent events are published to, once they pass the
+// sample filters and the maintenance window, so new subsystems like exporters, forwarders or dedupers can consume
+// them without being chained into the event senders.
+//
+// Every subscriber gets its own bounded queue, drained by its own goroutine, so a slow subscriber never delays the
+// publishers nor the rest of subscribers. When the queue is full, the subscriber drop policy decides whether the
+// oldest or the newest message is discarded, or whether the publisher waits for a while before discarding it.
+//
+// Delivery is at-least-once and ordered: a message is delivered again with an exponential backoff while its handler
+// fails, until MaxAttempts is reached, before delivering the next one. Handlers must hence be idempotent, and
+// they must treat the events as read-only, as they are shared with the event sender and the rest of subscribers.
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const (
+	DefaultQueueSize    = 1000
+	DefaultRetryBackoff = time.Second
+	DefaultBlockTimeout = time.Second
+	maxRetryBackoff     = time.Minute
+)
+
+var blog = log.WithComponent("EventBus")
+
+// ErrClosed is returned when subscribing to a closed bus.
+var ErrClosed = errors.New("event bus is closed")
+
+// DropPolicy decides which message is discarded when a subscriber queue is full.
+type DropPolicy string
+
+const (
+	// DropOldest discards the oldest queued message to make room for the published one.
+	DropOldest DropPolicy = "drop_oldest"
+	// DropNewest discards the published message.
+	DropNewest DropPolicy = "drop_newest"
+	// Block makes the publisher wait up to BlockTimeout for room, discarding the published message afterwards.
+	Block DropPolicy = "block"
+)
+
+// Message is an event published to the bus, along with the entity it belongs to.
+type Message struct {
+	Event     sample.Event
+	EntityKey entity.Key
+}
+
+// Handler processes the messages of a subscriber. Returning an error makes the bus deliver the message again.
+type Handler func(Message) error
+
+// SubscriberConfig defines the queue and the delivery of a subscriber. Zero values take the defaults.
+type SubscriberConfig struct {
+	// Name identifies the subscriber in the stats, it must be unique.
+	Name string
+	// QueueSize is the amount of messages queued before the drop policy applies.
+	QueueSize  int
+	DropPolicy DropPolicy
+	// BlockTimeout is the time the publisher waits for room with the Block policy.
+	BlockTimeout time.Duration
+	// MaxAttempts is the amount of times a message is delivered before discarding it, 0 for no limit.
+	MaxAttempts int
+	// RetryBackoff is the wait before the first redelivery, doubled on every attempt up to a minute.
+	RetryBackoff time.Duration
+}
+
+// Stats are the delivery counters of a subscriber since it subscribed.
+type Stats struct {
+	Name          string     `json:"name"`
+	DropPolicy    DropPolicy `json:"drop_policy"`
+	QueueSize     int        `json:"queue_size"`
+	QueueCapacity int        `json:"queue_capacity"`
+	Published     uint64     `json:"published"`
+	Delivered     uint64     `json:"delivered"`
+	Retried       uint64     `json:"retried"`
+	Dropped       uint64     `json:"dropped"`
+	Failed        uint64     `json:"failed"`
+}
+
+// Bus fans out the published messages to the queues of its subscribers.
+type Bus struct {
+	lock        sync.RWMutex
+	subscribers []*subscriber
+	closed      bool
+}
+
+// New creates an event bus without subscribers.
+func New() *Bus {
+	return &Bus{}
+}
+
+// Subscribe starts delivering the messages published from now on to the handler.
+func (b *Bus) Subscribe(cfg SubscriberConfig, handler Handler) error {
+	if cfg.Name == "" {
+		return errors.New("subscriber name is required")
+	}
+	switch cfg.DropPolicy {
+	case "":
+		cfg.DropPolicy = DropOldest
+	case DropOldest, DropNewest, Block:
+	default:
+		return fmt.Errorf("unknown drop policy %q", cfg.DropPolicy)
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.BlockTimeout <= 0 {
+		cfg.BlockTimeout = DefaultBlockTimeout
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	for _, s := range b.subscribers {
+		if s.cfg.Name == cfg.Name {
+			return fmt.Errorf("subscriber %q already exists", cfg.Name)
+		}
+	}
+
+	s := newSubscriber(cfg, handler)
+	b.subscribers = append(b.subscribers, s)
+	go s.run()
+	blog.WithField("subscriber", cfg.Name).WithField("dropPolicy", cfg.DropPolicy).Debug("Subscribed.")
+	return nil
+}
+
+// Unsubscribe stops the subscriber, discarding its queued messages. It returns false when it's not subscribed.
+func (b *Bus) Unsubscribe(name string) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for i, s := range b.subscribers {
+		if s.cfg.Name == name {
+			b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+			s.abort()
+			close(s.queue)
+			return true
+		}
+	}
+	return false
+}
+
+// Publish queues the message for every subscriber. It only waits for the subscribers with the Block policy.
+func (b *Bus) Publish(msg Message) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if b.closed {
+		return
+	}
+	for _, s := range b.subscribers {
+		s.offer(msg)
+	}
+}
+
+// Stats returns the stats of the subscribers, in subscription order.
+func (b *Bus) Stats() []Stats {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	stats := make([]Stats, 0, len(b.subscribers))
+	for _, s := range b.subscribers {
+		stats = append(stats, s.stats())
+	}
+	return stats
+}
+
+// Close stops accepting messages and waits for the subscribers to deliver the queued ones. When the context is done
+// first, the pending deliveries are discarded and its error is returned.
+func (b *Bus) Close(ctx context.Context) error {
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return nil
+	}
+	b.closed = true
+	subscribers := b.subscribers
+	for _, s := range subscribers {
+		close(s.queue)
+	}
+	b.lock.Unlock()
+
+	for _, s := range subscribers {
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			for _, pending := range subscribers {
+				pending.abort()
+			}
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+type subscriber struct {
+	cfg     SubscriberConfig
+	handler Handler
+	queue   chan Message
+	aborted chan struct{}
+	once    sync.Once
+	done    chan struct{}
+
+	published atomic.Uint64
+	delivered atomic.Uint64
+	retried   atomic.Uint64
+	dropped   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+func newSubscriber(cfg SubscriberConfig, handler Handler) *subscriber {
+	return &subscriber{
+		cfg:     cfg,
+		handler: handler,
+		queue:   make(chan Message, cfg.QueueSize),
+		aborted: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *subscriber) offer(msg Message) {
+	s.published.Add(1)
+	switch s.cfg.DropPolicy {
+	case DropNewest:
+		select {
+		case s.queue <- msg:
+		default:
+			s.dropped.Add(1)
+		}
+	case Block:
+		timer := time.NewTimer(s.cfg.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case s.queue <- msg:
+		case <-timer.C:
+			s.dropped.Add(1)
+		}
+	default:
+		for {
+			select {
+			case s.queue <- msg:
+				return
+			default:
+			}
+			select {
+			case <-s.queue:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	}
+}
+
+func (s *subscriber) run() {
+	defer close(s.done)
+	for msg := range s.queue {
+		select {
+		case <-s.aborted:
+			return
+		default:
+		}
+		s.deliver(msg)
+	}
+}
+
+// deliver calls the handler until it succeeds, the attempts are exhausted or the subscriber is aborted.
+func (s *subscriber) deliver(msg Message) {
+	backoff := s.cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := s.handler(msg)
+		if err == nil {
+			s.delivered.Add(1)
+			return
+		}
+		if s.cfg.MaxAttempts > 0 && attempt >= s.cfg.MaxAttempts {
+			s.failed.Add(1)
+			blog.WithError(err).WithField("subscriber", s.cfg.Name).WithField("attempts", attempt).
+				Warn("Discarding message not delivered.")
+			return
+		}
+		s.retried.Add(1)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-s.aborted:
+			timer.Stop()
+			s.failed.Add(1)
+			return
+		}
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+func (s *subscriber) abort() {
+	s.once.Do(func() {
+		close(s.aborted)
+	})
+}
+
+func (s *subscriber) stats() Stats {
+	return Stats{
+		Name:          s.cfg.Name,
+		DropPolicy:    s.cfg.DropPolicy,
+		QueueSize:     len(s.queue),
+		QueueCapacity: cap(s.queue),
+		Published:     s.published.Load(),
+		Delivered:     s.delivered.Load(),
+		Retried:       s.retried.Load(),
+		Dropped:       s.dropped.Load(),
+		Failed:        s.failed.Load(),
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package bus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+func message(eventType string) Message {
+	e := &sample.BaseEvent{}
+	e.Type(eventType)
+	return Message{Event: e, EntityKey: entity.Key("my-host")}
+}
+
+func eventType(msg Message) string {
+	return msg.Event.(*sample.BaseEvent).EventType
+}
+
+type recorder struct {
+	lock     sync.Mutex
+	received []string
+}
+
+func (r *recorder) handle(msg Message) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.received = append(r.received, eventType(msg))
+	return nil
+}
+
+func (r *recorder) events() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string{}, r.received...)
+}
+
+func TestBus_FanOut(t *testing.T) {
+	b := New()
+	first, second := &recorder{}, &recorder{}
+	require.NoError(t, b.Subscribe(SubscriberConfig{Name: "first"}, first.handle))
+	require.NoError(t, b.Subscribe(SubscriberConfig{Name: "second"}, second.handle))
+
+	b.Publish(message("SystemSample"))
+	b.Publish(message("ProcessSample"))
+	require.NoError(t, b.Close(context.Background()))
+
+	assert.Equal(t, []string{"SystemSample", "ProcessSample"}, first.events())
+	assert.Equal(t, []string{"SystemSample", "ProcessSample"}, second.events())
+
+	stats := b.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, Stats{Name: "first", DropPolicy: DropOldest, QueueCapacity: DefaultQueueSize, Published: 2, Delivered: 2}, stats[0])
+	assert.Equal(t, "second", stats[1].Name)
+
+	// closed bus
+	b.Publish(message("StorageSample"))
+	assert.Len(t, first.events(), 2)
+	assert.Equal(t, ErrClosed, b.Subscribe(SubscriberConfig{Name: "third"}, first.handle))
+}
+
+func TestBus_Subscribe_Invalid(t *testing.T) {
+	b := New()
+	r := &recorder{}
+	assert.Error(t, b.Subscribe(SubscriberConfig{}, r.handle))
+	assert.Error(t, b.Subscribe(SubscriberConfig{Name: "s", DropPolicy: "unknown"}, r.handle))
+	require.NoError(t, b.Subscribe(SubscriberConfig{Name: "s"}, r.handle))
+	assert.Error(t, b.Subscribe(SubscriberConfig{Name: "s"}, r.handle))
+}
+
+func TestBus_Redelivery(t *testing.T) {
+	b := New()
+	var attempts int
+	r := &recorder{}
+	require.NoError(t, b.Subscribe(SubscriberConfig{Name: "flaky", RetryBackoff: time.Millisecond}, func(msg Message) error {
+		if eventType(msg) == "SystemSample" {
+			if attempts++; attempts < 3 {
+				return errors.New("unavailable")
+			}
+		}
+		return r.handle(msg)
+	}))
+
+	b.Publish(message("SystemSample"))
+	b.Publish(message("ProcessSample"))
+	require.NoError(t, b.Close(context.Background()))
+
+	// messages are delivered in order
+	assert.Equal(t, []string{"SystemSample", "ProcessSample"}, r.events())
+	stats := b.Stats()[0]
+	assert.Equal(t, uint64(2), stats.Delivered)
+	assert.Equal(t, uint64(2), stats.Retried)
+	assert.Equal(t, uint64(0), stats.Failed)
+}
+
+func TestBus_MaxAttempts(t *testing.T) {
+	b := New()
+	require.NoError(t, b.Subscribe(SubscriberConfig{Name: "failing", MaxAttempts: 2, RetryBackoff: time.Millisecond}, func(Message) error {
+		return errors.New("unavailable")
+	}))
+
+	b.Publish(message("SystemSample"))
+	require.NoError(t, b.Close(context.Background()))
+
+	stats := b.Stats()[0]
+	assert.Equal(t, uint64(0), stats.Delivered)
+	assert.Equal(t, uint64(1), stats.Retried)
+	assert.Equal(t, uint64(1), stats.Failed)
+}
+
+// blockedSubscriber subscribes a handler waiting for the release channel, returning once the first message is
+// being handled so the rest stay queued.
+func blockedSubscriber(t *testing.T, b *Bus, cfg SubscriberConfig, r *recorder) chan struct{} {
+	handling, release := make(chan struct{}, 1), make(chan struct{})
+	require.NoError(t, b.Subscribe(cfg, func(msg Message) error {
+		select {
+		case handling <- struct{}{}:
+		default:
+		}
+		<-release
+		return r.handle(msg)
+	}))
+	b.Publish(message("first"))
+	<-handling
+	return release
+}
+
+func TestBus_DropPolicies(t *testing.T) {
+	tests := []struct {
+		policy SubscriberConfig
+		want   []string
+	}{
+		{SubscriberConfig{Name: "oldest", QueueSize: 2, DropPolicy: DropOldest}, []string{"first", "third", "fourth"}},
+		{SubscriberConfig{Name: "newest", QueueSize: 2, DropPolicy: DropNewest}, []string{"first", "second", "third"}},
+		{SubscriberConfig{Name: "block", QueueSize: 2, DropPolicy: Block, BlockTimeout: time.Millisecond}, []string{"first", "second", "third"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.Name, func(t *testing.T) {
+			b := New()
+			r := &recorder{}
+			release := blockedSubscriber(t, b, tt.policy, r)
+
+			b.Publish(message("second"))
+			b.Publish(message("third"))
+			b.Publish(message("fourth"))
+			assert.Equal(t, uint64(1), b.Stats()[0].Dropped)
+			assert.Equal(t, 2, b.Stats()[0].QueueSize)
+
+			close(release)
+			require.NoError(t, b.Close(context.Background()))
+			assert.Equal(t, tt.want, r.events())
+		})
+	}
+}
+
+func TestBus_CloseTimeout(t *testing.T) {
+	b := New()
+	r := &recorder{}
+	release := blockedSubscriber(t, b, SubscriberConfig{Name: "slow"}, r)
+	b.Publish(message("second"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, b.Close(ctx))
+
+	// the queued messages are discarded once the running delivery ends
+	close(release)
+	assert.Eventually(t, func() bool { return len(r.events()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"first"}, r.events())
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	b := New()
+	r := &recorder{}
+	require.NoError(t, b.Subscribe(SubscriberConfig{Name: "s"}, r.handle))
+	assert.True(t, b.Unsubscribe("s"))
+	assert.False(t, b.Unsubscribe("s"))
+
+	b.Publish(message("SystemSample"))
+	assert.Empty(t, b.Stats())
+	assert.Empty(t, r.events())
+}
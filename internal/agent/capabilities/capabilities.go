@@ -0,0 +1,131 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package capabilities probes on startup which data the agent can collect under its user and Linux capabilities, so
+// the samplers skip the fields it cannot access instead of failing on every harvest.
+package capabilities
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// Probed capabilities.
+const (
+	ProcessIOCounters = "process_io_counters"
+	ProcessFDCount    = "process_fd_count"
+	ProductUUID       = "product_uuid"
+)
+
+var clog = log.WithComponent("Capabilities")
+
+var (
+	lock   sync.RWMutex
+	probed *Matrix
+)
+
+// Capability reports whether some data requiring privileges is available to the agent.
+type Capability struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	// Requires is the privilege needed to access the data.
+	Requires string `json:"requires"`
+	// Affects lists the samplers fields or inventory items not reported when it's unavailable.
+	Affects []string `json:"affects"`
+	// Reason why it's unavailable.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Matrix is the result of probing the capabilities of the agent.
+type Matrix struct {
+	RunMode string `json:"run_mode"`
+	User    string `json:"user"`
+	// EffectiveCapabilities lists the Linux capabilities of the agent process, when they can be read.
+	EffectiveCapabilities []string     `json:"effective_capabilities,omitempty"`
+	Capabilities          []Capability `json:"capabilities"`
+}
+
+// probe checks whether some data is available, returning the reason when it isn't. The effective Linux capabilities
+// are nil when they cannot be read.
+type probe struct {
+	name     string
+	requires string
+	affects  []string
+	check    func(runMode string, effective map[string]bool) error
+}
+
+// Probe checks the capabilities of the agent, logging once those unavailable, and keeps the result for Available
+// and Report.
+func Probe(cfg *config.Config) Matrix {
+	m := Matrix{
+		RunMode:      cfg.RunMode,
+		User:         cfg.AgentUser,
+		Capabilities: []Capability{},
+	}
+
+	effective, err := effectiveCapabilities()
+	if err != nil {
+		clog.WithError(err).Debug("Cannot read the effective capabilities of the agent.")
+	}
+	for name := range effective {
+		m.EffectiveCapabilities = append(m.EffectiveCapabilities, name)
+	}
+	sort.Strings(m.EffectiveCapabilities)
+
+	for _, p := range probes {
+		c := Capability{
+			Name:      p.name,
+			Available: true,
+			Requires:  p.requires,
+			Affects:   p.affects,
+		}
+		if err := p.check(cfg.RunMode, effective); err != nil {
+			c.Available = false
+			c.Reason = err.Error()
+			clog.WithFields(logrus.Fields{
+				"capability": c.Name,
+				"requires":   c.Requires,
+				"affects":    c.Affects,
+				"reason":     c.Reason,
+			}).Info("Capability not available, the data it affects is not reported.")
+		}
+		m.Capabilities = append(m.Capabilities, c)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	probed = &m
+	return m
+}
+
+// Available returns whether the capability is available to the agent. Capabilities are considered available until
+// they are probed.
+func Available(name string) bool {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if probed == nil {
+		return true
+	}
+	for _, c := range probed.Capabilities {
+		if c.Name == name {
+			return c.Available
+		}
+	}
+	return true
+}
+
+// Report returns the probed capabilities, empty until they are probed.
+func Report() Matrix {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if probed == nil {
+		return Matrix{Capabilities: []Capability{}}
+	}
+	return *probed
+}
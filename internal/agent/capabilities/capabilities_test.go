@@ -0,0 +1,64 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package capabilities
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+func TestProbe(t *testing.T) {
+	defaultProbes := probes
+	defer func() {
+		probes = defaultProbes
+		probed = nil
+	}()
+	probes = []probe{
+		{
+			name:     "readable",
+			requires: "CAP_DAC_READ_SEARCH",
+			affects:  []string{"Sample.readable"},
+			check:    func(string, map[string]bool) error { return nil },
+		},
+		{
+			name:     "forbidden",
+			requires: "CAP_SYS_PTRACE",
+			affects:  []string{"Sample.forbidden"},
+			check: func(runMode string, _ map[string]bool) error {
+				return errors.New("permission denied in " + runMode)
+			},
+		},
+	}
+
+	// every capability is available until probed
+	assert.True(t, Available("forbidden"))
+	assert.Empty(t, Report().Capabilities)
+
+	m := Probe(&config.Config{RunMode: config.ModeRoot, AgentUser: "root"})
+
+	assert.Equal(t, config.ModeRoot, m.RunMode)
+	assert.Equal(t, "root", m.User)
+	require.Len(t, m.Capabilities, 2)
+	assert.Equal(t, Capability{
+		Name:      "readable",
+		Available: true,
+		Requires:  "CAP_DAC_READ_SEARCH",
+		Affects:   []string{"Sample.readable"},
+	}, m.Capabilities[0])
+	assert.Equal(t, Capability{
+		Name:     "forbidden",
+		Requires: "CAP_SYS_PTRACE",
+		Affects:  []string{"Sample.forbidden"},
+		Reason:   "permission denied in root",
+	}, m.Capabilities[1])
+	assert.Equal(t, m, Report())
+
+	assert.True(t, Available("readable"))
+	assert.False(t, Available("forbidden"))
+	assert.True(t, Available("unknown"))
+}
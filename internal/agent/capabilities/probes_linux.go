@@ -0,0 +1,124 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package capabilities
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+const capSysPtrace = "CAP_SYS_PTRACE"
+
+var errUnprivileged = errors.New("agent running in unprivileged mode")
+
+// capabilityNames are the Linux capabilities by their bit number, as listed in capabilities(7).
+var capabilityNames = []string{
+	"CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_DAC_READ_SEARCH", "CAP_FOWNER", "CAP_FSETID", "CAP_KILL", "CAP_SETGID",
+	"CAP_SETUID", "CAP_SETPCAP", "CAP_LINUX_IMMUTABLE", "CAP_NET_BIND_SERVICE", "CAP_NET_BROADCAST", "CAP_NET_ADMIN",
+	"CAP_NET_RAW", "CAP_IPC_LOCK", "CAP_IPC_OWNER", "CAP_SYS_MODULE", "CAP_SYS_RAWIO", "CAP_SYS_CHROOT",
+	"CAP_SYS_PTRACE", "CAP_SYS_PACCT", "CAP_SYS_ADMIN", "CAP_SYS_BOOT", "CAP_SYS_NICE", "CAP_SYS_RESOURCE",
+	"CAP_SYS_TIME", "CAP_SYS_TTY_CONFIG", "CAP_MKNOD", "CAP_LEASE", "CAP_AUDIT_WRITE", "CAP_AUDIT_CONTROL",
+	"CAP_SETFCAP", "CAP_MAC_OVERRIDE", "CAP_MAC_ADMIN", "CAP_SYSLOG", "CAP_WAKE_ALARM", "CAP_BLOCK_SUSPEND",
+	"CAP_AUDIT_READ", "CAP_PERFMON", "CAP_BPF", "CAP_CHECKPOINT_RESTORE",
+}
+
+// The process probes read the data of the init process, which other users cannot access without CAP_SYS_PTRACE.
+var probes = []probe{
+	{
+		name:     ProcessIOCounters,
+		requires: capSysPtrace,
+		affects: []string{
+			"ProcessSample.ioTotalReadCount", "ProcessSample.ioTotalWriteCount", "ProcessSample.ioTotalReadBytes",
+			"ProcessSample.ioTotalWriteBytes", "ProcessSample.ioReadCountPerSecond", "ProcessSample.ioWriteCountPerSecond",
+			"ProcessSample.ioReadBytesPerSecond", "ProcessSample.ioWriteBytesPerSecond",
+		},
+		check: func(runMode string, effective map[string]bool) error {
+			if err := checkProcessAccess(runMode, effective); err != nil {
+				return err
+			}
+			_, err := os.ReadFile(helpers.HostProc("1", "io"))
+			return err
+		},
+	},
+	{
+		name:     ProcessFDCount,
+		requires: capSysPtrace,
+		affects:  []string{"ProcessSample.fileDescriptorCount"},
+		check: func(runMode string, effective map[string]bool) error {
+			if err := checkProcessAccess(runMode, effective); err != nil {
+				return err
+			}
+			d, err := os.Open(helpers.HostProc("1", "fd"))
+			if err != nil {
+				return err
+			}
+			defer d.Close()
+			_, err = d.Readdirnames(1)
+			return err
+		},
+	},
+	{
+		name:     ProductUUID,
+		requires: "CAP_DAC_READ_SEARCH",
+		affects:  []string{"inventory system/host_info product_uuid"},
+		check: func(runMode string, _ map[string]bool) error {
+			if runMode == config.ModeUnprivileged {
+				return errUnprivileged
+			}
+			_, err := os.ReadFile(helpers.HostSys("/class/dmi/id/product_uuid"))
+			return err
+		},
+	},
+}
+
+func checkProcessAccess(runMode string, effective map[string]bool) error {
+	if runMode == config.ModeUnprivileged {
+		return errUnprivileged
+	}
+	if effective != nil && !effective[capSysPtrace] {
+		return fmt.Errorf("missing %s capability", capSysPtrace)
+	}
+	return nil
+}
+
+// effectiveCapabilities returns the effective capabilities of the agent process.
+func effectiveCapabilities() (map[string]bool, error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value := strings.TrimPrefix(scanner.Text(), "CapEff:"); value != scanner.Text() {
+			return parseCapabilities(strings.TrimSpace(value))
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("CapEff not found in /proc/self/status")
+}
+
+// parseCapabilities parses the hexadecimal bitmask of capabilities from /proc/<pid>/status.
+func parseCapabilities(mask string) (map[string]bool, error) {
+	bits, err := strconv.ParseUint(mask, 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid capabilities mask %q: %w", mask, err)
+	}
+	names := map[string]bool{}
+	for bit, name := range capabilityNames {
+		if bits&(1<<uint(bit)) != 0 {
+			names[name] = true
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package capabilities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	caps, err := parseCapabilities("0000000000080004")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"CAP_DAC_READ_SEARCH": true, "CAP_SYS_PTRACE": true}, caps)
+
+	caps, err = parseCapabilities("0000000000000000")
+	require.NoError(t, err)
+	assert.Empty(t, caps)
+
+	_, err = parseCapabilities("nope")
+	assert.Error(t, err)
+}
+
+func TestCheckProcessAccess(t *testing.T) {
+	assert.Equal(t, errUnprivileged, checkProcessAccess(config.ModeUnprivileged, nil))
+	assert.EqualError(t, checkProcessAccess(config.ModeRoot, map[string]bool{"CAP_DAC_READ_SEARCH": true}), "missing CAP_SYS_PTRACE capability")
+	assert.NoError(t, checkProcessAccess(config.ModeRoot, map[string]bool{"CAP_SYS_PTRACE": true}))
+	// the capabilities couldn't be read
+	assert.NoError(t, checkProcessAccess(config.ModePrivileged, nil))
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package capabilities
+
+// Only Linux capabilities are probed.
+var probes []probe
+
+func effectiveCapabilities() (map[string]bool, error) {
+	return nil, nil
+}
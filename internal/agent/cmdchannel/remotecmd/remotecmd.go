@@ -0,0 +1,292 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package remotecmd handles the signed command channel commands operating the running agent: reloading its
+// configuration, restarting its integrations and setting its log level for a while. Only the commands allowed by the
+// local configuration are run, and every command received is reported with an audit event.
+package remotecmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/commandapi"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/offline"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/redact"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// Names of the commands.
+const (
+	ReloadConfig        = "reload_config"
+	RestartIntegrations = "restart_integrations"
+	SetLogLevel         = "set_log_level"
+)
+
+const (
+	auditEventType = "AgentRemoteCommand"
+
+	// maxLogLevelDuration limits the time the log level can be set for.
+	maxLogLevelDuration = 24 * time.Hour
+)
+
+// Outcomes of the commands, reported by the audit events.
+const (
+	OutcomeExecuted = "executed"
+	OutcomeRejected = "rejected"
+	OutcomeFailed   = "failed"
+)
+
+// Errors
+var (
+	ErrNotAllowed       = errors.New("command not allowed by command_channel_allowed_commands")
+	ErrInvalidSignature = errors.New("invalid command signature")
+	ErrCommandMismatch  = errors.New("signed payload is for another command")
+	ErrExpired          = errors.New("signed payload expired")
+	ErrReplayed         = errors.New("signed payload already executed")
+)
+
+var commands = []string{ReloadConfig, RestartIntegrations, SetLogLevel}
+
+// Args are the arguments of the signed commands. The payload is the JSON of a Payload, signed with ed25519 over its
+// SHA-256 checksum, as the remote configuration documents. Both fields are base64 encoded.
+type Args struct {
+	Payload   []byte `json:"payload"`
+	Signature []byte `json:"signature"`
+}
+
+// Payload binds the signature to the command and its expiration, so it can't be used for another command and it's
+// run only once.
+type Payload struct {
+	Command string          `json:"command"`
+	Expires time.Time       `json:"expires"`
+	Args    json.RawMessage `json:"args,omitempty"`
+}
+
+// LogLevelArgs are the arguments of the set_log_level command, restoring the previous level after the duration.
+type LogLevelArgs struct {
+	Level    string `json:"level"`
+	Duration string `json:"duration"`
+}
+
+// Actions run the commands on the agent.
+type Actions struct {
+	// ReloadConfig reloads the configuration, returning the applied options.
+	ReloadConfig func() ([]string, error)
+	// RestartIntegrations restarts the integrations, returning the number of restarted files.
+	RestartIntegrations func() int
+	// SetLogLevel sets the log level for the given duration.
+	SetLogLevel func(level logrus.Level, d time.Duration)
+}
+
+// AuditEvent reports a command received from the command channel and its outcome.
+type AuditEvent struct {
+	sample.BaseEvent
+
+	Command string `json:"command"`
+	CmdHash string `json:"cmdHash"`
+	Args    string `json:"args,omitempty"`
+	Outcome string `json:"outcome"`
+	Result  string `json:"result,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Handler verifies and runs the signed commands.
+type Handler struct {
+	allowed   map[string]bool
+	publicKey ed25519.PublicKey
+	actions   Actions
+	audit     func(sample.Event)
+	logger    log.Entry
+	now       func() time.Time
+
+	lock     sync.Mutex
+	executed map[[sha256.Size]byte]time.Time // checksums of the executed payloads, until they expire
+}
+
+// NewHandler creates the handler of the commands allowed by the configuration, reporting the audit events through
+// the audit function.
+func NewHandler(cfg *config.Config, actions Actions, audit func(sample.Event), logger log.Entry) (*Handler, error) {
+	allowed := map[string]bool{}
+	for _, name := range cfg.CommandChannelAllowedCommands {
+		if !isCommand(name) {
+			return nil, fmt.Errorf("unknown command %q in command_channel_allowed_commands, expected one of: %s",
+				name, strings.Join(commands, ", "))
+		}
+		allowed[name] = true
+	}
+	if cfg.CommandChannelPublicKeyFile == "" {
+		return nil, fmt.Errorf("command_channel_public_key_file is required to verify the allowed commands")
+	}
+	publicKey, err := offline.LoadPublicKey(cfg.CommandChannelPublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		allowed:   allowed,
+		publicKey: publicKey,
+		actions:   actions,
+		audit:     audit,
+		logger:    logger,
+		now:       time.Now,
+		executed:  map[[sha256.Size]byte]time.Time{},
+	}, nil
+}
+
+// CmdHandlers returns the command channel handlers of all the commands, so the ones not allowed are audited too.
+func (h *Handler) CmdHandlers() []*cmdchannel.CmdHandler {
+	handlers := make([]*cmdchannel.CmdHandler, 0, len(commands))
+	for _, name := range commands {
+		handlers = append(handlers, cmdchannel.NewCmdHandler(name, h.Handle))
+	}
+	return handlers
+}
+
+// Handle verifies the command and runs it when it's allowed, reporting its outcome with an audit event.
+func (h *Handler) Handle(_ context.Context, cmd commandapi.Command, _ bool) error {
+	h.logger.
+		WithField(config.TracesFieldName, config.FeatureTrace).
+		WithField("cmd_name", cmd.Name).
+		Trace("remote command request received")
+
+	event := &AuditEvent{Command: cmd.Name, CmdHash: cmd.Hash}
+	event.Type(auditEventType)
+	defer func() {
+		if h.audit != nil {
+			h.audit(event)
+		}
+	}()
+
+	payload, err := h.verify(cmd)
+	if err != nil {
+		event.Outcome, event.Error = OutcomeRejected, err.Error()
+		h.logger.WithField("cmd_name", cmd.Name).WithField("cmd_hash", cmd.Hash).WithError(err).
+			Warn("Remote command rejected.")
+		return err
+	}
+	event.Args = string(redact.JSON(payload.Args))
+
+	event.Result, err = h.run(cmd.Name, payload.Args)
+	if err != nil {
+		event.Outcome, event.Error = OutcomeFailed, err.Error()
+		return err
+	}
+	event.Outcome = OutcomeExecuted
+	h.logger.WithField("cmd_name", cmd.Name).WithField("cmd_hash", cmd.Hash).WithField("result", event.Result).
+		Info("Remote command executed.")
+	return nil
+}
+
+// verify checks the command is allowed and signed, returning its payload. Expired payloads are rejected, as the
+// ones already executed, so they can't be replayed.
+func (h *Handler) verify(cmd commandapi.Command) (Payload, error) {
+	if !h.allowed[cmd.Name] {
+		return Payload{}, ErrNotAllowed
+	}
+
+	var args Args
+	if err := json.Unmarshal(cmd.Args, &args); err != nil {
+		return Payload{}, cmdchannel.NewArgsErr(err)
+	}
+	checksum := sha256.Sum256(args.Payload)
+	if !ed25519.Verify(h.publicKey, checksum[:], args.Signature) {
+		return Payload{}, ErrInvalidSignature
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(args.Payload, &payload); err != nil {
+		return Payload{}, cmdchannel.NewArgsErr(err)
+	}
+	if payload.Command != cmd.Name {
+		return Payload{}, ErrCommandMismatch
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.now()
+	if !now.Before(payload.Expires) {
+		return Payload{}, ErrExpired
+	}
+	for executed, expires := range h.executed {
+		if !now.Before(expires) {
+			delete(h.executed, executed)
+		}
+	}
+	if _, ok := h.executed[checksum]; ok {
+		return Payload{}, ErrReplayed
+	}
+	h.executed[checksum] = payload.Expires
+	return payload, nil
+}
+
+// run executes the command, returning a description of its result.
+func (h *Handler) run(name string, rawArgs json.RawMessage) (string, error) {
+	switch name {
+	case ReloadConfig:
+		applied, err := h.actions.ReloadConfig()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("applied options: %s", strings.Join(applied, ", ")), nil
+
+	case RestartIntegrations:
+		return fmt.Sprintf("restarted files: %d", h.actions.RestartIntegrations()), nil
+
+	case SetLogLevel:
+		var args LogLevelArgs
+		if len(rawArgs) > 0 {
+			if err := json.Unmarshal(rawArgs, &args); err != nil {
+				return "", cmdchannel.NewArgsErr(err)
+			}
+		}
+		level, d, err := parseLogLevelArgs(args)
+		if err != nil {
+			return "", cmdchannel.NewArgsErr(err)
+		}
+		h.actions.SetLogLevel(level, d)
+		return fmt.Sprintf("log level %s for %s", level, d), nil
+	}
+	return "", fmt.Errorf("unknown command %q", name)
+}
+
+// parseLogLevelArgs validates the set_log_level arguments, the level being debug unless provided.
+func parseLogLevelArgs(args LogLevelArgs) (logrus.Level, time.Duration, error) {
+	level := logrus.DebugLevel
+	if args.Level != "" {
+		var err error
+		if level, err = log.ParseLevel(args.Level); err != nil {
+			return level, 0, err
+		}
+	}
+	d, err := time.ParseDuration(args.Duration)
+	if err != nil {
+		return level, 0, fmt.Errorf("invalid duration: %w", err)
+	}
+	if d <= 0 || d > maxLogLevelDuration {
+		return level, 0, fmt.Errorf("duration %s out of range, it must be greater than 0 and up to %s", d, maxLogLevelDuration)
+	}
+	return level, d, nil
+}
+
+func isCommand(name string) bool {
+	for _, c := range commands {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package remotecmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/commandapi"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+var (
+	l   = log.WithComponent("test")
+	now = time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+)
+
+type recorder struct {
+	reloads  int
+	restarts int
+	level    logrus.Level
+	duration time.Duration
+	events   []*AuditEvent
+}
+
+func (r *recorder) actions() Actions {
+	return Actions{
+		ReloadConfig: func() ([]string, error) {
+			r.reloads++
+			return []string{"custom_attributes"}, nil
+		},
+		RestartIntegrations: func() int {
+			r.restarts++
+			return 2
+		},
+		SetLogLevel: func(level logrus.Level, d time.Duration) {
+			r.level, r.duration = level, d
+		},
+	}
+}
+
+func (r *recorder) audit(e sample.Event) {
+	r.events = append(r.events, e.(*AuditEvent))
+}
+
+func newTestHandler(t *testing.T, allowed ...string) (*Handler, *recorder, ed25519.PrivateKey) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	cfg := config.NewConfig()
+	cfg.CommandChannelAllowedCommands = allowed
+	cfg.CommandChannelPublicKeyFile = filepath.Join(t.TempDir(), "commands.pem")
+	require.NoError(t, os.WriteFile(cfg.CommandChannelPublicKeyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600))
+
+	r := &recorder{}
+	h, err := NewHandler(cfg, r.actions(), r.audit, l)
+	require.NoError(t, err)
+	h.now = func() time.Time { return now }
+	return h, r, private
+}
+
+func signedCmd(t *testing.T, key ed25519.PrivateKey, name string, expires time.Time, args string) commandapi.Command {
+	t.Helper()
+
+	p := Payload{Command: name, Expires: expires}
+	if args != "" {
+		p.Args = json.RawMessage(args)
+	}
+	payload, err := json.Marshal(p)
+	require.NoError(t, err)
+	checksum := sha256.Sum256(payload)
+	cmdArgs, err := json.Marshal(Args{Payload: payload, Signature: ed25519.Sign(key, checksum[:])})
+	require.NoError(t, err)
+	return commandapi.Command{Name: name, Hash: "hash-" + name, Args: cmdArgs}
+}
+
+func TestHandle_Executed(t *testing.T) {
+	h, r, key := newTestHandler(t, ReloadConfig, RestartIntegrations, SetLogLevel)
+	expires := now.Add(time.Minute)
+
+	require.NoError(t, h.Handle(context.Background(), signedCmd(t, key, ReloadConfig, expires, ""), false))
+	require.NoError(t, h.Handle(context.Background(), signedCmd(t, key, RestartIntegrations, expires, ""), false))
+	require.NoError(t, h.Handle(context.Background(),
+		signedCmd(t, key, SetLogLevel, expires, `{"level": "trace", "duration": "15m"}`), false))
+
+	assert.Equal(t, 1, r.reloads)
+	assert.Equal(t, 1, r.restarts)
+	assert.Equal(t, logrus.TraceLevel, r.level)
+	assert.Equal(t, 15*time.Minute, r.duration)
+
+	require.Len(t, r.events, 3)
+	for _, e := range r.events {
+		assert.Equal(t, auditEventType, e.EventType)
+		assert.Equal(t, OutcomeExecuted, e.Outcome)
+		assert.Empty(t, e.Error)
+	}
+	assert.Equal(t, "hash-reload_config", r.events[0].CmdHash)
+	assert.Equal(t, "applied options: custom_attributes", r.events[0].Result)
+	assert.Equal(t, "restarted files: 2", r.events[1].Result)
+	assert.JSONEq(t, `{"level": "trace", "duration": "15m"}`, r.events[2].Args)
+}
+
+func TestHandle_Rejected(t *testing.T) {
+	h, r, key := newTestHandler(t, ReloadConfig, SetLogLevel)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	expires := now.Add(time.Minute)
+
+	mismatch := signedCmd(t, key, SetLogLevel, expires, `{"duration": "1m"}`)
+	mismatch.Name = ReloadConfig
+
+	tests := []struct {
+		name string
+		cmd  commandapi.Command
+		err  error
+	}{
+		{"not allowed", signedCmd(t, key, RestartIntegrations, expires, ""), ErrNotAllowed},
+		{"invalid signature", signedCmd(t, otherKey, ReloadConfig, expires, ""), ErrInvalidSignature},
+		{"another command", mismatch, ErrCommandMismatch},
+		{"expired", signedCmd(t, key, ReloadConfig, now, ""), ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.events = nil
+
+			err := h.Handle(context.Background(), tt.cmd, false)
+
+			assert.True(t, errors.Is(err, tt.err), err)
+			require.Len(t, r.events, 1)
+			assert.Equal(t, OutcomeRejected, r.events[0].Outcome)
+			assert.Equal(t, tt.err.Error(), r.events[0].Error)
+		})
+	}
+	assert.Zero(t, r.reloads)
+	assert.Zero(t, r.restarts)
+}
+
+func TestHandle_Replayed(t *testing.T) {
+	h, r, key := newTestHandler(t, ReloadConfig)
+	cmd := signedCmd(t, key, ReloadConfig, now.Add(time.Minute), "")
+
+	require.NoError(t, h.Handle(context.Background(), cmd, false))
+	assert.Equal(t, ErrReplayed, h.Handle(context.Background(), cmd, false))
+
+	assert.Equal(t, 1, r.reloads)
+	require.Len(t, r.events, 2)
+	assert.Equal(t, OutcomeRejected, r.events[1].Outcome)
+}
+
+func TestHandle_Failed(t *testing.T) {
+	h, r, key := newTestHandler(t, ReloadConfig, SetLogLevel)
+	h.actions.ReloadConfig = func() ([]string, error) {
+		return nil, errors.New("invalid configuration file")
+	}
+	expires := now.Add(time.Minute)
+
+	err := h.Handle(context.Background(), signedCmd(t, key, ReloadConfig, expires, ""), false)
+	assert.EqualError(t, err, "invalid configuration file")
+
+	err = h.Handle(context.Background(), signedCmd(t, key, SetLogLevel, expires, `{"duration": "48h"}`), false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), cmdchannel.ErrMsgInvalidArgs)
+
+	require.Len(t, r.events, 2)
+	assert.Equal(t, OutcomeFailed, r.events[0].Outcome)
+	assert.Equal(t, "invalid configuration file", r.events[0].Error)
+	assert.Equal(t, OutcomeFailed, r.events[1].Outcome)
+}
+
+func TestNewHandler_Invalid(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.CommandChannelAllowedCommands = []string{ReloadConfig}
+	_, err := NewHandler(cfg, Actions{}, nil, l)
+	assert.EqualError(t, err, "command_channel_public_key_file is required to verify the allowed commands")
+
+	cfg.CommandChannelAllowedCommands = []string{"shell"}
+	_, err = NewHandler(cfg, Actions{}, nil, l)
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package rundiagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/commandapi"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/redact"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+const (
+	cmdName = "run_diagnostics"
+	// ArtifactName is the name the diagnostics results are uploaded with.
+	ArtifactName = "diagnostics.json"
+)
+
+// Check runs a single diagnostics check, returning its JSON serializable result.
+type Check func(ctx context.Context) (interface{}, error)
+
+// Args are the arguments of the run_diagnostics command, all the checks run when none is provided.
+type Args struct {
+	Checks []string `json:"checks"`
+}
+
+// Result is a check result within the artifact.
+type Result struct {
+	DurationMs float64     `json:"duration_ms"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Artifact is the diagnostics run uploaded to the command API.
+type Artifact struct {
+	CmdHash      string            `json:"cmd_hash"`
+	AgentVersion string            `json:"agent_version"`
+	Started      time.Time         `json:"started"`
+	Checks       map[string]Result `json:"checks"`
+}
+
+// NewHandler creates a cmd-channel handler for run-diagnostics requests, running the requested checks and uploading
+// their results as an artifact of the command.
+func NewHandler(client commandapi.Client, agentID func() entity.ID, version string, checks map[string]Check, logger log.Entry) *cmdchannel.CmdHandler {
+	handleF := func(ctx context.Context, cmd commandapi.Command, initialFetch bool) (err error) {
+		logger.
+			WithField(config.TracesFieldName, config.FeatureTrace).
+			Trace("run diagnostics request received")
+
+		var args Args
+		if len(cmd.Args) > 0 {
+			if err = json.Unmarshal(cmd.Args, &args); err != nil {
+				return cmdchannel.NewArgsErr(err)
+			}
+		}
+
+		names := args.Checks
+		if len(names) == 0 {
+			for name := range checks {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+		for _, name := range names {
+			if _, ok := checks[name]; !ok {
+				return cmdchannel.NewArgsErr(fmt.Errorf("unknown diagnostics check %q", name))
+			}
+		}
+
+		artifact := Artifact{
+			CmdHash:      cmd.Hash,
+			AgentVersion: version,
+			Started:      time.Now(),
+			Checks:       make(map[string]Result, len(names)),
+		}
+		for _, name := range names {
+			artifact.Checks[name] = runCheck(ctx, checks[name])
+		}
+
+		content, err := json.Marshal(artifact)
+		if err != nil {
+			return fmt.Errorf("cannot marshal diagnostics: %w", err)
+		}
+		// the checks results, ie: errors, can contain secrets
+		content = redact.JSON(content)
+
+		if err = client.UploadArtifact(agentID(), cmd.Hash, ArtifactName, content); err != nil {
+			return fmt.Errorf("cannot upload diagnostics: %w", err)
+		}
+
+		logger.
+			WithField("cmd_hash", cmd.Hash).
+			WithField("checks", names).
+			Info("Diagnostics requested from the command channel uploaded.")
+		return nil
+	}
+
+	return cmdchannel.NewCmdHandler(cmdName, handleF)
+}
+
+func runCheck(ctx context.Context, check Check) (result Result) {
+	start := time.Now()
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			result.Error = fmt.Sprintf("check panicked: %v", panicErr)
+		}
+		result.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	}()
+
+	value, err := check(ctx)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Result = value
+	return result
+}
+
+// ConfigCheck dumps the running configuration, with the secrets hidden.
+func ConfigCheck(cfg *config.Config) Check {
+	return func(context.Context) (interface{}, error) {
+		return cfg.PublicFields()
+	}
+}
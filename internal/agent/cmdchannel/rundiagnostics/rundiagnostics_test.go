@@ -0,0 +1,115 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package rundiagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/cmdchannel"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/commandapi"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+var l = log.WithComponent("test")
+
+type uploadSpy struct {
+	commandapi.Client
+	agentID entity.ID
+	cmdHash string
+	name    string
+	content []byte
+	err     error
+}
+
+func (s *uploadSpy) UploadArtifact(agentID entity.ID, cmdHash string, name string, content []byte) error {
+	s.agentID, s.cmdHash, s.name, s.content = agentID, cmdHash, name, content
+	return s.err
+}
+
+func agentID() entity.ID {
+	return 123
+}
+
+func testChecks() map[string]Check {
+	cfg := config.NewConfig()
+	cfg.License = "0123456789abcdef0123456789abcdef0123NRAL"
+	return map[string]Check{
+		"config": ConfigCheck(cfg),
+		"samplers": func(context.Context) (interface{}, error) {
+			return []string{"SystemSampler"}, nil
+		},
+		"dnschecks": func(context.Context) (interface{}, error) {
+			return nil, errors.New("collector unreachable")
+		},
+	}
+}
+
+func TestHandle_AllChecks(t *testing.T) {
+	client := &uploadSpy{}
+	h := NewHandler(client, agentID, "1.2.3", testChecks(), l)
+
+	err := h.Handle(context.Background(), commandapi.Command{Name: cmdName, Hash: "abc"}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, entity.ID(123), client.agentID)
+	assert.Equal(t, "abc", client.cmdHash)
+	assert.Equal(t, ArtifactName, client.name)
+	assert.NotContains(t, string(client.content), "0123456789abcdef0123456789abcdef0123NRAL")
+
+	var artifact struct {
+		CmdHash      string `json:"cmd_hash"`
+		AgentVersion string `json:"agent_version"`
+		Checks       map[string]struct {
+			Result json.RawMessage `json:"result"`
+			Error  string          `json:"error"`
+		} `json:"checks"`
+	}
+	require.NoError(t, json.Unmarshal(client.content, &artifact))
+	assert.Equal(t, "abc", artifact.CmdHash)
+	assert.Equal(t, "1.2.3", artifact.AgentVersion)
+	require.Len(t, artifact.Checks, 3)
+	assert.JSONEq(t, `["SystemSampler"]`, string(artifact.Checks["samplers"].Result))
+	assert.Equal(t, "collector unreachable", artifact.Checks["dnschecks"].Error)
+	var cfg map[string]string
+	require.NoError(t, json.Unmarshal(artifact.Checks["config"].Result, &cfg))
+	assert.Equal(t, "<HIDDEN>", cfg["license_key"])
+}
+
+func TestHandle_SelectedChecks(t *testing.T) {
+	client := &uploadSpy{}
+	h := NewHandler(client, agentID, "1.2.3", testChecks(), l)
+
+	err := h.Handle(context.Background(), commandapi.Command{Hash: "abc", Args: []byte(`{"checks": ["samplers"]}`)}, false)
+	require.NoError(t, err)
+
+	var artifact Artifact
+	require.NoError(t, json.Unmarshal(client.content, &artifact))
+	assert.Len(t, artifact.Checks, 1)
+	assert.Contains(t, artifact.Checks, "samplers")
+}
+
+func TestHandle_InvalidArgs(t *testing.T) {
+	client := &uploadSpy{}
+	h := NewHandler(client, agentID, "1.2.3", testChecks(), l)
+
+	err := h.Handle(context.Background(), commandapi.Command{Args: []byte(`{"checks": ["shell"]}`)}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), cmdchannel.ErrMsgInvalidArgs)
+	assert.Nil(t, client.content)
+}
+
+func TestHandle_UploadError(t *testing.T) {
+	client := &uploadSpy{err: errors.New("status:500")}
+	h := NewHandler(client, agentID, "1.2.3", testChecks(), l)
+
+	err := h.Handle(context.Background(), commandapi.Command{Hash: "abc"}, false)
+	assert.EqualError(t, err, "cannot upload diagnostics: status:500")
+}
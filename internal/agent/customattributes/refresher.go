@@ -0,0 +1,162 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package customattributes resolves the custom attributes set from commands and files, refreshing them together
+// with the ones using databind variables so they stay current without restarting the agent.
+package customattributes
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+// commandTimeout limits the execution of the commands setting custom attributes.
+const commandTimeout = 10 * time.Second
+
+const customAttributesKey = "custom_attributes"
+
+var calog = log.WithComponent("CustomAttributes")
+
+// Refresher sets the custom attributes of the running configuration from their sources. The last value of an
+// attribute is kept while its source fails.
+type Refresher struct {
+	cfg      *config.Config
+	registry *config.Registry
+
+	lock       sync.Mutex
+	handlers   []config.ReloadHandler
+	last       map[string]string // last values read from commands and files
+	discovered map[string]string // attributes discovered by the agent, like the Kubernetes node ones
+}
+
+// NewRefresher creates the custom attributes refresher for the agent configuration.
+func NewRefresher(cfg *config.Config) (*Refresher, error) {
+	registry, err := config.NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+	return &Refresher{
+		cfg:      cfg,
+		registry: registry,
+		last:     map[string]string{},
+	}, nil
+}
+
+// OnApply registers a handler invoked when the custom attributes are modified.
+func (r *Refresher) OnApply(handler config.ReloadHandler) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.handlers = append(r.handlers, handler)
+}
+
+// SetDiscovered sets attributes discovered by the agent from its environment. They're reported on the next refresh,
+// unless a custom attribute with the same name is configured.
+func (r *Refresher) SetDiscovered(attributes map[string]string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.discovered = attributes
+}
+
+// Run refreshes the custom attributes every custom_attributes_refresh_sec, until the context is done. It returns
+// right away when refreshing is disabled.
+func (r *Refresher) Run(ctx context.Context) {
+	interval := time.Duration(r.cfg.CustomAttributesRefreshSec) * time.Second
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Refresh(ctx)
+		}
+	}
+}
+
+// Refresh resolves the custom attributes and sets them into the running configuration, returning whether they
+// were modified.
+func (r *Refresher) Refresh(ctx context.Context) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	running := r.registry.Values(r.cfg)
+	current, _ := running[customAttributesKey].(config.CustomAttributeMap)
+
+	// the databind variables are fetched again once their ttl expires
+	base := current
+	if provided := r.cfg.Provide(); provided != r.cfg {
+		base = provided.CustomAttributes
+	}
+
+	attributes := make(config.CustomAttributeMap, len(base)+len(r.discovered))
+	for name, value := range r.discovered {
+		attributes[name] = value
+	}
+	for name, value := range base {
+		attributes[name] = value
+	}
+	commands, _ := running["custom_attributes_commands"].(config.KeyValMap)
+	for name, command := range commands {
+		r.set(attributes, name, func() (string, error) {
+			return helpers.RunCommandLine(ctx, commandTimeout, command)
+		})
+	}
+	files, _ := running["custom_attributes_files"].(config.KeyValMap)
+	for name, file := range files {
+		r.set(attributes, name, func() (string, error) {
+			return readFile(file)
+		})
+	}
+
+	if reflect.DeepEqual(attributes, current) {
+		return false
+	}
+	if err := r.registry.SetValues(r.cfg, map[string]interface{}{customAttributesKey: attributes}); err != nil {
+		calog.WithError(err).Warn("Cannot set custom attributes.")
+		return false
+	}
+	calog.Debug("Custom attributes refreshed.")
+	for _, handler := range r.handlers {
+		handler([]string{customAttributesKey})
+	}
+	return true
+}
+
+// set sets the attribute from its source, or its last value when the source fails.
+func (r *Refresher) set(attributes config.CustomAttributeMap, name string, read func() (string, error)) {
+	value, err := read()
+	if err != nil {
+		last, ok := r.last[name]
+		calog.WithError(err).WithField("attribute", name).WithField("keepingLastValue", ok).
+			Warn("Cannot read custom attribute.")
+		if ok {
+			attributes[name] = last
+		}
+		return
+	}
+	r.last[name] = value
+	attributes[name] = value
+}
+
+// readFile returns the trimmed content of the file.
+func readFile(file string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package customattributes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefresher_Refresh(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses the echo command")
+	}
+	file := filepath.Join(t.TempDir(), "deployment_ring")
+	require.NoError(t, os.WriteFile(file, []byte("canary\n"), 0o600))
+
+	cfg := config.NewConfig()
+	cfg.CustomAttributes = config.CustomAttributeMap{"team": "ops", "chef_role": "from-file"}
+	cfg.CustomAttributesCommands = config.KeyValMap{"chef_role": `echo "  web server "`}
+	cfg.CustomAttributesFiles = config.KeyValMap{"deployment_ring": file}
+
+	r, err := NewRefresher(cfg)
+	require.NoError(t, err)
+	var applied []string
+	r.OnApply(func(keys []string) {
+		applied = append(applied, keys...)
+	})
+
+	assert.True(t, r.Refresh(context.Background()))
+	assert.Equal(t, config.CustomAttributeMap{
+		"team":            "ops",
+		"chef_role":       "web server",
+		"deployment_ring": "canary",
+	}, cfg.CustomAttributes)
+	assert.Equal(t, []string{"custom_attributes"}, applied)
+
+	// unchanged sources don't apply the attributes again
+	assert.False(t, r.Refresh(context.Background()))
+	assert.Len(t, applied, 1)
+
+	require.NoError(t, os.WriteFile(file, []byte("production"), 0o600))
+	assert.True(t, r.Refresh(context.Background()))
+	assert.Equal(t, "production", cfg.CustomAttributes["deployment_ring"])
+}
+
+func TestRefresher_RefreshKeepsLastValueOnError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deployment_ring")
+	require.NoError(t, os.WriteFile(file, []byte("canary"), 0o600))
+
+	cfg := config.NewConfig()
+	cfg.CustomAttributesCommands = config.KeyValMap{"chef_role": "command-not-found-for-test"}
+	cfg.CustomAttributesFiles = config.KeyValMap{"deployment_ring": file}
+
+	r, err := NewRefresher(cfg)
+	require.NoError(t, err)
+
+	assert.True(t, r.Refresh(context.Background()))
+	assert.Equal(t, config.CustomAttributeMap{"deployment_ring": "canary"}, cfg.CustomAttributes)
+
+	require.NoError(t, os.Remove(file))
+	assert.False(t, r.Refresh(context.Background()))
+	assert.Equal(t, config.CustomAttributeMap{"deployment_ring": "canary"}, cfg.CustomAttributes)
+}
+
+func TestRefresher_RunDisabled(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.CustomAttributesRefreshSec = 0
+
+	r, err := NewRefresher(cfg)
+	require.NoError(t, err)
+
+	// returns without waiting for the context
+	r.Run(context.Background())
+}
+
+func TestRefresher_RefreshDiscovered(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.CustomAttributes = config.CustomAttributeMap{"clusterName": "configured"}
+
+	r, err := NewRefresher(cfg)
+	require.NoError(t, err)
+	r.SetDiscovered(map[string]string{"clusterName": "discovered", "nodeName": "node-1"})
+
+	// configured attributes take precedence over the discovered ones
+	assert.True(t, r.Refresh(context.Background()))
+	assert.Equal(t, config.CustomAttributeMap{
+		"clusterName": "configured",
+		"nodeName":    "node-1",
+	}, cfg.CustomAttributes)
+	assert.False(t, r.Refresh(context.Background()))
+}
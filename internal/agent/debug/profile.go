@@ -0,0 +1,40 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package debug
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"time"
+)
+
+// DefaultMutexProfileDuration is the sampling window used when collecting mutex contention.
+const DefaultMutexProfileDuration = 5 * time.Second
+
+// mutexProfileFraction reports on average 1 out of N contention events while sampling.
+const mutexProfileFraction = 5
+
+// WriteStacks writes the stack traces of all the current goroutines in human readable format.
+func WriteStacks(w io.Writer) error {
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// WriteMutexProfile enables mutex contention sampling for the given duration and writes
+// the resulting profile in pprof format. Previous sampling rate is restored afterwards.
+func WriteMutexProfile(ctx context.Context, w io.Writer, duration time.Duration) error {
+	prev := runtime.SetMutexProfileFraction(mutexProfileFraction)
+	defer runtime.SetMutexProfileFraction(prev)
+
+	t := time.NewTimer(duration)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+	}
+
+	return pprof.Lookup("mutex").WriteTo(w, 0)
+}
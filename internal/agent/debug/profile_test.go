@@ -0,0 +1,40 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package debug
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteStacks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteStacks(buf))
+
+	assert.Contains(t, buf.String(), "TestWriteStacks")
+}
+
+func TestWriteMutexProfile_RestoresFraction(t *testing.T) {
+	prev := runtime.SetMutexProfileFraction(0)
+	defer runtime.SetMutexProfileFraction(prev)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteMutexProfile(context.Background(), buf, time.Millisecond))
+
+	assert.NotEmpty(t, buf.Bytes())
+	assert.Equal(t, 0, runtime.SetMutexProfileFraction(-1))
+}
+
+func TestWriteMutexProfile_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := WriteMutexProfile(ctx, &bytes.Buffer{}, time.Minute)
+	assert.ErrorIs(t, err, context.Canceled)
+}
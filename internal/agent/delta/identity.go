@@ -0,0 +1,55 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package delta
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+)
+
+// StoredLocalEntityID returns the agent ID the local entity inventory was last submitted with, stored in the data
+// directory, or an empty ID when none is stored.
+func StoredLocalEntityID(dataDir string) (entity.ID, error) {
+	return NewEntityIDFilePersist(dataDir, localEntityFolder).GetEntityID()
+}
+
+// ResetIdentity removes the agent identity state stored in the data directory: the agent ID and time of the last
+// inventory submissions, and the inventory deltas, so the next agent run registers and submits its whole inventory
+// as a new one. The directories in keep, ie: the payload spool, are preserved. It returns the removed paths.
+func ResetIdentity(dataDir string, keep ...string) (removed []string, err error) {
+	entries, err := os.ReadDir(dataDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kept := map[string]bool{}
+	for _, k := range keep {
+		kept[filepath.Clean(k)] = true
+	}
+
+	var errs []string
+	for _, e := range entries {
+		path := filepath.Join(dataDir, e.Name())
+		// besides the agent state folders, any other folder is an inventory plugin category
+		if !e.IsDir() || kept[path] {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		removed = append(removed, path)
+	}
+	if len(errs) > 0 {
+		return removed, fmt.Errorf("cannot remove agent identity state: %s", strings.Join(errs, ", "))
+	}
+	return removed, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package delta
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+)
+
+func TestStoredLocalEntityID(t *testing.T) {
+	dataDir := t.TempDir()
+
+	id, err := StoredLocalEntityID(dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, entity.EmptyID, id)
+
+	require.NoError(t, NewEntityIDFilePersist(dataDir, localEntityFolder).UpdateEntityID(123))
+
+	id, err = StoredLocalEntityID(dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, entity.ID(123), id)
+}
+
+func TestResetIdentity(t *testing.T) {
+	dataDir := t.TempDir()
+	require.NoError(t, NewEntityIDFilePersist(dataDir, localEntityFolder).UpdateEntityID(123))
+	require.NoError(t, NewLastSubmissionStore(dataDir, localEntityFolder).UpdateTime(time.Now()))
+	store := NewStore(dataDir, "localhost", maxInventorySize, true)
+	require.NoError(t, store.SavePluginSource("localhost", "metadata", "plugin", map[string]interface{}{"key": "value"}))
+	spool := filepath.Join(dataDir, "spool")
+	require.NoError(t, os.Mkdir(spool, DATA_DIR_MODE))
+	state := filepath.Join(dataDir, "maintenance.json")
+	require.NoError(t, os.WriteFile(state, []byte("{}"), DATA_FILE_MODE))
+
+	removed, err := ResetIdentity(dataDir, spool)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dataDir, CACHE_DIR),
+		filepath.Join(dataDir, SAMPLING_REPO),
+		filepath.Join(dataDir, lastEntityIDFolder),
+		filepath.Join(dataDir, lastSuccessSubmissionFolder),
+		filepath.Join(dataDir, "metadata"),
+	}, removed)
+	id, err := StoredLocalEntityID(dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, entity.EmptyID, id)
+	assert.DirExists(t, spool)
+	assert.FileExists(t, state)
+}
+
+func TestResetIdentity_MissingDataDir(t *testing.T) {
+	removed, err := ResetIdentity(filepath.Join(t.TempDir(), "missing"))
+
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
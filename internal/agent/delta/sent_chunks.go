@@ -0,0 +1,100 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package delta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+// SentChunksStore records the chunks of the large deltas accepted by the backend, so a submission interrupted by an
+// error or an agent restart resumes from the first chunk not sent. Only the delta being sent is kept by source.
+type SentChunksStore interface {
+	// IsSent returns whether the chunk of the delta, both identified by their digests, was accepted.
+	IsSent(source, deltaDigest, chunkDigest string) bool
+	// MarkSent records the chunk as accepted, forgetting the chunks of any other delta of the source.
+	MarkSent(source, deltaDigest, chunkDigest string) error
+	// Done forgets the chunks of the source, once its delta is complete.
+	Done(source string) error
+}
+
+type sentChunks struct {
+	DeltaDigest string          `json:"delta_digest"`
+	Chunks      map[string]bool `json:"chunks"`
+}
+
+// SentChunksFileStore persists the sent chunks in a JSON file.
+type SentChunksFileStore struct {
+	file    string
+	sources map[string]*sentChunks
+}
+
+// NewSentChunksStore creates a new SentChunksStore storing data in file.
+func NewSentChunksStore(dataDir, fileName string) SentChunksStore {
+	return &SentChunksFileStore{
+		file: filepath.Join(dataDir, sentChunksFolder, helpers.SanitizeFileName(fileName)),
+	}
+}
+
+func (s *SentChunksFileStore) load() {
+	if s.sources != nil {
+		return
+	}
+	s.sources = make(map[string]*sentChunks)
+	content, err := ioutil.ReadFile(s.file)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(content, &s.sources); err != nil {
+		slog.WithError(err).WithField("file", s.file).Warn("can't read sent chunks, resending them")
+		s.sources = make(map[string]*sentChunks)
+	}
+}
+
+func (s *SentChunksFileStore) IsSent(source, deltaDigest, chunkDigest string) bool {
+	s.load()
+	sent, ok := s.sources[source]
+	return ok && sent.DeltaDigest == deltaDigest && sent.Chunks[chunkDigest]
+}
+
+func (s *SentChunksFileStore) MarkSent(source, deltaDigest, chunkDigest string) error {
+	s.load()
+	sent, ok := s.sources[source]
+	if !ok || sent.DeltaDigest != deltaDigest {
+		sent = &sentChunks{DeltaDigest: deltaDigest, Chunks: make(map[string]bool)}
+		s.sources[source] = sent
+	}
+	sent.Chunks[chunkDigest] = true
+	return s.save()
+}
+
+func (s *SentChunksFileStore) Done(source string) error {
+	s.load()
+	if _, ok := s.sources[source]; !ok {
+		return nil
+	}
+	delete(s.sources, source)
+	return s.save()
+}
+
+func (s *SentChunksFileStore) save() error {
+	serialised, err := json.Marshal(s.sources)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.file)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err = os.MkdirAll(dir, DATA_DIR_MODE); err != nil {
+			return fmt.Errorf("sent chunks directory does not exist and cannot be created: %s", dir)
+		}
+	}
+
+	return ioutil.WriteFile(s.file, serialised, DATA_FILE_MODE)
+}
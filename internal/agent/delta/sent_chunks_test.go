@@ -0,0 +1,35 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package delta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSentChunksStore(t *testing.T) {
+	dataDir, err := TempDeltaStoreDir()
+	require.NoError(t, err)
+	s := NewSentChunksStore(dataDir, "entity-key")
+
+	require.NoError(t, s.MarkSent("packages/dpkg", "delta1", "chunk1"))
+	assert.True(t, s.IsSent("packages/dpkg", "delta1", "chunk1"))
+	assert.False(t, s.IsSent("packages/dpkg", "delta1", "chunk2"))
+	assert.False(t, s.IsSent("packages/rpm", "delta1", "chunk1"))
+
+	// the sent chunks survive restarts
+	s = NewSentChunksStore(dataDir, "entity-key")
+	assert.True(t, s.IsSent("packages/dpkg", "delta1", "chunk1"))
+
+	// a new delta of the source replaces the previous one
+	require.NoError(t, s.MarkSent("packages/dpkg", "delta2", "chunk1"))
+	assert.False(t, s.IsSent("packages/dpkg", "delta1", "chunk1"))
+	assert.True(t, s.IsSent("packages/dpkg", "delta2", "chunk1"))
+
+	require.NoError(t, s.Done("packages/dpkg"))
+	assert.False(t, s.IsSent("packages/dpkg", "delta2", "chunk1"))
+	s = NewSentChunksStore(dataDir, "entity-key")
+	assert.False(t, s.IsSent("packages/dpkg", "delta2", "chunk1"))
+}
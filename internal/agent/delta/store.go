@@ -23,6 +23,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/backend/inventoryapi"
 	"github.com/newrelic/infrastructure-agent/pkg/disk"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/redact"
 )
 
 const (
@@ -38,6 +39,9 @@ const (
 	DisableInventorySplit       = 0
 	lastSuccessSubmissionFolder = "last_success"
 	lastEntityIDFolder          = "last_entityID"
+	sentChunksFolder            = "sent_chunks"
+	// MaxChunkedInventorySize bounds the plugin inventory data when it can be submitted in chunks.
+	MaxChunkedInventorySize = 64 * 1000 * 1000
 )
 
 var EMPTY_DELTA = []byte{'{', '}'}
@@ -52,6 +56,7 @@ var nonEntityFolders = map[string]bool{
 	SAMPLING_REPO:               true,
 	lastSuccessSubmissionFolder: true,
 	lastEntityIDFolder:          true,
+	sentChunksFolder:            true,
 }
 
 type delta struct {
@@ -106,6 +111,8 @@ type Store struct {
 	lastSuccessSubmission time.Time
 	// if enabled, will save archive deltas in .sent files
 	archiveEnabled bool
+	// if enabled, plugin data larger than maxInventorySize is kept, as its deltas are submitted in chunks
+	chunkingEnabled bool
 }
 
 // NewStore creates a new Store and returns a pointer to it. If maxInventorySize <= 0, the inventory splitting is disabled
@@ -995,14 +1002,19 @@ func (s *Store) SavePluginSource(entityKey, category, term string, source map[st
 			return
 		}
 	}
+	sourceB = redact.JSON(sourceB)
 
-	if len(sourceB) > s.maxInventorySize {
+	maxSize := s.maxInventorySize
+	if s.chunkingEnabled {
+		maxSize = MaxChunkedInventorySize
+	}
+	if len(sourceB) > maxSize {
 		err = fmt.Errorf(
 			"Plugin data for entity %v plugin %v/%v is larger than max size of %v",
 			entityKey,
 			category,
 			term,
-			s.maxInventorySize,
+			maxSize,
 		)
 		return
 	}
@@ -1017,3 +1029,8 @@ func (s *Store) IsArchiveEnabled() bool {
 func (s *Store) SetArchiveEnabled(archiveEnabled bool) {
 	s.archiveEnabled = archiveEnabled
 }
+
+// SetChunkingEnabled allows plugin data up to MaxChunkedInventorySize, for senders submitting large deltas in chunks.
+func (s *Store) SetChunkingEnabled(chunkingEnabled bool) {
+	s.chunkingEnabled = chunkingEnabled
+}
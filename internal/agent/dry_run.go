@@ -0,0 +1,76 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/maintenance"
+	"github.com/newrelic/infrastructure-agent/internal/feature_flags"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sampler"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/hostname"
+)
+
+// writerSender writes the events as JSON, one per line, instead of sending them to New Relic.
+type writerSender struct {
+	ctx  *context
+	w    io.Writer
+	lock sync.Mutex
+}
+
+func (s *writerSender) QueueEvent(event sample.Event, key entity.Key) error {
+	if key == "" {
+		key = entity.Key(s.ctx.EntityKey())
+	}
+	event.Entity(key)
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshalling event to JSON: %+v (%+v)", event, err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err = fmt.Fprintf(s.w, "%s\n", data)
+	return err
+}
+
+func (s *writerSender) Start() error {
+	return nil
+}
+
+func (s *writerSender) Stop() error {
+	return nil
+}
+
+// NewDryRunContext creates an agent context writing the events to w instead of sending them, for the dry-run mode.
+// The agent key is resolved as the agent does, without connecting to New Relic. The events are decorated and
+// filtered as the agent does too, but with no agent ID and no custom attributes, which are added when sending them.
+func NewDryRunContext(cfg *config.Config, buildVersion string, ffRetriever feature_flags.Retriever, w io.Writer) (*context, error) {
+	hostnameResolver := hostname.CreateResolver(
+		cfg.OverrideHostname, cfg.OverrideHostnameShort, cfg.DnsHostnameResolution)
+
+	cloudHarvester := cloud.NewDetector(cfg.DisableCloudMetadata, cfg.CloudMaxRetryCount, cfg.CloudRetryBackOffSec, cfg.CloudMetadataExpiryInSec, cfg.CloudMetadataDisableKeepAlive)
+	cloudHarvester.Initialize(cloud.WithIMDSv2Only(cfg.AWSIMDSv2Only), cloud.WithProvider(cloud.Type(cfg.CloudProvider)))
+
+	idLookupTable := NewIdLookup(hostnameResolver, cloudHarvester, cfg.DisplayName)
+	sampleMatchFn := sampler.NewSampleMatchFn(cfg.EnableProcessMetrics, cfg.IncludeMetricsMatchers, ffRetriever)
+	ctx := NewContext(cfg, buildVersion, hostnameResolver, idLookupTable, sampleMatchFn)
+	ctx.maintenance = maintenance.NewWindow(cfg, DataDir(cfg))
+
+	agentKey, err := idLookupTable.AgentKey()
+	if err != nil {
+		return nil, err
+	}
+	ctx.setAgentKey(agentKey)
+	ctx.eventSender = &writerSender{ctx: ctx, w: w}
+
+	return ctx, nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/feature_flags"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+)
+
+func TestNewDryRunContext(t *testing.T) {
+	cfg := &config.Config{
+		OverrideHostname:     "my-host.example.com",
+		DisableCloudMetadata: true,
+		DisplayName:          "my-display-name",
+	}
+	var out bytes.Buffer
+
+	ctx, err := NewDryRunContext(cfg, "1.2.3", feature_flags.NewManager(nil), &out)
+	require.NoError(t, err)
+
+	ctx.SendEvent(mapEvent{"eventType": "SystemSample", "cpuPercent": 1.5}, "")
+	ctx.SendEvent(mapEvent{"eventType": "ContainerSample"}, entity.Key("container:123"))
+
+	assert.Equal(t, "my-display-name", ctx.EntityKey())
+	assert.Equal(t,
+		`{"cpuPercent":1.5,"entityKey":"my-display-name","eventType":"SystemSample"}`+"\n"+
+			`{"entityKey":"container:123","eventType":"ContainerSample"}`+"\n",
+		out.String())
+}
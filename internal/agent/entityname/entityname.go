@@ -0,0 +1,222 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package entityname resolves the entity name of the host from an ordered list of strategies, keeping the name
+// stable when the strategy that resolved it fails temporarily.
+package entityname
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/hostname"
+)
+
+// Strategies resolving the entity name.
+const (
+	InstanceID = "instance_id"
+	FQDN       = "fqdn"
+	Hostname   = "hostname"
+	Command    = "command"
+	Template   = "template"
+)
+
+// stateFile is the file, in the agent data directory, storing the last resolved name.
+const stateFile = "entity_name.json"
+
+// commandTimeout limits the execution of the entity name command.
+const commandTimeout = 10 * time.Second
+
+var elog = log.WithComponent("EntityName")
+
+// ErrNotResolved is returned when none of the strategies resolves a name.
+var ErrNotResolved = errors.New("no entity name strategy resolved a name")
+
+// state is the last resolved name and the strategy that resolved it.
+type state struct {
+	Strategy string `json:"strategy"`
+	Name     string `json:"name"`
+}
+
+// Resolver resolves the entity name with the configured strategies.
+type Resolver struct {
+	strategies []string
+	command    string
+	template   *template.Template
+	hostname   hostname.Resolver
+	cloud      cloud.Harvester
+	stateFile  string
+}
+
+// NewResolver creates the resolver for the entity_name_strategies of the configuration. The resolved names are
+// stored in the data directory, unless it's empty.
+func NewResolver(cfg *config.Config, hostnameResolver hostname.Resolver, cloudHarvester cloud.Harvester, dataDir string) (*Resolver, error) {
+	r := &Resolver{
+		strategies: cfg.EntityNameStrategies,
+		command:    cfg.EntityNameCommand,
+		hostname:   hostnameResolver,
+		cloud:      cloudHarvester,
+	}
+	if dataDir != "" {
+		r.stateFile = filepath.Join(dataDir, stateFile)
+	}
+
+	for _, strategy := range r.strategies {
+		switch strategy {
+		case InstanceID, FQDN, Hostname:
+		case Command:
+			if r.command == "" {
+				return nil, fmt.Errorf("entity_name_command is required by the %s entity name strategy", Command)
+			}
+		case Template:
+			if cfg.EntityNameTemplate == "" {
+				return nil, fmt.Errorf("entity_name_template is required by the %s entity name strategy", Template)
+			}
+			tmpl, err := template.New(Template).Option("missingkey=error").Parse(cfg.EntityNameTemplate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid entity_name_template: %w", err)
+			}
+			r.template = tmpl
+		default:
+			return nil, fmt.Errorf("unknown entity name strategy %q, expected one of: %s", strategy,
+				strings.Join([]string{InstanceID, FQDN, Hostname, Command, Template}, ", "))
+		}
+	}
+	return r, nil
+}
+
+// Resolve returns the name resolved by the first strategy that succeeds. When the strategy of the previously
+// resolved name fails, or a strategy preferred to it, the previous name is kept instead of falling back to the next
+// strategies, so the entity is not renamed by transient failures.
+func (r *Resolver) Resolve() (string, error) {
+	previous, previousIndex := r.previous()
+
+	values := map[string]string{}
+	for i, strategy := range r.strategies {
+		name, err := r.resolve(strategy, values)
+		if err != nil || name == "" {
+			elog.WithError(err).WithField("strategy", strategy).Debug("Entity name strategy didn't resolve a name.")
+			continue
+		}
+		if previousIndex >= 0 && previousIndex < i {
+			elog.WithField("strategy", previous.Strategy).WithField("name", previous.Name).
+				Warn("Entity name strategy failed, keeping the previous name.")
+			return previous.Name, nil
+		}
+		r.store(state{Strategy: strategy, Name: name})
+		return name, nil
+	}
+
+	if previousIndex >= 0 {
+		elog.WithField("strategy", previous.Strategy).WithField("name", previous.Name).
+			Warn("No entity name strategy resolved a name, keeping the previous name.")
+		return previous.Name, nil
+	}
+	return "", ErrNotResolved
+}
+
+// resolve returns the name resolved by the strategy, caching the values the template strategy can combine.
+func (r *Resolver) resolve(strategy string, values map[string]string) (name string, err error) {
+	if name, ok := values[strategy]; ok {
+		return name, nil
+	}
+	switch strategy {
+	case InstanceID:
+		name, err = r.cloud.GetInstanceID()
+	case FQDN:
+		name = r.hostname.Long()
+	case Hostname:
+		_, name, err = r.hostname.Query()
+	case Command:
+		name, err = helpers.RunCommandLine(context.Background(), commandTimeout, r.command)
+	case Template:
+		return r.executeTemplate(values)
+	}
+	if err == nil && name != "" {
+		values[strategy] = name
+	}
+	return name, err
+}
+
+// executeTemplate combines the values of the other strategies, failing when any of them is not resolved.
+func (r *Resolver) executeTemplate(values map[string]string) (string, error) {
+	resolved := map[string]string{}
+	for _, strategy := range []string{InstanceID, FQDN, Hostname, Command} {
+		if strategy == Command && r.command == "" {
+			continue
+		}
+		if name, err := r.resolve(strategy, values); err == nil && name != "" {
+			resolved[strategy] = name
+		}
+	}
+
+	var name bytes.Buffer
+	if err := r.template.Execute(&name, resolved); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(name.String()), nil
+}
+
+// previous returns the stored name and the position of its strategy, -1 when there's none or its strategy is no
+// longer configured.
+func (r *Resolver) previous() (state, int) {
+	if r.stateFile == "" {
+		return state{}, -1
+	}
+	content, err := os.ReadFile(r.stateFile)
+	if err != nil {
+		return state{}, -1
+	}
+	var s state
+	if err = json.Unmarshal(content, &s); err != nil || s.Name == "" {
+		elog.WithError(err).WithField("file", r.stateFile).Debug("Ignoring invalid entity name state.")
+		return state{}, -1
+	}
+	for i, strategy := range r.strategies {
+		if strategy == s.Strategy {
+			return s, i
+		}
+	}
+	return state{}, -1
+}
+
+func (r *Resolver) store(s state) {
+	if r.stateFile == "" {
+		return
+	}
+	content, err := json.Marshal(s)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(r.stateFile), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(r.stateFile, content, 0o644)
+	}
+	if err != nil {
+		elog.WithError(err).WithField("file", r.stateFile).Warn("Cannot store the entity name.")
+	}
+}
+
+// ResetState removes the name stored in the data directory, so it's resolved again from the preferred strategy. It
+// returns the removed file, empty when there was none.
+func ResetState(dataDir string) (string, error) {
+	file := filepath.Join(dataDir, stateFile)
+	if err := os.Remove(file); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return file, nil
+}
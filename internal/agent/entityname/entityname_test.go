@@ -0,0 +1,132 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package entityname
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHostname struct {
+	full, short string
+	err         error
+}
+
+func (f *fakeHostname) Query() (string, string, error) {
+	return f.full, f.short, f.err
+}
+
+func (f *fakeHostname) Long() string {
+	return f.full
+}
+
+type fakeCloud struct {
+	cloud.Harvester
+	instanceID string
+	err        error
+}
+
+func (f *fakeCloud) GetInstanceID() (string, error) {
+	return f.instanceID, f.err
+}
+
+func TestNewResolver_InvalidStrategies(t *testing.T) {
+	tests := map[string]*config.Config{
+		"unknown strategy":   {EntityNameStrategies: []string{"mac_address"}},
+		"missing command":    {EntityNameStrategies: []string{Command}},
+		"missing template":   {EntityNameStrategies: []string{Template}},
+		"malformed template": {EntityNameStrategies: []string{Template}, EntityNameTemplate: "{{.hostname"},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewResolver(cfg, &fakeHostname{}, &fakeCloud{}, "")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	host := &fakeHostname{full: "web-1.example.com", short: "web-1"}
+	noCloud := &fakeCloud{err: errors.New("not in a cloud")}
+
+	tests := []struct {
+		name       string
+		strategies []string
+		template   string
+		expected   string
+	}{
+		{"first resolved", []string{InstanceID, FQDN, Hostname}, "", "web-1.example.com"},
+		{"short hostname", []string{Hostname, FQDN}, "", "web-1"},
+		{"template", []string{Template}, "{{.hostname}}-ring", "web-1-ring"},
+		{"template with unresolved value", []string{Template, Hostname}, "{{.hostname}}-{{.instance_id}}", "web-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{EntityNameStrategies: tt.strategies, EntityNameTemplate: tt.template}
+			r, err := NewResolver(cfg, host, noCloud, "")
+			require.NoError(t, err)
+
+			name, err := r.Resolve()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, name)
+		})
+	}
+}
+
+func TestResolver_ResolveNone(t *testing.T) {
+	cfg := &config.Config{EntityNameStrategies: []string{InstanceID}}
+	r, err := NewResolver(cfg, &fakeHostname{}, &fakeCloud{err: errors.New("not in a cloud")}, "")
+	require.NoError(t, err)
+
+	_, err = r.Resolve()
+	assert.Equal(t, ErrNotResolved, err)
+}
+
+func TestResolver_ResolveKeepsPreviousName(t *testing.T) {
+	dataDir := t.TempDir()
+	host := &fakeHostname{full: "web-1.example.com", short: "web-1"}
+	cfg := &config.Config{EntityNameStrategies: []string{FQDN, Hostname}}
+
+	r, err := NewResolver(cfg, host, &fakeCloud{}, dataDir)
+	require.NoError(t, err)
+	name, err := r.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "web-1.example.com", name)
+
+	// the FQDN is not resolved, ie: DNS failure, so the name doesn't fall back to the short hostname
+	host.full = ""
+	r, err = NewResolver(cfg, host, &fakeCloud{}, dataDir)
+	require.NoError(t, err)
+	name, err = r.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "web-1.example.com", name)
+
+	// the same strategy resolving another name renames the entity
+	host.full = "web-2.example.com"
+	name, err = r.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "web-2.example.com", name)
+}
+
+func TestResetState(t *testing.T) {
+	dataDir := t.TempDir()
+	r, err := NewResolver(&config.Config{EntityNameStrategies: []string{Hostname}}, &fakeHostname{short: "web-1"}, &fakeCloud{}, dataDir)
+	require.NoError(t, err)
+	_, err = r.Resolve()
+	require.NoError(t, err)
+
+	file, err := ResetState(dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dataDir, stateFile), file)
+	assert.NoFileExists(t, file)
+
+	file, err = ResetState(dataDir)
+	require.NoError(t, err)
+	assert.Empty(t, file)
+}
@@ -0,0 +1,262 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	goContext "context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/bus"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/otlpapi"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// OTLP_MAX_QUEUED_GAUGES is the maximum amount of data points waiting to be exported, so an unavailable collector
+// doesn't make the agent memory grow.
+const OTLP_MAX_QUEUED_GAUGES = 100000
+
+// otlpServiceName identifies the agent as the OTLP resource service and instrumentation scope.
+const otlpServiceName = "newrelic-infra"
+
+var olog = log.WithComponent("OTLPExporter")
+
+// hostSampleEventTypes are the host samples exported through OTLP and Prometheus.
+var hostSampleEventTypes = map[string]bool{
+	"SystemSample":  true,
+	"ProcessSample": true,
+	"StorageSample": true,
+}
+
+// sampleNumericAttributes are the numeric sample fields identifying the data points, rather than measuring anything.
+var sampleNumericAttributes = map[string]bool{
+	"processId":       true,
+	"parentProcessId": true,
+}
+
+// sampleIgnoredFields aren't exported, as they are part of the metric name or the data point time.
+var sampleIgnoredFields = map[string]bool{
+	"eventType": true,
+	"timestamp": true,
+}
+
+// otlpSubscriber is the event bus subscriber exporting the host samples through OTLP. The oldest samples are dropped
+// when it falls behind, as the newer ones are the relevant ones for host monitoring.
+const otlpSubscriber = "otlp"
+
+// exportOTLP subscribes the OTLP exporter of the host samples to the event bus. In exclusive mode the host samples
+// are only published to the bus, while the rest of the events keep being sent to New Relic.
+func (a *Agent) exportOTLP() error {
+	cfg := a.Context.Config()
+	httpClient := backendhttp.GetHttpClient(backendhttp.ClientTimeout, backendhttp.NewReloadableExternalTransport(cfg, backendhttp.ClientTimeout))
+	client, err := otlpapi.NewClient(otlpapi.Config{
+		Endpoint: cfg.OTLPExport.Endpoint,
+		Protocol: cfg.OTLPExport.Protocol,
+		Headers:  cfg.OTLPExport.Headers,
+		Insecure: cfg.OTLPExport.Insecure,
+	}, httpClient.Do)
+	if err != nil {
+		return err
+	}
+
+	exporter := newOTLPExporter(a.Context, client, time.Duration(cfg.OTLPExport.Interval)*time.Second)
+	if err = subscribeOTLP(a.Context.eventBus, exporter); err != nil {
+		return err
+	}
+	a.otlpExporter = exporter
+	if cfg.OTLPExport.Exclusive {
+		a.Context.busOnlyEventTypes = hostSampleEventTypes
+	}
+
+	olog.WithField("endpoint", cfg.OTLPExport.Endpoint).
+		WithField("protocol", cfg.OTLPExport.Protocol).
+		WithField("exclusive", cfg.OTLPExport.Exclusive).
+		Info("Exporting samples through OTLP.")
+	return nil
+}
+
+// subscribeOTLP queues the host samples published to the event bus to be exported.
+func subscribeOTLP(eventBus *bus.Bus, exporter *otlpExporter) error {
+	return eventBus.Subscribe(bus.SubscriberConfig{
+		Name:        otlpSubscriber,
+		DropPolicy:  bus.DropOldest,
+		MaxAttempts: 1,
+	}, func(msg bus.Message) error {
+		return exporter.queue(msg.Event, msg.EntityKey)
+	})
+}
+
+// otlpExporter periodically exports the queued samples as OTLP gauges.
+type otlpExporter struct {
+	context  AgentContext
+	client   otlpapi.Client
+	interval time.Duration
+
+	lock   sync.Mutex
+	gauges []otlpapi.Gauge
+
+	stopChannel chan struct{}
+	done        sync.WaitGroup
+}
+
+func newOTLPExporter(ctx AgentContext, client otlpapi.Client, interval time.Duration) *otlpExporter {
+	return &otlpExporter{
+		context:  ctx,
+		client:   client,
+		interval: interval,
+	}
+}
+
+// queue converts the host samples to gauges and queues them, ignoring the rest of events.
+func (e *otlpExporter) queue(event sample.Event, key entity.Key) error {
+	eventType, fields, err := hostSampleFields(e.context, event, key)
+	if err != nil || fields == nil {
+		return err
+	}
+	gauges := eventGauges(eventType, fields, time.Now())
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(e.gauges)+len(gauges) > OTLP_MAX_QUEUED_GAUGES {
+		return fmt.Errorf("could not queue %s: OTLP export queue is full", eventType)
+	}
+	e.gauges = append(e.gauges, gauges...)
+	return nil
+}
+
+// hostSampleFields returns the event type and fields of host samples, nil fields for the rest of events. The event
+// isn't modified, as it's shared with the event bus subscribers.
+func hostSampleFields(ctx AgentContext, event sample.Event, key entity.Key) (string, map[string]interface{}, error) {
+	// Default to the agent's own ID if we didn't receive one
+	if key == "" {
+		key = entity.Key(ctx.EntityKey())
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", nil, fmt.Errorf("error marshalling event to JSON: %v", err)
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return "", nil, fmt.Errorf("error unmarshalling event JSON: %v", err)
+	}
+
+	eventType, _ := fields["eventType"].(string)
+	if !hostSampleEventTypes[eventType] {
+		return eventType, nil, nil
+	}
+	fields["entityKey"] = string(key)
+	return eventType, fields, nil
+}
+
+// eventGauges returns a gauge for every numeric field of the event, with its string fields as attributes.
+func eventGauges(eventType string, fields map[string]interface{}, now time.Time) []otlpapi.Gauge {
+	timestamp := now
+	if seconds, ok := fields["timestamp"].(float64); ok && seconds > 0 {
+		timestamp = time.Unix(int64(seconds), 0)
+	}
+
+	attributes := make(map[string]string)
+	values := make(map[string]float64)
+	for name, value := range fields {
+		if sampleIgnoredFields[name] {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			attributes[name] = v
+		case bool:
+			attributes[name] = strconv.FormatBool(v)
+		case float64:
+			if sampleNumericAttributes[name] {
+				attributes[name] = strconv.FormatFloat(v, 'f', -1, 64)
+			} else if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				values[name] = v
+			}
+		}
+	}
+
+	gauges := make([]otlpapi.Gauge, 0, len(values))
+	for name, value := range values {
+		gauges = append(gauges, otlpapi.Gauge{
+			Name:       eventType + "." + name,
+			Attributes: attributes,
+			Value:      value,
+			Time:       timestamp,
+		})
+	}
+	return gauges
+}
+
+func (e *otlpExporter) start() {
+	e.stopChannel = make(chan struct{})
+	e.done.Add(1)
+	go func() {
+		defer e.done.Done()
+
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.export()
+			case <-e.stopChannel:
+				// export the remaining samples before leaving
+				e.export()
+				return
+			}
+		}
+	}()
+}
+
+func (e *otlpExporter) stop() {
+	if e.stopChannel == nil {
+		return
+	}
+	close(e.stopChannel)
+	e.done.Wait()
+	e.stopChannel = nil
+
+	if err := e.client.Close(); err != nil {
+		olog.WithError(err).Debug("Error closing the OTLP client.")
+	}
+}
+
+// export sends the queued gauges. They are discarded when the export fails, as retrying them would delay the newer
+// samples, which are the relevant ones for host monitoring.
+func (e *otlpExporter) export() {
+	e.lock.Lock()
+	gauges := e.gauges
+	e.gauges = nil
+	e.lock.Unlock()
+
+	if len(gauges) == 0 {
+		return
+	}
+
+	resource := map[string]string{"service.name": otlpServiceName}
+	if fullHostname, _, err := e.context.HostnameResolver().Query(); err == nil {
+		resource["host.name"] = fullHostname
+	}
+
+	ctx, cancel := goContext.WithTimeout(goContext.Background(), backendhttp.ClientTimeout)
+	defer cancel()
+	err := e.client.Export(ctx, otlpapi.Batch{
+		Resource:     resource,
+		ScopeName:    otlpServiceName,
+		ScopeVersion: e.context.Version(),
+		Gauges:       gauges,
+	})
+	if err != nil {
+		olog.WithError(err).WithField("dataPoints", len(gauges)).Warn("OTLP export failed, discarding data points.")
+		return
+	}
+	olog.WithField("dataPoints", len(gauges)).Debug("Samples exported through OTLP.")
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	goContext "context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/testhelpers"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/otlpapi"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingEventSender struct {
+	eventTypes []interface{}
+}
+
+func (r *recordingEventSender) QueueEvent(event sample.Event, _ entity.Key) error {
+	r.eventTypes = append(r.eventTypes, event.(mapEvent)["eventType"])
+	return nil
+}
+
+func (r *recordingEventSender) Start() error {
+	return nil
+}
+
+func (r *recordingEventSender) Stop() error {
+	return nil
+}
+
+type fakeOTLPClient struct {
+	lock    sync.Mutex
+	batches []otlpapi.Batch
+	closed  bool
+}
+
+func (f *fakeOTLPClient) Export(_ goContext.Context, batch otlpapi.Batch) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.batches = append(f.batches, batch)
+	return nil
+}
+
+func (f *fakeOTLPClient) Close() error {
+	f.closed = true
+	return nil
+}
+
+func newOTLPTestContext(exclusive bool) (*context, *recordingEventSender, *otlpExporter, *fakeOTLPClient) {
+	ctx := NewContext(
+		&config.Config{},
+		"1.2.3",
+		testhelpers.NewFakeHostnameResolver("my-host.example.com", "my-host", nil),
+		NilIDLookup,
+		func(sample interface{}) bool { return true },
+	)
+	ctx.agentKey.Store("my-host")
+	if exclusive {
+		ctx.busOnlyEventTypes = hostSampleEventTypes
+	}
+
+	newRelic := &recordingEventSender{}
+	ctx.eventSender = newRelic
+	client := &fakeOTLPClient{}
+	return ctx, newRelic, newOTLPExporter(ctx, client, time.Hour), client
+}
+
+func TestOTLPSubscriber_Export(t *testing.T) {
+	ctx, newRelic, exporter, client := newOTLPTestContext(false)
+	require.NoError(t, subscribeOTLP(ctx.eventBus, exporter))
+	exporter.start()
+
+	ctx.SendEvent(mapEvent{
+		"eventType":          "ProcessSample",
+		"timestamp":          float64(1700000000),
+		"processDisplayName": "nginx",
+		"processId":          float64(42),
+		"cpuPercent":         1.5,
+	}, "")
+	ctx.SendEvent(mapEvent{"eventType": "NetworkSample", "receiveBytesPerSecond": 10.0}, "")
+	require.NoError(t, ctx.eventBus.Close(goContext.Background()))
+	exporter.stop()
+
+	// samples keep being sent to New Relic
+	assert.Equal(t, []interface{}{"ProcessSample", "NetworkSample"}, newRelic.eventTypes)
+	assert.Equal(t, otlpSubscriber, ctx.eventBus.Stats()[0].Name)
+
+	require.Len(t, client.batches, 1)
+	assert.Equal(t, otlpapi.Batch{
+		Resource:     map[string]string{"service.name": "newrelic-infra", "host.name": "my-host.example.com"},
+		ScopeName:    "newrelic-infra",
+		ScopeVersion: "1.2.3",
+		Gauges: []otlpapi.Gauge{{
+			Name: "ProcessSample.cpuPercent",
+			Attributes: map[string]string{
+				"entityKey":          "my-host",
+				"processDisplayName": "nginx",
+				"processId":          "42",
+			},
+			Value: 1.5,
+			Time:  time.Unix(1700000000, 0),
+		}},
+	}, client.batches[0])
+	assert.True(t, client.closed)
+}
+
+func TestOTLPSubscriber_Exclusive(t *testing.T) {
+	ctx, newRelic, exporter, client := newOTLPTestContext(true)
+	require.NoError(t, subscribeOTLP(ctx.eventBus, exporter))
+	exporter.start()
+
+	ctx.SendEvent(mapEvent{"eventType": "SystemSample", "cpuPercent": 10.0}, "")
+	ctx.SendEvent(mapEvent{"eventType": "StorageSample", "diskUsedPercent": 50.0}, "")
+	ctx.SendEvent(mapEvent{"eventType": "NetworkSample", "receiveBytesPerSecond": 10.0}, "")
+	require.NoError(t, ctx.eventBus.Close(goContext.Background()))
+	exporter.stop()
+
+	// only the events which are not exported are sent to New Relic
+	assert.Equal(t, []interface{}{"NetworkSample"}, newRelic.eventTypes)
+	require.Len(t, client.batches, 1)
+	assert.Len(t, client.batches[0].Gauges, 2)
+}
+
+func TestEventGauges(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	gauges := eventGauges("StorageSample", map[string]interface{}{
+		"eventType":       "StorageSample",
+		"mountPoint":      "/",
+		"isReadOnly":      false,
+		"diskUsedPercent": 50.0,
+		"diskFreeBytes":   1024.0,
+	}, now)
+
+	require.Len(t, gauges, 2)
+	values := map[string]float64{}
+	for _, g := range gauges {
+		values[g.Name] = g.Value
+		assert.Equal(t, map[string]string{"mountPoint": "/", "isReadOnly": "false"}, g.Attributes)
+		assert.Equal(t, now, g.Time)
+	}
+	assert.Equal(t, map[string]float64{"StorageSample.diskUsedPercent": 50, "StorageSample.diskFreeBytes": 1024}, values)
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/bus"
+	promInstrumentation "github.com/newrelic/infrastructure-agent/internal/instrumentation"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+// PROMETHEUS_MIN_TTL is the minimum time the host samples are exposed without being updated, as the process samples
+// are not harvested more often than every 20 seconds.
+const PROMETHEUS_MIN_TTL = time.Minute
+
+// prometheusSubscriber is the event bus subscriber exposing the host samples through Prometheus. Only the last value
+// of a series is exposed, so the oldest samples are dropped when it falls behind.
+const prometheusSubscriber = "prometheus"
+
+var plog = log.WithComponent("PrometheusExporter")
+
+// subscribePrometheus exposes the last value of the host samples published to the event bus, which keep being sent
+// to New Relic.
+func subscribePrometheus(ctx AgentContext, eventBus *bus.Bus, prometheus *promInstrumentation.Prometheus) error {
+	return eventBus.Subscribe(bus.SubscriberConfig{
+		Name:        prometheusSubscriber,
+		DropPolicy:  bus.DropOldest,
+		MaxAttempts: 1,
+	}, prometheusHandler(ctx, prometheus))
+}
+
+func prometheusHandler(ctx AgentContext, prometheus *promInstrumentation.Prometheus) bus.Handler {
+	return func(msg bus.Message) error {
+		eventType, fields, err := hostSampleFields(ctx, msg.Event, msg.EntityKey)
+		if err != nil {
+			plog.WithError(err).Warn("Cannot expose event.")
+			return nil
+		}
+		if fields != nil {
+			for _, gauge := range eventGauges(eventType, fields, time.Now()) {
+				prometheus.SetGauge(promInstrumentation.PrometheusName(promInstrumentation.PrometheusPrefix, gauge.Name), gauge.Attributes, gauge.Value)
+			}
+		}
+		return nil
+	}
+}
+
+// prometheusTTL returns the time the host samples are exposed without being updated: a few sample periods, so a
+// delayed sample doesn't make the series flap.
+func prometheusTTL(sampleRates ...int) time.Duration {
+	ttl := PROMETHEUS_MIN_TTL
+	for _, rate := range sampleRates {
+		if d := 3 * time.Duration(rate) * time.Second; d > ttl {
+			ttl = d
+		}
+	}
+	return ttl
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	goContext "context"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/instrumentation"
+	"github.com/newrelic/infrastructure-agent/internal/testhelpers"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusSubscriber(t *testing.T) {
+	ctx := NewContext(
+		&config.Config{},
+		"1.2.3",
+		testhelpers.NewFakeHostnameResolver("my-host.example.com", "my-host", nil),
+		NilIDLookup,
+		func(sample interface{}) bool { return true },
+	)
+	ctx.agentKey.Store("my-host")
+
+	newRelic := &recordingEventSender{}
+	ctx.eventSender = newRelic
+	prometheus := instrumentation.NewPrometheus(time.Minute)
+	require.NoError(t, subscribePrometheus(ctx, ctx.eventBus, prometheus))
+
+	ctx.SendEvent(mapEvent{
+		"eventType":          "ProcessSample",
+		"processDisplayName": "nginx",
+		"processId":          float64(42),
+		"cpuPercent":         1.5,
+	}, "")
+	ctx.SendEvent(mapEvent{"eventType": "NetworkSample", "receiveBytesPerSecond": 10.0}, "")
+	require.NoError(t, ctx.eventBus.Close(goContext.Background()))
+
+	// samples keep being sent to New Relic
+	assert.Equal(t, []interface{}{"ProcessSample", "NetworkSample"}, newRelic.eventTypes)
+	assert.Equal(t, uint64(2), ctx.eventBus.Stats()[0].Delivered)
+
+	rec := httptest.NewRecorder()
+	prometheus.GetHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "# TYPE newrelic_infra_process_sample_cpu_percent gauge\n"+
+		`newrelic_infra_process_sample_cpu_percent{entity_key="my-host",process_display_name="nginx",process_id="42"} 1.5`+"\n",
+		string(body))
+}
+
+func TestPrometheusTTL(t *testing.T) {
+	assert.Equal(t, PROMETHEUS_MIN_TTL, prometheusTTL(5, 20, -1))
+	assert.Equal(t, 3*time.Minute, prometheusTTL(5, 60, 20))
+}
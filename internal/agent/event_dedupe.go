@@ -0,0 +1,117 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"bytes"
+	context2 "context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/dedupe"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// minEventDedupeInterval bounds how often the ended windows of the deduplicated events are checked.
+const minEventDedupeInterval = time.Second
+
+// eventDeduper sends only the first of the identical events of the configured types within a window, sending the
+// last one suppressed with their amount once the window ends.
+type eventDeduper struct {
+	types      map[string]bool
+	window     time.Duration
+	aggregator *dedupe.Aggregator
+}
+
+type dedupedEvent struct {
+	event     sample.Event
+	entityKey entity.Key
+}
+
+// newEventDeduper returns nil when the deduplication is disabled.
+func newEventDeduper(cfg *config.Config) *eventDeduper {
+	if cfg == nil || cfg.EventDedupeIntervalSec <= 0 || len(cfg.EventDedupeTypes) == 0 {
+		return nil
+	}
+	types := make(map[string]bool, len(cfg.EventDedupeTypes))
+	for _, eventType := range cfg.EventDedupeTypes {
+		types[eventType] = true
+	}
+	window := time.Duration(cfg.EventDedupeIntervalSec) * time.Second
+	return &eventDeduper{
+		types:      types,
+		window:     window,
+		aggregator: dedupe.NewAggregator(window),
+	}
+}
+
+// shouldSend records the event, returning whether it has to be sent as is.
+func (d *eventDeduper) shouldSend(event sample.Event, entityKey entity.Key) bool {
+	// the events from the plugins are checked without marshalling them
+	if m, ok := event.(mapEvent); ok && !d.types[fmt.Sprint(m["eventType"])] {
+		return true
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return true
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return true
+	}
+	if eventType, _ := fields["eventType"].(string); !d.types[eventType] {
+		return true
+	}
+	delete(fields, "timestamp")
+	// maps are marshalled with their keys sorted
+	key, err := json.Marshal(fields)
+	if err != nil {
+		return true
+	}
+	return d.aggregator.Seen(string(entityKey)+"|"+string(key), dedupedEvent{event: event, entityKey: entityKey})
+}
+
+// run sends the last of the events suppressed within the windows ended until the context is done.
+func (d *eventDeduper) run(ctx context2.Context, send func(sample.Event, entity.Key)) {
+	interval := d.window / 5
+	if interval < minEventDedupeInterval {
+		interval = minEventDedupeInterval
+	}
+	d.aggregator.Run(ctx, interval, func(s dedupe.Summary) {
+		last, ok := s.Last.(dedupedEvent)
+		if !ok {
+			return
+		}
+		send(repeatedEvent{Event: last.event, count: s.Count, window: s.Window}, last.entityKey)
+	})
+}
+
+// repeatedEvent is reported with the amount of identical events suppressed within the window.
+type repeatedEvent struct {
+	sample.Event
+	count  int
+	window time.Duration
+}
+
+func (e repeatedEvent) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(e.Event)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) < 2 || data[0] != '{' || data[len(data)-1] != '}' {
+		return data, nil
+	}
+
+	repeated := make([]byte, 0, len(data)+48)
+	repeated = append(repeated, data[:len(data)-1]...)
+	if len(bytes.TrimSpace(data[1:len(data)-1])) > 0 {
+		repeated = append(repeated, ',')
+	}
+	repeated = append(repeated, fmt.Sprintf(`"repeatCount":%d,"repeatWindowSec":%d}`, e.count, int(e.window.Seconds()))...)
+	return repeated, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	context2 "context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/testhelpers"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+func TestNewEventDeduper_Disabled(t *testing.T) {
+	assert.Nil(t, newEventDeduper(&config.Config{EventDedupeTypes: []string{"InfrastructureEvent"}}))
+	assert.Nil(t, newEventDeduper(&config.Config{EventDedupeIntervalSec: 60}))
+}
+
+func TestContext_SendEvent_Dedupe(t *testing.T) {
+	cfg := config.Config{EventDedupeIntervalSec: 60, EventDedupeTypes: []string{"InfrastructureEvent"}}
+	c := NewContext(
+		&cfg,
+		"0.0.0",
+		testhelpers.NewFakeHostnameResolver("foobar", "foo", nil),
+		NilIDLookup,
+		func(sample interface{}) bool { return true },
+	)
+	sender := &queuedEventSender{}
+	c.eventSender = sender
+
+	for timestamp := int64(1); timestamp <= 3; timestamp++ {
+		c.SendEvent(mapEvent{"eventType": "InfrastructureEvent", "summary": "disk full", "timestamp": timestamp}, "some key")
+	}
+	// different attributes, entities and types aren't deduplicated
+	c.SendEvent(mapEvent{"eventType": "InfrastructureEvent", "summary": "disk ok"}, "some key")
+	c.SendEvent(mapEvent{"eventType": "InfrastructureEvent", "summary": "disk full"}, "other key")
+	c.SendEvent(mapEvent{"eventType": "SystemSample", "cpuPercent": 1.5}, "some key")
+	c.SendEvent(mapEvent{"eventType": "SystemSample", "cpuPercent": 1.5}, "some key")
+	require.Len(t, sender.events, 5)
+
+	// the last event suppressed is sent with the amount of them once stopped
+	ctx, cancel := context2.WithCancel(context2.Background())
+	cancel()
+	var summaryKey entity.Key
+	c.eventDeduper.run(ctx, func(event sample.Event, entityKey entity.Key) {
+		summaryKey = entityKey
+		require.NoError(t, c.queueEvent(event, entityKey))
+	})
+
+	require.Len(t, sender.events, 6)
+	assert.Equal(t, entity.Key("some key"), summaryKey)
+	data, err := json.Marshal(sender.events[5])
+	require.NoError(t, err)
+	var summary map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &summary))
+	assert.Equal(t, "disk full", summary["summary"])
+	assert.EqualValues(t, 3, summary["timestamp"])
+	assert.EqualValues(t, 2, summary["repeatCount"])
+	assert.Contains(t, summary, "repeatWindowSec")
+}
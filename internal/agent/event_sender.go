@@ -4,7 +4,6 @@ package agent
 
 import (
 	"bytes"
-	"compress/gzip"
 	goContext "context"
 	"encoding/json"
 	"fmt"
@@ -15,6 +14,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/newrelic/infrastructure-agent/pkg/log"
@@ -25,6 +25,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/internal/agent/id"
 
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/redact"
 	"github.com/newrelic/infrastructure-agent/pkg/sample"
 
 	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
@@ -54,6 +55,30 @@ func (d *eventData) IsAgent() bool {
 	return d.entityKey.String() == d.agentKey
 }
 
+// SenderStats is a snapshot of the event sender queues and the latency of the last post to the backend.
+type SenderStats struct {
+	EventQueueSize     int
+	EventQueueCapacity int
+	BatchQueueSize     int
+	BatchQueueCapacity int
+	// LastPostLatency is zero until the first post is completed.
+	LastPostLatency time.Duration
+	// SplitPayloads counts the posts split for being too large since the agent started.
+	SplitPayloads uint64
+	// DroppedEvents counts the events dropped for being too large to be posted since the agent started.
+	DroppedEvents uint64
+}
+
+// senderStatsProvider is implemented by the event senders able to report their stats.
+type senderStatsProvider interface {
+	Stats() SenderStats
+}
+
+// queueShedder is implemented by the event senders able to discard their queued events, ie: to release memory.
+type queueShedder interface {
+	shedQueues() int
+}
+
 // eventSender specifies a type of object which can take events to eventually send to <somewhere>.
 // The send operation is assumed to be asynchronous, and so this only supports an instruction to
 // queue an event for later sending.
@@ -63,6 +88,24 @@ type eventSender interface {
 	Stop() error
 }
 
+// flushableSender is implemented by the event senders able to send their queued events while stopping.
+type flushableSender interface {
+	// Flush stops the sender once its queued events are sent, or the context is done.
+	Flush(ctx goContext.Context) error
+}
+
+// stopEventSender stops the sender, sending its queued events within the timeout when it's supported.
+func stopEventSender(sender eventSender, timeout time.Duration) error {
+	f, ok := sender.(flushableSender)
+	if !ok || timeout <= 0 {
+		return sender.Stop()
+	}
+
+	ctx, cancel := goContext.WithTimeout(goContext.Background(), timeout)
+	defer cancel()
+	return f.Flush(ctx)
+}
+
 // Implementation of eventSender which periodically sends events to the metrics ingest endpoint.
 type metricsIngestSender struct {
 	eventQueue               chan eventData  // Individual events waiting to be put into a batch
@@ -70,6 +113,9 @@ type metricsIngestSender struct {
 	metricIngestURL          string
 	internalRoutineWaits     *sync.WaitGroup // Waitgroup to keep track of how many goroutines are running and wait for them to stop
 	stopChannel              chan bool       // Channel will be closed when we want to stop all internal goroutines
+	sendCtx                  goContext.Context
+	cancelSend               goContext.CancelFunc // Cancels the in-flight post
+	unsent                   []eventBatch         // Batches accumulated but not queued when the routines stopped
 	licenseKey               string
 	userAgent                string
 	HttpClient               backendhttp.Client
@@ -81,6 +127,10 @@ type metricsIngestSender struct {
 	connectEnabled           bool
 	getBackoffTimer          func(time.Duration) *time.Timer
 	postCount                uint64 // counts post requests for debugging purposes
+	lastPostLatency          int64  // nanoseconds, accessed atomically
+	compressor               *backendhttp.PayloadCompressor
+	spool                    *payloadSpool
+	payloadStats             payloadStats
 }
 
 func newMetricsIngestSender(ctx *context, licenseKey, userAgent string, httpClient backendhttp.Client, connectEnabled bool) *metricsIngestSender {
@@ -123,6 +173,8 @@ func newMetricsIngestSender(ctx *context, licenseKey, userAgent string, httpClie
 		connectEnabled:           connectEnabled,
 		getBackoffTimer:          time.NewTimer,
 		postCount:                0,
+		compressor:               backendhttp.NewPayloadCompressor(cfg.PayloadCompression, cfg.PayloadCompressionLevel),
+		spool:                    newPayloadSpool(cfg),
 	}
 }
 
@@ -134,6 +186,7 @@ func (sender *metricsIngestSender) Start() (err error) {
 
 	// Set up the stop channel so the routines can wait for it to be closed
 	sender.stopChannel = make(chan bool)
+	sender.sendCtx, sender.cancelSend = goContext.WithCancel(goContext.Background())
 
 	// Wait for accumulateBatches and sendBatches to complete
 	sender.internalRoutineWaits.Add(3)
@@ -165,11 +218,112 @@ func (sender *metricsIngestSender) Stop() (err error) {
 
 	close(sender.stopChannel)
 	sender.internalRoutineWaits.Wait()
+	sender.cancelSend()
 	sender.stopChannel = nil
 
 	return
 }
 
+// Flush stops the sender after sending the queued events. When the context is done the in-flight post is cancelled
+// and the events not sent yet are kept in the payload spool, when enabled, to be sent on the next start.
+func (sender *metricsIngestSender) Flush(ctx goContext.Context) error {
+	if sender.stopChannel == nil {
+		return fmt.Errorf("Cannot flush sender: The sender is not running. (stopChannel is nil)")
+	}
+
+	close(sender.stopChannel)
+	stopped := make(chan struct{})
+	go func() {
+		sender.internalRoutineWaits.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		// the cancelled post is spooled as the backend couldn't be reached
+		sender.cancelSend()
+		<-stopped
+	}
+	sender.cancelSend()
+	sender.stopChannel = nil
+
+	batches := sender.pendingBatches()
+	if len(batches) == 0 {
+		return nil
+	}
+	ilog.WithField("batches", len(batches)).Info("Flushing queued events.")
+
+	agentID := sender.agentID()
+	for i, batch := range batches {
+		if ctx.Err() != nil {
+			sender.spoolBatches(batches[i:], agentID)
+			return fmt.Errorf("events not flushed within the deadline: %w", ctx.Err())
+		}
+
+		post, agentKey := newMetricPostBatch(batch, agentID)
+		err := sender.send(ctx, post, agentKey)
+		if isBackendUnavailable(err) {
+			// the failed post has been spooled, the rest would fail as well
+			sender.spoolBatches(batches[i+1:], agentID)
+			return err
+		}
+		if err != nil {
+			ilog.WithError(err).Warn("Cannot flush events batch.")
+		}
+	}
+	return nil
+}
+
+// pendingBatches returns the batches left when the routines stopped, in the order they were accumulated: the queued
+// batches, the ones not queued yet and the queued events.
+func (sender *metricsIngestSender) pendingBatches() []eventBatch {
+	var batches []eventBatch
+	for len(sender.batchQueue) > 0 {
+		batches = append(batches, <-sender.batchQueue)
+	}
+	batches = append(batches, sender.unsent...)
+	sender.unsent = nil
+
+	var batch eventBatch
+	var batchSize payloadSize
+	for len(sender.eventQueue) > 0 {
+		event := <-sender.eventQueue
+		sender.setEntityID(&event)
+		if batchSize.with(event.entityKey, len(event.data)) > sender.maxMetricsBatchSizeBytes || len(batch) == MAX_EVENT_BATCH_COUNT {
+			batches = append(batches, batch)
+			batch = nil
+			batchSize = payloadSize{}
+		}
+		batch = append(batch, event)
+		batchSize.add(event.entityKey, len(event.data))
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// spoolBatches keeps the batches which couldn't be flushed in the payload spool, or drops them when it's disabled.
+func (sender *metricsIngestSender) spoolBatches(batches []eventBatch, agentID entity.ID) {
+	if len(batches) == 0 {
+		return
+	}
+	if sender.spool == nil {
+		events := 0
+		for _, batch := range batches {
+			events += len(batch)
+		}
+		ilog.WithField("events", events).Warn("Events not flushed on shutdown are dropped, enable the payload spool to keep them.")
+		return
+	}
+
+	for _, batch := range batches {
+		post, agentKey := newMetricPostBatch(batch, agentID)
+		sender.spool.push(post, agentKey)
+	}
+	ilog.WithField("batches", len(batches)).Info("Events not flushed on shutdown spooled.")
+}
+
 // We can accept any kind of object to represent an event. We assume that it will marshal to a valid JSON event object.
 func (sender *metricsIngestSender) QueueEvent(event sample.Event, key entity.Key) (err error) {
 	agentKey := sender.Context.EntityKey()
@@ -183,9 +337,11 @@ func (sender *metricsIngestSender) QueueEvent(event sample.Event, key entity.Key
 	if err != nil {
 		return fmt.Errorf("error marshalling event to JSON: %+v (%+v)", event, err)
 	}
+	edata = redact.JSON(edata)
 
-	if len(edata) > sender.maxMetricsBatchSizeBytes {
-		return fmt.Errorf("Could not queue event: Event is larger than the maximum event post size (%d > %d).", len(edata), sender.maxMetricsBatchSizeBytes)
+	if size := (payloadSize{}).with(key, len(edata)); size > sender.maxMetricsBatchSizeBytes {
+		sender.payloadStats.drop(1)
+		return fmt.Errorf("Could not queue event: Event is larger than the maximum event post size (%d > %d).", size, sender.maxMetricsBatchSizeBytes)
 	}
 
 	queuedEvent := eventData{
@@ -229,7 +385,7 @@ func reportEventQueueMetrics(queue chan eventData, stopChannel chan bool) {
 // queue receiver and accumulate a reasonable number of batches before we fill up on batches as well.
 func (sender *metricsIngestSender) accumulateBatches() {
 	var batch eventBatch
-	var batchBytes int // Accumulated batch size in bytes
+	var batchSize payloadSize // Accumulated post size in bytes
 
 	sendTimerD := EVENT_BATCH_TIMER_DURATION * time.Second
 	sendTimer := time.NewTimer(sendTimerD)
@@ -237,31 +393,30 @@ func (sender *metricsIngestSender) accumulateBatches() {
 		select {
 		case event := <-sender.eventQueue:
 
-			// Add entityID if connect is enabled and if is not a remote entity.
-			if sender.connectEnabled && event.IsAgent() {
-				event.entityID = sender.agentIDProvide().ID
-			}
+			sender.setEntityID(&event)
 
-			if batchBytes+len(event.data) > sender.maxMetricsBatchSizeBytes || len(batch) == MAX_EVENT_BATCH_COUNT {
+			if batchSize.with(event.entityKey, len(event.data)) > sender.maxMetricsBatchSizeBytes || len(batch) == MAX_EVENT_BATCH_COUNT {
 				// Current batch + this event would either be too many events or too many bytes, so queue the batch first.
 				select {
 				case sender.batchQueue <- batch:
 					batch = make(eventBatch, 0)
-					batchBytes = 0
+					batchSize = payloadSize{}
 				case <-sender.stopChannel:
+					sender.unsent = append(sender.unsent, batch, eventBatch{event})
 					return
 				}
 			}
 			batch = append(batch, event)
-			batchBytes += len(event.data)
+			batchSize.add(event.entityKey, len(event.data))
 		case <-sendTimer.C:
 			// Timer has fired - send any queued events to ensure a minimum delay in sending.
 			if len(batch) > 0 {
 				select {
 				case sender.batchQueue <- batch:
 					batch = make(eventBatch, 0)
-					batchBytes = 0
+					batchSize = payloadSize{}
 				case <-sender.stopChannel:
+					sender.unsent = append(sender.unsent, batch)
 					return
 				}
 			}
@@ -269,11 +424,21 @@ func (sender *metricsIngestSender) accumulateBatches() {
 		case <-sender.stopChannel:
 			// Stop channel has been closed - exit.
 			// There might still be some events in the queue, but they'll still be there in case we start the sender back up.
+			if len(batch) > 0 {
+				sender.unsent = append(sender.unsent, batch)
+			}
 			return
 		}
 	}
 }
 
+// setEntityID adds the agent entityID to its events if connect is enabled.
+func (sender *metricsIngestSender) setEntityID(event *eventData) {
+	if sender.connectEnabled && event.IsAgent() {
+		event.entityID = sender.agentIDProvide().ID
+	}
+}
+
 // MetricPost entity item for the HTTP post to be sent to the ingest service.
 type MetricPost struct {
 	ExternalKeys []string          `json:"ExternalKeys,omitempty"`
@@ -305,6 +470,27 @@ func newMetricPost(entityKey entity.Key, entityID, agentID entity.ID, agentKey s
 	return mp
 }
 
+// newMetricPostBatch groups the batch events by entity, returning the agent key they were queued with.
+func newMetricPostBatch(batch eventBatch, agentID entity.ID) (MetricPostBatch, string) {
+	agentKey := ""
+	var bulkPost MetricPostBatch
+	dataByEntity := make(map[entity.Key]*MetricPost)
+	// We need to rebuild the array of events as a []json.RawMessage, or else JSON marshalling won't handle them correctly.
+	for _, event := range batch {
+		entityData := dataByEntity[event.entityKey]
+		if entityData == nil {
+			entityData = newMetricPost(event.entityKey, event.entityID, agentID, event.agentKey)
+			dataByEntity[event.entityKey] = entityData
+			bulkPost = append(bulkPost, entityData)
+		}
+		entityData.Events = append(entityData.Events, event.data)
+		if event.agentKey != "" {
+			agentKey = event.agentKey
+		}
+	}
+	return bulkPost, agentKey
+}
+
 // getLoggingField will add an identifier for the MetricPost for the logs.
 func (mp *MetricPost) getLoggingField() logrus.Fields {
 	if len(mp.ExternalKeys) > 0 {
@@ -336,59 +522,43 @@ func (mp *MetricPost) getTimestampLoggingFields() logrus.Fields {
 
 // Wait for queued batches and send any to the ingest API
 func (sender *metricsIngestSender) sendBatches() {
-	retryBO := backoff.NewDefaultBackoff()
+	retryBO := backoff.NewEndpointBackoff(metricsEndpoint)
 	for {
 		select {
 
 		case batch := <-sender.batchQueue:
-			ctx := goContext.Background()
+			ctx := sender.sendCtx
 			ctx, txn := instrumentation.SelfInstrumentation.StartTransaction(ctx, "sender.sendBatches")
 
 			pclog := ilog.WithField("postCount", sender.postCount)
 			sender.postCount++
 
-			agentKey := ""
-			dataByEntity := make(map[entity.Key]*MetricPost)
-
 			ctx, seg := txn.StartSegment(ctx, "getAgentId")
 			agentID := sender.agentID()
 			seg.End()
 
 			ctx, seg = txn.StartSegment(ctx, "rebuildEvents")
-			// We need to rebuild the array of events as a []json.RawMessage, or else JSON marshalling won't handle them correctly.
-			for _, event := range batch {
-				entityData := dataByEntity[event.entityKey]
-				if entityData == nil {
-					entityData = newMetricPost(event.entityKey, event.entityID, agentID, event.agentKey)
-					dataByEntity[event.entityKey] = entityData
-				}
-				entityData.Events = append(entityData.Events, event.data)
-				if event.agentKey != "" {
-					agentKey = event.agentKey
-				}
-			}
+			bulkPost, agentKey := newMetricPostBatch(batch, agentID)
 			seg.End()
 
 			ctx, seg = txn.StartSegment(ctx, "prepareBulkPost")
-			var bulkPost MetricPostBatch
-			for _, entityData := range dataByEntity {
+			for _, entityData := range bulkPost {
 				metric := instrumentation.NewGauge("agent.postEventsNum", float64(len(entityData.Events)))
 				instrumentation.SelfInstrumentation.RecordMetric(ctx, metric)
 				pclog.WithFieldsF(entityData.getLoggingField).
 					WithFieldsF(entityData.getTimestampLoggingFields).
 					WithField("numEvents", len(entityData.Events)).
 					Debug("Sending events to metrics-ingest.")
-				bulkPost = append(bulkPost, entityData)
 			}
 			pclog.Debug("Preparing metrics post.")
 			seg.End()
 
-			err := sender.doPost(ctx, bulkPost, agentKey)
+			err := sender.send(ctx, bulkPost, agentKey)
 
 			if err == nil {
 				pclog.Debug("Metrics post succeeded.")
 				sender.sendErrorCount = 0
-				retryBO.Reset()
+				retryBO.Success()
 				txn.End()
 				continue
 			}
@@ -396,27 +566,24 @@ func (sender *metricsIngestSender) sendBatches() {
 			sender.sendErrorCount++
 			pclog.WithError(err).WithField("sendErrorCount", sender.sendErrorCount).Error("metric sender can't process")
 
-			e, ok := err.(*errRetry)
+			policy, ok := retryPolicy(err)
 			if !ok {
 				txn.NoticeError(err)
 				txn.End()
 				continue
 			}
 
-			if e.retryPolicy.After > 0 {
-				pclog.WithField("retryAfter", e.retryPolicy.After).Debug("Metric sender retry requested.")
-				retryBO.Reset()
-				sender.backoff(e.retryPolicy.After)
-				txn.NoticeError(e)
-				txn.AddAttribute("retryAfter", e.retryPolicy.After)
-				txn.End()
-				continue
+			retryBOAfter := retryBO.Next(policy)
+			if policy.After > 0 {
+				pclog.WithField("retryAfter", retryBOAfter).Debug("Metric sender retry requested.")
+				txn.AddAttribute("retryAfter", retryBOAfter)
+			} else {
+				pclog.WithField("retryBackoffAfter", retryBOAfter).Debug("Metric sender backoff and retry requested.")
+				txn.AddAttribute("retryBackoffAfter", retryBOAfter)
 			}
-			retryBOAfter := retryBO.DurationWithMax(e.retryPolicy.MaxBackOff)
-			pclog.WithField("retryBackoffAfter", retryBOAfter).Debug("Metric sender backoff and retry requested.")
+			instrumentation.RecordRetryStats(ctx, retryBO.Stats())
 			sender.backoff(retryBOAfter)
-			txn.AddAttribute("retryBackoffAfter", retryBOAfter)
-			txn.NoticeError(e)
+			txn.NoticeError(err)
 			txn.End()
 		case <-sender.stopChannel:
 			// Stop channel has been closed - exit.
@@ -426,6 +593,34 @@ func (sender *metricsIngestSender) sendBatches() {
 	}
 }
 
+// Stats returns the current queues usage and the latency of the last post.
+func (sender *metricsIngestSender) Stats() SenderStats {
+	stats := SenderStats{
+		EventQueueSize:     len(sender.eventQueue),
+		EventQueueCapacity: cap(sender.eventQueue),
+		BatchQueueSize:     len(sender.batchQueue),
+		BatchQueueCapacity: cap(sender.batchQueue),
+		LastPostLatency:    time.Duration(atomic.LoadInt64(&sender.lastPostLatency)),
+	}
+	sender.payloadStats.fill(&stats)
+	return stats
+}
+
+// shedQueues discards the events and batches waiting to be sent, returning the number of events discarded.
+func (sender *metricsIngestSender) shedQueues() (dropped int) {
+	defer func() { sender.payloadStats.drop(dropped) }()
+	for {
+		select {
+		case <-sender.eventQueue:
+			dropped++
+		case batch := <-sender.batchQueue:
+			dropped += len(batch)
+		default:
+			return dropped
+		}
+	}
+}
+
 func (s *metricsIngestSender) agentID() entity.ID {
 	if s.Context != nil &&
 		s.Context.Config() != nil &&
@@ -446,12 +641,9 @@ func (s *metricsIngestSender) backoff(d time.Duration) {
 	}
 }
 
-// Make one HTTP call to push a load of events up to the server
-func (sender *metricsIngestSender) doPost(ctx goContext.Context, post []*MetricPost, agentKey string) error {
-	if agentKey == "" {
-		ilog.Warn("no available agent-id on metrics sender")
-	}
-
+// send posts the batch, splitting it in halves while it's too large to be posted. Events too large to be posted by
+// themselves are dropped.
+func (sender *metricsIngestSender) send(ctx goContext.Context, post MetricPostBatch, agentKey string) error {
 	txn := instrumentation.TransactionFromContext(ctx)
 	txnCtx, segment := txn.StartSegment(ctx, "doPost.marshall")
 	postBytes, err := json.Marshal(post)
@@ -461,37 +653,64 @@ func (sender *metricsIngestSender) doPost(ctx goContext.Context, post []*MetricP
 		return fmt.Errorf("Could not marshal events object [%v]: %v", post, err)
 	}
 
-	// GZIP
-	var reqBuf *bytes.Buffer
-	if sender.Context.Config().PayloadCompressionLevel > gzip.NoCompression {
-		// GZIP
-		txnCtx, segment = txn.StartSegment(txnCtx, "doPost.gzip")
-		reqBuf = &bytes.Buffer{}
-		compressionLevel := sender.Context.Config().PayloadCompressionLevel
-		gzipWriter, err := gzip.NewWriterLevel(reqBuf, compressionLevel)
-		if err != nil {
-			segment.End()
-			return fmt.Errorf("Unable to create gzip writer: %v", err)
+	if len(postBytes) <= sender.maxMetricsBatchSizeBytes {
+		err = sender.sendPayload(txnCtx, postBytes, agentKey)
+		if !isPayloadTooLarge(err) {
+			return err
 		}
-		if _, err := gzipWriter.Write(postBytes); err != nil {
-			segment.End()
-			return fmt.Errorf("Gzip writer was not able to write to request body: %s", err)
-		}
-		if err := gzipWriter.Close(); err != nil {
-			segment.End()
-			return fmt.Errorf("Gzip writer did not close: %s", err)
+	}
+
+	first, second, ok := post.split()
+	if !ok {
+		return sender.payloadStats.drop(post.eventCount())
+	}
+	sender.payloadStats.split()
+	ilog.WithField("postBytes", len(postBytes)).Debug("Splitting metrics post too large to be sent.")
+
+	err = sender.send(ctx, first, agentKey)
+	if secondErr := sender.send(ctx, second, agentKey); err == nil {
+		err = secondErr
+	}
+	return err
+}
+
+// sendPayload posts the marshalled batch, spooling it when the backend is unavailable. When there are spooled
+// batches the new one is spooled as well, and the oldest ones are sent first so the backend receives them in order.
+func (sender *metricsIngestSender) sendPayload(ctx goContext.Context, postBytes []byte, agentKey string) error {
+	if !sender.spool.pending() {
+		err := sender.postPayload(ctx, postBytes, agentKey)
+		if isBackendUnavailable(err) {
+			sender.spool.push(json.RawMessage(postBytes), agentKey)
 		}
-		segment.End()
-	} else {
-		reqBuf = bytes.NewBuffer(postBytes)
+		return err
+	}
+
+	sender.spool.push(json.RawMessage(postBytes), agentKey)
+	return sender.spool.replay(func(postBytes []byte, agentKey string) error {
+		return sender.postPayload(ctx, postBytes, agentKey)
+	})
+}
+
+// postPayload sends the marshalled events post to the server.
+func (sender *metricsIngestSender) postPayload(ctx goContext.Context, postBytes []byte, agentKey string) error {
+	if agentKey == "" {
+		ilog.Warn("no available agent-id on metrics sender")
+	}
+
+	txn := instrumentation.TransactionFromContext(ctx)
+	_, segment := txn.StartSegment(ctx, "doPost.compress")
+	reqBody, encoding, err := sender.compressor.Compress(postBytes)
+	segment.End()
+	if err != nil {
+		return err
 	}
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/events/bulk", sender.metricIngestURL), reqBuf)
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/events/bulk", sender.metricIngestURL), bytes.NewReader(reqBody))
 	if err != nil {
 		return fmt.Errorf("Error creating event POST: %v", err)
 	}
 
-	if sender.Context.Config().PayloadCompressionLevel > gzip.NoCompression {
-		req.Header.Set("Content-Encoding", "gzip")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -515,11 +734,13 @@ func (sender *metricsIngestSender) doPost(ctx goContext.Context, post []*MetricP
 
 	ctx, extSeg := txn.StartExternalSegment(ctx, "event_sender", req)
 	extSeg.AddAttribute("postSize", len(postBytes))
+	postStart := time.Now()
 	resp, err := sender.HttpClient(req)
+	atomic.StoreInt64(&sender.lastPostLatency, int64(time.Since(postStart)))
 	extSeg.End()
 
 	if err != nil {
-		return fmt.Errorf("error sending events: %v", err)
+		return &errUnreachable{fmt.Errorf("error sending events: %v", err)}
 	}
 
 	// To let the http client reusing the connections, the response body
@@ -530,6 +751,10 @@ func (sender *metricsIngestSender) doPost(ctx goContext.Context, post []*MetricP
 	defer resp.Body.Close()
 	buf, bodyErr := ioutil.ReadAll(resp.Body)
 
+	if sender.compressor.Negotiate(resp, encoding) {
+		return sender.postPayload(ctx, postBytes, agentKey)
+	}
+
 	hasError, cause := backendhttp.IsResponseUnsuccessful(resp)
 	if !hasError {
 		return nil
@@ -539,23 +764,11 @@ func (sender *metricsIngestSender) doPost(ctx goContext.Context, post []*MetricP
 		return fmt.Errorf("error sending events: Unable to read server response: %s", bodyErr)
 	}
 
-	var retryPolicy backendhttp.RetryPolicy
-	retryAfterH := resp.Header.Get("Retry-After")
-	if retryAfterH != "" {
-		if retryPolicy.After, err = time.ParseDuration(retryAfterH + "s"); err != nil {
-			ilog.WithError(err).Debug(
-				"error parsing connect Retry-After header, continuing with exponential backoff",
-			)
-		}
-	}
-
-	retryPolicy.MaxBackOff = backoff.GetMaxBackoffByCause(cause)
-
 	return newErrRetry(
 		"events were not accepted",
 		resp.StatusCode,
 		resp.Status,
 		string(buf),
-		retryPolicy,
+		newRetryPolicy(resp, cause, ilog),
 	)
 }
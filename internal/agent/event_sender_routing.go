@@ -0,0 +1,161 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	goContext "context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+var rtlog = log.WithComponent("SubmissionRouting")
+
+// routingSender sends the events matching a submission route through the sender of the route, with its own license
+// key, queue and transport, and the rest of them through the agent sender.
+type routingSender struct {
+	eventSender // agent sender
+	routes      []eventRoute
+}
+
+// eventRoute is a submission route, matching the events by their type or the integration reporting them.
+type eventRoute struct {
+	name         string
+	eventTypes   map[string]bool
+	integrations map[string]bool
+	sender       eventSender
+}
+
+// routedFields are the event fields the routes match.
+type routedFields struct {
+	EventType       string `json:"eventType"`
+	IntegrationName string `json:"integrationName"`
+}
+
+// newRoutingSender wraps the agent sender to send the events matched by the submission routes to their accounts.
+// The routes send the events without the agent ID, as it belongs to the agent account, and without payload spool.
+func newRoutingSender(ctx *context, sender eventSender, userAgent string) eventSender {
+	cfg := ctx.Config()
+	s := &routingSender{eventSender: sender}
+	for _, r := range cfg.SubmissionRoutes {
+		transport := backendhttp.NewReloadableProxyTransport(cfg, backendhttp.ClientTimeout, r.Proxy)
+		transport = backendhttp.NewRequestDecoratorTransport(cfg, transport)
+		routeSender := newMetricsIngestSender(ctx, r.License, userAgent, backendhttp.GetHttpClient(backendhttp.ClientTimeout, transport).Do, false)
+		routeSender.spool = nil
+
+		s.routes = append(s.routes, newEventRoute(r, routeSender))
+		rtlog.
+			WithField("route", r.Name).
+			WithField("event_types", strings.Join(r.EventTypes, ",")).
+			WithField("integrations", strings.Join(r.Integrations, ",")).
+			Info("Routing events to another account.")
+	}
+	return s
+}
+
+func newEventRoute(r config.SubmissionRoute, sender eventSender) eventRoute {
+	route := eventRoute{
+		name:         r.Name,
+		eventTypes:   map[string]bool{},
+		integrations: map[string]bool{},
+		sender:       sender,
+	}
+	for _, t := range r.EventTypes {
+		route.eventTypes[t] = true
+	}
+	for _, i := range r.Integrations {
+		route.integrations[i] = true
+	}
+	return route
+}
+
+func (r eventRoute) matches(fields routedFields) bool {
+	return r.eventTypes[fields.EventType] || (fields.IntegrationName != "" && r.integrations[fields.IntegrationName])
+}
+
+func (s *routingSender) QueueEvent(event sample.Event, key entity.Key) error {
+	fields, err := eventRoutedFields(event)
+	if err != nil {
+		return err
+	}
+	for _, r := range s.routes {
+		if r.matches(fields) {
+			if err = r.sender.QueueEvent(event, key); err != nil {
+				return fmt.Errorf("submission route %s: %w", r.name, err)
+			}
+			return nil
+		}
+	}
+	return s.eventSender.QueueEvent(event, key)
+}
+
+// eventRoutedFields returns the fields of the event matched by the routes, without marshalling the integration
+// events, which are maps.
+func eventRoutedFields(event sample.Event) (fields routedFields, err error) {
+	if m, ok := event.(mapEvent); ok {
+		fields.EventType, _ = m["eventType"].(string)
+		fields.IntegrationName, _ = m["integrationName"].(string)
+		return fields, nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fields, fmt.Errorf("error marshalling event to JSON: %v", err)
+	}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return fields, fmt.Errorf("error unmarshalling event JSON: %v", err)
+	}
+	return fields, nil
+}
+
+func (s *routingSender) Start() error {
+	if err := s.eventSender.Start(); err != nil {
+		return err
+	}
+	for _, r := range s.routes {
+		if err := r.sender.Start(); err != nil {
+			return fmt.Errorf("cannot start submission route %s: %w", r.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *routingSender) Stop() error {
+	for _, r := range s.routes {
+		if err := r.sender.Stop(); err != nil {
+			rtlog.WithError(err).WithField("route", r.name).Warn("Cannot stop submission route.")
+		}
+	}
+	return s.eventSender.Stop()
+}
+
+// Flush flushes the routes along with the agent sender, within the same deadline.
+func (s *routingSender) Flush(ctx goContext.Context) error {
+	errs := make(chan error, len(s.routes))
+	for _, r := range s.routes {
+		go func(r eventRoute) {
+			errs <- stopEventSenderContext(ctx, r.sender)
+		}(r)
+	}
+
+	err := stopEventSenderContext(ctx, s.eventSender)
+	for range s.routes {
+		if routeErr := <-errs; routeErr != nil {
+			rtlog.WithError(routeErr).Warn("Cannot flush submission route.")
+		}
+	}
+	return err
+}
+
+// stopEventSenderContext flushes the sender within the context deadline, or stops it when it can't be flushed.
+func stopEventSenderContext(ctx goContext.Context, sender eventSender) error {
+	if f, ok := sender.(flushableSender); ok {
+		return f.Flush(ctx)
+	}
+	return sender.Stop()
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+func TestRoutingSender_QueueEvent(t *testing.T) {
+	agentSender := &recordingEventSender{}
+	securitySender := &recordingEventSender{}
+	mysqlSender := &recordingEventSender{}
+	s := &routingSender{
+		eventSender: agentSender,
+		routes: []eventRoute{
+			newEventRoute(config.SubmissionRoute{Name: "security", EventTypes: []string{"SecurityEvent"}}, securitySender),
+			newEventRoute(config.SubmissionRoute{Name: "mysql", EventTypes: []string{"SecurityEvent"}, Integrations: []string{"com.newrelic.mysql"}}, mysqlSender),
+		},
+	}
+
+	events := []mapEvent{
+		{"eventType": "SystemSample"},
+		{"eventType": "SecurityEvent", "integrationName": "com.newrelic.mysql"},
+		{"eventType": "MysqlSample", "integrationName": "com.newrelic.mysql"},
+		{"eventType": "RedisSample", "integrationName": "com.newrelic.redis"},
+	}
+	for _, e := range events {
+		require.NoError(t, s.QueueEvent(e, entity.EmptyKey))
+	}
+
+	assert.Equal(t, []interface{}{"SystemSample", "RedisSample"}, agentSender.eventTypes)
+	// the first matching route wins
+	assert.Equal(t, []interface{}{"SecurityEvent"}, securitySender.eventTypes)
+	assert.Equal(t, []interface{}{"MysqlSample"}, mysqlSender.eventTypes)
+}
+
+func TestEventRoutedFields(t *testing.T) {
+	fields, err := eventRoutedFields(&struct {
+		sample.BaseEvent
+		IntegrationName string `json:"integrationName"`
+	}{BaseEvent: sample.BaseEvent{EventType: "StorageSample"}, IntegrationName: "nri-storage"})
+
+	require.NoError(t, err)
+	assert.Equal(t, routedFields{EventType: "StorageSample", IntegrationName: "nri-storage"}, fields)
+}
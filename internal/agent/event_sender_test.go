@@ -4,8 +4,10 @@ package agent
 
 import (
 	"compress/gzip"
+	goContext "context"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
@@ -13,6 +15,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -540,6 +543,136 @@ func TestEventSender_ResponseError(t *testing.T) {
 	}
 }
 
+func TestEventSender_Stats(t *testing.T) {
+	ctx := newTestContext("testAgent", &config.Config{
+		PayloadCompressionLevel: gzip.NoCompression,
+		EventQueueDepth:         2000,
+	})
+	acceptingClient := func(req *http.Request) (*http.Response, error) {
+		time.Sleep(time.Millisecond)
+		return &http.Response{StatusCode: http.StatusAccepted, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	sender := newMetricsIngestSender(ctx, "license", "userAgent", acceptingClient, false)
+
+	assert.NoError(t, sender.QueueEvent(mapEvent{"eventType": "TestEvent"}, ""))
+
+	stats := sender.Stats()
+	assert.Equal(t, 1, stats.EventQueueSize)
+	assert.Equal(t, 2000, stats.EventQueueCapacity)
+	assert.Equal(t, 0, stats.BatchQueueSize)
+	assert.Equal(t, BATCH_QUEUE_CAPACITY, stats.BatchQueueCapacity)
+	assert.Zero(t, stats.LastPostLatency)
+
+	assert.NoError(t, sender.send(goContext.Background(), nil, "testAgent"))
+	assert.GreaterOrEqual(t, sender.Stats().LastPostLatency, time.Millisecond)
+}
+
+func TestEventSender_ShedQueues(t *testing.T) {
+	ctx := newTestContext("testAgent", &config.Config{
+		PayloadCompressionLevel: gzip.NoCompression,
+	})
+	sender := newMetricsIngestSender(ctx, "license", "userAgent", http2.NullHttpClient, false)
+
+	assert.NoError(t, sender.QueueEvent(mapEvent{"eventType": "TestEvent"}, ""))
+	assert.NoError(t, sender.QueueEvent(mapEvent{"eventType": "TestEvent"}, ""))
+	sender.batchQueue <- eventBatch{{}, {}, {}}
+
+	assert.Equal(t, 5, sender.shedQueues())
+	stats := sender.Stats()
+	assert.Zero(t, stats.EventQueueSize)
+	assert.Zero(t, stats.BatchQueueSize)
+	assert.Equal(t, uint64(5), stats.DroppedEvents)
+}
+
+func TestEventSender_Flush(t *testing.T) {
+	var lock sync.Mutex
+	var received []string
+	acceptingClient := func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		lock.Lock()
+		received = append(received, string(body))
+		lock.Unlock()
+		return &http.Response{StatusCode: http.StatusAccepted, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	ctx := newTestContext("testAgent", &config.Config{PayloadCompressionLevel: gzip.NoCompression})
+	sender := newMetricsIngestSender(ctx, "license", "userAgent", acceptingClient, false)
+	assert.NoError(t, sender.Start())
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, sender.QueueEvent(mapEvent{"eventType": "TestEvent", "n": i}, ""))
+	}
+
+	flushCtx, cancel := goContext.WithTimeout(goContext.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, sender.Flush(flushCtx))
+
+	// the queued events are sent before the batch timer fires
+	lock.Lock()
+	defer lock.Unlock()
+	body := strings.Join(received, "")
+	for i := 0; i < 3; i++ {
+		assert.Contains(t, body, fmt.Sprintf(`"n":%d`, i))
+	}
+	assert.Error(t, sender.Flush(flushCtx), "the sender is stopped")
+}
+
+func TestAgent_FlushQueuedData(t *testing.T) {
+	var lock sync.Mutex
+	var received []string
+	acceptingClient := func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		lock.Lock()
+		received = append(received, string(body))
+		lock.Unlock()
+		return &http.Response{StatusCode: http.StatusAccepted, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	a := newTesting(nil)
+	a.Context.cfg.ConnectEnabled = false
+	a.Context.cfg.ShutdownFlushTimeoutSec = 5
+	a.Context.cfg.PayloadCompressionLevel = gzip.NoCompression
+	sender := newMetricsIngestSender(a.Context, "license", "userAgent", acceptingClient, false)
+	a.Context.eventSender = sender
+	assert.NoError(t, sender.Start())
+
+	assert.NoError(t, sender.QueueEvent(mapEvent{"eventType": "HostTerminationImminent"}, ""))
+	a.FlushQueuedData()
+
+	lock.Lock()
+	assert.Contains(t, strings.Join(received, ""), "HostTerminationImminent")
+	lock.Unlock()
+	assert.Len(t, a.flushRequests, 1, "the inventory is sent too")
+
+	// the sender keeps running for the samples taken until the agent stops
+	assert.NoError(t, sender.Stop())
+}
+
+func TestEventSender_FlushDeadlineSpools(t *testing.T) {
+	blockingClient := func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	cfg := &config.Config{
+		PayloadCompressionLevel:  gzip.NoCompression,
+		MaxMetricsBatchSizeBytes: 200,
+		PayloadSpoolMaxSizeMb:    1,
+		PayloadSpoolDir:          t.TempDir(),
+	}
+	sender := newMetricsIngestSender(newTestContext("testAgent", cfg), "license", "userAgent", blockingClient, false)
+	assert.NoError(t, sender.Start())
+
+	// each event fills a batch
+	assert.NoError(t, sender.QueueEvent(mapEvent{"eventType": "TestEvent", "n": 1}, ""))
+	assert.NoError(t, sender.QueueEvent(mapEvent{"eventType": "TestEvent", "n": 2}, ""))
+
+	flushCtx, cancel := goContext.WithTimeout(goContext.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Error(t, sender.Flush(flushCtx))
+
+	assert.Equal(t, 2, sender.spool.Len())
+}
+
 func newTestContext(agentKey string, cfg *config.Config) *context {
 	var atomicAgentKey atomic.Value
 	atomicAgentKey.Store(agentKey)
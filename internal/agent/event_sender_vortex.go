@@ -4,7 +4,6 @@ package agent
 
 import (
 	"bytes"
-	"compress/gzip"
 	context2 "context"
 	"encoding/json"
 	"fmt"
@@ -21,6 +20,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 
 	"github.com/newrelic/infrastructure-agent/internal/agent/id"
+	"github.com/newrelic/infrastructure-agent/internal/agent/instrumentation"
 	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
 	"github.com/newrelic/infrastructure-agent/pkg/backend/identityapi"
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
@@ -91,6 +91,10 @@ type vortexEventSender struct {
 	registerBatchSize        int
 	registerFrequency        time.Duration
 	getBackoffTimer          func(time.Duration) *time.Timer
+	lastPostLatency          int64 // nanoseconds, accessed atomically
+	compressor               *backendhttp.PayloadCompressor
+	spool                    *payloadSpool
+	payloadStats             payloadStats
 }
 
 // IsAgent returns true when event belongs to the agent/local entity.
@@ -146,6 +150,8 @@ func newVortexEventSender(ctx *context, licenseKey, userAgent string, httpClient
 		registerFrequency:        time.Duration(cfg.RegisterFrequencySecs) * time.Second,
 		getBackoffTimer:          time.NewTimer,
 		sendErrorCount:           new(uint32),
+		compressor:               backendhttp.NewPayloadCompressor(cfg.PayloadCompression, cfg.PayloadCompressionLevel),
+		spool:                    newPayloadSpool(cfg),
 	}
 }
 
@@ -189,6 +195,34 @@ func (s *vortexEventSender) Stop() (err error) {
 	return
 }
 
+// Stats returns the current queues usage and the latency of the last post.
+func (s *vortexEventSender) Stats() SenderStats {
+	stats := SenderStats{
+		EventQueueSize:     len(s.eventQueue),
+		EventQueueCapacity: cap(s.eventQueue),
+		BatchQueueSize:     len(s.batchQueue),
+		BatchQueueCapacity: cap(s.batchQueue),
+		LastPostLatency:    time.Duration(atomic.LoadInt64(&s.lastPostLatency)),
+	}
+	s.payloadStats.fill(&stats)
+	return stats
+}
+
+// shedQueues discards the events and batches waiting to be sent, returning the number of events discarded.
+func (s *vortexEventSender) shedQueues() (dropped int) {
+	defer func() { s.payloadStats.drop(dropped) }()
+	for {
+		select {
+		case <-s.eventQueue:
+			dropped++
+		case batch := <-s.batchQueue:
+			dropped += len(batch)
+		default:
+			return dropped
+		}
+	}
+}
+
 // We can accept any kind of object to represent an event. We assume that it will marshal to a valid JSON event object.
 func (s *vortexEventSender) QueueEvent(event sample.Event, key entity.Key) (err error) {
 	agentKey := s.Context.EntityKey()
@@ -203,8 +237,9 @@ func (s *vortexEventSender) QueueEvent(event sample.Event, key entity.Key) (err
 		return fmt.Errorf("error marshalling event to JSON: %+v (%+v)", event, err)
 	}
 
-	if len(edata) > s.maxMetricsBatchSizeBytes {
-		return fmt.Errorf("cannot queue event: larger than max size (%d > %d)", len(edata), s.maxMetricsBatchSizeBytes)
+	if size := (payloadSize{}).with(key, len(edata)); size > s.maxMetricsBatchSizeBytes {
+		s.payloadStats.drop(1)
+		return fmt.Errorf("cannot queue event: larger than max size (%d > %d)", size, s.maxMetricsBatchSizeBytes)
 	}
 
 	select {
@@ -318,7 +353,7 @@ func (s *vortexEventSender) flushRegister(events []eventVortexData) {
 // queue receiver and accumulate a reasonable number of batches before we fill up on batches as well.
 func (s *vortexEventSender) accumulateBatches() {
 	var batch eventVortexBatch
-	var batchBytes int // Accumulated batch size in bytes
+	var batchSize payloadSize // Accumulated post size in bytes
 
 	ctx, cancel := context2.WithCancel(context2.Background())
 
@@ -365,18 +400,18 @@ func (s *vortexEventSender) accumulateBatches() {
 			}
 
 		case event := <-s.eventsWithID:
-			if batchBytes+len(event.data) > s.maxMetricsBatchSizeBytes || len(batch) == MAX_EVENT_BATCH_COUNT {
+			if batchSize.with(event.entityKey, len(event.data)) > s.maxMetricsBatchSizeBytes || len(batch) == MAX_EVENT_BATCH_COUNT {
 				// Current batch + this event would either be too many events or too many bytes, so queue the batch first.
 				select {
 				case s.batchQueue <- batch:
 					batch = make(eventVortexBatch, 0)
-					batchBytes = 0
+					batchSize = payloadSize{}
 				case <-s.stopChannel:
 					return
 				}
 			}
 			batch = append(batch, event)
-			batchBytes += len(event.data)
+			batchSize.add(event.entityKey, len(event.data))
 
 		case <-sendTimer.C:
 			// Timer has fired - send any queued events to ensure a minimum delay in sending.
@@ -384,7 +419,7 @@ func (s *vortexEventSender) accumulateBatches() {
 				select {
 				case s.batchQueue <- batch:
 					batch = make(eventVortexBatch, 0)
-					batchBytes = 0
+					batchSize = payloadSize{}
 				case <-s.stopChannel:
 					return
 				}
@@ -421,7 +456,7 @@ func newMetricVortexPost(entityKey entity.Key, entityID, agentID entity.ID) *Met
 
 // Wait for queued batches and send any to the ingest API
 func (s *vortexEventSender) sendBatches() {
-	retryBO := backoff.NewDefaultBackoff()
+	retryBO := backoff.NewEndpointBackoff(metricsEndpoint)
 	for {
 		select {
 
@@ -452,30 +487,29 @@ func (s *vortexEventSender) sendBatches() {
 				bulkPost = append(bulkPost, entityData)
 			}
 
-			err := s.doPost(bulkPost, agentKey)
+			err := s.send(bulkPost, agentKey)
 
 			if err == nil {
 				atomic.StoreUint32(s.sendErrorCount, 0)
-				retryBO.Reset()
+				retryBO.Success()
 				continue
 			}
 
 			currentSendErrCount := atomic.AddUint32(s.sendErrorCount, 1)
 			vlog.WithError(err).WithField("sendErrorCount", currentSendErrCount).Error("metric sender can't process")
 
-			e, ok := err.(*errRetry)
+			policy, ok := retryPolicy(err)
 			if !ok {
 				continue
 			}
 
-			if e.retryPolicy.After > 0 {
-				vlog.WithField("retryAfter", e.retryPolicy.After).Debug("Metric sender retry requested.")
-				retryBO.Reset()
-				s.backoff(e.retryPolicy.After)
-				continue
+			retryBOAfter := retryBO.Next(policy)
+			if policy.After > 0 {
+				vlog.WithField("retryAfter", retryBOAfter).Debug("Metric sender retry requested.")
+			} else {
+				vlog.WithField("retryBackoffAfter", retryBOAfter).Debug("Metric sender backoff and retry requested.")
 			}
-			retryBOAfter := retryBO.DurationWithMax(e.retryPolicy.MaxBackOff)
-			vlog.WithField("retryBackoffAfter", retryBOAfter).Debug("Metric sender backoff and retry requested.")
+			instrumentation.RecordRetryStats(context2.Background(), retryBO.Stats())
 			s.backoff(retryBOAfter)
 
 		case <-s.stopChannel:
@@ -496,9 +530,52 @@ func (s *vortexEventSender) backoff(d time.Duration) {
 	}
 }
 
-// Make one HTTP call to push a load of events up to the server
-func (s *vortexEventSender) doPost(post []*MetricVortexPost, agentKey string) error {
+// send posts the batch, splitting it in halves while it's too large to be posted. Events too large to be posted by
+// themselves are dropped.
+func (s *vortexEventSender) send(post MetricVortexPostBatch, agentKey string) error {
+	postBytes, err := json.Marshal(post)
+	if err != nil {
+		return fmt.Errorf("Could not marshal events object [%v]: %v", post, err)
+	}
+
+	if len(postBytes) <= s.maxMetricsBatchSizeBytes {
+		err = s.sendPayload(postBytes, agentKey)
+		if !isPayloadTooLarge(err) {
+			return err
+		}
+	}
 
+	first, second, ok := post.split()
+	if !ok {
+		return s.payloadStats.drop(post.eventCount())
+	}
+	s.payloadStats.split()
+	vlog.WithField("postBytes", len(postBytes)).Debug("Splitting metrics post too large to be sent.")
+
+	err = s.send(first, agentKey)
+	if secondErr := s.send(second, agentKey); err == nil {
+		err = secondErr
+	}
+	return err
+}
+
+// sendPayload posts the marshalled batch, spooling it when the backend is unavailable. When there are spooled
+// batches the new one is spooled as well, and the oldest ones are sent first so the backend receives them in order.
+func (s *vortexEventSender) sendPayload(postBytes []byte, agentKey string) error {
+	if !s.spool.pending() {
+		err := s.postPayload(postBytes, agentKey)
+		if isBackendUnavailable(err) {
+			s.spool.push(json.RawMessage(postBytes), agentKey)
+		}
+		return err
+	}
+
+	s.spool.push(json.RawMessage(postBytes), agentKey)
+	return s.spool.replay(s.postPayload)
+}
+
+// postPayload sends the marshalled events post to the server.
+func (s *vortexEventSender) postPayload(postBytes []byte, agentKey string) error {
 	if agentKey == "" {
 		vlog.Warn("no available agent-key on metrics sender")
 	}
@@ -510,37 +587,17 @@ func (s *vortexEventSender) doPost(post []*MetricVortexPost, agentKey string) er
 		return fmt.Errorf("empty agent-id on metrics sender")
 	}
 
-	postBytes, err := json.Marshal(post)
+	reqBody, encoding, err := s.compressor.Compress(postBytes)
 	if err != nil {
-		return fmt.Errorf("Could not marshal events object [%v]: %v", post, err)
-	}
-
-	// GZIP
-	var reqBuf *bytes.Buffer
-	if s.Context.Config().PayloadCompressionLevel > gzip.NoCompression {
-		// GZIP
-		reqBuf = &bytes.Buffer{}
-		compressionLevel := s.Context.Config().PayloadCompressionLevel
-		gzipWriter, err := gzip.NewWriterLevel(reqBuf, compressionLevel)
-		if err != nil {
-			return fmt.Errorf("Unable to create gzip writer: %v", err)
-		}
-		if _, err := gzipWriter.Write(postBytes); err != nil {
-			return fmt.Errorf("Gzip writer was not able to write to request body: %s", err)
-		}
-		if err := gzipWriter.Close(); err != nil {
-			return fmt.Errorf("Gzip writer did not close: %s", err)
-		}
-	} else {
-		reqBuf = bytes.NewBuffer(postBytes)
+		return err
 	}
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/events/bulk", s.metricIngestURL), reqBuf)
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/events/bulk", s.metricIngestURL), bytes.NewReader(reqBody))
 	if err != nil {
 		return fmt.Errorf("Error creating event POST: %v", err)
 	}
 
-	if s.Context.Config().PayloadCompressionLevel > gzip.NoCompression {
-		req.Header.Set("Content-Encoding", "gzip")
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -549,9 +606,11 @@ func (s *vortexEventSender) doPost(post []*MetricVortexPost, agentKey string) er
 	req.Header.Set(backendhttp.EntityKeyHeader, agentKey)
 	req.Header.Set(backendhttp.AgentEntityIdHeader, agentID.String())
 
+	postStart := time.Now()
 	resp, err := s.HttpClient(req)
+	atomic.StoreInt64(&s.lastPostLatency, int64(time.Since(postStart)))
 	if err != nil {
-		return fmt.Errorf("error sending events: %v", err)
+		return &errUnreachable{fmt.Errorf("error sending events: %v", err)}
 	}
 
 	// To let the http client reusing the connections, the response body
@@ -562,6 +621,10 @@ func (s *vortexEventSender) doPost(post []*MetricVortexPost, agentKey string) er
 	defer resp.Body.Close()
 	buf, bodyErr := ioutil.ReadAll(resp.Body)
 
+	if s.compressor.Negotiate(resp, encoding) {
+		return s.postPayload(postBytes, agentKey)
+	}
+
 	hasError, cause := backendhttp.IsResponseUnsuccessful(resp)
 
 	if !hasError {
@@ -572,23 +635,11 @@ func (s *vortexEventSender) doPost(post []*MetricVortexPost, agentKey string) er
 		return fmt.Errorf("error sending events: Unable to read server response: %s", bodyErr)
 	}
 
-	var retryPolicy backendhttp.RetryPolicy
-	retryAfterH := resp.Header.Get("Retry-After")
-	if retryAfterH != "" {
-		if retryPolicy.After, err = time.ParseDuration(retryAfterH + "s"); err != nil {
-			vlog.WithError(err).Debug(
-				"error parsing connect Retry-After header, continuing with exponential backoff",
-			)
-		}
-	}
-
-	retryPolicy.MaxBackOff = backoff.GetMaxBackoffByCause(cause)
-
 	return newErrRetry(
 		"events were not accepted",
 		resp.StatusCode,
 		resp.Status,
 		string(buf),
-		retryPolicy,
+		newRetryPolicy(resp, cause, vlog),
 	)
 }
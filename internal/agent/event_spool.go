@@ -0,0 +1,134 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/spool"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+// SPOOL_REPLAY_LIMIT is the maximum amount of spooled payloads replayed per batch, so the batch queue doesn't fill
+// up while the spool is drained after a long outage.
+const SPOOL_REPLAY_LIMIT = 10
+
+var splog = log.WithComponent("PayloadSpool")
+
+// errUnreachable is returned when a payload couldn't be sent to the backend.
+type errUnreachable struct {
+	error
+}
+
+func (e *errUnreachable) Unwrap() error {
+	return e.error
+}
+
+// spooledPost is a metrics post that couldn't be sent, along with the agent key it was sent with.
+type spooledPost struct {
+	AgentKey string          `json:"agentKey"`
+	Post     json.RawMessage `json:"post"`
+}
+
+// payloadSender sends a marshalled metrics post to the backend.
+type payloadSender func(postBytes []byte, agentKey string) error
+
+// payloadSpool keeps on disk the metrics posts which couldn't be sent because the backend was unavailable, so they
+// are not lost during collector outages or agent restarts. A nil spool is disabled.
+type payloadSpool struct {
+	*spool.Spool
+}
+
+// newPayloadSpool returns the configured payload spool, or nil when it's disabled or cannot be opened.
+func newPayloadSpool(cfg *config.Config) *payloadSpool {
+	if cfg == nil || cfg.PayloadSpoolMaxSizeMb <= 0 {
+		return nil
+	}
+
+	s, err := spool.New(
+		cfg.PayloadSpoolDir,
+		int64(cfg.PayloadSpoolMaxSizeMb)*1024*1024,
+		time.Duration(cfg.PayloadSpoolMaxAgeSec)*time.Second,
+	)
+	if err != nil {
+		splog.WithError(err).Error("Cannot open the payload spool, payloads won't be kept during backend outages.")
+		return nil
+	}
+	if s.Len() > 0 {
+		splog.WithField("payloads", s.Len()).WithField("bytes", s.Size()).Info("Spooled payloads will be replayed.")
+	}
+	return &payloadSpool{Spool: s}
+}
+
+// pending returns true when there are spooled payloads, which must be sent before any newer one to keep the order.
+func (p *payloadSpool) pending() bool {
+	return p != nil && p.Len() > 0
+}
+
+// push spools the post, logging the errors as there is nothing else to do with it.
+func (p *payloadSpool) push(post interface{}, agentKey string) {
+	if p == nil {
+		return
+	}
+
+	postBytes, err := json.Marshal(post)
+	if err == nil {
+		postBytes, err = json.Marshal(spooledPost{AgentKey: agentKey, Post: postBytes})
+	}
+	if err == nil {
+		err = p.Push(postBytes)
+	}
+	if err != nil {
+		splog.WithError(err).Warn("Cannot spool payload, discarding it.")
+		return
+	}
+	splog.WithField("payloads", p.Len()).Debug("Payload spooled.")
+}
+
+// replay sends the oldest spooled payloads, stopping on the first one failing because the backend is unavailable.
+// Payloads rejected for other reasons, like being malformed, would never be accepted so they are discarded.
+func (p *payloadSpool) replay(send payloadSender) error {
+	for i := 0; i < SPOOL_REPLAY_LIMIT; i++ {
+		e, err := p.Peek()
+		if err != nil {
+			// the spool is empty
+			return nil
+		}
+
+		var sp spooledPost
+		if err = json.Unmarshal(e.Payload, &sp); err == nil {
+			err = send(sp.Post, sp.AgentKey)
+			if isBackendUnavailable(err) {
+				return err
+			}
+		}
+		if err != nil {
+			splog.WithError(err).WithField("spooledAt", e.Created).Warn("Spooled payload discarded.")
+		}
+
+		if err = p.Remove(e.Name); err != nil {
+			splog.WithError(err).Warn("Cannot remove sent payload from the spool.")
+		}
+	}
+	return nil
+}
+
+// isBackendUnavailable returns true when the payload could be accepted by sending it again later: the backend
+// couldn't be reached, it's failing or it's throttling the agent.
+func isBackendUnavailable(err error) bool {
+	var unreachable *errUnreachable
+	if errors.As(err, &unreachable) {
+		return true
+	}
+	var retry *errRetry
+	if errors.As(err, &retry) {
+		return retry.StatusCode >= http.StatusInternalServerError ||
+			retry.StatusCode == http.StatusRequestTimeout ||
+			retry.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"compress/gzip"
+	goContext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spoolTestPost(n int) MetricPostBatch {
+	return MetricPostBatch{{Events: []json.RawMessage{json.RawMessage(fmt.Sprintf(`{"n":%d}`, n))}}}
+}
+
+func TestEventSender_SpoolsWhileBackendUnavailable(t *testing.T) {
+	status := 0
+	var received []string
+	client := func(req *http.Request) (*http.Response, error) {
+		if status == 0 {
+			return nil, errors.New("connection refused")
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		if status == http.StatusAccepted {
+			received = append(received, string(body))
+		}
+		return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	cfg := &config.Config{
+		PayloadCompressionLevel: gzip.NoCompression,
+		PayloadSpoolMaxSizeMb:   1,
+		PayloadSpoolDir:         t.TempDir(),
+	}
+	ctx := goContext.Background()
+
+	sender := newMetricsIngestSender(newTestContext("testAgent", cfg), "license", "userAgent", client, false)
+	assert.Error(t, sender.send(ctx, spoolTestPost(1), "testAgent"))
+	status = http.StatusServiceUnavailable
+	assert.Error(t, sender.send(ctx, spoolTestPost(2), "testAgent"))
+	assert.Equal(t, 2, sender.spool.Len())
+
+	// spooled payloads survive agent restarts
+	sender = newMetricsIngestSender(newTestContext("testAgent", cfg), "license", "userAgent", client, false)
+	require.Equal(t, 2, sender.spool.Len())
+
+	status = http.StatusAccepted
+	assert.NoError(t, sender.send(ctx, spoolTestPost(3), "testAgent"))
+	assert.Zero(t, sender.spool.Len())
+
+	require.Len(t, received, 3)
+	for i, body := range received {
+		assert.Contains(t, body, fmt.Sprintf(`{"n":%d}`, i+1))
+	}
+}
+
+func TestEventSender_DoesNotSpoolRejectedPayloads(t *testing.T) {
+	client := func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusBadRequest, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	cfg := &config.Config{
+		PayloadCompressionLevel: gzip.NoCompression,
+		PayloadSpoolMaxSizeMb:   1,
+		PayloadSpoolDir:         t.TempDir(),
+	}
+	sender := newMetricsIngestSender(newTestContext("testAgent", cfg), "license", "userAgent", client, false)
+
+	assert.Error(t, sender.send(goContext.Background(), spoolTestPost(1), "testAgent"))
+	assert.Zero(t, sender.spool.Len())
+}
+
+func TestEventSender_SpoolDisabled(t *testing.T) {
+	sender := newMetricsIngestSender(newTestContext("testAgent", &config.Config{}), "license", "userAgent", nil, false)
+
+	assert.Nil(t, sender.spool)
+	assert.False(t, sender.spool.pending())
+}
+
+func TestIsBackendUnavailable(t *testing.T) {
+	retryErr := func(code int) error {
+		return newErrRetry("events were not accepted", code, "", "", backendhttp.RetryPolicy{MaxBackOff: backoff.GetMaxBackoffByCause(backendhttp.ServiceError)})
+	}
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"no error":          {nil, false},
+		"unreachable":       {&errUnreachable{errors.New("timeout")}, true},
+		"server error":      {retryErr(http.StatusInternalServerError), true},
+		"throttled":         {retryErr(http.StatusTooManyRequests), true},
+		"request timeout":   {retryErr(http.StatusRequestTimeout), true},
+		"bad request":       {retryErr(http.StatusBadRequest), false},
+		"invalid license":   {retryErr(http.StatusUnauthorized), false},
+		"marshalling error": {errors.New("Could not marshal events object"), false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, isBackendUnavailable(tc.err))
+		})
+	}
+}
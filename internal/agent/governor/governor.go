@@ -0,0 +1,312 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package governor limits the resources used by the agent, degrading what it collects while its own CPU or memory
+// usage exceeds the configured ceilings, ie: on constrained edge devices.
+package governor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const eventType = "AgentGovernorEvent"
+
+const (
+	// intervalFactor multiplies the sample rates while degraded.
+	intervalFactor = 2
+	// recoveryRatio of the ceilings the usage has to be below for recoveryChecks consecutive checks to recover a
+	// degradation level, so the agent doesn't flap around the ceilings.
+	recoveryRatio  = 0.8
+	recoveryChecks = 3
+)
+
+// processSampleRateKey is the sample rate of the process samples, disabled from LevelNoProcessSamples.
+const processSampleRateKey = "metrics_process_sample_rate"
+
+// sampleRateKeys are the sample rates multiplied from LevelIntervals.
+var sampleRateKeys = []string{
+	"metrics_system_sample_rate",
+	"metrics_storage_sample_rate",
+	"metrics_network_sample_rate",
+	processSampleRateKey,
+	"metrics_nfs_sample_rate",
+}
+
+var glog = log.WithComponent("Governor")
+
+// Level is a degradation level, each one adding to the previous ones.
+type Level int
+
+const (
+	// LevelNone collects as configured.
+	LevelNone Level = iota
+	// LevelIntervals multiplies the sample rates by intervalFactor.
+	LevelIntervals
+	// LevelNoProcessSamples disables the process samples.
+	LevelNoProcessSamples
+	// LevelShedQueues discards the queued events on every check exceeding a ceiling.
+	LevelShedQueues
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelNone:
+		return "none"
+	case LevelIntervals:
+		return "longer_intervals"
+	case LevelNoProcessSamples:
+		return "no_process_samples"
+	case LevelShedQueues:
+		return "shed_queues"
+	}
+	return fmt.Sprintf("level_%d", int(l))
+}
+
+// Usage is the agent process resource usage.
+type Usage struct {
+	CPUPercent          float64
+	MemoryResidentBytes uint64
+}
+
+// Event reports a change of the degradation level.
+type Event struct {
+	sample.BaseEvent
+
+	Level               string  `json:"level"`
+	PreviousLevel       string  `json:"previousLevel"`
+	Summary             string  `json:"summary"`
+	CPUPercent          float64 `json:"cpuPercent"`
+	CPUPercentLimit     int     `json:"cpuPercentLimit,omitempty"`
+	MemoryResidentBytes uint64  `json:"memoryResidentBytes"`
+	MemoryLimitBytes    uint64  `json:"memoryLimitBytes,omitempty"`
+	ShedEvents          int     `json:"shedEvents,omitempty"`
+}
+
+// Governor checks the agent process resource usage periodically. Every check exceeding a ceiling degrades the agent
+// one level further, and every recoveryChecks consecutive checks below recoveryRatio of the ceilings recover one.
+type Governor struct {
+	cfg      *config.Config
+	registry *config.Registry
+	interval time.Duration
+	cpuLimit int
+	memLimit uint64
+	usage    func() (Usage, error)
+	shed     func() int
+	send     func(sample.Event)
+
+	lock        sync.Mutex
+	level       Level
+	belowChecks int
+	original    map[string]interface{} // values of the degraded options, restored on recovery
+	degraded    map[string]interface{} // values set by the governor
+}
+
+// New creates the governor of the agent resources. The shed function discards the queued events, returning how
+// many, and send reports the governor events.
+func New(cfg *config.Config, shed func() int, send func(sample.Event)) (*Governor, error) {
+	if cfg.GovernorIntervalSec <= 0 {
+		return nil, fmt.Errorf("invalid governor_interval_sec: %d", cfg.GovernorIntervalSec)
+	}
+	registry, err := config.NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve agent process: %w", err)
+	}
+	// primes the CPU usage calculation, as the first reading has no previous one to compare with
+	_, _ = proc.Percent(0)
+
+	return newGovernor(cfg, registry, processUsage(proc), shed, send), nil
+}
+
+func newGovernor(cfg *config.Config, registry *config.Registry, usage func() (Usage, error), shed func() int, send func(sample.Event)) *Governor {
+	return &Governor{
+		cfg:      cfg,
+		registry: registry,
+		interval: time.Duration(cfg.GovernorIntervalSec) * time.Second,
+		cpuLimit: cfg.GovernorCPUPercentLimit,
+		memLimit: uint64(cfg.GovernorMemoryLimitMB) * 1024 * 1024,
+		usage:    usage,
+		shed:     shed,
+		send:     send,
+	}
+}
+
+// processUsage returns the CPU usage since the previous call and the resident memory of the process.
+func processUsage(proc *process.Process) func() (Usage, error) {
+	return func() (Usage, error) {
+		cpu, err := proc.Percent(0)
+		if err != nil {
+			return Usage{}, err
+		}
+		mem, err := proc.MemoryInfo()
+		if err != nil {
+			return Usage{}, err
+		}
+		return Usage{CPUPercent: cpu, MemoryResidentBytes: mem.RSS}, nil
+	}
+}
+
+// Run checks the agent resource usage every interval, until the context is done.
+func (g *Governor) Run(ctx context.Context) {
+	glog.
+		WithField("cpu_percent_limit", g.cpuLimit).
+		WithField("memory_limit_bytes", g.memLimit).
+		WithField("interval", g.interval).
+		Info("Governing agent resources.")
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			usage, err := g.usage()
+			if err != nil {
+				glog.WithError(err).Warn("Cannot retrieve agent resource usage.")
+				continue
+			}
+			g.Check(usage)
+		}
+	}
+}
+
+// Level returns the current degradation level.
+func (g *Governor) Level() Level {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.level
+}
+
+// Check degrades or recovers the agent according to its resource usage.
+func (g *Governor) Check(usage Usage) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	switch {
+	case g.exceeds(usage, 1):
+		g.belowChecks = 0
+		if g.level < LevelShedQueues {
+			g.setLevel(g.level+1, usage)
+			return
+		}
+		if shed := g.shedQueues(); shed > 0 {
+			glog.WithField("events", shed).Warn("Agent resources still exceed the governor limits, discarded the queued events.")
+		}
+	case g.level > LevelNone && !g.exceeds(usage, recoveryRatio):
+		g.belowChecks++
+		if g.belowChecks >= recoveryChecks {
+			g.belowChecks = 0
+			g.setLevel(g.level-1, usage)
+		}
+	default:
+		g.belowChecks = 0
+	}
+}
+
+// exceeds returns whether the usage exceeds the ratio of any of the ceilings.
+func (g *Governor) exceeds(usage Usage, ratio float64) bool {
+	if g.cpuLimit > 0 && usage.CPUPercent > float64(g.cpuLimit)*ratio {
+		return true
+	}
+	return g.memLimit > 0 && float64(usage.MemoryResidentBytes) > float64(g.memLimit)*ratio
+}
+
+func (g *Governor) setLevel(level Level, usage Usage) {
+	previous := g.level
+	if err := g.applySampleRates(level); err != nil {
+		glog.WithError(err).WithField("level", level).Warn("Cannot apply the governor sample rates.")
+	}
+	g.level = level
+
+	e := &Event{
+		Level:               level.String(),
+		PreviousLevel:       previous.String(),
+		CPUPercent:          usage.CPUPercent,
+		CPUPercentLimit:     g.cpuLimit,
+		MemoryResidentBytes: usage.MemoryResidentBytes,
+		MemoryLimitBytes:    g.memLimit,
+	}
+	if level == LevelShedQueues {
+		e.ShedEvents = g.shedQueues()
+	}
+	if level > previous {
+		e.Summary = fmt.Sprintf("Agent degraded to %s, its resources exceed the governor limits", level)
+	} else {
+		e.Summary = fmt.Sprintf("Agent recovered to %s, its resources are below the governor limits", level)
+	}
+	glog.
+		WithField("level", level).
+		WithField("previous_level", previous).
+		WithField("cpu_percent", usage.CPUPercent).
+		WithField("memory_resident_bytes", usage.MemoryResidentBytes).
+		WithField("shed_events", e.ShedEvents).
+		Warn(e.Summary + ".")
+
+	e.Type(eventType)
+	e.Timestamp(time.Now().Unix())
+	g.send(e)
+}
+
+// shedQueues discards the queued events, returning the freed memory to the OS.
+func (g *Governor) shedQueues() int {
+	shed := g.shed()
+	debug.FreeOSMemory()
+	return shed
+}
+
+// applySampleRates sets the sample rates of the level into the running configuration. The sample rates modified
+// meanwhile, ie: by reloading the configuration, become the ones to degrade and restore.
+func (g *Governor) applySampleRates(level Level) error {
+	running := g.registry.Values(g.cfg)
+	if g.original == nil {
+		g.original = map[string]interface{}{}
+		for _, key := range sampleRateKeys {
+			g.original[key] = running[key]
+		}
+	}
+	for key, value := range g.degraded {
+		if !reflect.DeepEqual(running[key], value) {
+			g.original[key] = running[key]
+		}
+	}
+
+	values := map[string]interface{}{}
+	for _, key := range sampleRateKeys {
+		rate, _ := g.original[key].(int)
+		switch {
+		case level >= LevelNoProcessSamples && key == processSampleRateKey:
+			values[key] = config.FREQ_DISABLE_SAMPLING
+		case level >= LevelIntervals && rate > 0:
+			values[key] = rate * intervalFactor
+		default:
+			values[key] = rate
+		}
+	}
+	if err := g.registry.SetValues(g.cfg, values); err != nil {
+		return err
+	}
+
+	if level == LevelNone {
+		g.original, g.degraded = nil, nil
+	} else {
+		g.degraded = values
+	}
+	return nil
+}
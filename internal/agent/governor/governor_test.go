@@ -0,0 +1,123 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package governor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const mb = 1024 * 1024
+
+type recorder struct {
+	events []*Event
+	shed   int
+}
+
+func (r *recorder) send(e sample.Event) {
+	r.events = append(r.events, e.(*Event))
+}
+
+func (r *recorder) shedQueues() int {
+	r.shed++
+	return 10
+}
+
+func newTestGovernor(t *testing.T) (*Governor, *config.Config, *recorder) {
+	t.Helper()
+
+	cfg := config.NewConfig()
+	cfg.MetricsSystemSampleRate = 5
+	cfg.MetricsStorageSampleRate = 20
+	cfg.MetricsNetworkSampleRate = 10
+	cfg.MetricsProcessSampleRate = 20
+	cfg.MetricsNFSSampleRate = config.FREQ_DISABLE_SAMPLING
+	cfg.GovernorCPUPercentLimit = 50
+	cfg.GovernorMemoryLimitMB = 100
+
+	registry, err := config.NewRegistry()
+	require.NoError(t, err)
+	r := &recorder{}
+	return newGovernor(cfg, registry, nil, r.shedQueues, r.send), cfg, r
+}
+
+func TestGovernor_Degrade(t *testing.T) {
+	g, cfg, r := newTestGovernor(t)
+
+	g.Check(Usage{CPUPercent: 10, MemoryResidentBytes: 50 * mb})
+	assert.Equal(t, LevelNone, g.Level())
+	assert.Empty(t, r.events)
+
+	g.Check(Usage{CPUPercent: 80, MemoryResidentBytes: 50 * mb})
+	assert.Equal(t, LevelIntervals, g.Level())
+	assert.Equal(t, 10, cfg.MetricsSystemSampleRate)
+	assert.Equal(t, 40, cfg.MetricsStorageSampleRate)
+	assert.Equal(t, 20, cfg.MetricsNetworkSampleRate)
+	assert.Equal(t, 40, cfg.MetricsProcessSampleRate)
+	assert.Equal(t, config.FREQ_DISABLE_SAMPLING, cfg.MetricsNFSSampleRate)
+
+	g.Check(Usage{CPUPercent: 10, MemoryResidentBytes: 120 * mb})
+	assert.Equal(t, LevelNoProcessSamples, g.Level())
+	assert.Equal(t, 10, cfg.MetricsSystemSampleRate)
+	assert.Equal(t, config.FREQ_DISABLE_SAMPLING, cfg.MetricsProcessSampleRate)
+
+	g.Check(Usage{CPUPercent: 80, MemoryResidentBytes: 120 * mb})
+	assert.Equal(t, LevelShedQueues, g.Level())
+	assert.Equal(t, 1, r.shed)
+
+	// the queues keep being shed while the ceilings are exceeded
+	g.Check(Usage{CPUPercent: 80, MemoryResidentBytes: 120 * mb})
+	assert.Equal(t, LevelShedQueues, g.Level())
+	assert.Equal(t, 2, r.shed)
+
+	require.Len(t, r.events, 3)
+	assert.Equal(t, "AgentGovernorEvent", r.events[0].EventType)
+	assert.Equal(t, "longer_intervals", r.events[0].Level)
+	assert.Equal(t, "none", r.events[0].PreviousLevel)
+	assert.Equal(t, 80.0, r.events[0].CPUPercent)
+	assert.Equal(t, 50, r.events[0].CPUPercentLimit)
+	assert.Equal(t, uint64(100*mb), r.events[0].MemoryLimitBytes)
+	assert.Equal(t, "no_process_samples", r.events[1].Level)
+	assert.Equal(t, "shed_queues", r.events[2].Level)
+	assert.Equal(t, 10, r.events[2].ShedEvents)
+}
+
+func TestGovernor_Recover(t *testing.T) {
+	g, cfg, r := newTestGovernor(t)
+	g.Check(Usage{CPUPercent: 80})
+	g.Check(Usage{CPUPercent: 80})
+	require.Equal(t, LevelNoProcessSamples, g.Level())
+
+	// below the ceilings, but not below the recovery ratio
+	for i := 0; i < recoveryChecks; i++ {
+		g.Check(Usage{CPUPercent: 45})
+	}
+	assert.Equal(t, LevelNoProcessSamples, g.Level())
+
+	for i := 0; i < recoveryChecks; i++ {
+		g.Check(Usage{CPUPercent: 10})
+	}
+	assert.Equal(t, LevelIntervals, g.Level())
+	assert.Equal(t, 40, cfg.MetricsProcessSampleRate)
+
+	// a sample rate modified meanwhile, ie: reloading the configuration, is the one restored
+	cfg.MetricsSystemSampleRate = 30
+	for i := 0; i < recoveryChecks; i++ {
+		g.Check(Usage{CPUPercent: 10})
+	}
+	assert.Equal(t, LevelNone, g.Level())
+	assert.Equal(t, 30, cfg.MetricsSystemSampleRate)
+	assert.Equal(t, 20, cfg.MetricsStorageSampleRate)
+	assert.Equal(t, 20, cfg.MetricsProcessSampleRate)
+
+	require.Len(t, r.events, 4)
+	assert.Equal(t, "longer_intervals", r.events[2].Level)
+	assert.Equal(t, "no_process_samples", r.events[2].PreviousLevel)
+	assert.Equal(t, "none", r.events[3].Level)
+	assert.Zero(t, r.shed)
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"github.com/newrelic/infrastructure-agent/internal/agent/delta"
+	"github.com/newrelic/infrastructure-agent/internal/agent/entityname"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/fingerprint"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+)
+
+// resetClonedIdentity resets the agent identity stored in the data directory when the host is a clone of the one
+// which stored it, ie: a VM created from a golden image without removing the agent data, so the clone registers as
+// a new host instead of reporting its metrics and inventory as the original one. It returns whether it was reset.
+func resetClonedIdentity(cfg *config.Config, dataDir string, cloudHarvester cloud.Harvester) bool {
+	if !cfg.CloneDetectionEnabled {
+		return false
+	}
+
+	current := fingerprint.HarvestIdentifiers(cfg.HostIdentitySources, cloudHarvester)
+	stored, found, err := fingerprint.LoadIdentifiers(dataDir)
+	if err != nil {
+		alog.WithError(err).Warn("Cannot load the stored host identifiers.")
+	}
+
+	var reset bool
+	if found {
+		if source, cloned := fingerprint.IsClone(stored, current, cfg.HostIdentitySources); cloned {
+			alog.WithField("identifier", source).
+				Warn("Host identifier differs from the stored one, the host is a clone: resetting the agent identity.")
+			removed, err := delta.ResetIdentity(dataDir, cfg.PayloadSpoolDir)
+			if err != nil {
+				alog.WithError(err).Error("Cannot reset the agent identity.")
+			}
+			for _, path := range removed {
+				alog.WithField("path", path).Debug("Removed agent identity state.")
+			}
+			if _, err = entityname.ResetState(dataDir); err != nil {
+				alog.WithError(err).Error("Cannot reset the entity name.")
+			}
+			reset = true
+		}
+	}
+
+	// the identifiers known only now, ie: the instance metadata was unavailable, are stored for the next runs
+	if err = fingerprint.StoreIdentifiers(dataDir, mergeIdentifiers(stored, current, reset)); err != nil {
+		alog.WithError(err).Warn("Cannot store the host identifiers.")
+	}
+	return reset
+}
+
+// mergeIdentifiers returns the current identifiers, completed with the stored ones unknown now unless the host is a clone.
+func mergeIdentifiers(stored, current fingerprint.Identifiers, cloned bool) fingerprint.Identifiers {
+	if cloned {
+		return current
+	}
+	if current.CloudInstanceID == "" {
+		current.CloudInstanceID = stored.CloudInstanceID
+	}
+	if current.DMIUUID == "" {
+		current.DMIUUID = stored.DMIUUID
+	}
+	if current.MachineID == "" {
+		current.MachineID = stored.MachineID
+	}
+	return current
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/fingerprint"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+)
+
+func TestResetClonedIdentity(t *testing.T) {
+	dataDir := t.TempDir()
+	cfg := &config.Config{
+		CloneDetectionEnabled: true,
+		HostIdentitySources:   []string{fingerprint.IdentitySourceCloudInstanceID},
+		PayloadSpoolDir:       filepath.Join(dataDir, "spool"),
+	}
+	harvester := NewMockHarvester(t, cloud.TypeAWS, true)
+	currentID, _ := harvester.GetInstanceID()
+
+	// first run, the identifiers are stored
+	assert.False(t, resetClonedIdentity(cfg, dataDir, harvester))
+	stored, found, err := fingerprint.LoadIdentifiers(dataDir)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, currentID, stored.CloudInstanceID)
+
+	// same host
+	inventory := filepath.Join(dataDir, "packages")
+	require.NoError(t, os.MkdirAll(inventory, 0o755))
+	assert.False(t, resetClonedIdentity(cfg, dataDir, harvester))
+	assert.DirExists(t, inventory)
+
+	// the data directory comes from an image taken in another instance
+	require.NoError(t, fingerprint.StoreIdentifiers(dataDir, fingerprint.Identifiers{CloudInstanceID: "i-golden"}))
+	require.NoError(t, os.MkdirAll(cfg.PayloadSpoolDir, 0o755))
+	assert.True(t, resetClonedIdentity(cfg, dataDir, harvester))
+	assert.NoDirExists(t, inventory)
+	assert.DirExists(t, cfg.PayloadSpoolDir)
+	stored, _, err = fingerprint.LoadIdentifiers(dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, currentID, stored.CloudInstanceID)
+}
+
+func TestResetClonedIdentity_CloudNotDetectedYet(t *testing.T) {
+	dataDir := t.TempDir()
+	cfg := &config.Config{
+		CloneDetectionEnabled: true,
+		HostIdentitySources:   []string{fingerprint.IdentitySourceCloudInstanceID, fingerprint.IdentitySourceMachineID},
+	}
+	require.NoError(t, fingerprint.StoreIdentifiers(dataDir, fingerprint.Identifiers{CloudInstanceID: "i-1", MachineID: "regenerated"}))
+
+	// the machine ID isn't compared while the cloud instance ID isn't known
+	assert.False(t, resetClonedIdentity(cfg, dataDir, NewMockHarvester(t, cloud.TypeInProgress, false)))
+	stored, _, err := fingerprint.LoadIdentifiers(dataDir)
+	require.NoError(t, err)
+	assert.Equal(t, "i-1", stored.CloudInstanceID)
+}
+
+func TestResetClonedIdentity_Disabled(t *testing.T) {
+	dataDir := t.TempDir()
+	cfg := &config.Config{HostIdentitySources: []string{fingerprint.IdentitySourceCloudInstanceID}}
+	require.NoError(t, fingerprint.StoreIdentifiers(dataDir, fingerprint.Identifiers{CloudInstanceID: "i-golden"}))
+
+	assert.False(t, resetClonedIdentity(cfg, dataDir, NewMockHarvester(t, cloud.TypeAWS, true)))
+}
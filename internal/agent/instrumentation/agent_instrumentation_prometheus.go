@@ -0,0 +1,38 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+	"fmt"
+
+	promInstrumentation "github.com/newrelic/infrastructure-agent/internal/instrumentation"
+)
+
+// agentInstrumentationPrometheus exposes the self-instrumentation gauges in the Prometheus format, besides
+// recording them in the wrapped instrumentation.
+type agentInstrumentationPrometheus struct {
+	AgentInstrumentation
+	prometheus *promInstrumentation.Prometheus
+}
+
+// ExposeMetrics exposes the self-instrumentation gauges in the Prometheus format. It has to be called after
+// InitSelfInstrumentation, as it wraps the configured instrumentation.
+func ExposeMetrics(prometheus *promInstrumentation.Prometheus) {
+	SelfInstrumentation = &agentInstrumentationPrometheus{
+		AgentInstrumentation: SelfInstrumentation,
+		prometheus:           prometheus,
+	}
+}
+
+func (a *agentInstrumentationPrometheus) RecordMetric(ctx context.Context, metric metric) {
+	if metric.Type == Gauge {
+		labels := make(map[string]string, len(metric.Attributes))
+		for name, value := range metric.Attributes {
+			labels[name] = fmt.Sprint(value)
+		}
+		a.prometheus.SetGauge(promInstrumentation.PrometheusName(promInstrumentation.PrometheusPrefix, metric.Name), labels, metric.Value)
+	}
+	a.AgentInstrumentation.RecordMetric(ctx, metric)
+}
@@ -0,0 +1,23 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"context"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
+)
+
+// RecordRetryStats reports the retries of the requests to a backend endpoint.
+func RecordRetryStats(ctx context.Context, stats backoff.EndpointStats) {
+	attrs := map[string]interface{}{"endpoint": stats.Endpoint}
+	for name, value := range map[string]float64{
+		"agent.retry.count":               float64(stats.Retries),
+		"agent.retry.throttledCount":      float64(stats.Throttled),
+		"agent.retry.consecutiveFailures": float64(stats.ConsecutiveFailures),
+		"agent.retry.backoffSeconds":      stats.LastBackoff.Seconds(),
+	} {
+		SelfInstrumentation.RecordMetric(ctx, NewGaugeWithAttributes(name, value, attrs))
+	}
+}
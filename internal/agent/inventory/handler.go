@@ -5,12 +5,10 @@ package inventory
 
 import (
 	context2 "context"
+	"github.com/newrelic/infrastructure-agent/internal/agent/instrumentation"
 	"github.com/newrelic/infrastructure-agent/internal/agent/types"
-	"github.com/newrelic/infrastructure-agent/pkg/backend/inventoryapi"
-	"github.com/newrelic/infrastructure-agent/pkg/config"
-	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
-	"net/http"
 	"time"
 )
 
@@ -41,7 +39,7 @@ type Handler struct {
 
 	sendTimer *time.Timer
 
-	sendErrorCount uint32
+	sendBackoff *backoff.EndpointBackoff
 }
 
 // NewInventoryHandler returns a new instances of an inventory.Handler.
@@ -55,6 +53,7 @@ func NewInventoryHandler(ctx context2.Context, cfg HandlerConfig, patcher Patche
 		cancelFn:    cancelFn,
 		patcher:     patcher,
 		initialReap: true,
+		sendBackoff: NewSendBackoff(cfg.SendInterval),
 	}
 }
 
@@ -117,29 +116,20 @@ func (h *Handler) doProcess() {
 
 // send will submit the deltas.
 func (h *Handler) send() {
-	backoffMax := config.MAX_BACKOFF
+	sendTimerVal := h.cfg.SendInterval
 
 	err := h.patcher.Send()
 	if err != nil {
-		if ingestError, ok := err.(*inventoryapi.IngestError); ok &&
-			ingestError.StatusCode == http.StatusTooManyRequests {
+		sendTimerVal = h.sendBackoff.Next(RetryPolicy(err))
+		stats := h.sendBackoff.Stats()
+		instrumentation.RecordRetryStats(h.ctx, stats)
 
-			ilog.Warn("server is rate limiting inventory submission")
-
-			backoffMax = config.RATE_LIMITED_BACKOFF
-			h.sendErrorCount = helpers.MaxBackoffErrorCount
-		} else {
-			h.sendErrorCount++
-		}
-
-		ilog.WithError(err).WithField("errorCount", h.sendErrorCount).
+		ilog.WithError(err).WithField("errorCount", stats.ConsecutiveFailures).
+			WithField("retryAfter", sendTimerVal).
 			Debug("Inventory sender can't process data.")
 	} else {
-		h.sendErrorCount = 0
+		h.sendBackoff.Success()
 	}
 
-	sendTimerVal := helpers.ExpBackoff(h.cfg.SendInterval,
-		time.Duration(backoffMax)*time.Second,
-		h.sendErrorCount)
 	h.sendTimer.Reset(sendTimerVal)
 }
@@ -0,0 +1,39 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/inventoryapi"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+// NewSendBackoff returns the backoff for the inventory submissions, regularly sent every interval.
+func NewSendBackoff(interval time.Duration) *backoff.EndpointBackoff {
+	return backoff.NewEndpointBackoff("inventory").WithMin(interval)
+}
+
+// RetryPolicy returns the retry policy for a failed inventory submission. Delays requested by the backend are
+// honored, when the submissions are rate limited without a requested delay they wait for RATE_LIMITED_BACKOFF.
+func RetryPolicy(err error) backendhttp.RetryPolicy {
+	policy := backendhttp.RetryPolicy{MaxBackOff: time.Duration(config.MAX_BACKOFF) * time.Second}
+
+	var ingestErr *inventoryapi.IngestError
+	if !errors.As(err, &ingestErr) {
+		return policy
+	}
+	policy.After = ingestErr.RetryAfter
+	if ingestErr.StatusCode == http.StatusTooManyRequests {
+		ilog.WithField("retryAfter", policy.After).Warn("server is rate limiting inventory submission")
+		if policy.After == 0 {
+			policy.After = time.Duration(config.RATE_LIMITED_BACKOFF) * time.Second
+		}
+	}
+	return policy
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/inventoryapi"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	maxBackoff := time.Duration(config.MAX_BACKOFF) * time.Second
+
+	ingestErr := func(code int, retryAfter time.Duration) error {
+		err := inventoryapi.NewIngestError("inventory deltas were not accepted", code, "", "")
+		err.RetryAfter = retryAfter
+		return err
+	}
+
+	tests := map[string]struct {
+		err       error
+		wantAfter time.Duration
+	}{
+		"network error":                    {errors.New("connection refused"), 0},
+		"server error":                     {ingestErr(http.StatusServiceUnavailable, 0), 0},
+		"server error with retry after":    {ingestErr(http.StatusServiceUnavailable, time.Minute), time.Minute},
+		"rate limited with retry after":    {ingestErr(http.StatusTooManyRequests, 30*time.Second), 30 * time.Second},
+		"rate limited without retry after": {ingestErr(http.StatusTooManyRequests, 0), time.Duration(config.RATE_LIMITED_BACKOFF) * time.Second},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy := RetryPolicy(tc.err)
+			assert.Equal(t, tc.wantAfter, policy.After)
+			assert.Equal(t, maxBackoff, policy.MaxBackOff)
+		})
+	}
+}
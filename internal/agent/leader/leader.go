@@ -0,0 +1,157 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package leader elects a leader per group among the agents sharing a directory, ie: an NFS or SMB mount, so only
+// one of them reports the samples of a resource they all monitor. The leader holds an exclusive lock on the file of
+// the group until it stops, when the lock is released and any other agent can become the leader.
+package leader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+// errLocked is returned when another process holds the lock of a group.
+var errLocked = errors.New("lock held by another process")
+
+var llog = log.WithComponent("LeaderElection")
+
+var (
+	defaultLock    sync.Mutex
+	defaultElector *Elector
+)
+
+// SetDefault sets the elector of the agent, nil when leader election is not configured.
+func SetDefault(e *Elector) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+
+	defaultElector = e
+}
+
+// Default returns the elector of the agent, nil when leader election is not configured.
+func Default() *Elector {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+
+	return defaultElector
+}
+
+// Elector campaigns to lead the groups it's asked about, keeping the leaderships it gets until it's closed.
+type Elector struct {
+	dir      string
+	interval time.Duration
+	holder   string
+	now      func() time.Time
+
+	lock   sync.Mutex
+	groups map[string]*group
+}
+
+type group struct {
+	file    *os.File // lock file, open while leading the group
+	lastTry time.Time
+}
+
+// NewElector creates an elector using the lock files in dir. The followers of a group try to become its leader at
+// most once per interval. The holder identifies this agent in the lock files, for the logs of the followers.
+func NewElector(dir string, interval time.Duration, holder string) *Elector {
+	return &Elector{
+		dir:      dir,
+		interval: interval,
+		holder:   holder,
+		now:      time.Now,
+		groups:   map[string]*group{},
+	}
+}
+
+// IsLeader returns whether this agent leads the group, trying to become its leader when it doesn't.
+func (e *Elector) IsLeader(name string) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	g, ok := e.groups[name]
+	if !ok {
+		g = &group{}
+		e.groups[name] = g
+	}
+	if g.file != nil {
+		return true
+	}
+
+	now := e.now()
+	if !g.lastTry.IsZero() && now.Sub(g.lastTry) < e.interval {
+		return false
+	}
+	g.lastTry = now
+
+	glog := llog.WithField("group", name)
+	f, err := e.acquire(name)
+	if errors.Is(err, errLocked) {
+		glog.WithField("leader", e.leader(name)).Debug("Another agent leads the group.")
+		return false
+	}
+	if err != nil {
+		glog.WithError(err).Warn("Cannot campaign to lead the group.")
+		return false
+	}
+	g.file = f
+	glog.Info("Became the leader of the group.")
+	return true
+}
+
+// Close releases the leaderships held, so other agents can take them over without waiting for this one to stop.
+func (e *Elector) Close() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for name, g := range e.groups {
+		if g.file != nil {
+			_ = g.file.Close()
+			llog.WithField("group", name).Info("Released the leadership of the group.")
+		}
+	}
+	e.groups = map[string]*group{}
+}
+
+// acquire locks the file of the group, writing the holder into it.
+func (e *Elector) acquire(name string) (*os.File, error) {
+	f, err := os.OpenFile(e.path(name), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err = tryLock(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	holder := fmt.Sprintf("%s since %s\n", e.holder, e.now().Format(time.RFC3339))
+	if err = f.Truncate(0); err == nil {
+		_, err = f.WriteAt([]byte(holder), 0)
+	}
+	if err != nil {
+		llog.WithError(err).WithField("group", name).Debug("Cannot write the leader into the lock file.")
+	}
+	return f, nil
+}
+
+// leader returns the holder written by the leader of the group, if it can be read.
+func (e *Elector) leader(name string) string {
+	content, err := os.ReadFile(e.path(name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+func (e *Elector) path(name string) string {
+	return filepath.Join(e.dir, helpers.SanitizeFileName(name)+".lock")
+}
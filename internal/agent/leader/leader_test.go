@@ -0,0 +1,63 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package leader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestElector(dir, holder string, now *time.Time) *Elector {
+	e := NewElector(dir, time.Minute, holder)
+	e.now = func() time.Time { return *now }
+	return e
+}
+
+func TestElector_IsLeader(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := newTestElector(dir, "first", &now)
+	second := newTestElector(dir, "second", &now)
+	defer second.Close()
+
+	assert.True(t, first.IsLeader("db"))
+	assert.True(t, first.IsLeader("db"))
+	assert.False(t, second.IsLeader("db"))
+
+	content, err := os.ReadFile(filepath.Join(dir, "db.lock"))
+	require.NoError(t, err)
+	assert.Equal(t, "first since 2020-01-01T00:00:00Z\n", string(content))
+
+	// groups are elected independently
+	assert.True(t, second.IsLeader("cache"))
+	assert.False(t, first.IsLeader("cache"))
+
+	first.Close()
+
+	// the follower campaigns again only once the interval passes
+	assert.False(t, second.IsLeader("db"))
+	now = now.Add(time.Minute)
+	assert.True(t, second.IsLeader("db"))
+	assert.False(t, first.IsLeader("db"))
+}
+
+func TestElector_SanitizesGroupName(t *testing.T) {
+	dir := t.TempDir()
+	e := NewElector(dir, time.Minute, "holder")
+	defer e.Close()
+
+	assert.True(t, e.IsLeader("shared/db:5432"))
+	assert.FileExists(t, filepath.Join(dir, "shareddb5432.lock"))
+}
+
+func TestElector_UnreachableDir(t *testing.T) {
+	e := NewElector(filepath.Join(t.TempDir(), "missing"), time.Minute, "holder")
+	defer e.Close()
+
+	assert.False(t, e.IsLeader("db"))
+}
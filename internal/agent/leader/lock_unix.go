@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package leader
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLock locks the file exclusively without waiting, returning errLocked when another process holds the lock. Linux
+// NFS clients emulate it with a lock handled by the NFS server, so it's held across hosts.
+func tryLock(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLocked
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package maintenance
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// taggedEvent is an event marshalled with the maintenance attribute.
+type taggedEvent struct {
+	sample.Event
+}
+
+// Tag returns the event reported with the maintenance=true attribute.
+func Tag(event sample.Event) sample.Event {
+	return taggedEvent{Event: event}
+}
+
+func (e taggedEvent) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(e.Event)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) < 2 || data[0] != '{' || data[len(data)-1] != '}' {
+		return data, nil
+	}
+
+	tagged := make([]byte, 0, len(data)+len(Attribute)+8)
+	tagged = append(tagged, data[:len(data)-1]...)
+	if len(bytes.TrimSpace(data[1:len(data)-1])) > 0 {
+		tagged = append(tagged, ',')
+	}
+	tagged = append(tagged, `"`+Attribute+`":true}`...)
+	return tagged, nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package maintenance
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEvent struct {
+	EventType string `json:"eventType,omitempty"`
+	EntityKey string `json:"entityKey,omitempty"`
+}
+
+func (e *testEvent) Type(eventType string) { e.EventType = eventType }
+func (e *testEvent) Entity(key entity.Key) { e.EntityKey = key.String() }
+func (e *testEvent) Timestamp(_ int64)     {}
+
+func TestTag(t *testing.T) {
+	event := &testEvent{EventType: "SystemSample"}
+	tagged := Tag(event)
+
+	// the tagged event keeps setting the fields of the original one
+	tagged.Entity("host")
+
+	data, err := json.Marshal(tagged)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"eventType":"SystemSample","entityKey":"host","maintenance":true}`, string(data))
+
+	data, err = json.Marshal(Tag(&testEvent{}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"maintenance":true}`, string(data))
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package maintenance handles the maintenance windows of the agent, during which its events are tagged or
+// suppressed, ie: host patching, so alert conditions don't have to be disabled.
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+// Attribute is the attribute added to the events sent during maintenance with the tag policy.
+const Attribute = "maintenance"
+
+// stateFile is the file, in the agent data directory, storing the window started from the control socket, so it
+// survives agent restarts, ie: when the host is patched.
+const stateFile = "maintenance.json"
+
+var mlog = log.WithComponent("Maintenance")
+
+// Status represents the current maintenance window.
+type Status struct {
+	Active bool       `json:"active"`
+	Until  *time.Time `json:"until,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+	Policy string     `json:"policy"`
+}
+
+type window struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Window tracks whether the agent is in maintenance, either from the maintenance_until option or a window
+// started on demand. A nil Window is never active.
+type Window struct {
+	cfg       *config.Config
+	stateFile string
+
+	lock   sync.Mutex
+	window window
+}
+
+// NewWindow creates the maintenance window of the agent, restoring the one stored in the data directory, unless
+// it's empty.
+func NewWindow(cfg *config.Config, dataDir string) *Window {
+	w := &Window{cfg: cfg}
+	if dataDir == "" {
+		return w
+	}
+	w.stateFile = filepath.Join(dataDir, stateFile)
+
+	content, err := os.ReadFile(w.stateFile)
+	if err != nil {
+		return w
+	}
+	if err = json.Unmarshal(content, &w.window); err != nil {
+		mlog.WithError(err).WithField("file", w.stateFile).Warn("Ignoring invalid maintenance window state.")
+		w.window = window{}
+		return w
+	}
+	if time.Now().Before(w.window.Until) {
+		mlog.WithField("until", w.window.Until).WithField("reason", w.window.Reason).Info("Maintenance window restored.")
+	}
+	return w
+}
+
+// Start starts a maintenance window for the duration, replacing the current one.
+func (w *Window) Start(duration time.Duration, reason string) error {
+	if duration <= 0 {
+		return fmt.Errorf("invalid maintenance duration: %s", duration)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.window = window{Until: time.Now().Add(duration), Reason: reason}
+	mlog.WithField("until", w.window.Until).WithField("reason", reason).
+		WithField("policy", w.policy()).Info("Maintenance window started.")
+	w.store()
+	return nil
+}
+
+// Stop ends the maintenance window started on demand. A window set by maintenance_until is not affected.
+func (w *Window) Stop() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if !w.window.Until.IsZero() {
+		mlog.Info("Maintenance window stopped.")
+	}
+	w.window = window{}
+	w.store()
+}
+
+// Policy returns the maintenance policy when the agent is in maintenance, empty otherwise. Once the window ends
+// the events are sent as usual.
+func (w *Window) Policy() string {
+	if w == nil {
+		return ""
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if _, active := w.until(); !active {
+		return ""
+	}
+	return w.policy()
+}
+
+// Status returns the current maintenance window.
+func (w *Window) Status() Status {
+	if w == nil {
+		return Status{Policy: config.MaintenancePolicyTag}
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	status := Status{Policy: w.policy()}
+	if until, active := w.until(); active {
+		status.Active, status.Until, status.Reason = true, &until, w.window.Reason
+	}
+	return status
+}
+
+// until returns the end of the latest active window, clearing the window started on demand once ended.
+func (w *Window) until() (until time.Time, active bool) {
+	now := time.Now()
+	if !w.window.Until.IsZero() {
+		if now.Before(w.window.Until) {
+			until, active = w.window.Until, true
+		} else {
+			mlog.WithField("reason", w.window.Reason).Info("Maintenance window ended.")
+			w.window = window{}
+			w.store()
+		}
+	}
+	if cfgUntilRaw, _ := w.cfg.GetMaintenance(); cfgUntilRaw != "" {
+		if cfgUntil, err := time.Parse(time.RFC3339, cfgUntilRaw); err == nil && now.Before(cfgUntil) && cfgUntil.After(until) {
+			until, active = cfgUntil, true
+		}
+	}
+	return until, active
+}
+
+func (w *Window) policy() string {
+	if _, policy := w.cfg.GetMaintenance(); policy == config.MaintenancePolicySuppress {
+		return config.MaintenancePolicySuppress
+	}
+	return config.MaintenancePolicyTag
+}
+
+// store persists the window started on demand, removing the state when there's none.
+func (w *Window) store() {
+	if w.stateFile == "" {
+		return
+	}
+	var err error
+	if w.window.Until.IsZero() {
+		if err = os.Remove(w.stateFile); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	} else {
+		var content []byte
+		content, err = json.Marshal(w.window)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(w.stateFile), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(w.stateFile, content, 0o644)
+		}
+	}
+	if err != nil {
+		mlog.WithError(err).WithField("file", w.stateFile).Warn("Cannot store the maintenance window, it won't survive restarts.")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindow_StartStop(t *testing.T) {
+	cfg := &config.Config{MaintenancePolicy: config.MaintenancePolicySuppress}
+	w := NewWindow(cfg, t.TempDir())
+	assert.Empty(t, w.Policy())
+
+	require.NoError(t, w.Start(time.Hour, "patching"))
+	assert.Equal(t, config.MaintenancePolicySuppress, w.Policy())
+	status := w.Status()
+	assert.True(t, status.Active)
+	assert.Equal(t, "patching", status.Reason)
+	require.NotNil(t, status.Until)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *status.Until, time.Minute)
+
+	w.Stop()
+	assert.Empty(t, w.Policy())
+	assert.False(t, w.Status().Active)
+
+	assert.Error(t, w.Start(0, ""))
+}
+
+func TestWindow_Expires(t *testing.T) {
+	w := NewWindow(&config.Config{}, "")
+
+	require.NoError(t, w.Start(50*time.Millisecond, ""))
+	assert.Equal(t, config.MaintenancePolicyTag, w.Policy())
+	assert.Eventually(t, func() bool {
+		return w.Policy() == ""
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWindow_MaintenanceUntil(t *testing.T) {
+	cfg := &config.Config{MaintenanceUntil: time.Now().Add(time.Hour).Format(time.RFC3339)}
+	w := NewWindow(cfg, "")
+	assert.Equal(t, config.MaintenancePolicyTag, w.Policy())
+
+	// stopping doesn't end the window of the configuration
+	w.Stop()
+	assert.True(t, w.Status().Active)
+
+	cfg.MaintenanceUntil = time.Now().Add(-time.Hour).Format(time.RFC3339)
+	assert.Empty(t, w.Policy())
+}
+
+func TestWindow_Restored(t *testing.T) {
+	dataDir := t.TempDir()
+	require.NoError(t, NewWindow(&config.Config{}, dataDir).Start(time.Hour, "patching"))
+
+	w := NewWindow(&config.Config{}, dataDir)
+	assert.True(t, w.Status().Active)
+	assert.Equal(t, "patching", w.Status().Reason)
+
+	w.Stop()
+	assert.False(t, NewWindow(&config.Config{}, dataDir).Status().Active)
+}
+
+func TestWindow_Nil(t *testing.T) {
+	var w *Window
+	assert.Empty(t, w.Policy())
+	assert.False(t, w.Status().Active)
+}
@@ -0,0 +1,135 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metadata
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+const (
+	// serviceAccountDir holds the credentials Kubernetes mounts into the pods to reach the API server.
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	k8sRequestTimeout = 5 * time.Second
+
+	// Attributes the samples are enriched with.
+	K8sClusterNameAttr     = "clusterName"
+	K8sNodeNameAttr        = "nodeName"
+	K8sNodeLabelAttrPrefix = "nodeLabel."
+
+	// Environment variables set in the pod spec of the agent DaemonSet.
+	K8sClusterNameEnv = "NEW_RELIC_METADATA_KUBERNETES_CLUSTER_NAME"
+	K8sNodeNameEnv    = "NEW_RELIC_METADATA_KUBERNETES_NODE_NAME"
+)
+
+var klog = log.WithComponent("K8sMetadata")
+
+// InKubernetes returns whether the agent runs in a Kubernetes pod.
+func InKubernetes() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
+// NodeLabelsFetcher returns the labels of a Kubernetes node.
+type NodeLabelsFetcher func(ctx context.Context, nodeName string) (map[string]string, error)
+
+// K8sNodeAttributes returns the attributes linking the host to its Kubernetes cluster and node: the cluster name,
+// the node name and the node labels in the allow-list. Empty values are skipped, and the labels are only fetched
+// when the node name is known. A failure fetching them is logged and the other attributes are still returned.
+func K8sNodeAttributes(ctx context.Context, clusterName, nodeName string, allowedLabels []string, fetchLabels NodeLabelsFetcher) map[string]string {
+	attributes := make(map[string]string)
+	if clusterName != "" {
+		attributes[K8sClusterNameAttr] = clusterName
+	}
+	if nodeName == "" {
+		return attributes
+	}
+	attributes[K8sNodeNameAttr] = nodeName
+
+	if len(allowedLabels) == 0 {
+		return attributes
+	}
+	labels, err := fetchLabels(ctx, nodeName)
+	if err != nil {
+		klog.WithError(err).WithField("node", nodeName).Warn("Cannot fetch the node labels.")
+		return attributes
+	}
+	for _, key := range allowedLabels {
+		if value, ok := labels[key]; ok {
+			attributes[K8sNodeLabelAttrPrefix+key] = value
+		}
+	}
+	return attributes
+}
+
+// APIServerNodeLabels fetches the node labels from the Kubernetes API server with the pod service account, which
+// must be allowed to get nodes.
+func APIServerNodeLabels(ctx context.Context, nodeName string) (map[string]string, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("kubernetes API server not defined")
+	}
+
+	token, err := os.ReadFile(filepath.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the service account token: %w", err)
+	}
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid service account CA")
+	}
+
+	client := &http.Client{
+		Timeout:   k8sRequestTimeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+	apiURL := "https://" + net.JoinHostPort(host, port)
+	return nodeLabels(ctx, client, apiURL, strings.TrimSpace(string(token)), nodeName)
+}
+
+func nodeLabels(ctx context.Context, client *http.Client, apiURL, token, nodeName string) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+"/api/v1/nodes/"+url.PathEscape(nodeName), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("kubernetes API server returned %s", resp.Status)
+	}
+
+	var node struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&node); err != nil {
+		return nil, fmt.Errorf("cannot decode the node: %w", err)
+	}
+	return node.Metadata.Labels, nil
+}
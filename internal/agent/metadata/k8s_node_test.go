@@ -0,0 +1,65 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestK8sNodeAttributes(t *testing.T) {
+	fetch := func(_ context.Context, nodeName string) (map[string]string, error) {
+		assert.Equal(t, "node-1", nodeName)
+		return map[string]string{
+			"topology.kubernetes.io/zone": "us-east-1a",
+			"kubernetes.io/os":            "linux",
+		}, nil
+	}
+
+	attributes := K8sNodeAttributes(context.Background(), "production", "node-1",
+		[]string{"topology.kubernetes.io/zone", "not-found"}, fetch)
+	assert.Equal(t, map[string]string{
+		"clusterName":                           "production",
+		"nodeName":                              "node-1",
+		"nodeLabel.topology.kubernetes.io/zone": "us-east-1a",
+	}, attributes)
+}
+
+func TestK8sNodeAttributes_LabelsNotFetched(t *testing.T) {
+	fetch := func(context.Context, string) (map[string]string, error) {
+		return nil, errors.New("forbidden")
+	}
+
+	// without node name, the labels are not fetched
+	assert.Equal(t, map[string]string{"clusterName": "production"},
+		K8sNodeAttributes(context.Background(), "production", "", []string{"kubernetes.io/os"}, fetch))
+
+	// failing to fetch the labels keeps the other attributes
+	assert.Equal(t, map[string]string{"nodeName": "node-1"},
+		K8sNodeAttributes(context.Background(), "", "node-1", []string{"kubernetes.io/os"}, fetch))
+}
+
+func TestNodeLabels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		assert.Equal(t, "/api/v1/nodes/node-1", r.URL.Path)
+		_, _ = w.Write([]byte(`{"kind":"Node","metadata":{"name":"node-1","labels":{"kubernetes.io/os":"linux"}}}`))
+	}))
+	defer srv.Close()
+
+	labels, err := nodeLabels(context.Background(), srv.Client(), srv.URL, "secret", "node-1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"kubernetes.io/os": "linux"}, labels)
+
+	_, err = nodeLabels(context.Background(), srv.Client(), srv.URL, "wrong", "node-1")
+	assert.Error(t, err)
+}
@@ -29,6 +29,8 @@ type patchSenderIngest struct {
 	postDeltas       postDeltas
 	lastSubmission   delta.LastSubmissionStore
 	lastEntityID     delta.EntityIDPersist
+	sentChunks       delta.SentChunksStore
+	chunkSize        int // deltas larger than chunkSize are submitted in chunks, if positive
 	userAgent        string
 	compactEnabled   bool
 	compactThreshold uint64
@@ -48,7 +50,7 @@ var pslog = log.WithComponent("PatchSender")
 // Reference to post delta function that can be stubbed for unit testing
 type postDeltas func(entityKeys []string, entityID entity.ID, isAgent bool, deltas ...*inventoryapi.RawDelta) (*inventoryapi.PostDeltaResponse, error)
 
-func newPatchSender(entityInfo entity.Entity, context AgentContext, store delta.Storage, lastSubmission delta.LastSubmissionStore, lastEntityID delta.EntityIDPersist, userAgent string, agentIDProvide id.Provide, httpClient http2.Client) (inventory.PatchSender, error) {
+func newPatchSender(entityInfo entity.Entity, context AgentContext, store delta.Storage, lastSubmission delta.LastSubmissionStore, lastEntityID delta.EntityIDPersist, sentChunks delta.SentChunksStore, userAgent string, agentIDProvide id.Provide, httpClient http2.Client) (inventory.PatchSender, error) {
 	if store == nil {
 		return nil, fmt.Errorf("creating patch sender: delta store can't be nil")
 	}
@@ -68,6 +70,7 @@ func newPatchSender(entityInfo entity.Entity, context AgentContext, store delta.
 		context.Config().License,
 		userAgent,
 		context.Config().PayloadCompressionLevel,
+		context.Config().PayloadCompression,
 		context.EntityKey(),
 		agentIDProvide,
 		context.Config().ConnectEnabled,
@@ -86,11 +89,18 @@ func newPatchSender(entityInfo entity.Entity, context AgentContext, store delta.
 		resetIfOffline, _ = time.ParseDuration("24h")
 	}
 
+	chunkSize := 0
+	if context.Config().InventoryChunkingEnabled && !context.Config().DisableInventorySplit {
+		chunkSize = context.Config().MaxInventorySize
+	}
+
 	return &patchSenderIngest{
 		entityInfo:       entityInfo,
 		store:            store,
 		lastSubmission:   lastSubmission,
 		lastEntityID:     lastEntityID,
+		sentChunks:       sentChunks,
+		chunkSize:        chunkSize,
 		postDeltas:       client.PostDeltas,
 		context:          context,
 		userAgent:        userAgent,
@@ -201,6 +211,16 @@ func (p *patchSenderIngest) sendAllDeltas(allDeltas []inventoryapi.RawDeltaBlock
 
 	llog.WithField("numberOfBlocks", len(allDeltas)).Debug("Sending deltas divided in blocks.")
 	for n, deltas := range allDeltas {
+		deltas, largeDeltas := p.largeDeltas(deltas)
+		for _, d := range largeDeltas {
+			if err := p.sendChunks(entityKey, areAgentDeltas, d, &reset); err != nil {
+				return err
+			}
+		}
+		if len(deltas) == 0 {
+			continue
+		}
+
 		llog.WithTraceFieldsF(func() logrus.Fields {
 			deltaJson, err := json.Marshal(deltas)
 			if err == nil {
@@ -277,3 +297,67 @@ func (p *patchSenderIngest) agentEntityIDChanged() bool {
 
 	return lastEntityID != p.agentIDProvide().ID
 }
+
+// largeDeltas separates the deltas to be submitted in chunks, when enabled.
+func (p *patchSenderIngest) largeDeltas(block inventoryapi.RawDeltaBlock) (deltas, large []*inventoryapi.RawDelta) {
+	if p.chunkSize <= 0 || p.sentChunks == nil {
+		return block, nil
+	}
+	for _, d := range block {
+		if size, err := d.Size(); err == nil && size > p.chunkSize {
+			large = append(large, d)
+		} else {
+			deltas = append(deltas, d)
+		}
+	}
+	return
+}
+
+// sendChunks submits a large delta in chunks, skipping the ones already accepted in a previous submission. The last
+// chunk is always sent, as its response holds the state of the complete delta.
+func (p *patchSenderIngest) sendChunks(entityKey string, areAgentDeltas bool, d *inventoryapi.RawDelta, reset *bool) error {
+	llog := pslog.WithField("entityKey", entityKey).WithField("source", d.Source)
+
+	chunks, err := inventoryapi.SplitDelta(d, p.chunkSize)
+	if err != nil {
+		llog.WithError(err).Error("couldn't split delta in chunks")
+		return err
+	}
+
+	llog.WithField("numberOfChunks", len(chunks)).Debug("Sending delta divided in chunks.")
+	for _, chunk := range chunks {
+		if !chunk.Chunk.IsLast() && p.sentChunks.IsSent(d.Source, chunk.Chunk.DeltaDigest, chunk.Chunk.Digest) {
+			continue
+		}
+
+		postDeltaResults, err := p.postDeltas([]string{entityKey}, p.entityInfo.ID, areAgentDeltas, chunk)
+		if err != nil {
+			llog.WithError(err).WithField("chunk", chunk.Chunk.Index).Error("couldn't post delta chunk")
+			return err
+		}
+
+		if !p.entityInfo.Key.IsEmpty() {
+			if err = p.lastSubmission.UpdateTime(timeNow()); err != nil {
+				llog.WithError(err).Error("can't save submission time")
+			}
+		}
+
+		if !chunk.Chunk.IsLast() {
+			if err = p.sentChunks.MarkSent(d.Source, chunk.Chunk.DeltaDigest, chunk.Chunk.Digest); err != nil {
+				llog.WithError(err).Warn("can't save sent delta chunk")
+			}
+			continue
+		}
+
+		if err = p.sentChunks.Done(d.Source); err != nil {
+			llog.WithError(err).Warn("can't clean sent delta chunks")
+		}
+		if postDeltaResults.Reset == inventoryapi.ResetAll {
+			*reset = true
+		} else {
+			deltaStateResults := postDeltaResults.StateMap
+			p.store.UpdateState(entityKey, []*inventoryapi.RawDelta{d}, &deltaStateResults)
+		}
+	}
+	return nil
+}
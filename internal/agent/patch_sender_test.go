@@ -359,6 +359,59 @@ func TestPatchSender_Process_DisabledDeltaSplit(t *testing.T) {
 	assert.Contains(t, pdt.Sources[0], "plugin3/plugin3")
 }
 
+func TestPatchSender_Process_ChunkedDeltas(t *testing.T) {
+	// Given a patch sender submitting the large deltas in chunks
+	dataDir, err := TempDeltaStoreDir()
+	assert.NoError(t, err)
+	store := delta.NewStore(dataDir, "localhost", 10000, true)
+	ps := newTestPatchSender(t, dataDir, store, delta.NewLastSubmissionInMemory(), getLastEntityIDMock())
+	ps.chunkSize = 10000
+	ps.compactEnabled = false
+
+	// Whose backend fails on the third request
+	var chunks []*inventoryapi.DeltaChunk
+	var sources []string
+	fail := true
+	ps.postDeltas = func(_ []string, _ entity.ID, _ bool, deltas ...*inventoryapi.RawDelta) (*inventoryapi.PostDeltaResponse, error) {
+		if fail && len(chunks) == 2 {
+			fail = false
+			return nil, fmt.Errorf("service unavailable")
+		}
+		for _, d := range deltas {
+			sources = append(sources, d.Source)
+			if d.Chunk != nil {
+				chunks = append(chunks, d.Chunk)
+			}
+		}
+		return &inventoryapi.PostDeltaResponse{}, nil
+	}
+
+	// And a delta larger than the maximum inventory data size, along with a normal one
+	testhelpers.PopulateDeltas(dataDir, entityKey, []testhelpers.FakeDeltaEntry{
+		{Source: "packages/dpkg", DeltasSize: 1, BodySize: 50000},
+		{Source: "plugin1/plugin1", DeltasSize: 1, BodySize: 1000},
+	})
+
+	// When the patch sender processes them
+	assert.Error(t, ps.Process())
+	require.Len(t, chunks, 2)
+
+	// And resumes after the failure
+	assert.NoError(t, ps.Process())
+
+	// The large delta is sent in chunks, each of them once
+	count := chunks[0].Count
+	assert.True(t, count > 2)
+	require.Len(t, chunks, count)
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.Index)
+		assert.Equal(t, chunks[0].DeltaDigest, chunk.DeltaDigest)
+	}
+
+	// And the normal delta as usual
+	assert.Contains(t, sources, "plugin1/plugin1")
+}
+
 func TestPatchSender_Process_SingleRequestDeltas(t *testing.T) {
 	// Given a patch sender
 	dataDir, err := TempDeltaStoreDir()
@@ -586,6 +639,7 @@ func newTestPatchSender(t *testing.T, dataDir string, store delta.Storage, ls de
 		store,
 		ls,
 		lastEntityID,
+		delta.NewSentChunksStore(dataDir, entityKey),
 		"user agent",
 		idCtx.AgentIdnOrEmpty,
 		http.NullHttpClient,
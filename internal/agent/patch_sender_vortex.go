@@ -65,6 +65,7 @@ func newPatchSenderVortex(entityKey, agentKey string, context AgentContext, stor
 		context.Config().License,
 		userAgent,
 		context.Config().PayloadCompressionLevel,
+		context.Config().PayloadCompression,
 		context.EntityKey(),
 		agentIDProvide,
 		context.Config().ConnectEnabled,
@@ -0,0 +1,155 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+)
+
+const (
+	// postEnvelopeBytes is the maximum size of an entity post without its events and key, ie:
+	// {"ExternalKeys":[""],"EntityID":-9223372036854775808,"IsAgent":false,"Events":[],"ReportingAgentID":-9223372036854775808}
+	postEnvelopeBytes = 128
+	// batchEnvelopeBytes is the size of the brackets enclosing the entity posts.
+	batchEnvelopeBytes = 2
+)
+
+var errPayloadTooLarge = errors.New("payload is larger than the maximum post size")
+
+// payloadSize estimates the size of the post marshalled from the accumulated events, which are grouped by entity
+// in posts with their own envelope, so batches are queued before exceeding the maximum post size.
+type payloadSize struct {
+	bytes    int
+	entities map[entity.Key]struct{}
+}
+
+// with returns the size of the post if the event was added to it.
+func (p payloadSize) with(key entity.Key, eventBytes int) int {
+	size := p.bytes + eventBytes + 1 // events separator
+	if p.bytes == 0 {
+		size += batchEnvelopeBytes
+	}
+	if _, ok := p.entities[key]; !ok {
+		size += postEnvelopeBytes + len(key) + 1 // posts separator
+	}
+	return size
+}
+
+// add accounts the event in the post size.
+func (p *payloadSize) add(key entity.Key, eventBytes int) {
+	p.bytes = p.with(key, eventBytes)
+	if p.entities == nil {
+		p.entities = make(map[entity.Key]struct{})
+	}
+	p.entities[key] = struct{}{}
+}
+
+// payloadStats counts the posts split for being too large and the events dropped for not fitting in any post.
+// Fields are accessed atomically.
+type payloadStats struct {
+	splits  uint64
+	dropped uint64
+}
+
+func (s *payloadStats) split() {
+	atomic.AddUint64(&s.splits, 1)
+}
+
+// drop accounts the dropped events, returning the error explaining why.
+func (s *payloadStats) drop(events int) error {
+	atomic.AddUint64(&s.dropped, uint64(events))
+	return fmt.Errorf("dropping %d events: %w", events, errPayloadTooLarge)
+}
+
+// fill sets the counters in the sender stats.
+func (s *payloadStats) fill(stats *SenderStats) {
+	stats.SplitPayloads = atomic.LoadUint64(&s.splits)
+	stats.DroppedEvents = atomic.LoadUint64(&s.dropped)
+}
+
+// isPayloadTooLarge returns true when the post has to be split to be sent, either because it's larger than the
+// maximum post size or because the backend rejected it for its size.
+func isPayloadTooLarge(err error) bool {
+	if errors.Is(err, errPayloadTooLarge) {
+		return true
+	}
+	var retry *errRetry
+	return errors.As(err, &retry) && retry.StatusCode == http.StatusRequestEntityTooLarge
+}
+
+// eventCount returns the number of events in the post.
+func (b MetricPostBatch) eventCount() int {
+	count := 0
+	for _, mp := range b {
+		count += len(mp.Events)
+	}
+	return count
+}
+
+// split halves the post by events, splitting the entity post in the middle if needed. It returns false when the
+// post cannot be split as it has a single event.
+func (b MetricPostBatch) split() (MetricPostBatch, MetricPostBatch, bool) {
+	half := b.eventCount() / 2
+	if half == 0 {
+		return nil, nil, false
+	}
+
+	var first, second MetricPostBatch
+	for _, mp := range b {
+		switch {
+		case half == 0:
+			second = append(second, mp)
+		case len(mp.Events) <= half:
+			first = append(first, mp)
+			half -= len(mp.Events)
+		default:
+			head, tail := *mp, *mp
+			head.Events, tail.Events = mp.Events[:half], mp.Events[half:]
+			first = append(first, &head)
+			second = append(second, &tail)
+			half = 0
+		}
+	}
+	return first, second, true
+}
+
+// eventCount returns the number of events in the post.
+func (b MetricVortexPostBatch) eventCount() int {
+	count := 0
+	for _, mp := range b {
+		count += len(mp.Events)
+	}
+	return count
+}
+
+// split halves the post by events, splitting the entity post in the middle if needed. It returns false when the
+// post cannot be split as it has a single event.
+func (b MetricVortexPostBatch) split() (MetricVortexPostBatch, MetricVortexPostBatch, bool) {
+	half := b.eventCount() / 2
+	if half == 0 {
+		return nil, nil, false
+	}
+
+	var first, second MetricVortexPostBatch
+	for _, mp := range b {
+		switch {
+		case half == 0:
+			second = append(second, mp)
+		case len(mp.Events) <= half:
+			first = append(first, mp)
+			half -= len(mp.Events)
+		default:
+			head, tail := *mp, *mp
+			head.Events, tail.Events = mp.Events[:half], mp.Events[half:]
+			first = append(first, &head)
+			second = append(second, &tail)
+			half = 0
+		}
+	}
+	return first, second, true
+}
@@ -0,0 +1,150 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"compress/gzip"
+	goContext "context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvents(n int, size int) []json.RawMessage {
+	events := make([]json.RawMessage, n)
+	for i := range events {
+		events[i] = json.RawMessage(fmt.Sprintf(`{"n":%d,"s":"%s"}`, i, strings.Repeat("a", size)))
+	}
+	return events
+}
+
+func TestPayloadSize_NotLowerThanMarshalled(t *testing.T) {
+	var size payloadSize
+	post := map[entity.Key]*MetricPost{}
+	var batch MetricPostBatch
+	for i, event := range testEvents(30, 10) {
+		key := entity.Key(fmt.Sprintf("entity-%d", i%4))
+		size.add(key, len(event))
+		if post[key] == nil {
+			post[key] = &MetricPost{
+				ExternalKeys:     []string{string(key)},
+				EntityID:         entity.ID(math.MinInt64),
+				ReportingAgentID: entity.ID(math.MinInt64),
+			}
+			batch = append(batch, post[key])
+		}
+		post[key].Events = append(post[key].Events, event)
+
+		postBytes, err := json.Marshal(batch)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, size.bytes, len(postBytes))
+		// the estimation is close enough not to waste the post size
+		assert.Less(t, size.bytes, len(postBytes)+100)
+	}
+}
+
+func TestMetricPostBatch_Split(t *testing.T) {
+	events := testEvents(5, 0)
+	post := MetricPostBatch{
+		{ExternalKeys: []string{"a"}, Events: events[:2]},
+		{ExternalKeys: []string{"b"}, Events: events[2:]},
+	}
+
+	first, second, ok := post.split()
+	require.True(t, ok)
+	assert.Equal(t, MetricPostBatch{{ExternalKeys: []string{"a"}, Events: events[:2]}}, first)
+	assert.Equal(t, MetricPostBatch{{ExternalKeys: []string{"b"}, Events: events[2:]}}, second)
+
+	first, second, ok = second.split()
+	require.True(t, ok)
+	assert.Equal(t, MetricPostBatch{{ExternalKeys: []string{"b"}, Events: events[2:3]}}, first)
+	assert.Equal(t, MetricPostBatch{{ExternalKeys: []string{"b"}, Events: events[3:]}}, second)
+
+	_, _, ok = first.split()
+	assert.False(t, ok)
+}
+
+func TestMetricVortexPostBatch_Split(t *testing.T) {
+	events := testEvents(3, 0)
+	post := MetricVortexPostBatch{{EntityKey: "a", EntityID: 1, Events: events}}
+
+	first, second, ok := post.split()
+	require.True(t, ok)
+	assert.Equal(t, MetricVortexPostBatch{{EntityKey: "a", EntityID: 1, Events: events[:1]}}, first)
+	assert.Equal(t, MetricVortexPostBatch{{EntityKey: "a", EntityID: 1, Events: events[1:]}}, second)
+	assert.Equal(t, 3, post.eventCount())
+}
+
+func TestEventSender_SplitsLargePayloads(t *testing.T) {
+	var received []int
+	client := func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		var post MetricPostBatch
+		require.NoError(t, json.Unmarshal(body, &post))
+		received = append(received, post.eventCount())
+		return &http.Response{StatusCode: http.StatusAccepted, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	sender := newMetricsIngestSender(newTestContext("testAgent", &config.Config{
+		PayloadCompressionLevel:  gzip.NoCompression,
+		MaxMetricsBatchSizeBytes: 1000,
+	}), "license", "userAgent", client, false)
+
+	post := MetricPostBatch{{ExternalKeys: []string{"testAgent"}, Events: testEvents(8, 200)}}
+	require.NoError(t, sender.send(goContext.Background(), post, "testAgent"))
+
+	assert.Equal(t, []int{4, 4}, received)
+	stats := sender.Stats()
+	assert.Equal(t, uint64(1), stats.SplitPayloads)
+	assert.Zero(t, stats.DroppedEvents)
+}
+
+func TestEventSender_SplitsPayloadsRejectedForSize(t *testing.T) {
+	var received []int
+	client := func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		var post MetricPostBatch
+		require.NoError(t, json.Unmarshal(body, &post))
+		if post.eventCount() > 1 {
+			return &http.Response{StatusCode: http.StatusRequestEntityTooLarge, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+		received = append(received, post.eventCount())
+		return &http.Response{StatusCode: http.StatusAccepted, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	sender := newMetricsIngestSender(newTestContext("testAgent", &config.Config{
+		PayloadCompressionLevel: gzip.NoCompression,
+	}), "license", "userAgent", client, false)
+
+	post := MetricPostBatch{{ExternalKeys: []string{"testAgent"}, Events: testEvents(2, 0)}}
+	require.NoError(t, sender.send(goContext.Background(), post, "testAgent"))
+
+	assert.Equal(t, []int{1, 1}, received)
+	assert.Equal(t, uint64(1), sender.Stats().SplitPayloads)
+}
+
+func TestEventSender_DropsEventsLargerThanPost(t *testing.T) {
+	sender := newMetricsIngestSender(newTestContext("testAgent", &config.Config{
+		PayloadCompressionLevel:  gzip.NoCompression,
+		MaxMetricsBatchSizeBytes: 1000,
+	}), "license", "userAgent", nil, false)
+
+	assert.Error(t, sender.QueueEvent(mapEvent{"eventType": "TestEvent", "value": strings.Repeat("a", 1000)}, ""))
+
+	post := MetricPostBatch{{ExternalKeys: []string{"testAgent"}, Events: testEvents(1, 1000)}}
+	err := sender.send(goContext.Background(), post, "testAgent")
+	assert.True(t, isPayloadTooLarge(err))
+
+	stats := sender.Stats()
+	assert.Zero(t, stats.SplitPayloads)
+	assert.Equal(t, uint64(2), stats.DroppedEvents)
+}
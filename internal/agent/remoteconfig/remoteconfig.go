@@ -0,0 +1,382 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package remoteconfig fetches a signed configuration document periodically, from the command API or a customer
+// provided URL, applying the options it allows to the running agent.
+package remoteconfig
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/id"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/backend/offline"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	config_loader "github.com/newrelic/infrastructure-agent/pkg/config/loader"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+// Endpoint is the path of the remote configuration document in the command API.
+const Endpoint = "/agent_commands/v1/remote_config"
+
+// maxDocumentSize limits the size of the fetched documents.
+const maxDocumentSize = 1024 * 1024
+
+var rclog = log.WithComponent("RemoteConfig")
+
+// ErrInvalidSignature is returned for documents not signed by the configured key.
+var ErrInvalidSignature = errors.New("invalid remote configuration signature")
+
+// validator checks the value of an option set remotely.
+type validator func(value interface{}) error
+
+// allowedOptions are the options the remote configuration can set, all of them applied without restarting.
+var allowedOptions = map[string]validator{
+	"metrics_system_sample_rate":  sampleRate(config.FREQ_INTERVAL_FLOOR_SYSTEM_METRICS),
+	"metrics_storage_sample_rate": sampleRate(config.FREQ_INTERVAL_FLOOR_STORAGE_METRICS),
+	"metrics_network_sample_rate": sampleRate(config.FREQ_INTERVAL_FLOOR_NETWORK_METRICS),
+	"metrics_process_sample_rate": sampleRate(config.FREQ_INTERVAL_FLOOR_PROCESS_METRICS),
+	"metrics_nfs_sample_rate":     sampleRate(config.FREQ_INTERVAL_FLOOR_STORAGE_METRICS),
+	"custom_attributes":           customAttributes,
+}
+
+// localOptions are other local options taking precedence over the remote ones, ie: the process samples disabled
+// locally are not enabled remotely.
+var localOptions = map[string][]string{
+	"metrics_process_sample_rate": {"enable_process_metrics"},
+}
+
+// Document is the signed remote configuration. Its payload is the JSON of a Payload, signed with ed25519 over its
+// SHA-256 checksum. Both fields are base64 encoded.
+type Document struct {
+	Payload   []byte `json:"payload"`
+	Signature []byte `json:"signature"`
+}
+
+// Payload sets the options of the remote configuration, indexed by their key in the configuration file. Documents
+// whose version is not greater than the applied one are ignored, so older documents can't be replayed.
+type Payload struct {
+	Version int64                      `json:"version"`
+	Options map[string]json.RawMessage `json:"options"`
+}
+
+// Channel fetches the remote configuration and applies it to the running configuration. The options defined in the
+// configuration file or the environment take precedence, and the running configuration is kept when a document is
+// not valid.
+type Channel struct {
+	cfg        *config.Config
+	registry   *config.Registry
+	url        string
+	license    string // only sent to the command API
+	userAgent  string
+	publicKey  ed25519.PublicKey
+	interval   time.Duration
+	httpClient backendhttp.Client
+
+	lock     sync.Mutex
+	handlers []config.ReloadHandler
+	version  int64
+	etag     string
+	local    map[string]interface{} // values replaced by the remote ones, restored when no longer set remotely
+}
+
+// NewChannel creates the remote configuration channel for the agent configuration.
+func NewChannel(cfg *config.Config, userAgent string, httpClient backendhttp.Client) (*Channel, error) {
+	if cfg.RemoteConfigPublicKeyFile == "" {
+		return nil, fmt.Errorf("remote_config_public_key_file is required to verify the remote configuration")
+	}
+	publicKey, err := offline.LoadPublicKey(cfg.RemoteConfigPublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	registry, err := config.NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Channel{
+		cfg:        cfg,
+		registry:   registry,
+		userAgent:  userAgent,
+		publicKey:  publicKey,
+		interval:   time.Duration(cfg.RemoteConfigIntervalSec) * time.Second,
+		httpClient: httpClient,
+		local:      map[string]interface{}{},
+	}
+	if cfg.RemoteConfigURL == "" {
+		c.url = strings.TrimSuffix(cfg.CommandChannelURL, "/") + Endpoint
+		c.license = cfg.License
+	} else if c.url, err = documentURL(cfg.RemoteConfigURL); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// documentURL validates the customer provided URL, converting the S3 ones to their HTTPS endpoint.
+func documentURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid remote_config_url: %w", err)
+	}
+	switch u.Scheme {
+	case "https":
+		return u.String(), nil
+	case "s3":
+		if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+			return "", fmt.Errorf("invalid remote_config_url %s: expected s3://bucket/key", rawURL)
+		}
+		return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", u.Host, strings.TrimPrefix(u.Path, "/")), nil
+	default:
+		return "", fmt.Errorf("invalid remote_config_url %s: only https and s3 URLs are supported", rawURL)
+	}
+}
+
+// OnApply registers a handler invoked with the keys of the options modified by a document.
+func (c *Channel) OnApply(handler config.ReloadHandler) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.handlers = append(c.handlers, handler)
+}
+
+// Run fetches the remote configuration at startup and then periodically, until the context is done.
+func (c *Channel) Run(ctx context.Context, agentIDProvide id.Provide) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Fetch(ctx, agentIDProvide().ID); err != nil {
+			rclog.WithError(err).Warn("Cannot apply remote configuration, keeping the running one.")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Fetch retrieves the remote configuration document and applies it when it was modified.
+func (c *Channel) Fetch(ctx context.Context, agentID entity.ID) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.license != "" {
+		req.Header.Set(backendhttp.LicenseHeader, c.license)
+		req.Header.Set(backendhttp.AgentEntityIdHeader, agentID.String())
+	}
+	c.lock.Lock()
+	if c.etag != "" {
+		req.Header.Set("If-None-Match", c.etag)
+	}
+	c.lock.Unlock()
+
+	resp, err := c.httpClient(req)
+	if err != nil {
+		return fmt.Errorf("remote configuration request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil
+	case http.StatusNotFound:
+		rclog.Debug("No remote configuration available.")
+		return nil
+	}
+	if backendhttp.IsResponseError(resp) {
+		return fmt.Errorf("unsuccessful remote configuration response, status: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return fmt.Errorf("cannot read remote configuration: %w", err)
+	}
+	if len(body) > maxDocumentSize {
+		return fmt.Errorf("remote configuration larger than %d bytes", maxDocumentSize)
+	}
+
+	if _, err = c.Apply(body); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	c.etag = resp.Header.Get("ETag")
+	c.lock.Unlock()
+	return nil
+}
+
+// Apply verifies the signed document and applies its options to the running configuration, returning the keys of
+// the modified ones. The options no longer set by the document get back their local values. No option is applied
+// when the document or any of its values is not valid.
+func (c *Channel) Apply(document []byte) ([]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	payload, err := c.verify(document)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Version <= c.version {
+		rclog.WithField("version", payload.Version).Debug("Remote configuration already applied.")
+		return nil, nil
+	}
+
+	remote, err := c.parseOptions(payload.Options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote configuration version %d: %w", payload.Version, err)
+	}
+
+	running := c.registry.Values(c.cfg)
+	values := map[string]interface{}{}
+	var ignored []string
+	for key, value := range remote {
+		if c.definedLocally(key) {
+			ignored = append(ignored, key)
+			continue
+		}
+		if _, ok := c.local[key]; !ok {
+			c.local[key] = running[key]
+		}
+		values[key] = value
+	}
+	for key, value := range c.local {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		// the local value is restored, unless it's defined locally now, as reloading the file applied it
+		if !c.definedLocally(key) {
+			values[key] = value
+		}
+		delete(c.local, key)
+	}
+
+	if err = c.registry.SetValues(c.cfg, values); err != nil {
+		return nil, fmt.Errorf("cannot apply remote configuration version %d: %w", payload.Version, err)
+	}
+	c.version = payload.Version
+
+	var applied []string
+	for key, value := range values {
+		if !reflect.DeepEqual(value, running[key]) {
+			applied = append(applied, key)
+		}
+	}
+	sort.Strings(applied)
+	sort.Strings(ignored)
+
+	if len(ignored) > 0 {
+		rclog.WithField("options", strings.Join(ignored, ", ")).
+			Info("Remote configuration options defined locally, they are not applied.")
+	}
+	rclog.WithField("version", payload.Version).WithField("applied", strings.Join(applied, ", ")).
+		Info("Remote configuration applied.")
+
+	for _, handler := range c.handlers {
+		handler(applied)
+	}
+	return applied, nil
+}
+
+// verify checks the signature of the document, returning its payload.
+func (c *Channel) verify(document []byte) (Payload, error) {
+	var doc Document
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return Payload{}, fmt.Errorf("invalid remote configuration document: %w", err)
+	}
+	checksum := sha256.Sum256(doc.Payload)
+	if !ed25519.Verify(c.publicKey, checksum[:], doc.Signature) {
+		return Payload{}, ErrInvalidSignature
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(doc.Payload, &payload); err != nil {
+		return Payload{}, fmt.Errorf("invalid remote configuration payload: %w", err)
+	}
+	return payload, nil
+}
+
+// definedLocally returns whether the option, or any other option taking precedence over it, is defined in the
+// configuration file or the environment.
+func (c *Channel) definedLocally(key string) bool {
+	for _, k := range append([]string{key}, localOptions[key]...) {
+		if c.cfg.IsDefined(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOptions decodes the remote options as the configuration file ones, validating their values.
+func (c *Channel) parseOptions(options map[string]json.RawMessage) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		if _, ok := allowedOptions[key]; !ok {
+			return nil, fmt.Errorf("option %s can't be set remotely", key)
+		}
+		keys = append(keys, key)
+	}
+
+	// JSON is valid YAML, so the options are decoded into their configuration fields
+	raw, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	parsed := &config.Config{}
+	if _, err = config_loader.ParseConfig(raw, parsed); err != nil {
+		return nil, err
+	}
+
+	parsedValues := c.registry.Values(parsed)
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if err = allowedOptions[key](parsedValues[key]); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		values[key] = parsedValues[key]
+	}
+	return values, nil
+}
+
+// sampleRate validates the sample rates, which can disable their sampler.
+func sampleRate(floor int) validator {
+	return func(value interface{}) error {
+		rate, _ := value.(int)
+		if rate != config.FREQ_DISABLE_SAMPLING && rate < floor {
+			return fmt.Errorf("%d seconds is below the minimum of %d, or %d to disable it", rate, floor, config.FREQ_DISABLE_SAMPLING)
+		}
+		return nil
+	}
+}
+
+// customAttributes validates the custom attributes have scalar values.
+func customAttributes(value interface{}) error {
+	attributes, _ := value.(config.CustomAttributeMap)
+	for name, attr := range attributes {
+		if name == "" {
+			return fmt.Errorf("empty attribute name")
+		}
+		switch attr.(type) {
+		case string, int, int64, float64, bool:
+		default:
+			return fmt.Errorf("attribute %s is not a string, number or boolean", name)
+		}
+	}
+	return nil
+}
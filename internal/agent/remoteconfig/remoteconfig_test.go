@@ -0,0 +1,163 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package remoteconfig
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestChannel(t *testing.T, cfg *config.Config, httpClient backendhttp.Client) (*Channel, ed25519.PrivateKey) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	cfg.RemoteConfigPublicKeyFile = filepath.Join(t.TempDir(), "remote-config.pem")
+	require.NoError(t, os.WriteFile(cfg.RemoteConfigPublicKeyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600))
+
+	c, err := NewChannel(cfg, "user-agent", httpClient)
+	require.NoError(t, err)
+	return c, private
+}
+
+func signedDocument(t *testing.T, key ed25519.PrivateKey, version int64, options string) []byte {
+	t.Helper()
+
+	payload, err := json.Marshal(map[string]interface{}{"version": version, "options": json.RawMessage(options)})
+	require.NoError(t, err)
+	checksum := sha256.Sum256(payload)
+	doc, err := json.Marshal(Document{Payload: payload, Signature: ed25519.Sign(key, checksum[:])})
+	require.NoError(t, err)
+	return doc
+}
+
+func TestChannel_Apply(t *testing.T) {
+	// options defined locally take precedence
+	t.Setenv("NRIA_METRICS_NETWORK_SAMPLE_RATE", "30")
+
+	cfg := config.NewConfig()
+	cfg.MetricsNetworkSampleRate = 30
+	processRate := cfg.MetricsProcessSampleRate
+	c, key := newTestChannel(t, cfg, nil)
+	var handled []string
+	c.OnApply(func(applied []string) { handled = applied })
+
+	applied, err := c.Apply(signedDocument(t, key, 1, `{
+		"metrics_process_sample_rate": -1,
+		"metrics_network_sample_rate": 60,
+		"custom_attributes": {"team": "ops"}
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"custom_attributes", "metrics_process_sample_rate"}, applied)
+	assert.Equal(t, applied, handled)
+	assert.Equal(t, config.FREQ_DISABLE_SAMPLING, cfg.MetricsProcessSampleRate)
+	assert.Equal(t, config.CustomAttributeMap{"team": "ops"}, cfg.CustomAttributes)
+	assert.Equal(t, 30, cfg.MetricsNetworkSampleRate)
+
+	// the options no longer set remotely get back their local values
+	applied, err = c.Apply(signedDocument(t, key, 2, `{"custom_attributes": {"team": "ops"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"metrics_process_sample_rate"}, applied)
+	assert.Equal(t, processRate, cfg.MetricsProcessSampleRate)
+	assert.Equal(t, config.CustomAttributeMap{"team": "ops"}, cfg.CustomAttributes)
+}
+
+func TestChannel_Apply_Invalid(t *testing.T) {
+	cfg := config.NewConfig()
+	processRate := cfg.MetricsProcessSampleRate
+	c, key := newTestChannel(t, cfg, nil)
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, err = c.Apply(signedDocument(t, key, 1, `{"metrics_system_sample_rate": 60}`))
+	require.NoError(t, err)
+
+	tests := map[string][]byte{
+		"not signed by the key":  signedDocument(t, other, 2, `{"metrics_system_sample_rate": 30}`),
+		"not allowed option":     signedDocument(t, key, 2, `{"metrics_system_sample_rate": 30, "license_key": "x"}`),
+		"below the minimum rate": signedDocument(t, key, 2, `{"metrics_system_sample_rate": 30, "metrics_process_sample_rate": 1}`),
+		"invalid type":           signedDocument(t, key, 2, `{"metrics_system_sample_rate": "30"}`),
+		"non scalar attribute":   signedDocument(t, key, 2, `{"custom_attributes": {"team": {"name": "ops"}}}`),
+		"not a document":         []byte("metrics_system_sample_rate: 30"),
+	}
+	for name, doc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.Apply(doc)
+			assert.Error(t, err)
+			// the running configuration is kept
+			assert.Equal(t, 60, cfg.MetricsSystemSampleRate)
+			assert.Equal(t, processRate, cfg.MetricsProcessSampleRate)
+		})
+	}
+
+	// older documents are not applied again
+	applied, err := c.Apply(signedDocument(t, key, 1, `{"metrics_system_sample_rate": 30}`))
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.Equal(t, 60, cfg.MetricsSystemSampleRate)
+}
+
+func TestChannel_Fetch(t *testing.T) {
+	var doc []byte
+	var requests int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, Endpoint, r.URL.Path)
+		assert.Equal(t, "license", r.Header.Get(backendhttp.LicenseHeader))
+		assert.Equal(t, "123", r.Header.Get(backendhttp.AgentEntityIdHeader))
+		if r.Header.Get("If-None-Match") == `"1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"1"`)
+		_, _ = w.Write(doc)
+	}))
+	defer srv.Close()
+
+	cfg := config.NewConfig()
+	cfg.License = "license"
+	cfg.CommandChannelURL = srv.URL + "/"
+	c, key := newTestChannel(t, cfg, srv.Client().Do)
+	doc = signedDocument(t, key, 1, `{"metrics_storage_sample_rate": 60}`)
+
+	require.NoError(t, c.Fetch(context.Background(), entity.ID(123)))
+	assert.Equal(t, 60, cfg.MetricsStorageSampleRate)
+
+	require.NoError(t, c.Fetch(context.Background(), entity.ID(123)))
+	assert.Equal(t, 2, requests)
+}
+
+func TestDocumentURL(t *testing.T) {
+	u, err := documentURL("https://config.example.com/agents/infra.json")
+	require.NoError(t, err)
+	assert.Equal(t, "https://config.example.com/agents/infra.json", u)
+
+	u, err = documentURL("s3://config-bucket/agents/infra.json")
+	require.NoError(t, err)
+	assert.Equal(t, "https://config-bucket.s3.amazonaws.com/agents/infra.json", u)
+
+	for _, rawURL := range []string{"http://config.example.com/infra.json", "s3://config-bucket", "file:///etc/infra.json"} {
+		_, err = documentURL(rawURL)
+		assert.Error(t, err, rawURL)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package agent
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/backoff"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+// metricsEndpoint identifies the metrics ingest endpoint in the retry self-telemetry.
+const metricsEndpoint = "metrics"
+
+// retryPolicy returns the retry policy for a failed post, false when it's not worth retrying it.
+func retryPolicy(err error) (backendhttp.RetryPolicy, bool) {
+	var retry *errRetry
+	if errors.As(err, &retry) {
+		return retry.retryPolicy, true
+	}
+	var unreachable *errUnreachable
+	if errors.As(err, &unreachable) {
+		return backendhttp.RetryPolicy{MaxBackOff: backoff.GetMaxBackoffByCause(backendhttp.ServiceError)}, true
+	}
+	return backendhttp.RetryPolicy{}, false
+}
+
+// newRetryPolicy returns the retry policy for a failed backend response.
+func newRetryPolicy(resp *http.Response, cause backendhttp.ErrorCause, logger log.Entry) backendhttp.RetryPolicy {
+	after, err := backendhttp.RetryAfter(resp)
+	if err != nil {
+		logger.WithError(err).Debug("error parsing Retry-After header, continuing with exponential backoff")
+	}
+	return backendhttp.RetryPolicy{
+		After:      after,
+		MaxBackOff: backoff.GetMaxBackoffByCause(cause),
+	}
+}
@@ -8,6 +8,7 @@ package service
 import (
 	"os"
 	"os/exec"
+	"os/signal"
 
 	"github.com/kardianos/service"
 
@@ -19,6 +20,7 @@ import (
 // Start is called when the service manager tells us to start
 func (svc *Service) Start(s service.Service) (err error) {
 	go svc.daemon.run(s)
+	go svc.daemon.forwardReload()
 
 	return
 }
@@ -72,3 +74,19 @@ func (d *daemon) run(s service.Service) {
 		}
 	}
 }
+
+// forwardReload forwards the reload signal, ie: sent by "systemctl reload", to the agent process so it reloads its
+// configuration.
+func (d *daemon) forwardReload() {
+	s := make(chan os.Signal, 1)
+	signal.Notify(s, signals.Reload)
+	for range s {
+		d.Lock()
+		if d.cmd != nil && d.cmd.Process != nil {
+			if err := d.cmd.Process.Signal(signals.Reload); err != nil {
+				log.WithError(err).Warn("Failed to forward reload signal to agent process.")
+			}
+		}
+		d.Unlock()
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package spool provides a bounded on-disk FIFO queue, keeping the payloads that couldn't be sent to the backend
+// across agent restarts and collector outages.
+package spool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/disk"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+const (
+	dirMode  = 0o755
+	fileMode = 0o600
+
+	payloadExt = ".payload"
+	tmpExt     = ".tmp"
+)
+
+// ErrEmpty is returned when there are no payloads in the spool.
+var ErrEmpty = errors.New("spool is empty")
+
+var splog = log.WithComponent("PayloadSpool")
+
+// Entry is a spooled payload.
+type Entry struct {
+	Name    string
+	Created time.Time
+	Payload []byte
+}
+
+type entry struct {
+	name    string
+	created time.Time
+	size    int64
+}
+
+// Spool stores payloads as files in a directory, returning them in the same order they were pushed. The oldest
+// payloads are evicted when the spool exceeds its maximum size or they are older than the maximum age.
+type Spool struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+
+	lock    sync.Mutex
+	entries []entry // oldest first
+	size    int64
+	seq     uint64
+	now     func() time.Time
+}
+
+// New returns a spool storing payloads in dir, loading the ones left by previous agent executions. Zero maxAge
+// disables the age based eviction.
+func New(dir string, maxSize int64, maxAge time.Duration) (*Spool, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid spool max size: %d", maxSize)
+	}
+	if err := disk.MkdirAll(dir, dirMode); err != nil {
+		return nil, fmt.Errorf("cannot create spool directory %s: %w", dir, err)
+	}
+
+	s := &Spool{
+		dir:     dir,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		now:     time.Now,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.evict(0)
+
+	return s, nil
+}
+
+// load reads the payloads in the spool directory, removing the ones partially written.
+func (s *Spool) load() error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("cannot read spool directory %s: %w", s.dir, err)
+	}
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		path := filepath.Join(s.dir, f.Name())
+		if strings.HasSuffix(f.Name(), tmpExt) {
+			_ = os.Remove(path)
+			continue
+		}
+		created, ok := parseName(f.Name())
+		if !ok {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		s.entries = append(s.entries, entry{name: f.Name(), created: created, size: info.Size()})
+		s.size += info.Size()
+	}
+
+	// names start with the creation time, so sorting them keeps the push order
+	sort.Slice(s.entries, func(i, j int) bool {
+		return s.entries[i].name < s.entries[j].name
+	})
+	return nil
+}
+
+// Push stores the payload at the end of the spool, evicting the oldest payloads if there is not enough room.
+func (s *Spool) Push(payload []byte) error {
+	size := int64(len(payload))
+	if size > s.maxSize {
+		return fmt.Errorf("payload is larger than the spool max size (%d > %d)", size, s.maxSize)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	created := s.now()
+	s.seq++
+	name := fmt.Sprintf("%020d-%010d%s", created.UnixNano(), s.seq, payloadExt)
+	path := filepath.Join(s.dir, name)
+
+	// written to a temporary file first, so partial payloads are never replayed
+	tmpPath := path + tmpExt
+	if err := disk.WriteFile(tmpPath, payload, fileMode); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("cannot write spooled payload: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("cannot write spooled payload: %w", err)
+	}
+
+	s.evict(size)
+	s.entries = append(s.entries, entry{name: name, created: created, size: size})
+	s.size += size
+	return nil
+}
+
+// Peek returns the oldest payload without removing it, or ErrEmpty when there are none.
+func (s *Spool) Peek() (Entry, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.evict(0)
+	for len(s.entries) > 0 {
+		e := s.entries[0]
+		payload, err := os.ReadFile(filepath.Join(s.dir, e.name))
+		if err == nil {
+			return Entry{Name: e.name, Created: e.created, Payload: payload}, nil
+		}
+		splog.WithError(err).WithField("file", e.name).Warn("Cannot read spooled payload, discarding it.")
+		s.removeFirst()
+	}
+	return Entry{}, ErrEmpty
+}
+
+// Remove deletes the payload, usually once it has been sent.
+func (s *Spool) Remove(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, e := range s.entries {
+		if e.name != name {
+			continue
+		}
+		s.entries = append(s.entries[:i], s.entries[i+1:]...)
+		s.size -= e.size
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove spooled payload: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// Len returns the number of spooled payloads.
+func (s *Spool) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.entries)
+}
+
+// Size returns the size in bytes of the spooled payloads.
+func (s *Spool) Size() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.size
+}
+
+// evict removes the expired payloads and the oldest ones until there is room for incoming bytes.
+func (s *Spool) evict(incoming int64) {
+	expired, overflow := 0, 0
+	for len(s.entries) > 0 {
+		if s.maxAge > 0 && s.now().Sub(s.entries[0].created) > s.maxAge {
+			expired++
+		} else if s.size+incoming > s.maxSize {
+			overflow++
+		} else {
+			break
+		}
+		s.removeFirst()
+	}
+	if expired > 0 || overflow > 0 {
+		splog.WithField("expired", expired).WithField("overflow", overflow).
+			Warn("Spooled payloads evicted, their data is lost.")
+	}
+}
+
+func (s *Spool) removeFirst() {
+	e := s.entries[0]
+	s.entries = s.entries[1:]
+	s.size -= e.size
+	if err := os.Remove(filepath.Join(s.dir, e.name)); err != nil && !os.IsNotExist(err) {
+		splog.WithError(err).WithField("file", e.name).Warn("Cannot remove spooled payload.")
+	}
+}
+
+// parseName returns the creation time of a spooled payload file.
+func parseName(name string) (time.Time, bool) {
+	if !strings.HasSuffix(name, payloadExt) {
+		return time.Time{}, false
+	}
+	nanos, _, ok := strings.Cut(strings.TrimSuffix(name, payloadExt), "-")
+	if !ok {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, n), true
+}
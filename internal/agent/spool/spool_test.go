@@ -0,0 +1,113 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pop(t *testing.T, s *Spool) string {
+	t.Helper()
+	e, err := s.Peek()
+	require.NoError(t, err)
+	require.NoError(t, s.Remove(e.Name))
+	return string(e.Payload)
+}
+
+func TestSpool_FIFO(t *testing.T) {
+	s, err := New(t.TempDir(), 1024, 0)
+	require.NoError(t, err)
+
+	for _, p := range []string{"first", "second", "third"} {
+		require.NoError(t, s.Push([]byte(p)))
+	}
+	assert.Equal(t, 3, s.Len())
+	assert.EqualValues(t, len("first")+len("second")+len("third"), s.Size())
+
+	assert.Equal(t, "first", pop(t, s))
+	assert.Equal(t, "second", pop(t, s))
+	assert.Equal(t, "third", pop(t, s))
+
+	_, err = s.Peek()
+	assert.Equal(t, ErrEmpty, err)
+	assert.Zero(t, s.Size())
+}
+
+func TestSpool_SurvivesRestarts(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir, 1024, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Push([]byte("first")))
+	require.NoError(t, s.Push([]byte("second")))
+	// partially written payload from a crash
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000001-0000000001.payload.tmp"), []byte("x"), 0o600))
+
+	s, err = New(dir, 1024, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, s.Len())
+	assert.Equal(t, "first", pop(t, s))
+	assert.Equal(t, "second", pop(t, s))
+	assert.NoFileExists(t, filepath.Join(dir, "00000000000000000001-0000000001.payload.tmp"))
+}
+
+func TestSpool_EvictsOldestWhenFull(t *testing.T) {
+	s, err := New(t.TempDir(), 10, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Push([]byte("aaaa")))
+	require.NoError(t, s.Push([]byte("bbbb")))
+	require.NoError(t, s.Push([]byte("cccc")))
+
+	assert.Equal(t, 2, s.Len())
+	assert.EqualValues(t, 8, s.Size())
+	assert.Equal(t, "bbbb", pop(t, s))
+	assert.Equal(t, "cccc", pop(t, s))
+}
+
+func TestSpool_RejectsPayloadsLargerThanMaxSize(t *testing.T) {
+	s, err := New(t.TempDir(), 4, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Push([]byte("aaaa")))
+
+	assert.Error(t, s.Push([]byte("bbbbb")))
+	assert.Equal(t, "aaaa", pop(t, s))
+}
+
+func TestSpool_EvictsExpired(t *testing.T) {
+	s, err := New(t.TempDir(), 1024, time.Hour)
+	require.NoError(t, err)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	require.NoError(t, s.Push([]byte("old")))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, s.Push([]byte("new")))
+	now = now.Add(45 * time.Minute)
+
+	assert.Equal(t, "new", pop(t, s))
+	_, err = s.Peek()
+	assert.Equal(t, ErrEmpty, err)
+}
+
+func TestSpool_DiscardsUnreadablePayloads(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir, 1024, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Push([]byte("first")))
+	require.NoError(t, s.Push([]byte("second")))
+
+	first, err := s.Peek()
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(dir, first.Name)))
+
+	assert.Equal(t, "second", pop(t, s))
+	assert.Zero(t, s.Len())
+}
@@ -0,0 +1,244 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package status
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/bus"
+	"github.com/newrelic/infrastructure-agent/internal/agent/capabilities"
+	"github.com/newrelic/infrastructure-agent/internal/feature_flags"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/runner"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sampler"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+)
+
+// staleSamplerIntervals is the number of intervals a sampler can go without harvesting before it's unhealthy.
+const staleSamplerIntervals = 3
+
+// HealthReport summarizes whether the agent is working from its runtime state. Unlike the Report, New Relic
+// endpoints are not checked, so it's cheap enough for frequent health checks.
+type HealthReport struct {
+	Healthy        bool          `json:"healthy"`
+	Problems       []string      `json:"problems,omitempty"`
+	Queues         *QueuesReport `json:"queues,omitempty"`
+	LastHarvest    *time.Time    `json:"last_harvest,omitempty"`
+	LastSubmission *time.Time    `json:"last_submission,omitempty"`
+}
+
+// QueuesReport represents the usage of the event sender queues.
+type QueuesReport struct {
+	EventQueueSize     int `json:"event_queue_size"`
+	EventQueueCapacity int `json:"event_queue_capacity"`
+	BatchQueueSize     int `json:"batch_queue_size"`
+	BatchQueueCapacity int `json:"batch_queue_capacity"`
+}
+
+// IntegrationsReport represents the running integrations.
+type IntegrationsReport struct {
+	Integrations []runner.IntegrationReport `json:"integrations"`
+}
+
+// QuarantineReport represents the integrations quarantined for their invalid output.
+type QuarantineReport struct {
+	Quarantined []runner.QuarantineReport `json:"quarantined"`
+}
+
+// SamplersReport represents the running samplers.
+type SamplersReport struct {
+	Samplers []sampler.SamplerReport `json:"samplers"`
+}
+
+// FeatureFlagsReport represents the effective feature flags.
+type FeatureFlagsReport struct {
+	FeatureFlags []feature_flags.Flag `json:"feature_flags"`
+}
+
+// CapabilitiesReport represents the data the agent can access under its user and Linux capabilities.
+type CapabilitiesReport struct {
+	capabilities.Matrix
+}
+
+// WithBackendRequests includes the requests sent to the backend endpoints, and their last errors, into the reports.
+func WithBackendRequests(reports func() []backendhttp.BackendReport) ReporterOption {
+	return func(r *nrReporter) {
+		r.backendRequests = reports
+	}
+}
+
+// WithSenderQueues includes the event sender queues usage into the reports, false when not available.
+func WithSenderQueues(queues func() (QueuesReport, bool)) ReporterOption {
+	return func(r *nrReporter) {
+		r.senderQueues = queues
+	}
+}
+
+// WithSamplers reports the running samplers, checking they keep harvesting.
+func WithSamplers(reports func() []sampler.SamplerReport) ReporterOption {
+	return func(r *nrReporter) {
+		r.samplers = reports
+	}
+}
+
+// WithIntegrations reports the running integrations.
+func WithIntegrations(reports func() []runner.IntegrationReport) ReporterOption {
+	return func(r *nrReporter) {
+		r.integrations = reports
+	}
+}
+
+// WithQuarantine reports the integrations quarantined for their invalid output.
+func WithQuarantine(reports func() []runner.QuarantineReport) ReporterOption {
+	return func(r *nrReporter) {
+		r.quarantine = reports
+	}
+}
+
+// WithFeatureFlags reports the effective feature flags.
+func WithFeatureFlags(flags func() []feature_flags.Flag) ReporterOption {
+	return func(r *nrReporter) {
+		r.featureFlags = flags
+	}
+}
+
+// WithCapabilities reports the probed capabilities.
+func WithCapabilities(matrix func() capabilities.Matrix) ReporterOption {
+	return func(r *nrReporter) {
+		r.capabilities = matrix
+	}
+}
+
+// WithCloudMetadata includes the cloud detection outcome into the reports, false when not available.
+func WithCloudMetadata(report func() (cloud.MetadataReport, bool)) ReporterOption {
+	return func(r *nrReporter) {
+		r.cloudMetadata = report
+	}
+}
+
+// WithEventBus includes the event bus subscribers delivery stats into the reports.
+func WithEventBus(stats func() []bus.Stats) ReporterOption {
+	return func(r *nrReporter) {
+		r.eventBus = stats
+	}
+}
+
+// ReportHealth reports the agent as unhealthy when requests to New Relic are failing, the event queues are full,
+// samplers stopped harvesting, integrations were stopped by their crash-loop breaker or the background prober found
+// unhealthy endpoints.
+func (r *nrReporter) ReportHealth() (report HealthReport, err error) {
+	if r.backendRequests != nil {
+		for _, b := range r.backendRequests() {
+			if b.Failing {
+				report.Problems = append(report.Problems, fmt.Sprintf("requests to %s failing: %s", b.URL, b.LastError))
+			}
+			if b.LastSuccess != nil && (report.LastSubmission == nil || b.LastSuccess.After(*report.LastSubmission)) {
+				report.LastSubmission = b.LastSuccess
+			}
+		}
+	}
+
+	report.Queues = r.queues()
+	if q := report.Queues; q != nil {
+		if q.EventQueueCapacity > 0 && q.EventQueueSize >= q.EventQueueCapacity {
+			report.Problems = append(report.Problems, fmt.Sprintf("event queue full with %d events", q.EventQueueSize))
+		}
+		if q.BatchQueueCapacity > 0 && q.BatchQueueSize >= q.BatchQueueCapacity {
+			report.Problems = append(report.Problems, fmt.Sprintf("batch queue full with %d batches", q.BatchQueueSize))
+		}
+	}
+
+	if r.samplers != nil {
+		now := time.Now()
+		for _, s := range r.samplers() {
+			if s.LastHarvest != nil && (report.LastHarvest == nil || s.LastHarvest.After(*report.LastHarvest)) {
+				report.LastHarvest = s.LastHarvest
+			}
+			if s.Disabled || s.IntervalSec <= 0 {
+				continue
+			}
+			since := s.Started
+			if s.LastHarvest != nil {
+				since = *s.LastHarvest
+			}
+			if now.Sub(since) > staleSamplerIntervals*time.Duration(s.IntervalSec)*time.Second {
+				report.Problems = append(report.Problems, fmt.Sprintf("sampler %s not harvested for %s", s.Name, now.Sub(since).Round(time.Second)))
+			}
+		}
+	}
+
+	if r.integrations != nil {
+		for _, i := range r.integrations() {
+			if i.CrashLoopSince != nil {
+				report.Problems = append(report.Problems, fmt.Sprintf("integration %s crash looping: %s", i.Name, i.LastError))
+			}
+		}
+	}
+
+	if r.prober != nil {
+		for _, p := range r.prober.Reports() {
+			if !p.Healthy {
+				report.Problems = append(report.Problems, fmt.Sprintf("endpoint %s unhealthy: %s", p.URL, p.LastError))
+			}
+		}
+	}
+
+	report.Healthy = len(report.Problems) == 0
+	return report, nil
+}
+
+// ReportIntegrations reports the running integrations.
+func (r *nrReporter) ReportIntegrations() (report IntegrationsReport, err error) {
+	report.Integrations = []runner.IntegrationReport{}
+	if r.integrations != nil {
+		report.Integrations = append(report.Integrations, r.integrations()...)
+	}
+	return report, nil
+}
+
+// ReportQuarantine reports the integrations quarantined for their invalid output.
+func (r *nrReporter) ReportQuarantine() (report QuarantineReport, err error) {
+	report.Quarantined = []runner.QuarantineReport{}
+	if r.quarantine != nil {
+		report.Quarantined = append(report.Quarantined, r.quarantine()...)
+	}
+	return report, nil
+}
+
+// ReportSamplers reports the running samplers.
+func (r *nrReporter) ReportSamplers() (report SamplersReport, err error) {
+	report.Samplers = []sampler.SamplerReport{}
+	if r.samplers != nil {
+		report.Samplers = append(report.Samplers, r.samplers()...)
+	}
+	return report, nil
+}
+
+// ReportFeatureFlags reports the effective feature flags, and the values from other sources they override.
+func (r *nrReporter) ReportFeatureFlags() (report FeatureFlagsReport, err error) {
+	report.FeatureFlags = []feature_flags.Flag{}
+	if r.featureFlags != nil {
+		report.FeatureFlags = append(report.FeatureFlags, r.featureFlags()...)
+	}
+	return report, nil
+}
+
+// ReportCapabilities reports the probed capabilities, and the data not reported because they are unavailable.
+func (r *nrReporter) ReportCapabilities() (report CapabilitiesReport, err error) {
+	report.Capabilities = []capabilities.Capability{}
+	if r.capabilities != nil {
+		report.Matrix = r.capabilities()
+	}
+	return report, nil
+}
+
+func (r *nrReporter) queues() *QueuesReport {
+	if r.senderQueues == nil {
+		return nil
+	}
+	if q, ok := r.senderQueues(); ok {
+		return &q
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package status
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/runner"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sampler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRuntimeReporter(opts ...ReporterOption) Reporter {
+	idProvide := func() entity.Identity { return entity.EmptyIdentity }
+	entityKeyProvider := func() string { return "" }
+	return NewReporter(context.Background(), log.WithComponent("test"), nil, time.Millisecond, &http.Transport{}, idProvide, entityKeyProvider, "user-agent", "agent-key", nil, opts...)
+}
+
+func TestReporter_ReportHealth(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Minute)
+	backend := []backendhttp.BackendReport{
+		{URL: "https://infra-api.newrelic.com/inventory/deltas", LastSuccess: &earlier},
+		{URL: "https://infra-api.newrelic.com/infra/v2/metrics", LastSuccess: &now},
+	}
+	samplers := []sampler.SamplerReport{
+		{Name: "SystemSampler", IntervalSec: 5, Started: now.Add(-time.Hour), LastHarvest: &now},
+		{Name: "NetworkSampler", IntervalSec: 10, Started: now.Add(-time.Hour), LastHarvest: &earlier},
+		{Name: "StorageSampler", IntervalSec: 20, Started: now, Disabled: true},
+		// not harvested yet
+		{Name: "ProcessSampler", IntervalSec: 20, Started: now},
+	}
+	queues := QueuesReport{EventQueueSize: 1, EventQueueCapacity: 1000, BatchQueueCapacity: 200}
+	integrations := []runner.IntegrationReport{{Name: "nri-nginx"}}
+	r := newRuntimeReporter(
+		WithBackendRequests(func() []backendhttp.BackendReport { return backend }),
+		WithSamplers(func() []sampler.SamplerReport { return samplers }),
+		WithSenderQueues(func() (QueuesReport, bool) { return queues, true }),
+		WithIntegrations(func() []runner.IntegrationReport { return integrations }),
+	)
+
+	report, err := r.ReportHealth()
+	require.NoError(t, err)
+	assert.False(t, report.Healthy)
+	assert.Equal(t, []string{"sampler NetworkSampler not harvested for 1m0s"}, report.Problems)
+	assert.Equal(t, &now, report.LastHarvest)
+	assert.Equal(t, &now, report.LastSubmission)
+	assert.Equal(t, &queues, report.Queues)
+
+	samplers[1].LastHarvest = &now
+	backend[0].Failing, backend[0].LastError = true, "unsuccessful response, status: 503"
+	queues.EventQueueSize = 1000
+	integrations[0].CrashLoopSince, integrations[0].LastError = &now, "integration failed 5 times within 10m0s"
+	report, err = r.ReportHealth()
+	require.NoError(t, err)
+	assert.False(t, report.Healthy)
+	assert.Equal(t, []string{
+		"requests to https://infra-api.newrelic.com/inventory/deltas failing: unsuccessful response, status: 503",
+		"event queue full with 1000 events",
+		"integration nri-nginx crash looping: integration failed 5 times within 10m0s",
+	}, report.Problems)
+
+	backend[0].Failing = false
+	queues.EventQueueSize = 0
+	integrations[0].CrashLoopSince = nil
+	report, err = r.ReportHealth()
+	require.NoError(t, err)
+	assert.True(t, report.Healthy)
+	assert.Empty(t, report.Problems)
+}
+
+func TestReporter_ReportErrors_Backend(t *testing.T) {
+	backend := []backendhttp.BackendReport{
+		{URL: "https://infra-api.newrelic.com/inventory/deltas", Failing: true, LastError: "timeout"},
+		{URL: "https://infra-api.newrelic.com/infra/v2/metrics"},
+	}
+	r := newRuntimeReporter(WithBackendRequests(func() []backendhttp.BackendReport { return backend }))
+
+	report, err := r.ReportErrors()
+	require.NoError(t, err)
+	require.NotNil(t, report.Checks)
+	assert.Equal(t, backend[:1], report.Backend)
+
+	report, err = r.Report()
+	require.NoError(t, err)
+	assert.Equal(t, backend, report.Backend)
+	// queues are not reported when not available
+	assert.Nil(t, report.Queues)
+}
+
+func TestReporter_ReportRuntime(t *testing.T) {
+	r := newRuntimeReporter()
+
+	integrations, err := r.ReportIntegrations()
+	require.NoError(t, err)
+	assert.Empty(t, integrations.Integrations)
+	assert.NotNil(t, integrations.Integrations)
+	quarantine, err := r.ReportQuarantine()
+	require.NoError(t, err)
+	assert.NotNil(t, quarantine.Quarantined)
+
+	r = newRuntimeReporter(
+		WithIntegrations(func() []runner.IntegrationReport { return []runner.IntegrationReport{{Name: "nri-nginx"}} }),
+		WithQuarantine(func() []runner.QuarantineReport { return []runner.QuarantineReport{{Name: "nri-flex"}} }),
+		WithSamplers(func() []sampler.SamplerReport { return []sampler.SamplerReport{{Name: "SystemSampler"}} }),
+	)
+	integrations, err = r.ReportIntegrations()
+	require.NoError(t, err)
+	assert.Equal(t, []runner.IntegrationReport{{Name: "nri-nginx"}}, integrations.Integrations)
+	quarantine, err = r.ReportQuarantine()
+	require.NoError(t, err)
+	assert.Equal(t, []runner.QuarantineReport{{Name: "nri-flex"}}, quarantine.Quarantined)
+	samplers, err := r.ReportSamplers()
+	require.NoError(t, err)
+	assert.Equal(t, []sampler.SamplerReport{{Name: "SystemSampler"}}, samplers.Samplers)
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package status
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/id"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+// ProbeReport represents the connectivity history of a backend endpoint, as seen by the background prober.
+type ProbeReport struct {
+	URL           string     `json:"url"`
+	Healthy       bool       `json:"healthy"`
+	Probes        int        `json:"probes"`
+	Failures      int        `json:"failures"`
+	SuccessRate   float64    `json:"success_rate"`
+	LastLatencyMs float64    `json:"last_latency_ms"`
+	AvgLatencyMs  float64    `json:"avg_latency_ms"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastSuccess   *time.Time `json:"last_success,omitempty"`
+	LastProbe     time.Time  `json:"last_probe"`
+}
+
+// reachabilityFn checks whether an endpoint is reachable, as backendhttp.CheckEndpointReachability.
+type reachabilityFn func(ctx context.Context, endpoint string) (timedOut bool, err error)
+
+// Prober periodically checks the backend endpoints reachability, tracking their success rate and latency, so
+// connectivity outages are reported by the status API even after they're gone.
+type Prober struct {
+	log       log.Entry
+	endpoints []string
+	interval  time.Duration
+	check     reachabilityFn
+
+	lock  sync.RWMutex
+	stats map[string]*endpointStats
+}
+
+type endpointStats struct {
+	probes       int
+	failures     int
+	totalLatency time.Duration
+	lastLatency  time.Duration
+	lastErr      error
+	lastSuccess  time.Time
+	lastProbe    time.Time
+}
+
+// NewProber creates a prober checking the backend endpoints every interval.
+func NewProber(
+	l log.Entry,
+	backendEndpoints []string,
+	interval time.Duration,
+	timeout time.Duration,
+	transport http.RoundTripper,
+	agentIDProvide id.Provide,
+	license,
+	userAgent string,
+) *Prober {
+	check := func(ctx context.Context, endpoint string) (bool, error) {
+		return backendhttp.CheckEndpointReachability(
+			ctx,
+			l,
+			endpoint,
+			license,
+			userAgent,
+			agentIDProvide().ID.String(),
+			timeout,
+			transport,
+		)
+	}
+	return newProber(l, backendEndpoints, interval, check)
+}
+
+func newProber(l log.Entry, endpoints []string, interval time.Duration, check reachabilityFn) *Prober {
+	stats := make(map[string]*endpointStats, len(endpoints))
+	for _, ep := range endpoints {
+		stats[ep] = &endpointStats{}
+	}
+	return &Prober{
+		log:       l,
+		endpoints: endpoints,
+		interval:  interval,
+		check:     check,
+		stats:     stats,
+	}
+}
+
+// Run probes the endpoints until the context is cancelled.
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe checks all the endpoints concurrently.
+func (p *Prober) probe(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	wg.Add(len(p.endpoints))
+	for _, ep := range p.endpoints {
+		go func(endpoint string) {
+			defer wg.Done()
+
+			start := time.Now()
+			timedOut, err := p.check(ctx, endpoint)
+			latency := time.Since(start)
+			if timedOut && err != nil {
+				err = fmt.Errorf("%s, %w", endpointTimeoutMsg, err)
+			}
+			p.record(endpoint, start, latency, err)
+		}(ep)
+	}
+	wg.Wait()
+}
+
+func (p *Prober) record(endpoint string, when time.Time, latency time.Duration, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	s := p.stats[endpoint]
+	wasHealthy := s.probes == 0 || s.lastErr == nil
+
+	s.probes++
+	s.totalLatency += latency
+	s.lastLatency = latency
+	s.lastProbe = when
+	s.lastErr = err
+	if err != nil {
+		s.failures++
+	} else {
+		s.lastSuccess = when
+	}
+
+	// only log state transitions, to not flood the logs during long outages
+	if wasHealthy && err != nil {
+		p.log.WithError(err).WithField("url", endpoint).Warn("Backend endpoint became unreachable.")
+	} else if !wasHealthy && err == nil {
+		p.log.WithField("url", endpoint).Info("Backend endpoint is reachable again.")
+	}
+}
+
+// Reports returns the connectivity report of the probed endpoints, skipping the ones not probed yet.
+func (p *Prober) Reports() []ProbeReport {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	reports := make([]ProbeReport, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		s := p.stats[ep]
+		if s.probes == 0 {
+			continue
+		}
+		r := ProbeReport{
+			URL:           ep,
+			Healthy:       s.lastErr == nil,
+			Probes:        s.probes,
+			Failures:      s.failures,
+			SuccessRate:   float64(s.probes-s.failures) / float64(s.probes) * 100,
+			LastLatencyMs: durationMs(s.lastLatency),
+			AvgLatencyMs:  durationMs(s.totalLatency / time.Duration(s.probes)),
+			LastProbe:     s.lastProbe,
+		}
+		if s.lastErr != nil {
+			r.LastError = s.lastErr.Error()
+		}
+		if !s.lastSuccess.IsZero() {
+			lastSuccess := s.lastSuccess
+			r.LastSuccess = &lastSuccess
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package status
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChecks replies each endpoint with the queued results, reachable when exhausted.
+type fakeChecks struct {
+	lock    sync.Mutex
+	results map[string][]error
+}
+
+func (f *fakeChecks) check(_ context.Context, endpoint string) (bool, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	queued := f.results[endpoint]
+	if len(queued) == 0 {
+		return false, nil
+	}
+	f.results[endpoint] = queued[1:]
+	return queued[0] != nil, queued[0]
+}
+
+func TestProber_Reports(t *testing.T) {
+	checks := &fakeChecks{results: map[string][]error{
+		"http://metrics": {nil, errors.New("connection refused"), nil},
+		"http://command": {nil, errors.New("connection refused"), errors.New("connection refused")},
+	}}
+	p := newProber(log.WithComponent("test"), []string{"http://metrics", "http://command", "http://inventory"}, time.Minute, checks.check)
+
+	assert.Empty(t, p.Reports(), "no reports before probing")
+
+	for i := 0; i < 4; i++ {
+		p.probe(context.Background())
+	}
+
+	reports := p.Reports()
+	require.Len(t, reports, 3)
+
+	metrics := reports[0]
+	assert.Equal(t, "http://metrics", metrics.URL)
+	assert.True(t, metrics.Healthy)
+	assert.Equal(t, 4, metrics.Probes)
+	assert.Equal(t, 1, metrics.Failures)
+	assert.Equal(t, 75.0, metrics.SuccessRate)
+	assert.Empty(t, metrics.LastError)
+	require.NotNil(t, metrics.LastSuccess)
+	assert.Equal(t, metrics.LastProbe, *metrics.LastSuccess)
+
+	command := reports[1]
+	assert.True(t, command.Healthy, "recovered on the last probe")
+	assert.Equal(t, 2, command.Failures)
+	assert.Equal(t, 50.0, command.SuccessRate)
+
+	inventory := reports[2]
+	assert.True(t, inventory.Healthy)
+	assert.Equal(t, 100.0, inventory.SuccessRate)
+}
+
+func TestProber_Unhealthy(t *testing.T) {
+	checks := &fakeChecks{results: map[string][]error{
+		"http://metrics": {nil, errors.New("i/o timeout")},
+	}}
+	p := newProber(log.WithComponent("test"), []string{"http://metrics"}, time.Minute, checks.check)
+	p.probe(context.Background())
+	p.probe(context.Background())
+
+	reports := p.Reports()
+	require.Len(t, reports, 1)
+	assert.False(t, reports[0].Healthy)
+	assert.Contains(t, reports[0].LastError, endpointTimeoutMsg)
+	assert.Contains(t, reports[0].LastError, "i/o timeout")
+	assert.NotNil(t, reports[0].LastSuccess)
+	assert.True(t, reports[0].LastSuccess.Before(reports[0].LastProbe) || reports[0].LastSuccess.Equal(reports[0].LastProbe))
+}
+
+func TestProber_Run(t *testing.T) {
+	checks := &fakeChecks{results: map[string][]error{}}
+	p := newProber(log.WithComponent("test"), []string{"http://metrics"}, time.Millisecond, checks.check)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		reports := p.Reports()
+		return len(reports) == 1 && reports[0].Probes > 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("prober didn't stop after context cancellation")
+	}
+}
+
+func TestNewReporter_WithProber(t *testing.T) {
+	checks := &fakeChecks{results: map[string][]error{
+		"http://command": {errors.New("connection refused")},
+	}}
+	p := newProber(log.WithComponent("test"), []string{"http://metrics", "http://command"}, time.Minute, checks.check)
+	p.probe(context.Background())
+
+	emptyIDProvide := func() entity.Identity {
+		return entity.EmptyIdentity
+	}
+	emptyEntityKeyProvider := func() string {
+		return ""
+	}
+	r := NewReporter(context.Background(), log.WithComponent("test"), []string{}, time.Millisecond, &http.Transport{}, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key", nil, WithProber(p))
+
+	report, err := r.Report()
+	require.NoError(t, err)
+	require.NotNil(t, report.Checks)
+	assert.Len(t, report.Checks.Probes, 2)
+
+	report, err = r.ReportErrors()
+	require.NoError(t, err)
+	require.NotNil(t, report.Checks, "unhealthy probes are errors")
+	require.Len(t, report.Checks.Probes, 1)
+	assert.Equal(t, "http://command", report.Checks.Probes[0].URL)
+
+	// once recovered there are no errors
+	p.probe(context.Background())
+	report, err = r.ReportErrors()
+	require.NoError(t, err)
+	assert.Nil(t, report.Checks)
+}
@@ -9,9 +9,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/newrelic/infrastructure-agent/internal/agent/bus"
+	"github.com/newrelic/infrastructure-agent/internal/agent/capabilities"
 	"github.com/newrelic/infrastructure-agent/internal/agent/id"
+	"github.com/newrelic/infrastructure-agent/internal/feature_flags"
+	"github.com/newrelic/infrastructure-agent/internal/fips"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/runner"
 	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sampler"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
 )
 
 const (
@@ -21,21 +29,36 @@ const (
 // Report agent status report. It contains:
 // - checks:
 //   - backend endpoints reachability statuses
+//   - backend endpoints connectivity history, when the background prober is enabled
 //
-// - configuration
+// - configuration, including the warnings found while loading it
+// - backend endpoints cached resolutions, when the DNS cache is enabled
+// - requests sent to the backend endpoints and their last errors
+// - event sender queues usage
+// - event bus subscribers delivery stats
+// - cloud detection outcome, like the AWS instance metadata service version in use
+// - FIPS mode status
 // fields will be empty when ReportErrors() report no errors.
 type Report struct {
-	Checks *ChecksReport `json:"checks,omitempty"`
-	Config *ConfigReport `json:"config,omitempty"`
+	Checks   *ChecksReport                `json:"checks,omitempty"`
+	Config   *ConfigReport                `json:"config,omitempty"`
+	DNSCache []backendhttp.DNSCacheReport `json:"dns_cache,omitempty"`
+	Backend  []backendhttp.BackendReport  `json:"backend,omitempty"`
+	Queues   *QueuesReport                `json:"queues,omitempty"`
+	EventBus []bus.Stats                  `json:"event_bus,omitempty"`
+	Cloud    *cloud.MetadataReport        `json:"cloud,omitempty"`
+	FIPS     *fips.Report                 `json:"fips,omitempty"`
 }
 
 type ChecksReport struct {
 	Endpoints []EndpointReport `json:"endpoints,omitempty"`
+	Probes    []ProbeReport    `json:"probes,omitempty"`
 }
 
 // ConfigReport configuration used for status report.
 type ConfigReport struct {
-	ReachabilityTimeout string `json:"reachability_timeout,omitempty"`
+	ReachabilityTimeout string   `json:"reachability_timeout,omitempty"`
+	Warnings            []string `json:"warnings,omitempty"`
 }
 
 // EndpointReport represents a single backend endpoint reachability status.
@@ -49,6 +72,10 @@ type EndpointReport struct {
 type ReportEntity struct {
 	GUID string `json:"guid"`
 	Key  string `json:"key"`
+	// ID is the agent ID assigned on the current run, StoredID the one persisted in the data directory with the
+	// last inventory submission. They differ until the inventory is submitted after the identity changed.
+	ID       entity.ID `json:"id,omitempty"`
+	StoredID entity.ID `json:"stored_id,omitempty"`
 }
 
 // Reporter reports agent status.
@@ -59,6 +86,18 @@ type Reporter interface {
 	ReportErrors() (Report, error)
 	// ReportEntity agent entity report.
 	ReportEntity() (ReportEntity, error)
+	// ReportHealth reports whether the agent is working, from its runtime state.
+	ReportHealth() (HealthReport, error)
+	// ReportIntegrations reports the running integrations.
+	ReportIntegrations() (IntegrationsReport, error)
+	// ReportQuarantine reports the integrations quarantined for their invalid output.
+	ReportQuarantine() (QuarantineReport, error)
+	// ReportSamplers reports the running samplers.
+	ReportSamplers() (SamplersReport, error)
+	// ReportFeatureFlags reports the effective feature flags.
+	ReportFeatureFlags() (FeatureFlagsReport, error)
+	// ReportCapabilities reports the data the agent can access under its user and Linux capabilities.
+	ReportCapabilities() (CapabilitiesReport, error)
 }
 
 type nrReporter struct {
@@ -71,6 +110,43 @@ type nrReporter struct {
 	agentEntityKeyProvider func() string
 	timeout                time.Duration
 	transport              http.RoundTripper
+	configWarnings         []string
+	prober                 *Prober
+	dnsCache               func() []backendhttp.DNSCacheReport
+	backendRequests        func() []backendhttp.BackendReport
+	senderQueues           func() (QueuesReport, bool)
+	eventBus               func() []bus.Stats
+	samplers               func() []sampler.SamplerReport
+	integrations           func() []runner.IntegrationReport
+	quarantine             func() []runner.QuarantineReport
+	storedID               func() (entity.ID, error)
+	featureFlags           func() []feature_flags.Flag
+	capabilities           func() capabilities.Matrix
+	cloudMetadata          func() (cloud.MetadataReport, bool)
+}
+
+// ReporterOption customizes the status reporter.
+type ReporterOption func(r *nrReporter)
+
+// WithProber includes the background prober connectivity history into the reports.
+func WithProber(p *Prober) ReporterOption {
+	return func(r *nrReporter) {
+		r.prober = p
+	}
+}
+
+// WithDNSCache includes the backend endpoints resolutions cached by the agent into the reports.
+func WithDNSCache(reports func() []backendhttp.DNSCacheReport) ReporterOption {
+	return func(r *nrReporter) {
+		r.dnsCache = reports
+	}
+}
+
+// WithStoredIdentity includes the agent ID persisted in the data directory into the entity report.
+func WithStoredIdentity(storedID func() (entity.ID, error)) ReporterOption {
+	return func(r *nrReporter) {
+		r.storedID = storedID
+	}
 }
 
 // Report reports agent status.
@@ -133,25 +209,75 @@ func (r *nrReporter) report(onlyErrors bool) (report Report, err error) {
 		}
 	}
 
+	var pReports []ProbeReport
+	if r.prober != nil {
+		for _, p := range r.prober.Reports() {
+			if !onlyErrors || !p.Healthy {
+				pReports = append(pReports, p)
+			}
+			if !p.Healthy {
+				errored = true
+			}
+		}
+	}
+
+	var bReports []backendhttp.BackendReport
+	if r.backendRequests != nil {
+		for _, b := range r.backendRequests() {
+			if !onlyErrors || b.Failing {
+				bReports = append(bReports, b)
+			}
+			if b.Failing {
+				errored = true
+			}
+		}
+	}
+
 	if !onlyErrors || errored {
 		if report.Checks == nil {
 			report.Checks = &ChecksReport{}
 		}
 		report.Checks.Endpoints = eReports
+		report.Checks.Probes = pReports
 		report.Config = &ConfigReport{
 			ReachabilityTimeout: r.timeout.String(),
+			Warnings:            r.configWarnings,
 		}
-
+		if r.dnsCache != nil {
+			report.DNSCache = r.dnsCache()
+		}
+		report.Backend = bReports
+		report.Queues = r.queues()
+		if r.eventBus != nil {
+			report.EventBus = r.eventBus()
+		}
+		if r.cloudMetadata != nil {
+			if cloudReport, ok := r.cloudMetadata(); ok {
+				report.Cloud = &cloudReport
+			}
+		}
+		fipsReport := fips.Status()
+		report.FIPS = &fipsReport
 	}
 
 	return
 }
 
 func (r *nrReporter) ReportEntity() (re ReportEntity, err error) {
-	return ReportEntity{
-		GUID: r.idProvide().GUID.String(),
+	identity := r.idProvide()
+	re = ReportEntity{
+		GUID: identity.GUID.String(),
 		Key:  r.agentEntityKeyProvider(),
-	}, nil
+		ID:   identity.ID,
+	}
+	if r.storedID != nil {
+		storedID, storedErr := r.storedID()
+		if storedErr != nil {
+			r.log.WithError(storedErr).Warn("cannot read the stored agent ID")
+		}
+		re.StoredID = storedID
+	}
+	return re, nil
 }
 
 // NewReporter creates a new status reporter.
@@ -165,9 +291,11 @@ func NewReporter(
 	agentEntityKeyProvider func() string,
 	license,
 	userAgent string,
+	configWarnings []string,
+	opts ...ReporterOption,
 ) Reporter {
 
-	return &nrReporter{
+	r := &nrReporter{
 		ctx:                    ctx,
 		log:                    l,
 		endpoints:              backendEndpoints,
@@ -177,5 +305,10 @@ func NewReporter(
 		agentEntityKeyProvider: agentEntityKeyProvider,
 		timeout:                timeout,
 		transport:              transport,
+		configWarnings:         configWarnings,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
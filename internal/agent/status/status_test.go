@@ -9,8 +9,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/newrelic/infrastructure-agent/internal/agent/bus"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -78,7 +81,7 @@ func TestNewReporter_Report(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			l := log.WithComponent(tt.name)
-			r := NewReporter(context.Background(), l, tt.endpoints, timeout, transport, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key")
+			r := NewReporter(context.Background(), l, tt.endpoints, timeout, transport, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key", nil)
 
 			got, err := r.Report()
 
@@ -156,7 +159,7 @@ func TestNewReporter_ReportErrors(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			l := log.WithComponent(tt.name)
-			r := NewReporter(context.Background(), l, tt.endpoints, timeout, transport, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key")
+			r := NewReporter(context.Background(), l, tt.endpoints, timeout, transport, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key", nil)
 
 			got, err := r.ReportErrors()
 
@@ -216,7 +219,7 @@ func TestNewReporter_ReportEntity(t *testing.T) {
 			entityKeyProvider := func() string {
 				return tt.entityKey
 			}
-			r := NewReporter(context.Background(), l, []string{}, timeout, transport, idProvide, entityKeyProvider, "user-agent", "agent-key")
+			r := NewReporter(context.Background(), l, []string{}, timeout, transport, idProvide, entityKeyProvider, "user-agent", "agent-key", nil)
 
 			got, err := r.ReportEntity()
 
@@ -230,3 +233,112 @@ func TestNewReporter_ReportEntity(t *testing.T) {
 		})
 	}
 }
+
+func TestNewReporter_ReportEntity_StoredIdentity(t *testing.T) {
+	idProvide := func() entity.Identity {
+		return entity.Identity{ID: 13, GUID: "foo"}
+	}
+	entityKeyProvider := func() string { return "bar" }
+	storedID := func() (entity.ID, error) { return 7, nil }
+	r := NewReporter(context.Background(), log.WithComponent("test"), []string{}, time.Millisecond, &http.Transport{}, idProvide, entityKeyProvider, "user-agent", "agent-key", nil, WithStoredIdentity(storedID))
+
+	got, err := r.ReportEntity()
+
+	require.NoError(t, err)
+	assert.Equal(t, ReportEntity{GUID: "foo", Key: "bar", ID: 13, StoredID: 7}, got)
+}
+
+func TestNewReporter_ReportConfigWarnings(t *testing.T) {
+	emptyIDProvide := func() entity.Identity {
+		return entity.EmptyIdentity
+	}
+	emptyEntityKeyProvider := func() string {
+		return ""
+	}
+	warnings := []string{"verbose: deprecated configuration option, use log.level instead"}
+
+	r := NewReporter(context.Background(), log.WithComponent("test"), []string{}, time.Millisecond, &http.Transport{}, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key", warnings)
+
+	got, err := r.Report()
+	require.NoError(t, err)
+	assert.Equal(t, warnings, got.Config.Warnings)
+
+	// warnings are not errors
+	got, err = r.ReportErrors()
+	require.NoError(t, err)
+	assert.Nil(t, got.Config)
+}
+
+func TestNewReporter_WithDNSCache(t *testing.T) {
+	emptyIDProvide := func() entity.Identity {
+		return entity.EmptyIdentity
+	}
+	emptyEntityKeyProvider := func() string {
+		return ""
+	}
+	dnsCache := []backendhttp.DNSCacheReport{{
+		Host:      "infra-api.newrelic.com",
+		Addresses: []string{"162.247.241.2"},
+		Hits:      3,
+		Misses:    1,
+	}}
+
+	r := NewReporter(context.Background(), log.WithComponent("test"), []string{}, time.Millisecond, &http.Transport{}, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key", nil, WithDNSCache(func() []backendhttp.DNSCacheReport {
+		return dnsCache
+	}))
+
+	got, err := r.Report()
+	require.NoError(t, err)
+	assert.Equal(t, dnsCache, got.DNSCache)
+
+	// the cache is not reported without errors
+	got, err = r.ReportErrors()
+	require.NoError(t, err)
+	assert.Nil(t, got.DNSCache)
+}
+
+func TestNewReporter_WithCloudMetadata(t *testing.T) {
+	emptyIDProvide := func() entity.Identity {
+		return entity.EmptyIdentity
+	}
+	emptyEntityKeyProvider := func() string {
+		return ""
+	}
+	cloudReport := cloud.MetadataReport{Type: cloud.TypeAWS, IMDSVersion: "v1", Warning: "hop limit"}
+
+	r := NewReporter(context.Background(), log.WithComponent("test"), []string{}, time.Millisecond, &http.Transport{}, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key", nil, WithCloudMetadata(func() (cloud.MetadataReport, bool) {
+		return cloudReport, true
+	}))
+
+	got, err := r.Report()
+	require.NoError(t, err)
+	require.NotNil(t, got.Cloud)
+	assert.Equal(t, cloudReport, *got.Cloud)
+
+	// the cloud detection is not reported without errors
+	got, err = r.ReportErrors()
+	require.NoError(t, err)
+	assert.Nil(t, got.Cloud)
+}
+
+func TestNewReporter_WithEventBus(t *testing.T) {
+	emptyIDProvide := func() entity.Identity {
+		return entity.EmptyIdentity
+	}
+	emptyEntityKeyProvider := func() string {
+		return ""
+	}
+	stats := []bus.Stats{{Name: "prometheus", DropPolicy: bus.DropOldest, QueueCapacity: 1000, Published: 10, Delivered: 9, Dropped: 1}}
+
+	r := NewReporter(context.Background(), log.WithComponent("test"), []string{}, time.Millisecond, &http.Transport{}, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key", nil, WithEventBus(func() []bus.Stats {
+		return stats
+	}))
+
+	got, err := r.Report()
+	require.NoError(t, err)
+	assert.Equal(t, stats, got.EventBus)
+
+	got, err = r.ReportErrors()
+	require.NoError(t, err)
+	assert.Nil(t, got.EventBus)
+}
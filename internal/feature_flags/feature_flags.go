@@ -4,13 +4,40 @@ package feature_flags
 
 import (
 	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/newrelic/infrastructure-agent/pkg/log"
 )
 
 var (
 	ErrFeatureFlagAlreadyExists = errors.New("feature flag already exists")
 )
 
+// EnvPrefix prefixes the environment variables setting a feature flag, ie: NRIA_FEATURE_FULL_PROCESS_SAMPLING=true.
+const EnvPrefix = "NRIA_FEATURE_"
+
+// Source is where the value of a feature flag comes from.
+type Source string
+
+// Feature flag sources, from the lowest to the highest precedence.
+const (
+	SourceCommandAPI   Source = "command_api"
+	SourceConfig       Source = "config"
+	SourceEnvironment  Source = "environment"
+	SourceOverrideFile Source = "override_file"
+)
+
+// precedence lists the sources from the highest to the lowest precedence.
+var precedence = []Source{SourceOverrideFile, SourceEnvironment, SourceConfig, SourceCommandAPI}
+
+var fflog = log.WithComponent("FeatureFlags")
+
 type Setter interface {
 	// SetFeatureFlag enables or disables FF on the config if not already set.
 	SetFeatureFlag(name string, enabled bool) error
@@ -26,55 +53,170 @@ type Manager interface {
 	Retriever
 }
 
+// Flag is the effective value of a feature flag, and the values from lower precedence sources it overrides.
+type Flag struct {
+	Name       string     `json:"name"`
+	Enabled    bool       `json:"enabled"`
+	Source     Source     `json:"source"`
+	Overridden []Override `json:"overridden,omitempty"`
+}
+
+// Override is a value of a feature flag ignored because a higher precedence source sets it.
+type Override struct {
+	Source  Source `json:"source"`
+	Enabled bool   `json:"enabled"`
+}
+
+// FeatureFlags keeps the feature flags values per source. The effective value of a flag is the one from the source
+// with the highest precedence: the override file, the environment, the config and the command API.
 type FeatureFlags struct {
-	featuresFromCfg map[string]bool
-	features        map[string]bool
-	lock            sync.Mutex
+	values map[string]map[Source]bool
+	lock   sync.Mutex
 }
 
-func NewManager(initialFeatureFlags map[string]bool) *FeatureFlags {
-	fInitial := map[string]bool{}
-	fFromCfg := map[string]bool{}
+// Option sets the feature flags from a source other than the config.
+type Option func(f *FeatureFlags)
 
-	if initialFeatureFlags != nil {
-		for key, value := range initialFeatureFlags {
-			fInitial[key] = value
-			fFromCfg[key] = value
+// WithEnvironment sets the feature flags from the environment variables, in "key=value" form as returned by
+// os.Environ, prefixed by EnvPrefix. The name of the flag is the lower-cased rest of the variable name.
+func WithEnvironment(environ []string) Option {
+	return func(f *FeatureFlags) {
+		for _, kv := range environ {
+			key, value, found := strings.Cut(kv, "=")
+			if !found || !strings.HasPrefix(key, EnvPrefix) || len(key) == len(EnvPrefix) {
+				continue
+			}
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				fflog.WithField("variable", key).WithField("value", value).Warn("Ignoring invalid feature flag value, use true or false.")
+				continue
+			}
+			f.set(strings.ToLower(strings.TrimPrefix(key, EnvPrefix)), SourceEnvironment, enabled)
 		}
 	}
+}
 
-	return &FeatureFlags{
-		featuresFromCfg: fFromCfg,
-		features:        fInitial,
-		lock:            sync.Mutex{},
+// WithOverrideFile sets the feature flags from the YAML file, mapping the flag names to their value. A missing file
+// is ignored, so it's only created when the flags have to be overridden locally.
+func WithOverrideFile(path string) Option {
+	return func(f *FeatureFlags) {
+		if path == "" {
+			return
+		}
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return
+		}
+		var flags map[string]bool
+		if err == nil {
+			err = yaml.Unmarshal(content, &flags)
+		}
+		if err != nil {
+			fflog.WithError(err).WithField("file", path).Warn("Cannot read the feature flags override file, it's ignored.")
+			return
+		}
+		for name, enabled := range flags {
+			f.set(name, SourceOverrideFile, enabled)
+		}
 	}
 }
 
-// SetFeatureFlag adds a new FF to the config. FFs can be defined in the agent's config file or they may come from the
-// Command Channel. As the command channel runs asynchronously, we need to lock the feature flags.
+// NewManager creates the feature flags manager from the config flags and the other local sources.
+func NewManager(initialFeatureFlags map[string]bool, opts ...Option) *FeatureFlags {
+	f := &FeatureFlags{
+		values: map[string]map[Source]bool{},
+	}
+	for key, value := range initialFeatureFlags {
+		f.set(key, SourceConfig, value)
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	for _, flag := range f.Flags() {
+		for _, o := range flag.Overridden {
+			fflog.
+				WithField("feature_flag", flag.Name).
+				WithField("enabled", flag.Enabled).
+				WithField("source", flag.Source).
+				WithField("overridden_source", o.Source).
+				WithField("overridden_enabled", o.Enabled).
+				Info("Feature flag overridden.")
+		}
+	}
+	return f
+}
+
+func (f *FeatureFlags) set(name string, source Source, enabled bool) {
+	if f.values[name] == nil {
+		f.values[name] = map[Source]bool{}
+	}
+	f.values[name][source] = enabled
+}
+
+// SetFeatureFlag sets the feature flag from the command API, unless it's set locally, as the local sources prevail.
 func (f *FeatureFlags) SetFeatureFlag(name string, enabled bool) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
 
-	// if the FF was provided by user config, that one prevails
-	if _, ok := f.featuresFromCfg[name]; ok {
-		return ErrFeatureFlagAlreadyExists
+	values := f.values[name]
+	for source := range values {
+		if source != SourceCommandAPI {
+			return ErrFeatureFlagAlreadyExists
+		}
 	}
 
 	// value from command-channel equals current state
-	if v, ok := f.features[name]; ok && v == enabled {
+	if v, ok := values[SourceCommandAPI]; ok && v == enabled {
 		return ErrFeatureFlagAlreadyExists
 	}
 
-	f.features[name] = enabled
+	f.set(name, SourceCommandAPI, enabled)
+	fflog.
+		WithField("feature_flag", name).
+		WithField("enabled", enabled).
+		WithField("source", SourceCommandAPI).
+		Info("Feature flag set.")
 	return nil
 }
 
-// GetFeatureFlag returns if a FF is enabled and exists
 func (f *FeatureFlags) GetFeatureFlag(name string) (enabled, exists bool) {
 	f.lock.Lock()
 	defer f.lock.Unlock()
 
-	enabled, exists = f.features[name]
-	return
+	flag, exists := f.flag(name)
+	return flag.Enabled, exists
+}
+
+// Flags returns the effective feature flags, sorted by name.
+func (f *FeatureFlags) Flags() []Flag {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	flags := make([]Flag, 0, len(f.values))
+	for name := range f.values {
+		if flag, ok := f.flag(name); ok {
+			flags = append(flags, flag)
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+	return flags
+}
+
+func (f *FeatureFlags) flag(name string) (flag Flag, exists bool) {
+	values := f.values[name]
+	for _, source := range precedence {
+		enabled, ok := values[source]
+		if !ok {
+			continue
+		}
+		if !exists {
+			flag, exists = Flag{Name: name, Enabled: enabled, Source: source}, true
+			continue
+		}
+		flag.Overridden = append(flag.Overridden, Override{Source: source, Enabled: enabled})
+	}
+	return flag, exists
 }
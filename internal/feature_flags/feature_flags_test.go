@@ -3,9 +3,12 @@
 package feature_flags
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_FeatureFlags_WithNoInitialFeatures(t *testing.T) {
@@ -54,3 +57,41 @@ func TestConfig_FeatureFlags_WithInitialFeatures(t *testing.T) {
 	enabled, _ := f.GetFeatureFlag("foo")
 	assert.True(t, enabled)
 }
+
+func TestFeatureFlags_Precedence(t *testing.T) {
+	overrideFile := filepath.Join(t.TempDir(), "feature_flags.yml")
+	require.NoError(t, os.WriteFile(overrideFile, []byte("foo: false\n"), 0o600))
+	environ := []string{"NRIA_FEATURE_FOO=true", "NRIA_FEATURE_BAR=false", "NRIA_FEATURE_BAZ=invalid", "NRIA_LICENSE_KEY=abc"}
+
+	f := NewManager(map[string]bool{"foo": true, "qux": true}, WithEnvironment(environ), WithOverrideFile(overrideFile))
+
+	// the override file prevails over the environment, which prevails over the config
+	enabled, exists := f.GetFeatureFlag("foo")
+	assert.True(t, exists)
+	assert.False(t, enabled)
+	enabled, exists = f.GetFeatureFlag("bar")
+	assert.True(t, exists)
+	assert.False(t, enabled)
+	_, exists = f.GetFeatureFlag("baz")
+	assert.False(t, exists)
+
+	// the command API doesn't change flags set locally
+	assert.Equal(t, ErrFeatureFlagAlreadyExists, f.SetFeatureFlag("bar", true))
+	assert.NoError(t, f.SetFeatureFlag("quux", true))
+
+	assert.Equal(t, []Flag{
+		{Name: "bar", Enabled: false, Source: SourceEnvironment},
+		{Name: "foo", Enabled: false, Source: SourceOverrideFile, Overridden: []Override{
+			{Source: SourceEnvironment, Enabled: true},
+			{Source: SourceConfig, Enabled: true},
+		}},
+		{Name: "quux", Enabled: true, Source: SourceCommandAPI},
+		{Name: "qux", Enabled: true, Source: SourceConfig},
+	}, f.Flags())
+}
+
+func TestFeatureFlags_MissingOverrideFile(t *testing.T) {
+	f := NewManager(map[string]bool{"foo": true}, WithOverrideFile(filepath.Join(t.TempDir(), "missing.yml")))
+
+	assert.Equal(t, []Flag{{Name: "foo", Enabled: true, Source: SourceConfig}}, f.Flags())
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package fips reports whether the agent uses a FIPS 140 validated cryptographic backend. The agent is built in
+// FIPS mode with the fips build tag and a Go toolchain experiment replacing the Go crypto: boringcrypto on Linux,
+// or systemcrypto (CNG) with the Microsoft Go toolchain on Windows. In FIPS mode TLS is restricted to the FIPS
+// approved versions, cipher suites and curves.
+package fips
+
+import (
+	"errors"
+	"runtime/debug"
+	"strings"
+)
+
+// ErrNotEnabled is returned when FIPS mode is required but the agent doesn't use a FIPS validated backend.
+var ErrNotEnabled = errors.New("FIPS mode is required but the agent is not running with a FIPS 140 validated crypto backend, install the FIPS build of the agent")
+
+// Report is the FIPS status of the agent.
+type Report struct {
+	// Build is whether the agent was built in FIPS mode.
+	Build bool `json:"build"`
+	// Enabled is whether the cryptographic operations are run by the FIPS validated backend.
+	Enabled bool `json:"enabled"`
+	// Backend is the Go experiment providing the crypto backend the agent was built with, ie: boringcrypto.
+	Backend string `json:"backend,omitempty"`
+}
+
+// Status returns the FIPS status of the agent.
+func Status() Report {
+	return Report{
+		Build:   buildEnabled,
+		Enabled: buildEnabled && backendEnabled(),
+		Backend: cryptoExperiment(),
+	}
+}
+
+// Verify checks that the FIPS validated backend is in use when the agent is built in FIPS mode, or when FIPS mode is
+// required by the configuration.
+func Verify(required bool) error {
+	status := Status()
+	if status.Build && !status.Enabled {
+		return errors.New("the agent is built in FIPS mode but its crypto backend is not enabled")
+	}
+	if required && !status.Enabled {
+		return ErrNotEnabled
+	}
+	return nil
+}
+
+// cryptoExperiment returns the crypto backend among the Go experiments the agent was built with.
+func cryptoExperiment() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key != "GOEXPERIMENT" {
+			continue
+		}
+		for _, experiment := range strings.Split(setting.Value, ",") {
+			if strings.HasSuffix(experiment, "crypto") {
+				return experiment
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,12 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build !fips
+// +build !fips
+
+package fips
+
+const buildEnabled = false
+
+func backendEnabled() bool {
+	return false
+}
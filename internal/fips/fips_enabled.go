@@ -0,0 +1,18 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build fips
+// +build fips
+
+package fips
+
+import (
+	"crypto/boring"
+	// restricts TLS to the FIPS approved settings
+	_ "crypto/tls/fipsonly"
+)
+
+const buildEnabled = true
+
+func backendEnabled() bool {
+	return boring.Enabled()
+}
@@ -0,0 +1,20 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build fips
+// +build fips
+
+package fips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Run with: GOEXPERIMENT=boringcrypto CGO_ENABLED=1 go test -tags fips ./internal/fips/
+func TestStatus_FIPSBuild(t *testing.T) {
+	status := Status()
+	assert.True(t, status.Build)
+	assert.True(t, status.Enabled)
+	assert.NoError(t, Verify(true))
+}
@@ -0,0 +1,23 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build !fips
+// +build !fips
+
+package fips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus_StandardBuild(t *testing.T) {
+	status := Status()
+	assert.False(t, status.Build)
+	assert.False(t, status.Enabled)
+}
+
+func TestVerify_StandardBuild(t *testing.T) {
+	assert.NoError(t, Verify(false))
+	assert.ErrorIs(t, Verify(true), ErrNotEnabled)
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/internal/agent/status"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/integration"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/emitter"
+	"github.com/newrelic/infrastructure-agent/pkg/ipc"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 	"github.com/sirupsen/logrus"
 )
@@ -29,6 +30,12 @@ const (
 	statusAPIPath              = "/v1/status"
 	statusOnlyErrorsAPIPath    = "/v1/status/errors"
 	statusEntityAPIPath        = "/v1/status/entity"
+	statusHealthAPIPath        = "/v1/status/health"
+	statusIntegrationsAPIPath  = "/v1/status/integrations"
+	statusQuarantineAPIPath    = "/v1/status/integrations/quarantined"
+	statusSamplersAPIPath      = "/v1/status/samplers"
+	statusFeatureFlagsAPIPath  = "/v1/status/feature_flags"
+	statusCapabilitiesAPIPath  = "/v1/status/capabilities"
 	statusAPIPathReady         = "/v1/status/ready"
 	ingestAPIPath              = "/v1/data"
 	ingestAPIPathReady         = "/v1/data/ready"
@@ -45,22 +52,30 @@ type responseError struct {
 
 // Server runtime for status API server.
 type Server struct {
-	Ingest        ComponentConfig
-	Status        ComponentConfig
-	reporter      status.Reporter
-	logger        log.Entry
-	definition    integration.Definition
-	emitter       emitter.Emitter
-	statusReadyCh chan struct{}
-	ingestReadyCh chan struct{}
-	timeout       time.Duration
+	Ingest            ComponentConfig
+	Status            ComponentConfig
+	Webhook           ComponentConfig
+	Metrics           ComponentConfig
+	reporter          status.Reporter
+	logger            log.Entry
+	definition        integration.Definition
+	webhookDefinition integration.Definition
+	emitter           emitter.Emitter
+	statusReadyCh     chan struct{}
+	ingestReadyCh     chan struct{}
+	webhookReadyCh    chan struct{}
+	metricsReadyCh    chan struct{}
+	metricsHandler    http.Handler
+	timeout           time.Duration
 }
 
 // ComponentConfig stores configuration for a server component.
 type ComponentConfig struct {
 	enabled bool
 	address string
+	socket  string
 	tls     tlsConfig
+	token   string
 }
 
 // tlsConfig stores tls-related configuration.
@@ -78,6 +93,14 @@ func (sc *ComponentConfig) Enable(host string, port int) {
 	sc.address = net.JoinHostPort(host, fmt.Sprint(port))
 }
 
+// EnableSocket configures and enables a server component on a local socket only accessible by the agent user, without
+// opening a TCP port: a unix domain socket path on Linux and macOS, a named pipe on Windows. It can be combined with
+// Enable.
+func (sc *ComponentConfig) EnableSocket(path string) {
+	sc.enabled = true
+	sc.socket = path
+}
+
 // TLS configures and enables TLS for a server component.
 func (sc *ComponentConfig) TLS(certPath, keyPath string) {
 	sc.tls.enabled = true
@@ -85,6 +108,11 @@ func (sc *ComponentConfig) TLS(certPath, keyPath string) {
 	sc.tls.keyPath = keyPath
 }
 
+// Token configures the bearer token required by a server component to authenticate requests.
+func (sc *ComponentConfig) Token(token string) {
+	sc.token = token
+}
+
 // VerifyTLSClient configures and enables TLS client certificate validation for a server component.
 func (sc *ComponentConfig) VerifyTLSClient(caCertPath string) {
 	sc.tls.validateClient = true
@@ -100,26 +128,34 @@ func NewServer(r status.Reporter, em emitter.Emitter) (*Server, error) {
 		return nil, fmt.Errorf("cannot create API definition for HTTP API server, err: %s", err)
 	}
 
+	wd, err := integration.NewAPIDefinition(webhookIntegrationName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create API definition for webhook server, err: %s", err)
+	}
+
 	return &Server{
-		logger:        log.WithComponent(componentName),
-		reporter:      r,
-		definition:    d,
-		emitter:       em,
-		ingestReadyCh: make(chan struct{}),
-		statusReadyCh: make(chan struct{}),
-		timeout:       readinessProbeTimeout,
+		logger:            log.WithComponent(componentName),
+		reporter:          r,
+		definition:        d,
+		webhookDefinition: wd,
+		emitter:           em,
+		ingestReadyCh:     make(chan struct{}),
+		statusReadyCh:     make(chan struct{}),
+		webhookReadyCh:    make(chan struct{}),
+		metricsReadyCh:    make(chan struct{}),
+		timeout:           readinessProbeTimeout,
 	}, nil
 }
 
-// Serve serves status API requests and ingest.
+// Serve serves status API requests, ingest, webhook and metrics.
 // Nice2Have: context cancellation.
 func (s *Server) Serve(ctx context.Context) {
-	if !s.Status.enabled && !s.Ingest.enabled {
+	if !s.Status.enabled && !s.Ingest.enabled && !s.Webhook.enabled && !s.Metrics.enabled {
 		return
 	}
 
 	var serversWg sync.WaitGroup
-	var statusErr, ingestErr error
+	var statusErr, ingestErr, webhookErr, metricsErr error
 
 	if s.Status.enabled {
 		serversWg.Add(1)
@@ -149,9 +185,37 @@ func (s *Server) Serve(ctx context.Context) {
 		close(s.ingestReadyCh)
 	}
 
+	if s.Webhook.enabled {
+		serversWg.Add(1)
+		go func() {
+			webhookErr = s.serveWebhook()
+			if webhookErr != nil {
+				s.logger.WithError(webhookErr).Error("error serving agent webhook")
+			}
+			close(s.webhookReadyCh)
+			serversWg.Done()
+		}()
+	} else {
+		close(s.webhookReadyCh)
+	}
+
+	if s.Metrics.enabled {
+		serversWg.Add(1)
+		go func() {
+			metricsErr = s.serveMetrics()
+			if metricsErr != nil {
+				s.logger.WithError(metricsErr).Error("error serving agent metrics")
+			}
+			close(s.metricsReadyCh)
+			serversWg.Done()
+		}()
+	} else {
+		close(s.metricsReadyCh)
+	}
+
 	serversWg.Wait()
 
-	if statusErr != nil && ingestErr != nil {
+	if statusErr != nil && ingestErr != nil && (!s.Webhook.enabled || webhookErr != nil) && (!s.Metrics.enabled || metricsErr != nil) {
 		return
 	}
 
@@ -159,7 +223,39 @@ func (s *Server) Serve(ctx context.Context) {
 }
 
 // serveStatus serves status API requests.
-func (s *Server) serveStatus(_ context.Context) error {
+func (s *Server) serveStatus(ctx context.Context) error {
+	router := httprouter.New()
+	// read only API
+	router.GET(statusAPIPathReady, s.handleReady)
+	router.GET(statusEntityAPIPath, s.handleEntity)
+	router.GET(statusAPIPath, s.handle(false))
+	router.GET(statusOnlyErrorsAPIPath, s.handle(true))
+	router.GET(statusHealthAPIPath, s.handleHealth)
+	router.GET(statusIntegrationsAPIPath, s.handleReport("integrations", func() (interface{}, error) {
+		return s.reporter.ReportIntegrations()
+	}))
+	router.GET(statusQuarantineAPIPath, s.handleReport("quarantine", func() (interface{}, error) {
+		return s.reporter.ReportQuarantine()
+	}))
+	router.GET(statusSamplersAPIPath, s.handleReport("samplers", func() (interface{}, error) {
+		return s.reporter.ReportSamplers()
+	}))
+	router.GET(statusFeatureFlagsAPIPath, s.handleReport("feature flags", func() (interface{}, error) {
+		return s.reporter.ReportFeatureFlags()
+	}))
+	router.GET(statusCapabilitiesAPIPath, s.handleReport("capabilities", func() (interface{}, error) {
+		return s.reporter.ReportCapabilities()
+	}))
+
+	if s.Status.socket != "" {
+		if err := s.serveLocal(ctx, "Status API", s.Status.socket, router); err != nil {
+			return err
+		}
+		if s.Status.address == "" {
+			return nil
+		}
+	}
+
 	statusServerErr := make(chan error, 1)
 
 	go func() {
@@ -168,12 +264,6 @@ func (s *Server) serveStatus(_ context.Context) error {
 			"address": s.Status.address,
 		}).Debug("Status API starting listening.")
 
-		router := httprouter.New()
-		// read only API
-		router.GET(statusAPIPathReady, s.handleReady)
-		router.GET(statusEntityAPIPath, s.handleEntity)
-		router.GET(statusAPIPath, s.handle(false))
-		router.GET(statusOnlyErrorsAPIPath, s.handle(true))
 		// local only API
 		err := http.ListenAndServe(s.Status.address, router)
 		statusServerErr <- err
@@ -190,8 +280,42 @@ func (s *Server) serveStatus(_ context.Context) error {
 	return s.waitUntilReadyOrError(s.Status.address, statusAPIPathReady, s.Status.tls.enabled, s.Status.tls.validateClient, statusServerErr)
 }
 
+// serveLocal serves the handler on a local socket until the context is cancelled. Unlike the TCP servers, the socket
+// is ready to accept connections once this returns without error.
+func (s *Server) serveLocal(ctx context.Context, name, socket string, handler http.Handler) error {
+	l, err := ipc.Listen(socket)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s socket %s: %w", name, socket, err)
+	}
+	server := &http.Server{Handler: handler}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	go func() {
+		s.logger.WithField("socket", socket).Debug(name + " listening on local socket.")
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.WithError(err).Error(name + " local socket server error")
+		}
+	}()
+	return nil
+}
+
 // serveIngest creates and starts an HTTP server handling ingestAPIPathReady and ingestAPIPath using Config.Ingest
-func (s *Server) serveIngest(_ context.Context) error {
+func (s *Server) serveIngest(ctx context.Context) error {
+	router := httprouter.New()
+	router.GET(ingestAPIPathReady, s.handleReady)
+	router.POST(ingestAPIPath, s.handleIngest)
+
+	if s.Ingest.socket != "" {
+		if err := s.serveLocal(ctx, "Ingest API", s.Ingest.socket, router); err != nil {
+			return err
+		}
+		if s.Ingest.address == "" {
+			return nil
+		}
+	}
+
 	serverErr := make(chan error, 1)
 
 	go func() {
@@ -200,10 +324,6 @@ func (s *Server) serveIngest(_ context.Context) error {
 			"address": s.Ingest.address,
 		}).Debug("Ingest API starting listening.")
 
-		router := httprouter.New()
-		router.GET(ingestAPIPathReady, s.handleReady)
-		router.POST(ingestAPIPath, s.handleIngest)
-
 		server := &http.Server{
 			Handler: router,
 			Addr:    s.Ingest.address,
@@ -302,6 +422,8 @@ func (s *Server) isGetSuccessful(c http.Client, URL string) bool {
 func (s *Server) waitUntilReady() {
 	<-s.ingestReadyCh
 	<-s.statusReadyCh
+	<-s.webhookReadyCh
+	<-s.metricsReadyCh
 }
 
 // handle returns a HTTP handler function for full status report or just errors status report.
@@ -356,7 +478,8 @@ func (s *Server) handleEntity(w http.ResponseWriter, r *http.Request, ps httprou
 		return
 	}
 
-	if re.GUID == "" {
+	// the stored ID is reported before the agent is registered, to check the identity it will be registered with
+	if re.GUID == "" && re.StoredID.IsEmpty() {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
@@ -377,6 +500,50 @@ func (s *Server) handleEntity(w http.ResponseWriter, r *http.Request, ps httprou
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleHealth returns 200 when the agent is healthy and 503 otherwise, both with the health report.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rep, err := s.reporter.ReportHealth()
+	if err != nil {
+		s.logger.WithError(err).Error("cannot report health Status")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	code := http.StatusOK
+	if !rep.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+	s.writeJSON(w, code, rep)
+}
+
+// handleReport returns a HTTP handler function for the runtime reports, ie: integrations or samplers.
+func (s *Server) handleReport(name string, report func() (interface{}, error)) func(http.ResponseWriter, *http.Request, httprouter.Params) {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		rep, err := report()
+		if err != nil {
+			s.logger.WithError(err).WithField("report", name).Error("cannot report Status")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, rep)
+	}
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		s.logger.WithError(err).Warn("couldn't encode Status report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if _, err = w.Write(b); err != nil {
+		s.logger.Warn("cannot write Status response, error: " + err.Error())
+	}
+}
+
 func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	rawBody, err := ioutil.ReadAll(r.Body)
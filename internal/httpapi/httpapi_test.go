@@ -17,6 +17,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/newrelic/infrastructure-agent/internal/agent/capabilities"
 	"github.com/newrelic/infrastructure-agent/internal/agent/id"
 	"github.com/newrelic/infrastructure-agent/internal/agent/status"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/testhelp/testemit"
@@ -24,6 +25,7 @@ import (
 	networkHelpers "github.com/newrelic/infrastructure-agent/pkg/helpers/network"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/fixtures"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sampler"
 	logHelper "github.com/newrelic/infrastructure-agent/test/log"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -63,7 +65,7 @@ func (suite *HTTPAPITestSuite) TestServe_Status() {
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	r := status.NewReporter(ctx, logger, endpoints, timeout, transport, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key")
+	r := status.NewReporter(ctx, logger, endpoints, timeout, transport, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key", nil)
 
 	// When agent status API server is ready
 	em := &testemit.RecordEmitter{}
@@ -124,7 +126,7 @@ func (suite *HTTPAPITestSuite) TestServe_OnlyErrors() {
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	r := status.NewReporter(ctx, logger, endpoints, timeout, transport, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key")
+	r := status.NewReporter(ctx, logger, endpoints, timeout, transport, emptyIDProvide, emptyEntityKeyProvider, "user-agent", "agent-key", nil)
 
 	// When agent status API server is ready
 	em := &testemit.RecordEmitter{}
@@ -191,7 +193,7 @@ func (suite *HTTPAPITestSuite) TestServe_Entity() {
 			port, err := networkHelpers.TCPPort()
 			require.NoError(t, err)
 
-			r := status.NewReporter(ctx, logger, []string{}, timeout, transport, tt.idProvide, emptyEntityKeyProvider, "user-agent", "agent-key")
+			r := status.NewReporter(ctx, logger, []string{}, timeout, transport, tt.idProvide, emptyEntityKeyProvider, "user-agent", "agent-key", nil)
 			// When agent status API server is ready
 			em := &testemit.RecordEmitter{}
 			s, err := NewServer(r, em)
@@ -224,6 +226,87 @@ func (suite *HTTPAPITestSuite) TestServe_Entity() {
 	}
 }
 
+func (suite *HTTPAPITestSuite) TestServe_Health() {
+	port, err := networkHelpers.TCPPort()
+	require.NoError(suite.T(), err)
+
+	queues := status.QueuesReport{EventQueueSize: 10, EventQueueCapacity: 10, BatchQueueCapacity: 200}
+	samplers := []sampler.SamplerReport{{Name: "ProcessSampler", IntervalSec: 20, Started: time.Now()}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := status.NewReporter(ctx, log.WithComponent(suite.T().Name()), nil, time.Second, &http.Transport{},
+		func() entity.Identity { return entity.EmptyIdentity }, func() string { return "" }, "user-agent", "agent-key", nil,
+		status.WithSenderQueues(func() (status.QueuesReport, bool) { return queues, true }),
+		status.WithSamplers(func() []sampler.SamplerReport { return samplers }),
+		status.WithCapabilities(func() capabilities.Matrix {
+			return capabilities.Matrix{
+				RunMode:      "root",
+				User:         "root",
+				Capabilities: []capabilities.Capability{{Name: "process_fd_count", Requires: "CAP_SYS_PTRACE", Affects: []string{"ProcessSample.fileDescriptorCount"}, Reason: "missing CAP_SYS_PTRACE capability"}},
+			}
+		}))
+
+	s, err := NewServer(r, &testemit.RecordEmitter{})
+	require.NoError(suite.T(), err)
+	s.Status.Enable("localhost", port)
+	go s.Serve(ctx)
+	s.waitUntilReady()
+
+	// the full event queue makes the agent unhealthy
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d%s", port, statusHealthAPIPath))
+	require.NoError(suite.T(), err)
+	defer res.Body.Close()
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, res.StatusCode)
+	var health status.HealthReport
+	require.NoError(suite.T(), json.NewDecoder(res.Body).Decode(&health))
+	assert.False(suite.T(), health.Healthy)
+	assert.Equal(suite.T(), []string{"event queue full with 10 events"}, health.Problems)
+	assert.Equal(suite.T(), &queues, health.Queues)
+
+	res, err = http.Get(fmt.Sprintf("http://localhost:%d%s", port, statusSamplersAPIPath))
+	require.NoError(suite.T(), err)
+	defer res.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, res.StatusCode)
+	var samplersReport status.SamplersReport
+	require.NoError(suite.T(), json.NewDecoder(res.Body).Decode(&samplersReport))
+	require.Len(suite.T(), samplersReport.Samplers, 1)
+	assert.Equal(suite.T(), "ProcessSampler", samplersReport.Samplers[0].Name)
+
+	res, err = http.Get(fmt.Sprintf("http://localhost:%d%s", port, statusIntegrationsAPIPath))
+	require.NoError(suite.T(), err)
+	defer res.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, res.StatusCode)
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(suite.T(), err)
+	assert.JSONEq(suite.T(), `{"integrations": []}`, string(body))
+
+	res, err = http.Get(fmt.Sprintf("http://localhost:%d%s", port, statusFeatureFlagsAPIPath))
+	require.NoError(suite.T(), err)
+	defer res.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, res.StatusCode)
+	body, err = ioutil.ReadAll(res.Body)
+	require.NoError(suite.T(), err)
+	assert.JSONEq(suite.T(), `{"feature_flags": []}`, string(body))
+
+	res, err = http.Get(fmt.Sprintf("http://localhost:%d%s", port, statusCapabilitiesAPIPath))
+	require.NoError(suite.T(), err)
+	defer res.Body.Close()
+	assert.Equal(suite.T(), http.StatusOK, res.StatusCode)
+	body, err = ioutil.ReadAll(res.Body)
+	require.NoError(suite.T(), err)
+	assert.JSONEq(suite.T(), `{
+		"run_mode": "root",
+		"user": "root",
+		"capabilities": [{
+			"name": "process_fd_count",
+			"available": false,
+			"requires": "CAP_SYS_PTRACE",
+			"affects": ["ProcessSample.fileDescriptorCount"],
+			"reason": "missing CAP_SYS_PTRACE capability"
+		}]
+	}`, string(body))
+}
+
 func (suite *HTTPAPITestSuite) TestServe_IngestData() {
 	port, err := networkHelpers.TCPPort()
 	require.NoError(suite.T(), err)
@@ -463,3 +546,27 @@ func (r *noopReporter) ReportErrors() (status.Report, error) {
 func (r *noopReporter) ReportEntity() (re status.ReportEntity, err error) {
 	return status.ReportEntity{}, nil
 }
+
+func (r *noopReporter) ReportHealth() (status.HealthReport, error) {
+	return status.HealthReport{Healthy: true}, nil
+}
+
+func (r *noopReporter) ReportIntegrations() (status.IntegrationsReport, error) {
+	return status.IntegrationsReport{}, nil
+}
+
+func (r *noopReporter) ReportSamplers() (status.SamplersReport, error) {
+	return status.SamplersReport{}, nil
+}
+
+func (r *noopReporter) ReportFeatureFlags() (status.FeatureFlagsReport, error) {
+	return status.FeatureFlagsReport{}, nil
+}
+
+func (r *noopReporter) ReportQuarantine() (status.QuarantineReport, error) {
+	return status.QuarantineReport{}, nil
+}
+
+func (r *noopReporter) ReportCapabilities() (status.CapabilitiesReport, error) {
+	return status.CapabilitiesReport{}, nil
+}
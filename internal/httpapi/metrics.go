@@ -0,0 +1,55 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+)
+
+const metricsAPIPath = "/metrics"
+
+var errMetricsNoHandler = errors.New("metrics endpoint requires a metrics handler")
+
+// MetricsHandler sets the handler serving the agent metrics in the Prometheus format.
+func (s *Server) MetricsHandler(handler http.Handler) {
+	s.metricsHandler = handler
+}
+
+// serveMetrics creates and starts an HTTP server handling metricsAPIPath using Config.Metrics. The metrics path is
+// also probed for readiness, so scrapers don't need a different one.
+func (s *Server) serveMetrics() error {
+	if s.metricsHandler == nil {
+		return errMetricsNoHandler
+	}
+
+	serverErr := make(chan error, 1)
+
+	go func() {
+		defer close(serverErr)
+		s.logger.WithFields(logrus.Fields{
+			"address": s.Metrics.address,
+		}).Debug("Metrics API starting listening.")
+
+		router := httprouter.New()
+		router.Handler(http.MethodGet, metricsAPIPath, s.metricsHandler)
+
+		server := &http.Server{
+			Handler:           router,
+			Addr:              s.Metrics.address,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		err := server.ListenAndServe()
+		if err != nil {
+			s.logger.WithError(err).Error("Metrics server error")
+		}
+		serverErr <- err
+	}()
+
+	return s.waitUntilReadyOrError(s.Metrics.address, metricsAPIPath, false, false, serverErr)
+}
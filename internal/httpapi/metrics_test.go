@@ -0,0 +1,51 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/testhelp/testemit"
+	networkHelpers "github.com/newrelic/infrastructure-agent/pkg/helpers/network"
+)
+
+func (suite *HTTPAPITestSuite) TestServe_Metrics() {
+	port, err := networkHelpers.TCPPort()
+	require.NoError(suite.T(), err)
+
+	s, err := NewServer(&noopReporter{}, &testemit.RecordEmitter{})
+	require.NoError(suite.T(), err)
+	s.Metrics.Enable("localhost", port)
+	s.MetricsHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("newrelic_infra_system_sample_cpu_percent 1.5\n"))
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go s.Serve(ctx)
+	s.waitUntilReady()
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d%s", port, metricsAPIPath))
+	require.NoError(suite.T(), err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(suite.T(), err)
+
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), "newrelic_infra_system_sample_cpu_percent 1.5\n", string(body))
+}
+
+func (suite *HTTPAPITestSuite) TestServe_MetricsRequiresHandler() {
+	s, err := NewServer(&noopReporter{}, &testemit.RecordEmitter{})
+	require.NoError(suite.T(), err)
+	s.Metrics.Enable("localhost", 0)
+
+	assert.ErrorIs(suite.T(), s.serveMetrics(), errMetricsNoHandler)
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux || darwin
+// +build linux darwin
+
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/status"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/testhelp/testemit"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/fixtures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func socketClient(socket string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+}
+
+func TestServe_LocalSockets(t *testing.T) {
+	dir := t.TempDir()
+	statusSocket, ingestSocket := filepath.Join(dir, "status.sock"), filepath.Join(dir, "ingest.sock")
+
+	// GIVEN the status and ingest APIs served only on local sockets
+	em := &testemit.RecordEmitter{}
+	s, err := NewServer(&noopReporter{}, em)
+	require.NoError(t, err)
+	s.Status.EnableSocket(statusSocket)
+	s.Ingest.EnableSocket(ingestSocket)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Serve(ctx)
+	s.waitUntilReady()
+
+	// THEN the sockets are only accessible by the agent user
+	info, err := os.Stat(statusSocket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// AND the status API is served
+	resp, err := socketClient(statusSocket).Get("http://localhost" + statusHealthAPIPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var health status.HealthReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	assert.True(t, health.Healthy)
+
+	// AND the integration payloads are ingested
+	resp, err = socketClient(ingestSocket).Post("http://localhost"+ingestAPIPath, "application/json", bytes.NewReader(fixtures.FooBytes))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 20, resp.StatusCode/10, "status code: %v", resp.StatusCode)
+	d, err := em.ReceiveFrom(IntegrationName)
+	require.NoError(t, err)
+	assert.Equal(t, "unique foo", d.DataSet.PluginDataSet.Entity.Name)
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package httpapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/protocol"
+)
+
+const (
+	webhookIntegrationName    = "webhook"
+	webhookIntegrationVersion = "1.0.0"
+	webhookAPIPath            = "/v1/events"
+	webhookAPIPathReady       = "/v1/events/ready"
+	// webhookMaxPayloadBytes limits payloads to small custom events and metrics.
+	webhookMaxPayloadBytes = 256 * 1024
+	bearerPrefix           = "Bearer "
+)
+
+var (
+	errWebhookEmptyPayload = errors.New("payload has neither events nor metrics")
+	errWebhookNoToken      = errors.New("webhook requires an authentication token")
+)
+
+// WebhookPayload is the body accepted by the webhook, a simplified version of the integrations
+// protocol v4 dataset: events require a "summary" and might override the default "eventType".
+// Data is attached to the host entity.
+type WebhookPayload struct {
+	Events  []protocol.EventData `json:"events"`
+	Metrics []protocol.Metric    `json:"metrics"`
+}
+
+// serveWebhook creates and starts an HTTP server handling webhookAPIPathReady and webhookAPIPath using Config.Webhook.
+func (s *Server) serveWebhook() error {
+	if s.Webhook.token == "" {
+		return errWebhookNoToken
+	}
+
+	serverErr := make(chan error, 1)
+
+	go func() {
+		defer close(serverErr)
+		s.logger.WithFields(logrus.Fields{
+			"address": s.Webhook.address,
+		}).Debug("Webhook API starting listening.")
+
+		router := httprouter.New()
+		router.GET(webhookAPIPathReady, s.handleReady)
+		router.POST(webhookAPIPath, s.authenticated(s.Webhook.token, s.handleWebhook))
+
+		server := &http.Server{
+			Handler:           router,
+			Addr:              s.Webhook.address,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		err := server.ListenAndServe()
+		if err != nil {
+			s.logger.WithError(err).Error("Webhook server error")
+		}
+		serverErr <- err
+	}()
+
+	return s.waitUntilReadyOrError(s.Webhook.address, webhookAPIPathReady, false, false, serverErr)
+}
+
+// authenticated only allows requests providing the bearer token.
+func (s *Server) authenticated(token string, handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		provided, isBearer := strings.CutPrefix(r.Header.Get("Authorization"), bearerPrefix)
+		if !isBearer || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			s.logger.WithField("remoteAddr", r.RemoteAddr).Debug("Unauthorized webhook request.")
+			s.writeError(w, http.StatusUnauthorized, "invalid or missing authentication token")
+			return
+		}
+		handle(w, r, ps)
+	}
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rawBody, err := io.ReadAll(http.MaxBytesReader(w, r.Body, webhookMaxPayloadBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("payload exceeds %d bytes", webhookMaxPayloadBytes))
+			return
+		}
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot read HTTP payload: %s", err))
+		return
+	}
+
+	payload, err := webhookPayloadToV4(rawBody)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid payload: %s", err))
+		return
+	}
+
+	err = s.emitter.Emit(s.webhookDefinition, nil, nil, payload)
+	if err != nil {
+		s.logger.WithError(err).Warn("cannot emit webhook payload")
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("cannot emit payload: %s", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// webhookPayloadToV4 validates the webhook payload and converts it into an integrations protocol v4 one.
+func webhookPayloadToV4(raw []byte) ([]byte, error) {
+	var p WebhookPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Events) == 0 && len(p.Metrics) == 0 {
+		return nil, errWebhookEmptyPayload
+	}
+
+	for i, e := range p.Events {
+		if _, ok := e["summary"]; !ok {
+			return nil, fmt.Errorf("event %d: missing required 'summary' field", i)
+		}
+	}
+
+	for i, m := range p.Metrics {
+		if m.Name == "" || m.Type == "" || len(m.Value) == 0 {
+			return nil, fmt.Errorf("metric %d: 'name', 'type' and 'value' fields are required", i)
+		}
+	}
+
+	data := protocol.NewData(webhookIntegrationName, webhookIntegrationVersion, []protocol.Dataset{
+		{
+			Events:  p.Events,
+			Metrics: p.Metrics,
+		},
+	})
+
+	return json.Marshal(data)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, statusCode int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(responseError{Error: msg}); err != nil {
+		s.logger.WithError(err).Warn("couldn't encode a failed response")
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/testhelp/testemit"
+	networkHelpers "github.com/newrelic/infrastructure-agent/pkg/helpers/network"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/protocol"
+)
+
+const webhookPayload = `{
+  "events": [{"eventType": "BackupJob", "summary": "backup finished", "attributes": {"durationSec": 12}}],
+  "metrics": [{"name": "backup.size", "type": "gauge", "value": 1024}]
+}`
+
+func TestWebhookPayloadToV4(t *testing.T) {
+	raw, err := webhookPayloadToV4([]byte(webhookPayload))
+	require.NoError(t, err)
+
+	var data protocol.DataV4
+	require.NoError(t, json.Unmarshal(raw, &data))
+
+	version, err := protocol.VersionFromPayload(raw, false)
+	require.NoError(t, err)
+	assert.Equal(t, protocol.V4, version)
+	assert.Equal(t, webhookIntegrationName, data.Integration.Name)
+
+	require.Len(t, data.DataSets, 1)
+	ds := data.DataSets[0]
+	assert.Empty(t, ds.Entity.Name, "data is attached to the host entity")
+	require.Len(t, ds.Events, 1)
+	assert.Equal(t, "BackupJob", ds.Events[0]["eventType"])
+	require.Len(t, ds.Metrics, 1)
+	assert.Equal(t, "backup.size", ds.Metrics[0].Name)
+}
+
+func TestWebhookPayloadToV4_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		err     string
+	}{
+		{"not json", `foo`, "invalid character"},
+		{"empty", `{}`, errWebhookEmptyPayload.Error()},
+		{"event without summary", `{"events": [{"eventType": "Foo"}]}`, "event 0: missing required 'summary' field"},
+		{"metric without value", `{"metrics": [{"name": "foo", "type": "gauge"}]}`, "metric 0: 'name', 'type' and 'value' fields are required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := webhookPayloadToV4([]byte(tt.payload))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func (suite *HTTPAPITestSuite) TestServe_Webhook() {
+	port, err := networkHelpers.TCPPort()
+	require.NoError(suite.T(), err)
+
+	em := &testemit.RecordEmitter{}
+	s, err := NewServer(&noopReporter{}, em)
+	require.NoError(suite.T(), err)
+	s.Webhook.Enable("localhost", port)
+	s.Webhook.Token("secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go s.Serve(ctx)
+	s.waitUntilReady()
+
+	postAuthorized := func(authorization string, body []byte) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:%d%s", port, webhookAPIPath), bytes.NewReader(body))
+		require.NoError(suite.T(), err)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(suite.T(), err)
+		resp.Body.Close()
+		return resp
+	}
+	post := func(token string, body []byte) *http.Response {
+		if token == "" {
+			return postAuthorized("", body)
+		}
+		return postAuthorized("Bearer "+token, body)
+	}
+
+	assert.Equal(suite.T(), http.StatusUnauthorized, post("", []byte(webhookPayload)).StatusCode)
+	// the token is only accepted as a bearer one
+	assert.Equal(suite.T(), http.StatusUnauthorized, postAuthorized("secret", []byte(webhookPayload)).StatusCode)
+	assert.Equal(suite.T(), http.StatusUnauthorized, post("wrong", []byte(webhookPayload)).StatusCode)
+	assert.Equal(suite.T(), http.StatusBadRequest, post("secret", []byte(`{}`)).StatusCode)
+
+	tooLarge := []byte(`{"events": [{"summary": "` + strings.Repeat("a", webhookMaxPayloadBytes) + `"}]}`)
+	assert.Equal(suite.T(), http.StatusRequestEntityTooLarge, post("secret", tooLarge).StatusCode)
+
+	require.Equal(suite.T(), http.StatusNoContent, post("secret", []byte(webhookPayload)).StatusCode)
+
+	d, err := em.ReceiveFrom(webhookIntegrationName)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), d.DataSet.Events, 1)
+	assert.Equal(suite.T(), "backup finished", d.DataSet.Events[0]["summary"])
+	assert.Len(suite.T(), d.DataSet.Metrics, 1)
+}
+
+func (suite *HTTPAPITestSuite) TestServe_WebhookRequiresToken() {
+	em := &testemit.RecordEmitter{}
+	s, err := NewServer(&noopReporter{}, em)
+	require.NoError(suite.T(), err)
+	s.Webhook.Enable("localhost", 0)
+
+	assert.ErrorIs(suite.T(), s.serveWebhook(), errWebhookNoToken)
+}
@@ -0,0 +1,219 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// PrometheusPrefix prefixes the names of the metrics exposed by the agent.
+	PrometheusPrefix = "newrelic_infra"
+	// instrumentationPrefix prefixes the agent instrumentation metrics, ie: newrelic_infra_instrumentation_logged_errors
+	instrumentationPrefix = PrometheusPrefix + "_instrumentation"
+	// prometheusContentType is the text exposition format version 0.0.4.
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// Prometheus is an Instrumenter exposing the agent metrics in the Prometheus text format. Besides the agent
+// instrumentation counters, it exposes the last value of the gauges set with SetGauge, which expire when they are
+// not updated during the TTL, ie: the samples of a finished process.
+type Prometheus struct {
+	ttl time.Duration
+	now func() time.Time
+
+	lock     sync.Mutex
+	families map[string]*family
+}
+
+type family struct {
+	metricType MetricType
+	series     map[string]*series
+}
+
+type series struct {
+	value   float64
+	updated time.Time
+	// expires is false for the instrumentation metrics, which are kept until the agent stops
+	expires bool
+}
+
+// NewPrometheus creates a Prometheus Instrumenter, expiring the gauges not updated after the TTL.
+func NewPrometheus(ttl time.Duration) *Prometheus {
+	return &Prometheus{
+		ttl:      ttl,
+		now:      time.Now,
+		families: make(map[string]*family),
+	}
+}
+
+// Measure records an agent instrumentation metric.
+func (p *Prometheus) Measure(metricType MetricType, name MetricName, val int64) {
+	metricName, ok := metricsToRegister[name]
+	if !ok {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	s := p.series(PrometheusName(instrumentationPrefix, metricName), metricType, nil)
+	s.expires = false
+	if metricType == Counter {
+		s.value += float64(val)
+	} else {
+		s.value = float64(val)
+	}
+}
+
+// SetGauge records the last value of a gauge. The name must be a valid Prometheus metric name, see PrometheusName.
+func (p *Prometheus) SetGauge(name string, labels map[string]string, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	s := p.series(name, Gauge, labels)
+	s.value = value
+	s.expires = true
+}
+
+// series returns the series of the metric with the labels, creating it when it doesn't exist.
+func (p *Prometheus) series(name string, metricType MetricType, labels map[string]string) *series {
+	f, ok := p.families[name]
+	if !ok {
+		f = &family{metricType: metricType, series: make(map[string]*series)}
+		p.families[name] = f
+	}
+
+	key := labelsKey(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{}
+		f.series[key] = s
+	}
+	s.updated = p.now()
+	return s
+}
+
+// GetHandler returns the handler serving the metrics in the Prometheus text format.
+func (p *Prometheus) GetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", prometheusContentType)
+		_, _ = w.Write(p.exposition())
+	})
+}
+
+// GetHttpTransport returns the transport as it is, outgoing requests are not instrumented.
+func (p *Prometheus) GetHttpTransport(base http.RoundTripper) http.RoundTripper {
+	return base
+}
+
+// exposition returns the metrics in the Prometheus text format, sorted by name and labels, removing the expired ones.
+func (p *Prometheus) exposition() []byte {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	expiration := p.now().Add(-p.ttl)
+	names := make([]string, 0, len(p.families))
+	for name, f := range p.families {
+		for key, s := range f.series {
+			if s.expires && s.updated.Before(expiration) {
+				delete(f.series, key)
+			}
+		}
+		if len(f.series) == 0 {
+			delete(p.families, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		f := p.families[name]
+		metricType := "gauge"
+		if f.metricType == Counter {
+			metricType = "counter"
+		}
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", name, metricType)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&buf, "%s%s %s\n", name, key, strconv.FormatFloat(f.series[key].value, 'g', -1, 64))
+		}
+	}
+	return buf.Bytes()
+}
+
+// labelsKey returns the labels formatted as in the exposition, ie: {a="1",b="2"}, which identifies the series.
+func labelsKey(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	formatted := make([]string, 0, len(names))
+	for _, name := range names {
+		formatted = append(formatted, fmt.Sprintf(`%s="%s"`, PrometheusName(name), labelValueReplacer.Replace(labels[name])))
+	}
+	return "{" + strings.Join(formatted, ",") + "}"
+}
+
+// labelValueReplacer escapes the label values as required by the text format.
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// PrometheusName joins the parts as a valid Prometheus name in snake case, ie: SystemSample and cpuPercent become
+// system_sample_cpu_percent. Invalid characters are replaced by underscores.
+func PrometheusName(parts ...string) string {
+	var b strings.Builder
+	for _, part := range parts {
+		if b.Len() > 0 {
+			b.WriteByte('_')
+		}
+		var prev rune
+		for i, r := range part {
+			switch {
+			case unicode.IsUpper(r) && r < unicode.MaxASCII:
+				if i > 0 && (unicode.IsLower(prev) || unicode.IsDigit(prev)) {
+					b.WriteByte('_')
+				}
+				b.WriteRune(unicode.ToLower(r))
+			case (r >= 'a' && r <= 'z') || r == '_':
+				b.WriteRune(r)
+			case r >= '0' && r <= '9':
+				// names cannot start with a digit
+				if b.Len() == 0 {
+					b.WriteByte('_')
+				}
+				b.WriteRune(r)
+			default:
+				b.WriteByte('_')
+			}
+			prev = r
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package instrumentation
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheus_GetHandler(t *testing.T) {
+	p := NewPrometheus(time.Minute)
+	p.Measure(Counter, LoggedErrors, 1)
+	p.Measure(Counter, LoggedErrors, 2)
+	p.SetGauge("newrelic_infra_process_sample_cpu_percent", map[string]string{"processDisplayName": "nginx", "commandLine": `/bin/sh -c "echo \ok"`}, 1.5)
+	p.SetGauge("newrelic_infra_process_sample_cpu_percent", map[string]string{"processDisplayName": "ssh"}, 0.25)
+	p.SetGauge("newrelic_infra_system_sample_cpu_percent", nil, 10)
+	p.SetGauge("newrelic_infra_system_sample_cpu_percent", nil, 12)
+
+	ts := httptest.NewServer(p.GetHandler())
+	defer ts.Close()
+	res, err := http.Get(ts.URL)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(res.Body)
+	_ = res.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.Equal(t, `# TYPE newrelic_infra_instrumentation_logged_errors counter
+newrelic_infra_instrumentation_logged_errors 3
+# TYPE newrelic_infra_process_sample_cpu_percent gauge
+newrelic_infra_process_sample_cpu_percent{command_line="/bin/sh -c \"echo \\ok\"",process_display_name="nginx"} 1.5
+newrelic_infra_process_sample_cpu_percent{process_display_name="ssh"} 0.25
+# TYPE newrelic_infra_system_sample_cpu_percent gauge
+newrelic_infra_system_sample_cpu_percent 12
+`, string(body))
+}
+
+func TestPrometheus_ExpiresGauges(t *testing.T) {
+	now := time.Now()
+	p := NewPrometheus(time.Minute)
+	p.now = func() time.Time { return now }
+
+	p.Measure(Counter, LoggedErrors, 1)
+	p.SetGauge("finished_process", map[string]string{"pid": "1"}, 1)
+	p.SetGauge("running_process", map[string]string{"pid": "2"}, 1)
+
+	now = now.Add(50 * time.Second)
+	p.SetGauge("running_process", map[string]string{"pid": "2"}, 2)
+	now = now.Add(50 * time.Second)
+
+	assert.Equal(t, `# TYPE newrelic_infra_instrumentation_logged_errors counter
+newrelic_infra_instrumentation_logged_errors 1
+# TYPE running_process gauge
+running_process{pid="2"} 2
+`, string(p.exposition()))
+}
+
+func TestPrometheus_IgnoresInvalidValues(t *testing.T) {
+	p := NewPrometheus(time.Minute)
+	p.SetGauge("nan", nil, math.NaN())
+	p.SetGauge("inf", nil, math.Inf(1))
+
+	assert.Empty(t, p.exposition())
+}
+
+func TestPrometheusName(t *testing.T) {
+	tests := map[string][]string{
+		"system_sample_cpu_percent":                    {"SystemSample", "cpuPercent"},
+		"newrelic_infra_agent_event_queue_size":        {"newrelic_infra", "agent.eventQueueSize"},
+		"newrelic_infra_instrumentation_logged_errors": {instrumentationPrefix, "logged.errors"},
+		"disk_read_bytes_per_second":                   {"disk-readBytesPerSecond"},
+		"_9lives":                                      {"9lives"},
+		"io_total_read_count":                          {"ioTotalReadCount"},
+	}
+	for want, parts := range tests {
+		t.Run(want, func(t *testing.T) {
+			assert.Equal(t, want, PrometheusName(parts...))
+		})
+	}
+}
@@ -17,6 +17,10 @@ type Config struct {
 	Environment map[string]string
 	// Global variables that need to be retrieved before the integration runs
 	Passthrough []string
+	// Limits bound the resources of the process and its children
+	Limits Limits
+	// Secrets are written into an inherited pipe, whose file descriptor is set in the SecretsFDEnvVar variable
+	Secrets []byte
 }
 
 // for testing purposes
@@ -89,5 +93,7 @@ func (c *Config) deepClone() *Config {
 		IntegrationName: c.IntegrationName,
 		Environment:     envCopy,
 		Passthrough:     passthroughCopy,
+		Limits:          c.Limits,
+		Secrets:         c.Secrets,
 	}
 }
@@ -8,7 +8,9 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"sync"
 
@@ -20,6 +22,9 @@ import (
 
 const unknownErrExitCode = -3
 
+// SecretsFDEnvVar is the environment variable with the file descriptor of the pipe the secrets are written into.
+const SecretsFDEnvVar = "NRI_SECRETS_FD"
+
 var illog = log.WithComponent("integrations.Executor")
 
 // Executor handles Executable commands asynchronously.
@@ -92,8 +97,29 @@ func (r *Executor) Execute(ctx context.Context, pidChan, exitCodeCh chan<- int)
 			close(closedPipes)
 		}()
 
-		if err = startProcess(cmd); err != nil {
+		secrets, err := r.secretsPipe(cmd)
+		if err != nil {
+			out.Errors <- err
+			return
+		}
+
+		var lim limiter
+		if r.Cfg.Limits.Enabled() {
+			if lim, err = newLimiter(r.Cfg.IntegrationName, r.Cfg.Limits); err != nil {
+				logger.WithError(err).Warn("Cannot enforce the integration resource limits, running it without them.")
+			}
+		}
+
+		err = startProcess(cmd)
+		if secrets != nil {
+			secrets(err == nil)
+		}
+		if err != nil {
 			out.Errors <- err
+		} else if lim != nil {
+			if err = lim.add(cmd); err != nil {
+				logger.WithError(err).Warn("Cannot enforce the integration resource limits, running it without them.")
+			}
 		}
 
 		if pidChan != nil {
@@ -118,6 +144,13 @@ func (r *Executor) Execute(ctx context.Context, pidChan, exitCodeCh chan<- int)
 			exitCodeCh <- 0
 		}
 
+		if lim != nil {
+			if lim.exceeded() {
+				out.Errors <- &LimitExceededError{IntegrationName: r.Cfg.IntegrationName, MemoryBytes: r.Cfg.Limits.MemoryBytes}
+			}
+			lim.release()
+		}
+
 		allOutputForwarded.Wait() // waiting again to avoid closing output before the data is received during cancellation
 	}()
 	return receiver
@@ -166,6 +199,35 @@ func (r *Executor) buildCommand(ctx context.Context) *exec.Cmd {
 	return cmd
 }
 
+// secretsPipe passes the secrets to the command through an inherited pipe, returning the function that writes them once
+// the command is started. Without secrets it returns nil.
+func (r *Executor) secretsPipe(cmd *exec.Cmd) (func(started bool), error) {
+	if len(r.Cfg.Secrets) == 0 {
+		return nil, nil
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the secrets pipe: %w", err)
+	}
+	// the extra files start after stdin, stdout and stderr
+	cmd.ExtraFiles = append(cmd.ExtraFiles, reader)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", SecretsFDEnvVar, 2+len(cmd.ExtraFiles)))
+
+	return func(started bool) {
+		// the child has its own copy
+		_ = reader.Close()
+		if !started {
+			_ = writer.Close()
+			return
+		}
+		// the pipe could be full until the integration reads it
+		go func() {
+			_, _ = writer.Write(r.Cfg.Secrets)
+			_ = writer.Close()
+		}()
+	}, nil
+}
+
 // DeepClone returns an exact copy of an Executor, without references to the same data structures.
 // It will allow replacing ${config.path} variables by the agent in several executor instances.
 func (r *Executor) DeepClone() Executor {
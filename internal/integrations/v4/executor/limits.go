@@ -0,0 +1,42 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Limits bound the resources of an integration process and its children, enforced by cgroups on Linux and by job
+// objects on Windows.
+type Limits struct {
+	// CPUPercent throttles the process to a percentage of a single CPU, ie: 150 for one and a half. 0 for no limit.
+	CPUPercent float64
+	// MemoryBytes kills the process when its memory exceeds it. 0 for no limit.
+	MemoryBytes uint64
+}
+
+// Enabled returns whether any limit is set.
+func (l Limits) Enabled() bool {
+	return l.CPUPercent > 0 || l.MemoryBytes > 0
+}
+
+// LimitExceededError is sent through the output errors when the process is killed for exceeding its memory limit.
+type LimitExceededError struct {
+	IntegrationName string
+	MemoryBytes     uint64
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("integration %s killed for exceeding its memory limit of %d bytes", e.IntegrationName, e.MemoryBytes)
+}
+
+// limiter enforces the limits of a process and its children.
+type limiter interface {
+	// add enforces the limits on the started process.
+	add(cmd *exec.Cmd) error
+	// exceeded returns whether the process was killed for exceeding the limits.
+	exceeded() bool
+	// release kills the remaining children of the process and frees the limiter resources.
+	release()
+}
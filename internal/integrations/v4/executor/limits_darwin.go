@@ -0,0 +1,11 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package executor
+
+import (
+	"errors"
+)
+
+func newLimiter(_ string, _ Limits) (limiter, error) {
+	return nil, errors.New("resource limits are only supported on Linux and Windows")
+}
@@ -0,0 +1,195 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package executor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+const (
+	// cgroupParent groups the cgroups of the integrations, under the root of the cgroup hierarchies.
+	cgroupParent = "newrelic-infra-integrations"
+	// cfsPeriodUs is the period of the CPU quota, in microseconds.
+	cfsPeriodUs = 100000
+)
+
+var (
+	// for testing purposes
+	cgroupRoot = "/sys/fs/cgroup"
+	// cgroupSeq makes the cgroup of every process unique
+	cgroupSeq uint64
+)
+
+// cgroupLimiter enforces the limits with a cgroup for the process, on the v2 unified hierarchy or on the v1 cpu and
+// memory hierarchies.
+type cgroupLimiter struct {
+	// dirs of the cgroup, one per hierarchy
+	dirs []string
+	// oomFile has the oom_kill count of the cgroup
+	oomFile string
+}
+
+func newLimiter(name string, limits Limits) (limiter, error) {
+	cgroupName := fmt.Sprintf("%s-%d", helpers.SanitizeFileName(name), atomic.AddUint64(&cgroupSeq, 1))
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		return newCgroupV2Limiter(cgroupName, limits)
+	}
+	return newCgroupV1Limiter(cgroupName, limits)
+}
+
+func newCgroupV2Limiter(name string, limits Limits) (*cgroupLimiter, error) {
+	parent := filepath.Join(cgroupRoot, cgroupParent)
+	if err := mkdirCgroup(parent); err != nil {
+		return nil, err
+	}
+	if err := writeCgroupFile(parent, "cgroup.subtree_control", "+cpu +memory"); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(parent, name)
+	if err := mkdirCgroup(dir); err != nil {
+		return nil, err
+	}
+	l := &cgroupLimiter{dirs: []string{dir}, oomFile: filepath.Join(dir, "memory.events")}
+	if limits.CPUPercent > 0 {
+		quota := fmt.Sprintf("%d %d", cpuQuotaUs(limits.CPUPercent), cfsPeriodUs)
+		if err := writeCgroupFile(dir, "cpu.max", quota); err != nil {
+			l.release()
+			return nil, err
+		}
+	}
+	if limits.MemoryBytes > 0 {
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatUint(limits.MemoryBytes, 10)); err != nil {
+			l.release()
+			return nil, err
+		}
+		// the swap could be used to exceed the limit, and killing only a child would leave the integration broken
+		_ = writeCgroupFile(dir, "memory.swap.max", "0")
+		_ = writeCgroupFile(dir, "memory.oom.group", "1")
+	}
+	return l, nil
+}
+
+func newCgroupV1Limiter(name string, limits Limits) (*cgroupLimiter, error) {
+	l := &cgroupLimiter{}
+	if limits.CPUPercent > 0 {
+		dir := filepath.Join(cgroupRoot, "cpu", cgroupParent, name)
+		if err := mkdirCgroup(dir); err != nil {
+			return nil, err
+		}
+		l.dirs = append(l.dirs, dir)
+		err := writeCgroupFile(dir, "cpu.cfs_period_us", strconv.Itoa(cfsPeriodUs))
+		if err == nil {
+			err = writeCgroupFile(dir, "cpu.cfs_quota_us", strconv.Itoa(cpuQuotaUs(limits.CPUPercent)))
+		}
+		if err != nil {
+			l.release()
+			return nil, err
+		}
+	}
+	if limits.MemoryBytes > 0 {
+		dir := filepath.Join(cgroupRoot, "memory", cgroupParent, name)
+		if err := mkdirCgroup(dir); err != nil {
+			l.release()
+			return nil, err
+		}
+		l.dirs = append(l.dirs, dir)
+		l.oomFile = filepath.Join(dir, "memory.oom_control")
+		if err := writeCgroupFile(dir, "memory.limit_in_bytes", strconv.FormatUint(limits.MemoryBytes, 10)); err != nil {
+			l.release()
+			return nil, err
+		}
+		_ = writeCgroupFile(dir, "memory.memsw.limit_in_bytes", strconv.FormatUint(limits.MemoryBytes, 10))
+	}
+	return l, nil
+}
+
+func (l *cgroupLimiter) add(cmd *exec.Cmd) error {
+	for _, dir := range l.dirs {
+		if err := writeCgroupFile(dir, "cgroup.procs", strconv.Itoa(cmd.Process.Pid)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exceeded returns whether the OOM killer killed any process of the cgroup, from the oom_kill count of the
+// memory.events (v2) or memory.oom_control (v1) file.
+func (l *cgroupLimiter) exceeded() bool {
+	if l.oomFile == "" {
+		return false
+	}
+	f, err := os.Open(l.oomFile)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			return fields[1] != "0"
+		}
+	}
+	return false
+}
+
+func (l *cgroupLimiter) release() {
+	for _, dir := range l.dirs {
+		// the children left by the integration would keep the cgroup busy
+		if err := writeCgroupFile(dir, "cgroup.kill", "1"); err != nil {
+			killCgroupProcs(dir)
+		}
+		if err := os.Remove(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			illog.WithError(err).WithField("cgroup", dir).Warn("cannot remove the integration cgroup")
+		}
+	}
+}
+
+// killCgroupProcs kills the processes of the cgroup on kernels without cgroup.kill.
+func killCgroupProcs(dir string) {
+	procs, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return
+	}
+	for _, pid := range strings.Fields(string(procs)) {
+		if p, err := strconv.Atoi(pid); err == nil {
+			if proc, err := os.FindProcess(p); err == nil {
+				_ = proc.Kill()
+			}
+		}
+	}
+}
+
+func cpuQuotaUs(cpuPercent float64) int {
+	quota := int(cpuPercent / 100 * cfsPeriodUs)
+	// the minimum quota accepted by the kernel
+	if quota < 1000 {
+		quota = 1000
+	}
+	return quota
+}
+
+func mkdirCgroup(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create cgroup %s: %w", dir, err)
+	}
+	return nil
+}
+
+func writeCgroupFile(dir, file, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+		return fmt.Errorf("cannot write %s into cgroup %s: %w", file, dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLimiter_CgroupV2(t *testing.T) {
+	defer func(root string) { cgroupRoot = root }(cgroupRoot)
+	cgroupRoot = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupRoot, "cgroup.controllers"), []byte("cpu memory"), 0644))
+
+	lim, err := newLimiter("nri/mysql", Limits{CPUPercent: 150, MemoryBytes: 64 << 20})
+	require.NoError(t, err)
+	l := lim.(*cgroupLimiter)
+	require.Len(t, l.dirs, 1)
+	dir := l.dirs[0]
+	assert.Equal(t, filepath.Join(cgroupRoot, cgroupParent), filepath.Dir(dir))
+
+	assertCgroupFile(t, filepath.Dir(dir), "cgroup.subtree_control", "+cpu +memory")
+	assertCgroupFile(t, dir, "cpu.max", "150000 100000")
+	assertCgroupFile(t, dir, "memory.max", "67108864")
+	assertCgroupFile(t, dir, "memory.oom.group", "1")
+
+	assert.False(t, l.exceeded())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "memory.events"), []byte("low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n"), 0644))
+	assert.True(t, l.exceeded())
+}
+
+func TestNewLimiter_CgroupV1(t *testing.T) {
+	defer func(root string) { cgroupRoot = root }(cgroupRoot)
+	cgroupRoot = t.TempDir()
+
+	lim, err := newLimiter("nri-redis", Limits{CPUPercent: 0.5, MemoryBytes: 1 << 20})
+	require.NoError(t, err)
+	l := lim.(*cgroupLimiter)
+	require.Len(t, l.dirs, 2)
+
+	assertCgroupFile(t, l.dirs[0], "cpu.cfs_period_us", "100000")
+	// the quota is at least the kernel minimum
+	assertCgroupFile(t, l.dirs[0], "cpu.cfs_quota_us", "1000")
+	assertCgroupFile(t, l.dirs[1], "memory.limit_in_bytes", "1048576")
+
+	require.NoError(t, os.WriteFile(filepath.Join(l.dirs[1], "memory.oom_control"), []byte("oom_kill_disable 0\nunder_oom 0\noom_kill 0\n"), 0644))
+	assert.False(t, l.exceeded())
+}
+
+func assertCgroupFile(t *testing.T, dir, file, expected string) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, file))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+}
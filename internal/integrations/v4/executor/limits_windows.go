@@ -0,0 +1,138 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// job object notifications, from winnt.h
+	jobObjectMsgActiveProcessZero = 4
+	jobObjectMsgJobMemoryLimit    = 10
+
+	// JOBOBJECT_CPU_RATE_CONTROL_INFORMATION.ControlFlags
+	jobObjectCPURateControlEnable  = 0x1
+	jobObjectCPURateControlHardCap = 0x4
+)
+
+type jobObjectCPURateControlInformation struct {
+	ControlFlags uint32
+	CPURate      uint32
+}
+
+type jobObjectAssociateCompletionPort struct {
+	CompletionKey  windows.Handle
+	CompletionPort windows.Handle
+}
+
+// jobLimiter enforces the limits with a job object, notifying the memory limit violations through a completion port.
+type jobLimiter struct {
+	job            windows.Handle
+	port           windows.Handle
+	memoryExceeded int32
+}
+
+func newLimiter(_ string, limits Limits) (limiter, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create job object: %w", err)
+	}
+	l := &jobLimiter{job: job}
+	l.port, err = windows.CreateIoCompletionPort(windows.InvalidHandle, 0, 0, 1)
+	if err != nil {
+		l.release()
+		return nil, fmt.Errorf("cannot create job object completion port: %w", err)
+	}
+	port := jobObjectAssociateCompletionPort{CompletionKey: job, CompletionPort: l.port}
+	if _, err = windows.SetInformationJobObject(job, windows.JobObjectAssociateCompletionPortInformation,
+		uintptr(unsafe.Pointer(&port)), uint32(unsafe.Sizeof(port))); err != nil {
+		l.release()
+		return nil, fmt.Errorf("cannot associate the job object completion port: %w", err)
+	}
+
+	// the children left by the integration are killed when the job is closed
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{}
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if limits.MemoryBytes > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_JOB_MEMORY
+		info.JobMemoryLimit = uintptr(limits.MemoryBytes)
+	}
+	if _, err = windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		l.release()
+		return nil, fmt.Errorf("cannot set the job object memory limit: %w", err)
+	}
+
+	if limits.CPUPercent > 0 {
+		// the rate is relative to all the CPUs, in hundredths of percent
+		rate := uint32(limits.CPUPercent * 100 / float64(runtime.NumCPU()))
+		if rate < 1 {
+			rate = 1
+		} else if rate > 10000 {
+			rate = 10000
+		}
+		cpu := jobObjectCPURateControlInformation{
+			ControlFlags: jobObjectCPURateControlEnable | jobObjectCPURateControlHardCap,
+			CPURate:      rate,
+		}
+		if _, err = windows.SetInformationJobObject(job, windows.JobObjectCpuRateControlInformation,
+			uintptr(unsafe.Pointer(&cpu)), uint32(unsafe.Sizeof(cpu))); err != nil {
+			l.release()
+			return nil, fmt.Errorf("cannot set the job object CPU rate: %w", err)
+		}
+	}
+	return l, nil
+}
+
+func (l *jobLimiter) add(cmd *exec.Cmd) error {
+	handle, err := processHandle(cmd)
+	if err != nil {
+		return err
+	}
+	if err = windows.AssignProcessToJobObject(l.job, handle); err != nil {
+		return fmt.Errorf("cannot assign the process to the job object: %w", err)
+	}
+	go l.watch()
+	return nil
+}
+
+// watch terminates the job once its memory limit is exceeded, as the allocations failing otherwise could leave the
+// integration broken.
+func (l *jobLimiter) watch() {
+	for {
+		var code uint32
+		var key uintptr
+		var overlapped *windows.Overlapped
+		// fails once the port is closed
+		if err := windows.GetQueuedCompletionStatus(l.port, &code, &key, &overlapped, windows.INFINITE); err != nil {
+			return
+		}
+		switch code {
+		case jobObjectMsgJobMemoryLimit:
+			atomic.StoreInt32(&l.memoryExceeded, 1)
+			_ = windows.TerminateJobObject(l.job, 1)
+		case jobObjectMsgActiveProcessZero:
+			return
+		}
+	}
+}
+
+func (l *jobLimiter) exceeded() bool {
+	return atomic.LoadInt32(&l.memoryExceeded) == 1
+}
+
+func (l *jobLimiter) release() {
+	if l.job != 0 {
+		_ = windows.CloseHandle(l.job)
+	}
+	if l.port != 0 {
+		_ = windows.CloseHandle(l.port)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/executor"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/schedule"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/when"
 	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
 	cfgreq "github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest/protocol"
@@ -20,6 +21,7 @@ import (
 
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -45,10 +47,23 @@ type Definition struct {
 	WhenConditions  []when.Condition
 	CmdChanReq      *ctx.CmdChannelRequest // not empty: command-channel run/stop integration requests
 	CfgProtocol     *cfgreq.Context
+	GRPCSocket      string        // not empty: the integration sends its data through the gRPC protocol, on this unix socket
+	MaxBackoff      time.Duration // maximum delay before running again an instance killed by its limits or timeout
+	SecretsDelivery string        // how the secret variables reach the integration: args (default), file or fd
+	Schedule        schedule.Schedule
+	Restart         Restart
 	runnable        executor.Executor
 	newTempFile     func(template []byte) (string, error)
 }
 
+// Restart policy of an integration and its crash-loop breaker.
+type Restart struct {
+	Policy         string        // always, on-failure or never. Empty: always, without backing off failed executions
+	Backoff        time.Duration // initial delay before running again a failed execution, doubling up to MaxBackoff
+	MaxFailures    int           // failed executions within FailuresWindow stopping the integration, 0 disables it
+	FailuresWindow time.Duration
+}
+
 func (d *Definition) Hash() string {
 	h := sha256.New()
 	identifier := fmt.Sprintf("%v%v%v%v%v%v%v%v%v%v%v%v%v%v",
@@ -76,7 +91,7 @@ func (d *Definition) TimeoutEnabled() bool {
 }
 
 func (d *Definition) SingleRun() bool {
-	return d.Interval == 0
+	return d.Schedule.RunOnce || (d.Interval == 0 && d.Schedule.Cron == nil)
 }
 
 // PluginID returns inventory plugin ID
@@ -113,6 +128,13 @@ func (d *Definition) Run(ctx context.Context, bindVals *databind.Values, discove
 		ConfigTemplate []byte
 	}
 
+	secure := secureDelivery(d.SecretsDelivery) && bindVals.VarsLen() > 0
+	if secure {
+		if err := checkSecretArgs(d.SecretsDelivery, d.runnable, bindVals.Vars()); err != nil {
+			return nil, err
+		}
+	}
+
 	// used to post-process "${config.path}" appearances only if we have found it previously
 	foundConfigPath := false
 	onDemand := noOnDemand
@@ -130,6 +152,8 @@ func (d *Definition) Run(ctx context.Context, bindVals *databind.Values, discove
 	logger.WithField("discovery_type", discoveryInfo.Type).
 		WithField("discovery_name", discoveryInfo.Name).
 		WithField("discovery_matchers", discoveryInfo.Matchers).
+		WithField("discovery_match_expression", discoveryInfo.MatchExpression).
+		WithField("discovery_merged", discoveryInfo.Merged).
 		Debug("Running through all discovery matches.")
 
 	for _, ir := range matches {
@@ -141,13 +165,37 @@ func (d *Definition) Run(ctx context.Context, bindVals *databind.Values, discove
 		}
 
 		var removeFile func(<-chan struct{})
+		// with a secure secrets delivery, the secrets and the config file are only written into a private directory
+		var secretsDir string
+		if secure {
+			if secretsDir, err = newSecretsDir(); err != nil {
+				return nil, err
+			}
+			removeFile = removeTempFile(secretsDir)
+			if err = deliverSecrets(d.SecretsDelivery, secretsDir, &dc.Executor, bindVals.Vars()); err != nil {
+				_ = os.RemoveAll(secretsDir)
+				return nil, err
+			}
+		}
+
 		if dc.ConfigTemplate != nil {
-			templateFile, err := d.newTempFile(dc.ConfigTemplate)
+			var templateFile string
+			if secure {
+				templateFile = filepath.Join(secretsDir, "config.yml")
+				err = os.WriteFile(templateFile, dc.ConfigTemplate, 0600)
+			} else {
+				templateFile, err = d.newTempFile(dc.ConfigTemplate)
+			}
 			if err != nil {
+				if secretsDir != "" {
+					_ = os.RemoveAll(secretsDir)
+				}
 				return nil, err
 			}
 			// Setting to remove this file after the integration has finished
-			removeFile = removeTempFile(templateFile)
+			if !secure {
+				removeFile = removeTempFile(templateFile)
+			}
 
 			// If we previously detected some "${config.path}" placeholder in the arguments
 			// or the environment, we look again for it and replace it by the
@@ -183,12 +231,12 @@ func (d *Definition) Run(ctx context.Context, bindVals *databind.Values, discove
 	return tasksOutput, nil
 }
 
-// remoteTempFile returns a function that removes the file corresponding to the passed path when the provided channel
-// is closed
+// remoteTempFile returns a function that removes the file or directory corresponding to the passed path when the
+// provided channel is closed
 func removeTempFile(path string) func(<-chan struct{}) {
 	return func(done <-chan struct{}) {
 		<-done
-		if err := os.Remove(path); err != nil {
+		if err := os.RemoveAll(path); err != nil {
 			elog.WithError(err).WithField("path", path).Warn("can't remove temporary integration config file")
 		}
 	}
@@ -3,16 +3,23 @@
 package integration
 
 import (
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
 	"time"
 
+	"github.com/newrelic/infrastructure-agent/internal/agent/leader"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/executor"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/schedule"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/when"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
 	config2 "github.com/newrelic/infrastructure-agent/pkg/integrations/v4/config"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/grpcapi"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
 	"github.com/sirupsen/logrus"
@@ -23,6 +30,9 @@ import (
 const (
 	defaultIntegrationInterval = config.FREQ_PLUGIN_EXTERNAL_PLUGINS * time.Second
 	defaultTimeout             = 120 * time.Second
+	defaultMaxBackoff          = 5 * time.Minute
+	defaultRestartBackoff      = 10 * time.Second
+	defaultFailuresWindow      = 10 * time.Minute
 	minimumTimeout             = 100 * time.Millisecond
 	intervalEnvVarName         = "NRI_CONFIG_INTERVAL"
 )
@@ -69,6 +79,12 @@ func newDefinitionWithoutLookup(ce config2.ConfigEntry, passthroughEnv []string,
 	// Reading this env the integration can know configured interval.
 	ce.Env[intervalEnvVarName] = fmt.Sprintf("%v", interval)
 
+	var grpcSocket string
+	if ce.Protocol == config2.ProtocolGRPC {
+		grpcSocket = grpcSocketPath(ce)
+		ce.Env[grpcapi.SocketEnvVar] = grpcSocket
+	}
+
 	d := Definition{
 		ExecutorConfig: executor.Config{
 			User:            ce.User,
@@ -76,6 +92,10 @@ func newDefinitionWithoutLookup(ce config2.ConfigEntry, passthroughEnv []string,
 			IntegrationName: ce.InstanceName,
 			Environment:     ce.Env,
 			Passthrough:     passthroughEnv,
+			Limits: executor.Limits{
+				CPUPercent:  ce.Limits.CPUPercent,
+				MemoryBytes: uint64(ce.Limits.MemoryMB) * 1024 * 1024,
+			},
 		},
 		Labels:         ce.Labels,
 		Tags:           ce.Tags,
@@ -84,9 +104,33 @@ func newDefinitionWithoutLookup(ce config2.ConfigEntry, passthroughEnv []string,
 		LogsQueueSize:  ce.LogsQueueSize,
 		WhenConditions: conditions(ce.When),
 		ConfigTemplate: configTemplate,
+		GRPCSocket:     grpcSocket,
+		MaxBackoff:     defaultMaxBackoff,
 		newTempFile:    newTempFile,
 	}
 
+	d.SecretsDelivery = ce.SecretsDelivery
+	var err error
+	d.Schedule, err = schedule.New(ce.Schedule.Cron, ce.Schedule.Window, ce.Schedule.RunOnce)
+	if err != nil {
+		return Definition{}, errors.New("Error parsing 'schedule' YAML property: " + err.Error())
+	}
+	if ce.Limits.MaxBackoff != nil {
+		d.MaxBackoff = *ce.Limits.MaxBackoff
+	}
+	d.Restart = Restart{
+		Policy:         ce.Restart.Policy,
+		Backoff:        defaultRestartBackoff,
+		MaxFailures:    ce.Restart.MaxFailures,
+		FailuresWindow: defaultFailuresWindow,
+	}
+	if ce.Restart.Backoff != nil {
+		d.Restart.Backoff = *ce.Restart.Backoff
+	}
+	if ce.Restart.FailuresWindow != nil {
+		d.Restart.FailuresWindow = *ce.Restart.FailuresWindow
+	}
+
 	if ce.InventorySource == "" {
 		// Set to empty as currently Inventory source unknown
 		d.InventorySource = ids.EmptyInventorySource
@@ -119,6 +163,17 @@ func newDefinitionWithoutLookup(ce config2.ConfigEntry, passthroughEnv []string,
 	return d, nil
 }
 
+// grpcSocketPath returns the path of the socket where the agent listens to an integration using the gRPC protocol, a
+// named pipe on Windows. It only depends on the integration configuration, so the definition hash doesn't change
+// across loads.
+func grpcSocketPath(ce config2.ConfigEntry) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%v", ce)))
+	if runtime.GOOS == "windows" {
+		return fmt.Sprintf(`\\.\pipe\nri-%x`, hash[:8])
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("nri-%x.sock", hash[:8]))
+}
+
 // NewDefinition creates Definition from ConfigEntry, config template, executables lookup and
 // passed through env vars.
 func NewDefinition(ce config2.ConfigEntry, lookup InstancesLookup, passthroughEnv []string, configTemplate []byte) (d Definition, err error) {
@@ -261,6 +316,14 @@ func conditions(enabling config2.EnableConditions) []when.Condition {
 	if len(enabling.EnvExists) > 0 {
 		conds = append(conds, when.EnvExists(enabling.EnvExists))
 	}
+
+	if enabling.Leader != "" {
+		if leader.Default() == nil {
+			ilog.WithField("group", enabling.Leader).
+				Warn("Integration conditioned to lead a group, but leader_election_dir is not set. It runs on every agent.")
+		}
+		conds = append(conds, when.Leader(enabling.Leader))
+	}
 	return conds
 }
 
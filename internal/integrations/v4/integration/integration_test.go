@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	config2 "github.com/newrelic/infrastructure-agent/pkg/integrations/v4/config"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/grpcapi"
 
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/fixtures"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/testhelp"
@@ -192,6 +193,31 @@ func TestInterval_EnvironmentVariableDefault(t *testing.T) {
 	assert.Equal(t, fmt.Sprintf("%v", defaultIntegrationInterval), i.ExecutorConfig.Environment[intervalEnvVarName])
 }
 
+func TestProtocol_GRPCSocket(t *testing.T) {
+	// GIVEN a configuration with the gRPC protocol
+	ce := config2.ConfigEntry{InstanceName: "foo", Protocol: config2.ProtocolGRPC, Exec: config2.ShlexOpt{"bar"}}
+	// WHEN an integration is loaded from it twice
+	i, err := NewDefinition(ce, ErrLookup, nil, nil)
+	require.NoError(t, err)
+	i2, err := NewDefinition(ce, ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	// THEN the integration has an environment variable with the same socket path
+	assert.NotEmpty(t, i.GRPCSocket)
+	assert.Equal(t, i.GRPCSocket, i.ExecutorConfig.Environment[grpcapi.SocketEnvVar])
+	assert.Equal(t, i.Hash(), i2.Hash())
+
+	// AND no socket is set for the stdout protocol
+	i, err = NewDefinition(config2.ConfigEntry{InstanceName: "foo", Exec: config2.ShlexOpt{"bar"}}, ErrLookup, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, i.GRPCSocket)
+	assert.NotContains(t, i.ExecutorConfig.Environment, grpcapi.SocketEnvVar)
+
+	// AND unknown protocols are rejected
+	_, err = NewDefinition(config2.ConfigEntry{InstanceName: "foo", Protocol: "http", Exec: config2.ShlexOpt{"bar"}}, ErrLookup, nil, nil)
+	assert.Error(t, err)
+}
+
 func TestTimeout_TooLow(t *testing.T) {
 	// GIVEN a configured timeout where the user forgot to write a suffix
 	var config config2.ConfigEntry
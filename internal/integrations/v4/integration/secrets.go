@@ -0,0 +1,86 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/executor"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/config"
+)
+
+// secretsDirEnv is the environment variable with the directory of the secret files.
+const secretsDirEnv = "NRI_SECRETS_DIR"
+
+var (
+	placeholderRegex = regexp.MustCompile(`\$\{\s*([^}]*?)\s*\}`)
+	// for testing purposes
+	memoryDir = "/dev/shm"
+)
+
+// checkSecretArgs fails when the command or its arguments reference any secret variable, as they're visible to every
+// user of the host.
+func checkSecretArgs(delivery string, runnable executor.Executor, vars data.Map) error {
+	for _, arg := range append([]string{runnable.Command}, runnable.Args...) {
+		for _, match := range placeholderRegex.FindAllStringSubmatch(arg, -1) {
+			if _, ok := vars[match[1]]; ok {
+				return fmt.Errorf("secret variable %s can't be referenced from the integration arguments with the %q secrets delivery, use its env instead",
+					match[1], delivery)
+			}
+		}
+	}
+	return nil
+}
+
+// newSecretsDir creates a private directory for the secrets of an integration instance, in memory when the platform
+// allows it.
+func newSecretsDir() (string, error) {
+	base := os.TempDir()
+	if runtime.GOOS == "linux" {
+		if info, err := os.Stat(memoryDir); err == nil && info.IsDir() {
+			base = memoryDir
+		}
+	}
+	dir, err := os.MkdirTemp(base, "nri-secrets-")
+	if err != nil {
+		return "", fmt.Errorf("can't create the secrets directory: %w", err)
+	}
+	return dir, nil
+}
+
+// deliverSecrets sets the secrets into the executor of an integration instance, as files of its private directory or
+// through a pipe depending on the delivery.
+func deliverSecrets(delivery, dir string, exec *executor.Executor, vars data.Map) error {
+	switch delivery {
+	case config.SecretsDeliveryFile:
+		for name, value := range vars {
+			path := filepath.Join(dir, helpers.SanitizeFileName(name))
+			if err := os.WriteFile(path, []byte(value), 0600); err != nil {
+				return fmt.Errorf("can't write secret %s: %w", name, err)
+			}
+		}
+		if exec.Cfg.Environment == nil {
+			exec.Cfg.Environment = map[string]string{}
+		}
+		exec.Cfg.Environment[secretsDirEnv] = dir
+	case config.SecretsDeliveryFD:
+		secrets, err := json.Marshal(vars)
+		if err != nil {
+			return fmt.Errorf("can't encode the secrets: %w", err)
+		}
+		exec.Cfg.Secrets = secrets
+	}
+	return nil
+}
+
+// secureDelivery returns whether the secrets can't be passed through the arguments.
+func secureDelivery(delivery string) bool {
+	return delivery == config.SecretsDeliveryFile || delivery == config.SecretsDeliveryFD
+}
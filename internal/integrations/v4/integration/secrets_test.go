@@ -0,0 +1,85 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux || darwin
+// +build linux darwin
+
+package integration
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
+	config2 "github.com/newrelic/infrastructure-agent/pkg/integrations/v4/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runSecrets(t *testing.T, ce config2.ConfigEntry, template []byte) (lines []string) {
+	t.Helper()
+	def, err := NewDefinition(ce, ErrLookup, nil, template)
+	require.NoError(t, err)
+
+	vals := databind.NewValues(data.Map{"creds.password": "s3cr3t"})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	outputs, err := def.Run(ctx, &vals, databind.DiscovererInfo{}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, outputs, 1)
+
+	for line := range outputs[0].Receive.Stdout {
+		lines = append(lines, string(line))
+	}
+	<-outputs[0].Receive.Done
+	return lines
+}
+
+func TestRun_SecretsDeliveryFile(t *testing.T) {
+	defer func(dir string) { memoryDir = dir }(memoryDir)
+	memoryDir = t.TempDir()
+
+	lines := runSecrets(t, config2.ConfigEntry{
+		InstanceName:    "foo",
+		Exec:            config2.ShlexOpt{"/bin/sh", "-c", `echo "$NRI_SECRETS_DIR"; cat "$NRI_SECRETS_DIR/creds.password"; echo; cat "$CONFIG_PATH"; echo; echo "$PASSWORD"`},
+		Env:             map[string]string{"PASSWORD": "${creds.password}"},
+		SecretsDelivery: config2.SecretsDeliveryFile,
+	}, []byte("password: ${creds.password}"))
+
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"s3cr3t", "password: s3cr3t", "s3cr3t"}, lines[1:])
+	if _, err := os.Stat("/dev/shm"); err == nil {
+		assert.Contains(t, lines[0], memoryDir)
+	}
+
+	// THEN the secrets directory is removed once the integration finishes
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(lines[0])
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRun_SecretsDeliveryFD(t *testing.T) {
+	lines := runSecrets(t, config2.ConfigEntry{
+		InstanceName:    "foo",
+		Exec:            config2.ShlexOpt{"/bin/sh", "-c", `eval "cat <&$NRI_SECRETS_FD"`},
+		SecretsDelivery: config2.SecretsDeliveryFD,
+	}, nil)
+
+	assert.Equal(t, []string{`{"creds.password":"s3cr3t"}`}, lines)
+}
+
+func TestRun_SecretsDeliveryRejectsArgs(t *testing.T) {
+	def, err := NewDefinition(config2.ConfigEntry{
+		InstanceName:    "foo",
+		Exec:            config2.ShlexOpt{"/bin/echo", "--password", "${ creds.password }"},
+		SecretsDelivery: config2.SecretsDeliveryFile,
+	}, ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	vals := databind.NewValues(data.Map{"creds.password": "s3cr3t"})
+	_, err = def.Run(context.Background(), &vals, databind.DiscovererInfo{}, nil, nil)
+	assert.Error(t, err)
+}
@@ -5,9 +5,11 @@ package runner
 import (
 	"context"
 	"github.com/newrelic/infrastructure-agent/pkg/entity/host"
+	"strconv"
 	"sync"
 
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/integration"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/schedule"
 	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/cmdrequest"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest"
@@ -32,6 +34,10 @@ type Group struct {
 	configHandle         configrequest.HandleFn
 	terminateDefinitionQ chan string
 	idLookup             host.IDLookup
+	// keys identify the configuration of every integration, to detect the ones changed when the group is reloaded
+	keys []string
+	// running cancels the running integrations by key
+	running map[string]context.CancelFunc
 }
 
 type runnerErrorHandler func(ctx context.Context, errs <-chan error)
@@ -64,20 +70,71 @@ func NewGroup(
 // Run launches all the integrations to run in background. They can be cancelled with the
 // provided context
 func (g *Group) Run(ctx context.Context) (hasStartedAnyOHI bool) {
-	for _, integr := range g.integrations {
-		go NewRunner(integr, g.emitter, g.dSources, g.handleErrorsProvide, g.cmdReqHandle, g.configHandle, g.terminateDefinitionQ, g.idLookup).Run(ctx, nil, nil)
+	for i, integr := range g.integrations {
+		g.start(ctx, g.key(i), integr)
 		hasStartedAnyOHI = true
 	}
 
 	return
 }
 
+// Apply updates the running group to the integrations of next, loaded from a new version of its configuration file.
+// The integrations removed from the file are stopped, the added ones are started under the provided context and the
+// changed ones are restarted, while the unchanged ones keep running. The discovery sources of the group are kept, so
+// the whole group must be restarted when they change. Returns whether any integration is still running.
+func (g *Group) Apply(ctx context.Context, next Group) (running bool) {
+	keep := make(map[string]bool, len(next.integrations))
+	for i := range next.integrations {
+		keep[next.key(i)] = true
+	}
+	for i, integr := range g.integrations {
+		key := g.key(i)
+		if cancel, ok := g.running[key]; ok && !keep[key] {
+			illog.WithField("integration_name", integr.Name).Info("Integration removed or changed in its configuration file, stopping it.")
+			cancel()
+			delete(g.running, key)
+		}
+	}
+
+	g.integrations, g.keys = next.integrations, next.keys
+	for i, integr := range g.integrations {
+		key := g.key(i)
+		if _, ok := g.running[key]; ok {
+			continue
+		}
+		illog.WithField("integration_name", integr.Name).Info("Integration added or changed in its configuration file, starting it.")
+		g.start(ctx, key, integr)
+	}
+
+	return len(g.running) > 0
+}
+
+// start runs an integration in background, until the provided context or its own one is cancelled.
+func (g *Group) start(ctx context.Context, key string, integr integration.Definition) {
+	if g.running == nil {
+		g.running = map[string]context.CancelFunc{}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	g.running[key] = cancel
+	go NewRunner(integr, g.emitter, g.dSources, g.handleErrorsProvide, g.cmdReqHandle, g.configHandle, g.terminateDefinitionQ, g.idLookup).Run(ctx, nil, nil)
+}
+
+// key returns the key of the integration at the given index, or its index when the group wasn't loaded from a
+// configuration file.
+func (g *Group) key(i int) string {
+	if i < len(g.keys) {
+		return g.keys[i]
+	}
+	return strconv.Itoa(i)
+}
+
 // RunOnce will execute the group of integrations just one time.
 func (g *Group) RunOnce(ctx context.Context) {
 
 	wg := sync.WaitGroup{}
 	for _, integrationDef := range g.integrations {
 		integrationDef.Interval = 0
+		integrationDef.Schedule = schedule.Schedule{}
 		wg.Add(1)
 		go func(definition integration.Definition) {
 			r := NewRunner(definition, g.emitter, g.dSources, g.handleErrorsProvide, g.cmdReqHandle, g.configHandle, g.terminateDefinitionQ, g.idLookup)
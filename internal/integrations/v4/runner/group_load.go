@@ -3,10 +3,14 @@
 package runner
 
 import (
+	"crypto/sha256"
+	"fmt"
+
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/integration"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/cmdrequest"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest"
 	config2 "github.com/newrelic/infrastructure-agent/pkg/integrations/v4/config"
+	"gopkg.in/yaml.v2"
 )
 
 // LoadFn provides a basic, incomplete Group instance to be configured by the NewGroup function.
@@ -30,6 +34,7 @@ func NewLoadFn(cfg config2.YAML, agentAndCCFeatures *Features) LoadFn {
 			terminateDefinitionQ: terminateDefinitionQ,
 		}
 		c = make(FeaturesCache)
+		occurrences := map[string]int{}
 
 		for _, cfgEntry := range cfg.Integrations {
 			var template []byte
@@ -46,10 +51,15 @@ func NewLoadFn(cfg config2.YAML, agentAndCCFeatures *Features) LoadFn {
 				return
 			}
 
+			key := entryKey(cfgEntry, template)
+			occurrences[key]++
+			key = fmt.Sprintf("%s#%d", key, occurrences[key])
+
 			if agentAndCCFeatures == nil {
 				if cfgEntry.When.Feature == "" {
 					// no features at all => run
 					g.integrations = append(g.integrations, i)
+					g.keys = append(g.keys, key)
 				} else {
 					// if feature only in OHI => cache for possible later usage via CC
 					c[cfgEntry.When.Feature] = cfgPath
@@ -61,6 +71,7 @@ func NewLoadFn(cfg config2.YAML, agentAndCCFeatures *Features) LoadFn {
 			c[cfgEntry.When.Feature] = cfgPath
 			if agentAndCCFeatures.IsOHIExecutable(cfgEntry.When.Feature) {
 				g.integrations = append(g.integrations, i)
+				g.keys = append(g.keys, key)
 				continue
 			}
 
@@ -71,3 +82,17 @@ func NewLoadFn(cfg config2.YAML, agentAndCCFeatures *Features) LoadFn {
 		return
 	}
 }
+
+// entryKey identifies the configuration of an integration, including its configuration template, so the integrations
+// whose configuration didn't change keep running when their file is reloaded.
+func entryKey(cfgEntry config2.ConfigEntry, template []byte) string {
+	h := sha256.New()
+	// the YAML encoding is deterministic and dereferences the pointers
+	entry, err := yaml.Marshal(cfgEntry)
+	if err != nil {
+		entry = []byte(fmt.Sprintf("%+v", cfgEntry))
+	}
+	h.Write(entry)
+	h.Write(template)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
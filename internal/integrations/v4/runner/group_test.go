@@ -4,6 +4,7 @@ package runner
 
 import (
 	"context"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -291,6 +292,59 @@ func interceptGroupErrors(gr *Group) <-chan error {
 	return handledError
 }
 
+func TestGroup_Apply(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	sleep := config2.ShlexOpt{"sleep", "30"}
+	load := func(entries ...config2.ConfigEntry) Group {
+		gr, _, err := NewGroup(NewLoadFn(config2.YAML{Integrations: entries}, nil), integration.InstancesLookup{}, nil, &testemit.RecordEmitter{}, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, "", terminatedQueue, host.IDLookup{})
+		require.NoError(t, err)
+		return gr
+	}
+	started := func() map[string]time.Time {
+		reports := map[string]time.Time{}
+		for _, r := range IntegrationReports() {
+			reports[r.Name] = r.Started
+		}
+		return reports
+	}
+
+	// GIVEN a running group
+	gr := load(
+		config2.ConfigEntry{InstanceName: "unchanged", Exec: sleep},
+		config2.ConfigEntry{InstanceName: "changed", Exec: sleep},
+		config2.ConfigEntry{InstanceName: "removed", Exec: sleep},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.True(t, gr.Run(ctx))
+	require.Eventually(t, func() bool { return len(started()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	before := started()
+
+	// WHEN a new version of its configuration file is applied
+	running := gr.Apply(ctx, load(
+		config2.ConfigEntry{InstanceName: "unchanged", Exec: sleep},
+		config2.ConfigEntry{InstanceName: "changed", Exec: sleep, Env: map[string]string{"FOO": "bar"}},
+		config2.ConfigEntry{InstanceName: "added", Exec: sleep},
+	))
+	assert.True(t, running)
+
+	// THEN only the added and changed integrations are started, and the removed one stopped
+	require.Eventually(t, func() bool {
+		after := started()
+		_, removed := after["removed"]
+		return len(after) == 3 && !removed && after["changed"].After(before["changed"])
+	}, 5*time.Second, 10*time.Millisecond)
+	after := started()
+	assert.Equal(t, before["unchanged"], after["unchanged"])
+	assert.Contains(t, after, "added")
+
+	// AND removing all the integrations stops the group
+	assert.False(t, gr.Apply(ctx, load()))
+	require.Eventually(t, func() bool { return len(started()) == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
 func TestGroup_Run_IntegrationScriptPrintsErrorsAndReturnCodeIsZero(t *testing.T) {
 	defer leaktest.Check(t)()
 
@@ -0,0 +1,38 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"errors"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/grpcapi"
+	"github.com/sirupsen/logrus"
+)
+
+// grpcHandler processes the messages the integration sends through the gRPC protocol as the lines of its output.
+// The extra labels and entity rewrites of the discovered instances don't apply to them, as the instances share the
+// socket.
+type grpcHandler struct {
+	r *runner
+}
+
+func (h *grpcHandler) Payload(payload []byte) error {
+	_, err := h.r.handleLine(payload, nil, nil)
+	return err
+}
+
+func (h *grpcHandler) Ping() {
+	h.r.log.Debug("Received gRPC ping.")
+	h.r.heartBeat()
+}
+
+func (h *grpcHandler) Error(err grpcapi.Error) {
+	message := helpers.ObfuscateSensitiveDataFromString(err.Message)
+	recordError(h.r, errors.New(message))
+	fields := logrus.Fields{}
+	for k, v := range err.Attributes {
+		fields[k] = helpers.ObfuscateSensitiveDataFromString(v)
+	}
+	h.r.log.WithFields(fields).WithField("error", message).Warn("integration reported an error")
+}
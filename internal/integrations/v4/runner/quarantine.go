@@ -0,0 +1,144 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/redact"
+)
+
+// Default quarantine settings, overridden by the agent configuration.
+const (
+	defaultQuarantineThreshold = 5
+	defaultQuarantineDuration  = time.Hour
+	// maxQuarantinedPayload bounds the invalid payload kept for the status API.
+	maxQuarantinedPayload = 1024
+)
+
+var (
+	quarantineLock      sync.RWMutex
+	quarantineThreshold = defaultQuarantineThreshold
+	quarantineDuration  = defaultQuarantineDuration
+)
+
+// QuarantineReport represents an integration quarantined for its invalid output.
+type QuarantineReport struct {
+	Name            string    `json:"name"`
+	ConfigName      string    `json:"config_name,omitempty"`
+	Since           time.Time `json:"since"`
+	Until           time.Time `json:"until"`
+	InvalidPayloads int       `json:"invalid_payloads"`
+	LastError       string    `json:"last_error"`
+	LastPayload     string    `json:"last_payload"`
+}
+
+// quarantine tracks the consecutive invalid payloads of an integration.
+type quarantine struct {
+	invalidPayloads int
+	lastErr         string
+	lastPayload     string
+	since           time.Time
+	until           time.Time
+}
+
+// ConfigureQuarantine sets the consecutive invalid payloads after which an integration is quarantined, and for how
+// long. A zero threshold disables the quarantine.
+func ConfigureQuarantine(threshold int, duration time.Duration) {
+	quarantineLock.Lock()
+	defer quarantineLock.Unlock()
+
+	quarantineThreshold, quarantineDuration = threshold, duration
+}
+
+func quarantineSettings() (int, time.Duration) {
+	quarantineLock.RLock()
+	defer quarantineLock.RUnlock()
+
+	return quarantineThreshold, quarantineDuration
+}
+
+// recordPayload records whether a payload of the integration is valid, returning true when its invalid payloads just
+// quarantined it.
+func recordPayload(r *runner, line []byte, err error) (quarantined bool) {
+	threshold, duration := quarantineSettings()
+
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+
+	st, ok := runners[r]
+	if !ok {
+		return false
+	}
+	q := &st.quarantine
+	if err == nil {
+		q.invalidPayloads = 0
+		return false
+	}
+
+	q.invalidPayloads++
+	q.lastErr = err.Error()
+	payload := line
+	if len(payload) > maxQuarantinedPayload {
+		payload = payload[:maxQuarantinedPayload]
+	}
+	q.lastPayload = redact.String(helpers.ObfuscateSensitiveDataFromString(string(payload)))
+	if threshold <= 0 || q.invalidPayloads < threshold || !q.until.IsZero() {
+		return false
+	}
+	q.since = time.Now()
+	q.until = q.since.Add(duration)
+	return true
+}
+
+// quarantinedUntil returns when the quarantine of the integration expires, and false when it's not quarantined,
+// releasing it once expired.
+func quarantinedUntil(r *runner) (time.Time, bool) {
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+
+	st, ok := runners[r]
+	if !ok || st.quarantine.until.IsZero() {
+		return time.Time{}, false
+	}
+	if time.Now().Before(st.quarantine.until) {
+		return st.quarantine.until, true
+	}
+	st.quarantine = quarantine{}
+	r.log.Info("Integration released from quarantine.")
+	return time.Time{}, false
+}
+
+// QuarantineReports returns the integrations quarantined for their invalid output, sorted by name.
+func QuarantineReports() []QuarantineReport {
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+
+	reports := []QuarantineReport{}
+	now := time.Now()
+	for r, st := range runners {
+		q := st.quarantine
+		if q.until.IsZero() || now.After(q.until) {
+			continue
+		}
+		report := QuarantineReport{
+			Name:            r.definition.Name,
+			Since:           q.since,
+			Until:           q.until,
+			InvalidPayloads: q.invalidPayloads,
+			LastError:       q.lastErr,
+			LastPayload:     q.lastPayload,
+		}
+		if r.definition.CfgProtocol != nil {
+			report.ConfigName = r.definition.CfgProtocol.ConfigName
+		}
+		reports = append(reports, report)
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].Name < reports[j].Name
+	})
+	return reports
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/config"
+)
+
+// restarts returns whether the integration runs again after an execution, according to its restart policy.
+func (r *runner) restarts(failed bool) bool {
+	switch r.definition.Restart.Policy {
+	case config.RestartNever:
+		return false
+	case config.RestartOnFailure:
+		return failed
+	default:
+		return true
+	}
+}
+
+// crashLooping records a failed execution, returning whether the integration failed its restart MaxFailures within
+// the FailuresWindow. In that case the integration is stopped and reported as crash looping.
+func (r *runner) crashLooping(now time.Time) bool {
+	restart := r.definition.Restart
+	if restart.MaxFailures <= 0 {
+		return false
+	}
+	r.failures = append(r.failures, now)
+	from := now.Add(-restart.FailuresWindow)
+	for len(r.failures) > 0 && r.failures[0].Before(from) {
+		r.failures = r.failures[1:]
+	}
+	if len(r.failures) < restart.MaxFailures {
+		return false
+	}
+
+	err := fmt.Errorf("integration failed %d times within %s, stopped until its configuration changes", len(r.failures), restart.FailuresWindow)
+	recordCrashLoop(r, now, err)
+	r.log.WithError(err).Error("Integration is crash looping")
+	return true
+}
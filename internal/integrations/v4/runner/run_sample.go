@@ -0,0 +1,195 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/gobackfill"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/executor"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/redact"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const runSampleEventType = "IntegrationRunSample"
+
+const (
+	// stderrSummaryLines are the last standard error lines kept in the run samples.
+	stderrSummaryLines = 3
+	// maxStderrSummary bounds the standard error summary of the run samples.
+	maxStderrSummary = 1024
+	// startErrExitCode is reported when an instance can't be started or waited for.
+	startErrExitCode = -2
+)
+
+var (
+	runSamplesLock sync.RWMutex
+	runSamplesSend func(sample.Event)
+)
+
+// RunSample reports an execution of an integration, aggregating all the instances run for its discovery matches.
+type RunSample struct {
+	sample.BaseEvent
+
+	IntegrationName  string  `json:"integrationName"`
+	ConfigName       string  `json:"configName,omitempty"`
+	DurationMs       float64 `json:"durationMs"`
+	Instances        int     `json:"instances"`
+	DiscoveryMatches int     `json:"discoveryMatches"`
+	FailedInstances  int     `json:"failedInstances"`
+	ExitCode         int     `json:"exitCode"` // first non-zero exit code of the instances, -1 when killed by a signal
+	StdoutBytes      int64   `json:"stdoutBytes"`
+	StderrBytes      int64   `json:"stderrBytes"`
+	StderrLines      int     `json:"stderrLines"`
+	StderrSummary    string  `json:"stderrSummary,omitempty"` // last lines of the standard error, obfuscated
+	Killed           bool    `json:"killed"`                  // by its limits or timeout
+	Error            string  `json:"error,omitempty"`
+}
+
+// ConfigureRunSamples sets the function sending an IntegrationRunSample for every execution of the integrations.
+// Nil disables them.
+func ConfigureRunSamples(send func(sample.Event)) {
+	runSamplesLock.Lock()
+	defer runSamplesLock.Unlock()
+
+	runSamplesSend = send
+}
+
+func runSampleSender() func(sample.Event) {
+	runSamplesLock.RLock()
+	defer runSamplesLock.RUnlock()
+
+	return runSamplesSend
+}
+
+// runStats accumulates the output and exit status of the instances of an execution.
+type runStats struct {
+	mutex       sync.Mutex
+	stdoutBytes int64
+	stderrBytes int64
+	stderrLines int
+	lastStderr  []string
+	failed      int
+	exitCode    int
+	err         string
+}
+
+func (s *runStats) addStdout(line []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stdoutBytes += int64(len(line)) + 1
+}
+
+func (s *runStats) addStderr(line []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stderrBytes += int64(len(line)) + 1
+	s.stderrLines++
+	s.lastStderr = append(s.lastStderr, string(line))
+	if len(s.lastStderr) > stderrSummaryLines {
+		s.lastStderr = s.lastStderr[1:]
+	}
+}
+
+// addError records an error of an instance: the error exit status of the process, or any other error starting or
+// running it.
+func (s *runStats) addError(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// the killed instance also reports its exit status
+	var limitErr *executor.LimitExceededError
+	if errors.As(err, &limitErr) {
+		s.err = limitErr.Error()
+		return
+	}
+
+	exitCode := startErrExitCode
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = gobackfill.ExitCode(exitErr)
+	} else if s.err == "" {
+		s.err = helpers.ObfuscateSensitiveDataFromError(err).Error()
+	}
+	s.failed++
+	if s.exitCode == 0 {
+		s.exitCode = exitCode
+	}
+}
+
+// failures returns the number of instances failing.
+func (s *runStats) failures() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.failed
+}
+
+// errors records the errors of an instance while forwarding them. Once the execution is interrupted, ie: killed on
+// timeout, the errors are only kept while they fit in the buffer, as the ones of the executor are.
+func (s *runStats) errors(ctx context.Context, errs <-chan error) <-chan error {
+	fwd := make(chan error, cap(errs))
+	go func() {
+		defer close(fwd)
+		for err := range errs {
+			s.addError(err)
+			select {
+			case fwd <- err:
+			case <-ctx.Done():
+				select {
+				case fwd <- err:
+				default:
+				}
+			}
+		}
+	}()
+	return fwd
+}
+
+// sample returns the run sample of an execution started at the given time.
+func (s *runStats) sample(r *runner, start time.Time, instances int, killed bool) *RunSample {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rs := &RunSample{
+		IntegrationName: r.definition.Name,
+		DurationMs:      float64(time.Since(start)) / float64(time.Millisecond),
+		Instances:       instances,
+		FailedInstances: s.failed,
+		ExitCode:        s.exitCode,
+		StdoutBytes:     s.stdoutBytes,
+		StderrBytes:     s.stderrBytes,
+		StderrLines:     s.stderrLines,
+		Killed:          killed,
+		Error:           s.err,
+	}
+	if r.definition.CfgProtocol != nil {
+		rs.ConfigName = r.definition.CfgProtocol.ConfigName
+	}
+	if r.dSources != nil {
+		rs.DiscoveryMatches = instances
+	}
+	summary := strings.Join(s.lastStderr, "\n")
+	if len(summary) > maxStderrSummary {
+		summary = summary[len(summary)-maxStderrSummary:]
+	}
+	rs.StderrSummary = redact.String(helpers.ObfuscateSensitiveDataFromString(summary))
+	rs.Type(runSampleEventType)
+	rs.Timestamp(start.Unix())
+	return rs
+}
+
+// sendRunSample sends the run sample of an execution, when enabled.
+func (r *runner) sendRunSample(stats *runStats, start time.Time, instances int, killed bool) {
+	if send := runSampleSender(); send != nil {
+		send(stats.sample(r, start, instances, killed))
+	}
+}
@@ -5,13 +5,16 @@ package runner
 import (
 	"bytes"
 	"context"
+	"errors"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/newrelic/infrastructure-agent/internal/agent/instrumentation"
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/constants"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/executor"
 	"github.com/newrelic/infrastructure-agent/pkg/entity/host"
 
 	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/cache"
@@ -22,11 +25,14 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/databind"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers/contexts"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/redact"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/cmdrequest"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/cmdrequest/protocol"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest"
 	cfgprotocol "github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest/protocol"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/emitter"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/grpcapi"
+	intprotocol "github.com/newrelic/infrastructure-agent/pkg/integrations/v4/protocol"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 
 	"github.com/sirupsen/logrus"
@@ -43,6 +49,10 @@ var (
 	logrusRegexp = regexp.MustCompile(`([^\s]*?)=(".*?[^\\]"|&{.*?}|[^\s]*)`)
 )
 
+// initialBackoff delays the next execution of an integration killed for exceeding its limits or timeout, doubling on
+// every consecutive kill or failure up to the definition MaxBackoff.
+const initialBackoff = 10 * time.Second
+
 // generic types to handle the stderr log parsing
 type logFields map[string]interface{}
 type logParser func(line string) (fields logFields)
@@ -64,6 +74,11 @@ type runner struct {
 	cache          cache.Cache
 	terminateQueue chan<- string
 	idLookup       host.IDLookup
+	limitExceeded  int32 // set when an instance is killed for exceeding its memory limit
+	backoff        time.Duration
+	failures       []time.Time // failed executions within the crash-loop window
+	cancelMutex    sync.Mutex
+	cancelCurrent  context.CancelFunc // stops the running instances when the integration is quarantined
 }
 
 // NewRunner creates an integration runner instance.
@@ -103,8 +118,18 @@ func NewRunner(
 func (r *runner) Run(ctx context.Context, pidWCh, exitCodeCh chan<- int) {
 	r.log = illog.WithFields(LogFields(r.definition))
 	defer r.killChildren()
+	registerRunner(r)
+	defer unregisterRunner(r)
+
+	sched := r.definition.Schedule
+	if !sched.IsZero() {
+		r.log.WithField("schedule", sched.String()).Debug("Integration scheduled")
+		if !r.waitUntil(ctx, sched.First(time.Now())) {
+			return
+		}
+	}
 	for {
-		waitForNextExecution := time.After(r.definition.Interval)
+		started := time.Now()
 
 		// only cmd-channel run-requests require exit-code, and they only trigger a single instance
 		//var exitCodeCh chan int
@@ -112,14 +137,19 @@ func (r *runner) Run(ctx context.Context, pidWCh, exitCodeCh chan<- int) {
 		//	exitCodeCh = make(chan int, 1)
 		//}
 
+		executed, killed, failed := false, false, false
 		discovery, info, err := r.applyDiscovery()
 		if err != nil {
+			recordError(r, err)
 			r.log.
 				WithError(helpers.ObfuscateSensitiveDataFromError(err)).
 				Error("can't fetch discovery items")
+		} else if until, ok := quarantinedUntil(r); ok {
+			r.log.WithField("until", until).Debug("Integration quarantined for its invalid output, skipping execution")
 		} else {
 			if when.All(r.definition.WhenConditions...) {
-				r.execute(ctx, discovery, info, pidWCh, exitCodeCh)
+				executed = true
+				killed, failed = r.execute(ctx, discovery, info, pidWCh, exitCodeCh)
 			} else {
 				r.log.Debug("Integration conditions where not met, skipping execution")
 			}
@@ -130,15 +160,73 @@ func (r *runner) Run(ctx context.Context, pidWCh, exitCodeCh chan<- int) {
 			return
 		}
 
+		if executed && !r.restarts(failed) {
+			r.log.WithField("policy", r.definition.Restart.Policy).Debug("Integration not restarted by its restart policy")
+			return
+		}
+
+		if failed && r.crashLooping(time.Now()) {
+			// it stays reported as crash looping until it's reloaded or the agent stops
+			<-ctx.Done()
+			return
+		}
+
+		if !r.waitUntil(ctx, sched.Next(started, time.Now(), r.definition.Interval)) {
+			return
+		}
+
+		switch {
+		case killed:
+			r.backoff = r.nextBackoff(initialBackoff)
+			r.log.WithField("backoff", r.backoff).Warn("Integration was killed by its limits or timeout, delaying its next execution")
+		case failed && r.definition.Restart.Policy != "":
+			r.backoff = r.nextBackoff(r.definition.Restart.Backoff)
+			r.log.WithField("backoff", r.backoff).Warn("Integration failed, delaying its next execution")
+		default:
+			r.backoff = 0
+			continue
+		}
 		select {
 		case <-ctx.Done():
 			r.log.Debug("Integration has been interrupted")
 			return
-		case <-waitForNextExecution:
+		case <-time.After(r.backoff):
 		}
 	}
 }
 
+// waitUntil waits for the next execution of the integration, returning false when it's interrupted or its schedule
+// has no next execution.
+func (r *runner) waitUntil(ctx context.Context, next time.Time) bool {
+	if next.IsZero() {
+		r.log.Warn("Integration schedule has no next execution, stopping it")
+		return false
+	}
+	recordNextExecution(r, next)
+	if !r.definition.Schedule.IsZero() {
+		r.log.WithField("next", next).Debug("Waiting for the next scheduled execution")
+	}
+	select {
+	case <-ctx.Done():
+		r.log.Debug("Integration has been interrupted")
+		return false
+	case <-time.After(time.Until(next)):
+		return true
+	}
+}
+
+// nextBackoff doubles the previous backoff, or starts with the initial one, up to the definition MaxBackoff.
+func (r *runner) nextBackoff(initial time.Duration) time.Duration {
+	backoff := initial
+	if r.backoff > 0 {
+		backoff = 2 * r.backoff
+	}
+	if backoff > r.definition.MaxBackoff {
+		backoff = r.definition.MaxBackoff
+	}
+	return backoff
+}
+
 func (r *runner) killChildren() {
 	if c := r.cache; c != nil {
 		cfgNames := c.ListConfigNames()
@@ -215,7 +303,9 @@ func (r *runner) heartBeat() {
 // to finish
 // For long-time running integrations, avoids starting the next
 // discover-execute cycle until all the parallel processes have ended
-func (r *runner) execute(ctx context.Context, matches *databind.Values, discoveryInfo databind.DiscovererInfo, pidWCh, exitCodeCh chan<- int) {
+// Returns whether any instance was killed for exceeding its limits or timeout.
+func (r *runner) execute(ctx context.Context, matches *databind.Values, discoveryInfo databind.DiscovererInfo, pidWCh, exitCodeCh chan<- int) (killed, failed bool) {
+	parentCtx := ctx
 	ctx, txn := instrumentation.SelfInstrumentation.StartTransaction(ctx, "integration.v4."+r.definition.Name)
 	if hostname, ok := r.definition.ExecutorConfig.Environment["HOSTNAME"]; ok {
 		txn.AddAttribute("integration_hostname", hostname)
@@ -226,6 +316,8 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, discover
 
 	defer txn.End()
 	def := r.definition
+	start := time.Now()
+	stats := &runStats{}
 
 	// If timeout configuration is set, wraps current context in a heartbeat-enabled timeout context
 	if def.TimeoutEnabled() {
@@ -235,6 +327,12 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, discover
 		defer act.HeartBeatStop()
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.cancelMutex.Lock()
+	r.cancelCurrent = cancel
+	r.cancelMutex.Unlock()
+
 	// add hostID in the context to fetch and set in executor
 	hostID, err := r.idLookup.AgentShortEntityName()
 
@@ -245,12 +343,32 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, discover
 		r.log.WithError(err).Error("can't fetch host ID")
 	}
 
+	// The integration connects to the gRPC server as soon as it starts
+	if def.GRPCSocket != "" {
+		server, err := grpcapi.Listen(def.GRPCSocket, &grpcHandler{r: r})
+		if err != nil {
+			txn.NoticeError(err)
+			recordError(r, err)
+			r.log.WithError(err).Error("can't listen for the integration gRPC protocol")
+			stats.err = err.Error()
+			r.sendRunSample(stats, start, 0, false)
+			return false, true
+		}
+		defer server.Close()
+		if r.dSources != nil {
+			r.log.Debug("Discovery labels and entity rewrites don't apply to the data sent through gRPC.")
+		}
+	}
+
 	// Runs all the matching integration instances
 	outputs, err := r.definition.Run(ctx, matches, discoveryInfo, pidWCh, exitCodeCh)
 	if err != nil {
 		txn.NoticeError(err)
+		recordError(r, err)
 		r.log.WithError(err).Error("can't start integration")
-		return
+		stats.err = helpers.ObfuscateSensitiveDataFromError(err).Error()
+		r.sendRunSample(stats, start, 0, false)
+		return false, true
 	}
 
 	// Waits for all the integrations to finish and reads the standard output and errors
@@ -261,17 +379,17 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, discover
 		o := out
 		go func(txn instrumentation.Transaction) {
 			defer wg.Done()
-			r.handleLines(ctx, o.Receive.Stdout, o.ExtraLabels, o.EntityRewrite)
+			r.handleLines(ctx, o.Receive.Stdout, o.ExtraLabels, o.EntityRewrite, stats)
 		}(txn)
 
 		go func(txn instrumentation.Transaction) {
 			defer wg.Done()
-			r.handleStderr(o.Receive.Stderr)
+			r.handleStderr(o.Receive.Stderr, stats)
 		}(txn)
 
 		go func(txn instrumentation.Transaction) {
 			defer wg.Done()
-			r.handleErrors(ctx, o.Receive.Errors)
+			r.handleErrors(ctx, stats.errors(ctx, o.Receive.Errors))
 
 		}(txn)
 	}
@@ -285,16 +403,26 @@ func (r *runner) execute(ctx context.Context, matches *databind.Values, discover
 	select {
 	case <-ctx.Done():
 		r.log.Debug("Integration has been interrupted. Finishing.")
+		// not interrupted by the agent or the quarantine: the heartbeat timeout expired
+		_, quarantined := quarantinedUntil(r)
+		killed = parentCtx.Err() == nil && def.TimeoutEnabled() && !quarantined
 	case <-waitForCurrent:
+		recordExecution(r, start)
 		r.log.Debug("Integration instances finished their execution. Waiting until next interval.")
 	}
 
-	return
+	killed = killed || atomic.SwapInt32(&r.limitExceeded, 0) == 1
+	// not reported when the agent stops the integration
+	if parentCtx.Err() == nil {
+		r.sendRunSample(stats, start, len(outputs), killed)
+	}
+	return killed, killed || stats.failures() > 0
 }
 
-func (r *runner) handleStderr(stderr <-chan []byte) {
+func (r *runner) handleStderr(stderr <-chan []byte, stats *runStats) {
 	for line := range stderr {
 		r.lastStderr.Add(line)
+		stats.addStderr(line)
 
 		// obfuscated stderr
 		obfuscatedLine := helpers.ObfuscateSensitiveDataFromString(string(line))
@@ -324,6 +452,11 @@ func (r *runner) logErrors(ctx context.Context, errs <-chan error) {
 				// channel closed: exiting
 				return
 			}
+			recordError(r, err)
+			var limitErr *executor.LimitExceededError
+			if errors.As(err, &limitErr) {
+				atomic.StoreInt32(&r.limitExceeded, 1)
+			}
 			flush := r.lastStderr.Flush()
 			// err contains the exit code number
 			r.log.WithError(err).WithField("stderr", helpers.ObfuscateSensitiveDataFromString(flush)).
@@ -332,78 +465,113 @@ func (r *runner) logErrors(ctx context.Context, errs <-chan error) {
 	}
 }
 
-func (r *runner) handleLines(ctx context.Context, stdout <-chan []byte, extraLabels data.Map, entityRewrite []data.EntityRewrite) {
+func (r *runner) handleLines(ctx context.Context, stdout <-chan []byte, extraLabels data.Map, entityRewrite []data.EntityRewrite, stats *runStats) {
 	txn := instrumentation.TransactionFromContext(ctx)
 	payloadSize := 0
 	for line := range stdout {
-		llog := r.log.WithFieldsF(func() logrus.Fields {
-			return logrus.Fields{"payload": string(line)}
-		})
+		stats.addStdout(line)
+		size, _ := r.handleLine(line, extraLabels, entityRewrite)
+		payloadSize += size
+	}
 
-		if isHeartBeat(line) {
-			llog.Debug("Received heartbeat.")
-			r.heartBeat()
-			continue
+	txn.AddAttribute("payload_size", payloadSize)
+}
+
+// handleLine processes a line of the integration output, returning its size when it's an emitted payload, and the
+// error emitting it, if any.
+func (r *runner) handleLine(line []byte, extraLabels data.Map, entityRewrite []data.EntityRewrite) (payloadSize int, err error) {
+	llog := r.log.WithFieldsF(func() logrus.Fields {
+		return logrus.Fields{"payload": string(line)}
+	})
+
+	if isHeartBeat(line) {
+		llog.Debug("Received heartbeat.")
+		r.heartBeat()
+		return 0, nil
+	}
+
+	if ok, ver := protocol.IsCommandRequest(line); ok {
+		if r.handleCmdReq == nil {
+			llog.Warn("received cmd request payload without a handler")
+			return 0, nil
 		}
+		llog.WithField("version", ver).Debug("Received run request.")
+		cr, err := protocol.DeserializeLine(line)
+		if err != nil {
+			llog.
+				WithError(err).
+				Warn("cannot deserialize integration run request payload")
+			return 0, err
+		}
+		r.handleCmdReq(cr)
+		return 0, nil
+	}
 
-		if ok, ver := protocol.IsCommandRequest(line); ok {
-			if r.handleCmdReq == nil {
-				llog.Warn("received cmd request payload without a handler")
-				continue
-			}
-			llog.WithField("version", ver).Debug("Received run request.")
-			cr, err := protocol.DeserializeLine(line)
-			if err != nil {
-				llog.
-					WithError(err).
-					Warn("cannot deserialize integration run request payload")
-				continue
-			}
-			r.handleCmdReq(cr)
-			continue
+	if cfgProtocolBuilder := cfgprotocol.GetConfigProtocolBuilder(line); cfgProtocolBuilder != nil {
+		// obfuscate config protocol output
+		obfuscatedllog := r.log.WithFieldsF(func() logrus.Fields {
+			return logrus.Fields{"payload": helpers.ObfuscateSensitiveDataFromString(string(line))}
+		})
+
+		if r.handleConfig == nil {
+			obfuscatedllog.Warn("received config protocol request payload without a handler")
+			return 0, nil
+		}
+		cfgProtocol, err := cfgProtocolBuilder.Build()
+		obfuscatedllog.WithField("version", cfgProtocol.Version()).Debug("Received config protocol request.")
+		if err != nil {
+			obfuscatedllog.
+				WithError(err).
+				Warn("cannot build config protocol")
+			return 0, err
 		}
 
-		if cfgProtocolBuilder := cfgprotocol.GetConfigProtocolBuilder(line); cfgProtocolBuilder != nil {
-			// obfuscate config protocol output
-			obfuscatedllog := r.log.WithFieldsF(func() logrus.Fields {
-				return logrus.Fields{"payload": helpers.ObfuscateSensitiveDataFromString(string(line))}
-			})
+		r.handleConfig(cfgProtocol, r.cache, r.definition)
+		return 0, nil
+	}
 
-			if r.handleConfig == nil {
-				obfuscatedllog.Warn("received config protocol request payload without a handler")
-				continue
-			}
-			cfgProtocol, err := cfgProtocolBuilder.Build()
-			obfuscatedllog.WithField("version", cfgProtocol.Version()).Debug("Received config protocol request.")
-			if err != nil {
-				obfuscatedllog.
-					WithError(err).
-					Warn("cannot build config protocol")
-				continue
-			}
+	err = intprotocol.Validate(line)
+	if recordPayload(r, line, err) {
+		threshold, duration := quarantineSettings()
+		recordError(r, err)
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"invalid_payloads": threshold,
+			"duration":         duration,
+		}).Error("Integration quarantined for repeatedly emitting invalid payloads, stopping it")
+		r.stopCurrent()
+		return 0, err
+	}
+	if err != nil {
+		recordError(r, err)
+		llog.WithError(err).Warn("Rejected integration payload")
+		return 0, err
+	}
 
-			r.handleConfig(cfgProtocol, r.cache, r.definition)
-			continue
-		}
+	err = r.emitter.Emit(r.definition, extraLabels, entityRewrite, redact.JSON(line))
+	if err != nil {
+		llog.WithError(err).Warn("Cannot emit integration payload")
+	} else {
+		r.heartBeat()
+	}
 
-		payloadSize += len(line)
-		err := r.emitter.Emit(r.definition, extraLabels, entityRewrite, line)
-		if err != nil {
-			llog.WithError(err).Warn("Cannot emit integration payload")
+	r.healthCheck.Do(func() {
+		if err == nil {
+			r.log.Info("Integration health check finished with success")
 		} else {
-			r.heartBeat()
+			r.log.WithError(err).Warn("Integration health check finished with some errors")
 		}
+	})
+	return len(line), err
+}
 
-		r.healthCheck.Do(func() {
-			if err == nil {
-				r.log.Info("Integration health check finished with success")
-			} else {
-				r.log.WithError(err).Warn("Integration health check finished with some errors")
-			}
-		})
-	}
+// stopCurrent cancels the running instances of the integration.
+func (r *runner) stopCurrent() {
+	r.cancelMutex.Lock()
+	defer r.cancelMutex.Unlock()
 
-	txn.AddAttribute("payload_size", payloadSize)
+	if r.cancelCurrent != nil {
+		r.cancelCurrent()
+	}
 }
 
 func contextWithHostID(ctx context.Context, hostID string) context.Context {
@@ -6,6 +6,7 @@ package runner
 import (
 	"context"
 	"github.com/newrelic/infrastructure-agent/pkg/entity/host"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
 	"io/ioutil"
 	"os"
 	"runtime"
@@ -22,6 +23,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/configrequest/protocol"
 	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/config"
+	"github.com/newrelic/infrastructure-agent/pkg/integrations/v4/grpcapi"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
@@ -185,3 +187,333 @@ func Test_runner_Run_handlesCfgProtocol(t *testing.T) {
 		return false
 	}, time.Second, 10*time.Millisecond)
 }
+
+func TestIntegrationReports(t *testing.T) {
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.IntegrationScript, "bar"),
+		Interval:     "15s",
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	r := NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, nil, nil)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		reports := IntegrationReports()
+		return len(reports) == 1 && reports[0].Executions == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	report := IntegrationReports()[0]
+	assert.Equal(t, "foo", report.Name)
+	assert.Equal(t, 15, report.IntervalSec)
+	assert.NotNil(t, report.LastExecution)
+	assert.Zero(t, report.Errors)
+
+	// stopped integrations are not reported
+	cancel()
+	<-done
+	assert.Empty(t, IntegrationReports())
+}
+
+func Test_runner_Run_GRPCProtocol(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         config.ShlexOpt{"sleep", "5"},
+		Protocol:     config.ProtocolGRPC,
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	e := &testemit.RecordEmitter{}
+	r := NewRunner(def, e, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, nil, nil)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// the integration connects once the socket is listening
+	var client *grpcapi.Client
+	require.Eventually(t, func() bool {
+		dialCtx, dialCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer dialCancel()
+		client, err = grpcapi.Dial(dialCtx, def.GRPCSocket)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	defer client.Close()
+
+	require.NoError(t, client.Send([]byte(`{"name":"com.newrelic.test","protocol_version":"1","integration_version":"1.0.0","metrics":[{"event_type":"TestSample","value":"bar"}]}`)))
+	assert.Error(t, client.Send([]byte(`not a payload`)))
+
+	dataset, err := e.ReceiveFrom("foo")
+	require.NoError(t, err)
+	metrics := dataset.DataSet.Metrics
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "bar", metrics[0]["value"])
+}
+
+func Test_runner_nextBackoff(t *testing.T) {
+	maxBackoff := 50 * time.Second
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         testhelp.Command(fixtures.IntegrationScript, "bar"),
+		Limits:       config.ResourceLimits{MemoryMB: 10, MaxBackoff: &maxBackoff},
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10<<20), def.ExecutorConfig.Limits.MemoryBytes)
+
+	r := NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{})
+	var backoffs []time.Duration
+	for i := 0; i < 4; i++ {
+		r.backoff = r.nextBackoff(initialBackoff)
+		backoffs = append(backoffs, r.backoff)
+	}
+	assert.Equal(t, []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 50 * time.Second}, backoffs)
+}
+
+func Test_runner_Run_QuarantinesInvalidOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	ConfigureQuarantine(2, time.Hour)
+	defer ConfigureQuarantine(defaultQuarantineThreshold, defaultQuarantineDuration)
+
+	// GIVEN an integration emitting payloads without the v4 integration metadata
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         config.ShlexOpt{"/bin/sh", "-c", `echo '{"protocol_version":"4","data":[]}'; echo '{"protocol_version":"4","data":[]}'; sleep 30`},
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	r := NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, nil, nil)
+		close(done)
+	}()
+
+	// THEN the integration is quarantined with the schema error
+	require.Eventually(t, func() bool {
+		return len(QuarantineReports()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	report := QuarantineReports()[0]
+	assert.Equal(t, "foo", report.Name)
+	assert.Equal(t, 2, report.InvalidPayloads)
+	assert.Equal(t, "invalid protocol v4 payload at integration: required", report.LastError)
+	assert.Equal(t, `{"protocol_version":"4","data":[]}`, report.LastPayload)
+	assert.True(t, report.Until.Sub(report.Since) == time.Hour)
+	assert.NotNil(t, IntegrationReports()[0].QuarantinedUntil)
+
+	// AND the valid payloads reset the count of invalid ones
+	_, err = r.handleLine([]byte(`{"name":"com.newrelic.test","protocol_version":"1","metrics":[{"event_type":"TestSample"}]}`), nil, nil)
+	assert.NoError(t, err)
+	_, err = r.handleLine([]byte(`not a payload`), nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1, QuarantineReports()[0].InvalidPayloads)
+
+	cancel()
+	<-done
+	assert.Empty(t, QuarantineReports())
+}
+
+func Test_runner_Run_Schedule(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	run := func(ce config.ConfigEntry) (context.CancelFunc, chan struct{}) {
+		def, err := integration.NewDefinition(ce, integration.ErrLookup, nil, nil)
+		require.NoError(t, err)
+		r := NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{})
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			r.Run(ctx, nil, nil)
+			close(done)
+		}()
+		return cancel, done
+	}
+
+	// GIVEN an integration running once, despite its interval
+	cancel, done := run(config.ConfigEntry{
+		InstanceName: "once",
+		Exec:         config.ShlexOpt{"/bin/echo", "{}"},
+		Interval:     "15s",
+		Schedule:     config.Schedule{RunOnce: true},
+	})
+	defer cancel()
+	// THEN it finishes after its single execution
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run once integration didn't finish")
+	}
+
+	// GIVEN an integration scheduled within a time window starting in a few hours
+	now := time.Now()
+	window := now.Add(2*time.Hour).Format("15:04") + "-" + now.Add(3*time.Hour).Format("15:04")
+	cancel, done = run(config.ConfigEntry{
+		InstanceName: "windowed",
+		Exec:         config.ShlexOpt{"/bin/echo", "{}"},
+		Schedule:     config.Schedule{Window: window},
+	})
+	defer cancel()
+
+	// THEN it waits for the window to start
+	require.Eventually(t, func() bool {
+		reports := IntegrationReports()
+		return len(reports) == 1 && reports[0].NextExecution != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	report := IntegrationReports()[0]
+	assert.Equal(t, "interval within "+window, report.Schedule)
+	assert.Equal(t, 0, report.Executions)
+	assert.WithinDuration(t, now.Add(2*time.Hour), *report.NextExecution, time.Minute)
+
+	cancel()
+	<-done
+}
+
+func Test_runner_Run_RunSamples(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	samples := make(chan sample.Event, 10)
+	ConfigureRunSamples(func(e sample.Event) {
+		samples <- e
+	})
+	defer ConfigureRunSamples(nil)
+
+	// GIVEN an integration writing to stdout and stderr before exiting with an error
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "foo",
+		Exec:         config.ShlexOpt{"/bin/sh", "-c", `echo '{}'; echo first >&2; echo 'password=s3cr3t' >&2; exit 3`},
+		Schedule:     config.Schedule{RunOnce: true},
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+
+	// WHEN it runs
+	NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}).
+		Run(context.Background(), nil, nil)
+
+	// THEN a run sample reports its execution
+	var e sample.Event
+	select {
+	case e = <-samples:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no run sample received")
+	}
+	rs, ok := e.(*RunSample)
+	require.True(t, ok)
+	assert.Equal(t, "IntegrationRunSample", rs.EventType)
+	assert.Equal(t, "foo", rs.IntegrationName)
+	assert.Equal(t, 1, rs.Instances)
+	assert.Equal(t, 0, rs.DiscoveryMatches)
+	assert.Equal(t, 1, rs.FailedInstances)
+	assert.Equal(t, 3, rs.ExitCode)
+	assert.Equal(t, int64(3), rs.StdoutBytes)
+	assert.Equal(t, 2, rs.StderrLines)
+	assert.Equal(t, "first\npassword=<HIDDEN>", rs.StderrSummary)
+	assert.False(t, rs.Killed)
+	assert.True(t, rs.DurationMs > 0)
+}
+
+func Test_runner_Run_RestartPolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	for _, tc := range []struct {
+		policy string
+		script string
+	}{
+		{config.RestartOnFailure, "exit 0"},
+		{config.RestartNever, "exit 1"},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			// GIVEN an integration whose restart policy doesn't restart it after its execution
+			def, err := integration.NewDefinition(config.ConfigEntry{
+				InstanceName: "foo",
+				Exec:         config.ShlexOpt{"/bin/sh", "-c", tc.script},
+				Restart:      config.Restart{Policy: tc.policy},
+			}, integration.ErrLookup, nil, nil)
+			require.NoError(t, err)
+			def.Interval = 10 * time.Millisecond
+
+			// WHEN it runs THEN it finishes after its first execution
+			done := make(chan struct{})
+			go func() {
+				NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}).
+					Run(context.Background(), nil, nil)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("integration should not be restarted")
+			}
+		})
+	}
+}
+
+func Test_runner_Run_CrashLoop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+	// GIVEN an integration failing on every execution, with a crash-loop breaker
+	zero := time.Duration(0)
+	def, err := integration.NewDefinition(config.ConfigEntry{
+		InstanceName: "crashing",
+		Exec:         config.ShlexOpt{"/bin/sh", "-c", "exit 1"},
+		Restart:      config.Restart{Policy: config.RestartAlways, Backoff: &zero, MaxFailures: 3},
+	}, integration.ErrLookup, nil, nil)
+	require.NoError(t, err)
+	def.Interval = 10 * time.Millisecond
+
+	// WHEN it runs
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewRunner(def, &testemit.RecordEmitter{}, nil, nil, cmdrequest.NoopHandleFn, configrequest.NoopHandleFn, nil, host.IDLookup{}).
+			Run(ctx, nil, nil)
+		close(done)
+	}()
+
+	// THEN it's stopped after 3 failures and reported as crash looping until it's interrupted
+	var report IntegrationReport
+	require.Eventually(t, func() bool {
+		for _, report = range IntegrationReports() {
+			if report.Name == "crashing" && report.CrashLoopSince != nil {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "integration failed 3 times within 10m0s, stopped until its configuration changes", report.LastError)
+	executions := report.Executions
+	time.Sleep(50 * time.Millisecond)
+	for _, report = range IntegrationReports() {
+		if report.Name == "crashing" {
+			assert.Equal(t, executions, report.Executions)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("integration should be interrupted")
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package runner
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+var (
+	runnersLock sync.Mutex
+	runners     = map[*runner]*runnerStatus{}
+)
+
+// IntegrationReport represents the executions of a running integration and its last error.
+type IntegrationReport struct {
+	Name           string     `json:"name"`
+	ConfigName     string     `json:"config_name,omitempty"`
+	IntervalSec    int        `json:"interval_sec"`
+	Started        time.Time  `json:"started"`
+	Executions     int        `json:"executions"`
+	LastExecution  *time.Time `json:"last_execution,omitempty"`
+	LastDurationMs float64    `json:"last_duration_ms"`
+	Errors         int        `json:"errors"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorTime  *time.Time `json:"last_error_time,omitempty"`
+	// QuarantinedUntil is set while the integration is quarantined for its invalid output.
+	QuarantinedUntil *time.Time `json:"quarantined_until,omitempty"`
+	// Schedule and NextExecution are set for the integrations with a schedule other than every interval.
+	Schedule      string     `json:"schedule,omitempty"`
+	NextExecution *time.Time `json:"next_execution,omitempty"`
+	// CrashLoopSince is set when the integration was stopped by its crash-loop breaker.
+	CrashLoopSince *time.Time `json:"crash_loop_since,omitempty"`
+}
+
+type runnerStatus struct {
+	started       time.Time
+	executions    int
+	lastExecution time.Time
+	lastDuration  time.Duration
+	errors        int
+	lastErr       string
+	lastErrTime   time.Time
+	quarantine    quarantine
+	nextExecution time.Time
+	crashLoop     time.Time
+}
+
+func registerRunner(r *runner) {
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+
+	runners[r] = &runnerStatus{started: time.Now()}
+}
+
+func unregisterRunner(r *runner) {
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+
+	delete(runners, r)
+}
+
+// recordExecution records an execution of the integration instances, started at the given time.
+func recordExecution(r *runner, start time.Time) {
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+
+	if st, ok := runners[r]; ok {
+		st.executions++
+		st.lastExecution, st.lastDuration = start, time.Since(start)
+	}
+}
+
+// recordNextExecution records when the next execution of the integration starts.
+func recordNextExecution(r *runner, next time.Time) {
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+
+	if st, ok := runners[r]; ok {
+		st.nextExecution = next
+	}
+}
+
+// recordError records an error discovering, starting or running the integration, obfuscating its secrets.
+func recordError(r *runner, err error) {
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+
+	if st, ok := runners[r]; ok {
+		st.errors++
+		st.lastErr, st.lastErrTime = helpers.ObfuscateSensitiveDataFromError(err).Error(), time.Now()
+	}
+}
+
+// recordCrashLoop records the integration was stopped by its crash-loop breaker, with its error.
+func recordCrashLoop(r *runner, since time.Time, err error) {
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+
+	if st, ok := runners[r]; ok {
+		st.crashLoop = since
+		st.errors++
+		st.lastErr, st.lastErrTime = err.Error(), since
+	}
+}
+
+// IntegrationReports returns the running integrations, sorted by name.
+func IntegrationReports() []IntegrationReport {
+	runnersLock.Lock()
+	defer runnersLock.Unlock()
+
+	reports := make([]IntegrationReport, 0, len(runners))
+	for r, st := range runners {
+		report := IntegrationReport{
+			Name:           r.definition.Name,
+			IntervalSec:    int(r.definition.Interval / time.Second),
+			Started:        st.started,
+			Executions:     st.executions,
+			LastDurationMs: float64(st.lastDuration) / float64(time.Millisecond),
+			Errors:         st.errors,
+		}
+		if r.definition.CfgProtocol != nil {
+			report.ConfigName = r.definition.CfgProtocol.ConfigName
+		}
+		if !st.lastExecution.IsZero() {
+			lastExecution := st.lastExecution
+			report.LastExecution = &lastExecution
+		}
+		if st.lastErr != "" {
+			errTime := st.lastErrTime
+			report.LastError, report.LastErrorTime = st.lastErr, &errTime
+		}
+		if until := st.quarantine.until; !until.IsZero() && time.Now().Before(until) {
+			report.QuarantinedUntil = &until
+		}
+		if !r.definition.Schedule.IsZero() {
+			report.Schedule = r.definition.Schedule.String()
+			if next := st.nextExecution; !next.IsZero() {
+				report.NextExecution = &next
+			}
+		}
+		if since := st.crashLoop; !since.IsZero() {
+			report.CrashLoopSince = &since
+		}
+		reports = append(reports, report)
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].Name < reports[j].Name
+	})
+	return reports
+}
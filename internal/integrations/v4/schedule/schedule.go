@@ -0,0 +1,99 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package schedule decides when the executions of the v4 integrations start: every interval, at the times of a cron
+// expression or once, optionally only within a daily time window.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Schedule of the executions of an integration. The zero value runs them every interval.
+type Schedule struct {
+	Cron    *Cron   // not nil: the executions start at the times of the expression, instead of every interval
+	Window  *Window // not nil: the executions only start within the daily time window
+	RunOnce bool    // a single execution, when the integration is loaded or at the next start of the window
+}
+
+// New returns the schedule for a cron expression, a "HH:MM-HH:MM" daily window and whether the integration runs once.
+// Empty expressions and windows are not set.
+func New(cron, window string, runOnce bool) (s Schedule, err error) {
+	if cron != "" && runOnce {
+		return Schedule{}, errors.New("a schedule can't define both a cron expression and run once")
+	}
+	s.RunOnce = runOnce
+	if cron != "" {
+		if s.Cron, err = ParseCron(cron); err != nil {
+			return Schedule{}, err
+		}
+	}
+	if window != "" {
+		if s.Window, err = ParseWindow(window); err != nil {
+			return Schedule{}, err
+		}
+	}
+	if s.Cron != nil && s.Window != nil && s.First(time.Now()).IsZero() {
+		return Schedule{}, fmt.Errorf("cron expression %q never matches within the time window %s", cron, s.Window)
+	}
+	return s, nil
+}
+
+// IsZero returns whether the schedule is the default one, every interval.
+func (s Schedule) IsZero() bool {
+	return s.Cron == nil && s.Window == nil && !s.RunOnce
+}
+
+// First returns when the first execution starts. The zero time means never.
+func (s Schedule) First(now time.Time) time.Time {
+	if s.Cron != nil {
+		return s.nextCron(now)
+	}
+	return s.inWindow(now)
+}
+
+// Next returns when the execution after the one started and finished at the given times starts: an interval after
+// it started or, for cron expressions, the first matching time after it finished, so the times missed by long
+// executions are skipped. The zero time means never.
+func (s Schedule) Next(started, finished time.Time, interval time.Duration) time.Time {
+	if s.Cron != nil {
+		return s.nextCron(finished)
+	}
+	return s.inWindow(started.Add(interval))
+}
+
+func (s Schedule) inWindow(t time.Time) time.Time {
+	if s.Window == nil {
+		return t
+	}
+	return s.Window.Next(t)
+}
+
+// nextCron returns the first time matching the cron expression within the window, if any.
+func (s Schedule) nextCron(after time.Time) time.Time {
+	limit := after.Add(maxLookahead)
+	for t := s.Cron.Next(after); !t.IsZero() && !t.After(limit); t = s.Cron.Next(t) {
+		if s.Window == nil || s.Window.Contains(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s Schedule) String() string {
+	var desc string
+	switch {
+	case s.Cron != nil:
+		desc = "cron " + s.Cron.String()
+	case s.RunOnce:
+		desc = "once"
+	default:
+		desc = "interval"
+	}
+	if s.Window != nil {
+		desc += " within " + s.Window.String()
+	}
+	return desc
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(day, hour, minute int) time.Time {
+	// 2024-01-01 is a Monday
+	return time.Date(2024, time.January, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestCron_Next(t *testing.T) {
+	testCases := []struct {
+		expr  string
+		after time.Time
+		next  time.Time
+	}{
+		{"* * * * *", date(1, 10, 0), date(1, 10, 1)},
+		{"*/15 * * * *", date(1, 10, 7), date(1, 10, 15)},
+		{"5/20 * * * *", date(1, 10, 30), date(1, 10, 45)},
+		{"0 3 * * *", date(1, 3, 0), date(2, 3, 0)},
+		{"@daily", date(1, 10, 0), date(2, 0, 0)},
+		{"@hourly", date(1, 10, 30), date(1, 11, 0)},
+		{"30 2 * * sun", date(1, 10, 0), date(7, 2, 30)},
+		{"30 2 * * 7", date(1, 10, 0), date(7, 2, 30)},
+		{"0 9-17/4 * * MON-FRI", date(5, 14, 0), date(5, 17, 0)},
+		{"0 9-17/4 * * MON-FRI", date(5, 17, 0), date(8, 9, 0)},
+		{"0 0 1,15 * *", date(2, 0, 0), date(15, 0, 0)},
+		{"0 0 1 feb *", date(2, 0, 0), time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", date(2, 0, 0), time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// restricted day of month and week: any of them matches
+		{"0 0 15 * fri", date(1, 0, 0), date(5, 0, 0)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			cron, err := ParseCron(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.next, cron.Next(tc.after))
+		})
+	}
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * foo *",
+		"*/0 * * * *", "5-1 * * * *", "0 0 30 2 *", "@every 5m"} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := ParseCron(expr)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	w, err := ParseWindow("02:00-04:00")
+	require.NoError(t, err)
+	assert.True(t, w.Contains(date(1, 2, 0)))
+	assert.True(t, w.Contains(date(1, 3, 59)))
+	assert.False(t, w.Contains(date(1, 4, 0)))
+	assert.Equal(t, date(1, 3, 0), w.Next(date(1, 3, 0)))
+	assert.Equal(t, date(1, 2, 0), w.Next(date(1, 1, 0)))
+	assert.Equal(t, date(2, 2, 0), w.Next(date(1, 5, 0)))
+
+	// crossing midnight
+	w, err = ParseWindow("22:30 - 01:00")
+	require.NoError(t, err)
+	assert.True(t, w.Contains(date(1, 23, 0)))
+	assert.True(t, w.Contains(date(1, 0, 30)))
+	assert.False(t, w.Contains(date(1, 12, 0)))
+	assert.Equal(t, date(1, 22, 30), w.Next(date(1, 12, 0)))
+	assert.Equal(t, "22:30-01:00", w.String())
+
+	for _, window := range []string{"02:00", "2-4", "02:00-25:00", "02:00-02:00"} {
+		_, err = ParseWindow(window)
+		assert.Error(t, err, window)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	interval := 30 * time.Second
+
+	// every interval
+	var s Schedule
+	assert.True(t, s.IsZero())
+	assert.Equal(t, date(1, 10, 0), s.First(date(1, 10, 0)))
+	assert.Equal(t, date(1, 10, 0).Add(interval), s.Next(date(1, 10, 0), date(1, 10, 0), interval))
+
+	// every interval within a window
+	s, err := New("", "02:00-04:00", false)
+	require.NoError(t, err)
+	assert.Equal(t, date(2, 2, 0), s.First(date(1, 10, 0)))
+	assert.Equal(t, date(1, 3, 0).Add(interval), s.Next(date(1, 3, 0), date(1, 3, 0), interval))
+	assert.Equal(t, date(2, 2, 0), s.Next(date(1, 3, 59), date(1, 3, 59), 2*time.Minute))
+
+	// cron times within a window, skipping the times missed by long executions
+	s, err = New("*/30 * * * *", "02:00-04:00", false)
+	require.NoError(t, err)
+	assert.Equal(t, date(2, 2, 0), s.First(date(1, 10, 0)))
+	assert.Equal(t, date(1, 3, 30), s.Next(date(1, 2, 0), date(1, 3, 10), interval))
+	assert.Equal(t, date(2, 2, 0), s.Next(date(1, 3, 30), date(1, 3, 31), interval))
+	assert.Equal(t, "cron */30 * * * * within 02:00-04:00", s.String())
+
+	// once
+	s, err = New("", "", true)
+	require.NoError(t, err)
+	assert.Equal(t, date(1, 10, 0), s.First(date(1, 10, 0)))
+	assert.Equal(t, "once", s.String())
+
+	_, err = New("@daily", "", true)
+	assert.Error(t, err)
+	_, err = New("0 12 * * *", "02:00-04:00", false)
+	assert.EqualError(t, err, `cron expression "0 12 * * *" never matches within the time window 02:00-04:00`)
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily time window, in minutes of the day. It crosses midnight when it ends before it starts,
+// ie: 22:00-02:00.
+type Window struct {
+	start int
+	end   int
+}
+
+// ParseWindow parses a "HH:MM-HH:MM" daily time window.
+func ParseWindow(window string) (*Window, error) {
+	bounds := strings.Split(strings.ReplaceAll(window, " ", ""), "-")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("invalid time window %q, expected HH:MM-HH:MM", window)
+	}
+	start, err := parseTimeOfDay(bounds[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid time window %q start: %s", window, err)
+	}
+	end, err := parseTimeOfDay(bounds[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid time window %q end: %s", window, err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("invalid time window %q: it can't start and end at the same time", window)
+	}
+	return &Window{start: start, end: end}, nil
+}
+
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains returns whether the time of the day of t is within the window.
+func (w *Window) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// Next returns t when it's within the window, or the next start of the window otherwise.
+func (w *Window) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	start := time.Date(t.Year(), t.Month(), t.Day(), w.start/60, w.start%60, 0, 0, t.Location())
+	if !start.After(t) {
+		start = time.Date(t.Year(), t.Month(), t.Day()+1, w.start/60, w.start%60, 0, 0, t.Location())
+	}
+	return start
+}
+
+func (w *Window) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.start/60, w.start%60, w.end/60, w.end%60)
+}
@@ -56,6 +56,7 @@ func (t *RecordEmitter) Emit(metadata integration.Definition, extraLabels data.M
 					Entity: ds.Entity,
 					// TODO but for now it's enough for the assertion mechanism:
 					Metrics: make([]protocol.MetricData, len(ds.Metrics)),
+					Events:  ds.Events,
 				}},
 				Metadata:      metadata,
 				ExtraLabels:   extraLabels,
@@ -2,7 +2,11 @@
 // SPDX-License-Identifier: Apache-2.0
 package when
 
-import "os"
+import (
+	"os"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/leader"
+)
 
 // Condition is any function that can return true or false
 type Condition func() bool
@@ -32,6 +36,15 @@ func EnvExists(envVars map[string]string) Condition {
 	}
 }
 
+// Leader creates a Condition returning true when the agent leads the group. When leader election is not configured,
+// it's always true, as the agent is considered alone.
+func Leader(group string) Condition {
+	return func() bool {
+		elector := leader.Default()
+		return elector == nil || elector.IsLeader(group)
+	}
+}
+
 // All returns true if and only if all the passed conditions are true.
 // If an empty conditions list is passed, it also returns true.
 func All(conditions ...Condition) bool {
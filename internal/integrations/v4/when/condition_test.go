@@ -6,9 +6,12 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/newrelic/infrastructure-agent/internal/agent/leader"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -74,6 +77,31 @@ func TestEnvExists(t *testing.T) {
 	}
 }
 
+func TestLeader(t *testing.T) {
+	// GIVEN no leader election configured
+	leader.SetDefault(nil)
+
+	// THEN the Leader condition returns true, as the agent is alone
+	assert.True(t, Leader("db")())
+
+	// GIVEN another agent leading the group
+	dir, err := ioutil.TempDir("", "conditions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	other := leader.NewElector(dir, time.Minute, "other")
+	defer other.Close()
+	require.True(t, other.IsLeader("db"))
+
+	agent := leader.NewElector(dir, time.Minute, "agent")
+	defer agent.Close()
+	leader.SetDefault(agent)
+	defer leader.SetDefault(nil)
+
+	// THEN the Leader condition returns false for its group only
+	assert.False(t, Leader("db")())
+	assert.True(t, Leader("cache")())
+}
+
 func TestAll(t *testing.T) {
 	trueFunc := func() bool { return true }
 	falseFunc := func() bool { return false }
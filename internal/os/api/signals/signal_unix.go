@@ -14,4 +14,6 @@ const (
 	Notification = syscall.SIGUSR1
 	// GracefulStop signal is used to gracefully stop, we use SIGTSTP as SIGSTOP can not be handled.
 	GracefulStop = syscall.SIGUSR2
+	// Reload signal is used to reload the configuration file without restarting the agent.
+	Reload = syscall.SIGHUP
 )
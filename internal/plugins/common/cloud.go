@@ -5,6 +5,8 @@ package common
 
 import (
 	"fmt"
+	"strconv"
+
 	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
 )
 
@@ -13,6 +15,8 @@ type CloudData struct {
 	AzureCloudData   `mapstructure:",squash"`
 	GoogleCloudData  `mapstructure:",squash"`
 	AlibabaCloudData `mapstructure:",squash"`
+	OracleCloudData  `mapstructure:",squash"`
+	HetznerCloudData `mapstructure:",squash"`
 }
 
 type AwsCloudData struct {
@@ -26,16 +30,31 @@ type AzureCloudData struct {
 	RegionAzure           string `json:"region_name,omitempty"`
 	AzureSubscriptionID   string `json:"azure_subscription_id,omitempty"`
 	AzureAvailabilityZone string `json:"azure_availability_zone,omitempty"`
+	AzureVMScaleSet       string `json:"azure_vm_scale_set,omitempty"`
+	AzureSpot             string `json:"azure_spot,omitempty"`
 }
 
 type GoogleCloudData struct {
-	RegionGCP string `json:"zone,omitempty"`
+	RegionGCP        string `json:"zone,omitempty"`
+	GCPInstanceGroup string `json:"gcp_instance_group,omitempty"`
+	GCPPreemptible   string `json:"gcp_preemptible,omitempty"`
 }
 
 type AlibabaCloudData struct {
 	RegionAlibaba string `json:"region_id,omitempty"`
 }
 
+type OracleCloudData struct {
+	RegionOCI             string `json:"oci_region,omitempty"`
+	OCICompartmentID      string `json:"oci_compartment_id,omitempty"`
+	OCIAvailabilityDomain string `json:"oci_availability_domain,omitempty"`
+}
+
+type HetznerCloudData struct {
+	RegionHetzner     string `json:"hetzner_region,omitempty"`
+	HetznerDatacenter string `json:"hetzner_datacenter,omitempty"`
+}
+
 // getAWSCloudData gathers the exported information for the AWS Cloud.
 func getAWSCloudData(cloudHarvester cloud.Harvester) (awsData AwsCloudData, err error) {
 	awsData.RegionAWS, err = cloudHarvester.GetRegion()
@@ -78,6 +97,78 @@ func getAzureCloudData(cloudHarvester cloud.Harvester) (azureData AzureCloudData
 		return azureData, fmt.Errorf("couldn't retrieve cloud availability zone: %w", err)
 	}
 
+	azureData.AzureVMScaleSet, azureData.AzureSpot, err = getCapacityData(cloudHarvester)
+	return
+}
+
+// getGCPCloudData gathers the exported information for the Google Cloud.
+func getGCPCloudData(cloudHarvester cloud.Harvester) (gcpData GoogleCloudData, err error) {
+	gcpData.RegionGCP, err = cloudHarvester.GetRegion()
+	if err != nil {
+		return gcpData, fmt.Errorf("couldn't retrieve cloud region: %w", err)
+	}
+
+	gcpData.GCPInstanceGroup, gcpData.GCPPreemptible, err = getCapacityData(cloudHarvester)
+	return
+}
+
+// getCapacityData returns the scale group of the instance and whether it's a spot one, when provided by the
+// detected harvester.
+func getCapacityData(cloudHarvester cloud.Harvester) (scaleGroup string, spot string, err error) {
+	h, err := cloudHarvester.GetHarvester()
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't retrieve cloud harvester: %w", err)
+	}
+	capacityHarvester, ok := h.(cloud.CapacityHarvester)
+	if !ok {
+		return "", "", nil
+	}
+
+	scaleGroup, err = capacityHarvester.GetScaleGroup()
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't retrieve cloud scale group: %w", err)
+	}
+
+	isSpot, err := capacityHarvester.IsSpot()
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't retrieve cloud spot flag: %w", err)
+	}
+
+	return scaleGroup, strconv.FormatBool(isSpot), nil
+}
+
+// getOCICloudData gathers the exported information for the Oracle Cloud Infrastructure.
+func getOCICloudData(cloudHarvester cloud.Harvester) (ociData OracleCloudData, err error) {
+	ociData.RegionOCI, err = cloudHarvester.GetRegion()
+	if err != nil {
+		return ociData, fmt.Errorf("couldn't retrieve cloud region: %w", err)
+	}
+
+	ociData.OCICompartmentID, err = cloudHarvester.GetAccountID()
+	if err != nil {
+		return ociData, fmt.Errorf("couldn't retrieve cloud compartment ID: %w", err)
+	}
+
+	ociData.OCIAvailabilityDomain, err = cloudHarvester.GetZone()
+	if err != nil {
+		return ociData, fmt.Errorf("couldn't retrieve cloud availability domain: %w", err)
+	}
+
+	return
+}
+
+// getHetznerCloudData gathers the exported information for the Hetzner Cloud.
+func getHetznerCloudData(cloudHarvester cloud.Harvester) (hetznerData HetznerCloudData, err error) {
+	hetznerData.RegionHetzner, err = cloudHarvester.GetRegion()
+	if err != nil {
+		return hetznerData, fmt.Errorf("couldn't retrieve cloud region: %w", err)
+	}
+
+	hetznerData.HetznerDatacenter, err = cloudHarvester.GetZone()
+	if err != nil {
+		return hetznerData, fmt.Errorf("couldn't retrieve cloud datacenter: %w", err)
+	}
+
 	return
 }
 
@@ -89,9 +180,13 @@ func getCloudData(cloudHarvester cloud.Harvester) (cloudData CloudData, err erro
 	case cloud.TypeAzure:
 		cloudData.AzureCloudData, err = getAzureCloudData(cloudHarvester)
 	case cloud.TypeGCP:
-		cloudData.RegionGCP, err = cloudHarvester.GetRegion()
+		cloudData.GoogleCloudData, err = getGCPCloudData(cloudHarvester)
 	case cloud.TypeAlibaba:
 		cloudData.RegionAlibaba, err = cloudHarvester.GetRegion()
+	case cloud.TypeOCI:
+		cloudData.OracleCloudData, err = getOCICloudData(cloudHarvester)
+	case cloud.TypeHetzner:
+		cloudData.HetznerCloudData, err = getHetznerCloudData(cloudHarvester)
 	case cloud.TypeNoCloud:
 		return
 	}
@@ -34,7 +34,12 @@ type HostInfoData struct {
 	CpuNum string `json:"cpu_num"`
 	// Total number of cores in all the CPUs
 	// It is shown as 'processorCount' in New Relic UI
-	TotalCpu        string `json:"total_cpu"`
+	TotalCpu string `json:"total_cpu"`
+	// Number of physical CPUs, when known
+	CpuSockets string `json:"cpu_sockets,omitempty"`
+	// Number of cores of each model of heterogeneous CPUs (ie: ARM big.LITTLE or Apple Silicon performance and
+	// efficiency cores), empty when all the cores are the same
+	CpuClusters     string `json:"cpu_clusters,omitempty"`
 	Ram             string `json:"ram"`
 	UpSince         string `json:"boot_timestamp"`
 	AgentVersion    string `json:"agent_version"`
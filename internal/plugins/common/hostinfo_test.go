@@ -162,6 +162,36 @@ func TestGetHostInfo(t *testing.T) {
 				h.On("GetRegion").Return("us-east-1", nil)
 			},
 		},
+		{
+			name: "cloud oci",
+			assertions: func(data *HostInfoData, err error) {
+				assert.Equal(t, "", data.RegionAWS)
+				assert.Equal(t, "us-phoenix-1", data.RegionOCI)
+				assert.Equal(t, "ocid1.compartment.oc1..aaaa", data.OCICompartmentID)
+				assert.Equal(t, "EMIr:PHX-AD-1", data.OCIAvailabilityDomain)
+				assert.NoError(t, err)
+			},
+			setMock: func(h *fakeHarvester) {
+				h.On("GetCloudType").Return(cloud.TypeOCI)
+				h.On("GetRegion").Return("us-phoenix-1", nil)
+				h.On("GetAccountID").Return("ocid1.compartment.oc1..aaaa", nil)
+				h.On("GetZone").Return("EMIr:PHX-AD-1", nil)
+			},
+		},
+		{
+			name: "cloud hetzner",
+			assertions: func(data *HostInfoData, err error) {
+				assert.Equal(t, "", data.RegionAWS)
+				assert.Equal(t, "eu-central", data.RegionHetzner)
+				assert.Equal(t, "fsn1-dc14", data.HetznerDatacenter)
+				assert.NoError(t, err)
+			},
+			setMock: func(h *fakeHarvester) {
+				h.On("GetCloudType").Return(cloud.TypeHetzner)
+				h.On("GetRegion").Return("eu-central", nil)
+				h.On("GetZone").Return("fsn1-dc14", nil)
+			},
+		},
 		{
 			name: "cloud error",
 			assertions: func(data *HostInfoData, err error) {
@@ -194,6 +224,47 @@ func TestGetHostInfo(t *testing.T) {
 	}
 }
 
+type fakeCapacityHarvester struct {
+	*fakeHarvester
+	scaleGroup string
+	spot       bool
+}
+
+func (f *fakeCapacityHarvester) GetHarvester() (cloud.Harvester, error) {
+	return f, nil
+}
+
+func (f *fakeCapacityHarvester) GetScaleGroup() (string, error) {
+	return f.scaleGroup, nil
+}
+
+func (f *fakeCapacityHarvester) IsSpot() (bool, error) {
+	return f.spot, nil
+}
+
+func TestGetHostInfo_Capacity(t *testing.T) {
+	h := &fakeCapacityHarvester{fakeHarvester: new(fakeHarvester), scaleGroup: "web-mig", spot: true}
+	h.On("GetCloudType").Return(cloud.TypeGCP)
+	h.On("GetRegion").Return("us-central1-a", nil)
+
+	data, err := NewHostInfoCommon("test", true, h).GetHostInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "us-central1-a", data.RegionGCP)
+	assert.Equal(t, "web-mig", data.GCPInstanceGroup)
+	assert.Equal(t, "true", data.GCPPreemptible)
+
+	h = &fakeCapacityHarvester{fakeHarvester: new(fakeHarvester)}
+	h.On("GetCloudType").Return(cloud.TypeAzure)
+	h.On("GetRegion").Return("northeurope", nil)
+	h.On("GetAccountID").Return("x123", nil)
+	h.On("GetZone").Return("1", nil)
+
+	data, err = NewHostInfoCommon("test", true, h).GetHostInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "", data.AzureVMScaleSet)
+	assert.Equal(t, "false", data.AzureSpot)
+}
+
 func TestGetCloudHostType(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -133,6 +133,10 @@ func (hip *HostinfoPlugin) gatherHostinfo(context agent.AgentContext) *HostInfoD
 	data.CpuName = cpuName
 	data.CpuNum = fmt.Sprintf("%d", cpuNum)
 	data.TotalCpu = ho.TotalNumberOfCores
+	if numberOfProcessors > 0 {
+		data.CpuSockets = ho.NumberOfProcessors
+	}
+	data.CpuClusters = ho.CoreClusters
 	data.Ram = ho.Memory
 	data.UpSince = getUpSince()
 	data.OperatingSystem = "macOS"
@@ -183,6 +187,18 @@ type processorInfo struct {
 	ProcessorSpeed     string
 	NumberOfProcessors string
 	TotalNumberOfCores string
+	// CoreClusters is the number of performance and efficiency cores of Apple Silicon, ie: "8 performance, 2 efficiency"
+	CoreClusters string
+}
+
+// coreClusters returns the number of cores of each kind out of the system_profiler total number of cores, ie:
+// "8 performance, 2 efficiency" for "10 (8 performance and 2 efficiency)". Empty when not detailed.
+func coreClusters(totalNumberOfCores string) string {
+	start, end := strings.Index(totalNumberOfCores, "("), strings.LastIndex(totalNumberOfCores, ")")
+	if start == -1 || end < start {
+		return ""
+	}
+	return strings.ReplaceAll(totalNumberOfCores[start+1:end], " and ", ", ")
 }
 
 func (hip *HostinfoPlugin) getHardwareOverview() (hardwareOverview, error) {
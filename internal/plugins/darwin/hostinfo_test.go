@@ -120,6 +120,11 @@ func TestValidateHardwareUUID(t *testing.T) {
 	}
 }
 
+func TestCoreClusters(t *testing.T) {
+	assert.Equal(t, "8 performance, 2 efficiency", coreClusters("10 (8 performance and 2 efficiency)"))
+	assert.Equal(t, "", coreClusters("6"))
+}
+
 type HostInfoMock struct {
 	getHostInfo      func() (common.HostInfoData, error)
 	getCloudHostType func() (string, error)
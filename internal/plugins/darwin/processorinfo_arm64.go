@@ -16,5 +16,7 @@ func getProcessorData(output string) processorInfo {
 		// Apple doesn’t particularly expose the clock speed on Apple silicon configurations.
 		ProcessorSpeed:     "",
 		TotalNumberOfCores: helpers.SplitRightSubstring(output, "Total Number of Cores: ", " "),
+		// ie: Total Number of Cores: 10 (8 performance and 2 efficiency)
+		CoreClusters: coreClusters(helpers.SplitRightSubstring(output, "Total Number of Cores: ", "\n")),
 	}
 }
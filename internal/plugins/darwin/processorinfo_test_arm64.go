@@ -54,6 +54,8 @@ func TestData(t *testing.T) {
 					CpuName:         "Apple M1 Max",
 					CpuNum:          "10",
 					TotalCpu:        "10",
+					CpuSockets:      "1",
+					CpuClusters:     "8 performance, 2 efficiency",
 					Ram:             "67108864 kB",
 					UpSince:         "2021-07-01 09:59:30",
 					AgentVersion:    "mock",
@@ -102,6 +104,8 @@ func TestData(t *testing.T) {
 			assert.Equal(t, tt.expectedData.CpuName, data.CpuName)
 			assert.Equal(t, tt.expectedData.CpuNum, data.CpuNum)
 			assert.Equal(t, tt.expectedData.TotalCpu, data.TotalCpu)
+			assert.Equal(t, tt.expectedData.CpuSockets, data.CpuSockets)
+			assert.Equal(t, tt.expectedData.CpuClusters, data.CpuClusters)
 			assert.Equal(t, tt.expectedData.Ram, data.Ram)
 			// assert.Equal(t, expected.UpSince, data.UpSince)
 			assert.Equal(t, tt.expectedData.AgentVersion, data.AgentVersion)
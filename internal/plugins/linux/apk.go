@@ -0,0 +1,214 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/agent/types"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	APK_DB_DIR         = "/lib/apk/db"
+	APK_INSTALLED_FILE = "installed"
+)
+
+var apklog = log.WithPlugin("Apk")
+
+type ApkPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+	dbDir     string
+	rootDir   string
+}
+
+type ApkItem struct {
+	Name         string `json:"id"`
+	Version      string `json:"version"`
+	Architecture string `json:"architecture"`
+	InstallTime  string `json:"installed_epoch"`
+	Source       string `json:"source,omitempty"`
+	Digest       string `json:"digest,omitempty"`
+}
+
+func (self ApkItem) SortKey() string {
+	return self.Name
+}
+
+func NewApkPlugin(id ids.PluginID, ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &ApkPlugin{
+		PluginCommon: agent.PluginCommon{ID: id, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.ApkRefreshSec,
+			config.FREQ_MINIMUM_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_PACKAGE_MGRS_UPDATES,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		dbDir:   APK_DB_DIR,
+		rootDir: "/",
+	}
+}
+
+func (self *ApkPlugin) fetchPackageInfo() (packages types.PluginInventoryDataset, err error) {
+	f, err := os.Open(filepath.Join(self.dbDir, APK_INSTALLED_FILE))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return self.parsePackageInfo(f)
+}
+
+// parsePackageInfo parses the apk installed database, made of blank line separated stanzas of "<field>:<value>"
+// lines for each installed package.
+func (self *ApkPlugin) parsePackageInfo(r io.Reader) (packages types.PluginInventoryDataset, err error) {
+	var item ApkItem
+	var firstFile, dir string
+	add := func() {
+		if item.Name != "" {
+			item.InstallTime = self.guessInstallTime(firstFile)
+			packages = append(packages, item)
+		}
+		item, firstFile, dir = ApkItem{}, "", ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			add()
+			continue
+		}
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch field {
+		case "P":
+			item.Name = value
+		case "V":
+			item.Version = value
+		case "A":
+			item.Architecture = value
+		case "o":
+			item.Source = value
+		case "C":
+			item.Digest = apkDigest(value)
+		case "F":
+			dir = value
+		case "R":
+			if firstFile == "" {
+				firstFile = filepath.Join(dir, value)
+			}
+		}
+	}
+	add()
+	return packages, scanner.Err()
+}
+
+// apkDigest returns the checksum of the package control section, stored as "Q1" followed by the base64 encoded SHA1,
+// as "sha1:<hex>".
+func apkDigest(checksum string) string {
+	if !strings.HasPrefix(checksum, "Q1") {
+		return ""
+	}
+	sum, err := base64.StdEncoding.DecodeString(checksum[2:])
+	if err != nil {
+		return ""
+	}
+	return "sha1:" + hex.EncodeToString(sum)
+}
+
+// guessInstallTime makes a best guess at a package's install time, as apk doesn't record it, by grabbing the
+// creation time of the first file it installed. It returns an empty string for packages without files.
+func (self *ApkPlugin) guessInstallTime(file string) string {
+	if file == "" {
+		return ""
+	}
+	fi, err := os.Lstat(filepath.Join(self.rootDir, file))
+	if err != nil {
+		return ""
+	}
+	stat := fi.Sys().(*syscall.Stat_t)
+	ctime := time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec))
+
+	return fmt.Sprintf("%d", ctime.Unix())
+}
+
+// Run is the main processing loop that drives the logic for the plugin
+func (self *ApkPlugin) Run() {
+	if self.frequency <= config.FREQ_DISABLE_SAMPLING {
+		apklog.Debug("Disabled.")
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		apklog.WithError(err).Error("can't instantiate apk watcher")
+		self.Unregister()
+		return
+	}
+
+	// apk replaces the installed database by renaming a temporary file, so its directory is watched
+	err = watcher.Add(self.dbDir)
+	if err != nil {
+		apklog.WithError(err).Error("can't setup trigger file watcher for apk")
+		self.Unregister()
+		return
+	}
+
+	counter := 1
+	ticker := time.NewTicker(1)
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if ok {
+				if filepath.Base(event.Name) == APK_INSTALLED_FILE && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+					counter = counter + 1
+					if counter > 1 {
+						apklog.WithFields(logrus.Fields{
+							"frequency": self.frequency,
+							"counter":   counter,
+						}).Debug("apk plugin oversampling.")
+					}
+				}
+			} else {
+				apklog.Debug("apk database watcher closed.")
+				return
+			}
+		case <-ticker.C:
+			ticker.Stop()
+			ticker = time.NewTicker(self.frequency)
+			if counter > 0 {
+				data, err := self.fetchPackageInfo()
+				if err != nil {
+					apklog.WithError(err).Error("fetching apk data")
+				} else {
+					self.EmitInventory(data, entity.NewFromNameWithoutID(self.Context.EntityKey()))
+				}
+				counter = 0
+			}
+		}
+	}
+}
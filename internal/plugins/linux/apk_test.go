@@ -0,0 +1,77 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testing2 "github.com/newrelic/infrastructure-agent/internal/plugins/testing"
+)
+
+const apkInstalled = `C:Q1Ud06BCbGbp8Ta6WYKUB2jXb8TAE=
+P:musl
+V:1.2.4-r2
+A:x86_64
+S:383152
+I:622592
+T:the musl c library (libc) implementation
+o:musl
+F:lib
+R:ld-musl-x86_64.so.1
+a:0:0:755
+Z:Q1ztnUfVx4Kk6T+ySK1mrA34P0xu8=
+R:libc.musl-x86_64.so.1
+
+C:Q1fHxbi3sszkCU7Vve1b5VH6AUEa0=
+P:alpine-baselayout-data
+V:3.4.3-r1
+A:x86_64
+o:alpine-baselayout
+`
+
+func TestApkParsePackageInfo(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "lib", "ld-musl-x86_64.so.1"), nil, 0755))
+
+	p := NewApkPlugin(ids.PluginID{Category: "packages", Term: "apk"}, testing2.NewMockAgent()).(*ApkPlugin)
+	p.rootDir = root
+
+	packages, err := p.parsePackageInfo(strings.NewReader(apkInstalled))
+	require.NoError(t, err)
+	require.Len(t, packages, 2)
+
+	musl := packages[0].(ApkItem)
+	assert.NotEmpty(t, musl.InstallTime)
+	musl.InstallTime = ""
+	assert.Equal(t, ApkItem{
+		Name:         "musl",
+		Version:      "1.2.4-r2",
+		Architecture: "x86_64",
+		Source:       "musl",
+		Digest:       "sha1:51dd3a0426c66e9f136ba5982940768d76fc4c01",
+	}, musl)
+
+	assert.Equal(t, ApkItem{
+		Name:         "alpine-baselayout-data",
+		Version:      "3.4.3-r1",
+		Architecture: "x86_64",
+		Source:       "alpine-baselayout",
+		Digest:       "sha1:7c7c5b8b7b2cce4094ed5bded5be551fa01411ad",
+	}, packages[1])
+}
+
+func TestApkDigest(t *testing.T) {
+	assert.Equal(t, "", apkDigest("invalid"))
+	assert.Equal(t, "", apkDigest("Q1!"))
+}
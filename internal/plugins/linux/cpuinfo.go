@@ -0,0 +1,194 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux || darwin
+// +build linux darwin
+
+package linux
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const awsSysVendor = "Amazon EC2"
+
+// armImplementers are the vendors of the ARM cores, by their "CPU implementer" code in /proc/cpuinfo.
+var armImplementers = map[string]string{
+	"0x41": "ARM",
+	"0x42": "Broadcom",
+	"0x43": "Cavium",
+	"0x46": "Fujitsu",
+	"0x48": "HiSilicon",
+	"0x4e": "NVIDIA",
+	"0x50": "APM",
+	"0x51": "Qualcomm",
+	"0x61": "Apple",
+	"0xc0": "Ampere",
+}
+
+// armParts are the core models, by implementer and "CPU part" codes, as listed by lscpu.
+var armParts = map[string]string{
+	"0x41/0xd03": "Cortex-A53",
+	"0x41/0xd04": "Cortex-A35",
+	"0x41/0xd05": "Cortex-A55",
+	"0x41/0xd07": "Cortex-A57",
+	"0x41/0xd08": "Cortex-A72",
+	"0x41/0xd09": "Cortex-A73",
+	"0x41/0xd0a": "Cortex-A75",
+	"0x41/0xd0b": "Cortex-A76",
+	"0x41/0xd0c": "Neoverse-N1",
+	"0x41/0xd0d": "Cortex-A77",
+	"0x41/0xd40": "Neoverse-V1",
+	"0x41/0xd41": "Cortex-A78",
+	"0x41/0xd44": "Cortex-X1",
+	"0x41/0xd46": "Cortex-A510",
+	"0x41/0xd47": "Cortex-A710",
+	"0x41/0xd48": "Cortex-X2",
+	"0x41/0xd49": "Neoverse-N2",
+	"0x41/0xd4a": "Neoverse-E1",
+	"0x41/0xd4f": "Neoverse-V2",
+	"0x41/0xd80": "Cortex-A520",
+	"0x41/0xd81": "Cortex-A720",
+	"0x41/0xd82": "Cortex-X4",
+	"0x41/0xd84": "Neoverse-V3",
+	"0x41/0xd8e": "Neoverse-N3",
+	"0x43/0x0af": "ThunderX2 99xx",
+	"0x46/0x001": "A64FX",
+	"0x48/0xd01": "TaiShan-v110",
+	"0x4e/0x004": "Carmel",
+	"0x61/0x022": "Icestorm",
+	"0x61/0x023": "Firestorm",
+	"0xc0/0xac3": "Ampere-1",
+	"0xc0/0xac4": "Ampere-1a",
+}
+
+// gravitonParts are the AWS Graviton generations, by the ARM core they're built on.
+var gravitonParts = map[string]string{
+	"0x41/0xd08": "AWS Graviton",
+	"0x41/0xd0c": "AWS Graviton2",
+	"0x41/0xd40": "AWS Graviton3",
+	"0x41/0xd4f": "AWS Graviton4",
+}
+
+// armCPU is the CPU information of ARM hosts, whose /proc/cpuinfo has neither the "model name" nor the
+// "cpu cores" entries.
+type armCPU struct {
+	// ModelName is the vendor and the model of the cores, ie: "ARM Neoverse-N1", or "AWS Graviton2 (Neoverse-N1)" on
+	// EC2. The models of heterogeneous (big.LITTLE) CPUs are joined, ie: "ARM Cortex-A55 + Cortex-A76".
+	ModelName string
+	// Clusters lists the number of cores of each model of heterogeneous CPUs, ie: "4x Cortex-A55, 4x Cortex-A76".
+	// Empty when all the cores are the same.
+	Clusters string
+}
+
+// parseARMCPUInfo returns the ARM CPU information out of the /proc/cpuinfo content, where every processor
+// reports its "CPU implementer" and "CPU part". The ok value is false when the content isn't the one of an ARM
+// CPU. The sysVendor, from the DMI, identifies the AWS Graviton processors.
+func parseARMCPUInfo(cpuinfo, sysVendor string) (cpu armCPU, ok bool) {
+	var order []string
+	counts := make(map[string]int)
+	implementer := ""
+	for _, line := range strings.Split(cpuinfo, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "CPU implementer":
+			implementer = strings.ToLower(strings.TrimSpace(value))
+		case "CPU part":
+			part := implementer + "/" + strings.ToLower(strings.TrimSpace(value))
+			if counts[part] == 0 {
+				order = append(order, part)
+			}
+			counts[part]++
+		}
+	}
+	if len(order) == 0 {
+		return cpu, false
+	}
+
+	if len(order) == 1 {
+		part := order[0]
+		cpu.ModelName = armVendor(part) + " " + armPartName(part)
+		if graviton, isGraviton := gravitonParts[part]; isGraviton && sysVendor == awsSysVendor {
+			cpu.ModelName = fmt.Sprintf("%s (%s)", graviton, armPartName(part))
+		}
+		return cpu, true
+	}
+
+	names := make([]string, 0, len(order))
+	clusters := make([]string, 0, len(order))
+	for _, part := range order {
+		names = append(names, armPartName(part))
+		clusters = append(clusters, fmt.Sprintf("%dx %s", counts[part], armPartName(part)))
+	}
+	cpu.ModelName = armVendor(order[0]) + " " + strings.Join(names, " + ")
+	cpu.Clusters = strings.Join(clusters, ", ")
+	return cpu, true
+}
+
+func armVendor(part string) string {
+	implementer, _, _ := strings.Cut(part, "/")
+	if vendor, ok := armImplementers[implementer]; ok {
+		return vendor
+	}
+	return "ARM implementer " + implementer
+}
+
+func armPartName(part string) string {
+	if name, ok := armParts[part]; ok {
+		return name
+	}
+	_, code, _ := strings.Cut(part, "/")
+	return "part " + code
+}
+
+// cpuTopology is the number of sockets and cores per socket, read from the sysfs topology of the CPUs.
+type cpuTopology struct {
+	Sockets        int
+	CoresPerSocket int
+}
+
+// readCPUTopology reads the topology of the CPUs under the sysfs cpu directory (/sys/devices/system/cpu). The cores
+// are identified by their package, cluster and core IDs, as ARM cores of different clusters share core IDs. A
+// package ID of -1, reported by ARM kernels not knowing the socket, is counted as a single socket.
+func readCPUTopology(cpuDir string) (cpuTopology, error) {
+	dirs, err := filepath.Glob(filepath.Join(cpuDir, "cpu[0-9]*", "topology"))
+	if err != nil {
+		return cpuTopology{}, err
+	}
+	if len(dirs) == 0 {
+		return cpuTopology{}, fmt.Errorf("no cpu topology found in %s", cpuDir)
+	}
+
+	sockets := make(map[string]bool)
+	cores := make(map[string]bool)
+	for _, dir := range dirs {
+		pkg := readTopologyID(dir, "physical_package_id")
+		cluster := readTopologyID(dir, "cluster_id")
+		core := readTopologyID(dir, "core_id")
+		sockets[pkg] = true
+		cores[pkg+"/"+cluster+"/"+core] = true
+	}
+
+	return cpuTopology{
+		Sockets:        len(sockets),
+		CoresPerSocket: len(cores) / len(sockets),
+	}, nil
+}
+
+func readTopologyID(dir, name string) string {
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	id := strings.TrimSpace(string(content))
+	if n, err := strconv.Atoi(id); err != nil || n < 0 {
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux || darwin
+// +build linux darwin
+
+package linux
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func armCPUInfo(parts ...string) string {
+	var sb strings.Builder
+	for i, part := range parts {
+		implementer, code, _ := strings.Cut(part, "/")
+		sb.WriteString("processor\t: " + strconv.Itoa(i) + "\n")
+		sb.WriteString("BogoMIPS\t: 243.75\n")
+		sb.WriteString("Features\t: fp asimd evtstrm aes pmull sha1 sha2 crc32 atomics fphp asimdhp cpuid\n")
+		sb.WriteString("CPU implementer\t: " + implementer + "\n")
+		sb.WriteString("CPU architecture: 8\n")
+		sb.WriteString("CPU variant\t: 0x3\n")
+		sb.WriteString("CPU part\t: " + code + "\n")
+		sb.WriteString("CPU revision\t: 1\n\n")
+	}
+	return sb.String()
+}
+
+func TestParseARMCPUInfo(t *testing.T) {
+	tests := []struct {
+		name      string
+		cpuinfo   string
+		sysVendor string
+		expected  armCPU
+	}{
+		{
+			name:      "graviton2",
+			cpuinfo:   armCPUInfo("0x41/0xd0c", "0x41/0xd0c"),
+			sysVendor: "Amazon EC2",
+			expected:  armCPU{ModelName: "AWS Graviton2 (Neoverse-N1)"},
+		},
+		{
+			name:      "graviton3",
+			cpuinfo:   armCPUInfo("0x41/0xd40"),
+			sysVendor: "Amazon EC2",
+			expected:  armCPU{ModelName: "AWS Graviton3 (Neoverse-V1)"},
+		},
+		{
+			name:      "ampere altra",
+			cpuinfo:   armCPUInfo("0x41/0xd0c", "0x41/0xd0c"),
+			sysVendor: "Hetzner",
+			expected:  armCPU{ModelName: "ARM Neoverse-N1"},
+		},
+		{
+			name:     "ampereone",
+			cpuinfo:  armCPUInfo("0xc0/0xac3"),
+			expected: armCPU{ModelName: "Ampere Ampere-1"},
+		},
+		{
+			name:     "big.LITTLE",
+			cpuinfo:  armCPUInfo("0x41/0xd05", "0x41/0xd05", "0x41/0xd05", "0x41/0xd05", "0x41/0xd0b", "0x41/0xd0b"),
+			expected: armCPU{ModelName: "ARM Cortex-A55 + Cortex-A76", Clusters: "4x Cortex-A55, 2x Cortex-A76"},
+		},
+		{
+			name:     "apple silicon",
+			cpuinfo:  armCPUInfo("0x61/0x022", "0x61/0x022", "0x61/0x023"),
+			expected: armCPU{ModelName: "Apple Icestorm + Firestorm", Clusters: "2x Icestorm, 1x Firestorm"},
+		},
+		{
+			name:     "unknown part",
+			cpuinfo:  armCPUInfo("0x99/0x123"),
+			expected: armCPU{ModelName: "ARM implementer 0x99 part 0x123"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpu, ok := parseARMCPUInfo(tt.cpuinfo, tt.sysVendor)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, cpu)
+		})
+	}
+}
+
+func TestParseARMCPUInfo_NotARM(t *testing.T) {
+	_, ok := parseARMCPUInfo(cpuinfo, "")
+	assert.False(t, ok)
+}
+
+func TestReadCPUTopology(t *testing.T) {
+	writeTopology := func(t *testing.T, dir string, cpu int, ids map[string]string) {
+		t.Helper()
+		topology := filepath.Join(dir, "cpu"+strconv.Itoa(cpu), "topology")
+		require.NoError(t, os.MkdirAll(topology, 0o755))
+		for name, id := range ids {
+			require.NoError(t, os.WriteFile(filepath.Join(topology, name), []byte(id+"\n"), 0o644))
+		}
+	}
+
+	t.Run("two sockets with hyper-threading", func(t *testing.T) {
+		dir := t.TempDir()
+		for cpu := 0; cpu < 8; cpu++ {
+			writeTopology(t, dir, cpu, map[string]string{
+				"physical_package_id": strconv.Itoa(cpu / 4),
+				"core_id":             strconv.Itoa(cpu % 2),
+			})
+		}
+		topology, err := readCPUTopology(dir)
+		require.NoError(t, err)
+		assert.Equal(t, cpuTopology{Sockets: 2, CoresPerSocket: 2}, topology)
+	})
+
+	t.Run("arm clusters sharing core ids and unknown package", func(t *testing.T) {
+		dir := t.TempDir()
+		for cpu := 0; cpu < 8; cpu++ {
+			writeTopology(t, dir, cpu, map[string]string{
+				"physical_package_id": "-1",
+				"cluster_id":          strconv.Itoa(cpu / 4),
+				"core_id":             strconv.Itoa(cpu % 4),
+			})
+		}
+		topology, err := readCPUTopology(dir)
+		require.NoError(t, err)
+		assert.Equal(t, cpuTopology{Sockets: 1, CoresPerSocket: 8}, topology)
+	})
+
+	t.Run("no topology", func(t *testing.T) {
+		_, err := readCPUTopology(t.TempDir())
+		assert.Error(t, err)
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -29,6 +30,7 @@ import (
 
 const (
 	DPKG_INFO_DIR   = "/var/lib/dpkg/info"
+	APT_LISTS_DIR   = "/var/lib/apt/lists"
 	FSN_CLOSE_WRITE = 16
 )
 
@@ -37,6 +39,7 @@ var dpkglog = log.WithPlugin("Dpkg")
 type DpkgPlugin struct {
 	agent.PluginCommon
 	frequency time.Duration
+	listsDir  string
 }
 
 type DpkgItem struct {
@@ -47,6 +50,9 @@ type DpkgItem struct {
 	Status       string `json:"status"`
 	Version      string `json:"version"`
 	InstallTime  string `json:"installed_epoch"`
+	Source       string `json:"source,omitempty"`
+	Repository   string `json:"repository,omitempty"`
+	Digest       string `json:"digest,omitempty"`
 }
 
 func (self DpkgItem) SortKey() string {
@@ -63,6 +69,7 @@ func NewDpkgPlugin(id ids.PluginID, ctx agent.AgentContext) agent.Plugin {
 			config.FREQ_PLUGIN_PACKAGE_MGRS_UPDATES,
 			cfg.DisableAllPlugins,
 		) * time.Second,
+		listsDir: APT_LISTS_DIR,
 	}
 }
 
@@ -96,34 +103,156 @@ func (self *DpkgPlugin) guessInstallTime(packageName string, arch string) string
 }
 
 func (self *DpkgPlugin) fetchPackageInfo() (packages types.PluginInventoryDataset, err error) {
-	output, err := helpers.RunCommand("/usr/bin/dpkg-query", "", "-W", "-f=${Package} ${Status} ${Architecture} ${Version} ${Essential} ${Priority}\n")
+	output, err := helpers.RunCommand("/usr/bin/dpkg-query", "", "-W", "-f=${Package}\t${Status}\t${Architecture}\t${Version}\t${Essential}\t${Priority}\t${source:Package}\n")
 	if err != nil {
 		return nil, err
 	}
+	return self.parsePackageInfo(output), nil
+}
 
+// parsePackageInfo parses the tab separated dpkg-query output, completing the packages with the repository and
+// digest of the apt lists entry they were installed from, if any.
+func (self *DpkgPlugin) parsePackageInfo(output string) (packages types.PluginInventoryDataset) {
+	var items []*DpkgItem
 	scanner := bufio.NewScanner(strings.NewReader(output))
 	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
-		if len(parts) < 8 {
+		parts := strings.Split(scanner.Text(), "\t")
+		if len(parts) < 6 {
+			continue
+		}
+		// Status is "<want> <error flag> <status>", ie: "install ok installed"
+		status := strings.Fields(parts[1])
+		if len(status) < 3 {
 			continue
 		}
 
-		dpkgItem := DpkgItem{
+		dpkgItem := &DpkgItem{
 			Name:         parts[0],
-			Status:       parts[3],
-			Architecture: parts[4],
-			Version:      parts[5],
-			Essential:    parts[6],
-			Priority:     parts[7],
-			InstallTime:  self.guessInstallTime(parts[0], parts[4]),
+			Status:       status[2],
+			Architecture: parts[2],
+			Version:      parts[3],
+			Essential:    parts[4],
+			Priority:     parts[5],
+			InstallTime:  self.guessInstallTime(parts[0], parts[2]),
 		}
-
-		packages = append(packages, dpkgItem)
+		if len(parts) > 6 {
+			dpkgItem.Source = strings.TrimSpace(parts[6])
+		}
+		items = append(items, dpkgItem)
 	}
 
+	origins := aptOrigins(self.listsDir, items)
+	for _, item := range items {
+		if o, ok := origins[aptKey(item.Name, item.Version, item.Architecture)]; ok {
+			item.Repository = o.repository
+			item.Digest = o.digest
+		}
+		packages = append(packages, *item)
+	}
 	return
 }
 
+// aptOrigin is the apt lists entry a package was installed from.
+type aptOrigin struct {
+	repository string
+	digest     string
+}
+
+func aptKey(name, version, arch string) string {
+	return name + " " + version + " " + arch
+}
+
+// aptOrigins looks up the installed packages in the uncompressed apt "Packages" lists, returning the repository and
+// the .deb digest of each one found, by its aptKey. The lists are usually removed in containers, so nothing is
+// returned in that case.
+func aptOrigins(listsDir string, items []*DpkgItem) map[string]aptOrigin {
+	origins := make(map[string]aptOrigin)
+	if len(items) == 0 {
+		return origins
+	}
+	installed := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		installed[aptKey(item.Name, item.Version, item.Architecture)] = struct{}{}
+	}
+
+	lists, err := filepath.Glob(filepath.Join(listsDir, "*_Packages"))
+	if err != nil {
+		return origins
+	}
+	sort.Strings(lists)
+	for _, list := range lists {
+		if err := readAptList(list, installed, origins); err != nil {
+			dpkglog.WithError(err).WithField("file", list).Debug("Can't read apt list.")
+		}
+	}
+	return origins
+}
+
+// readAptList adds to origins the installed packages found in an apt "Packages" list. The first list a package is
+// found in wins.
+func readAptList(list string, installed map[string]struct{}, origins map[string]aptOrigin) error {
+	f, err := os.Open(list)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	repository := aptRepositoryName(filepath.Base(list))
+	var name, version, arch, sha256 string
+	add := func() {
+		if name != "" && sha256 != "" {
+			key := aptKey(name, version, arch)
+			if _, ok := installed[key]; ok {
+				if _, found := origins[key]; !found {
+					origins[key] = aptOrigin{repository: repository, digest: "sha256:" + sha256}
+				}
+			}
+		}
+		name, version, arch, sha256 = "", "", "", ""
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			add()
+			continue
+		}
+		field, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		switch field {
+		case "Package":
+			name = value
+		case "Version":
+			version = value
+		case "Architecture":
+			arch = value
+		case "SHA256":
+			sha256 = value
+		}
+	}
+	add()
+	return scanner.Err()
+}
+
+// aptRepositoryName returns the repository of an apt list from its file name, ie:
+// "archive.ubuntu.com_ubuntu_dists_jammy_main_binary-amd64_Packages" is "archive.ubuntu.com/ubuntu jammy/main".
+func aptRepositoryName(listFile string) string {
+	base, dist, ok := strings.Cut(strings.TrimSuffix(listFile, "_Packages"), "_dists_")
+	if !ok {
+		return strings.ReplaceAll(strings.TrimSuffix(listFile, "_Packages"), "_", "/")
+	}
+	parts := strings.Split(dist, "_")
+	if len(parts) >= 3 {
+		// drop the binary-<arch> directory
+		parts = parts[:len(parts)-1]
+	}
+	return strings.ReplaceAll(base, "_", "/") + " " + strings.Join(parts, "/")
+}
+
 // Run is the main processing loop that drives the logic for the plugin
 func (self *DpkgPlugin) Run() {
 	if self.frequency <= config.FREQ_DISABLE_SAMPLING {
@@ -0,0 +1,75 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testing2 "github.com/newrelic/infrastructure-agent/internal/plugins/testing"
+)
+
+const aptList = `Package: bash
+Architecture: amd64
+Version: 5.1-6ubuntu1
+Priority: required
+Filename: pool/main/b/bash/bash_5.1-6ubuntu1_amd64.deb
+SHA256: 2f4a1a1ac1a2f4c6e6e7b1c9b4d3e1e6b0b8a4e4c1a7d0e9f1a2b3c4d5e6f7a8
+
+Package: bash
+Architecture: amd64
+Version: 5.0-6ubuntu1
+SHA256: 0000000000000000000000000000000000000000000000000000000000000000
+
+Package: tzdata
+Architecture: all
+Version: 2022a-0ubuntu1
+SHA256: 1111111111111111111111111111111111111111111111111111111111111111
+`
+
+func TestDpkgParsePackageInfo(t *testing.T) {
+	listsDir := t.TempDir()
+	list := filepath.Join(listsDir, "archive.ubuntu.com_ubuntu_dists_jammy_main_binary-amd64_Packages")
+	require.NoError(t, os.WriteFile(list, []byte(aptList), 0644))
+
+	p := NewDpkgPlugin(ids.PluginID{Category: "packages", Term: "dpkg"}, testing2.NewMockAgent()).(*DpkgPlugin)
+	p.listsDir = listsDir
+
+	output := "bash\tinstall ok installed\tamd64\t5.1-6ubuntu1\tyes\trequired\tbash\n" +
+		"libc6\tinstall ok installed\tamd64\t2.35-0ubuntu3\tno\toptional\tglibc\n" +
+		"tzdata\tinstall ok installed\tall\t2022a-0ubuntu1\tno\trequired\t\n" +
+		"broken\tinstall"
+	packages := p.parsePackageInfo(output)
+	require.Len(t, packages, 3)
+
+	bash := packages[0].(DpkgItem)
+	bash.InstallTime = ""
+	assert.Equal(t, DpkgItem{
+		Name:         "bash",
+		Architecture: "amd64",
+		Essential:    "yes",
+		Priority:     "required",
+		Status:       "installed",
+		Version:      "5.1-6ubuntu1",
+		Source:       "bash",
+		Repository:   "archive.ubuntu.com/ubuntu jammy/main",
+		Digest:       "sha256:2f4a1a1ac1a2f4c6e6e7b1c9b4d3e1e6b0b8a4e4c1a7d0e9f1a2b3c4d5e6f7a8",
+	}, bash)
+	assert.Equal(t, "glibc", packages[1].(DpkgItem).Source)
+	assert.Empty(t, packages[1].(DpkgItem).Repository)
+	assert.Equal(t, "sha256:1111111111111111111111111111111111111111111111111111111111111111", packages[2].(DpkgItem).Digest)
+}
+
+func TestAptRepositoryName(t *testing.T) {
+	assert.Equal(t, "archive.ubuntu.com/ubuntu jammy/main", aptRepositoryName("archive.ubuntu.com_ubuntu_dists_jammy_main_binary-amd64_Packages"))
+	assert.Equal(t, "deb.debian.org/debian bookworm-updates/main", aptRepositoryName("deb.debian.org_debian_dists_bookworm-updates_main_binary-arm64_Packages"))
+	assert.Equal(t, "ppa.example.com/repo/.", aptRepositoryName("ppa.example.com_repo_._Packages"))
+}
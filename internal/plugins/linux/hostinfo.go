@@ -23,6 +23,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
 
 	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/agent/capabilities"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers/fingerprint"
@@ -76,7 +77,7 @@ func getKernelRelease() string {
 func getProductUuid(mode string) string {
 	const unknownProductUUID = "unknown"
 
-	if mode == config.ModeUnprivileged {
+	if mode == config.ModeUnprivileged || !capabilities.Available(capabilities.ProductUUID) {
 		return unknownProductUUID
 	}
 
@@ -205,6 +206,16 @@ func (self *HostinfoPlugin) gatherHostinfo(context agent.AgentContext) *HostInfo
 
 	data.CpuName = readProcFile(helpers.HostProc("/cpuinfo"), regexp.MustCompile(`model\sname\s*:\s`))
 	data.CpuNum = getCpuNum(infoFile, totalCpu)
+	topology, err := readCPUTopology(helpers.HostSys("/devices/system/cpu"))
+	if err != nil {
+		hlog.WithError(err).Debug("cannot read cpu topology")
+	} else {
+		data.CpuSockets = strconv.Itoa(topology.Sockets)
+	}
+	// ARM cpuinfo has neither the model name nor the cores per socket
+	if data.CpuName == "unknown" {
+		self.setARMCPUInfo(data, infoFile, topology)
+	}
 	data.TotalCpu = totalCpu
 	data.Ram = readProcFile(helpers.HostProc("/meminfo"), regexp.MustCompile(`MemTotal:\s*`))
 	data.UpSince = getUpSince()
@@ -228,3 +239,20 @@ func (self *HostinfoPlugin) getHostType() string {
 
 	return fmt.Sprintf("%s %s", manufacturer, name)
 }
+
+func (self *HostinfoPlugin) setARMCPUInfo(data *HostInfoLinux, cpuInfoFile string, topology cpuTopology) {
+	content, err := ioutil.ReadFile(cpuInfoFile)
+	if err != nil {
+		return
+	}
+	sysVendor, _ := fs.ReadFirstLine(helpers.HostSys("/devices/virtual/dmi/id/sys_vendor"))
+	cpu, ok := parseARMCPUInfo(string(content), sysVendor)
+	if !ok {
+		return
+	}
+	data.CpuName = cpu.ModelName
+	data.CpuClusters = cpu.Clusters
+	if topology.CoresPerSocket > 0 {
+		data.CpuNum = strconv.Itoa(topology.CoresPerSocket)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/agent/types"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+var kblog = log.WithPlugin("KernelBoot")
+
+// KernelBootPlugin reports the parameters the running kernel was booted with. As they can only change on reboot, which
+// restarts the agent, they are read once.
+type KernelBootPlugin struct {
+	agent.PluginCommon
+	cmdlinePath string
+}
+
+// KernelBootParam is a parameter of the kernel command line. Flags without value, ie: quiet, have an empty Value, and
+// the values of a parameter given several times, ie: console, are joined by spaces.
+type KernelBootParam struct {
+	Name  string `json:"id"`
+	Value string `json:"value"`
+}
+
+func (self KernelBootParam) SortKey() string {
+	return self.Name
+}
+
+func NewKernelBootPlugin(id ids.PluginID, ctx agent.AgentContext) *KernelBootPlugin {
+	return &KernelBootPlugin{
+		PluginCommon: agent.PluginCommon{ID: id, Context: ctx},
+		cmdlinePath:  helpers.HostProc("/cmdline"),
+	}
+}
+
+// parseKernelCmdline splits the kernel command line in its parameters. Double quotes allow spaces in the values, as
+// done by the kernel itself.
+func parseKernelCmdline(cmdline string) types.PluginInventoryDataset {
+	var params []string
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range strings.TrimSpace(cmdline) {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case (r == ' ' || r == '\t' || r == '\n') && !inQuotes:
+			if current.Len() > 0 {
+				params = append(params, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		params = append(params, current.String())
+	}
+
+	byName := make(map[string]int)
+	var dataset types.PluginInventoryDataset
+	for _, param := range params {
+		name, value := param, ""
+		if i := strings.Index(param, "="); i >= 0 {
+			name, value = param[:i], param[i+1:]
+		}
+		if i, ok := byName[name]; ok {
+			prev := dataset[i].(KernelBootParam)
+			prev.Value += " " + value
+			dataset[i] = prev
+			continue
+		}
+		byName[name] = len(dataset)
+		dataset = append(dataset, KernelBootParam{Name: name, Value: value})
+	}
+	return dataset
+}
+
+func (self *KernelBootPlugin) Run() {
+	cmdline, err := ioutil.ReadFile(self.cmdlinePath)
+	if err != nil {
+		kblog.WithError(err).Error("reading kernel command line")
+		self.Unregister()
+		return
+	}
+	self.EmitInventory(parseKernelCmdline(string(cmdline)), entity.NewFromNameWithoutID(self.Context.EntityKey()))
+}
@@ -0,0 +1,27 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/types"
+)
+
+func TestParseKernelCmdline(t *testing.T) {
+	cmdline := `BOOT_IMAGE=/vmlinuz-6.1.0 root=UUID=1234-abcd ro console=tty0 console=ttyS0,115200 quiet dyndbg="file drivers/usb/* +p"` + "\n"
+
+	assert.Equal(t, types.PluginInventoryDataset{
+		KernelBootParam{Name: "BOOT_IMAGE", Value: "/vmlinuz-6.1.0"},
+		KernelBootParam{Name: "root", Value: "UUID=1234-abcd"},
+		KernelBootParam{Name: "ro"},
+		KernelBootParam{Name: "console", Value: "tty0 ttyS0,115200"},
+		KernelBootParam{Name: "quiet"},
+		KernelBootParam{Name: "dyndbg", Value: "file drivers/usb/* +p"},
+	}, parseKernelCmdline(cmdline))
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"github.com/newrelic/infrastructure-agent/internal/agent/types"
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
@@ -33,10 +34,16 @@ type KernelModulesPlugin struct {
 	frequency     time.Duration
 }
 
+// KernelModule is a loaded kernel module. Signer, SigKey and SigHashAlgo are only known for signed modules, and Taint
+// holds the taint flags the module set on the kernel when loaded, ie: "OE" for an unsigned out-of-tree module.
 type KernelModule struct {
 	Name        string `json:"id"`
 	Version     string `json:"version"`
 	Description string `json:"description"`
+	Signer      string `json:"signer,omitempty"`
+	SigKey      string `json:"sig_key,omitempty"`
+	SigHashAlgo string `json:"sig_hashalgo,omitempty"`
+	Taint       string `json:"taint,omitempty"`
 }
 
 func (self KernelModule) SortKey() string {
@@ -75,8 +82,19 @@ func (self *KernelModulesPlugin) populateKernelModuleInfo(modInfo *KernelModule)
 		return
 	}
 
-	reModuleInfo := regexp.MustCompile(`^(filename|version|description):\s+(.*)`)
+	parseModinfo(output, modInfo)
 
+	// modules not tainting the kernel have an empty taint file
+	if taint, taintErr := ioutil.ReadFile(helpers.HostSys("/module", modInfo.Name, "taint")); taintErr == nil {
+		modInfo.Taint = strings.TrimSpace(string(taint))
+	}
+
+	return
+}
+
+var reModuleInfo = regexp.MustCompile(`^(filename|version|description|signer|sig_key|sig_hashalgo):\s+(.*)`)
+
+func parseModinfo(output string, modInfo *KernelModule) {
 	scanner := bufio.NewScanner(strings.NewReader(output))
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -88,11 +106,15 @@ func (self *KernelModulesPlugin) populateKernelModuleInfo(modInfo *KernelModule)
 				modInfo.Version = matches[2]
 			case "description":
 				modInfo.Description = matches[2]
+			case "signer":
+				modInfo.Signer = matches[2]
+			case "sig_key":
+				modInfo.SigKey = matches[2]
+			case "sig_hashalgo":
+				modInfo.SigHashAlgo = matches[2]
 			}
 		}
 	}
-
-	return
 }
 
 func (self *KernelModulesPlugin) getKernelModuleStatus() (err error) {
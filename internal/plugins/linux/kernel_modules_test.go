@@ -0,0 +1,36 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseModinfo(t *testing.T) {
+	output := `filename:       /lib/modules/6.1.0/kernel/net/wireguard/wireguard.ko
+version:        1.0.0
+description:    WireGuard secure network tunnel
+license:        GPL v2
+signer:         Debian Secure Boot CA
+sig_key:        32:A0:28:7F:84:1A:03:6F:A3:93:C1:E0:65:C4:3A:E6:B2:42:26:43
+sig_hashalgo:   sha256
+signature:      8B:44:7C:2D:5E:9F:10:33:7A:AA:01:02:03:04:05:06:07:08:09:0A:
+		0B:0C:0D:0E:0F:10:11:12:13:14:15:16:17:18:19:1A:1B:1C:1D:1E
+`
+	module := KernelModule{Name: "wireguard"}
+	parseModinfo(output, &module)
+
+	assert.Equal(t, KernelModule{
+		Name:        "wireguard",
+		Version:     "1.0.0",
+		Description: "WireGuard secure network tunnel",
+		Signer:      "Debian Secure Boot CA",
+		SigKey:      "32:A0:28:7F:84:1A:03:6F:A3:93:C1:E0:65:C4:3A:E6:B2:42:26:43",
+		SigHashAlgo: "sha256",
+	}, module)
+}
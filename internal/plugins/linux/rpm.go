@@ -16,6 +16,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
 	"github.com/sirupsen/logrus"
+	"os"
 	"sort"
 	"strings"
 	"time"
@@ -29,7 +30,9 @@ var rpmlog = log.WithPlugin("Rpm")
 
 // Paths
 const (
-	RpmPath = "/bin/rpm"
+	RpmPath    = "/bin/rpm"
+	DnfPath    = "/usr/bin/dnf"
+	ZypperPath = "/usr/bin/zypper"
 )
 
 type rpmPlugin struct {
@@ -45,6 +48,9 @@ type RpmItem struct {
 	Architecture string `json:"architecture"`
 	InstallTime  string `json:"installed_epoch"`
 	EpochTag     string `json:"epoch_tag"`
+	Source       string `json:"source,omitempty"`
+	Repository   string `json:"repository,omitempty"`
+	Digest       string `json:"digest,omitempty"`
 }
 
 func (p RpmItem) SortKey() string {
@@ -68,14 +74,16 @@ func NewRpmPlugin(ctx agent.AgentContext) agent.Plugin {
 }
 
 func (p *rpmPlugin) fetchPackageInfo() (packages types.PluginInventoryDataset, err error) {
-	output, err := helpers.RunCommand(RpmPath, "", "-qa", "--queryformat=%{NAME} %{VERSION} %{RELEASE} %{ARCH} %{INSTALLTIME} %{EPOCH}\n")
+	output, err := helpers.RunCommand(RpmPath, "", "-qa", "--queryformat=%{NAME} %{VERSION} %{RELEASE} %{ARCH} %{INSTALLTIME} %{EPOCH} %{SOURCERPM} %{SIGMD5}\n")
 	if err != nil {
 		return nil, err
 	}
-	return p.parsePackageInfo(output)
+	return p.parsePackageInfo(output, rpmRepositories())
 }
 
-func (p *rpmPlugin) parsePackageInfo(output string) (packages types.PluginInventoryDataset, err error) {
+// parsePackageInfo parses the rpm query output. The repositories the packages were installed from are looked up by
+// rpmRepositoryKey.
+func (p *rpmPlugin) parsePackageInfo(output string, repositories map[string]string) (packages types.PluginInventoryDataset, err error) {
 	// Get output and sort it alphabetically to ensure consistent ordering
 	var outputLines []string
 	scanner := bufio.NewScanner(strings.NewReader(output))
@@ -115,6 +123,13 @@ func (p *rpmPlugin) parsePackageInfo(output string) (packages types.PluginInvent
 			Architecture: parts[3],
 			InstallTime:  parts[4],
 			EpochTag:     epoch,
+			Repository:   repositories[rpmRepositoryKey(parts[0], parts[3])],
+		}
+		if len(parts) > 6 {
+			RpmItem.Source = sourceRpmName(parts[6])
+		}
+		if len(parts) > 7 && parts[7] != "(none)" {
+			RpmItem.Digest = "md5:" + parts[7]
 		}
 
 		packages = append(packages, RpmItem)
@@ -123,6 +138,104 @@ func (p *rpmPlugin) parsePackageInfo(output string) (packages types.PluginInvent
 	return
 }
 
+// sourceRpmName returns the name of the source package from its file name, ie: "bash" for
+// "bash-5.1.8-6.el9.src.rpm".
+func sourceRpmName(sourceRpm string) string {
+	if sourceRpm == "(none)" {
+		return ""
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(sourceRpm, ".src.rpm"), ".nosrc.rpm")
+	// drop the version and release
+	for i := 0; i < 2; i++ {
+		idx := strings.LastIndex(name, "-")
+		if idx <= 0 {
+			return sourceRpm
+		}
+		name = name[:idx]
+	}
+	return name
+}
+
+func rpmRepositoryKey(name, arch string) string {
+	return name + " " + arch
+}
+
+// rpmRepositories returns the repositories the installed packages were installed from, by rpmRepositoryKey. The rpm
+// database doesn't keep them, so they're queried from dnf or zypper from their caches, without refreshing them.
+func rpmRepositories() map[string]string {
+	if _, err := os.Stat(DnfPath); err == nil {
+		output, err := helpers.RunCommand(DnfPath, "", "-C", "-q", "repoquery", "--installed", "--qf", "%{name} %{arch} %{from_repo}\\n")
+		if err != nil {
+			rpmlog.WithError(err).Debug("Can't query dnf repositories.")
+			return nil
+		}
+		return parseDnfRepositories(output)
+	}
+	if _, err := os.Stat(ZypperPath); err == nil {
+		output, err := helpers.RunCommand(ZypperPath, "", "--non-interactive", "--no-refresh", "--quiet", "search", "--installed-only", "--details", "--type", "package")
+		if err != nil {
+			rpmlog.WithError(err).Debug("Can't query zypper repositories.")
+			return nil
+		}
+		return parseZypperRepositories(output)
+	}
+	return nil
+}
+
+// parseDnfRepositories parses the "name arch from_repo" lines of dnf repoquery. Older dnf versions don't expand the
+// new line escape of the query format, so it's expanded here.
+func parseDnfRepositories(output string) map[string]string {
+	repositories := make(map[string]string)
+	for _, line := range strings.Split(strings.ReplaceAll(output, `\n`, "\n"), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) != 3 || parts[2] == "<unknown>" || parts[2] == "(none)" {
+			continue
+		}
+		repositories[rpmRepositoryKey(parts[0], parts[1])] = parts[2]
+	}
+	return repositories
+}
+
+// parseZypperRepositories parses the table of zypper search, looking up the columns by its header.
+func parseZypperRepositories(output string) map[string]string {
+	repositories := make(map[string]string)
+	nameCol, archCol, repoCol := -1, -1, -1
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.Contains(line, "|") {
+			continue
+		}
+		cols := strings.Split(line, "|")
+		for i := range cols {
+			cols[i] = strings.TrimSpace(cols[i])
+		}
+		if nameCol < 0 {
+			for i, col := range cols {
+				switch col {
+				case "Name":
+					nameCol = i
+				case "Arch":
+					archCol = i
+				case "Repository":
+					repoCol = i
+				}
+			}
+			if archCol < 0 || repoCol < 0 {
+				nameCol = -1
+			}
+			continue
+		}
+		if len(cols) <= nameCol || len(cols) <= archCol || len(cols) <= repoCol {
+			continue
+		}
+		repo := cols[repoCol]
+		if repo == "" || repo == "(System Packages)" {
+			continue
+		}
+		repositories[rpmRepositoryKey(cols[nameCol], cols[archCol])] = repo
+	}
+	return repositories
+}
+
 // Run is the main processing loop that drives the logic for the plugin
 func (p *rpmPlugin) Run() {
 	if p.frequency <= config.FREQ_DISABLE_SAMPLING {
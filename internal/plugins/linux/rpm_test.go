@@ -28,14 +28,14 @@ func TestParsePackageInfo(t *testing.T) {
 		err      error
 	}{
 		{"test\ntwo", []RpmItem{}, 0, nil},
-		{"test 1.0 r2 x386 12345 (none)\ntwo", []RpmItem{{"test", "1.0", "r2", "x386", "12345", "none"}}, 1, nil},
-		{"test 1.0 r2 x386 12345 9\ntwo", []RpmItem{{"test", "1.0", "r2", "x386", "12345", "9"}}, 1, nil},
-		{"test 1.0 r2 x386 12345 9\nchuck 1.9 r3 x386 92345 myepo", []RpmItem{{"chuck", "1.9", "r3", "x386", "92345", "myepo"}, {"test", "1.0", "r2", "x386", "12345", "9"}}, 2, nil},
-		{"test 1.0 r2 x386 12345 9\ntest 1.9 r3 x386 92345 myepo", []RpmItem{{"test", "1.0", "r2", "x386", "12345", "9"}, {"test-1", "1.9", "r3", "x386", "92345", "myepo"}}, 2, nil},
+		{"test 1.0 r2 x386 12345 (none)\ntwo", []RpmItem{{"test", "1.0", "r2", "x386", "12345", "none", "", "", ""}}, 1, nil},
+		{"test 1.0 r2 x386 12345 9\ntwo", []RpmItem{{"test", "1.0", "r2", "x386", "12345", "9", "", "", ""}}, 1, nil},
+		{"test 1.0 r2 x386 12345 9\nchuck 1.9 r3 x386 92345 myepo", []RpmItem{{"chuck", "1.9", "r3", "x386", "92345", "myepo", "", "", ""}, {"test", "1.0", "r2", "x386", "12345", "9", "", "", ""}}, 2, nil},
+		{"test 1.0 r2 x386 12345 9\ntest 1.9 r3 x386 92345 myepo", []RpmItem{{"test", "1.0", "r2", "x386", "12345", "9", "", "", ""}, {"test-1", "1.9", "r3", "x386", "92345", "myepo", "", "", ""}}, 2, nil},
 	}
 
 	for _, test := range tests {
-		packages, err := rpmP.parsePackageInfo(test.output)
+		packages, err := rpmP.parsePackageInfo(test.output, nil)
 		assert.Len(t, packages, test.count)
 		sort.Sort(packages)
 		for i, result := range packages {
@@ -51,14 +51,14 @@ func TestParsePackageInfo_WarnsOnDroppedLines(t *testing.T) {
 
 	p := NewRpmPlugin(testing2.NewMockAgent())
 	rpmP := p.(*rpmPlugin)
-	resultPackages, err := rpmP.parsePackageInfo("test 1.0 r2 x386 12345 (none)\nfoo")
+	resultPackages, err := rpmP.parsePackageInfo("test 1.0 r2 x386 12345 (none)\nfoo", nil)
 	assert.NoError(t, err)
 
 	assert.Contains(t, w.String(), "cannot parse rpm query line")
 	assert.Contains(t, w.String(), "foo")
 
 	require.Len(t, resultPackages, 1)
-	assert.Equal(t, RpmItem{"test", "1.0", "r2", "x386", "12345", "none"}, resultPackages[0])
+	assert.Equal(t, RpmItem{"test", "1.0", "r2", "x386", "12345", "none", "", "", ""}, resultPackages[0])
 }
 
 func TestParsePackageInfo_WarnsOnDroppedLinesOncePerLine(t *testing.T) {
@@ -67,12 +67,54 @@ func TestParsePackageInfo_WarnsOnDroppedLinesOncePerLine(t *testing.T) {
 
 	p := NewRpmPlugin(testing2.NewMockAgent())
 	rpmP := p.(*rpmPlugin)
-	resultPackages, err := rpmP.parsePackageInfo("foo\ntest 1.0 r2 x386 12345 (none)\nbar\nfoo")
+	resultPackages, err := rpmP.parsePackageInfo("foo\ntest 1.0 r2 x386 12345 (none)\nbar\nfoo", nil)
 	assert.NoError(t, err)
 
 	assert.Equal(t, 1, strings.Count(w.String(), "foo"))
 	assert.Equal(t, 1, strings.Count(w.String(), "bar"))
 
 	require.Len(t, resultPackages, 1)
-	assert.Equal(t, RpmItem{"test", "1.0", "r2", "x386", "12345", "none"}, resultPackages[0])
+	assert.Equal(t, RpmItem{"test", "1.0", "r2", "x386", "12345", "none", "", "", ""}, resultPackages[0])
+}
+
+func TestParsePackageInfo_Metadata(t *testing.T) {
+	p := NewRpmPlugin(testing2.NewMockAgent())
+	rpmP := p.(*rpmPlugin)
+
+	output := "bash 5.1.8 6.el9 x86_64 12345 (none) bash-5.1.8-6.el9.src.rpm 0123456789abcdef0123456789abcdef\n" +
+		"gpg-pubkey 8483c65d 5ccc5b19 (none) 12345 (none) (none) (none)"
+	packages, err := rpmP.parsePackageInfo(output, map[string]string{"bash x86_64": "baseos"})
+	require.NoError(t, err)
+	require.Len(t, packages, 2)
+
+	assert.Equal(t, RpmItem{"bash", "5.1.8", "6.el9", "x86_64", "12345", "none", "bash", "baseos", "md5:0123456789abcdef0123456789abcdef"}, packages[0])
+	assert.Equal(t, RpmItem{"gpg-pubkey", "8483c65d", "5ccc5b19", "(none)", "12345", "none", "", "", ""}, packages[1])
+}
+
+func TestSourceRpmName(t *testing.T) {
+	assert.Equal(t, "bash", sourceRpmName("bash-5.1.8-6.el9.src.rpm"))
+	assert.Equal(t, "python-requests", sourceRpmName("python-requests-2.25.1-6.el9.src.rpm"))
+	assert.Equal(t, "", sourceRpmName("(none)"))
+}
+
+func TestParseDnfRepositories(t *testing.T) {
+	for _, output := range []string{
+		"bash x86_64 baseos\ntzdata noarch appstream\nlocal x86_64 <unknown>",
+		`bash x86_64 baseos\ntzdata noarch appstream\nlocal x86_64 <unknown>\n`,
+	} {
+		assert.Equal(t, map[string]string{"bash x86_64": "baseos", "tzdata noarch": "appstream"}, parseDnfRepositories(output))
+	}
+}
+
+func TestParseZypperRepositories(t *testing.T) {
+	output := `S  | Name   | Type    | Version     | Arch   | Repository
+---+--------+---------+-------------+--------+-------------------
+i+ | bash   | package | 5.2.15-1.1  | x86_64 | Main Repository
+i  | local  | package | 1.0-1       | noarch | (System Packages)
+i  | tzdata | package | 2023c-1.1   | noarch | Main Update Repository`
+
+	assert.Equal(t, map[string]string{
+		"bash x86_64":   "Main Repository",
+		"tzdata noarch": "Main Update Repository",
+	}, parseZypperRepositories(output))
 }
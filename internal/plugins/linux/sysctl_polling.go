@@ -32,6 +32,7 @@ type SysctlPlugin struct {
 	fileService   fileService
 	ignoredListRE *regexp.Regexp
 	regexpCache   *lru.Cache
+	included      []string
 }
 
 // NewSysctlPollingMonitor creates a /proc/sys parser polling on intervals
@@ -53,6 +54,7 @@ func NewSysctlPollingMonitor(id ids.PluginID, ctx agent.AgentContext) *SysctlPlu
 		},
 		ignoredListRE: regexp.MustCompile(fmt.Sprintf("(%s)", strings.Join(ignoredListPatterns, ")|("))),
 		regexpCache:   lru.New(),
+		included:      cfg.SysctlInclude,
 	}
 }
 
@@ -90,6 +92,10 @@ func (sp *SysctlPlugin) walkSysctl(path string, fi os.FileInfo, _ error) (err er
 		return
 	}
 
+	if !sp.isIncluded(sp.sysctlKey(path)) {
+		return
+	}
+
 	output, readFileErr := sp.fileService.read(path)
 	if readFileErr != nil {
 		if os.IsNotExist(readFileErr) {
@@ -109,10 +115,28 @@ func (sp *SysctlPlugin) walkSysctl(path string, fi os.FileInfo, _ error) (err er
 }
 
 // reformat path into sysctl style dot separated
-func (sp *SysctlPlugin) newSysctlItem(filePath string, output []byte) SysctlItem {
+func (sp *SysctlPlugin) sysctlKey(filePath string) string {
 	keyPath := strings.TrimPrefix(filePath, sp.procSysDir)
-	keyPath = strings.Replace(keyPath, "/", ".", -1)
-	return SysctlItem{keyPath, strings.TrimSpace(string(output))}
+	return strings.Replace(keyPath, "/", ".", -1)
+}
+
+func (sp *SysctlPlugin) newSysctlItem(filePath string, output []byte) SysctlItem {
+	return SysctlItem{sp.sysctlKey(filePath), strings.TrimSpace(string(output))}
+}
+
+// isIncluded returns whether the sysctl is selected by the SysctlInclude config option, either by its full key or by
+// one of its parents. All sysctls are included when the option is empty.
+func (sp *SysctlPlugin) isIncluded(key string) bool {
+	if len(sp.included) == 0 {
+		return true
+	}
+	for _, include := range sp.included {
+		include = strings.TrimSuffix(include, ".")
+		if key == include || strings.HasPrefix(key, include+".") {
+			return true
+		}
+	}
+	return false
 }
 
 func (sp *SysctlPlugin) Sysctls() (dataset types.PluginInventoryDataset, err error) {
@@ -0,0 +1,24 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux
+// +build linux
+
+package linux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSysctlIsIncluded(t *testing.T) {
+	all := &SysctlPlugin{}
+	assert.True(t, all.isIncluded("vm.swappiness"))
+
+	sp := &SysctlPlugin{included: []string{"kernel.randomize_va_space", "net.ipv4."}}
+	assert.True(t, sp.isIncluded("kernel.randomize_va_space"))
+	assert.True(t, sp.isIncluded("net.ipv4.ip_forward"))
+	assert.False(t, sp.isIncluded("kernel.randomize_va_space_other"))
+	assert.False(t, sp.isIncluded("net.ipv6.conf.all.forwarding"))
+	assert.False(t, sp.isIncluded("vm.swappiness"))
+}
@@ -0,0 +1,192 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build windows
+// +build windows
+
+package windows
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/agent/types"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+var rlog = log.WithComponent("PendingRebootPlugin")
+
+// PendingRebootPlugin reports whether the host is pending a reboot to complete the installation of updates or
+// software, and the reasons for it.
+type PendingRebootPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+	checks    []rebootCheck
+}
+
+type PendingRebootItem struct {
+	Name    string `json:"id"`
+	Pending bool   `json:"pending"`
+	Reasons string `json:"reasons"`
+}
+
+func (self PendingRebootItem) SortKey() string {
+	return self.Name
+}
+
+// rebootCheck returns whether a reboot is pending for a reason.
+type rebootCheck struct {
+	reason  string
+	pending func() (bool, error)
+}
+
+// The reasons for a pending reboot
+const (
+	RebootReasonComponentServicing = "component_based_servicing"
+	RebootReasonWindowsUpdate      = "windows_update"
+	RebootReasonFileRename         = "pending_file_rename"
+	RebootReasonComputerRename     = "computer_rename"
+	RebootReasonUpdateExe          = "update_exe_volatile"
+)
+
+func NewPendingRebootPlugin(id ids.PluginID, ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &PendingRebootPlugin{
+		PluginCommon: agent.PluginCommon{ID: id, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.WindowsPendingRebootRefreshSec,
+			config.FREQ_MINIMUM_FAST_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_WINDOWS_REBOOT,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		checks: []rebootCheck{
+			{RebootReasonComponentServicing, keyExists(`SOFTWARE\Microsoft\Windows\CurrentVersion\Component Based Servicing\RebootPending`)},
+			{RebootReasonWindowsUpdate, keyExists(`SOFTWARE\Microsoft\Windows\CurrentVersion\WindowsUpdate\Auto Update\RebootRequired`)},
+			{RebootReasonFileRename, pendingFileRenames},
+			{RebootReasonComputerRename, computerRenamed},
+			{RebootReasonUpdateExe, updateExeVolatile},
+		},
+	}
+}
+
+func keyExists(path string) func() (bool, error) {
+	return func() (bool, error) {
+		key, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE)
+		if errors.Is(err, registry.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		key.Close()
+		return true, nil
+	}
+}
+
+func pendingFileRenames() (bool, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Session Manager`, registry.QUERY_VALUE)
+	if err != nil {
+		return false, err
+	}
+	defer key.Close()
+
+	renames, _, err := key.GetStringsValue("PendingFileRenameOperations")
+	if errors.Is(err, registry.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, rename := range renames {
+		if rename != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func computerRenamed() (bool, error) {
+	active, err := computerName(`SYSTEM\CurrentControlSet\Control\ComputerName\ActiveComputerName`)
+	if err != nil {
+		return false, err
+	}
+	pending, err := computerName(`SYSTEM\CurrentControlSet\Control\ComputerName\ComputerName`)
+	if err != nil {
+		return false, err
+	}
+	return !strings.EqualFold(active, pending), nil
+}
+
+func computerName(path string) (string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer key.Close()
+
+	name, _, err := key.GetStringValue("ComputerName")
+	return name, err
+}
+
+func updateExeVolatile() (bool, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Updates`, registry.QUERY_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer key.Close()
+
+	volatile, _, err := key.GetIntegerValue("UpdateExeVolatile")
+	if errors.Is(err, registry.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return volatile != 0, nil
+}
+
+// getDataset runs every check, reporting a pending reboot if any of them does. The failing checks are logged and
+// don't prevent the rest from being reported.
+func (self *PendingRebootPlugin) getDataset() types.PluginInventoryDataset {
+	var reasons []string
+	for _, check := range self.checks {
+		pending, err := check.pending()
+		if err != nil {
+			rlog.WithError(err).WithField("reason", check.reason).Debug("Can't check pending reboot.")
+			continue
+		}
+		if pending {
+			reasons = append(reasons, check.reason)
+		}
+	}
+	return types.PluginInventoryDataset{PendingRebootItem{
+		Name:    "pending_reboot",
+		Pending: len(reasons) > 0,
+		Reasons: strings.Join(reasons, ","),
+	}}
+}
+
+func (self *PendingRebootPlugin) Run() {
+	if self.frequency <= config.FREQ_DISABLE_SAMPLING {
+		rlog.Debug("Disabled.")
+		return
+	}
+
+	// Introduce some jitter to wait randomly before reporting based on frequency time
+	time.Sleep(config.JitterFrequency(self.frequency))
+
+	refreshTimer := time.NewTicker(self.frequency)
+	for {
+		self.EmitInventory(self.getDataset(), entity.NewFromNameWithoutID(self.Context.EntityKey()))
+		<-refreshTimer.C
+	}
+}
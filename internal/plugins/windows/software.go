@@ -0,0 +1,270 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build windows
+// +build windows
+
+package windows
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/agent/types"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+const uninstallPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall`
+
+// Installers of the installed software
+const (
+	InstallerMSI      = "msi"
+	InstallerRegistry = "registry"
+)
+
+var swlog = log.WithComponent("SoftwarePlugin")
+
+// SoftwarePlugin reports the installed software registered in the uninstall keys, the same software listed by
+// "Programs and Features", both MSI and non MSI installed. Win32_Product is not queried because it's slow and it
+// triggers a consistency check of every MSI product.
+type SoftwarePlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+}
+
+type SoftwareItem struct {
+	Name            string `json:"id"`
+	DisplayName     string `json:"display_name"`
+	Version         string `json:"version"`
+	Publisher       string `json:"publisher,omitempty"`
+	Architecture    string `json:"architecture,omitempty"`
+	InstallTime     string `json:"installed_epoch,omitempty"`
+	InstallLocation string `json:"install_location,omitempty"`
+	Installer       string `json:"installer"`
+	ProductCode     string `json:"product_code,omitempty"`
+	UserSID         string `json:"user_sid,omitempty"`
+}
+
+func (self SoftwareItem) SortKey() string {
+	return self.Name
+}
+
+// uninstallEntry is an installed software uninstall key.
+type uninstallEntry struct {
+	key              string
+	displayName      string
+	version          string
+	publisher        string
+	installDate      string
+	installLocation  string
+	windowsInstaller bool
+	lastWrite        time.Time
+	arch             string
+	userSID          string
+}
+
+// uninstallView is a registry view of the uninstall keys.
+type uninstallView struct {
+	root    registry.Key
+	path    string
+	access  uint32
+	arch    string
+	userSID string
+}
+
+func NewSoftwarePlugin(id ids.PluginID, ctx agent.AgentContext) agent.Plugin {
+	cfg := ctx.Config()
+	return &SoftwarePlugin{
+		PluginCommon: agent.PluginCommon{ID: id, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.WindowsSoftwareRefreshSec,
+			config.FREQ_MINIMUM_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_WINDOWS_SOFTWARE,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+	}
+}
+
+// uninstallViews returns the machine uninstall keys of the 64 and 32 bits software, and the ones of the users whose
+// registry hive is loaded.
+func uninstallViews() []uninstallView {
+	var views []uninstallView
+	if runtime.GOARCH == "386" {
+		views = append(views, uninstallView{root: registry.LOCAL_MACHINE, path: uninstallPath, arch: "x86"})
+	} else {
+		views = append(views,
+			uninstallView{root: registry.LOCAL_MACHINE, path: uninstallPath, access: registry.WOW64_64KEY, arch: "x64"},
+			uninstallView{root: registry.LOCAL_MACHINE, path: uninstallPath, access: registry.WOW64_32KEY, arch: "x86"},
+		)
+	}
+
+	users, err := registry.USERS.ReadSubKeyNames(0)
+	if err != nil {
+		swlog.WithError(err).Debug("Can't read the loaded user hives.")
+		return views
+	}
+	for _, sid := range users {
+		if strings.HasSuffix(sid, "_Classes") || sid == ".DEFAULT" {
+			continue
+		}
+		views = append(views, uninstallView{root: registry.USERS, path: sid + `\` + uninstallPath, userSID: sid})
+	}
+	return views
+}
+
+func readUninstallEntries(view uninstallView) ([]uninstallEntry, error) {
+	key, err := registry.OpenKey(view.root, view.path, registry.ENUMERATE_SUB_KEYS|view.access)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	names, err := key.ReadSubKeyNames(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []uninstallEntry
+	for _, name := range names {
+		entry, err := readUninstallEntry(key, name, view.access)
+		if err != nil {
+			swlog.WithError(err).WithField("key", name).Debug("Can't read uninstall key.")
+			continue
+		}
+		if entry.displayName == "" {
+			// not listed by "Programs and Features"
+			continue
+		}
+		entry.arch = view.arch
+		entry.userSID = view.userSID
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func readUninstallEntry(parent registry.Key, name string, access uint32) (entry uninstallEntry, err error) {
+	key, err := registry.OpenKey(parent, name, registry.QUERY_VALUE|access)
+	if err != nil {
+		return entry, err
+	}
+	defer key.Close()
+
+	entry.key = name
+	entry.displayName, _, _ = key.GetStringValue("DisplayName")
+	entry.version, _, _ = key.GetStringValue("DisplayVersion")
+	entry.publisher, _, _ = key.GetStringValue("Publisher")
+	entry.installDate, _, _ = key.GetStringValue("InstallDate")
+	entry.installLocation, _, _ = key.GetStringValue("InstallLocation")
+	if msi, _, err := key.GetIntegerValue("WindowsInstaller"); err == nil {
+		entry.windowsInstaller = msi == 1
+	}
+	if info, err := key.Stat(); err == nil {
+		entry.lastWrite = info.ModTime()
+	}
+	return entry, nil
+}
+
+// softwareItems converts the uninstall entries into inventory items, identified by their display name. As the same
+// software can be installed several times, ie: for both architectures, the repeated names are suffixed with -1, -2,
+// etc in a consistent order.
+func softwareItems(entries []uninstallEntry) (result types.PluginInventoryDataset) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.displayName != b.displayName {
+			return a.displayName < b.displayName
+		}
+		if a.arch != b.arch {
+			return a.arch < b.arch
+		}
+		if a.userSID != b.userSID {
+			return a.userSID < b.userSID
+		}
+		return a.key < b.key
+	})
+
+	nameDuplicateTracker := make(map[string]int)
+	for _, entry := range entries {
+		name := entry.displayName
+		nameCount := nameDuplicateTracker[name]
+		nameDuplicateTracker[name] = nameCount + 1
+		if nameCount > 0 {
+			name = fmt.Sprintf("%v-%v", name, nameCount)
+		}
+
+		item := SoftwareItem{
+			Name:            name,
+			DisplayName:     entry.displayName,
+			Version:         entry.version,
+			Publisher:       entry.publisher,
+			Architecture:    entry.arch,
+			InstallTime:     installEpoch(entry.installDate, entry.lastWrite),
+			InstallLocation: entry.installLocation,
+			Installer:       InstallerRegistry,
+			UserSID:         entry.userSID,
+		}
+		if entry.windowsInstaller {
+			item.Installer = InstallerMSI
+			// MSI products are registered by their product code
+			item.ProductCode = entry.key
+		}
+		result = append(result, item)
+	}
+	return
+}
+
+// installEpoch returns the install time from the YYYYMMDD InstallDate value or, when it's not set, the last write
+// time of the uninstall key.
+func installEpoch(installDate string, lastWrite time.Time) string {
+	if t, err := time.ParseInLocation("20060102", installDate, time.Local); err == nil {
+		return fmt.Sprintf("%d", t.Unix())
+	}
+	if lastWrite.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d", lastWrite.Unix())
+}
+
+func (self *SoftwarePlugin) getDataset() (types.PluginInventoryDataset, error) {
+	var entries []uninstallEntry
+	for _, view := range uninstallViews() {
+		viewEntries, err := readUninstallEntries(view)
+		if err != nil {
+			if view.userSID == "" {
+				return nil, fmt.Errorf("error reading uninstall keys: %s", err)
+			}
+			// most users don't have their own installed software
+			continue
+		}
+		entries = append(entries, viewEntries...)
+	}
+	return softwareItems(entries), nil
+}
+
+func (self *SoftwarePlugin) Run() {
+	if self.frequency <= config.FREQ_DISABLE_SAMPLING {
+		swlog.Debug("Disabled.")
+		return
+	}
+
+	// Introduce some jitter to wait randomly before reporting based on frequency time
+	time.Sleep(config.JitterFrequency(self.frequency))
+
+	refreshTimer := time.NewTicker(self.frequency)
+	for {
+		dataset, err := self.getDataset()
+		if err != nil {
+			swlog.WithError(err).Error("software plugin can't get dataset")
+		} else {
+			self.EmitInventory(dataset, entity.NewFromNameWithoutID(self.Context.EntityKey()))
+		}
+		<-refreshTimer.C
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build windows
+// +build windows
+
+package windows
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftwareItems(t *testing.T) {
+	lastWrite := time.Date(2023, 3, 14, 10, 0, 0, 0, time.Local)
+	items := softwareItems([]uninstallEntry{
+		{key: "7-Zip", displayName: "7-Zip 22.01", version: "22.01", publisher: "Igor Pavlov", arch: "x86", lastWrite: lastWrite},
+		{key: "{23170F69-40C1-2702-2201-000001000000}", displayName: "7-Zip 22.01", version: "22.01", windowsInstaller: true, installDate: "20230301", arch: "x64"},
+		{key: "Code", displayName: "Visual Studio Code", version: "1.76.1", userSID: "S-1-5-21-1"},
+	})
+	require.Len(t, items, 3)
+
+	assert.Equal(t, SoftwareItem{
+		Name:         "7-Zip 22.01",
+		DisplayName:  "7-Zip 22.01",
+		Version:      "22.01",
+		Architecture: "x64",
+		InstallTime:  fmt.Sprintf("%d", time.Date(2023, 3, 1, 0, 0, 0, 0, time.Local).Unix()),
+		Installer:    InstallerMSI,
+		ProductCode:  "{23170F69-40C1-2702-2201-000001000000}",
+	}, items[0])
+	assert.Equal(t, SoftwareItem{
+		Name:         "7-Zip 22.01-1",
+		DisplayName:  "7-Zip 22.01",
+		Version:      "22.01",
+		Publisher:    "Igor Pavlov",
+		Architecture: "x86",
+		InstallTime:  fmt.Sprintf("%d", lastWrite.Unix()),
+		Installer:    InstallerRegistry,
+	}, items[1])
+	assert.Equal(t, "S-1-5-21-1", items[2].(SoftwareItem).UserSID)
+	assert.Empty(t, items[2].(SoftwareItem).InstallTime)
+}
+
+func TestHotfixInstallEpoch(t *testing.T) {
+	assert.Equal(t, fmt.Sprintf("%d", time.Date(2023, 3, 14, 0, 0, 0, 0, time.Local).Unix()), hotfixInstallEpoch("3/14/2023"))
+	// 2010-11-05T00:00:00Z as FILETIME
+	assert.Equal(t, "1288915200", hotfixInstallEpoch("1cb7c7c63a90000"))
+	assert.Empty(t, hotfixInstallEpoch(""))
+}
+
+func TestPendingReboot(t *testing.T) {
+	p := &PendingRebootPlugin{checks: []rebootCheck{
+		{RebootReasonComponentServicing, func() (bool, error) { return false, nil }},
+		{RebootReasonWindowsUpdate, func() (bool, error) { return true, nil }},
+		{RebootReasonFileRename, func() (bool, error) { return true, errors.New("access denied") }},
+		{RebootReasonComputerRename, func() (bool, error) { return true, nil }},
+	}}
+	assert.Equal(t, PendingRebootItem{Name: "pending_reboot", Pending: true, Reasons: "windows_update,computer_rename"}, p.getDataset()[0])
+
+	p.checks = p.checks[:1]
+	assert.Equal(t, PendingRebootItem{Name: "pending_reboot"}, p.getDataset()[0])
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"github.com/newrelic/infrastructure-agent/internal/agent/types"
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"strconv"
 	"time"
 
 	"github.com/StackExchange/wmi"
@@ -38,6 +39,27 @@ func (self Win32_QuickFixEngineering) SortKey() string {
 	return self.HotFixID
 }
 
+// HotfixItem is an installed hotfix, reported with its install time as epoch as the rest of the packages.
+type HotfixItem struct {
+	Win32_QuickFixEngineering
+	InstallTime string `json:"installed_epoch,omitempty"`
+}
+
+// hotfixInstallEpoch returns the install time of a hotfix. InstalledOn is usually a M/D/YYYY date, but some
+// hotfixes report it as an hexadecimal FILETIME.
+func hotfixInstallEpoch(installedOn string) string {
+	if t, err := time.ParseInLocation("1/2/2006", installedOn, time.Local); err == nil {
+		return fmt.Sprintf("%d", t.Unix())
+	}
+	if ft, err := strconv.ParseUint(installedOn, 16, 64); err == nil && ft > filetimeUnixEpoch {
+		return fmt.Sprintf("%d", (ft-filetimeUnixEpoch)/10000000)
+	}
+	return ""
+}
+
+// filetimeUnixEpoch is the Unix epoch as FILETIME, 100ns intervals since January 1, 1601
+const filetimeUnixEpoch = 116444736000000000
+
 func NewUpdatesPlugin(id ids.PluginID, ctx agent.AgentContext) agent.Plugin {
 	cfg := ctx.Config()
 	return &UpdatesPlugin{
@@ -59,7 +81,10 @@ func (self *UpdatesPlugin) getDataset() (result types.PluginInventoryDataset, er
 	}
 
 	for _, wmiResult := range wmiResults {
-		result = append(result, wmiResult)
+		result = append(result, HotfixItem{
+			Win32_QuickFixEngineering: wmiResult,
+			InstallTime:               hotfixInstallEpoch(wmiResult.InstalledOn),
+		})
 	}
 	return
 }
@@ -0,0 +1,89 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package backoff
+
+import (
+	"sync"
+	"time"
+
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+)
+
+// EndpointStats is a snapshot of the retries of the requests to an endpoint.
+type EndpointStats struct {
+	Endpoint string
+	// Retries is the total amount of failed requests retried.
+	Retries uint64
+	// Throttled is the total amount of retries delayed as requested by the backend.
+	Throttled uint64
+	// ConsecutiveFailures is reset on every successful request.
+	ConsecutiveFailures uint64
+	// LastBackoff is the wait before the last retry.
+	LastBackoff time.Duration
+}
+
+// EndpointBackoff computes the wait before retrying a failed request to a backend endpoint. Delays requested by
+// the backend through Retry-After are honored, otherwise a jittered exponential backoff is applied, so agents
+// don't retry in lockstep and amplify collector incidents.
+type EndpointBackoff struct {
+	backoff *Backoff
+
+	lock  sync.Mutex
+	stats EndpointStats
+}
+
+// NewEndpointBackoff returns the backoff for the endpoint with the default exponential backoff limits.
+func NewEndpointBackoff(endpoint string) *EndpointBackoff {
+	return &EndpointBackoff{
+		backoff: NewDefaultBackoff(),
+		stats:   EndpointStats{Endpoint: endpoint},
+	}
+}
+
+// WithMin sets the minimum wait of the exponential backoff, ie: the regular interval between requests.
+func (e *EndpointBackoff) WithMin(min time.Duration) *EndpointBackoff {
+	e.backoff.Min = min
+	return e
+}
+
+// Next returns the wait before retrying a request which failed with the retry policy. The exponential backoff is
+// limited by the policy MaxBackOff, or the default maximum when it's zero.
+func (e *EndpointBackoff) Next(policy backendhttp.RetryPolicy) time.Duration {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var wait time.Duration
+	if policy.After > 0 {
+		// the backend tells when it's ready again, so the exponential backoff starts over
+		e.backoff.Reset()
+		e.stats.Throttled++
+		wait = policy.After
+		if wait < e.backoff.Min {
+			wait = e.backoff.Min
+		}
+	} else {
+		wait = e.backoff.DurationWithMax(policy.MaxBackOff)
+	}
+
+	e.stats.Retries++
+	e.stats.ConsecutiveFailures++
+	e.stats.LastBackoff = wait
+	return wait
+}
+
+// Success resets the backoff after a successful request.
+func (e *EndpointBackoff) Success() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.backoff.Reset()
+	e.stats.ConsecutiveFailures = 0
+}
+
+// Stats returns the endpoint retry stats.
+func (e *EndpointBackoff) Stats() EndpointStats {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.stats
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointBackoff_HonorsRetryAfter(t *testing.T) {
+	b := NewEndpointBackoff("metrics")
+
+	assert.Equal(t, 42*time.Second, b.Next(backendhttp.RetryPolicy{After: 42 * time.Second}))
+	// delays shorter than the minimum are not honored
+	assert.Equal(t, DefaultMin, b.Next(backendhttp.RetryPolicy{After: time.Millisecond}))
+
+	stats := b.Stats()
+	assert.Equal(t, "metrics", stats.Endpoint)
+	assert.EqualValues(t, 2, stats.Retries)
+	assert.EqualValues(t, 2, stats.Throttled)
+	assert.EqualValues(t, 2, stats.ConsecutiveFailures)
+	assert.Equal(t, DefaultMin, stats.LastBackoff)
+}
+
+func TestEndpointBackoff_JitteredExponentialBackoff(t *testing.T) {
+	b := NewEndpointBackoff("inventory").WithMin(10 * time.Second)
+	policy := backendhttp.RetryPolicy{MaxBackOff: time.Minute}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		wait := b.Next(policy)
+		assert.GreaterOrEqual(t, wait, 10*time.Second)
+		assert.LessOrEqual(t, wait, time.Minute)
+		assert.LessOrEqual(t, wait, 10*time.Second<<attempt)
+	}
+	assert.EqualValues(t, 10, b.Stats().ConsecutiveFailures)
+	assert.Zero(t, b.Stats().Throttled)
+
+	b.Success()
+	assert.Zero(t, b.Stats().ConsecutiveFailures)
+	assert.EqualValues(t, 10, b.Stats().Retries)
+	assert.Equal(t, 10*time.Second, b.Next(policy))
+}
+
+func TestEndpointBackoff_RetryAfterRestartsBackoff(t *testing.T) {
+	b := NewEndpointBackoff("metrics")
+	for i := 0; i < 5; i++ {
+		b.Next(backendhttp.RetryPolicy{})
+	}
+
+	b.Next(backendhttp.RetryPolicy{After: 5 * time.Second})
+
+	assert.Equal(t, DefaultMin, b.Next(backendhttp.RetryPolicy{}))
+}
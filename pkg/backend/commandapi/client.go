@@ -3,10 +3,12 @@
 package commandapi
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 
 	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
@@ -16,6 +18,8 @@ import (
 type Client interface {
 	GetCommands(agentID entity.ID) ([]Command, error)
 	AckCommand(agentID entity.ID, cmdHash string) error
+	// UploadArtifact submits the result of the command, ie: a diagnostics run, as a named JSON artifact.
+	UploadArtifact(agentID entity.ID, cmdHash string, name string, content []byte) error
 }
 
 type Command struct {
@@ -98,6 +102,34 @@ func (c *client) AckCommand(agentID entity.ID, cmdHash string) error {
 	return fmt.Errorf("unsuccessful ack, status:%d [%s]", resp.StatusCode, string(body))
 }
 
+func (c *client) UploadArtifact(agentID entity.ID, cmdHash string, name string, content []byte) error {
+	artifactURL := fmt.Sprintf("%s/artifacts/%s?name=%s", c.svcURL, url.PathEscape(cmdHash), url.QueryEscape(name))
+	req, err := http.NewRequest("POST", artifactURL, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("cmd channel artifact request creation failed: %s", err)
+	}
+
+	resp, err := c.do(req, agentID)
+	if err != nil {
+		return fmt.Errorf("cmd channel artifact request submission failed: %s", err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if !backendhttp.IsResponseError(resp) {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		body = []byte(fmt.Sprintf("cannot read cmd channel artifact response: %s", err.Error()))
+	}
+
+	return fmt.Errorf("unsuccessful artifact upload, status:%d [%s]", resp.StatusCode, string(body))
+}
+
 func (c *client) do(req *http.Request, agentID entity.ID) (*http.Response, error) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", c.userAgent)
@@ -4,9 +4,11 @@ package commandapi
 
 import (
 	"errors"
+	"net/http"
 	"testing"
 
 	"github.com/newrelic/infrastructure-agent/pkg/backend/commandapi/commandapitest"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
 	"github.com/stretchr/testify/assert"
 )
@@ -126,3 +128,22 @@ func TestClient_AckCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_UploadArtifact(t *testing.T) {
+	var received *http.Request
+	httpClient := commandapitest.ClientReturns(200, "", nil)
+	client := NewClient("https://foo/", "123", "Agent v0", func(req *http.Request) (*http.Response, error) {
+		received = req
+		return httpClient.Do(req)
+	})
+
+	err := client.UploadArtifact(1, "abc", "diagnostics.json", []byte(`{"checks":{}}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://foo/artifacts/abc?name=diagnostics.json", received.URL.String())
+	assert.Equal(t, "1", received.Header.Get(backendhttp.AgentEntityIdHeader))
+	assert.Equal(t, `{"checks":{}}`, httpClient.ReceivedPayload)
+
+	client = NewClient("https://foo", "123", "Agent v0", commandapitest.ClientReturns(413, "too large", nil).Do)
+	assert.EqualError(t, client.UploadArtifact(1, "abc", "diagnostics.json", nil), "unsuccessful artifact upload, status:413 [too large]")
+}
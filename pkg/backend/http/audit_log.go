@@ -0,0 +1,173 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditTailSize is the size read from the end of an existing audit log to find its last entry.
+const auditTailSize = 64 * 1024
+
+var (
+	auditLogsLock sync.Mutex
+	auditLogs     = map[string]*auditLog{}
+)
+
+// auditEntry records a request sent to New Relic. The payload is not stored, only its hash.
+type auditEntry struct {
+	Time     time.Time `json:"time"`
+	Method   string    `json:"method"`
+	URL      string    `json:"url"`
+	Type     string    `json:"type"`
+	Size     int       `json:"size"`
+	Encoding string    `json:"encoding,omitempty"`
+	SHA256   string    `json:"sha256"`
+	Signed   bool      `json:"signed"`
+	Status   int       `json:"status,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	// Prev is the hex SHA-256 hash of the previous line of the log, so removed or modified entries are detected.
+	Prev string `json:"prev"`
+}
+
+func newAuditEntry(req *http.Request, size int, bodyHash string, signed bool) auditEntry {
+	// the query and credentials are not recorded
+	u := *req.URL
+	u.User = nil
+	u.RawQuery = ""
+
+	return auditEntry{
+		Time:     time.Now(),
+		Method:   req.Method,
+		URL:      u.String(),
+		Type:     payloadType(u.Path),
+		Size:     size,
+		Encoding: req.Header.Get("Content-Encoding"),
+		SHA256:   bodyHash,
+		Signed:   signed,
+	}
+}
+
+// payloadType classifies the request by the endpoint it's sent to.
+func payloadType(path string) string {
+	switch {
+	case strings.Contains(path, "/events/bulk"):
+		return "events"
+	case strings.Contains(path, "/inventory"):
+		return "inventory"
+	case strings.Contains(path, "/identity/"):
+		return "identity"
+	case strings.Contains(path, "/agent_commands/"):
+		return "commands"
+	case strings.Contains(path, "/metric"):
+		return "metrics"
+	default:
+		return "other"
+	}
+}
+
+// auditLog appends the entries as JSON lines to the audit_log_file. The agent never truncates or rotates it, so
+// it must be rotated externally, ie: with logrotate copytruncate.
+type auditLog struct {
+	lock sync.Mutex
+	file *os.File
+	prev string
+	// newline is written before the first entry when the file doesn't end with a line break, ie: the agent
+	// stopped while writing.
+	newline bool
+}
+
+// sharedAuditLog returns the audit log for the file, which is shared by all the transports so the entries are
+// chained in a single sequence.
+func sharedAuditLog(file string) (*auditLog, error) {
+	auditLogsLock.Lock()
+	defer auditLogsLock.Unlock()
+
+	if l, ok := auditLogs[file]; ok {
+		return l, nil
+	}
+
+	l, err := openAuditLog(file)
+	if err != nil {
+		return nil, err
+	}
+	auditLogs[file] = l
+	return l, nil
+}
+
+func openAuditLog(name string) (*auditLog, error) {
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &auditLog{file: file}
+	if err = l.readTail(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// readTail continues the chain from the last entry of an existing log.
+func (l *auditLog) readTail() error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+
+	offset := info.Size() - auditTailSize
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, info.Size()-offset)
+	if _, err = l.file.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return err
+	}
+
+	if !bytes.HasSuffix(tail, []byte("\n")) {
+		l.newline = true
+		tail = append(tail, '\n')
+	}
+	lines := bytes.Split(bytes.TrimSuffix(tail, []byte("\n")), []byte("\n"))
+	l.prev = lineHash(lines[len(lines)-1])
+	return nil
+}
+
+func lineHash(line []byte) string {
+	hash := sha256.Sum256(line)
+	return hex.EncodeToString(hash[:])
+}
+
+func (l *auditLog) write(entry auditEntry) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	entry.Prev = l.prev
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	data := append(line, '\n')
+	if l.newline {
+		data = append([]byte("\n"), data...)
+	}
+	if _, err = l.file.Write(data); err != nil {
+		return err
+	}
+	l.prev, l.newline = lineHash(line), false
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAuditLog returns the entries of the log, verifying their chain.
+func readAuditLog(t *testing.T, file string) []auditEntry {
+	t.Helper()
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []auditEntry
+	prev := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry auditEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		assert.Equal(t, prev, entry.Prev, "entry %d is chained", len(entries))
+		prev = lineHash(scanner.Bytes())
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+	return entries
+}
+
+func TestAuditTransport_Log(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/inventory/deltas" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	logFile := filepath.Join(t.TempDir(), "audit.log")
+	cfg := config.NewConfig()
+	cfg.AuditLogFile = logFile
+	client := &http.Client{Transport: newAuditTransport(cfg, http.DefaultTransport)}
+
+	payload := []byte(`[{"eventType":"SystemSample"}]`)
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/metrics/events/bulk?token=secret", bytes.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	_, err = client.Do(req)
+	require.NoError(t, err)
+
+	_, err = client.Post(server.URL+"/inventory/deltas", "application/json", bytes.NewBufferString("{}"))
+	require.NoError(t, err)
+
+	_, err = client.Post("http://127.0.0.1:1/identity/v1/connect", "application/json", bytes.NewBufferString("{}"))
+	require.Error(t, err)
+
+	entries := readAuditLog(t, logFile)
+	require.Len(t, entries, 3)
+
+	hash := sha256.Sum256(payload)
+	assert.Equal(t, http.MethodPost, entries[0].Method)
+	assert.Equal(t, server.URL+"/metrics/events/bulk", entries[0].URL, "the query is not recorded")
+	assert.Equal(t, "events", entries[0].Type)
+	assert.Equal(t, len(payload), entries[0].Size)
+	assert.Equal(t, "gzip", entries[0].Encoding)
+	assert.Equal(t, hex.EncodeToString(hash[:]), entries[0].SHA256)
+	assert.False(t, entries[0].Signed)
+	assert.Equal(t, http.StatusAccepted, entries[0].Status)
+
+	assert.Equal(t, "inventory", entries[1].Type)
+	assert.Equal(t, http.StatusServiceUnavailable, entries[1].Status)
+
+	assert.Equal(t, "identity", entries[2].Type)
+	assert.Zero(t, entries[2].Status)
+	assert.NotEmpty(t, entries[2].Error)
+}
+
+func TestAuditLog_ContinuesChain(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := openAuditLog(logFile)
+	require.NoError(t, err)
+	require.NoError(t, l.write(auditEntry{Type: "events"}))
+	require.NoError(t, l.file.Close())
+
+	// the agent stopped while writing an entry
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	l, err = openAuditLog(logFile)
+	require.NoError(t, err)
+	require.NoError(t, l.write(auditEntry{Type: "inventory"}))
+	require.NoError(t, l.file.Close())
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n"))
+	require.Len(t, lines, 3)
+
+	var last auditEntry
+	require.NoError(t, json.Unmarshal(lines[2], &last))
+	assert.Equal(t, "inventory", last.Type)
+	assert.Equal(t, lineHash(lines[1]), last.Prev, "chained to the truncated line")
+}
+
+func TestSharedAuditLog(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "audit.log")
+	l, err := sharedAuditLog(logFile)
+	require.NoError(t, err)
+	other, err := sharedAuditLog(logFile)
+	require.NoError(t, err)
+	assert.Same(t, l, other)
+
+	_, err = sharedAuditLog(filepath.Join(t.TempDir(), "missing", "audit.log"))
+	assert.Error(t, err)
+}
+
+func TestPayloadType(t *testing.T) {
+	tests := map[string]string{
+		"/metrics/events/bulk":          "events",
+		"/infra/v2/metrics/events/bulk": "events",
+		"/inventory/deltas":             "inventory",
+		"/identity/v1/connect":          "identity",
+		"/agent_commands/v1/commands":   "commands",
+		"/metric/v1/infra":              "metrics",
+		"/v1/metrics":                   "metrics",
+		"/unknown":                      "other",
+	}
+	for path, expected := range tests {
+		assert.Equal(t, expected, payloadType(path), path)
+	}
+}
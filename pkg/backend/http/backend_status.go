@@ -0,0 +1,98 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxBackendReports limits the endpoints whose requests are reported.
+const maxBackendReports = 100
+
+var (
+	backendRequestsLock sync.Mutex
+	backendRequests     = map[string]*backendRequest{}
+)
+
+// BackendReport represents the requests sent by the agent to a New Relic endpoint, and their last error.
+type BackendReport struct {
+	URL           string     `json:"url"`
+	Requests      int        `json:"requests"`
+	Errors        int        `json:"errors"`
+	Failing       bool       `json:"failing"`
+	LastSuccess   *time.Time `json:"last_success,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+type backendRequest struct {
+	requests    int
+	errors      int
+	lastSuccess time.Time
+	lastErr     string
+	lastErrTime time.Time
+}
+
+// recordBackendRequest records the result of a request to New Relic. The HEAD requests are not recorded, as they're
+// the endpoints reachability checks, reported on their own.
+func recordBackendRequest(req *http.Request, resp *http.Response, err error) {
+	if req.Method == http.MethodHead || req.URL == nil {
+		return
+	}
+	if err == nil && IsResponseError(resp) {
+		err = fmt.Errorf("unsuccessful response, status: %d", resp.StatusCode)
+	}
+	endpoint := fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Host, req.URL.Path)
+
+	backendRequestsLock.Lock()
+	defer backendRequestsLock.Unlock()
+
+	r, ok := backendRequests[endpoint]
+	if !ok {
+		if len(backendRequests) >= maxBackendReports {
+			return
+		}
+		r = &backendRequest{}
+		backendRequests[endpoint] = r
+	}
+	r.requests++
+	if err != nil {
+		r.errors++
+		r.lastErr, r.lastErrTime = err.Error(), time.Now()
+	} else {
+		r.lastSuccess = time.Now()
+	}
+}
+
+// BackendReports returns the requests sent by the transports created by NewReloadableTransport, sorted by URL.
+func BackendReports() []BackendReport {
+	backendRequestsLock.Lock()
+	defer backendRequestsLock.Unlock()
+
+	reports := make([]BackendReport, 0, len(backendRequests))
+	for endpoint, r := range backendRequests {
+		report := BackendReport{
+			URL:      endpoint,
+			Requests: r.requests,
+			Errors:   r.errors,
+			Failing:  r.lastErrTime.After(r.lastSuccess),
+		}
+		if !r.lastSuccess.IsZero() {
+			lastSuccess := r.lastSuccess
+			report.LastSuccess = &lastSuccess
+		}
+		if r.lastErr != "" {
+			errTime := r.lastErrTime
+			report.LastError, report.LastErrorTime = r.lastErr, &errTime
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].URL < reports[j].URL
+	})
+	return reports
+}
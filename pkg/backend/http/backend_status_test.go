@@ -0,0 +1,56 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendReports(t *testing.T) {
+	backendRequestsLock.Lock()
+	backendRequests = map[string]*backendRequest{}
+	backendRequestsLock.Unlock()
+
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	client := GetHttpClient(time.Second, NewReloadableTransport(&config.Config{IgnoreSystemProxy: true}, time.Second))
+	post := func() {
+		resp, err := client.Post(srv.URL+"/infra/v2/metrics?query=ignored", "application/json", nil)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	post()
+	reports := BackendReports()
+	require.Len(t, reports, 1)
+	assert.Equal(t, srv.URL+"/infra/v2/metrics", reports[0].URL)
+	assert.True(t, reports[0].Failing)
+	assert.Equal(t, "unsuccessful response, status: 503", reports[0].LastError)
+	assert.Nil(t, reports[0].LastSuccess)
+
+	status = http.StatusAccepted
+	post()
+	// reachability checks are not recorded
+	resp, err := client.Head(srv.URL + "/identity/v1")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	reports = BackendReports()
+	require.Len(t, reports, 1)
+	assert.False(t, reports[0].Failing)
+	assert.Equal(t, 2, reports[0].Requests)
+	assert.Equal(t, 1, reports[0].Errors)
+	assert.NotNil(t, reports[0].LastSuccess)
+	assert.NotEmpty(t, reports[0].LastError)
+}
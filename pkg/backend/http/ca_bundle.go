@@ -0,0 +1,213 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"crypto/x509"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// caBundleReloadDelay is the time the CA bundle is reloaded after its last change, so the several events of
+// rewriting or replacing the files trigger a single reload.
+var caBundleReloadDelay = time.Second
+
+var (
+	caBundlesLock sync.Mutex
+	caBundles     = map[string]*caBundle{}
+)
+
+// caBundle provides the root certificates loaded from the ca_bundle_file and ca_bundle_dir options. The files are
+// watched and reloaded when modified, so the CAs can be rotated without restarting the agent.
+type caBundle struct {
+	file    string
+	dir     string
+	lock    sync.RWMutex
+	pool    *x509.CertPool
+	version uint64 // increased on every reload
+	watcher *fsnotify.Watcher
+}
+
+// sharedCABundle returns the CA bundle for the file and directory, which is shared by all the transports so it's
+// loaded and watched once. It exits when the bundle cannot be loaded, as the agent cannot connect securely.
+func sharedCABundle(file, dir string) *caBundle {
+	caBundlesLock.Lock()
+	defer caBundlesLock.Unlock()
+
+	key := file + string(filepath.ListSeparator) + dir
+	if b, ok := caBundles[key]; ok {
+		return b
+	}
+
+	b := &caBundle{file: file, dir: dir, pool: getCertPool(file, dir)}
+	b.watch()
+	caBundles[key] = b
+	return b
+}
+
+// load returns the current root certificates and their version.
+func (b *caBundle) load() (*x509.CertPool, uint64) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	return b.pool, b.version
+}
+
+func (b *caBundle) log() log.Entry {
+	return plog.WithFields(logrus.Fields{
+		"file":      b.file,
+		"directory": b.dir,
+	})
+}
+
+// watch reloads the bundle on the changes of its files. The directory of the file is watched rather than the file,
+// so it keeps being watched when it's replaced, ie: by package managers or mounted Kubernetes secrets.
+func (b *caBundle) watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		b.log().WithError(err).Warn("Cannot watch CA bundle, it won't be reloaded when modified.")
+		return
+	}
+
+	for _, path := range b.watchedPaths() {
+		if err := watcher.Add(path); err != nil {
+			b.log().WithError(err).WithField("path", path).Warn("Cannot watch CA bundle path, it won't be reloaded when modified.")
+		}
+	}
+
+	b.watcher = watcher
+	go b.reloadOnChange(watcher)
+}
+
+func (b *caBundle) watchedPaths() []string {
+	var paths []string
+	if b.file != "" {
+		paths = append(paths, filepath.Dir(b.file))
+	}
+	if b.dir != "" && (b.file == "" || filepath.Clean(b.dir) != filepath.Dir(b.file)) {
+		paths = append(paths, filepath.Clean(b.dir))
+	}
+	return paths
+}
+
+func (b *caBundle) reloadOnChange(watcher *fsnotify.Watcher) {
+	var reload *time.Timer
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod || !b.isBundleFile(event.Name) {
+				continue
+			}
+			if reload == nil {
+				reload = time.AfterFunc(caBundleReloadDelay, b.reload)
+			} else {
+				reload.Reset(caBundleReloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			b.log().WithError(err).Warn("Error watching CA bundle.")
+		}
+	}
+}
+
+// isBundleFile returns true for the files in the bundle directory, the bundle file and the hidden entries that
+// Kubernetes swaps to update mounted files, ie: ..data
+func (b *caBundle) isBundleFile(name string) bool {
+	name = filepath.Clean(name)
+	dir := filepath.Dir(name)
+	if b.dir != "" && dir == filepath.Clean(b.dir) {
+		return true
+	}
+	return b.file != "" && dir == filepath.Dir(b.file) &&
+		(name == filepath.Clean(b.file) || strings.HasPrefix(filepath.Base(name), ".."))
+}
+
+// reload loads the bundle again, keeping the previous root certificates when it cannot be loaded.
+func (b *caBundle) reload() {
+	pool, err := loadCertPool(b.file, b.dir)
+	if err != nil {
+		b.log().WithError(err).Warn("Cannot reload CA bundle, keeping the previous one.")
+		return
+	}
+
+	b.lock.Lock()
+	b.pool = pool
+	b.version++
+	b.lock.Unlock()
+	b.log().Info("CA bundle reloaded.")
+}
+
+// close stops watching the bundle and removes it from the shared ones.
+func (b *caBundle) close() {
+	caBundlesLock.Lock()
+	delete(caBundles, b.file+string(filepath.ListSeparator)+b.dir)
+	caBundlesLock.Unlock()
+
+	if b.watcher != nil {
+		_ = b.watcher.Close()
+	}
+}
+
+// caBundleTransport builds the transport again with the new root certificates when the CA bundle is reloaded, as
+// the TLS configuration can't be modified once in use.
+type caBundleTransport struct {
+	bundle *caBundle
+	build  func(roots *x509.CertPool) http.RoundTripper
+
+	lock      sync.Mutex
+	version   uint64
+	transport http.RoundTripper
+}
+
+func newCABundleTransport(bundle *caBundle, build func(roots *x509.CertPool) http.RoundTripper) *caBundleTransport {
+	roots, version := bundle.load()
+	return &caBundleTransport{
+		bundle:    bundle,
+		build:     build,
+		version:   version,
+		transport: build(roots),
+	}
+}
+
+func (t *caBundleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.current().RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the current transport.
+func (t *caBundleTransport) CloseIdleConnections() {
+	closeIdleConnections(t.current())
+}
+
+// current returns the transport for the current root certificates, closing the idle connections of the previous
+// one. Its active connections are closed once idle for the transport IdleConnTimeout.
+func (t *caBundleTransport) current() http.RoundTripper {
+	roots, version := t.bundle.load()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if version != t.version {
+		closeIdleConnections(t.transport)
+		t.transport = t.build(roots)
+		t.version = version
+	}
+	return t.transport
+}
+
+func closeIdleConnections(rt http.RoundTripper) {
+	if c, ok := rt.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
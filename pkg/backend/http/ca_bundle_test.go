@@ -0,0 +1,75 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serverCertPEM returns the certificate of the TLS test server in PEM format.
+func serverCertPEM(srv *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+}
+
+func TestBuildTransport_CABundleReload(t *testing.T) {
+	defer func(delay time.Duration) { caBundleReloadDelay = delay }(caBundleReloadDelay)
+	caBundleReloadDelay = 10 * time.Millisecond
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte(firstCA), 0o600))
+
+	cfg := &config.Config{CABundleFile: caFile, IgnoreSystemProxy: true}
+	client := GetHttpClient(time.Second, BuildTransport(cfg, time.Second))
+	defer sharedCABundle(caFile, "").close()
+
+	_, err := client.Get(srv.URL)
+	require.Error(t, err, "the server certificate is not trusted yet")
+
+	require.NoError(t, os.WriteFile(caFile, serverCertPEM(srv), 0o600))
+	assert.Eventually(t, func() bool {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return true
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestCABundle_KeepsPreviousOnError(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.pem"), []byte(anotherCA), 0o600))
+
+	b := sharedCABundle("", dir)
+	defer b.close()
+	pool, version := b.load()
+
+	require.NoError(t, os.RemoveAll(dir))
+	b.reload()
+	reloaded, reloadedVersion := b.load()
+	assert.Same(t, pool, reloaded)
+	assert.Equal(t, version, reloadedVersion)
+}
+
+func TestCABundle_IsBundleFile(t *testing.T) {
+	b := &caBundle{file: "/etc/newrelic-infra/ca/ca.pem", dir: "/etc/ssl/agent"}
+
+	assert.True(t, b.isBundleFile("/etc/newrelic-infra/ca/ca.pem"))
+	assert.True(t, b.isBundleFile("/etc/newrelic-infra/ca/..data"))
+	assert.True(t, b.isBundleFile("/etc/ssl/agent/other.pem"))
+	assert.False(t, b.isBundleFile("/etc/newrelic-infra/ca/other.pem"))
+	assert.False(t, b.isBundleFile("/etc/newrelic-infra/newrelic-infra.yml"))
+}
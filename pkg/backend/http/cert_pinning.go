@@ -0,0 +1,90 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+const pinPrefix = "sha256/"
+
+var errPinMismatch = errors.New("certificate chain doesn't match any of the collector_pins")
+
+// pinVerifier verifies that the certificate chains of the New Relic endpoints contain a public key of the
+// collector_pins, so an interception through a trusted CA is detected.
+type pinVerifier struct {
+	pins  map[string]bool // base64 SHA-256 hashes of the trusted public keys
+	hosts map[string]bool
+	err   error // invalid pins, failing the connections
+}
+
+// newPinVerifier returns the verifier for the collector_pins, or nil if they are not configured. Invalid pins are
+// logged, and the connections fail with their error.
+func newPinVerifier(cfg *config.Config) *pinVerifier {
+	if strings.TrimSpace(cfg.CollectorPins) == "" {
+		return nil
+	}
+
+	pins, err := parsePins(cfg.CollectorPins)
+	if err != nil {
+		err = fmt.Errorf("invalid collector_pins: %w", err)
+		plog.WithError(err).Error("Cannot connect to New Relic.")
+		return &pinVerifier{err: err}
+	}
+
+	return &pinVerifier{pins: pins, hosts: EndpointHosts(cfg)}
+}
+
+// parsePins parses the comma separated SPKI hashes, either plain base64 or prefixed by sha256/ as in HPKP.
+func parsePins(raw string) (map[string]bool, error) {
+	pins := make(map[string]bool)
+	for _, pin := range strings.Split(raw, ",") {
+		pin = strings.TrimPrefix(strings.TrimSpace(pin), pinPrefix)
+		if pin == "" {
+			continue
+		}
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("%q is not a base64 encoded SHA-256 hash", pin)
+		}
+		pins[pin] = true
+	}
+	return pins, nil
+}
+
+// spkiHash returns the base64 SHA-256 hash of the certificate public key, as used in collector_pins.
+func spkiHash(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// VerifyConnection implements tls.Config VerifyConnection. It's called after the certificate chain is verified,
+// so only the verified chains are checked. IP addresses are not pinned, as they are not sent as server name.
+func (v *pinVerifier) VerifyConnection(cs tls.ConnectionState) error {
+	if v.err != nil {
+		return v.err
+	}
+	if !v.hosts[strings.ToLower(cs.ServerName)] {
+		return nil
+	}
+
+	var presented []string
+	for _, chain := range cs.VerifiedChains {
+		for _, cert := range chain {
+			hash := spkiHash(cert)
+			if v.pins[hash] {
+				return nil
+			}
+			presented = append(presented, pinPrefix+hash)
+		}
+	}
+	return fmt.Errorf("%w for %s, presented: %s", errPinMismatch, cs.ServerName, strings.Join(presented, ","))
+}
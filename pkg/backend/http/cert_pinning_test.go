@@ -0,0 +1,88 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePins(t *testing.T) {
+	pins, err := parsePins(" sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=, AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=": true,
+		"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=": true,
+	}, pins)
+
+	_, err = parsePins("sha256/not-base64")
+	assert.Error(t, err)
+	_, err = parsePins("AAAA")
+	assert.Error(t, err, "not a SHA-256 hash")
+}
+
+func TestNewPinVerifier(t *testing.T) {
+	assert.Nil(t, newPinVerifier(&config.Config{}))
+
+	v := newPinVerifier(&config.Config{
+		CollectorURL:  "https://infra-api.newrelic.com",
+		MetricURL:     "https://metric-api.newrelic.com",
+		CollectorPins: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+	})
+	require.NotNil(t, v)
+	assert.Equal(t, map[string]bool{"infra-api.newrelic.com": true, "metric-api.newrelic.com": true}, v.hosts)
+}
+
+func TestBuildTransport_CollectorPins(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	srvURL, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, serverCertPEM(srv), 0o600))
+	defer sharedCABundle(caFile, "").close()
+
+	// the test server certificate is valid for example.com, which is dialed to the server
+	get := func(collectorURL, pins string) error {
+		cfg := &config.Config{
+			CollectorURL:      collectorURL,
+			CollectorPins:     pins,
+			CollectorDial:     "tcp://" + srvURL.Host,
+			CABundleFile:      caFile,
+			IgnoreSystemProxy: true,
+		}
+		resp, err := GetHttpClient(time.Second, BuildTransport(cfg, time.Second)).Get("https://example.com")
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	serverPin := pinPrefix + spkiHash(srv.Certificate())
+	otherPin := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+
+	t.Run("matching pin", func(t *testing.T) {
+		assert.NoError(t, get("https://example.com", otherPin+","+serverPin))
+	})
+	t.Run("mismatching pin", func(t *testing.T) {
+		err := get("https://example.com", otherPin)
+		assert.ErrorIs(t, err, errPinMismatch)
+		assert.ErrorContains(t, err, serverPin)
+	})
+	t.Run("host not pinned", func(t *testing.T) {
+		assert.NoError(t, get("https://infra-api.newrelic.com", otherPin))
+	})
+	t.Run("invalid pins", func(t *testing.T) {
+		assert.Error(t, get("https://example.com", "foo"))
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const pemPrefix = "-----BEGIN"
+
+// clientCertificate provides the client certificate for mutual TLS. The certificate and key are either PEM contents,
+// ie: resolved from databind variables, or paths to PEM files. Files are reloaded when modified, so certificates
+// can be rotated without restarting the agent.
+type clientCertificate struct {
+	certSource string
+	keySource  string
+
+	lock        sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// newClientCertificate loads the client certificate, returning nil when it's not configured. When it cannot be
+// loaded, ie: the files are not provisioned yet, the error is returned along with the client certificate, which
+// keeps retrying on the next handshakes.
+func newClientCertificate(certSource, keySource string) (*clientCertificate, error) {
+	if certSource == "" && keySource == "" {
+		return nil, nil
+	}
+	if certSource == "" || keySource == "" {
+		return nil, errors.New("both client_certificate and client_key must be provided")
+	}
+
+	c := &clientCertificate{certSource: certSource, keySource: keySource}
+	return c, c.load()
+}
+
+// GetClientCertificate implements tls.Config GetClientCertificate, reloading the certificate when its files
+// changed since the last handshake, or when it was never loaded. The previous certificate is kept when the new one
+// cannot be loaded, and no certificate is sent while none could be loaded.
+func (c *clientCertificate) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.cert == nil {
+		if err := c.load(); err != nil {
+			plog.WithError(err).Warn("Cannot load client certificate, connecting without it.")
+			return &tls.Certificate{}, nil
+		}
+		plog.Info("Client certificate loaded.")
+	} else if c.modified() {
+		if err := c.load(); err != nil {
+			plog.WithError(err).Warn("Cannot reload client certificate, keeping the previous one.")
+		} else {
+			plog.Info("Client certificate reloaded.")
+		}
+	}
+	return c.cert, nil
+}
+
+// modified returns true when any of the certificate or key files changed since they were loaded.
+func (c *clientCertificate) modified() bool {
+	return modTime(c.certSource) != c.certModTime || modTime(c.keySource) != c.keyModTime
+}
+
+func (c *clientCertificate) load() error {
+	// modification times are read before the contents, so changes while loading trigger another reload
+	certModTime, keyModTime := modTime(c.certSource), modTime(c.keySource)
+
+	certPEM, err := pemContent(c.certSource)
+	if err != nil {
+		return fmt.Errorf("cannot read client certificate: %w", err)
+	}
+	keyPEM, err := pemContent(c.keySource)
+	if err != nil {
+		return fmt.Errorf("cannot read client key: %w", err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("invalid client certificate: %w", err)
+	}
+
+	c.cert = &cert
+	c.certModTime, c.keyModTime = certModTime, keyModTime
+	return nil
+}
+
+func isPEM(source string) bool {
+	return strings.HasPrefix(strings.TrimSpace(source), pemPrefix)
+}
+
+// pemContent returns the source itself for PEM contents, or the file content otherwise.
+func pemContent(source string) ([]byte, error) {
+	if isPEM(source) {
+		return []byte(source), nil
+	}
+	return os.ReadFile(source)
+}
+
+// modTime returns the modification time of file sources, zero for PEM contents or missing files.
+func modTime(source string) time.Time {
+	if isPEM(source) {
+		return time.Time{}
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
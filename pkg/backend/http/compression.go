@@ -0,0 +1,88 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Payload compression algorithms, also used as Content-Encoding values.
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// PayloadCompressor compresses the payloads sent to the backend. When zstd is configured but the backend doesn't
+// support it, the compressor falls back to gzip. A nil compressor doesn't compress payloads.
+type PayloadCompressor struct {
+	level       int
+	zstdEncoder *zstd.Encoder
+	// zstdRejected is set once the backend rejects zstd payloads, accessed atomically
+	zstdRejected int32
+}
+
+// NewPayloadCompressor returns a compressor for the algorithm. Level is the gzip compression level, also mapped to
+// the zstd ones, and no compression is applied for gzip.NoCompression.
+func NewPayloadCompressor(algorithm string, level int) *PayloadCompressor {
+	c := &PayloadCompressor{level: level}
+	if algorithm != CompressionZstd || level <= gzip.NoCompression {
+		return c
+	}
+
+	// EncodeAll is safe for concurrent use, a single encoder is shared
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		plog.WithError(err).Warn("Cannot create zstd encoder, using gzip payload compression.")
+		return c
+	}
+	c.zstdEncoder = enc
+	return c
+}
+
+// Compress returns the compressed payload along with its Content-Encoding, empty when it's not compressed.
+func (c *PayloadCompressor) Compress(payload []byte) ([]byte, string, error) {
+	if c == nil || c.level <= gzip.NoCompression {
+		return payload, "", nil
+	}
+	if c.zstdEncoder != nil && atomic.LoadInt32(&c.zstdRejected) == 0 {
+		return c.zstdEncoder.EncodeAll(payload, make([]byte, 0, len(payload)/2)), CompressionZstd, nil
+	}
+
+	var buf bytes.Buffer
+	gzipWriter, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to create gzip writer: %v", err)
+	}
+	if _, err = gzipWriter.Write(payload); err != nil {
+		return nil, "", fmt.Errorf("gzip writer was not able to write to request body: %s", err)
+	}
+	if err = gzipWriter.Close(); err != nil {
+		return nil, "", fmt.Errorf("gzip writer did not close: %s", err)
+	}
+	return buf.Bytes(), CompressionGzip, nil
+}
+
+// Negotiate inspects the backend response to a payload compressed with encoding, falling back to gzip when zstd
+// is not supported: either the payload was rejected with 415 Unsupported Media Type, or the response Accept-Encoding
+// header (RFC 7694) doesn't list it. It returns true when the payload was rejected and must be sent again.
+func (c *PayloadCompressor) Negotiate(resp *http.Response, encoding string) (retry bool) {
+	if c == nil || encoding != CompressionZstd {
+		return false
+	}
+
+	rejected := resp.StatusCode == http.StatusUnsupportedMediaType
+	if accepted := resp.Header.Get("Accept-Encoding"); accepted != "" && !strings.Contains(accepted, CompressionZstd) {
+		rejected = true
+	}
+	if rejected && atomic.CompareAndSwapInt32(&c.zstdRejected, 0, 1) {
+		plog.WithField("status", resp.StatusCode).Info("Backend doesn't support zstd payloads, falling back to gzip.")
+	}
+	return rejected && resp.StatusCode == http.StatusUnsupportedMediaType
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPayload = bytes.Repeat([]byte(`{"eventType":"SystemSample","cpuPercent":12.5}`), 100)
+
+func TestPayloadCompressor_NoCompression(t *testing.T) {
+	for _, c := range []*PayloadCompressor{nil, NewPayloadCompressor(CompressionZstd, gzip.NoCompression)} {
+		body, encoding, err := c.Compress(testPayload)
+		require.NoError(t, err)
+		assert.Empty(t, encoding)
+		assert.Equal(t, testPayload, body)
+	}
+}
+
+func TestPayloadCompressor_Gzip(t *testing.T) {
+	body, encoding, err := NewPayloadCompressor(CompressionGzip, gzip.BestSpeed).Compress(testPayload)
+	require.NoError(t, err)
+	assert.Equal(t, CompressionGzip, encoding)
+
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, testPayload, decompressed)
+}
+
+func TestPayloadCompressor_Zstd(t *testing.T) {
+	body, encoding, err := NewPayloadCompressor(CompressionZstd, 6).Compress(testPayload)
+	require.NoError(t, err)
+	assert.Equal(t, CompressionZstd, encoding)
+
+	dec, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	defer dec.Close()
+	decompressed, err := dec.DecodeAll(body, nil)
+	require.NoError(t, err)
+	assert.Equal(t, testPayload, decompressed)
+}
+
+func TestPayloadCompressor_Negotiate(t *testing.T) {
+	response := func(status int, acceptEncoding string) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+		if acceptEncoding != "" {
+			resp.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		return resp
+	}
+
+	c := NewPayloadCompressor(CompressionZstd, 6)
+	assert.False(t, c.Negotiate(response(http.StatusAccepted, "gzip, zstd"), CompressionZstd))
+	assert.False(t, c.Negotiate(response(http.StatusUnsupportedMediaType, ""), CompressionGzip))
+	_, encoding, _ := c.Compress(testPayload)
+	assert.Equal(t, CompressionZstd, encoding)
+
+	// rejected payloads are retried with gzip
+	assert.True(t, c.Negotiate(response(http.StatusUnsupportedMediaType, "gzip"), CompressionZstd))
+	_, encoding, _ = c.Compress(testPayload)
+	assert.Equal(t, CompressionGzip, encoding)
+
+	// accepted payloads advertising other encodings fall back without retrying
+	c = NewPayloadCompressor(CompressionZstd, 6)
+	assert.False(t, c.Negotiate(response(http.StatusAccepted, "gzip"), CompressionZstd))
+	_, encoding, _ = c.Compress(testPayload)
+	assert.Equal(t, CompressionGzip, encoding)
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// dialContextFunc function type that can be assigned to transport.DialContext
+type dialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// DialerFactory creates the dialer of a collector_dial address. The forward dialer has the agent connection
+// timeouts, to be used by the dialers connecting through other networks.
+type DialerFactory func(u *url.URL, forward *net.Dialer) (func(ctx context.Context, network, addr string) (net.Conn, error), error)
+
+var (
+	dialersLock sync.RWMutex
+	dialers     = map[string]DialerFactory{
+		"unix": unixDialer,
+		"tcp":  tcpDialer,
+	}
+)
+
+// RegisterDialer registers the dialer factory for the collector_dial addresses with the scheme, so builds embedding
+// the agent can provide their own dialers, ie: through a tunnel.
+func RegisterDialer(scheme string, factory DialerFactory) {
+	dialersLock.Lock()
+	defer dialersLock.Unlock()
+
+	dialers[scheme] = factory
+}
+
+// collectorDialer establishes the backend connections, through the collector_dial address when it's configured.
+// It also implements the forward dialer of the SOCKS proxies, so they are connected to through it as well.
+type collectorDialer struct {
+	dial dialContextFunc
+}
+
+// newCollectorDialer returns the dialer for the collector_dial address, connecting to the requested address when
+// it's empty. An invalid address is logged, and the connections fail with its error.
+func newCollectorDialer(address string, timeout time.Duration) *collectorDialer {
+	forward := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if address == "" {
+		return &collectorDialer{dial: forward.DialContext}
+	}
+
+	dial, err := parseDialAddress(address, forward)
+	if err != nil {
+		err = fmt.Errorf("invalid collector_dial address %q: %v", address, err)
+		plog.WithError(err).Error("Cannot connect to New Relic.")
+		return &collectorDialer{dial: func(context.Context, string, string) (net.Conn, error) {
+			return nil, err
+		}}
+	}
+
+	plog.WithField("address", address).Info("Connecting through the collector_dial address.")
+	return &collectorDialer{dial: dial}
+}
+
+func parseDialAddress(address string, forward *net.Dialer) (dialContextFunc, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	dialersLock.RLock()
+	factory, ok := dialers[u.Scheme]
+	dialersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	return factory(u, forward)
+}
+
+func (d *collectorDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d.dial(ctx, network, addr)
+}
+
+func (d *collectorDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.dial(context.Background(), network, addr)
+}
+
+// unixDialer connects to the unix socket in the path of the address, ie: unix:///var/run/nr-proxy.sock
+func unixDialer(u *url.URL, forward *net.Dialer) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	path := u.Path
+	if path == "" {
+		// relative paths, ie: unix:nr-proxy.sock
+		path = u.Opaque
+	}
+	if path == "" {
+		return nil, errors.New("missing unix socket path")
+	}
+
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return forward.DialContext(ctx, "unix", path)
+	}, nil
+}
+
+// tcpDialer connects to the host and port of the address, ie: tcp://127.0.0.1:15001
+func tcpDialer(u *url.URL, forward *net.Dialer) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, errors.New("address must have a host and a port")
+	}
+
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return forward.DialContext(ctx, "tcp", u.Host)
+	}, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDialTestServer starts an HTTP server on the listener, replying with the requested host.
+func newDialTestServer(t *testing.T, l net.Listener) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host))
+	}))
+	server.Listener = l
+	server.Start()
+	t.Cleanup(server.Close)
+}
+
+func getThroughTransport(t *testing.T, cfg *config.Config) (string, error) {
+	client := &http.Client{Transport: BuildTransport(cfg, time.Second)}
+	resp, err := client.Get("http://collector.newrelic.com/v1/data")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body), nil
+}
+
+func TestBuildTransport_CollectorDialUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dial")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "nr-proxy.sock")
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets not supported: %v", err)
+	}
+	newDialTestServer(t, l)
+
+	host, err := getThroughTransport(t, &config.Config{CollectorDial: "unix://" + filepath.ToSlash(socket)})
+	require.NoError(t, err)
+	assert.Equal(t, "collector.newrelic.com", host)
+}
+
+func TestBuildTransport_CollectorDialTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	newDialTestServer(t, l)
+
+	host, err := getThroughTransport(t, &config.Config{CollectorDial: "tcp://" + l.Addr().String()})
+	require.NoError(t, err)
+	assert.Equal(t, "collector.newrelic.com", host)
+}
+
+func TestBuildTransport_CollectorDialThroughProxy(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	newDialTestServer(t, l)
+
+	// the proxy is connected to through the collector_dial address, so it receives the full URL
+	host, err := getThroughTransport(t, &config.Config{
+		CollectorDial: "tcp://" + l.Addr().String(),
+		Proxy:         "http://proxy.example.com:3128",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "collector.newrelic.com", host)
+}
+
+func TestBuildTransport_CollectorDialInvalid(t *testing.T) {
+	for _, address := range []string{"unix://", "tcp://127.0.0.1", "ftp://localhost:21", "%zz"} {
+		t.Run(address, func(t *testing.T) {
+			_, err := getThroughTransport(t, &config.Config{CollectorDial: address})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid collector_dial address")
+		})
+	}
+}
+
+func TestRegisterDialer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	newDialTestServer(t, l)
+
+	var requested string
+	RegisterDialer("test", func(u *url.URL, forward *net.Dialer) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			requested = u.Host + " " + addr
+			return forward.DialContext(ctx, network, l.Addr().String())
+		}, nil
+	})
+	defer func() {
+		dialersLock.Lock()
+		delete(dialers, "test")
+		dialersLock.Unlock()
+	}()
+
+	host, err := getThroughTransport(t, &config.Config{CollectorDial: "test://tunnel"})
+	require.NoError(t, err)
+	assert.Equal(t, "collector.newrelic.com", host)
+	assert.Equal(t, "tunnel collector.newrelic.com:80", requested)
+}
@@ -0,0 +1,230 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	dnsCachesLock sync.Mutex
+	dnsCaches     = map[time.Duration]*dnsCache{}
+)
+
+// DNSCacheReport represents the resolution of a New Relic endpoint host, as cached by the agent.
+type DNSCacheReport struct {
+	Host          string     `json:"host"`
+	Addresses     []string   `json:"addresses,omitempty"`
+	Hits          int        `json:"hits"`
+	Misses        int        `json:"misses"`
+	StaleHits     int        `json:"stale_hits"`
+	LastResolved  *time.Time `json:"last_resolved,omitempty"`
+	Expires       *time.Time `json:"expires,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+// dnsCache caches the addresses of the New Relic endpoints for the dns_cache_ttl_sec option. Expired addresses are
+// used while the resolver fails, so transient resolver outages don't interrupt the submissions.
+type dnsCache struct {
+	ttl    time.Duration
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+	now    func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]*dnsEntry
+}
+
+type dnsEntry struct {
+	addrs        []string
+	hits         int
+	misses       int
+	staleHits    int
+	resolved     time.Time
+	expires      time.Time
+	lastErr      error
+	lastErrTime  time.Time
+	staleLogTime time.Time
+}
+
+// sharedDNSCache returns the cache for the TTL, or nil when the TTL is not positive. It's shared by all the
+// transports, so the hosts are resolved once.
+func sharedDNSCache(ttl time.Duration) *dnsCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	dnsCachesLock.Lock()
+	defer dnsCachesLock.Unlock()
+
+	if c, ok := dnsCaches[ttl]; ok {
+		return c
+	}
+
+	plog.WithField("ttl", ttl).Info("Caching the New Relic endpoints resolution.")
+	c := newDNSCache(ttl, net.DefaultResolver.LookupIPAddr, time.Now)
+	dnsCaches[ttl] = c
+	return c
+}
+
+func newDNSCache(ttl time.Duration, lookup func(ctx context.Context, host string) ([]net.IPAddr, error), now func() time.Time) *dnsCache {
+	return &dnsCache{
+		ttl:     ttl,
+		lookup:  lookup,
+		now:     now,
+		entries: make(map[string]*dnsEntry),
+	}
+}
+
+// DNSCacheReports returns the resolutions cached by the agent, sorted by host. It's empty when the dns_cache_ttl_sec
+// option is not set.
+func DNSCacheReports() []DNSCacheReport {
+	dnsCachesLock.Lock()
+	defer dnsCachesLock.Unlock()
+
+	var reports []DNSCacheReport
+	for _, c := range dnsCaches {
+		reports = append(reports, c.reports()...)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Host < reports[j].Host
+	})
+	return reports
+}
+
+// EndpointHosts returns the hosts of the New Relic endpoints, lower cased.
+func EndpointHosts(cfg *config.Config) map[string]bool {
+	hosts := make(map[string]bool)
+	for _, endpoint := range []string{cfg.CollectorURL, cfg.IdentityURL, cfg.MetricURL, cfg.CommandChannelURL} {
+		if u, err := url.Parse(endpoint); err == nil && u.Hostname() != "" {
+			hosts[strings.ToLower(u.Hostname())] = true
+		}
+	}
+	return hosts
+}
+
+// dial wraps the dialer, connecting to the cached addresses of the hosts. Other addresses, ie: proxies, are
+// dialed as requested.
+func (c *dnsCache) dial(hosts map[string]bool, dial dialContextFunc) dialContextFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || !hosts[strings.ToLower(host)] {
+			return dial(ctx, network, addr)
+		}
+
+		addrs, err := c.resolve(ctx, strings.ToLower(host))
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range addrs {
+			if (network == "tcp4" && strings.Contains(ip, ":")) || (network == "tcp6" && !strings.Contains(ip, ":")) {
+				continue
+			}
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no %s addresses for %s", network, host)
+		}
+		return nil, lastErr
+	}
+}
+
+// resolve returns the addresses of the host, looking them up when they are not cached or expired. The expired
+// addresses are returned when the lookup fails.
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]string, error) {
+	c.lock.Lock()
+	e, ok := c.entries[host]
+	if ok && c.now().Before(e.expires) {
+		e.hits++
+		addrs := e.addrs
+		c.lock.Unlock()
+		return addrs, nil
+	}
+	c.lock.Unlock()
+
+	ipAddrs, err := c.lookup(ctx, host)
+	if err == nil && len(ipAddrs) == 0 {
+		err = fmt.Errorf("no addresses found for %s", host)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.now()
+	e, ok = c.entries[host]
+	if !ok {
+		e = &dnsEntry{}
+		c.entries[host] = e
+	}
+	e.misses++
+
+	if err == nil {
+		e.addrs = make([]string, 0, len(ipAddrs))
+		for _, ip := range ipAddrs {
+			e.addrs = append(e.addrs, ip.String())
+		}
+		e.resolved, e.expires = now, now.Add(c.ttl)
+		return e.addrs, nil
+	}
+
+	e.lastErr, e.lastErrTime = err, now
+	if len(e.addrs) == 0 {
+		return nil, err
+	}
+
+	e.staleHits++
+	// logged once per TTL, as every connection attempt fails the same
+	if now.Sub(e.staleLogTime) >= c.ttl {
+		e.staleLogTime = now
+		plog.WithError(err).WithFields(logrus.Fields{
+			"host":         host,
+			"lastResolved": e.resolved,
+		}).Warn("Cannot resolve host, using the expired addresses.")
+	}
+	return e.addrs, nil
+}
+
+func (c *dnsCache) reports() []DNSCacheReport {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	reports := make([]DNSCacheReport, 0, len(c.entries))
+	for host, e := range c.entries {
+		r := DNSCacheReport{
+			Host:      host,
+			Addresses: append([]string(nil), e.addrs...),
+			Hits:      e.hits,
+			Misses:    e.misses,
+			StaleHits: e.staleHits,
+		}
+		if !e.resolved.IsZero() {
+			resolved, expires := e.resolved, e.expires
+			r.LastResolved, r.Expires = &resolved, &expires
+		}
+		if e.lastErr != nil {
+			errTime := e.lastErrTime
+			r.LastError, r.LastErrorTime = e.lastErr.Error(), &errTime
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver struct {
+	addrs   []net.IPAddr
+	err     error
+	lookups int
+}
+
+func (r *fakeResolver) lookup(_ context.Context, _ string) ([]net.IPAddr, error) {
+	r.lookups++
+	return r.addrs, r.err
+}
+
+func TestDNSCache_Resolve(t *testing.T) {
+	now := time.Unix(0, 0)
+	resolver := &fakeResolver{addrs: []net.IPAddr{{IP: net.ParseIP("162.247.241.2")}}}
+	c := newDNSCache(time.Minute, resolver.lookup, func() time.Time { return now })
+
+	addrs, err := c.resolve(context.Background(), "infra-api.newrelic.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"162.247.241.2"}, addrs)
+
+	addrs, err = c.resolve(context.Background(), "infra-api.newrelic.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"162.247.241.2"}, addrs)
+	assert.Equal(t, 1, resolver.lookups, "cached")
+
+	// expired addresses are used while the resolver fails
+	now = now.Add(time.Minute)
+	resolver.err = errors.New("i/o timeout")
+	addrs, err = c.resolve(context.Background(), "infra-api.newrelic.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"162.247.241.2"}, addrs)
+	assert.Equal(t, 2, resolver.lookups)
+
+	resolver.err = nil
+	resolver.addrs = []net.IPAddr{{IP: net.ParseIP("162.247.241.3")}}
+	addrs, err = c.resolve(context.Background(), "infra-api.newrelic.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"162.247.241.3"}, addrs, "resolved again once the resolver recovers")
+
+	// hosts never resolved fail
+	resolver.err = errors.New("i/o timeout")
+	_, err = c.resolve(context.Background(), "identity-api.newrelic.com")
+	assert.Error(t, err)
+
+	reports := c.reports()
+	require.Len(t, reports, 2)
+	reportsByHost := map[string]DNSCacheReport{}
+	for _, r := range reports {
+		reportsByHost[r.Host] = r
+	}
+
+	infra := reportsByHost["infra-api.newrelic.com"]
+	assert.Equal(t, []string{"162.247.241.3"}, infra.Addresses)
+	assert.Equal(t, 1, infra.Hits)
+	assert.Equal(t, 3, infra.Misses)
+	assert.Equal(t, 1, infra.StaleHits)
+	require.NotNil(t, infra.LastResolved)
+	assert.Equal(t, now, *infra.LastResolved)
+	assert.Equal(t, now.Add(time.Minute), *infra.Expires)
+	assert.Equal(t, "i/o timeout", infra.LastError)
+
+	identity := reportsByHost["identity-api.newrelic.com"]
+	assert.Empty(t, identity.Addresses)
+	assert.Nil(t, identity.LastResolved)
+	assert.Equal(t, "i/o timeout", identity.LastError)
+}
+
+func TestDNSCache_Dial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	resolver := &fakeResolver{addrs: []net.IPAddr{{IP: net.ParseIP("127.0.0.2")}, {IP: net.ParseIP("127.0.0.1")}}}
+	c := newDNSCache(time.Minute, resolver.lookup, time.Now)
+
+	var dialed []string
+	dial := c.dial(map[string]bool{"infra-api.newrelic.com": true}, func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		if addr == net.JoinHostPort("127.0.0.2", u.Port()) {
+			return nil, errors.New("connection refused")
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	})
+	client := &http.Client{Transport: &http.Transport{DialContext: dial}}
+
+	resp, err := client.Get("http://infra-api.newrelic.com:" + u.Port() + "/metrics/events/bulk")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, []string{
+		net.JoinHostPort("127.0.0.2", u.Port()),
+		net.JoinHostPort("127.0.0.1", u.Port()),
+	}, dialed, "next address is tried when one fails")
+
+	// other hosts are dialed as requested
+	dialed = nil
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, []string{u.Host}, dialed)
+	assert.Equal(t, 1, resolver.lookups)
+}
+
+func TestSharedDNSCache(t *testing.T) {
+	assert.Nil(t, sharedDNSCache(0))
+
+	c := sharedDNSCache(time.Hour)
+	require.NotNil(t, c)
+	assert.Same(t, c, sharedDNSCache(time.Hour))
+}
+
+func TestEndpointHosts(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.CollectorURL = "https://Infra-API.newrelic.com"
+	cfg.IdentityURL = "https://identity-api.newrelic.com"
+	cfg.MetricURL = "https://metric-api.newrelic.com/metric/v1"
+	cfg.CommandChannelURL = ""
+
+	assert.Equal(t, map[string]bool{
+		"infra-api.newrelic.com":    true,
+		"identity-api.newrelic.com": true,
+		"metric-api.newrelic.com":   true,
+	}, EndpointHosts(cfg))
+}
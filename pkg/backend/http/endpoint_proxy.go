@@ -0,0 +1,127 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+// transportRoute sends the requests it matches through its own transport.
+type transportRoute struct {
+	name      string
+	matches   func(*url.URL) bool
+	transport http.RoundTripper
+}
+
+// routedTransport sends every request through the transport of the first route matching it, or the fallback
+// transport when none does.
+type routedTransport struct {
+	routes   []transportRoute
+	fallback http.RoundTripper
+}
+
+func (t *routedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, r := range t.routes {
+		if r.matches(req.URL) {
+			return r.transport.RoundTrip(req)
+		}
+	}
+	return t.fallback.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of every transport.
+func (t *routedTransport) CloseIdleConnections() {
+	type closeIdler interface {
+		CloseIdleConnections()
+	}
+	for _, r := range t.routes {
+		if c, ok := r.transport.(closeIdler); ok {
+			c.CloseIdleConnections()
+		}
+	}
+	if c, ok := t.fallback.(closeIdler); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// endpointRoutes returns the routes for the hosts excluded from the proxy and the endpoints with their own proxy.
+func endpointRoutes(cfg *config.Config, timeout time.Duration, dialer *collectorDialer, tlsOpts tlsOptions) []transportRoute {
+	var routes []transportRoute
+
+	if noProxy := ParseNoProxy(cfg.NoProxy); !noProxy.IsEmpty() {
+		routes = append(routes, transportRoute{
+			name:      "no_proxy",
+			matches:   noProxy.Matches,
+			transport: proxyTransport(cfg, timeout, dialer, tlsOpts, proxyConfig{}),
+		})
+	}
+
+	endpoints := []struct {
+		option   string
+		proxy    string
+		prefixes []string
+	}{
+		{
+			option: "metrics_proxy",
+			proxy:  cfg.MetricsProxy,
+			prefixes: []string{
+				joinURL(cfg.CollectorURL, cfg.MetricsIngestEndpoint),
+				cfg.MetricURL,
+			},
+		},
+		{
+			option:   "inventory_proxy",
+			proxy:    cfg.InventoryProxy,
+			prefixes: []string{joinURL(cfg.CollectorURL, cfg.InventoryIngestEndpoint)},
+		},
+		{
+			option:   "command_api_proxy",
+			proxy:    cfg.CommandAPIProxy,
+			prefixes: []string{joinURL(cfg.CommandChannelURL, cfg.CommandChannelEndpoint)},
+		},
+	}
+	for _, e := range endpoints {
+		if e.proxy == "" {
+			continue
+		}
+		p := proxyConfig{source: fmt.Sprintf("%s configuration option", e.option), raw: e.proxy}
+		if e.proxy == config.ProxyDirect {
+			p = proxyConfig{}
+		}
+		routes = append(routes, transportRoute{
+			name:      e.option,
+			matches:   urlPrefixMatcher(e.prefixes...),
+			transport: proxyTransport(cfg, timeout, dialer, tlsOpts, p),
+		})
+	}
+	return routes
+}
+
+// urlPrefixMatcher matches the URLs with the same host and a path starting with the path of any of the prefixes.
+func urlPrefixMatcher(prefixes ...string) func(*url.URL) bool {
+	var parsed []*url.URL
+	for _, prefix := range prefixes {
+		if u, err := url.Parse(prefix); err == nil && u.Host != "" {
+			parsed = append(parsed, u)
+		}
+	}
+
+	return func(u *url.URL) bool {
+		for _, prefix := range parsed {
+			if strings.EqualFold(u.Host, prefix.Host) && strings.HasPrefix(u.Path, strings.TrimSuffix(prefix.Path, "/")) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func joinURL(base, path string) string {
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
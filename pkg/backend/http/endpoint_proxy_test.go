@@ -0,0 +1,85 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namedTransport returns its name as the response status.
+type namedTransport string
+
+func (n namedTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return &http.Response{Status: string(n)}, nil
+}
+
+func TestRoutedTransport_RoundTrip(t *testing.T) {
+	transport := &routedTransport{
+		routes: []transportRoute{
+			{name: "no_proxy", matches: ParseNoProxy("10.0.0.0/8").Matches, transport: namedTransport("direct")},
+			{name: "metrics_proxy", matches: urlPrefixMatcher("https://metric-api.newrelic.com/metric/v1", "https://infra-api.newrelic.com/infra/v2/metrics"), transport: namedTransport("metrics")},
+			{name: "inventory_proxy", matches: urlPrefixMatcher("https://infra-api.newrelic.com/inventory"), transport: namedTransport("inventory")},
+		},
+		fallback: namedTransport("proxy"),
+	}
+
+	tests := map[string]string{
+		"https://infra-api.newrelic.com/infra/v2/metrics/events/bulk": "metrics",
+		"https://metric-api.newrelic.com/metric/v1":                   "metrics",
+		"https://infra-api.newrelic.com/inventory/deltas":             "inventory",
+		"https://infra-api.newrelic.com/identity/v1/connect":          "proxy",
+		"https://other.newrelic.com/infra/v2/metrics":                 "proxy",
+		"https://10.1.2.3/inventory":                                  "direct",
+	}
+	for rawURL, want := range tests {
+		t.Run(rawURL, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, rawURL, nil)
+			require.NoError(t, err)
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+			assert.Equal(t, want, resp.Status)
+		})
+	}
+}
+
+func TestEndpointRoutes(t *testing.T) {
+	cfg := &config.Config{
+		CollectorURL:            "https://infra-api.newrelic.com",
+		MetricsIngestEndpoint:   "/infra/v2/metrics",
+		InventoryIngestEndpoint: "/inventory",
+		CommandChannelURL:       "https://infrastructure-command-api.newrelic.com",
+		CommandChannelEndpoint:  "/agent_commands/v1/commands",
+		MetricURL:               "https://metric-api.newrelic.com/metric/v1",
+		Proxy:                   "http://proxy:3128",
+		MetricsProxy:            "http://metrics-proxy:3128",
+		InventoryProxy:          config.ProxyDirect,
+		NoProxy:                 "10.0.0.0/8",
+	}
+
+	routes := endpointRoutes(cfg, time.Second, newCollectorDialer("", time.Second), tlsOptions{})
+
+	var names []string
+	for _, r := range routes {
+		names = append(names, r.name)
+	}
+	assert.Equal(t, []string{"no_proxy", "metrics_proxy", "inventory_proxy"}, names)
+
+	metricsURL, err := url.Parse("https://infra-api.newrelic.com/infra/v2/metrics/events/bulk")
+	require.NoError(t, err)
+	metricsProxy, err := routes[1].transport.(*http.Transport).Proxy(&http.Request{URL: metricsURL})
+	require.NoError(t, err)
+	assert.Equal(t, "metrics-proxy:3128", metricsProxy.Host)
+
+	assert.Nil(t, routes[2].transport.(*http.Transport).Proxy)
+}
+
+func TestEndpointRoutes_NoEndpointProxies(t *testing.T) {
+	assert.Empty(t, endpointRoutes(&config.Config{Proxy: "http://proxy:3128"}, time.Second, newCollectorDialer("", time.Second), tlsOptions{}))
+}
@@ -29,9 +29,25 @@ func GetHttpClient(
 	}
 }
 
+// getCertPool loads the system root certificates plus the ones in the CA bundle file and directory, exiting when
+// they cannot be read.
 func getCertPool(certFile string, certDirectory string) *x509.CertPool {
+	caCertPool, err := loadCertPool(certFile, certDirectory)
+	if err != nil {
+		plog.WithFields(logrus.Fields{
+			"action":    "getCertPool",
+			"file":      certFile,
+			"directory": certDirectory,
+		}).WithError(err).Error("can't load CA bundle")
+		os.Exit(1)
+	}
+	return caCertPool
+}
+
+// loadCertPool loads the system root certificates plus the ones in the CA bundle file and directory.
+func loadCertPool(certFile string, certDirectory string) (*x509.CertPool, error) {
 	hlog := plog.WithFields(logrus.Fields{
-		"action":    "getCertPool",
+		"action":    "loadCertPool",
 		"file":      certFile,
 		"directory": certDirectory,
 	})
@@ -41,8 +57,7 @@ func getCertPool(certFile string, certDirectory string) *x509.CertPool {
 	if certFile != "" {
 		caCert, err := ioutil.ReadFile(certFile)
 		if err != nil {
-			hlog.WithError(err).Error("can't read certificate file")
-			os.Exit(1)
+			return nil, fmt.Errorf("can't read certificate file: %w", err)
 		}
 
 		ok := caCertPool.AppendCertsFromPEM(caCert)
@@ -53,8 +68,7 @@ func getCertPool(certFile string, certDirectory string) *x509.CertPool {
 	if certDirectory != "" {
 		files, err := ioutil.ReadDir(certDirectory)
 		if err != nil {
-			log.WithError(err).Error("can't read certificate directory")
-			os.Exit(1)
+			return nil, fmt.Errorf("can't read certificate directory: %w", err)
 		}
 
 		for _, f := range files {
@@ -62,8 +76,7 @@ func getCertPool(certFile string, certDirectory string) *x509.CertPool {
 				caCertFilePath := filepath.Join(certDirectory, f.Name())
 				caCert, err := ioutil.ReadFile(caCertFilePath)
 				if err != nil {
-					log.WithField("file", f.Name()).WithError(err).Error("can't read certificate file")
-					os.Exit(1)
+					return nil, fmt.Errorf("can't read certificate file %s: %w", f.Name(), err)
 				}
 				ok := caCertPool.AppendCertsFromPEM(caCert)
 				if !ok {
@@ -72,7 +85,7 @@ func getCertPool(certFile string, certDirectory string) *x509.CertPool {
 			}
 		}
 	}
-	return caCertPool
+	return caCertPool, nil
 }
 
 // Client sends a request and returns a response or error.
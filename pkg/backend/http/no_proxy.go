@@ -0,0 +1,105 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// NoProxy is a list of hosts which are connected to without proxy.
+type NoProxy struct {
+	all     bool
+	cidrs   []*net.IPNet
+	entries []noProxyEntry
+}
+
+// noProxyEntry is a host name, domain or IP address, optionally restricted to a port.
+type noProxyEntry struct {
+	host string
+	// domain entries match the host and its subdomains
+	domain bool
+	port   string
+}
+
+// ParseNoProxy parses a comma separated list of host names, domains (ie: .example.com, matching the subdomains),
+// IP addresses and CIDR blocks. Host names, domains and IP addresses can be followed by a port. "*" matches any host.
+func ParseNoProxy(list string) NoProxy {
+	var n NoProxy
+	for _, raw := range strings.Split(list, ",") {
+		raw = strings.ToLower(strings.TrimSpace(raw))
+		if raw == "" {
+			continue
+		}
+		if raw == "*" {
+			n.all = true
+			continue
+		}
+		if _, cidr, err := net.ParseCIDR(raw); err == nil {
+			n.cidrs = append(n.cidrs, cidr)
+			continue
+		}
+
+		e := noProxyEntry{host: raw}
+		if host, port, err := net.SplitHostPort(raw); err == nil {
+			e.host, e.port = host, port
+		}
+		// [::1] without port
+		e.host = strings.TrimSuffix(strings.TrimPrefix(e.host, "["), "]")
+		if strings.HasPrefix(e.host, "*.") {
+			e.host = e.host[1:]
+		}
+		if strings.HasPrefix(e.host, ".") {
+			e.host = e.host[1:]
+			e.domain = true
+		} else if net.ParseIP(e.host) == nil {
+			// like curl, host names match their subdomains too
+			e.domain = true
+		}
+		n.entries = append(n.entries, e)
+	}
+	return n
+}
+
+// IsEmpty returns true when no host is excluded from the proxy.
+func (n NoProxy) IsEmpty() bool {
+	return !n.all && len(n.cidrs) == 0 && len(n.entries) == 0
+}
+
+// Matches returns true when the URL must be connected to without proxy.
+func (n NoProxy) Matches(u *url.URL) bool {
+	if n.all {
+		return true
+	}
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
+		}
+	}
+
+	ip := net.ParseIP(host)
+	if ip != nil {
+		for _, cidr := range n.cidrs {
+			if cidr.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	for _, e := range n.entries {
+		if e.port != "" && e.port != port {
+			continue
+		}
+		// IP addresses only match the same address
+		if host == e.host || (ip == nil && e.domain && strings.HasSuffix(host, "."+e.host)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoProxy_Matches(t *testing.T) {
+	tests := map[string]struct {
+		list string
+		url  string
+		want bool
+	}{
+		"empty list":                {"", "https://collector.newrelic.com", false},
+		"any host":                  {"*", "https://collector.newrelic.com", true},
+		"host":                      {"collector.newrelic.com", "https://collector.newrelic.com/infra/v2", true},
+		"host case insensitive":     {"Collector.NewRelic.com", "https://collector.newrelic.com", true},
+		"host matches subdomains":   {"newrelic.com", "https://collector.newrelic.com", true},
+		"host not matching suffix":  {"relic.com", "https://collector.newrelic.com", false},
+		"domain":                    {".newrelic.com", "https://collector.newrelic.com", true},
+		"wildcard domain":           {"*.newrelic.com", "https://collector.newrelic.com", true},
+		"other host":                {"example.com", "https://collector.newrelic.com", false},
+		"host and port":             {"collector.newrelic.com:443", "https://collector.newrelic.com", true},
+		"host and other port":       {"collector.newrelic.com:8443", "https://collector.newrelic.com", false},
+		"http default port":         {"collector.newrelic.com:80", "http://collector.newrelic.com", true},
+		"ip":                        {"10.0.0.1", "https://10.0.0.1:8443", true},
+		"ip not matching suffix":    {"0.0.1", "https://10.0.0.1", false},
+		"cidr":                      {"10.0.0.0/8", "https://10.20.30.40", true},
+		"cidr other network":        {"10.0.0.0/8", "https://192.168.1.1", false},
+		"cidr doesn't match names":  {"10.0.0.0/8", "https://collector.newrelic.com", false},
+		"ipv6":                      {"[::1]", "https://[::1]:8443", true},
+		"ipv6 cidr":                 {"fd00::/8", "https://[fd00::1]", true},
+		"list with spaces":          {"example.com, 10.0.0.0/8 ,.newrelic.com", "https://collector.newrelic.com", true},
+		"list without matching one": {"example.com,10.0.0.0/8", "https://collector.newrelic.com", false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			u, err := url.Parse(tc.url)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, ParseNoProxy(tc.list).Matches(u))
+		})
+	}
+}
+
+func TestNoProxy_IsEmpty(t *testing.T) {
+	assert.True(t, ParseNoProxy("").IsEmpty())
+	assert.True(t, ParseNoProxy(" , ").IsEmpty())
+	assert.False(t, ParseNoProxy("*").IsEmpty())
+	assert.False(t, ParseNoProxy("10.0.0.0/8").IsEmpty())
+	assert.False(t, ParseNoProxy("example.com").IsEmpty())
+}
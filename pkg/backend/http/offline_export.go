@@ -0,0 +1,117 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/offline"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+var (
+	exportArchivesLock sync.Mutex
+	exportArchives     = map[string]*offline.Archive{}
+)
+
+// sharedExportArchive returns the archive for the offline_export_dir option, which is shared by all the transports
+// so they write into the same files.
+func sharedExportArchive(cfg *config.Config) (*offline.Archive, error) {
+	exportArchivesLock.Lock()
+	defer exportArchivesLock.Unlock()
+
+	if a, ok := exportArchives[cfg.OfflineExportDir]; ok {
+		return a, nil
+	}
+
+	a, err := offline.NewArchive(offline.ArchiveConfig{
+		Dir:            cfg.OfflineExportDir,
+		MaxFileSize:    int64(cfg.OfflineExportMaxFileSize),
+		RotateInterval: time.Duration(cfg.OfflineExportRotateSec) * time.Second,
+		MaxFiles:       cfg.OfflineExportMaxFiles,
+		SigningKeyFile: cfg.OfflineExportSigningKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	exportArchives[cfg.OfflineExportDir] = a
+	return a, nil
+}
+
+// exportTransport archives the requests instead of sending them, for hosts without connectivity to New Relic. The
+// archive is uploaded from a connected host with the "newrelic-infra-ctl replay" command.
+type exportTransport struct {
+	archive *offline.Archive
+	hosts   map[string]bool // New Relic endpoints, the only ones archived
+	err     error           // invalid archive configuration, failing the requests
+}
+
+// newExportTransport returns the transport archiving the requests into the offline_export_dir. An invalid archive is
+// logged, and the requests fail with its error.
+func newExportTransport(cfg *config.Config) *exportTransport {
+	archive, err := sharedExportArchive(cfg)
+	if err != nil {
+		err = fmt.Errorf("invalid offline_export_dir %q: %w", cfg.OfflineExportDir, err)
+		plog.WithError(err).Error("Cannot export data.")
+		return &exportTransport{err: err}
+	}
+
+	plog.WithField("directory", cfg.OfflineExportDir).Info("Exporting data into the offline archive instead of sending it.")
+	return &exportTransport{archive: archive, hosts: EndpointHosts(cfg)}
+}
+
+// RoundTrip archives the requests with a body, replying them as accepted with an empty payload. Requests without a
+// body, ie: connectivity checks or command polls, are not archived and get an empty response. Requests to other
+// hosts than the New Relic endpoints fail, as they're not replayed.
+func (t *exportTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if !t.hosts[strings.ToLower(req.URL.Hostname())] {
+		return nil, fmt.Errorf("cannot archive request to %s, only the New Relic endpoints are archived", req.URL.Host)
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return exportResponse(req, http.StatusOK, "{}"), nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// the license key is set by the replay command, so it's not stored in the archive
+	header := req.Header.Clone()
+	header.Del(LicenseHeader)
+
+	err = t.archive.Write(offline.Record{
+		Time:   time.Now(),
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: header,
+		Body:   body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot archive request: %w", err)
+	}
+	return exportResponse(req, http.StatusAccepted, `{"payload":{}}`), nil
+}
+
+func exportResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/backend/offline"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportTransport_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewConfig()
+	cfg.OfflineExportDir = dir
+	cfg.CollectorURL = "https://infra-api.newrelic.com"
+	transport := BuildTransport(cfg, 0)
+	require.IsType(t, &exportTransport{}, transport)
+	client := &http.Client{Transport: transport}
+
+	req, err := http.NewRequest(http.MethodPost, "https://infra-api.newrelic.com/metrics/events/bulk", bytes.NewBufferString(`[{"a":1}]`))
+	require.NoError(t, err)
+	req.Header.Set(LicenseHeader, "license")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.JSONEq(t, `{"payload":{}}`, string(body))
+
+	// requests without a body are not archived
+	resp, err = client.Get("https://infra-api.newrelic.com/agent_commands/v1/commands")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	archive, err := sharedExportArchive(cfg)
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+	files, err := offline.Files(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	records, err := offline.Read(files[0], nil)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, http.MethodPost, records[0].Method)
+	assert.Equal(t, "https://infra-api.newrelic.com/metrics/events/bulk", records[0].URL)
+	assert.Equal(t, `[{"a":1}]`, string(records[0].Body))
+	assert.Equal(t, "application/json", records[0].Header.Get("Content-Type"))
+	assert.Empty(t, records[0].Header.Get(LicenseHeader), "the license key is not archived")
+}
+
+func TestExportTransport_OtherHosts(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.OfflineExportDir = t.TempDir()
+	cfg.CollectorURL = "https://infra-api.newrelic.com"
+	client := &http.Client{Transport: BuildTransport(cfg, 0)}
+
+	_, err := client.Post("https://otlp.example.com/v1/metrics", "application/json", bytes.NewBufferString("{}"))
+	assert.ErrorContains(t, err, "only the New Relic endpoints are archived")
+}
+
+func TestExportTransport_InvalidDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	cfg := config.NewConfig()
+	cfg.OfflineExportDir = file
+	client := &http.Client{Transport: BuildTransport(cfg, 0)}
+
+	_, err := client.Post("https://infra-api.newrelic.com/metrics/events/bulk", "application/json", bytes.NewBufferString("{}"))
+	assert.ErrorContains(t, err, "invalid offline_export_dir")
+}
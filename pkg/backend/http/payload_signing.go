@@ -0,0 +1,142 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of the request, with the format t=<unix seconds>,v1=<hex>.
+	SignatureHeader = "X-NRI-Signature"
+	// ContentSHA256Header carries the hex SHA-256 hash of the request body, as sent.
+	ContentSHA256Header = "X-NRI-Content-SHA256"
+
+	minSigningKeySize = 32
+)
+
+// payloadSigner signs the requests with the key of the payload_signing_key_file option, so a gateway of the
+// customer verifies that the data leaving the host is sent by the agent and not modified.
+type payloadSigner struct {
+	key []byte
+	now func() time.Time
+}
+
+// newPayloadSigner loads the signing key from the file. The key is the file content, without its trailing line
+// break, ie: generated by openssl rand -hex 32
+func newPayloadSigner(file string) (*payloadSigner, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	key := bytes.TrimRight(content, "\r\n")
+	if len(key) < minSigningKeySize {
+		return nil, fmt.Errorf("the key must be at least %d bytes long", minSigningKeySize)
+	}
+	return &payloadSigner{key: key, now: time.Now}, nil
+}
+
+// sign sets the signature headers of the request, signing the timestamp, method, request URI and body hash
+// separated by line breaks.
+func (s *payloadSigner) sign(req *http.Request, bodyHash string) {
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strings.Join([]string{timestamp, req.Method, req.URL.RequestURI(), bodyHash}, "\n")))
+
+	req.Header.Set(ContentSHA256Header, bodyHash)
+	req.Header.Set(SignatureHeader, "t="+timestamp+",v1="+hex.EncodeToString(mac.Sum(nil)))
+}
+
+// auditTransport signs the requests and records them in the audit log, for the payload_signing_key_file and
+// audit_log_file options.
+type auditTransport struct {
+	next   http.RoundTripper
+	signer *payloadSigner
+	audit  *auditLog
+	err    error // invalid configuration, failing the requests
+}
+
+// newAuditTransport wraps the transport when the payloads are signed or audited, returning it as is otherwise. An
+// invalid configuration is logged, and the requests fail with its error so no data is sent without being signed
+// and audited.
+func newAuditTransport(cfg *config.Config, next http.RoundTripper) http.RoundTripper {
+	if cfg.PayloadSigningKeyFile == "" && cfg.AuditLogFile == "" {
+		return next
+	}
+
+	t := &auditTransport{next: next}
+	if cfg.PayloadSigningKeyFile != "" {
+		t.signer, t.err = newPayloadSigner(cfg.PayloadSigningKeyFile)
+		if t.err != nil {
+			t.err = fmt.Errorf("invalid payload_signing_key_file %q: %w", cfg.PayloadSigningKeyFile, t.err)
+			plog.WithError(t.err).Error("Cannot send data to New Relic.")
+			return t
+		}
+	}
+	if cfg.AuditLogFile != "" {
+		t.audit, t.err = sharedAuditLog(cfg.AuditLogFile)
+		if t.err != nil {
+			t.err = fmt.Errorf("invalid audit_log_file %q: %w", cfg.AuditLogFile, t.err)
+			plog.WithError(t.err).Error("Cannot send data to New Relic.")
+		}
+	}
+	return t
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	hash := sha256.Sum256(body)
+	bodyHash := hex.EncodeToString(hash[:])
+
+	// the request is not modified, as required by http.RoundTripper
+	out := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+		out.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	if t.signer != nil {
+		t.signer.sign(out, bodyHash)
+	}
+
+	resp, err := t.next.RoundTrip(out)
+
+	if t.audit != nil {
+		entry := newAuditEntry(out, len(body), bodyHash, t.signer != nil)
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.Status = resp.StatusCode
+		}
+		if auditErr := t.audit.write(entry); auditErr != nil {
+			plog.WithError(auditErr).Error("Cannot write the audit log.")
+		}
+	}
+	return resp, err
+}
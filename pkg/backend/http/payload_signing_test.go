@@ -0,0 +1,77 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+func TestAuditTransport_Sign(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "signing.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(testSigningKey+"\n"), 0o600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		hash := sha256.Sum256(body)
+		assert.Equal(t, hex.EncodeToString(hash[:]), r.Header.Get(ContentSHA256Header))
+
+		signature := strings.Split(r.Header.Get(SignatureHeader), ",")
+		require.Len(t, signature, 2)
+		timestamp := strings.TrimPrefix(signature[0], "t=")
+		mac := hmac.New(sha256.New, []byte(testSigningKey))
+		mac.Write([]byte(timestamp + "\n" + r.Method + "\n" + r.URL.RequestURI() + "\n" + r.Header.Get(ContentSHA256Header)))
+		assert.Equal(t, "v1="+hex.EncodeToString(mac.Sum(nil)), signature[1])
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig()
+	cfg.PayloadSigningKeyFile = keyFile
+	client := &http.Client{Transport: newAuditTransport(cfg, http.DefaultTransport)}
+
+	resp, err := client.Post(server.URL+"/metrics/events/bulk?a=b", "application/json", bytes.NewBufferString(`[{"a":1}]`))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp, err = client.Get(server.URL + "/agent_commands/v1/commands")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+func TestAuditTransport_InvalidKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "signing.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("short\n"), 0o600))
+
+	cfg := config.NewConfig()
+	cfg.PayloadSigningKeyFile = keyFile
+	client := &http.Client{Transport: newAuditTransport(cfg, http.DefaultTransport)}
+
+	_, err := client.Post("https://infra-api.newrelic.com/metrics/events/bulk", "application/json", bytes.NewBufferString("{}"))
+	assert.ErrorContains(t, err, "invalid payload_signing_key_file")
+
+	cfg.PayloadSigningKeyFile = filepath.Join(t.TempDir(), "missing.key")
+	client = &http.Client{Transport: newAuditTransport(cfg, http.DefaultTransport)}
+	_, err = client.Post("https://infra-api.newrelic.com/metrics/events/bulk", "application/json", bytes.NewBufferString("{}"))
+	assert.ErrorContains(t, err, "invalid payload_signing_key_file")
+}
+
+func TestNewAuditTransport_Disabled(t *testing.T) {
+	assert.Same(t, http.DefaultTransport, newAuditTransport(config.NewConfig(), http.DefaultTransport))
+}
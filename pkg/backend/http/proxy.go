@@ -3,6 +3,7 @@
 package http
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
@@ -39,25 +40,47 @@ func proxyWithError(err error) proxyFunc {
 	}
 }
 
+// tlsOptions configures the TLS connections of the transports.
+type tlsOptions struct {
+	roots      *x509.CertPool // nil for the system root certificates
+	clientCert *clientCertificate
+	pins       *pinVerifier
+}
+
+// config returns the TLS configuration, nil for the default one.
+func (o tlsOptions) config() *tls.Config {
+	if o.roots == nil && o.clientCert == nil && o.pins == nil {
+		return nil
+	}
+
+	cfg := &tls.Config{RootCAs: o.roots}
+	if o.clientCert != nil {
+		// presented to both HTTPS proxies and New Relic when requested
+		cfg.GetClientCertificate = o.clientCert.GetClientCertificate
+	}
+	if o.pins != nil {
+		cfg.VerifyConnection = o.pins.VerifyConnection
+	}
+	return cfg
+}
+
 func defaultHttpTransport(
-	certFile string,
-	certDirectory string,
 	httpTimeout time.Duration,
+	dial dialContextFunc,
+	forceHTTP1 bool,
+	tlsOpts tlsOptions,
 	p proxyFunc,
 ) *http.Transport {
-	var cfg *tls.Config
-	if certFile != "" || certDirectory != "" {
-		cfg = &tls.Config{RootCAs: getCertPool(certFile, certDirectory)}
-	}
 	// go default Http Transport
 	return &http.Transport{
 		Proxy:                 p,
-		DialContext:           (&net.Dialer{Timeout: httpTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		DialContext:           dial,
 		MaxIdleConns:          100,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   httpTimeout,
 		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig:       cfg,
+		TLSClientConfig:       tlsOpts.config(),
+		ForceAttemptHTTP2:     !forceHTTP1,
 	}
 }
 
@@ -126,14 +149,125 @@ func proxyByPriority(cfg *config.Config) proxyConfig {
 // If the configuration option ignore_system_proxy is set, it ignores the HTTPS_PROXY and HTTP_PROXY configuration
 // If the configuration option proxy_validate_certificates is set, it will force the HTTPS proxy options to verify the
 // certificates
+//
+// The metrics_proxy, inventory_proxy and command_api_proxy options override the proxy for their endpoints, and the
+// hosts in no_proxy are connected to directly.
+//
+// If the configuration option collector_dial is set, the connections are established through its address.
+//
+// The CA bundle is reloaded when its files change, and the New Relic endpoints are verified against the
+// collector_pins option when it's set.
+//
+// If the configuration option dns_cache_ttl_sec is set, the New Relic endpoints connected to directly are resolved
+// through the DNS cache.
+//
+// If the configuration option upload_rate_limit is set, the bytes sent through all the transports are limited to it.
+//
+// If the configuration option offline_export_dir is set, the requests are archived into it instead of being sent.
+//
+// The requests are signed with the payload_signing_key_file option and recorded into the audit_log_file option when
+// they are set.
 func BuildTransport(cfg *config.Config, timeout time.Duration) http.RoundTripper {
-	proxyConfig := proxyByPriority(cfg)
+	return newAuditTransport(cfg, backendTransport(cfg, timeout, buildTransport))
+}
+
+// BuildProxyTransport creates an http.Transport as BuildTransport does, but connecting through the given proxy to
+// every endpoint, or directly when it's "direct". An empty proxy keeps the proxy configuration.
+func BuildProxyTransport(cfg *config.Config, timeout time.Duration, proxy string) http.RoundTripper {
+	if proxy == "" {
+		return BuildTransport(cfg, timeout)
+	}
+	p := proxyConfig{source: "submission route proxy", raw: proxy}
+	if proxy == config.ProxyDirect {
+		p = proxyConfig{}
+	}
+	return newAuditTransport(cfg, backendTransport(cfg, timeout, func(cfg *config.Config, timeout time.Duration, dialer *collectorDialer, tlsOpts tlsOptions) http.RoundTripper {
+		return proxyTransport(cfg, timeout, dialer, tlsOpts, p)
+	}))
+}
+
+// BuildExternalTransport creates an http.Transport for endpoints other than New Relic, ie: OTLP collectors. It only
+// applies the proxy settings (proxy, ignore_system_proxy, proxy_validate_certificates and no_proxy) and the CA
+// bundle, so the options about the connections to New Relic, as collector_dial, collector_pins, upload_rate_limit,
+// offline_export_dir, payload signing and audit log, don't apply to them.
+func BuildExternalTransport(cfg *config.Config, timeout time.Duration) http.RoundTripper {
+	dialer := newCollectorDialer("", timeout)
+	build := func(tlsOpts tlsOptions) http.RoundTripper {
+		t := proxyTransport(cfg, timeout, dialer, tlsOpts, proxyByPriority(cfg))
+		noProxy := ParseNoProxy(cfg.NoProxy)
+		if noProxy.IsEmpty() {
+			return t
+		}
+		return &routedTransport{
+			routes: []transportRoute{{
+				name:      "no_proxy",
+				matches:   noProxy.Matches,
+				transport: proxyTransport(cfg, timeout, dialer, tlsOpts, proxyConfig{}),
+			}},
+			fallback: t,
+		}
+	}
+	if cfg.CABundleFile == "" && cfg.CABundleDir == "" {
+		return build(tlsOptions{})
+	}
+
+	return newCABundleTransport(sharedCABundle(cfg.CABundleFile, cfg.CABundleDir), func(roots *x509.CertPool) http.RoundTripper {
+		return build(tlsOptions{roots: roots})
+	})
+}
+
+// transportBuilder creates the transport connecting to the endpoints, through the proxies.
+type transportBuilder func(cfg *config.Config, timeout time.Duration, dialer *collectorDialer, tlsOpts tlsOptions) http.RoundTripper
+
+// backendTransport creates the transport sending the requests to New Relic, or archiving them in offline mode.
+func backendTransport(cfg *config.Config, timeout time.Duration, build transportBuilder) http.RoundTripper {
+	if cfg.OfflineExportDir != "" {
+		return newExportTransport(cfg)
+	}
+
+	clientCert, err := newClientCertificate(cfg.ClientCertificate, cfg.ClientKey)
+	if err != nil {
+		plog.WithError(err).Error("Cannot load client certificate, connecting without it until it can be loaded.")
+	}
+
+	tlsOpts := tlsOptions{clientCert: clientCert, pins: newPinVerifier(cfg)}
+	dialer := newCollectorDialer(cfg.CollectorDial, timeout)
+	if cache := sharedDNSCache(time.Duration(cfg.DNSCacheTTLSec) * time.Second); cache != nil && cfg.CollectorDial == "" {
+		dialer.dial = cache.dial(EndpointHosts(cfg), dialer.dial)
+	}
+	if limiter := sharedUploadLimiter(cfg.UploadRateLimit, cfg.UploadRateBurst); limiter != nil {
+		dialer.dial = limiter.dial(dialer.dial)
+	}
+	if cfg.CABundleFile == "" && cfg.CABundleDir == "" {
+		return build(cfg, timeout, dialer, tlsOpts)
+	}
+
+	return newCABundleTransport(sharedCABundle(cfg.CABundleFile, cfg.CABundleDir), func(roots *x509.CertPool) http.RoundTripper {
+		opts := tlsOpts
+		opts.roots = roots
+		return build(cfg, timeout, dialer, opts)
+	})
+}
+
+// buildTransport creates the transport for the proxy configuration, routing the endpoints with their own proxy.
+func buildTransport(cfg *config.Config, timeout time.Duration, dialer *collectorDialer, tlsOpts tlsOptions) http.RoundTripper {
+	t := proxyTransport(cfg, timeout, dialer, tlsOpts, proxyByPriority(cfg))
+
+	routes := endpointRoutes(cfg, timeout, dialer, tlsOpts)
+	if len(routes) == 0 {
+		return t
+	}
+	return &routedTransport{routes: routes, fallback: t}
+}
 
+// proxyTransport creates an http.Transport connecting through the proxy, or directly if it's empty.
+func proxyTransport(cfg *config.Config, timeout time.Duration, dialer *collectorDialer, tlsOpts tlsOptions, proxyConfig proxyConfig) *http.Transport {
 	if proxyConfig.isEmpty() {
 		return defaultHttpTransport(
-			cfg.CABundleFile,
-			cfg.CABundleDir,
 			timeout,
+			dialer.DialContext,
+			cfg.ForceHTTP1,
+			tlsOpts,
 			nil, // no proxy configuration
 		)
 	}
@@ -150,9 +284,10 @@ func BuildTransport(cfg *config.Config, timeout time.Duration) http.RoundTripper
 		err = fmt.Errorf("invalid proxy address %q: %v", proxyConfig.raw, err)
 		logrus.WithError(err).Error()
 		return defaultHttpTransport(
-			cfg.CABundleFile,
-			cfg.CABundleDir,
 			timeout,
+			dialer.DialContext,
+			true,
+			tlsOpts,
 			proxyWithError(err))
 	}
 
@@ -160,16 +295,23 @@ func BuildTransport(cfg *config.Config, timeout time.Duration) http.RoundTripper
 		err = fmt.Errorf("schema from %s must be %q", proxyConfig.source, proxyConfig.forceSchema)
 		logrus.WithError(err).Error()
 		return defaultHttpTransport(
-			cfg.CABundleFile,
-			cfg.CABundleDir,
 			timeout,
+			dialer.DialContext,
+			true,
+			tlsOpts,
 			proxyWithError(err))
 	}
 
+	if isSocksScheme(u.Scheme) {
+		return socksTransport(cfg, timeout, dialer, tlsOpts, u)
+	}
+
+	// proxied connections keep using HTTP/1.1, as the legacy proxy dialers below don't negotiate HTTP/2
 	t := defaultHttpTransport(
-		cfg.CABundleFile,
-		cfg.CABundleDir,
 		timeout,
+		dialer.DialContext,
+		true,
+		tlsOpts,
 		proxy(u),
 	)
 
@@ -189,8 +331,36 @@ func BuildTransport(cfg *config.Config, timeout time.Duration) http.RoundTripper
 	return t
 }
 
+// socksTransport creates an http.Transport tunneling the connections through the SOCKS5 proxy.
+func socksTransport(cfg *config.Config, timeout time.Duration, dialer *collectorDialer, tlsOpts tlsOptions, u *url.URL) *http.Transport {
+	dial, err := socksDialer(u, dialer)
+	if err != nil {
+		logrus.WithError(err).Error()
+		return defaultHttpTransport(
+			timeout,
+			dialer.DialContext,
+			true,
+			tlsOpts,
+			proxyWithError(err))
+	}
+
+	if cfg.ProxyValidateCerts {
+		plog.WithField("scheme", u.Scheme).
+			Warn("proxy_validate_certificates option is set to 'true', but it only applies to HTTPS proxies")
+	}
+
+	// the TLS handshake goes through the tunnel, so HTTP/2 is negotiated as in direct connections
+	return defaultHttpTransport(
+		timeout,
+		dial,
+		cfg.ForceHTTP1,
+		tlsOpts,
+		nil, // the proxy is handled by the dialer
+	)
+}
+
 func hasValidScheme(s string) bool {
-	return s == "http" || s == "https" || s == "socks5"
+	return s == "http" || s == "https" || isSocksScheme(s)
 }
 
 // Dial verifier implements the transport.Dialer interface to provide backwards compatibility with Go 1.9 proxy
@@ -235,6 +405,12 @@ func fallbackDialer(transport *http.Transport) func(network string, addr string)
 				transport.TLSClientConfig = &tls.Config{}
 			}
 			transport.TLSClientConfig.InsecureSkipVerify = true
+			// no chains are verified when skipping the verification, so there's nothing to pin
+			if transport.TLSClientConfig.VerifyConnection != nil {
+				plog.Warn("Skipping the verification of the collector_pins option." +
+					" Set proxy_validate_certificates to true to verify it.")
+				transport.TLSClientConfig.VerifyConnection = nil
+			}
 
 			// we will use tlsDialer directly from now on, with the insecure skip configuration
 			transport.DialTLS = tlsDialer(transport)
@@ -243,7 +419,7 @@ func fallbackDialer(transport *http.Transport) func(network string, addr string)
 			plog.WithError(err).Debug("Usual, secured configuration did not work as expected. Retrying with HTTP dialing.")
 			// if the problem was due to a non-https connection, we use a non-tls dialer directly
 			// from now on
-			transport.DialTLS = nonTLSDialer
+			transport.DialTLS = nonTLSDialer(transport)
 			return transport.DialTLS(network, addr)
 		default:
 			return conn, err
@@ -269,7 +445,7 @@ func fullTLSToHTTPConnectFallbackDialer(t *http.Transport) func(network string,
 		switch err.(type) {
 		case tls.RecordHeaderError:
 			plog.WithError(err).Debug("TLS handshake cannot be established. Retrying with HTTP CONNECT")
-			t.DialTLS = nonTLSDialer
+			t.DialTLS = nonTLSDialer(t)
 			return t.DialTLS(network, addr)
 		default:
 			return conn, err
@@ -277,42 +453,36 @@ func fullTLSToHTTPConnectFallbackDialer(t *http.Transport) func(network string,
 	}
 }
 
-// tlsDialer wraps the standard library tls.Dial function
+// tlsDialer mimics the standard library tls.Dial function, connecting with the transport dialer
 func tlsDialer(transport *http.Transport) func(network string, addr string) (net.Conn, error) {
 	return func(network string, addr string) (conn net.Conn, e error) {
-		return tls.Dial(network, addr, transport.TLSClientConfig)
-	}
-}
-
-// nonTlsDial mimics the tls.DialWithDialer function, but without performing TLS handshakes
-func nonTLSDialer(network, addr string) (net.Conn, error) {
-	dialer := new(net.Dialer)
-	// We want the Timeout and Deadline values from dialer to cover the
-	// whole process: TCP connection and TLS handshake. This means that we
-	// also need to start our own timers now.
-	timeout := dialer.Timeout
-
-	if !dialer.Deadline.IsZero() {
-		deadlineTimeout := time.Until(dialer.Deadline)
-		if timeout == 0 || deadlineTimeout < timeout {
-			timeout = deadlineTimeout
+		rawConn, err := transport.DialContext(context.Background(), network, addr)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	var errChannel chan error
+		cfg := transport.TLSClientConfig.Clone()
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			if host, _, err := net.SplitHostPort(addr); err == nil {
+				cfg.ServerName = host
+			}
+		}
 
-	if timeout != 0 {
-		errChannel = make(chan error, 2)
-		time.AfterFunc(timeout, func() {
-			errChannel <- timeoutError{}
-		})
+		tlsConn := tls.Client(rawConn, cfg)
+		if err = tlsConn.Handshake(); err != nil {
+			_ = rawConn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
 	}
-
-	return dialer.Dial(network, addr)
 }
 
-type timeoutError struct{}
-
-func (timeoutError) Error() string   { return "tls: DialWithDialer timed out" }
-func (timeoutError) Timeout() bool   { return true }
-func (timeoutError) Temporary() bool { return true }
+// nonTLSDialer mimics the tls.Dial function, but without performing TLS handshakes
+func nonTLSDialer(transport *http.Transport) func(network string, addr string) (net.Conn, error) {
+	return func(network string, addr string) (net.Conn, error) {
+		return transport.DialContext(context.Background(), network, addr)
+	}
+}
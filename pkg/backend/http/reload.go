@@ -0,0 +1,115 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+// transportsVersion is increased when the transports settings are reloaded, so the reloadable transports are built
+// again.
+var transportsVersion uint64
+
+// transportOptions are the configuration options the reloadable transports are built again for.
+var transportOptions = map[string]bool{
+	"proxy":                       true,
+	"ignore_system_proxy":         true,
+	"proxy_validate_certificates": true,
+	"metrics_proxy":               true,
+	"inventory_proxy":             true,
+	"command_api_proxy":           true,
+	"no_proxy":                    true,
+	"ca_bundle_file":              true,
+	"ca_bundle_dir":               true,
+}
+
+// ReloadTransports builds again the transports created by NewReloadableTransport when any of the changed options
+// configures them, ie: the proxy or CA bundle settings. The requests in flight complete with the previous transport.
+func ReloadTransports(changed []string) {
+	for _, key := range changed {
+		if transportOptions[key] {
+			atomic.AddUint64(&transportsVersion, 1)
+			plog.Info("Transport settings reloaded, new connections will use them.")
+			return
+		}
+	}
+}
+
+// reloadableTransport builds the transport again from the configuration after ReloadTransports, as proxies and TLS
+// settings can't be modified once in use.
+type reloadableTransport struct {
+	build func() http.RoundTripper
+	// external transports requests are not reported by BackendReports
+	external bool
+
+	lock      sync.Mutex
+	version   uint64
+	transport http.RoundTripper
+}
+
+// NewReloadableTransport creates the transport as BuildTransport does, building it again with the configuration
+// options modified at runtime when ReloadTransports is invoked. Its requests are reported by BackendReports.
+func NewReloadableTransport(cfg *config.Config, timeout time.Duration) http.RoundTripper {
+	return newReloadableTransport(func() http.RoundTripper {
+		return BuildTransport(cfg, timeout)
+	})
+}
+
+// NewReloadableProxyTransport creates the transport as BuildProxyTransport does, reloaded as the ones created by
+// NewReloadableTransport.
+func NewReloadableProxyTransport(cfg *config.Config, timeout time.Duration, proxy string) http.RoundTripper {
+	return newReloadableTransport(func() http.RoundTripper {
+		return BuildProxyTransport(cfg, timeout, proxy)
+	})
+}
+
+// NewReloadableExternalTransport creates the transport as BuildExternalTransport does, reloaded as the ones created
+// by NewReloadableTransport. Its requests are not reported by BackendReports, as they're not sent to New Relic.
+func NewReloadableExternalTransport(cfg *config.Config, timeout time.Duration) http.RoundTripper {
+	t := newReloadableTransport(func() http.RoundTripper {
+		return BuildExternalTransport(cfg, timeout)
+	})
+	t.external = true
+	return t
+}
+
+func newReloadableTransport(build func() http.RoundTripper) *reloadableTransport {
+	return &reloadableTransport{
+		build:     build,
+		version:   atomic.LoadUint64(&transportsVersion),
+		transport: build(),
+	}
+}
+
+func (t *reloadableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.current().RoundTrip(req)
+	if !t.external {
+		recordBackendRequest(req, resp, err)
+	}
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the current transport.
+func (t *reloadableTransport) CloseIdleConnections() {
+	closeIdleConnections(t.current())
+}
+
+// current returns the transport for the current settings, closing the idle connections of the previous one.
+func (t *reloadableTransport) current() http.RoundTripper {
+	version := atomic.LoadUint64(&transportsVersion)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if version != t.version {
+		closeIdleConnections(t.transport)
+		t.transport = t.build()
+		t.version = version
+	}
+	return t.transport
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReloadableTransport_ProxyReload(t *testing.T) {
+	var direct, proxied int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&direct, 1)
+	}))
+	defer srv.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+	}))
+	defer proxy.Close()
+
+	cfg := &config.Config{IgnoreSystemProxy: true}
+	client := GetHttpClient(time.Second, NewReloadableTransport(cfg, time.Second))
+
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	cfg.Proxy = proxy.URL
+	ReloadTransports([]string{"proxy"})
+
+	resp, err = client.Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&direct))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&proxied))
+}
+
+func TestReloadTransports_IgnoresOtherOptions(t *testing.T) {
+	builds := 0
+	transport := newReloadableTransport(func() http.RoundTripper {
+		builds++
+		return http.DefaultTransport
+	})
+
+	ReloadTransports([]string{"custom_attributes", "verbose"})
+	transport.current()
+	assert.Equal(t, 1, builds)
+
+	ReloadTransports([]string{"custom_attributes", "ca_bundle_file"})
+	transport.current()
+	assert.Equal(t, 2, builds)
+}
+
+func TestNewReloadableExternalTransport_OnlyProxyAndCA(t *testing.T) {
+	backendRequestsLock.Lock()
+	backendRequests = map[string]*backendRequest{}
+	backendRequestsLock.Unlock()
+
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+	}))
+	defer proxy.Close()
+
+	exportDir := t.TempDir()
+	cfg := &config.Config{
+		Proxy:            proxy.URL,
+		OfflineExportDir: exportDir,
+		CollectorDial:    "invalid://collector",
+		UploadRateLimit:  1,
+	}
+	client := GetHttpClient(time.Second, NewReloadableExternalTransport(cfg, time.Second))
+
+	resp, err := client.Post("http://otlp-collector:4318/v1/metrics", "application/x-protobuf", strings.NewReader("payload"))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&proxied))
+	archived, err := os.ReadDir(exportDir)
+	require.NoError(t, err)
+	assert.Empty(t, archived, "requests are not archived")
+	assert.Empty(t, BackendReports(), "requests are not reported as sent to New Relic")
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRetryAfter caps the delays requested by the backend, so a wrong header doesn't stop the agent from reporting.
+const MaxRetryAfter = 1 * time.Hour
+
+// ParseRetryAfter returns the delay requested by a Retry-After header value, either delay seconds or an HTTP date
+// (RFC 7231). Dates in the past return no delay.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	var after time.Duration
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("negative Retry-After delay: %d", seconds)
+		}
+		if seconds > int64(MaxRetryAfter/time.Second) {
+			return MaxRetryAfter, nil
+		}
+		after = time.Duration(seconds) * time.Second
+	} else {
+		date, err := http.ParseTime(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid Retry-After header %q: neither delay seconds nor an HTTP date", value)
+		}
+		after = date.Sub(now)
+		if after < 0 {
+			return 0, nil
+		}
+	}
+
+	if after > MaxRetryAfter {
+		return MaxRetryAfter, nil
+	}
+	return after, nil
+}
+
+// RetryAfter returns the delay requested by the response Retry-After header, zero when it's missing.
+func RetryAfter(resp *http.Response) (time.Duration, error) {
+	return ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+}
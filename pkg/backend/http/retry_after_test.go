@@ -0,0 +1,51 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		"missing":             {"", 0, false},
+		"seconds":             {"120", 2 * time.Minute, false},
+		"seconds with spaces": {" 5 ", 5 * time.Second, false},
+		"seconds over max":    {"999999999999", MaxRetryAfter, false},
+		"negative seconds":    {"-1", 0, true},
+		"http date":           {"Wed, 10 May 2023 12:00:30 GMT", 30 * time.Second, false},
+		"http date over max":  {"Thu, 11 May 2023 12:00:00 GMT", MaxRetryAfter, false},
+		"http date in past":   {"Wed, 10 May 2023 11:00:00 GMT", 0, false},
+		"malformed":           {"MalformedHeader", 0, true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseRetryAfter(tc.value, now)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", "42")
+
+	after, err := RetryAfter(resp)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 42*time.Second, after)
+}
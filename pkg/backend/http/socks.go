@@ -0,0 +1,43 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	netproxy "golang.org/x/net/proxy"
+)
+
+// defaultSocksPort is used when the SOCKS proxy URL has no port.
+const defaultSocksPort = "1080"
+
+func isSocksScheme(s string) bool {
+	return s == "socks5" || s == "socks5h"
+}
+
+// socksDialer returns a dialer connecting through the SOCKS5 proxy, authenticating with the URL user and password
+// when present. Host names are resolved by the proxy, as locked-down networks usually don't resolve external names.
+func socksDialer(u *url.URL, forward netproxy.Dialer) (dialContextFunc, error) {
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), defaultSocksPort)
+	}
+
+	var auth *netproxy.Auth
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth = &netproxy.Auth{User: u.User.Username(), Password: password}
+	}
+
+	d, err := netproxy.SOCKS5("tcp", address, auth, forward)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy %q: %v", u.Redacted(), err)
+	}
+	contextDialer, ok := d.(netproxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS5 proxy %q dialer doesn't support contexts", u.Redacted())
+	}
+	return contextDialer.DialContext, nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socksServer is a minimal SOCKS5 server supporting CONNECT, without authentication or with user and password.
+type socksServer struct {
+	listener net.Listener
+	user     string
+	password string
+	// targets stores the requested destinations
+	targets chan string
+}
+
+func newSocksServer(t *testing.T, user, password string) *socksServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &socksServer{listener: l, user: user, password: password, targets: make(chan string, 10)}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *socksServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	// greeting: version, methods
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	if s.user == "" {
+		_, _ = conn.Write([]byte{5, 0})
+	} else {
+		_, _ = conn.Write([]byte{5, 2})
+		// user/password sub-negotiation: version, user, password
+		buf := make([]byte, 2)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		user := make([]byte, buf[1])
+		if _, err := io.ReadFull(conn, user); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return
+		}
+		password := make([]byte, buf[0])
+		if _, err := io.ReadFull(conn, password); err != nil {
+			return
+		}
+		if string(user) != s.user || string(password) != s.password {
+			_, _ = conn.Write([]byte{1, 1})
+			return
+		}
+		_, _ = conn.Write([]byte{1, 0})
+	}
+
+	// request: version, command, reserved, address type
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 3:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return
+		}
+		name := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return
+		}
+		host = string(name)
+	default:
+		return
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return
+	}
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	s.targets <- target
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go func() { _, _ = io.Copy(upstream, conn) }()
+	_, _ = io.Copy(conn, upstream)
+}
+
+func TestBuildTransport_Socks5(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer backend.Close()
+
+	tests := map[string]struct {
+		user     string
+		password string
+		proxyURL func(addr string) string
+		wantErr  bool
+	}{
+		"no auth": {
+			proxyURL: func(addr string) string { return "socks5://" + addr },
+		},
+		"remote dns": {
+			proxyURL: func(addr string) string { return "socks5h://" + addr },
+		},
+		"auth": {
+			user:     "user",
+			password: "secret",
+			proxyURL: func(addr string) string { return "socks5://user:secret@" + addr },
+		},
+		"wrong auth": {
+			user:     "user",
+			password: "secret",
+			proxyURL: func(addr string) string { return "socks5://user:wrong@" + addr },
+			wantErr:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			socks := newSocksServer(t, tc.user, tc.password)
+			cfg := &config.Config{Proxy: tc.proxyURL(socks.listener.Addr().String())}
+			client := GetHttpClient(5*time.Second, BuildTransport(cfg, 5*time.Second))
+
+			resp, err := client.Get(backend.URL)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(body))
+
+			backendURL, err := url.Parse(backend.URL)
+			require.NoError(t, err)
+			assert.Equal(t, backendURL.Host, <-socks.targets)
+		})
+	}
+}
+
+func TestSocksDialer_DefaultPort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:"+defaultSocksPort)
+	if err != nil {
+		t.Skip("default SOCKS port not available:", err)
+	}
+	defer l.Close()
+	accepted := make(chan struct{})
+	go func() {
+		if conn, err := l.Accept(); err == nil {
+			close(accepted)
+			conn.Close()
+		}
+	}()
+
+	dial, err := socksDialer(&url.URL{Scheme: "socks5", Host: "127.0.0.1"}, &net.Dialer{Timeout: time.Second})
+	require.NoError(t, err)
+	_, _ = dial(context.Background(), "tcp", "example.com:443")
+
+	select {
+	case <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("SOCKS proxy default port not dialed")
+	}
+}
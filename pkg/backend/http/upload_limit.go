@@ -0,0 +1,123 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	uploadLimitersLock sync.Mutex
+	uploadLimiters     = map[string]*uploadLimiter{}
+)
+
+// uploadLimiter is a token bucket limiting the bytes sent per second by the agent, which can send up to the burst
+// bytes at once after being idle.
+type uploadLimiter struct {
+	rate  float64 // bytes per second
+	burst int
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// sharedUploadLimiter returns the limiter for the upload_rate_limit and upload_rate_burst options, or nil when the
+// upload rate is not limited. It's shared by all the transports, so they don't exceed the rate altogether.
+func sharedUploadLimiter(rate, burst int) *uploadLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = rate
+	}
+
+	uploadLimitersLock.Lock()
+	defer uploadLimitersLock.Unlock()
+
+	key := fmt.Sprintf("%d/%d", rate, burst)
+	if l, ok := uploadLimiters[key]; ok {
+		return l
+	}
+
+	plog.WithFields(logrus.Fields{
+		"bytesPerSecond": rate,
+		"burstBytes":     burst,
+	}).Info("Limiting upload rate.")
+	l := newUploadLimiter(rate, burst, time.Now)
+	uploadLimiters[key] = l
+	return l
+}
+
+func newUploadLimiter(rate, burst int, now func() time.Time) *uploadLimiter {
+	return &uploadLimiter{
+		rate:   float64(rate),
+		burst:  burst,
+		tokens: float64(burst),
+		last:   now(),
+		now:    now,
+	}
+}
+
+// reserve takes the bytes from the bucket, returning the time to wait before sending them. The bucket goes into
+// debt when there are not enough bytes, so the following reservations wait until it's paid off.
+func (l *uploadLimiter) reserve(bytes int) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+
+	l.tokens -= float64(bytes)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// dial wraps the dialer, limiting the upload rate of the connections it establishes.
+func (l *uploadLimiter) dial(dial dialContextFunc) dialContextFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &throttledConn{Conn: conn, limiter: l}, nil
+	}
+}
+
+// throttledConn writes to the connection no faster than the upload rate, in chunks of up to the burst size.
+type throttledConn struct {
+	net.Conn
+	limiter *uploadLimiter
+}
+
+func (c *throttledConn) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > c.limiter.burst {
+			chunk = chunk[:c.limiter.burst]
+		}
+		time.Sleep(c.limiter.reserve(len(chunk)))
+
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadLimiter_Reserve(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newUploadLimiter(1000, 500, func() time.Time { return now })
+
+	assert.Equal(t, time.Duration(0), l.reserve(500), "burst is available")
+	assert.Equal(t, 100*time.Millisecond, l.reserve(100))
+	assert.Equal(t, 300*time.Millisecond, l.reserve(200), "waits for the previous reservations")
+
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), l.reserve(500), "refilled after the debt is paid off")
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, time.Duration(0), l.reserve(500))
+	assert.Equal(t, 100*time.Millisecond, l.reserve(100), "refill doesn't exceed the burst")
+}
+
+func TestSharedUploadLimiter(t *testing.T) {
+	assert.Nil(t, sharedUploadLimiter(0, 100))
+	assert.Nil(t, sharedUploadLimiter(-1, 0))
+
+	l := sharedUploadLimiter(2048, 0)
+	require.NotNil(t, l)
+	assert.Equal(t, 2048, l.burst, "defaults to a second of rate")
+	assert.Same(t, l, sharedUploadLimiter(2048, 2048))
+	assert.NotSame(t, l, sharedUploadLimiter(2048, 4096))
+}
+
+func TestThrottledConn_Write(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() { _, _ = io.Copy(io.Discard, server) }()
+
+	l := newUploadLimiter(10000, 1000, time.Now)
+	conn, err := l.dial(func(context.Context, string, string) (net.Conn, error) {
+		return client, nil
+	})(context.Background(), "tcp", "newrelic.com:443")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	start := time.Now()
+	n, err := conn.Write(make([]byte, 3000))
+	require.NoError(t, err)
+	assert.Equal(t, 3000, n)
+	// the burst is sent at once, the remaining 2000 bytes at 10000 bytes per second
+	assert.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+}
@@ -137,12 +137,9 @@ func (ic *identityClient) Connect(fingerprint fingerprint.Fingerprint) (ids enti
 	hasError, cause := backendhttp.IsResponseUnsuccessful(resp)
 
 	if hasError {
-		retryAfterH := resp.Header.Get("Retry-After")
-		if retryAfterH != "" {
-			if retry.After, err = time.ParseDuration(retryAfterH + "s"); err != nil {
-				ilog.WithError(err).
-					Debug("Error parsing connect Retry-After header, continuing with exponential backoff.")
-			}
+		if retry.After, err = backendhttp.RetryAfter(resp); err != nil {
+			ilog.WithError(err).
+				Debug("Error parsing connect Retry-After header, continuing with exponential backoff.")
 		}
 
 		retry.MaxBackOff = backoff.GetMaxBackoffByCause(cause)
@@ -202,12 +199,9 @@ func (ic *identityClient) ConnectUpdate(entityIdn entity.Identity, fingerprint f
 	hasError, cause := backendhttp.IsResponseUnsuccessful(resp)
 
 	if hasError {
-		retryAfterH := resp.Header.Get("Retry-After")
-		if retryAfterH != "" {
-			if retry.After, err = time.ParseDuration(retryAfterH + "s"); err != nil {
-				ilog.WithError(err).
-					Debug("Error parsing reEmptyRetryTimeconnect Retry-After header, continuing with exponential backoff.")
-			}
+		if retry.After, err = backendhttp.RetryAfter(resp); err != nil {
+			ilog.WithError(err).
+				Debug("Error parsing connect Retry-After header, continuing with exponential backoff.")
 		}
 
 		retry.MaxBackOff = backoff.GetMaxBackoffByCause(cause)
@@ -240,7 +240,7 @@ func (rc *registerClient) RegisterEntitiesRemoveMe(agentID entity.ID, entities [
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		retryAfter, err = time.ParseDuration(resp.Header.Get("Retry-After") + "s")
+		retryAfter, err = backendhttp.RetryAfter(resp)
 		if err != nil {
 			retryAfter = EmptyRetryTime
 		}
@@ -0,0 +1,93 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package inventoryapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// DeltaChunk identifies a part of a delta too large to be submitted in a single request. Chunks are content-addressed:
+// Digest is the SHA-256 of the chunk diff and DeltaDigest the one of the whole delta diff, so the backend can assemble
+// the delta once it has the Count chunks, and ignore the ones it already received.
+type DeltaChunk struct {
+	DeltaDigest string `json:"delta_digest"`
+	Digest      string `json:"digest"`
+	Index       int    `json:"index"`
+	Count       int    `json:"count"`
+}
+
+// IsLast returns whether the chunk completes its delta.
+func (c *DeltaChunk) IsLast() bool {
+	return c.Index == c.Count-1
+}
+
+// Digest returns the SHA-256 of the JSON encoded diff, which is stable as the map keys are encoded sorted.
+func Digest(diff map[string]interface{}) (string, error) {
+	buf, err := json.Marshal(diff)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Size returns the size of the JSON encoded delta.
+func (rd *RawDelta) Size() (int, error) {
+	buf, err := json.Marshal(rd)
+	return len(buf), err
+}
+
+// SplitDelta splits the diff of a delta in chunks whose diffs are at most maxSize bytes once encoded, keeping its
+// source, ID and timestamp. Entries are never split, so an entry larger than maxSize is sent in a chunk on its own.
+func SplitDelta(d *RawDelta, maxSize int) ([]*RawDelta, error) {
+	deltaDigest, err := Digest(d.Diff)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(d.Diff))
+	for k := range d.Diff {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var diffs []map[string]interface{}
+	current := map[string]interface{}{}
+	currentSize := 2 // surrounding braces
+	for _, k := range keys {
+		entry, err := json.Marshal(map[string]interface{}{k: d.Diff[k]})
+		if err != nil {
+			return nil, err
+		}
+		// the entry without its braces, plus the separating comma
+		entrySize := len(entry) - 1
+		if len(current) > 0 && currentSize+entrySize > maxSize {
+			diffs = append(diffs, current)
+			current = map[string]interface{}{}
+			currentSize = 2
+		}
+		current[k] = d.Diff[k]
+		currentSize += entrySize
+	}
+	diffs = append(diffs, current)
+
+	chunks := make([]*RawDelta, 0, len(diffs))
+	for i, diff := range diffs {
+		digest, err := Digest(diff)
+		if err != nil {
+			return nil, err
+		}
+		chunk := NewRawDelta(d.Source, d.ID, d.Timestamp, diff, d.FullDiff)
+		chunk.Chunk = &DeltaChunk{
+			DeltaDigest: deltaDigest,
+			Digest:      digest,
+			Index:       i,
+			Count:       len(diffs),
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
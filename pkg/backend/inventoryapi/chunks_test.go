@@ -0,0 +1,67 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package inventoryapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitDelta(t *testing.T) {
+	diff := map[string]interface{}{}
+	for i := 0; i < 100; i++ {
+		diff[fmt.Sprintf("pkg%03d", i)] = map[string]interface{}{"id": fmt.Sprintf("pkg%03d", i), "version": "1.0.0"}
+	}
+	d := NewRawDelta("packages/dpkg", 7, 1234, diff, true)
+
+	chunks, err := SplitDelta(d, 1000)
+	require.NoError(t, err)
+	require.True(t, len(chunks) > 1)
+
+	deltaDigest, err := Digest(diff)
+	require.NoError(t, err)
+	merged := map[string]interface{}{}
+	for i, chunk := range chunks {
+		assert.Equal(t, "packages/dpkg", chunk.Source)
+		assert.EqualValues(t, 7, chunk.ID)
+		assert.True(t, chunk.FullDiff)
+		assert.Equal(t, deltaDigest, chunk.Chunk.DeltaDigest)
+		assert.Equal(t, i, chunk.Chunk.Index)
+		assert.Equal(t, len(chunks), chunk.Chunk.Count)
+		assert.Equal(t, i == len(chunks)-1, chunk.Chunk.IsLast())
+
+		buf, err := json.Marshal(chunk.Diff)
+		require.NoError(t, err)
+		assert.True(t, len(buf) <= 1000, "chunk %d has %d bytes", i, len(buf))
+		digest, err := Digest(chunk.Diff)
+		require.NoError(t, err)
+		assert.Equal(t, digest, chunk.Chunk.Digest)
+
+		for k, v := range chunk.Diff {
+			merged[k] = v
+		}
+	}
+	assert.Equal(t, diff, merged)
+
+	// the same delta is always split in the same chunks
+	again, err := SplitDelta(d, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, chunks, again)
+}
+
+func TestSplitDelta_LargeEntry(t *testing.T) {
+	d := NewRawDelta("files/config", 1, 1234, map[string]interface{}{
+		"big":   string(make([]byte, 200)),
+		"small": "a",
+	}, false)
+
+	chunks, err := SplitDelta(d, 100)
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	assert.Contains(t, chunks[0].Diff, "big")
+	assert.Contains(t, chunks[1].Diff, "small")
+}
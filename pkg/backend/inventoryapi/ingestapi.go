@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/newrelic/infrastructure-agent/internal/agent/id"
 	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
@@ -25,6 +26,8 @@ type IngestError struct {
 	Status     string
 	StatusCode int
 	Body       string
+	// RetryAfter is the delay requested by the backend before submitting again, zero when not requested.
+	RetryAfter time.Duration
 }
 
 func (e *IngestError) Error() string {
@@ -33,7 +36,15 @@ func (e *IngestError) Error() string {
 
 // NewIngestError returns a new IngestError.
 func NewIngestError(msg string, code int, status, body string) *IngestError {
-	return &IngestError{msg, status, code, body}
+	return &IngestError{msg: msg, Status: status, StatusCode: code, Body: body}
+}
+
+// newResponseError returns the error for a response not accepting the deltas. Invalid Retry-After headers are
+// ignored, so the regular backoff applies.
+func newResponseError(resp *http.Response, body []byte) *IngestError {
+	err := NewIngestError("inventory deltas were not accepted", resp.StatusCode, resp.Status, string(body))
+	err.RetryAfter, _ = backendhttp.RetryAfter(resp)
+	return err
 }
 
 type IngestClient struct {
@@ -45,11 +56,13 @@ type IngestClient struct {
 	connectEnabled   bool
 	HttpClient       backendhttp.Client
 	CompressionLevel int
+	compressor       *backendhttp.PayloadCompressor
 }
 
 func NewIngestClient(
 	svcUrl, licenseKey, userAgent string,
 	compressionLevel int,
+	compression string,
 	agentKey string,
 	agentIDProvide id.Provide,
 	connectEnabled bool,
@@ -67,6 +80,7 @@ func NewIngestClient(
 		HttpClient:       httpClient,
 		connectEnabled:   connectEnabled,
 		CompressionLevel: compressionLevel,
+		compressor:       backendhttp.NewPayloadCompressor(compression, compressionLevel),
 	}, nil
 }
 
@@ -119,20 +133,7 @@ func (ic *IngestClient) PostDeltas(entityKeys []string, entityID entity.ID, isAg
 		postDeltaBody.EntityID = entityID
 	}
 
-	buf, err := ic.marshal(postDeltaBody)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest("POST", ic.makeURL("/deltas"), buf)
-	if err != nil {
-		return nil, fmt.Errorf("New request failed: %s", err)
-	}
-	if ic.CompressionLevel > gzip.NoCompression {
-		req.Header.Set("Content-Encoding", "gzip")
-	}
-
-	resp, err := ic.Do(req)
+	resp, err := ic.post(ic.makeURL("/deltas"), postDeltaBody)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to submit state changes for entity %v: %s", entityKeys, err)
 	}
@@ -144,7 +145,7 @@ func (ic *IngestClient) PostDeltas(entityKeys []string, entityID entity.ID, isAg
 	}
 
 	if resp.StatusCode != http.StatusAccepted {
-		return nil, NewIngestError("inventory deltas were not accepted", resp.StatusCode, resp.Status, string(body))
+		return nil, newResponseError(resp, body)
 	}
 
 	var res struct {
@@ -157,25 +158,32 @@ func (ic *IngestClient) PostDeltas(entityKeys []string, entityID entity.ID, isAg
 	return res.Payload, nil
 }
 
-func (ic *IngestClient) marshal(b interface{}) (*bytes.Buffer, error) {
-	var buf bytes.Buffer
-	if ic.CompressionLevel > gzip.NoCompression {
-		gzipWriter, err := gzip.NewWriterLevel(&buf, ic.CompressionLevel)
+// post sends the payload compressed. Payloads rejected because of their compression are sent again, once the
+// compressor falls back to a supported one.
+func (ic *IngestClient) post(url string, b interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		body, encoding, err := ic.compressor.Compress(payload)
 		if err != nil {
-			return nil, fmt.Errorf("Unable to create gzip writer: %v", err)
+			return nil, err
 		}
-		if err := json.NewEncoder(gzipWriter).Encode(b); err != nil {
-			return nil, fmt.Errorf("Gzip writer was not able to write to request body: %s", err)
+		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("New request failed: %s", err)
 		}
-		if err := gzipWriter.Close(); err != nil {
-			return nil, fmt.Errorf("Gzip writer did not close: %s", err)
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
 		}
-	} else {
-		if err := json.NewEncoder(&buf).Encode(b); err != nil {
-			return nil, err
+
+		resp, err := ic.Do(req)
+		if err != nil || !ic.compressor.Negotiate(resp, encoding) {
+			return resp, err
 		}
+		_ = resp.Body.Close()
 	}
-	return &buf, nil
 }
 
 // PostDeltasBulk allows posting deltas for multiple entities in a single request.
@@ -190,20 +198,7 @@ func (ic *IngestClient) PostDeltasBulk(reqs []PostDeltaBody) ([]BulkDeltaRespons
 		}
 	}
 
-	buf, err := ic.marshal(reqs)
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest("POST", ic.makeURL("/deltas/bulk"), buf)
-	if err != nil {
-		return nil, fmt.Errorf("New request failed: %s", err)
-	}
-	if ic.CompressionLevel > gzip.NoCompression {
-		req.Header.Set("Content-Encoding", "gzip")
-	}
-
-	resp, err := ic.Do(req)
+	resp, err := ic.post(ic.makeURL("/deltas/bulk"), reqs)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to submit deltas: %s", err)
 	}
@@ -222,7 +217,7 @@ func (ic *IngestClient) PostDeltasBulk(reqs []PostDeltaBody) ([]BulkDeltaRespons
 	}
 
 	if resp.StatusCode != http.StatusAccepted {
-		return res.Payload, NewIngestError("inventory deltas were not accepted", resp.StatusCode, resp.Status, string(body))
+		return res.Payload, newResponseError(resp, body)
 	}
 
 	return res.Payload, nil
@@ -243,20 +238,7 @@ type PostDeltaVortexBody struct {
 func (ic *IngestClient) PostDeltasVortex(entityID entity.ID, entityKeys []string, isAgent bool, deltas ...*RawDelta) (*PostDeltaResponse, error) {
 	deltas = filterDeltas(deltas)
 
-	buf, err := ic.marshal(PostDeltaVortexBody{entityID, &isAgent, deltas})
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequest("POST", ic.makeURL("/deltas"), buf)
-	if err != nil {
-		return nil, fmt.Errorf("New request failed: %s", err)
-	}
-	if ic.CompressionLevel > gzip.NoCompression {
-		req.Header.Set("Content-Encoding", "gzip")
-	}
-
-	resp, err := ic.Do(req)
+	resp, err := ic.post(ic.makeURL("/deltas"), PostDeltaVortexBody{entityID, &isAgent, deltas})
 	if err != nil {
 		return nil, fmt.Errorf("Unable to submit state changes for entityID: %d entity %v: %s", entityID, entityKeys, err)
 	}
@@ -268,7 +250,7 @@ func (ic *IngestClient) PostDeltasVortex(entityID entity.ID, entityKeys []string
 	}
 
 	if resp.StatusCode != http.StatusAccepted {
-		return nil, NewIngestError("inventory deltas were not accepted", resp.StatusCode, resp.Status, string(body))
+		return nil, newResponseError(resp, body)
 	}
 
 	var res struct {
@@ -63,13 +63,13 @@ func (*IngestAPISuite) TestCreateRawDeltaPartial(c *C) {
 }
 
 func (*IngestAPISuite) TestMakeURLAccountPrefix(c *C) {
-	client, _ := NewIngestClient("http://test.com", "abc", "useragent", 0, "", nil, false, backendhttp.NullHttpClient)
+	client, _ := NewIngestClient("http://test.com", "abc", "useragent", 0, backendhttp.CompressionGzip, "", nil, false, backendhttp.NullHttpClient)
 	url := client.makeURL("/mypath")
 	c.Assert(url, Equals, "http://test.com/mypath")
 }
 
 func (*IngestAPISuite) TestMakeURLAccountPrefixTrimmed(c *C) {
-	client, _ := NewIngestClient("http://test.com/inventory/", "abc", "useragent", 0, "", nil, false, backendhttp.NullHttpClient)
+	client, _ := NewIngestClient("http://test.com/inventory/", "abc", "useragent", 0, backendhttp.CompressionGzip, "", nil, false, backendhttp.NullHttpClient)
 	url := client.makeURL("/mypath")
 	c.Assert(url, Equals, "http://test.com/inventory/mypath")
 }
@@ -156,7 +156,7 @@ func TestPostDeltasGoldenPathGzip(t *testing.T) {
 			defer ts.Close()
 
 			httpClient := backendhttp.GetHttpClient(1*time.Second, &http.Transport{})
-			client, _ := NewIngestClient(ts.URL, "abc", "useragent", 6, "", nil, false, httpClient.Do)
+			client, _ := NewIngestClient(ts.URL, "abc", "useragent", 6, backendhttp.CompressionGzip, "", nil, false, httpClient.Do)
 
 			// create real client using test server's URL (instead of mocked client)
 			msg, err := client.PostDeltas([]string{"MyKey", "OtherKey"}, testCase.entityID, testCase.isAgent, &RawDelta{})
@@ -270,7 +270,7 @@ func (*IngestAPISuite) TestPostDeltasBadBody(c *C) {
 }
 
 func (*IngestAPISuite) TestInvalidCompressionLevel(c *C) {
-	client, err := NewIngestClient("http://test.com", "abc", "useragent", 17, "", nil, false, backendhttp.NullHttpClient)
+	client, err := NewIngestClient("http://test.com", "abc", "useragent", 17, backendhttp.CompressionGzip, "", nil, false, backendhttp.NullHttpClient)
 	c.Assert(client, IsNil)
 	c.Assert(err, ErrorMatches, "gzip: invalid compression level: 17")
 }
@@ -366,7 +366,7 @@ func TestPostDeltas_EntityID(t *testing.T) {
 
 			httpClient := backendhttp.GetHttpClient(1*time.Second, &http.Transport{})
 			// create real client using test server's URL (instead of mocked client)
-			client, _ := NewIngestClient(ts.URL, "abc", "useragent", 6, "", agentIDProvide, true, httpClient.Do)
+			client, _ := NewIngestClient(ts.URL, "abc", "useragent", 6, backendhttp.CompressionGzip, "", agentIDProvide, true, httpClient.Do)
 
 			msg, err := client.PostDeltas([]string{"MyKey", "OtherKey"}, entity.EmptyID, testCase.isAgent, &RawDelta{})
 			assert.NoError(t, err)
@@ -20,6 +20,7 @@ type RawDelta struct {
 	Timestamp int64                  `json:"timestamp"`
 	Diff      map[string]interface{} `json:"diff"`
 	FullDiff  bool                   `json:"full_diff"` // See DiffType* constants
+	Chunk     *DeltaChunk            `json:"chunk,omitempty"`
 }
 
 // validate is used to validate the RawDelta fields.
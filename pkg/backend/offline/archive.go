@@ -0,0 +1,344 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package offline archives the agent requests in air-gapped hosts, so they are uploaded to New Relic from a
+// connected host.
+package offline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+const (
+	archivePrefix = "nri-export-"
+	// ArchiveExt is the extension of the finished archive files, which are gzip compressed JSON lines.
+	ArchiveExt = ".ndjson.gz"
+	// pendingExt is the extension of the archive file being written.
+	pendingExt = ".tmp"
+	// SignatureExt is the extension of the base64 ed25519 signature of the SHA-256 hash of an archive file.
+	SignatureExt = ".sig"
+)
+
+var alog = log.WithComponent("OfflineArchive")
+
+// Record is an archived request.
+type Record struct {
+	Time   time.Time   `json:"time"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// ArchiveConfig configures the archive files.
+type ArchiveConfig struct {
+	Dir string
+	// MaxFileSize is the compressed size the files are rotated at, 0 to only rotate them by time.
+	MaxFileSize int64
+	// RotateInterval is the time the files are rotated after being created, 0 to only rotate them by size.
+	RotateInterval time.Duration
+	// MaxFiles is the number of finished files kept, removing the oldest ones. 0 keeps all of them.
+	MaxFiles int
+	// SigningKeyFile is the PEM file of the ed25519 private key signing the files, empty to not sign them.
+	SigningKeyFile string
+}
+
+// Archive writes the records into rotated files. Every record is a gzip member of its own, so the files written
+// until the agent stopped are readable and finished on the next start.
+type Archive struct {
+	cfg    ArchiveConfig
+	signer ed25519.PrivateKey
+	now    func() time.Time
+
+	lock   sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	seq    int
+}
+
+// NewArchive creates the archive in the directory, finishing the files left by a previous run.
+func NewArchive(cfg ArchiveConfig) (*Archive, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create archive directory: %w", err)
+	}
+
+	a := &Archive{cfg: cfg, now: time.Now}
+	if cfg.SigningKeyFile != "" {
+		signer, err := loadPrivateKey(cfg.SigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		a.signer = signer
+	}
+
+	pending, err := filepath.Glob(filepath.Join(cfg.Dir, archivePrefix+"*"+ArchiveExt+pendingExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range pending {
+		if err := a.finish(name); err != nil {
+			return nil, fmt.Errorf("cannot finish archive file from the previous run: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// Write appends the record into the current file, rotating it when needed.
+func (a *Archive) Write(r Record) error {
+	member, err := gzipMember(r)
+	if err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := a.now()
+	if a.file != nil && ((a.cfg.MaxFileSize > 0 && a.size+int64(len(member)) > a.cfg.MaxFileSize) ||
+		(a.cfg.RotateInterval > 0 && now.Sub(a.opened) >= a.cfg.RotateInterval)) {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+	if a.file == nil {
+		if err := a.open(now); err != nil {
+			return err
+		}
+	}
+
+	n, err := a.file.Write(member)
+	a.size += int64(n)
+	return err
+}
+
+// Close finishes the current file.
+func (a *Archive) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	return a.rotate()
+}
+
+func gzipMember(r Record) ([]byte, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err = gz.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	if err = gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (a *Archive) open(now time.Time) error {
+	a.seq++
+	name := fmt.Sprintf("%s%s-%06d%s%s", archivePrefix, now.UTC().Format("20060102T150405.000000000Z"), a.seq, ArchiveExt, pendingExt)
+
+	file, err := os.OpenFile(filepath.Join(a.cfg.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot create archive file: %w", err)
+	}
+	a.file, a.size, a.opened = file, 0, now
+	return nil
+}
+
+func (a *Archive) rotate() error {
+	name := a.file.Name()
+	err := a.file.Close()
+	a.file = nil
+	if err != nil {
+		return fmt.Errorf("cannot close archive file: %w", err)
+	}
+
+	if err = a.finish(name); err != nil {
+		return err
+	}
+	return a.prune()
+}
+
+// finish signs the pending file and renames it, so it's available to be exported.
+func (a *Archive) finish(pending string) error {
+	name := strings.TrimSuffix(pending, pendingExt)
+	if a.signer != nil {
+		content, err := os.ReadFile(pending)
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(content)
+		signature := base64.StdEncoding.EncodeToString(ed25519.Sign(a.signer, hash[:]))
+		if err = os.WriteFile(name+SignatureExt, []byte(signature), 0o600); err != nil {
+			return fmt.Errorf("cannot write archive signature: %w", err)
+		}
+	}
+
+	if err := os.Rename(pending, name); err != nil {
+		return fmt.Errorf("cannot finish archive file: %w", err)
+	}
+	alog.WithField("file", name).Debug("Archive file finished.")
+	return nil
+}
+
+// prune removes the oldest files exceeding the maximum number of files.
+func (a *Archive) prune() error {
+	if a.cfg.MaxFiles <= 0 {
+		return nil
+	}
+
+	files, err := Files(a.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	for len(files) > a.cfg.MaxFiles {
+		alog.WithField("file", files[0]).Warn("Removing archive file not exported, exceeding the maximum number of files.")
+		if err = os.Remove(files[0]); err != nil {
+			return err
+		}
+		_ = os.Remove(files[0] + SignatureExt)
+		files = files[1:]
+	}
+	return nil
+}
+
+// Files returns the finished archive files in the directory, oldest first.
+func Files(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, archivePrefix+"*"+ArchiveExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Read returns the records of the archive file. The signature is verified when the public key is provided.
+func Read(file string, publicKey ed25519.PublicKey) ([]Record, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if publicKey != nil {
+		if err = verify(file, content, publicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive file %s: %w", file, err)
+	}
+	var records []Record
+	dec := json.NewDecoder(gz)
+	for dec.More() {
+		var r Record
+		err = dec.Decode(&r)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// the agent stopped while writing the last record
+			alog.WithField("file", file).Warn("Archive file is truncated, ignoring its last record.")
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive file %s: %w", file, err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+var errInvalidSignature = errors.New("invalid archive signature")
+
+func verify(file string, content []byte, publicKey ed25519.PublicKey) error {
+	encoded, err := os.ReadFile(file + SignatureExt)
+	if err != nil {
+		return fmt.Errorf("missing archive signature: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("%w for %s: %v", errInvalidSignature, file, err)
+	}
+
+	hash := sha256.Sum256(content)
+	if !ed25519.Verify(publicKey, hash[:], signature) {
+		return fmt.Errorf("%w for %s", errInvalidSignature, file)
+	}
+	return nil
+}
+
+// loadPrivateKey loads the ed25519 private key from a PKCS #8 PEM file, ie: generated by
+// openssl genpkey -algorithm ed25519 -out export-key.pem
+func loadPrivateKey(file string) (ed25519.PrivateKey, error) {
+	block, err := pemBlock(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key %s: %w", file, err)
+	}
+	signer, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid signing key %s: not an ed25519 key", file)
+	}
+	return signer, nil
+}
+
+// LoadPublicKey loads the ed25519 public key verifying the archive files from a PKIX PEM file, ie: generated by
+// openssl pkey -in export-key.pem -pubout -out export-key.pub
+func LoadPublicKey(file string) (ed25519.PublicKey, error) {
+	block, err := pemBlock(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", file, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid public key %s: not an ed25519 key", file)
+	}
+	return publicKey, nil
+}
+
+func pemBlock(file string) (*pem.Block, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", file)
+	}
+	return block, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package offline
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeys writes an ed25519 key pair in PEM files, returning their paths.
+func writeKeys(t *testing.T) (privateFile, publicFile string) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privateFile, publicFile = filepath.Join(dir, "export-key.pem"), filepath.Join(dir, "export-key.pub")
+	require.NoError(t, os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600))
+	require.NoError(t, os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600))
+	return privateFile, publicFile
+}
+
+func record(url, body string) Record {
+	return Record{
+		Time:   time.Unix(1700000000, 0).UTC(),
+		Method: http.MethodPost,
+		URL:    url,
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   []byte(body),
+	}
+}
+
+func TestArchive_WriteAndRead(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewArchive(ArchiveConfig{Dir: dir, MaxFileSize: 1024})
+	require.NoError(t, err)
+
+	first := record("https://infra-api.newrelic.com/metrics/events/bulk", `[{"a":1}]`)
+	second := record("https://infra-api.newrelic.com/inventory/deltas", `{"b":2}`)
+	require.NoError(t, a.Write(first))
+	require.NoError(t, a.Write(second))
+
+	files, err := Files(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files, "the file being written is not finished")
+
+	require.NoError(t, a.Close())
+	files, err = Files(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	records, err := Read(files[0], nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Record{first, second}, records)
+}
+
+func TestArchive_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Unix(1700000000, 0)
+	member, err := gzipMember(record("https://infra-api.newrelic.com/metrics/events/bulk", "{}"))
+	require.NoError(t, err)
+
+	// files are rotated every 2 records
+	a, err := NewArchive(ArchiveConfig{Dir: dir, MaxFileSize: int64(2 * len(member)), RotateInterval: time.Minute, MaxFiles: 3})
+	require.NoError(t, err)
+	a.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, a.Write(record("https://infra-api.newrelic.com/metrics/events/bulk", "{}")))
+	}
+	files, err := Files(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "rotated by size")
+
+	now = now.Add(time.Minute)
+	require.NoError(t, a.Write(record("https://infra-api.newrelic.com/metrics/events/bulk", "{}")))
+	files, err = Files(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2, "rotated by time")
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, a.Write(record("https://infra-api.newrelic.com/metrics/events/bulk", "{}")))
+	}
+	require.NoError(t, a.Close())
+	files, err = Files(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 3, "oldest files are removed")
+}
+
+func TestArchive_Signature(t *testing.T) {
+	dir := t.TempDir()
+	privateFile, publicFile := writeKeys(t)
+	publicKey, err := LoadPublicKey(publicFile)
+	require.NoError(t, err)
+
+	a, err := NewArchive(ArchiveConfig{Dir: dir, SigningKeyFile: privateFile})
+	require.NoError(t, err)
+	require.NoError(t, a.Write(record("https://infra-api.newrelic.com/metrics/events/bulk", "{}")))
+	require.NoError(t, a.Close())
+
+	files, err := Files(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	records, err := Read(files[0], publicKey)
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(files[0], append(content, content...), 0o600))
+	_, err = Read(files[0], publicKey)
+	assert.ErrorIs(t, err, errInvalidSignature)
+
+	require.NoError(t, os.Remove(files[0]+SignatureExt))
+	_, err = Read(files[0], publicKey)
+	assert.Error(t, err, "unsigned files are rejected")
+
+	_, err = NewArchive(ArchiveConfig{Dir: dir, SigningKeyFile: publicFile})
+	assert.Error(t, err, "public key can't sign")
+}
+
+func TestNewArchive_FinishesPreviousRun(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewArchive(ArchiveConfig{Dir: dir})
+	require.NoError(t, err)
+	require.NoError(t, a.Write(record("https://infra-api.newrelic.com/metrics/events/bulk", "{}")))
+	last := record("https://infra-api.newrelic.com/inventory/deltas", "{}")
+	require.NoError(t, a.Write(last))
+
+	// the agent stops while writing the last record
+	member, err := gzipMember(last)
+	require.NoError(t, err)
+	require.NoError(t, a.file.Truncate(a.size-int64(len(member)/2)))
+	require.NoError(t, a.file.Close())
+
+	_, err = NewArchive(ArchiveConfig{Dir: dir})
+	require.NoError(t, err)
+	files, err := Files(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	records, err := Read(files[0], nil)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "https://infra-api.newrelic.com/metrics/events/bulk", records[0].URL)
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package offline
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// progressExt is the extension of the file storing the number of records of an archive file already replayed.
+	progressExt = ".progress"
+	// ReplayedExt is appended to the archive files once replayed, when they are not removed.
+	ReplayedExt = ".replayed"
+)
+
+var errUnverified = errors.New("archive signatures must be verified, set the public key or allow unsigned archives")
+
+// Replayer sends the archived requests.
+type Replayer struct {
+	// Do sends the requests.
+	Do func(*http.Request) (*http.Response, error)
+	// Hosts are the hosts the requests are sent to, lower cased, ie: the New Relic endpoints. Requests to other
+	// hosts are rejected, so a crafted archive cannot send the license key elsewhere.
+	Hosts map[string]bool
+	// PublicKey verifies the archive signatures. Archives are only replayed without verifying them when
+	// AllowUnsigned is set.
+	PublicKey     ed25519.PublicKey
+	AllowUnsigned bool
+	// Header is set in every request, ie: the license key, which is not archived.
+	Header http.Header
+	// Remove the archive files once replayed, instead of renaming them.
+	Remove bool
+}
+
+// ReplayStats counts the replayed files and requests.
+type ReplayStats struct {
+	Files    int
+	Requests int
+}
+
+// Replay sends the requests of the archive files in the directory, oldest first. It stops on the first failed
+// request, and the next replay continues from it, so the requests are not sent twice.
+func (r *Replayer) Replay(ctx context.Context, dir string) (ReplayStats, error) {
+	var stats ReplayStats
+	if r.PublicKey == nil && !r.AllowUnsigned {
+		return stats, errUnverified
+	}
+
+	files, err := Files(dir)
+	if err != nil {
+		return stats, err
+	}
+	for _, file := range files {
+		sent, err := r.replayFile(ctx, file)
+		stats.Requests += sent
+		if err != nil {
+			return stats, err
+		}
+		stats.Files++
+	}
+	return stats, nil
+}
+
+func (r *Replayer) replayFile(ctx context.Context, file string) (int, error) {
+	records, err := Read(file, r.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	done := readProgress(file)
+	sent := 0
+	for i := done; i < len(records); i++ {
+		if err = r.send(ctx, records[i]); err != nil {
+			_ = os.WriteFile(file+progressExt, []byte(strconv.Itoa(i)), 0o600)
+			return sent, fmt.Errorf("cannot replay request %d of %s: %w", i+1, file, err)
+		}
+		sent++
+	}
+
+	_ = os.Remove(file + progressExt)
+	if r.Remove {
+		_ = os.Remove(file + SignatureExt)
+		return sent, os.Remove(file)
+	}
+	if _, err = os.Stat(file + SignatureExt); err == nil {
+		_ = os.Rename(file+SignatureExt, file+ReplayedExt+SignatureExt)
+	}
+	return sent, os.Rename(file, file+ReplayedExt)
+}
+
+func (r *Replayer) send(ctx context.Context, record Record) error {
+	req, err := http.NewRequestWithContext(ctx, record.Method, record.URL, bytes.NewReader(record.Body))
+	if err != nil {
+		return err
+	}
+	if !r.Hosts[strings.ToLower(req.URL.Hostname())] {
+		return fmt.Errorf("host %s is not a New Relic endpoint", req.URL.Host)
+	}
+	for name, values := range record.Header {
+		req.Header[name] = values
+	}
+	for name, values := range r.Header {
+		req.Header[name] = values
+	}
+
+	resp, err := r.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unsuccessful response, status: %d [%s]", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// readProgress returns the number of records of the file replayed by a previous replay.
+func readProgress(file string) int {
+	content, err := os.ReadFile(file + progressExt)
+	if err != nil {
+		return 0
+	}
+	done, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || done < 0 {
+		return 0
+	}
+	return done
+}
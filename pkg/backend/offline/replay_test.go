@@ -0,0 +1,166 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package offline
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayer_Replay(t *testing.T) {
+	var received []string
+	failAt := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "license", r.Header.Get("X-License-Key"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if len(received) == failAt {
+			failAt = -1
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.URL.Path+" "+string(body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	a, err := NewArchive(ArchiveConfig{Dir: dir})
+	require.NoError(t, err)
+	require.NoError(t, a.Write(record(server.URL+"/metrics/events/bulk", "1")))
+	require.NoError(t, a.Write(record(server.URL+"/metrics/events/bulk", "2")))
+	require.NoError(t, a.Write(record(server.URL+"/inventory/deltas", "3")))
+	require.NoError(t, a.Close())
+	files, err := Files(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	r := &Replayer{
+		Do:            server.Client().Do,
+		Hosts:         map[string]bool{"127.0.0.1": true},
+		AllowUnsigned: true,
+		Header:        http.Header{"X-License-Key": []string{"license"}},
+	}
+	stats, err := r.Replay(context.Background(), dir)
+	assert.Error(t, err)
+	assert.Equal(t, ReplayStats{Files: 0, Requests: 2}, stats)
+	assert.FileExists(t, files[0]+progressExt)
+
+	// the next replay continues from the failed request
+	stats, err = r.Replay(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, ReplayStats{Files: 1, Requests: 1}, stats)
+	assert.Equal(t, []string{
+		"/metrics/events/bulk 1",
+		"/metrics/events/bulk 2",
+		"/inventory/deltas 3",
+	}, received)
+
+	assert.NoFileExists(t, files[0])
+	assert.NoFileExists(t, files[0]+progressExt)
+	assert.FileExists(t, files[0]+ReplayedExt)
+
+	// replayed files are not sent again
+	stats, err = r.Replay(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, ReplayStats{}, stats)
+}
+
+func TestReplayer_Remove(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	privateFile, publicFile := writeKeys(t)
+	publicKey, err := LoadPublicKey(publicFile)
+	require.NoError(t, err)
+	a, err := NewArchive(ArchiveConfig{Dir: dir, SigningKeyFile: privateFile})
+	require.NoError(t, err)
+	require.NoError(t, a.Write(record(server.URL+"/metrics/events/bulk", "{}")))
+	require.NoError(t, a.Close())
+
+	r := &Replayer{Do: server.Client().Do, Hosts: map[string]bool{"127.0.0.1": true}, PublicKey: publicKey, Remove: true}
+	stats, err := r.Replay(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, ReplayStats{Files: 1, Requests: 1}, stats)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestReplayer_InvalidSignature(t *testing.T) {
+	dir := t.TempDir()
+	_, publicFile := writeKeys(t)
+	publicKey, err := LoadPublicKey(publicFile)
+	require.NoError(t, err)
+
+	otherKey, _ := writeKeys(t)
+	a, err := NewArchive(ArchiveConfig{Dir: dir, SigningKeyFile: otherKey})
+	require.NoError(t, err)
+	require.NoError(t, a.Write(record("http://localhost/metrics/events/bulk", "{}")))
+	require.NoError(t, a.Close())
+
+	r := &Replayer{
+		Do: func(*http.Request) (*http.Response, error) {
+			t.Fatal("requests of files with invalid signatures must not be sent")
+			return nil, nil
+		},
+		PublicKey: publicKey,
+	}
+	_, err = r.Replay(context.Background(), dir)
+	assert.ErrorIs(t, err, errInvalidSignature)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+ArchiveExt))
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "the file is kept")
+}
+
+func TestReplayer_Unverified(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewArchive(ArchiveConfig{Dir: dir})
+	require.NoError(t, err)
+	require.NoError(t, a.Write(record("http://localhost/metrics/events/bulk", "{}")))
+	require.NoError(t, a.Close())
+
+	r := &Replayer{
+		Do: func(*http.Request) (*http.Response, error) {
+			t.Fatal("requests of unverified archives must not be sent")
+			return nil, nil
+		},
+		Hosts: map[string]bool{"localhost": true},
+	}
+	_, err = r.Replay(context.Background(), dir)
+	assert.ErrorIs(t, err, errUnverified)
+}
+
+func TestReplayer_RejectsOtherHosts(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewArchive(ArchiveConfig{Dir: dir})
+	require.NoError(t, err)
+	require.NoError(t, a.Write(record("https://attacker.example.com/collect", "{}")))
+	require.NoError(t, a.Close())
+
+	r := &Replayer{
+		Do: func(*http.Request) (*http.Response, error) {
+			t.Fatal("requests to other hosts must not be sent")
+			return nil, nil
+		},
+		Hosts:         map[string]bool{"infra-api.newrelic.com": true},
+		AllowUnsigned: true,
+		Header:        http.Header{"X-License-Key": []string{"license"}},
+	}
+	stats, err := r.Replay(context.Background(), dir)
+	assert.ErrorContains(t, err, "host attacker.example.com is not a New Relic endpoint")
+	assert.Equal(t, ReplayStats{}, stats)
+}
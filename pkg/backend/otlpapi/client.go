@@ -0,0 +1,202 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package otlpapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/encoding/gzip" // registers the gzip compressor
+	"google.golang.org/grpc/metadata"
+)
+
+// OTLP protocols, as named by the OTEL_EXPORTER_OTLP_PROTOCOL environment variable of the OpenTelemetry SDKs.
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+	ProtocolHTTPJSON     = "http/json"
+)
+
+const (
+	// metricsPath is appended to the HTTP endpoints without path.
+	metricsPath = "/v1/metrics"
+	// exportMethod is the gRPC method of the OTLP metrics service.
+	exportMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+)
+
+// Client exports metrics to an OTLP endpoint.
+type Client interface {
+	Export(ctx context.Context, batch Batch) error
+	Close() error
+}
+
+// Config of the OTLP client.
+type Config struct {
+	// Endpoint is a host:port for gRPC, or a URL for HTTP. HTTP URLs without path are sent to /v1/metrics.
+	Endpoint string
+	Protocol string
+	// Headers are sent in every export request, ie: for authentication.
+	Headers map[string]string
+	// Insecure disables TLS for gRPC connections. HTTP connections use TLS depending on the URL scheme.
+	Insecure bool
+}
+
+// NewClient returns the client for the configured protocol. HTTP requests are sent through the http client.
+func NewClient(cfg Config, httpClient backendhttp.Client) (Client, error) {
+	switch cfg.Protocol {
+	case ProtocolGRPC:
+		return newGRPCClient(cfg)
+	case ProtocolHTTPProtobuf, ProtocolHTTPJSON, "":
+		return newHTTPClient(cfg, httpClient)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q, must be %q, %q or %q",
+			cfg.Protocol, ProtocolGRPC, ProtocolHTTPProtobuf, ProtocolHTTPJSON)
+	}
+}
+
+type httpClient struct {
+	url     string
+	json    bool
+	headers map[string]string
+	client  backendhttp.Client
+}
+
+func newHTTPClient(cfg Config, client backendhttp.Client) (*httpClient, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: %v", cfg.Endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: the HTTP protocol requires an http or https URL", cfg.Endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = metricsPath
+	}
+
+	return &httpClient{
+		url:     u.String(),
+		json:    cfg.Protocol == ProtocolHTTPJSON,
+		headers: cfg.Headers,
+		client:  client,
+	}, nil
+}
+
+func (c *httpClient) Export(ctx context.Context, batch Batch) error {
+	contentType := "application/x-protobuf"
+	var payload []byte
+	if c.json {
+		contentType = "application/json"
+		var err error
+		if payload, err = MarshalJSON(batch); err != nil {
+			return fmt.Errorf("cannot encode OTLP request: %v", err)
+		}
+	} else {
+		payload = MarshalProto(batch)
+	}
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	if _, err := gz.Write(payload); err != nil {
+		return fmt.Errorf("cannot compress OTLP request: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("cannot compress OTLP request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, &body)
+	if err != nil {
+		return fmt.Errorf("cannot create OTLP request: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Encoding", "gzip")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client(req)
+	if err != nil {
+		return fmt.Errorf("error sending OTLP request: %v", err)
+	}
+	defer resp.Body.Close()
+	// read the body so the connection can be reused
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP request rejected with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+func (c *httpClient) Close() error {
+	return nil
+}
+
+type grpcClient struct {
+	conn    *grpc.ClientConn
+	headers metadata.MD
+}
+
+func newGRPCClient(cfg Config) (*grpcClient, error) {
+	creds := credentials.NewTLS(&tls.Config{})
+	if cfg.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	// the connection is established lazily, so an unavailable collector doesn't fail the agent start
+	conn, err := grpc.Dial(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: %v", cfg.Endpoint, err)
+	}
+	return &grpcClient{conn: conn, headers: metadata.New(cfg.Headers)}, nil
+}
+
+func (c *grpcClient) Export(ctx context.Context, batch Batch) error {
+	ctx = metadata.NewOutgoingContext(ctx, c.headers)
+	var resp []byte
+	err := c.conn.Invoke(ctx, exportMethod, MarshalProto(batch), &resp,
+		grpc.ForceCodec(rawCodec{}), grpc.UseCompressor("gzip"))
+	if err != nil {
+		return fmt.Errorf("error sending OTLP request: %v", err)
+	}
+	return nil
+}
+
+func (c *grpcClient) Close() error {
+	return c.conn.Close()
+}
+
+// rawCodec sends the already encoded protobuf messages through gRPC.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name is the content subtype of the requests, the one expected by the collectors.
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package otlpapi
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHTTPClient_Export(t *testing.T) {
+	tests := map[string]struct {
+		protocol    string
+		contentType string
+		marshal     func(Batch) []byte
+	}{
+		"protobuf": {ProtocolHTTPProtobuf, "application/x-protobuf", MarshalProto},
+		"json": {ProtocolHTTPJSON, "application/json", func(b Batch) []byte {
+			payload, _ := MarshalJSON(b)
+			return payload
+		}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var req *http.Request
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req = r
+				gz, err := gzip.NewReader(r.Body)
+				require.NoError(t, err)
+				body, err = ioutil.ReadAll(gz)
+				require.NoError(t, err)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(Config{
+				Endpoint: server.URL,
+				Protocol: tc.protocol,
+				Headers:  map[string]string{"api-key": "secret"},
+			}, http.DefaultClient.Do)
+			require.NoError(t, err)
+
+			require.NoError(t, client.Export(context.Background(), testBatch))
+			assert.Equal(t, "/v1/metrics", req.URL.Path)
+			assert.Equal(t, tc.contentType, req.Header.Get("Content-Type"))
+			assert.Equal(t, "gzip", req.Header.Get("Content-Encoding"))
+			assert.Equal(t, "secret", req.Header.Get("api-key"))
+			assert.Equal(t, tc.marshal(testBatch), body)
+		})
+	}
+}
+
+func TestHTTPClient_ExportCustomPath(t *testing.T) {
+	var path string
+	client, err := NewClient(Config{Endpoint: "https://otlp.example.com/otlp/v1/metrics"}, func(req *http.Request) (*http.Response, error) {
+		path = req.URL.Path
+		return httptest.NewRecorder().Result(), nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Export(context.Background(), testBatch))
+	assert.Equal(t, "/otlp/v1/metrics", path)
+}
+
+func TestHTTPClient_ExportRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Endpoint: server.URL, Protocol: ProtocolHTTPProtobuf}, backendhttp.Client(http.DefaultClient.Do))
+	require.NoError(t, err)
+
+	err = client.Export(context.Background(), testBatch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+	assert.Contains(t, err.Error(), "invalid payload")
+}
+
+func TestNewClient_InvalidConfig(t *testing.T) {
+	_, err := NewClient(Config{Endpoint: "localhost:4318", Protocol: ProtocolHTTPProtobuf}, http.DefaultClient.Do)
+	assert.Error(t, err)
+
+	_, err = NewClient(Config{Endpoint: "http://localhost:4318", Protocol: "thrift"}, http.DefaultClient.Do)
+	assert.Error(t, err)
+}
+
+func TestGRPCClient_Export(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	type call struct {
+		method  string
+		request []byte
+		headers metadata.MD
+	}
+	calls := make(chan call, 1)
+	server := grpc.NewServer(
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+			var request []byte
+			if err := stream.RecvMsg(&request); err != nil {
+				return err
+			}
+			method, _ := grpc.MethodFromServerStream(stream)
+			headers, _ := metadata.FromIncomingContext(stream.Context())
+			calls <- call{method: method, request: request, headers: headers}
+			return stream.SendMsg([]byte{})
+		}),
+	)
+	go func() { _ = server.Serve(l) }()
+	defer server.Stop()
+
+	client, err := NewClient(Config{
+		Endpoint: l.Addr().String(),
+		Protocol: ProtocolGRPC,
+		Headers:  map[string]string{"api-key": "secret"},
+		Insecure: true,
+	}, nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.Export(context.Background(), testBatch))
+	c := <-calls
+	assert.Equal(t, exportMethod, c.method)
+	assert.Equal(t, MarshalProto(testBatch), c.request)
+	assert.Equal(t, []string{"secret"}, c.headers.Get("api-key"))
+}
@@ -0,0 +1,226 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package otlpapi exports metrics to OpenTelemetry collectors through the OTLP protocol, over gRPC or HTTP. The
+// export requests are encoded by hand, as the agent only sends gauges and doesn't need the generated OTLP types.
+package otlpapi
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Gauge is a data point of a gauge metric.
+type Gauge struct {
+	Name       string
+	Attributes map[string]string
+	Value      float64
+	Time       time.Time
+}
+
+// Batch is a set of gauges sharing the same resource, exported in a single request.
+type Batch struct {
+	// Resource attributes describe the entity producing the metrics, ie: service.name or host.name.
+	Resource map[string]string
+	// ScopeName and ScopeVersion identify the instrumentation producing the metrics.
+	ScopeName    string
+	ScopeVersion string
+	Gauges       []Gauge
+}
+
+// metricPoints groups the batch data points by metric name, keeping the order of the first appearance.
+func (b Batch) metricPoints() (names []string, points map[string][]Gauge) {
+	points = make(map[string][]Gauge)
+	for _, g := range b.Gauges {
+		if _, ok := points[g.Name]; !ok {
+			names = append(names, g.Name)
+		}
+		points[g.Name] = append(points[g.Name], g)
+	}
+	return names, points
+}
+
+// Field numbers of the OTLP messages, from opentelemetry/proto/collector/metrics/v1/metrics_service.proto and the
+// imported common, resource and metrics protos.
+const (
+	fieldRequestResourceMetrics  = 1 // ExportMetricsServiceRequest.resource_metrics
+	fieldResourceMetricsResource = 1 // ResourceMetrics.resource
+	fieldResourceMetricsScope    = 2 // ResourceMetrics.scope_metrics
+	fieldResourceAttributes      = 1 // Resource.attributes
+	fieldScopeMetricsScope       = 1 // ScopeMetrics.scope
+	fieldScopeMetricsMetrics     = 2 // ScopeMetrics.metrics
+	fieldScopeName               = 1 // InstrumentationScope.name
+	fieldScopeVersion            = 2 // InstrumentationScope.version
+	fieldMetricName              = 1 // Metric.name
+	fieldMetricGauge             = 5 // Metric.gauge
+	fieldGaugeDataPoints         = 1 // Gauge.data_points
+	fieldPointTime               = 3 // NumberDataPoint.time_unix_nano
+	fieldPointAsDouble           = 4 // NumberDataPoint.as_double
+	fieldPointAttributes         = 7 // NumberDataPoint.attributes
+	fieldKeyValueKey             = 1 // KeyValue.key
+	fieldKeyValueValue           = 2 // KeyValue.value
+	fieldAnyValueString          = 1 // AnyValue.string_value
+)
+
+// MarshalProto encodes the batch as an OTLP ExportMetricsServiceRequest protobuf message.
+func MarshalProto(b Batch) []byte {
+	var scope []byte
+	scope = appendString(scope, fieldScopeName, b.ScopeName)
+	scope = appendString(scope, fieldScopeVersion, b.ScopeVersion)
+
+	var scopeMetrics []byte
+	scopeMetrics = appendMessage(scopeMetrics, fieldScopeMetricsScope, scope)
+	names, points := b.metricPoints()
+	for _, name := range names {
+		var gauge []byte
+		for _, p := range points[name] {
+			var point []byte
+			point = protowire.AppendTag(point, fieldPointTime, protowire.Fixed64Type)
+			point = protowire.AppendFixed64(point, uint64(p.Time.UnixNano()))
+			point = protowire.AppendTag(point, fieldPointAsDouble, protowire.Fixed64Type)
+			point = protowire.AppendFixed64(point, math.Float64bits(p.Value))
+			point = appendAttributes(point, fieldPointAttributes, p.Attributes)
+			gauge = appendMessage(gauge, fieldGaugeDataPoints, point)
+		}
+
+		var metric []byte
+		metric = appendString(metric, fieldMetricName, name)
+		metric = appendMessage(metric, fieldMetricGauge, gauge)
+		scopeMetrics = appendMessage(scopeMetrics, fieldScopeMetricsMetrics, metric)
+	}
+
+	var resource []byte
+	resource = appendAttributes(resource, fieldResourceAttributes, b.Resource)
+
+	var resourceMetrics []byte
+	resourceMetrics = appendMessage(resourceMetrics, fieldResourceMetricsResource, resource)
+	resourceMetrics = appendMessage(resourceMetrics, fieldResourceMetricsScope, scopeMetrics)
+
+	return appendMessage(nil, fieldRequestResourceMetrics, resourceMetrics)
+}
+
+func appendString(b []byte, field protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendMessage(b []byte, field protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+// appendAttributes encodes the attributes as repeated KeyValue messages with string values, sorted by key.
+func appendAttributes(b []byte, field protowire.Number, attributes map[string]string) []byte {
+	for _, key := range sortedKeys(attributes) {
+		var value []byte
+		value = protowire.AppendTag(value, fieldAnyValueString, protowire.BytesType)
+		value = protowire.AppendString(value, attributes[key])
+
+		var kv []byte
+		kv = appendString(kv, fieldKeyValueKey, key)
+		kv = appendMessage(kv, fieldKeyValueValue, value)
+		b = appendMessage(b, field, kv)
+	}
+	return b
+}
+
+// OTLP JSON encoding, as described in https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type jsonRequest struct {
+	ResourceMetrics []jsonResourceMetrics `json:"resourceMetrics"`
+}
+
+type jsonResourceMetrics struct {
+	Resource     jsonResource       `json:"resource"`
+	ScopeMetrics []jsonScopeMetrics `json:"scopeMetrics"`
+}
+
+type jsonResource struct {
+	Attributes []jsonKeyValue `json:"attributes"`
+}
+
+type jsonScopeMetrics struct {
+	Scope   jsonScope    `json:"scope"`
+	Metrics []jsonMetric `json:"metrics"`
+}
+
+type jsonScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type jsonMetric struct {
+	Name  string    `json:"name"`
+	Gauge jsonGauge `json:"gauge"`
+}
+
+type jsonGauge struct {
+	DataPoints []jsonDataPoint `json:"dataPoints"`
+}
+
+type jsonDataPoint struct {
+	Attributes []jsonKeyValue `json:"attributes,omitempty"`
+	// 64 bits integers are encoded as strings
+	TimeUnixNano string  `json:"timeUnixNano"`
+	AsDouble     float64 `json:"asDouble"`
+}
+
+type jsonKeyValue struct {
+	Key   string    `json:"key"`
+	Value jsonValue `json:"value"`
+}
+
+type jsonValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// MarshalJSON encodes the batch as an OTLP ExportMetricsServiceRequest JSON message.
+func MarshalJSON(b Batch) ([]byte, error) {
+	scopeMetrics := jsonScopeMetrics{
+		Scope:   jsonScope{Name: b.ScopeName, Version: b.ScopeVersion},
+		Metrics: []jsonMetric{},
+	}
+	names, points := b.metricPoints()
+	for _, name := range names {
+		metric := jsonMetric{Name: name}
+		for _, p := range points[name] {
+			metric.Gauge.DataPoints = append(metric.Gauge.DataPoints, jsonDataPoint{
+				Attributes:   jsonAttributes(p.Attributes),
+				TimeUnixNano: strconv.FormatInt(p.Time.UnixNano(), 10),
+				AsDouble:     p.Value,
+			})
+		}
+		scopeMetrics.Metrics = append(scopeMetrics.Metrics, metric)
+	}
+
+	return json.Marshal(jsonRequest{
+		ResourceMetrics: []jsonResourceMetrics{{
+			Resource:     jsonResource{Attributes: jsonAttributes(b.Resource)},
+			ScopeMetrics: []jsonScopeMetrics{scopeMetrics},
+		}},
+	})
+}
+
+func jsonAttributes(attributes map[string]string) []jsonKeyValue {
+	kvs := []jsonKeyValue{}
+	for _, key := range sortedKeys(attributes) {
+		kvs = append(kvs, jsonKeyValue{Key: key, Value: jsonValue{StringValue: attributes[key]}})
+	}
+	return kvs
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
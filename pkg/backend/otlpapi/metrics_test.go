@@ -0,0 +1,120 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package otlpapi
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var testTime = time.Unix(1700000000, 0)
+
+var testBatch = Batch{
+	Resource:     map[string]string{"service.name": "newrelic-infra", "host.name": "my-host"},
+	ScopeName:    "newrelic-infra",
+	ScopeVersion: "1.2.3",
+	Gauges: []Gauge{
+		{Name: "ProcessSample.cpuPercent", Attributes: map[string]string{"processDisplayName": "nginx"}, Value: 1.5, Time: testTime},
+		{Name: "SystemSample.memoryUsedPercent", Value: 42, Time: testTime},
+		{Name: "ProcessSample.cpuPercent", Attributes: map[string]string{"processDisplayName": "sshd"}, Value: 0.5, Time: testTime},
+	},
+}
+
+// protoFields decodes a protobuf message into its fields, by field number, for the length delimited and fixed64
+// fields used by the OTLP metrics messages.
+func protoFields(t *testing.T, b []byte) map[protowire.Number][][]byte {
+	t.Helper()
+	fields := make(map[protowire.Number][][]byte)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+
+		var value []byte
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		case protowire.Fixed64Type:
+			var v uint64
+			v, n = protowire.ConsumeFixed64(b)
+			value = protowire.AppendFixed64(nil, v)
+		default:
+			t.Fatalf("unexpected wire type %v", typ)
+		}
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		fields[num] = append(fields[num], value)
+	}
+	return fields
+}
+
+func protoAttributes(t *testing.T, kvs [][]byte) map[string]string {
+	attributes := make(map[string]string)
+	for _, kv := range kvs {
+		f := protoFields(t, kv)
+		value := protoFields(t, f[fieldKeyValueValue][0])
+		attributes[string(f[fieldKeyValueKey][0])] = string(value[fieldAnyValueString][0])
+	}
+	return attributes
+}
+
+func TestMarshalProto(t *testing.T) {
+	request := protoFields(t, MarshalProto(testBatch))
+	require.Len(t, request[fieldRequestResourceMetrics], 1)
+	resourceMetrics := protoFields(t, request[fieldRequestResourceMetrics][0])
+
+	resource := protoFields(t, resourceMetrics[fieldResourceMetricsResource][0])
+	assert.Equal(t, testBatch.Resource, protoAttributes(t, resource[fieldResourceAttributes]))
+
+	require.Len(t, resourceMetrics[fieldResourceMetricsScope], 1)
+	scopeMetrics := protoFields(t, resourceMetrics[fieldResourceMetricsScope][0])
+	scope := protoFields(t, scopeMetrics[fieldScopeMetricsScope][0])
+	assert.Equal(t, "newrelic-infra", string(scope[fieldScopeName][0]))
+	assert.Equal(t, "1.2.3", string(scope[fieldScopeVersion][0]))
+
+	// data points are grouped by metric
+	require.Len(t, scopeMetrics[fieldScopeMetricsMetrics], 2)
+	metric := protoFields(t, scopeMetrics[fieldScopeMetricsMetrics][0])
+	assert.Equal(t, "ProcessSample.cpuPercent", string(metric[fieldMetricName][0]))
+	gauge := protoFields(t, metric[fieldMetricGauge][0])
+	require.Len(t, gauge[fieldGaugeDataPoints], 2)
+
+	point := protoFields(t, gauge[fieldGaugeDataPoints][1])
+	timestamp, _ := protowire.ConsumeFixed64(point[fieldPointTime][0])
+	assert.Equal(t, uint64(testTime.UnixNano()), timestamp)
+	value, _ := protowire.ConsumeFixed64(point[fieldPointAsDouble][0])
+	assert.Equal(t, 0.5, math.Float64frombits(value))
+	assert.Equal(t, map[string]string{"processDisplayName": "sshd"}, protoAttributes(t, point[fieldPointAttributes]))
+
+	metric = protoFields(t, scopeMetrics[fieldScopeMetricsMetrics][1])
+	assert.Equal(t, "SystemSample.memoryUsedPercent", string(metric[fieldMetricName][0]))
+}
+
+func TestMarshalJSON(t *testing.T) {
+	payload, err := MarshalJSON(testBatch)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"resourceMetrics":[{
+		"resource":{"attributes":[
+			{"key":"host.name","value":{"stringValue":"my-host"}},
+			{"key":"service.name","value":{"stringValue":"newrelic-infra"}}
+		]},
+		"scopeMetrics":[{
+			"scope":{"name":"newrelic-infra","version":"1.2.3"},
+			"metrics":[
+				{"name":"ProcessSample.cpuPercent","gauge":{"dataPoints":[
+					{"attributes":[{"key":"processDisplayName","value":{"stringValue":"nginx"}}],"timeUnixNano":"1700000000000000000","asDouble":1.5},
+					{"attributes":[{"key":"processDisplayName","value":{"stringValue":"sshd"}}],"timeUnixNano":"1700000000000000000","asDouble":0.5}
+				]}},
+				{"name":"SystemSample.memoryUsedPercent","gauge":{"dataPoints":[
+					{"timeUnixNano":"1700000000000000000","asDouble":42}
+				]}}
+			]
+		}]
+	}]}`, string(payload))
+}
@@ -14,5 +14,6 @@ func init() { //nolint:gochecknoinits
 	}
 	defaultAgentDir = filepath.Join("/opt", "homebrew", "var", "db", "newrelic-infra")
 	defaultSafeBinDir = defaultAgentDir
+	DefaultControlSocket = filepath.Join(defaultAgentDir, "newrelic-infra.sock")
 	defaultAgentTempDir = os.TempDir()
 }
@@ -32,6 +32,7 @@ func init() {
 
 	defaultAgentDir = filepath.Join("/var", "db", "newrelic-infra")
 	defaultSafeBinDir = filepath.Join("/opt", "newrelic-infra")
+	DefaultControlSocket = filepath.Join("/var", "run", "newrelic-infra", "newrelic-infra.sock")
 	defaultLogFile = filepath.Join("/var", "db", "newrelic-infra", "newrelic-infra.log")
 	defaultNetworkInterfaceFilters = map[string][]string{
 		"prefix":  {"dummy", "lo", "vmnet", "sit", "tun", "tap", "veth"},
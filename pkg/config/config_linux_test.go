@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	. "gopkg.in/check.v1"
 )
 
@@ -140,6 +141,7 @@ log:
     max_files: 20
     compression_enabled: true
     file_pattern: pattern.log
+    max_age_days: 7
     
 `
 	f, err := ioutil.TempFile("", "default_config_test")
@@ -160,6 +162,40 @@ log:
 	assert.Equal(t, 20, cfg.Log.Rotate.MaxFiles)
 	assert.Equal(t, true, cfg.Log.Rotate.CompressionEnabled)
 	assert.Equal(t, "pattern.log", cfg.Log.Rotate.FilePattern)
+	assert.Equal(t, 7, cfg.Log.Rotate.MaxAgeDays)
+}
+
+func TestRotateConfig_Containerized(t *testing.T) {
+	configStr := `
+license_key: abc123
+is_containerized: true
+log:
+  file: /host/var/log/newrelic-infra.log
+  level: info
+`
+	f, err := ioutil.TempFile("", "default_config_test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(configStr)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// containers usually lack logrotate, so the log is rotated by default
+	cfg, err := LoadConfig(f.Name())
+	require.NoError(t, err)
+	require.NotNil(t, cfg.Log.Rotate.MaxSizeMb)
+	assert.Equal(t, 100, *cfg.Log.Rotate.MaxSizeMb)
+	assert.Equal(t, 5, cfg.Log.Rotate.MaxFiles)
+	assert.True(t, cfg.Log.Rotate.CompressionEnabled)
+
+	// unless the rotation is explicitly disabled
+	configStr += `  rotate:
+    max_size_mb: 0
+`
+	require.NoError(t, ioutil.WriteFile(f.Name(), []byte(configStr), 0o600))
+	cfg, err = LoadConfig(f.Name())
+	require.NoError(t, err)
+	assert.False(t, cfg.Log.Rotate.IsEnabled())
 }
 
 func Test_ParseLogConfigRule_EnvVar(t *testing.T) {
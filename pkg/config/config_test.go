@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"testing"
@@ -358,6 +359,7 @@ agent_dir: /my/overriden/agent/dir
 	c.Assert(cfg.DockerApiVersion, Equals, DefaultDockerApiVersion)
 	c.Assert(cfg.DockerContainerdNamespace, Equals, DefaultDockerContainerdNamespace)
 	c.Assert(cfg.SelinuxEnableSemodule, Equals, defaultSelinuxEnableSemodule)
+	c.Assert(cfg.KernelBootEnabled, Equals, defaultKernelBootEnabled)
 	c.Assert(cfg.DnsHostnameResolution, Equals, defaultDnsHostnameResolution)
 	c.Assert(cfg.IgnoreReclaimable, Equals, defaultIgnoreReclaimable)
 	c.Assert(cfg.ProxyValidateCerts, Equals, defaultProxyValidateCerts)
@@ -673,6 +675,26 @@ verbose: 0
 	assert.Equal(t, LogLevelInfo, cfg.Log.Level)
 }
 
+func TestLoadYamlConfig_offlineExportDisablesConnect(t *testing.T) {
+	yamlData := []byte(`
+license_key: "xxx"
+connect_enabled: true
+offline_export_dir: /var/db/newrelic-infra/export
+`)
+
+	tmp, err := createTestFile(yamlData)
+	require.NoError(t, err)
+	defer os.Remove(tmp.Name())
+
+	cfg, err := LoadConfig(tmp.Name())
+	require.NoError(t, err)
+
+	assert.False(t, cfg.ConnectEnabled)
+	assert.Equal(t, defaultMetricsIngestEndpoint, cfg.MetricsIngestEndpoint)
+	assert.Equal(t, defaultOfflineExportMaxFileSize, cfg.OfflineExportMaxFileSize)
+	assert.Equal(t, defaultOfflineExportRotateSec, cfg.OfflineExportRotateSec)
+}
+
 func TestLoadYamlConfig_withLogVariables(t *testing.T) {
 	yamlData := []byte(`
 log:
@@ -890,6 +912,88 @@ ntp_metrics:
 	}
 }
 
+func TestLoadOTLPExportConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		yamlCfg  string
+		expected OTLPExportConfig
+	}{
+		{
+			name:     "Default",
+			yamlCfg:  `license_key: abc123`,
+			expected: NewOTLPExportConfig(),
+		},
+		{
+			name: "Custom",
+			yamlCfg: `
+license_key: abc123
+otlp_export:
+  endpoint: collector:4317
+  protocol: grpc
+  headers:
+    api-key: secret
+  insecure: true
+  exclusive: true
+  interval: 60
+`,
+			expected: OTLPExportConfig{
+				Endpoint:  "collector:4317",
+				Protocol:  "grpc",
+				Headers:   KeyValMap{"api-key": "secret"},
+				Insecure:  true,
+				Exclusive: true,
+				Interval:  60,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			tmp, err := createTestFile([]byte(testCase.yamlCfg))
+			require.NoError(t, err)
+			defer os.Remove(tmp.Name())
+
+			cfg, err := LoadConfig(tmp.Name())
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, cfg.OTLPExport)
+			assert.Equal(t, testCase.expected.Endpoint != "", cfg.OTLPExport.IsEnabled())
+		})
+	}
+}
+
+func TestLoadSubmissionRoutesConfig(t *testing.T) {
+	yamlData := []byte(`
+license_key: abc123
+submission_routes:
+  - name: security
+    license_key: sec123
+    proxy: direct
+    event_types: [SecurityEvent]
+  - name: security
+    license_key: other
+    event_types: [OtherEvent]
+  - name: mysql
+    license_key: db123
+    integrations: [com.newrelic.mysql]
+  - name: no-license
+    event_types: [SystemSample]
+  - name: no-matchers
+    license_key: abc
+`)
+
+	tmp, err := createTestFile(yamlData)
+	require.NoError(t, err)
+	defer os.Remove(tmp.Name())
+
+	cfg, err := LoadConfig(tmp.Name())
+	require.NoError(t, err)
+
+	assert.Equal(t, []SubmissionRoute{
+		{Name: "security", License: "sec123", Proxy: ProxyDirect, EventTypes: []string{"SecurityEvent"}},
+		{Name: "mysql", License: "db123", Integrations: []string{"com.newrelic.mysql"}},
+	}, cfg.SubmissionRoutes)
+}
+
 func TestLoadYamlConfig_withDatabindAndEnvVars(t *testing.T) {
 	yamlData := []byte(`
 variables:
@@ -1127,6 +1231,22 @@ license_key: "xxx"
 	}
 }
 
+func TestNewLogForward_Buffer(t *testing.T) {
+	cfg := NewConfig()
+	cfg.LoggingHomeDir = "/var/db/newrelic-infra/newrelic-integrations/logging"
+	assert.Equal(t, LogForwardBuffer{}, NewLogForward(cfg, Troubleshoot{}).Buffer)
+
+	cfg.LoggingBufferEnabled = true
+	assert.Equal(t, LogForwardBuffer{
+		Dir:       filepath.Join(cfg.LoggingHomeDir, "buffer"),
+		MaxSizeMB: 1024,
+		MemoryMB:  16,
+	}, NewLogForward(cfg, Troubleshoot{}).Buffer)
+
+	cfg.LoggingBufferDir = "/mnt/logs-buffer"
+	assert.Equal(t, "/mnt/logs-buffer", NewLogForward(cfg, Troubleshoot{}).Buffer.Dir)
+}
+
 func createTestFile(data []byte) (*os.File, error) {
 	tmp, err := ioutil.TempFile("", "loadconfig")
 	if err != nil {
@@ -1139,3 +1259,24 @@ func createTestFile(data []byte) (*os.File, error) {
 	tmp.Close()
 	return tmp, nil
 }
+
+func TestLoadedConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	defaultFile := filepath.Join(dir, "default.yml")
+	providedFile := filepath.Join(dir, "provided.yml")
+	require.NoError(t, os.WriteFile(defaultFile, nil, 0o600))
+
+	prev := defaultConfigFiles
+	defaultConfigFiles = []string{filepath.Join(dir, "missing.yml"), defaultFile}
+	defer func() { defaultConfigFiles = prev }()
+
+	// as LoadConfig, missing files are skipped
+	assert.Equal(t, defaultFile, LoadedConfigFile(""))
+	assert.Equal(t, defaultFile, LoadedConfigFile(providedFile))
+
+	require.NoError(t, os.WriteFile(providedFile, nil, 0o600))
+	assert.Equal(t, providedFile, LoadedConfigFile(providedFile))
+
+	defaultConfigFiles = nil
+	assert.Equal(t, "", LoadedConfigFile(""))
+}
@@ -58,6 +58,7 @@ func init() {
 	defaultConfigDir = defaultAgentDir
 	defaultLogFile = filepath.Join(defaultAgentDir, "newrelic-infra.log")
 	defaultPluginInstanceDir = filepath.Join(defaultAgentDir, "integrations.d")
+	DefaultControlSocket = `\\.\pipe\newrelic-infra-ctl`
 
 	defaultConfigFiles = []string{filepath.Join(defaultAgentDir, "newrelic-infra.yml")}
 	defaultPluginConfigFiles = []string{filepath.Join(defaultAgentDir, "newrelic-infra-plugins.yml")}
@@ -53,11 +53,14 @@ var (
 	DefaultSmartVerboseModeEntryLimit  = 1000
 	DefaultIntegrationsDir             = "newrelic-integrations"
 	DefaultInventoryQueue              = 0
+	DefaultControlSocket               string // set per OS
 
 	// private
 	defaultAppDataDir                    = ""
 	defaultCmdChannelEndpoint            = "/agent_commands/v1/commands"
 	defaultCmdChannelIntervalSec         = 60
+	defaultCmdChannelDiagnosticsEnabled  = false
+	defaultRemoteConfigIntervalSec       = 300
 	defaultInventoryArchiveEnabled       = true
 	defaultCompactEnabled                = true
 	defaultCompactThreshold              = 20 * 1024 * 1024 // (in bytes) compact repo when it hits 20MB
@@ -73,18 +76,54 @@ var (
 	defaultHTTPServerPort                = 8001
 	defaultTCPServerPort                 = 8002
 	defaultStatusServerPort              = 8003
+	defaultWebhookPort                   = 8004
+	defaultPrometheusExporterHost        = "localhost"
+	defaultPrometheusExporterPort        = 8005
+	defaultOfflineExportMaxFileSize      = 10 * 1024 * 1024 // (in bytes) rotate archive files when they hit 10MB
+	defaultOfflineExportRotateSec        = 60 * 60
 	defaultIpData                        = true
 	defaultTruncTextValues               = true
+	defaultEventDedupeTypes              = []string{"InfrastructureEvent"}
+	defaultProcessTopNCoveragePercent    = 90
+	defaultHostHeartbeatIntervalSec      = 30
 	defaultLogToStdout                   = true
 	defaultLogFormat                     = LogFormatText
 	defaultLogLevel                      = LogLevelInfo
 	defaultLogForward                    = false
+	defaultLogDedupeIntervalSec          = 5 * 60
+	defaultContainerLogMaxSizeMb         = 100
+	defaultContainerLogMaxFiles          = 5
 	defaultLoggingRetryLimit             = "5"         // nolint:gochecknoglobals
 	defaultMaxInventorySize              = 1000 * 1000 // Size limit from Vortex collector service (1MB)
 	defaultPayloadCompressionLevel       = 6           // default compression level used in go, higher than this does not show tangible benefits
+	defaultPayloadCompression            = payloadCompressionGzip
+	payloadCompressionGzip               = "gzip"
+	payloadCompressionZstd               = "zstd"
+	defaultPayloadSpoolMaxAgeSec         = 24 * 60 * 60 // 1 day
+	defaultPayloadSpoolDir               = "spool"
+	defaultFeatureFlagsFile              = "feature_flags.yml"
+	defaultShutdownFlushTimeoutSec       = 10
+	defaultCustomAttributesRefreshSec    = 300
+	defaultK8sMetadataEnabled            = true
+	defaultMaintenancePolicy             = MaintenancePolicyTag
+	defaultMetricsSampleJitterPercent    = 10
+	defaultGovernorIntervalSec           = 15
+	defaultLeaderElectionIntervalSec     = 15
+	defaultQuarantineThreshold           = 5
+	defaultQuarantineSec                 = 3600
+	defaultLoggingBufferDir              = "buffer"
+	defaultLoggingBufferMaxSizeMB        = 1024
+	defaultLoggingBufferMemoryMB         = 16
+	defaultRedactionEnabled              = false
 	defaultPidFile                       = "/var/run/newrelic-infra/newrelic-infra.pid"
+	defaultControlSocketEnabled          = false
+	defaultWinServiceSampleRate          = FREQ_DISABLE_SAMPLING
+	defaultSystemdUnitSampleRate         = FREQ_DISABLE_SAMPLING
+	defaultContainerSampleRate           = FREQ_DISABLE_SAMPLING
+	defaultSecurityModuleSampleRate      = FREQ_DISABLE_SAMPLING
 	defaultPluginActiveConfigsDir        = "integrations.d"
 	defaultSelinuxEnableSemodule         = true
+	defaultKernelBootEnabled             = true
 	defaultStartupConnectionTimeout      = "10s"
 	defaultPartitionsTTL                 = "60s" // TTL for the partitions cache, to avoid polling continuously for them
 	defaultStartupConnectionRetries      = 6     // -1 will try forever with an exponential backoff algorithm
@@ -96,6 +135,8 @@ var (
 	defaultIdentityIngestEndpoint        = "/identity/v1"      // default: V1 endpoint root (/connect, /register/batch)
 	defaultMetricsIngestV2Endpoint       = "/infra/v2/metrics" // default: V2 endpoint root (/events/bulk), combine this with defaultCollectorURL
 	defaultFingerprintUpdateFreqSec      = 60                  // Default update freq of the fingerprint in seconds.
+	defaultHostIdentitySources           = []string{"cloud_instance_id", "dmi_uuid", "machine_id"}
+	defaultCloneDetectionEnabled         = false
 	defaultCloudProvider                 = ""
 	defaultCloudMaxRetryCount            = 10
 	defaultCloudRetryBackOffSec          = 60  // In seconds.
@@ -113,6 +154,8 @@ var (
 	defaultNtpEnabled                    = false
 	defaultNtpInterval                   = uint(15) // minutes
 	defaultNtpTimeout                    = uint(5)  // seconds
+	defaultOTLPExportProtocol            = "http/protobuf"
+	defaultOTLPExportInterval            = uint(15) // seconds
 )
 
 // Default internal values
@@ -0,0 +1,134 @@
+// Copyright 2023 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/license"
+)
+
+// New Relic regions the agent can report to.
+const (
+	regionUS      = "US"
+	regionEU      = "EU"
+	regionFedRAMP = "FedRAMP"
+)
+
+const newRelicDomain = ".newrelic.com"
+
+// endpointRegion is the New Relic region and environment an endpoint belongs to.
+type endpointRegion struct {
+	region  string
+	staging bool
+}
+
+// parseEndpointRegion returns the region of a New Relic endpoint URL. Other hosts, like on-premise proxies
+// forwarding to New Relic, cannot be validated so false is returned.
+func parseEndpointRegion(endpoint string) (endpointRegion, bool) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpointRegion{}, false
+	}
+	host := strings.ToLower(u.Hostname())
+	if !strings.HasSuffix(host, newRelicDomain) {
+		return endpointRegion{}, false
+	}
+
+	r := endpointRegion{region: regionUS}
+	if strings.HasPrefix(host, "staging-") {
+		r.staging = true
+		host = strings.TrimPrefix(host, "staging-")
+	}
+	switch {
+	case strings.HasPrefix(host, "gov-"):
+		r.region = regionFedRAMP
+	case strings.HasSuffix(host, ".eu"+newRelicDomain):
+		r.region = regionEU
+	}
+	return r, true
+}
+
+// licenseRegion returns the region the license key and the fedramp setting point to.
+func licenseRegion(licenseKey string, fedramp bool) string {
+	switch {
+	case fedramp:
+		return regionFedRAMP
+	case license.IsRegionEU(licenseKey):
+		return regionEU
+	}
+	return regionUS
+}
+
+// regionSetting describes the setting leading to the expected region.
+func regionSetting(region string) string {
+	switch region {
+	case regionFedRAMP:
+		return "with fedramp enabled"
+	case regionEU:
+		return "with an EU license key"
+	}
+	return "with a US license key"
+}
+
+// validateRegion checks the New Relic endpoints match the license key region and the staging and fedramp
+// settings. Mismatches, like an EU license key with US endpoints, make the agent time out or get its data
+// rejected, so they are reported upfront.
+func validateRegion(cfg *Config) (warnings []ConfigWarning) {
+	expected := licenseRegion(cfg.License, cfg.Fedramp)
+	if cfg.Fedramp && license.IsRegionEU(cfg.License) {
+		warnings = append(warnings, ConfigWarning{
+			Key:     "fedramp",
+			Message: "FedRAMP endpoints don't accept EU license keys",
+		})
+		expected = regionEU
+	}
+
+	endpoints := []struct {
+		key string
+		url string
+	}{
+		{"collector_url", cfg.CollectorURL},
+		{"identity_url", cfg.IdentityURL},
+		{"command_channel_url", cfg.CommandChannelURL},
+		{"metric_url", cfg.MetricURL},
+	}
+	checked := map[string]bool{}
+	for _, e := range endpoints {
+		// the metric URL defaults to the collector one
+		if checked[e.url] {
+			continue
+		}
+		checked[e.url] = true
+		r, ok := parseEndpointRegion(e.url)
+		if !ok {
+			continue
+		}
+
+		want := expected
+		// there are no FedRAMP staging endpoints
+		if r.staging && want == regionFedRAMP {
+			want = regionUS
+		}
+		if r.region != want {
+			warnings = append(warnings, ConfigWarning{
+				Key: e.key,
+				Message: fmt.Sprintf("%s endpoint %s used %s, the agent won't be able to report, remove the "+
+					"option to use the default endpoint", r.region, e.url, regionSetting(want)),
+			})
+		}
+		if r.staging != cfg.Staging {
+			env, want := "production", "staging"
+			if r.staging {
+				env, want = want, env
+			}
+			warnings = append(warnings, ConfigWarning{
+				Key:     e.key,
+				Message: fmt.Sprintf("%s endpoint %s used in a %s environment", env, e.url, want),
+			})
+		}
+	}
+	return warnings
+}
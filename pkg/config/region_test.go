@@ -0,0 +1,124 @@
+// Copyright 2023 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	usLicense = "0123456789012345678901234567890123456789"
+	euLicense = "eu01xx6789012345678901234567890123456789"
+)
+
+func TestParseEndpointRegion(t *testing.T) {
+	tests := []struct {
+		url     string
+		region  string
+		staging bool
+		ok      bool
+	}{
+		{"https://infra-api.newrelic.com", regionUS, false, true},
+		{"https://infra-api.eu.newrelic.com", regionEU, false, true},
+		{"https://staging-infra-api.eu.newrelic.com", regionEU, true, true},
+		{"https://gov-infra-api.newrelic.com", regionFedRAMP, false, true},
+		{"https://staging-identity-api.newrelic.com", regionUS, true, true},
+		{"https://nr-proxy.corp.local:8443", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			r, ok := parseEndpointRegion(tt.url)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.region, r.region)
+			assert.Equal(t, tt.staging, r.staging)
+		})
+	}
+}
+
+func TestValidateRegion(t *testing.T) {
+	tests := []struct {
+		name     string
+		license  string
+		staging  bool
+		fedramp  bool
+		urls     []string
+		warnings []string
+	}{
+		{
+			name:    "US defaults",
+			license: usLicense,
+			urls:    []string{calculateCollectorURL(usLicense, false, false), calculateIdentityURL(usLicense, false, false), calculateCmdChannelURL(usLicense, false, false)},
+		},
+		{
+			name:    "EU defaults",
+			license: euLicense,
+			urls:    []string{calculateCollectorURL(euLicense, false, false), calculateIdentityURL(euLicense, false, false), calculateCmdChannelURL(euLicense, false, false)},
+		},
+		{
+			name:    "FedRAMP staging defaults",
+			license: usLicense,
+			staging: true,
+			fedramp: true,
+			urls:    []string{"https://staging-infra-api.newrelic.com", calculateIdentityURL(usLicense, true, true), calculateCmdChannelURL(usLicense, true, true)},
+		},
+		{
+			name:     "EU license with US endpoints",
+			license:  euLicense,
+			urls:     []string{"https://infra-api.newrelic.com", "https://identity-api.newrelic.com", calculateCmdChannelURL(euLicense, false, false)},
+			warnings: []string{"collector_url: US endpoint https://infra-api.newrelic.com used with an EU license key", "identity_url: US endpoint"},
+		},
+		{
+			name:     "US license with EU collector",
+			license:  usLicense,
+			urls:     []string{"https://infra-api.eu.newrelic.com", calculateIdentityURL(usLicense, false, false), calculateCmdChannelURL(usLicense, false, false)},
+			warnings: []string{"collector_url: EU endpoint https://infra-api.eu.newrelic.com used with a US license key"},
+		},
+		{
+			name:     "fedramp with US collector",
+			license:  usLicense,
+			fedramp:  true,
+			urls:     []string{"https://infra-api.newrelic.com", calculateIdentityURL(usLicense, false, true), calculateCmdChannelURL(usLicense, false, true)},
+			warnings: []string{"collector_url: US endpoint https://infra-api.newrelic.com used with fedramp enabled"},
+		},
+		{
+			name:     "fedramp with EU license",
+			license:  euLicense,
+			fedramp:  true,
+			urls:     []string{calculateCollectorURL(euLicense, false, true), "https://identity-api.eu.newrelic.com", ""},
+			warnings: []string{"fedramp: FedRAMP endpoints don't accept EU license keys", "collector_url: FedRAMP endpoint"},
+		},
+		{
+			name:     "staging endpoint in production",
+			license:  usLicense,
+			urls:     []string{"https://staging-infra-api.newrelic.com", "", ""},
+			warnings: []string{"collector_url: staging endpoint https://staging-infra-api.newrelic.com used in a production environment"},
+		},
+		{
+			name:    "custom endpoints",
+			license: euLicense,
+			urls:    []string{"https://nr-proxy.corp.local", "https://nr-proxy.corp.local", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				License:           tt.license,
+				Staging:           tt.staging,
+				Fedramp:           tt.fedramp,
+				CollectorURL:      tt.urls[0],
+				IdentityURL:       tt.urls[1],
+				CommandChannelURL: tt.urls[2],
+			}
+			cfg.MetricURL = calculateDimensionalMetricURL(cfg.CollectorURL, cfg.License, cfg.Staging, cfg.Fedramp)
+
+			warnings := validateRegion(cfg)
+			require.Len(t, warnings, len(tt.warnings), warnings)
+			for i, w := range tt.warnings {
+				assert.Contains(t, warnings[i].String(), w)
+			}
+		})
+	}
+}
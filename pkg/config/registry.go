@@ -0,0 +1,456 @@
+// Copyright 2023 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	config_loader "github.com/newrelic/infrastructure-agent/pkg/config/loader"
+	"gopkg.in/yaml.v2"
+)
+
+// Registry struct tags, declared on the Config fields along with the yaml one:
+//
+//	range:"min,max"          valid range (inclusive) for integer options.
+//	deprecated:"replacement" option is deprecated in favour of the replacement (yaml key).
+//	reload:"hot"             option is applied to the running agent when the configuration is reloaded.
+//	format:"duration"        string option holding a duration (ie: 30s, 1h).
+const (
+	rangeTag       = "range"
+	deprecatedTag  = "deprecated"
+	reloadTag      = "reload"
+	hotReload      = "hot"
+	formatTag      = "format"
+	durationFormat = "duration"
+	// maxSuggestionDistance is the max edit distance for an unknown key to be considered a typo.
+	maxSuggestionDistance = 2
+)
+
+// ConfigWarning is a configuration issue that doesn't prevent the agent from running.
+type ConfigWarning struct {
+	// Key is the yaml name of the option.
+	Key     string
+	Message string
+}
+
+func (w ConfigWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Key, w.Message)
+}
+
+// Option describes a configuration option.
+type Option struct {
+	// Key is the yaml name of the option.
+	Key string
+	// Type is the kind of value the option holds.
+	Type reflect.Kind
+	// Default is the value provided by NewConfig.
+	Default interface{}
+	// Min and Max bound the option value when HasRange is set.
+	HasRange bool
+	Min      int64
+	Max      int64
+	// Deprecated options are still applied, ReplacedBy holds the option to use instead.
+	Deprecated bool
+	ReplacedBy string
+	// OS lists the platforms the option applies to, all of them when empty.
+	OS string
+	// HotReload options are applied without restarting the agent.
+	HotReload bool
+	// Duration options hold a duration string.
+	Duration bool
+
+	fieldIndex []int
+}
+
+// AppliesToOS returns true if the option is used on the running platform.
+func (o Option) AppliesToOS() bool {
+	return o.OS == "" || strings.Contains(o.OS, runtime.GOOS)
+}
+
+// Registry indexes the agent configuration options by yaml name. It's built from the Config
+// struct, so declaring a new field is enough to register a new option.
+type Registry struct {
+	options map[string]Option
+}
+
+// NewRegistry creates a registry from the Config fields, taking defaults from NewConfig.
+func NewRegistry() (*Registry, error) {
+	r := &Registry{options: make(map[string]Option)}
+
+	defaults := reflect.ValueOf(NewConfig()).Elem()
+	if err := r.register(defaults, nil); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Registry) register(v reflect.Value, index []int) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+		if key == "" && strings.Contains(field.Tag.Get("yaml"), "inline") {
+			if err := r.register(v.Field(i), fieldIndex); err != nil {
+				return err
+			}
+			continue
+		}
+		if key == "" || key == "-" {
+			continue
+		}
+
+		opt := Option{
+			Key:        key,
+			Type:       field.Type.Kind(),
+			Default:    v.Field(i).Interface(),
+			OS:         field.Tag.Get("os"),
+			HotReload:  field.Tag.Get(reloadTag) == hotReload,
+			Duration:   field.Tag.Get(formatTag) == durationFormat,
+			fieldIndex: fieldIndex,
+		}
+
+		if replacement, ok := field.Tag.Lookup(deprecatedTag); ok {
+			opt.Deprecated = true
+			opt.ReplacedBy = replacement
+		}
+
+		if rng, ok := field.Tag.Lookup(rangeTag); ok {
+			min, max, err := parseRange(rng)
+			if err != nil {
+				return fmt.Errorf("invalid range for config option %s: %w", key, err)
+			}
+			opt.HasRange, opt.Min, opt.Max = true, min, max
+		}
+
+		r.options[key] = opt
+	}
+
+	return nil
+}
+
+func parseRange(rng string) (min, max int64, err error) {
+	parts := strings.Split(rng, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected min,max but got %q", rng)
+	}
+	if min, err = strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64); err != nil {
+		return 0, 0, err
+	}
+	if max, err = strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64); err != nil {
+		return 0, 0, err
+	}
+	if min > max {
+		return 0, 0, fmt.Errorf("min %d is greater than max %d", min, max)
+	}
+	return min, max, nil
+}
+
+// Lookup returns the option registered for the yaml key.
+func (r *Registry) Lookup(key string) (Option, bool) {
+	opt, ok := r.options[key]
+	return opt, ok
+}
+
+// Options returns all the registered options sorted by key.
+func (r *Registry) Options() []Option {
+	options := make([]Option, 0, len(r.options))
+	for _, opt := range r.options {
+		options = append(options, opt)
+	}
+	sort.Slice(options, func(i, j int) bool {
+		return options[i].Key < options[j].Key
+	})
+	return options
+}
+
+// Values returns the values of the options in the configuration, indexed by key.
+func (r *Registry) Values(cfg *Config) map[string]interface{} {
+	cfg.lock.Lock()
+	defer cfg.lock.Unlock()
+
+	v := reflect.ValueOf(cfg).Elem()
+	values := make(map[string]interface{}, len(r.options))
+	for key, opt := range r.options {
+		values[key] = v.FieldByIndex(opt.fieldIndex).Interface()
+	}
+	return values
+}
+
+// Apply sets the values of the options from the src configuration into the dst one, which may be in use by the
+// running agent.
+func (r *Registry) Apply(dst, src *Config, keys []string) error {
+	dst.lock.Lock()
+	defer dst.lock.Unlock()
+
+	dv, sv := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for _, key := range keys {
+		opt, ok := r.options[key]
+		if !ok {
+			return fmt.Errorf("unknown configuration option %s", key)
+		}
+		dv.FieldByIndex(opt.fieldIndex).Set(sv.FieldByIndex(opt.fieldIndex))
+	}
+	return nil
+}
+
+// SetValues sets the values of the options, indexed by key, into the configuration, which may be in use by the
+// running agent. No value is set when any of them doesn't match the type of its option.
+func (r *Registry) SetValues(cfg *Config, values map[string]interface{}) error {
+	cfg.lock.Lock()
+	defer cfg.lock.Unlock()
+
+	v := reflect.ValueOf(cfg).Elem()
+	for key, value := range values {
+		opt, ok := r.options[key]
+		if !ok {
+			return fmt.Errorf("unknown configuration option %s", key)
+		}
+		if value == nil || reflect.TypeOf(value) != v.FieldByIndex(opt.fieldIndex).Type() {
+			return fmt.Errorf("invalid value for configuration option %s: %v", key, value)
+		}
+	}
+	for key, value := range values {
+		v.FieldByIndex(r.options[key].fieldIndex).Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+// Validate checks the loaded configuration against the registry returning warnings for:
+// - unknown keys defined in the config file (ie: typos), suggesting the closest known option.
+// - deprecated options in use, either from the config file or the environment.
+// - integer options out of their valid range.
+// - duration options which can't be parsed.
+// Only top level config file keys are checked for unknown options, UnknownNestedKeys checks the nested ones.
+func (r *Registry) Validate(cfg *Config, cfgMetadata config_loader.YAMLMetadata) (warnings []ConfigWarning) {
+	keys := make([]string, 0, len(cfgMetadata))
+	for key := range cfgMetadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, ok := r.options[key]; ok {
+			continue
+		}
+		msg := "unknown configuration option, it will be ignored"
+		if suggestion := r.closestKey(key); suggestion != "" {
+			msg = fmt.Sprintf("%s, did you mean %s?", msg, suggestion)
+		}
+		warnings = append(warnings, ConfigWarning{Key: key, Message: msg})
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	for _, opt := range r.Options() {
+		if !opt.AppliesToOS() {
+			continue
+		}
+
+		if opt.Deprecated && isConfigDefined(opt.Key, cfgMetadata) {
+			msg := "deprecated configuration option"
+			if opt.ReplacedBy != "" {
+				msg = fmt.Sprintf("%s, use %s instead", msg, opt.ReplacedBy)
+			}
+			warnings = append(warnings, ConfigWarning{Key: opt.Key, Message: msg})
+		}
+
+		if opt.HasRange {
+			field := v.FieldByIndex(opt.fieldIndex)
+			if !field.CanInt() {
+				continue
+			}
+			if value := field.Int(); value < opt.Min || value > opt.Max {
+				warnings = append(warnings, ConfigWarning{
+					Key:     opt.Key,
+					Message: fmt.Sprintf("value %d out of range [%d, %d]", value, opt.Min, opt.Max),
+				})
+			}
+		}
+
+		if opt.Duration {
+			field := v.FieldByIndex(opt.fieldIndex)
+			if field.Kind() != reflect.String || field.String() == "" {
+				continue
+			}
+			if _, err := time.ParseDuration(field.String()); err != nil {
+				warnings = append(warnings, ConfigWarning{
+					Key:     opt.Key,
+					Message: fmt.Sprintf("invalid duration %q, use a number and a unit, ie: 30s, 5m or 1h", field.String()),
+				})
+			}
+		}
+	}
+
+	return warnings
+}
+
+// UnknownNestedKeys returns warnings for the keys nested into the options of the config file content which don't
+// match any field (ie: typos), suggesting the closest one. Keys are reported by their path, ie: log.levle. Values of
+// options with a custom yaml unmarshaler aren't checked, as they may accept any shape.
+func (r *Registry) UnknownNestedKeys(content []byte) (warnings []ConfigWarning, err error) {
+	var doc yaml.MapSlice
+	if err = yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	cfgType := reflect.TypeOf((*Config)(nil)).Elem()
+	for _, item := range doc {
+		key, _ := item.Key.(string)
+		opt, ok := r.options[key]
+		if !ok {
+			continue
+		}
+		warnings = append(warnings, unknownKeys(cfgType.FieldByIndex(opt.fieldIndex).Type, item.Value, key)...)
+	}
+	return warnings, nil
+}
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// unknownKeys returns warnings for the keys of the yaml value not matching the fields of the type.
+func unknownKeys(t reflect.Type, value interface{}, path string) (warnings []ConfigWarning) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Implements(yamlUnmarshalerType) || reflect.PtrTo(t).Implements(yamlUnmarshalerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		items, ok := value.(yaml.MapSlice)
+		if !ok {
+			return nil
+		}
+		fields := yamlFields(t)
+		for _, item := range items {
+			key := fmt.Sprint(item.Key)
+			field, ok := fields[key]
+			if !ok {
+				msg := "unknown configuration option, it will be ignored"
+				candidates := make([]string, 0, len(fields))
+				for name := range fields {
+					candidates = append(candidates, name)
+				}
+				if suggestion := closest(key, candidates); suggestion != "" {
+					msg = fmt.Sprintf("%s, did you mean %s.%s?", msg, path, suggestion)
+				}
+				warnings = append(warnings, ConfigWarning{Key: path + "." + key, Message: msg})
+				continue
+			}
+			warnings = append(warnings, unknownKeys(field.Type, item.Value, path+"."+key)...)
+		}
+	case reflect.Map:
+		items, ok := value.(yaml.MapSlice)
+		if !ok {
+			return nil
+		}
+		for _, item := range items {
+			warnings = append(warnings, unknownKeys(t.Elem(), item.Value, fmt.Sprintf("%s.%v", path, item.Key))...)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range items {
+			warnings = append(warnings, unknownKeys(t.Elem(), item, fmt.Sprintf("%s.%d", path, i))...)
+		}
+	}
+	return warnings
+}
+
+// yamlFields returns the fields of the struct by their yaml key, including the inlined ones. Fields without yaml tag
+// are keyed by their lowercased name, as the yaml decoder does.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if tag[0] == "" && strings.Contains(field.Tag.Get("yaml"), "inline") {
+			for key, inlined := range yamlFields(field.Type) {
+				fields[key] = inlined
+			}
+			continue
+		}
+		key := tag[0]
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		fields[key] = field
+	}
+	return fields
+}
+
+// closestKey returns the registered key closest to the provided one, empty if none is close enough.
+func (r *Registry) closestKey(key string) string {
+	keys := make([]string, 0, len(r.options))
+	for _, opt := range r.Options() {
+		keys = append(keys, opt.Key)
+	}
+	return closest(key, keys)
+}
+
+// closest returns the candidate closest to the key, empty if none is close enough. Short keys require a closer match
+// to avoid meaningless suggestions.
+func closest(key string, candidates []string) (closest string) {
+	sort.Strings(candidates)
+	best := minInt(maxSuggestionDistance, len(key)/3-1) + 1
+	for _, candidate := range candidates {
+		if d := editDistance(key, candidate); d < best {
+			best, closest = d, candidate
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,260 @@
+// Copyright 2023 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	config_loader "github.com/newrelic/infrastructure-agent/pkg/config/loader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRegistry(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+
+	opt, ok := r.Lookup("payload_compression_level")
+	require.True(t, ok)
+	assert.Equal(t, reflect.Int, opt.Type)
+	assert.Equal(t, defaultPayloadCompressionLevel, opt.Default)
+	assert.True(t, opt.HasRange)
+	assert.Equal(t, int64(0), opt.Min)
+	assert.Equal(t, int64(9), opt.Max)
+	assert.False(t, opt.Deprecated)
+
+	opt, ok = r.Lookup("verbose")
+	require.True(t, ok)
+	assert.True(t, opt.Deprecated)
+	assert.Equal(t, "log.level", opt.ReplacedBy)
+	assert.True(t, opt.HotReload)
+
+	opt, ok = r.Lookup("license_key")
+	require.True(t, ok)
+	assert.False(t, opt.HotReload)
+
+	// inlined databind options
+	_, ok = r.Lookup("variables")
+	assert.True(t, ok)
+
+	// ignored fields
+	_, ok = r.Lookup("-")
+	assert.False(t, ok)
+	_, ok = r.Lookup("")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Apply(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+
+	dst, src := NewConfig(), NewConfig()
+	src.Proxy = "http://proxy:3128"
+	src.License = "modified"
+	src.Log.Level = LogLevelDebug
+
+	require.NoError(t, r.Apply(dst, src, []string{"proxy", "log"}))
+	assert.Equal(t, "http://proxy:3128", dst.Proxy)
+	assert.Equal(t, LogLevelDebug, dst.Log.Level)
+	assert.Empty(t, dst.License)
+	assert.Equal(t, "http://proxy:3128", r.Values(dst)["proxy"])
+
+	assert.Error(t, r.Apply(dst, src, []string{"unknown"}))
+}
+
+func TestRegistry_SetValues(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	require.NoError(t, r.SetValues(cfg, map[string]interface{}{
+		"metrics_process_sample_rate": 60,
+		"custom_attributes":           CustomAttributeMap{"team": "ops"},
+	}))
+	assert.Equal(t, 60, cfg.MetricsProcessSampleRate)
+	assert.Equal(t, CustomAttributeMap{"team": "ops"}, cfg.CustomAttributes)
+
+	// no value is set when any of them is invalid
+	assert.Error(t, r.SetValues(cfg, map[string]interface{}{
+		"metrics_process_sample_rate": 30,
+		"custom_attributes":           "team",
+	}))
+	assert.Equal(t, 60, cfg.MetricsProcessSampleRate)
+	assert.Error(t, r.SetValues(cfg, map[string]interface{}{"unknown": 1}))
+}
+
+func TestRegistry_SetValues_ConcurrentReads(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+	cfg := NewConfig()
+
+	// run with -race: the options applied at runtime are read through the locked accessors
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = cfg.GetMetricsProcessSampleRate()
+			_ = cfg.GetCustomAttributes()
+			_, _ = cfg.GetProcessTopN()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, r.SetValues(cfg, map[string]interface{}{
+			"metrics_process_sample_rate": 20 + i,
+			"custom_attributes":           CustomAttributeMap{"run": i},
+		}))
+	}
+	<-done
+
+	assert.Equal(t, 119, cfg.GetMetricsProcessSampleRate())
+	assert.Equal(t, CustomAttributeMap{"run": 99}, cfg.GetCustomAttributes())
+}
+
+func TestParseRange(t *testing.T) {
+	min, max, err := parseRange("-1, 10")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), min)
+	assert.Equal(t, int64(10), max)
+
+	for _, rng := range []string{"1", "a,2", "1,b", "5,1"} {
+		_, _, err = parseRange(rng)
+		assert.Error(t, err, rng)
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.PayloadCompressionLevel = 12
+	cfg.StatusServerPort = 0
+	cfg.StartupConnectionTimeout = "10"
+
+	metadata := config_loader.YAMLMetadata{
+		"license_key":               true,
+		"verbose":                   true,
+		"payload_compression_level": true,
+		"status_server_enabled":     true,
+		"metrics_system_sampl_rate": true,
+		"unknown_option":            true,
+	}
+
+	warnings := r.Validate(cfg, metadata)
+
+	assert.Equal(t, []ConfigWarning{
+		{Key: "metrics_system_sampl_rate", Message: "unknown configuration option, it will be ignored, did you mean metrics_system_sample_rate?"},
+		{Key: "unknown_option", Message: "unknown configuration option, it will be ignored"},
+		{Key: "payload_compression_level", Message: "value 12 out of range [0, 9]"},
+		{Key: "startup_connection_timeout", Message: `invalid duration "10", use a number and a unit, ie: 30s, 5m or 1h`},
+		{Key: "status_server_port", Message: "value 0 out of range [1, 65535]"},
+		{Key: "verbose", Message: "deprecated configuration option, use log.level instead"},
+	}, warnings)
+}
+
+func TestRegistry_Validate_DeprecatedFromEnv(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+
+	t.Setenv("NRIA_LOG_FORMAT", "json")
+
+	warnings := r.Validate(NewConfig(), config_loader.YAMLMetadata{})
+
+	assert.Equal(t, []ConfigWarning{
+		{Key: "log_format", Message: "deprecated configuration option, use log.format instead"},
+	}, warnings)
+}
+
+func TestRegistry_Validate_NoWarnings(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+
+	assert.Empty(t, r.Validate(NewConfig(), config_loader.YAMLMetadata{"license_key": true}))
+}
+
+func TestLoadConfig_Warnings(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "newrelic-infra.yml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("license_key: abc123\nlicence_key: abc123\n"), 0o600))
+
+	cfg, err := LoadConfig(cfgPath)
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Warnings(), 1)
+	assert.Equal(t, "licence_key: unknown configuration option, it will be ignored, did you mean license_key?", cfg.Warnings()[0].String())
+}
+
+func TestRegistry_UnknownNestedKeys(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+
+	warnings, err := r.UnknownNestedKeys([]byte(`
+license_key: abc123
+unknown_top_level: true
+log:
+  level: debug
+  forwrd: true
+  rotate:
+    max_size_mb: 100
+    max_fils: 5
+http:
+  headers:
+    X-Custom: value
+variables:
+  creds:
+    vault:
+      http:
+        url: http://vault
+    tll: 1h
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []ConfigWarning{
+		{Key: "log.forwrd", Message: "unknown configuration option, it will be ignored, did you mean log.forward?"},
+		{Key: "log.rotate.max_fils", Message: "unknown configuration option, it will be ignored, did you mean log.rotate.max_files?"},
+		// too short to guess
+		{Key: "variables.creds.tll", Message: "unknown configuration option, it will be ignored"},
+	}, warnings)
+}
+
+func TestLoadConfig_NestedWarnings(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "newrelic-infra.yml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("license_key: abc123\nlog:\n  levle: debug\n"), 0o600))
+
+	cfg, err := LoadConfig(cfgPath)
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Warnings(), 1)
+	assert.Equal(t, "log.levle", cfg.Warnings()[0].Key)
+}
+
+func TestRegistry_ClosestKey(t *testing.T) {
+	r, err := NewRegistry()
+	require.NoError(t, err)
+
+	assert.Equal(t, "license_key", r.closestKey("licence_key"))
+	assert.Equal(t, "max_procs", r.closestKey("max_proc"))
+	// too short to guess
+	assert.Empty(t, r.closestKey("lg"))
+	assert.Empty(t, r.closestKey("foo"))
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"verbose", "verbose", 0},
+		{"verbos", "verbose", 1},
+		{"licence_key", "license_key", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, editDistance(tt.a, tt.b), "%s -> %s", tt.a, tt.b)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// logKey is the option of the log settings, whose level is the only one applied on reload.
+const logKey = "log"
+
+// configReloadDelay is the time the configuration is reloaded after the last change of its file, so the several
+// events of rewriting or replacing it trigger a single reload.
+var configReloadDelay = time.Second
+
+var rlog = log.WithComponent("ConfigReloader")
+
+// ReloadHandler is invoked after the configuration is reloaded, with the keys of the options applied to the running
+// configuration, if any.
+type ReloadHandler func(applied []string)
+
+// Reloader loads the configuration again, ie: on SIGHUP, applying the options tagged with reload:"hot" to the
+// running configuration. The identity of the agent and its queued data are kept, as it's not restarted. The
+// modified options requiring a restart are logged and not applied.
+type Reloader struct {
+	cfg      *Config
+	load     func() (*Config, error)
+	registry *Registry
+
+	lock     sync.Mutex
+	values   map[string]interface{} // values of the running options, to detect the modified ones
+	handlers []ReloadHandler
+}
+
+// NewReloader creates the reloader of the running configuration, loaded again by the load function.
+func NewReloader(cfg *Config, load func() (*Config, error)) (*Reloader, error) {
+	registry, err := NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reloader{
+		cfg:      cfg,
+		load:     load,
+		registry: registry,
+		values:   registry.Values(cfg),
+	}, nil
+}
+
+// OnReload registers a handler invoked after every reload.
+func (r *Reloader) OnReload(handler ReloadHandler) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.handlers = append(r.handlers, handler)
+}
+
+// Reload loads the configuration and applies the modified options supporting it, returning their keys. The running
+// configuration is kept when the loaded one is not valid.
+func (r *Reloader) Reload() ([]string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	loaded, err := r.load()
+	// loading the configuration sets its log level, which is set back to the running one
+	defer func() { setLogLevel(r.cfg.Log.Level) }()
+	if err != nil {
+		return nil, fmt.Errorf("cannot reload configuration, keeping the running one: %w", err)
+	}
+
+	values := r.registry.Values(loaded)
+	var applied, restart []string
+	for key, value := range values {
+		if reflect.DeepEqual(value, r.values[key]) {
+			continue
+		}
+
+		if key == logKey {
+			running := r.values[key].(LogConfig)
+			levelChanged, othersChanged := logChanges(running, loaded.Log)
+			if othersChanged || (levelChanged && (running.IsSmartLogging() || loaded.Log.IsSmartLogging())) {
+				restart = append(restart, key)
+			}
+			if levelChanged && !running.IsSmartLogging() && !loaded.Log.IsSmartLogging() {
+				// only the level is applied
+				running.Level = loaded.Log.Level
+				loaded.Log, values[key] = running, running
+				applied = append(applied, key)
+			}
+			continue
+		}
+
+		if opt, ok := r.registry.Lookup(key); ok && opt.HotReload {
+			applied = append(applied, key)
+		} else {
+			restart = append(restart, key)
+		}
+	}
+	sort.Strings(applied)
+	sort.Strings(restart)
+
+	if err = r.registry.Apply(r.cfg, loaded, applied); err != nil {
+		return nil, err
+	}
+	for _, key := range applied {
+		r.values[key] = values[key]
+	}
+	// the options defined in the loaded file take precedence over the ones set at runtime, ie: remotely
+	r.cfg.lock.Lock()
+	r.cfg.metadata = loaded.metadata
+	r.cfg.lock.Unlock()
+
+	if len(restart) > 0 {
+		rlog.WithField("options", strings.Join(restart, ", ")).
+			Warn("Modified configuration options require restarting the agent, they are not applied.")
+	}
+	rlog.WithField("applied", strings.Join(applied, ", ")).Info("Configuration reloaded.")
+
+	for _, handler := range r.handlers {
+		handler(applied)
+	}
+	return applied, nil
+}
+
+// logChanges compares the log settings, as the level is applied without restarting but the other settings are not.
+func logChanges(running, loaded LogConfig) (levelChanged, othersChanged bool) {
+	levelChanged = running.Level != loaded.Level
+	running.Level = loaded.Level
+	return levelChanged, !reflect.DeepEqual(running, loaded)
+}
+
+func setLogLevel(level string) {
+	logLevel, err := log.ParseLevel(level)
+	if err != nil {
+		logLevel = logrus.InfoLevel
+	}
+	log.SetLevel(logLevel)
+	logrus.SetLevel(logLevel)
+}
+
+// Watch reloads the configuration when its file is modified, until the context is done. The configFile is the one
+// provided to LoadConfig. The directory of the file is watched rather than the file, so it keeps being watched when
+// it's replaced, ie: by configuration management tools or mounted Kubernetes config maps.
+func (r *Reloader) Watch(ctx context.Context, configFile string) error {
+	file := LoadedConfigFile(configFile)
+	if file == "" {
+		return fmt.Errorf("no configuration file found")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err = watcher.Add(filepath.Dir(file)); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+	rlog.WithField("file", file).Info("Watching configuration file, it's reloaded when modified.")
+
+	go func() {
+		defer watcher.Close()
+
+		reload := time.NewTimer(configReloadDelay)
+		reload.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				reload.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Base(event.Name)
+				if event.Op == fsnotify.Chmod || (filepath.Clean(event.Name) != file && !strings.HasPrefix(name, "..")) {
+					continue
+				}
+				reload.Reset(configReloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				rlog.WithError(err).Warn("Error watching configuration file.")
+			case <-reload.C:
+				if _, err := r.Reload(); err != nil {
+					rlog.WithError(err).Error("Cannot apply modified configuration file.")
+				}
+			}
+		}
+	}()
+	return nil
+}
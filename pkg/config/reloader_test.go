@@ -0,0 +1,104 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config_loader "github.com/newrelic/infrastructure-agent/pkg/config/loader"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader_Reload(t *testing.T) {
+	defer func(level logrus.Level) { log.SetLevel(level) }(log.GetLevel())
+
+	running := NewConfig()
+	running.License = "license"
+	loaded := NewConfig()
+	loaded.License = "modified"
+	loaded.CustomAttributes = CustomAttributeMap{"environment": "staging"}
+	loaded.MetricsSystemSampleRate = 30
+	loaded.Log.Level = LogLevelDebug
+	loaded.Log.File = "/var/log/newrelic-infra.log"
+	loaded.metadata = config_loader.YAMLMetadata{"custom_attributes": true}
+
+	r, err := NewReloader(running, func() (*Config, error) { return loaded, nil })
+	require.NoError(t, err)
+	var handled []string
+	r.OnReload(func(applied []string) { handled = applied })
+
+	applied, err := r.Reload()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"custom_attributes", "log", "metrics_system_sample_rate"}, applied)
+	assert.Equal(t, applied, handled)
+	assert.Equal(t, CustomAttributeMap{"environment": "staging"}, running.CustomAttributes)
+	assert.Equal(t, 30, running.MetricsSystemSampleRate)
+	assert.Equal(t, LogLevelDebug, running.Log.Level)
+	assert.Equal(t, logrus.DebugLevel, log.GetLevel())
+	// options requiring a restart are not applied
+	assert.Equal(t, "license", running.License)
+	assert.Empty(t, running.Log.File)
+	// the options defined in the loaded file are known
+	assert.True(t, running.IsDefined("custom_attributes"))
+	assert.False(t, running.IsDefined("metrics_network_sample_rate"))
+
+	// the applied options are not reported again
+	applied, err = r.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestReloader_ReloadError(t *testing.T) {
+	defer func(level logrus.Level) { log.SetLevel(level) }(log.GetLevel())
+
+	running := NewConfig()
+	running.Log.Level = LogLevelInfo
+	r, err := NewReloader(running, func() (*Config, error) {
+		// loading sets the log level before failing
+		log.SetLevel(logrus.TraceLevel)
+		return nil, errors.New("invalid")
+	})
+	require.NoError(t, err)
+	r.OnReload(func([]string) { t.Fatal("handler should not be invoked") })
+
+	_, err = r.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, logrus.InfoLevel, log.GetLevel())
+}
+
+func TestReloader_Watch(t *testing.T) {
+	defer func(delay time.Duration) { configReloadDelay = delay }(configReloadDelay)
+	configReloadDelay = 10 * time.Millisecond
+
+	file := filepath.Join(t.TempDir(), "newrelic-infra.yml")
+	require.NoError(t, os.WriteFile(file, []byte("license_key: license\n"), 0o600))
+
+	var reloads int32
+	r, err := NewReloader(NewConfig(), func() (*Config, error) {
+		atomic.AddInt32(&reloads, 1)
+		return NewConfig(), nil
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, r.Watch(ctx, file))
+
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(file), "other.yml"), []byte("other"), 0o600))
+	require.NoError(t, os.WriteFile(file, []byte("license_key: license\nverbose: 1\n"), 0o600))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&reloads) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&reloads))
+}
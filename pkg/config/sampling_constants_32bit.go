@@ -38,15 +38,20 @@ const (
 	FREQ_PLUGIN_UPSTART_UPDATES        = 30 // seconds
 
 	FREQ_PLUGIN_FACTER_UPDATES            = 30 // seconds -- facter plugin
-	FREQ_PLUGIN_PACKAGE_MGRS_UPDATES      = 30 // seconds -- rpm, deb plugins. RPM watches /var/lib/rpm/.rpm.lock, dpkg: /var/lib/dpkg/lock
+	FREQ_PLUGIN_PACKAGE_MGRS_UPDATES      = 30 // seconds -- rpm, deb, apk plugins. RPM watches /var/lib/rpm/.rpm.lock, dpkg: /var/lib/dpkg/lock, apk: /lib/apk/db
 	FREQ_PLUGIN_SELINUX_UPDATES           = 30 // seconds
 	FREQ_PLUGIN_HOST_ALIASES              = 30 // seconds
 	FREQ_PLUGIN_NETWORK_INTERFACE_UPDATES = 60 // seconds
+	FREQ_PLUGIN_LISTENING_PORTS           = 60 // seconds
 	FREQ_PLUGIN_CLOUD_SECURITY_UPDATES    = 60 // seconds
+	FREQ_PLUGIN_AWS_INSTANCE_EVENTS       = 5  // seconds, spot interruption notices come two minutes ahead
+	FREQ_PLUGIN_TERMINATION_NOTICE        = 5  // seconds, GCP and Azure preemption notices come 30 seconds ahead
 
 	// WINDOWS PLUGINS
 	FREQ_PLUGIN_WINDOWS_SERVICES = 30 // seconds, 0 == off, 30 == minimum otherwise: inventory: running services
 	FREQ_PLUGIN_WINDOWS_UPDATES  = 60 // seconds
+	FREQ_PLUGIN_WINDOWS_SOFTWARE = 60 // seconds, inventory: registry uninstall keys
+	FREQ_PLUGIN_WINDOWS_REBOOT   = 60 // seconds, inventory: pending reboot
 
 	// BOTH
 	FREQ_EXTERNAL_USER_DATA      = 30 // seconds between external user data samples (deprecated user json plugin)
@@ -0,0 +1,107 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package ctl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/newrelic/infrastructure-agent/pkg/ipc"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+var cslog = log.WithComponent("ControlServer")
+
+// ControlHandler serves a control command writing its output into w.
+type ControlHandler func(ctx context.Context, w io.Writer, args []string) error
+
+// ControlServer serves request/response commands from newrelic-infra-ctl through a local socket
+// (unix domain socket on Linux/macOS, named pipe on Windows).
+type ControlServer struct {
+	address  string
+	lock     sync.RWMutex
+	handlers map[string]ControlHandler
+}
+
+// NewControlServer creates a control server listening at the given address once served.
+func NewControlServer(address string) *ControlServer {
+	return &ControlServer{
+		address:  address,
+		handlers: make(map[string]ControlHandler),
+	}
+}
+
+// RegisterHandler registers a handler for a control command.
+func (s *ControlServer) RegisterHandler(command string, handler ControlHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.handlers[command] = handler
+}
+
+// Serve listens for control requests until the context is cancelled.
+func (s *ControlServer) Serve(ctx context.Context) error {
+	l, err := ipc.Listen(s.address)
+	if err != nil {
+		return fmt.Errorf("cannot listen on control socket %s: %w", s.address, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	cslog.WithField("address", s.address).Debug("Control socket listening.")
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.handle(ctx, conn)
+	}
+}
+
+func (s *ControlServer) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	req, err := ipc.ReadRequest(bufio.NewReader(conn), s.commands())
+	if err != nil {
+		cslog.WithError(err).Warn("Cannot read control request.")
+		return
+	}
+
+	s.lock.RLock()
+	handler, ok := s.handlers[req.Command]
+	s.lock.RUnlock()
+
+	buf := &bytes.Buffer{}
+	if !ok {
+		err = fmt.Errorf("unknown command: %s", req.Command)
+	} else {
+		cslog.WithField("command", req.Command).Debug("Serving control request.")
+		err = handler(ctx, buf, req.Args)
+	}
+
+	if err := ipc.WriteResponse(conn, buf.Bytes(), err); err != nil {
+		cslog.WithError(err).WithField("command", req.Command).Warn("Cannot write control response.")
+	}
+}
+
+func (s *ControlServer) commands() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	commands := make([]string, 0, len(s.handlers))
+	for c := range s.handlers {
+		commands = append(commands, c)
+	}
+	return commands
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux || darwin
+// +build linux darwin
+
+package ctl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/ipc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func request(t *testing.T, address, command string, args ...string) ([]byte, error) {
+	t.Helper()
+
+	var conn net.Conn
+	var err error
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("unix", address)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer conn.Close()
+
+	require.NoError(t, ipc.WriteRequest(conn, command, args...))
+	return ipc.ReadResponse(conn)
+}
+
+func TestControlServer(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "run", "agent.sock")
+
+	srv := NewControlServer(address)
+	srv.RegisterHandler(ipc.DebugStacks, func(_ context.Context, w io.Writer, _ []string) error {
+		_, err := io.WriteString(w, "stacks")
+		return err
+	})
+	srv.RegisterHandler(ipc.DebugMutex, func(_ context.Context, w io.Writer, args []string) error {
+		if len(args) == 0 {
+			return errors.New("missing duration")
+		}
+		_, err := io.WriteString(w, strings.Join(args, ","))
+		return err
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ctx) }()
+
+	out, err := request(t, address, ipc.DebugStacks)
+	require.NoError(t, err)
+	assert.Equal(t, "stacks", string(out))
+
+	out, err = request(t, address, ipc.DebugMutex, "2s")
+	require.NoError(t, err)
+	assert.Equal(t, "2s", string(out))
+
+	_, err = request(t, address, ipc.DebugMutex)
+	assert.EqualError(t, err, "missing duration")
+
+	_, err = request(t, address, "unknown")
+	assert.EqualError(t, err, "unknown command: unknown")
+
+	info, err := os.Stat(address)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	cancel()
+	select {
+	case err = <-served:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("control server didn't stop")
+	}
+}
@@ -105,7 +105,7 @@ func processShutdownWatcher(shutdownChan chan shutdownCmd, watcher shutdownWatch
 
 func handleSignals(retCh chan<- ipc.Message, shutdownCh chan shutdownCmd, sdw shutdownWatcher) {
 	s := make(chan os.Signal, 1)
-	signal.Notify(s, signals.Notification, signals.GracefulStop, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(s, signals.Notification, signals.GracefulStop, signals.Reload, syscall.SIGINT, syscall.SIGTERM)
 	for {
 		select {
 		case sig := <-s:
@@ -120,6 +120,8 @@ func handleSignals(retCh chan<- ipc.Message, shutdownCh chan shutdownCmd, sdw sh
 
 			case signals.Notification:
 				retCh <- ipc.EnableVerboseLogging
+			case signals.Reload:
+				retCh <- ipc.ReloadConfig
 			default:
 				nlog.WithField("signal", sig).Info("did not recognise received signal")
 			}
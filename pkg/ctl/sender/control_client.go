@@ -0,0 +1,49 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package sender
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/newrelic/infrastructure-agent/pkg/ipc"
+)
+
+// ControlClient sends request/response commands to the agent control socket.
+type ControlClient struct {
+	address string
+}
+
+// NewControlClient creates a client for the agent control socket at the given address.
+func NewControlClient(address string) *ControlClient {
+	return &ControlClient{address: address}
+}
+
+// Request sends the command to the agent and returns its output.
+func (c *ControlClient) Request(ctx context.Context, command string, args ...string) ([]byte, error) {
+	conn, err := ipc.Dial(ctx, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to agent control socket %s: %w", c.address, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err = ipc.WriteRequest(conn, command, args...); err != nil {
+		return nil, fmt.Errorf("cannot send control request: %w", err)
+	}
+
+	return ipc.ReadResponse(conn)
+}
+
+// Reachable returns whether an agent is listening on the control socket.
+func (c *ControlClient) Reachable(ctx context.Context) bool {
+	conn, err := ipc.Dial(ctx, c.address)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
@@ -12,18 +12,19 @@ import (
 )
 
 type Command struct {
-	Exec        ShlexOpt          `yaml:"exec"`
-	Environment map[string]string `yaml:"env"`
-	Matcher     map[string]string `yaml:"match"`
-	Timeout     time.Duration     `yaml:"timeout"`
+	Exec            ShlexOpt          `yaml:"exec"`
+	Environment     map[string]string `yaml:"env"`
+	Matcher         map[string]string `yaml:"match"`
+	MatchExpression string            `yaml:"match_expression"`
+	Timeout         time.Duration     `yaml:"timeout"`
 }
 
 func (c *Command) Validate() error {
 	if len(c.Exec) == 0 {
 		return errors.New("missing 'cmd' entries")
 	}
-	if len(c.Matcher) == 0 {
-		return errors.New("missing 'match' entries")
+	if len(c.Matcher) == 0 && c.MatchExpression == "" {
+		return errors.New("missing 'match' entries or 'match_expression'")
 	}
 	return nil
 }
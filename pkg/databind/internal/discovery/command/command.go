@@ -23,7 +23,7 @@ var (
 // Discoverer returns an executable discoverer from the provided configuration.
 // The fetching process will return an array of map values
 func Discoverer(d discovery.Command) (fetchDiscoveries func() (discoveries []discovery.Discovery, err error), err error) {
-	matcher, err := discovery.NewMatcher(d.Matcher)
+	matcher, err := discovery.NewMatcher(d.Matcher, d.MatchExpression)
 	if err != nil {
 		return nil, err
 	}
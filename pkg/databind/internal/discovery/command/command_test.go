@@ -130,7 +130,7 @@ func TestFetch(t *testing.T) {
 			d := discovery.Command{
 				Exec: discovery.ShlexOpt{f.Name(), "expected", "args"},
 			}
-			matcher, err := discovery.NewMatcher(tt.matcher)
+			matcher, err := discovery.NewMatcher(tt.matcher, "")
 			require.NoError(t, err)
 			exe := newCommand(d, matcher)
 			assert.Equal(t, d.Exec, exe.d.Exec)
@@ -34,7 +34,7 @@ func TestExecutable_Validate(t *testing.T) {
 			fields: fields{
 				Exec: ShlexOpt{"/usr/bin/cmd"},
 			},
-			wantErr: "missing 'match' entries",
+			wantErr: "missing 'match' entries or 'match_expression'",
 		},
 		{
 			name: "Happy",
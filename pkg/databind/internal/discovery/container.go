@@ -9,13 +9,14 @@ import (
 
 // Container discovery parameters
 type Container struct {
-	Match      map[string]string `yaml:"match"`
-	ApiVersion string            `yaml:"api_version"` // for docker client
+	Match           map[string]string `yaml:"match"`
+	MatchExpression string            `yaml:"match_expression"`
+	ApiVersion      string            `yaml:"api_version"` // for docker client
 }
 
 func (d *Container) Validate() error {
-	if len(d.Match) == 0 {
-		return errors.New("missing 'match' entries")
+	if len(d.Match) == 0 && d.MatchExpression == "" {
+		return errors.New("missing 'match' entries or 'match_expression'")
 	}
 	return nil
 }
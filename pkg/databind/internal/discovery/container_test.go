@@ -14,7 +14,7 @@ func TestMatcher_All(t *testing.T) {
 	cm, err := NewMatcher(map[string]string{
 		"container":     "hello",
 		"label.version": "/^2\\./",
-	})
+	}, "")
 	require.NoError(t, err)
 
 	assert.True(t, cm.All(map[string]string{
@@ -34,6 +34,6 @@ func TestMatcher_CantInstantiate(t *testing.T) {
 		"container":     "hello",
 		"label.version": "/^2\\./",
 		"label.value":   "/[invalid regex/",
-	})
+	}, "")
 	assert.Error(t, err)
 }
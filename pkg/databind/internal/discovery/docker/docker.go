@@ -31,7 +31,7 @@ func Discoverer(d discovery.Container) (fetchDiscoveries func() (discoveries []d
 	if d.ApiVersion == "" {
 		d.ApiVersion = defaultDockerAPIVersion
 	}
-	matcher, err := discovery.NewMatcher(d.Match)
+	matcher, err := discovery.NewMatcher(d.Match, d.MatchExpression)
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +115,7 @@ func TestGetMatchingContainers(t *testing.T) {
 
 	matcher, err := discovery.NewMatcher(map[string]string{
 		"image": "/test-server/",
-	})
+	}, "")
 	require.NoError(t, err)
 
 	actualDiscoveryData := getDiscoveries(givenContainerList, &matcher)
@@ -0,0 +1,338 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expression is a boolean expression evaluated against the discovered fields, ie:
+//
+//	label.tier == "db" && (private.port >= 5432 || !label.legacy)
+//
+// Comparisons of a field not discovered are false.
+type expression interface {
+	eval(fields map[string]string) bool
+}
+
+type orExpr struct {
+	left, right expression
+}
+
+func (e orExpr) eval(fields map[string]string) bool {
+	return e.left.eval(fields) || e.right.eval(fields)
+}
+
+type andExpr struct {
+	left, right expression
+}
+
+func (e andExpr) eval(fields map[string]string) bool {
+	return e.left.eval(fields) && e.right.eval(fields)
+}
+
+type notExpr struct {
+	expr expression
+}
+
+func (e notExpr) eval(fields map[string]string) bool {
+	return !e.expr.eval(fields)
+}
+
+// existsExpr holds when the field is discovered.
+type existsExpr struct {
+	field string
+}
+
+func (e existsExpr) eval(fields map[string]string) bool {
+	_, ok := fields[e.field]
+	return ok
+}
+
+// comparison compares a field with a value. Equality compares numerically when both sides are numbers, so
+// `port == 80` matches "80" and "80.0", while ordering comparisons only hold for numeric fields.
+type comparison struct {
+	field   string
+	op      string
+	value   string
+	number  float64
+	numeric bool
+	regex   *regexp.Regexp
+}
+
+func (c comparison) eval(fields map[string]string) bool {
+	val, ok := fields[c.field]
+	if !ok {
+		return false
+	}
+
+	switch c.op {
+	case "=~":
+		return c.regex.MatchString(val)
+	case "!~":
+		return !c.regex.MatchString(val)
+	}
+
+	number, err := strconv.ParseFloat(val, 64)
+	numeric := c.numeric && err == nil
+	switch c.op {
+	case "==":
+		return val == c.value || numeric && number == c.number
+	case "!=":
+		return val != c.value && !(numeric && number == c.number)
+	case "<":
+		return numeric && number < c.number
+	case "<=":
+		return numeric && number <= c.number
+	case ">":
+		return numeric && number > c.number
+	case ">=":
+		return numeric && number >= c.number
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators, the longest first so they are tokenized greedily.
+var operators = []string{"||", "&&", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"}
+
+// tokenize splits the expression into words (fields, numbers and unquoted values), quoted strings, operators and
+// parentheses. Double quoted strings accept Go escape sequences while single quoted ones are taken verbatim, which
+// is handier for regular expressions.
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '(' || c == ')':
+			kind := tokLParen
+			if c == ')' {
+				kind = tokRParen
+			}
+			tokens = append(tokens, token{kind: kind, text: string(c), pos: i})
+			i++
+
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != src[i] {
+				if src[end] == '\\' && c == '"' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text := src[i+1 : end]
+			if c == '"' {
+				var err error
+				if text, err = strconv.Unquote(src[i : end+1]); err != nil {
+					return nil, fmt.Errorf("invalid string at position %d: %s", i, err.Error())
+				}
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: i})
+			i = end + 1
+
+		case isWordChar(c):
+			end := i
+			for end < len(src) && isWordChar(rune(src[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokWord, text: src[i:end], pos: i})
+			i = end
+
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isWordChar(c rune) bool {
+	return c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)) || strings.ContainsRune("_.-/:", c)
+}
+
+// parser is a recursive descent parser of the expressions, by increasing precedence:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | primary
+//	primary = "(" or ")" | field [ operator value ]
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpression parses a match expression.
+func parseExpression(src string) (expression, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", next.text, next.pos)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) acceptOp(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (expression, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (expression, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (expression, error) {
+	if p.acceptOp("!") {
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr: expr}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (expression, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("missing ')' at position %d", closing.pos)
+		}
+		return expr, nil
+	case tokWord:
+		return p.comparison(t.text)
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("expected a field at position %d, found %q", t.pos, t.text)
+}
+
+func (p *parser) comparison(field string) (expression, error) {
+	op := p.peek()
+	if op.kind != tokOp || !isComparison(op.text) {
+		return existsExpr{field: field}, nil
+	}
+	p.pos++
+
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, fmt.Errorf("expected a value for %q at position %d", field, value.pos)
+	}
+	c := comparison{field: field, op: op.text, value: value.text}
+	switch op.text {
+	case "=~", "!~":
+		regex := value.text
+		if metaRegexp.MatchString(regex) {
+			regex = regex[1 : len(regex)-1]
+		}
+		cmp, err := regexp.Compile(regex)
+		if err != nil {
+			return nil, fmt.Errorf("value of %q should be a valid regular expression: %s", field, err.Error())
+		}
+		c.regex = cmp
+	default:
+		number, err := strconv.ParseFloat(value.text, 64)
+		c.number, c.numeric = number, err == nil
+		if !c.numeric && op.text != "==" && op.text != "!=" {
+			return nil, fmt.Errorf("value of %q should be a number to compare with %s, found %q", field, op.text, value.text)
+		}
+	}
+	return c, nil
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher_Expression(t *testing.T) {
+	fields := map[string]string{
+		"image":        "postgres:13",
+		"label.tier":   "db",
+		"label.weight": "0.5",
+		"private.port": "5432",
+		"ports.0":      "5432",
+		"ports.1":      "8080",
+	}
+
+	tests := []struct {
+		expression string
+		matches    bool
+	}{
+		{`label.tier == "db" && private.port >= 5432`, true},
+		{`label.tier == "db" && private.port > 5432`, false},
+		{`label.tier == db`, true},
+		{`label.tier != "db" || ports.1 == 8080`, true},
+		{`private.port == 5432.0`, true},
+		{`private.port != 5432`, false},
+		{`label.weight < 1 && label.weight <= 0.5`, true},
+		{`image =~ '^postgres:\d+$'`, true},
+		{`image =~ "/^mysql/"`, false},
+		{`image !~ "^mysql"`, true},
+		{`label.legacy`, false},
+		{`!label.legacy && label.tier`, true},
+		{`!(label.tier == "db")`, false},
+		{`label.tier == "cache" || label.tier == "db" && ports.0 == 5432`, true},
+		{`(label.tier == "cache" || label.tier == "db") && ports.0 == 3306`, false},
+		// comparisons of fields not discovered are false
+		{`label.env != "production"`, false},
+		{`!(label.env == "production")`, true},
+		// ordering comparisons only hold for numbers
+		{`label.tier > 1`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			cm, err := NewMatcher(nil, tt.expression)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.matches, cm.All(fields))
+		})
+	}
+}
+
+func TestMatcher_ExpressionAndFields(t *testing.T) {
+	cm, err := NewMatcher(map[string]string{"image": "/^postgres/"}, "port >= 5432")
+	require.NoError(t, err)
+
+	assert.True(t, cm.All(map[string]string{"image": "postgres", "port": "5432"}))
+	assert.False(t, cm.All(map[string]string{"image": "postgres", "port": "80"}))
+	assert.False(t, cm.All(map[string]string{"image": "mysql", "port": "5432"}))
+}
+
+func TestMatcher_InvalidExpression(t *testing.T) {
+	tests := map[string]string{
+		"unterminated string": `label.tier == "db`,
+		"missing value":       `label.tier ==`,
+		"missing parenthesis": `(label.tier == db`,
+		"trailing tokens":     `label.tier == db port`,
+		"dangling operator":   `label.tier == db &&`,
+		"non numeric order":   `port >= high`,
+		"invalid regex":       `image =~ "[postgres"`,
+		"invalid character":   `label.tier = db`,
+	}
+	for name, expression := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMatcher(nil, expression)
+
+			assert.Error(t, err)
+		})
+	}
+}
@@ -32,7 +32,7 @@ var httpClient HTTPClient = &http.Client{} //nolint
 // The fetching process will return an array of map values for each discovered container, with the
 // keys discovery.port and discovery.ip
 func Discoverer(d discovery.Container) (func() ([]discovery.Discovery, error), error) {
-	matcher, err := discovery.NewMatcher(d.Match)
+	matcher, err := discovery.NewMatcher(d.Match, d.MatchExpression)
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +102,7 @@ func TestMatch(t *testing.T) {
 	matchRules := map[string]string{
 		"name": "mysql",
 	}
-	matcher, err := discovery.NewMatcher(matchRules)
+	matcher, err := discovery.NewMatcher(matchRules, "")
 	require.NoError(t, err)
 
 	taskMetadata := fargateMetadataTwoContainers
@@ -6,12 +6,14 @@ package discovery
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
 )
 
 type FieldsMatcher struct {
 	matcher map[string]matchingFunc
+	expr    expression
 }
 
 type matchingFunc func(val string) bool
@@ -31,7 +33,9 @@ func regexpMatch(regexp *regexp.Regexp) matchingFunc {
 // we'll identify any regular expression as a string between two slashes (as Ruby lang)
 var metaRegexp = regexp.MustCompile("^/.*/$")
 
-func NewMatcher(fieldMatchers map[string]string) (FieldsMatcher, error) {
+// NewMatcher returns a matcher of the discovered fields. Both the field matchers and the expression, when not
+// empty, need to match.
+func NewMatcher(fieldMatchers map[string]string, expression string) (FieldsMatcher, error) {
 	cm := FieldsMatcher{
 		matcher: map[string]matchingFunc{},
 	}
@@ -49,6 +53,13 @@ func NewMatcher(fieldMatchers map[string]string) (FieldsMatcher, error) {
 			cm.matcher[field] = stringEquals(str)
 		}
 	}
+	if strings.TrimSpace(expression) != "" {
+		expr, err := parseExpression(expression)
+		if err != nil {
+			return cm, fmt.Errorf("invalid match expression: %s", err.Error())
+		}
+		cm.expr = expr
+	}
 	return cm, nil
 }
 
@@ -58,7 +69,7 @@ func (cm *FieldsMatcher) All(fields map[string]string) bool {
 			return false
 		}
 	}
-	return true
+	return cm.expr == nil || cm.expr.eval(fields)
 }
 
 func LabelsToMap(prefix string, labels map[string]string) data.Map {
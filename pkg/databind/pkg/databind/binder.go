@@ -78,6 +78,11 @@ type Values struct {
 	discov []discovery.Discovery
 }
 
+// Vars returns the user-defined variables, ie: the secrets, by their flattened names.
+func (v *Values) Vars() data.Map {
+	return v.vars
+}
+
 // VarsLen amount of variables to be replaced.
 func (v *Values) VarsLen() int {
 	return len(v.vars)
@@ -64,9 +64,12 @@ const (
 
 // DiscovererInfo keeps util info about the discoverer.
 type DiscovererInfo struct {
-	Type     DiscovererType
-	Name     string
-	Matchers map[string]string
+	Type            DiscovererType
+	Name            string
+	Matchers        map[string]string
+	MatchExpression string
+	// Merged holds the info of the other discovery sources whose matches are merged, if any.
+	Merged []DiscovererInfo
 }
 
 // gatherer is any source fetching a single match from a variables source (e.g. a vault key)
@@ -32,7 +32,9 @@ func (y *YAMLAgentConfig) IsEmpty() bool {
 
 type YAMLConfig struct {
 	YAMLAgentConfig `yaml:",inline"`
-	Discovery       struct {
+	// Include globs of the files whose variables and discovery are merged into the configuration.
+	Include   []string `yaml:"include,omitempty"`
+	Discovery struct {
 		TTL     string               `yaml:"ttl,omitempty"`
 		Docker  *discovery.Container `yaml:"docker,omitempty"`
 		Fargate *discovery.Container `yaml:"fargate,omitempty"`
@@ -65,13 +67,17 @@ type Test struct {
 
 func (t *Test) Validate() error { return nil }
 
-// LoadYaml builds a set of data binding Sources from a YAML file
+// LoadYaml builds a set of data binding Sources from a YAML file. The relative globs of its include directive are
+// resolved against the working directory.
 func LoadYAML(bytes []byte) (*Sources, error) {
 	// Load raw yaml
 	dc := YAMLConfig{}
 	if err := yaml.Unmarshal(bytes, &dc); err != nil {
 		return nil, err
 	}
+	if err := dc.ResolveIncludes(".", nil); err != nil {
+		return nil, err
+	}
 
 	return dc.DataSources()
 }
@@ -138,80 +144,99 @@ func duration(fmt string, def time.Duration) (time.Duration, error) {
 	return duration, nil
 }
 
+// selectDiscoverer returns the discoverer of the configured discovery sources, merging their matches when there
+// are several of them.
 func (dc *YAMLConfig) selectDiscoverer(ttl time.Duration) (*discoverer, error) {
+	var sources []discoverySource
+	if dc.Discovery.Docker != nil {
+		fetch, err := docker.Discoverer(*dc.Discovery.Docker)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, discoverySource{kind: typeDocker, fetch: fetch})
+	}
 	if dc.Discovery.Fargate != nil {
 		fetch, err := fargate.Discoverer(*dc.Discovery.Fargate)
-		return &discoverer{
-			cache: cachedEntry{ttl: ttl},
-			fetch: fetch,
-		}, err
-
-	} else if dc.Discovery.Docker != nil {
-		fetch, err := docker.Discoverer(*dc.Discovery.Docker)
-		return &discoverer{
-			cache: cachedEntry{ttl: ttl},
-			fetch: fetch,
-		}, err
-
-	} else if dc.Discovery.Command != nil {
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, discoverySource{kind: typeFargate, fetch: fetch})
+	}
+	if dc.Discovery.Command != nil {
 		fetch, err := command.Discoverer(*dc.Discovery.Command)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, discoverySource{kind: typeCmd, fetch: fetch})
+	}
+
+	switch len(sources) {
+	case 0:
+		return nil, nil
+	case 1:
 		return &discoverer{
 			cache: cachedEntry{ttl: ttl},
-			fetch: fetch,
-		}, err
-
+			fetch: sources[0].fetch,
+		}, nil
 	}
-	return nil, nil
+	return &discoverer{
+		cache: cachedEntry{ttl: ttl},
+		fetch: mergeDiscoveries(sources),
+	}, nil
 }
 
+// addDiscoveryInfo returns the info of the first discovery source, in the order they are merged, holding the info
+// of the other ones.
 func (y *YAMLConfig) addDiscoveryInfo() DiscovererInfo {
-	var res DiscovererInfo
+	var infos []DiscovererInfo
 	if y.Discovery.Docker != nil {
-		res = DiscovererInfo{
-			Type:     typeDocker,
-			Matchers: y.Discovery.Docker.Match,
-		}
-	} else if y.Discovery.Fargate != nil {
-		res = DiscovererInfo{
-			Type:     typeFargate,
-			Matchers: y.Discovery.Fargate.Match,
-		}
-	} else if y.Discovery.Command != nil {
-		res = DiscovererInfo{
-			Type:     typeCmd,
-			Name:     fmt.Sprintf("%v", y.Discovery.Command.Exec),
-			Matchers: y.Discovery.Command.Matcher,
-		}
+		infos = append(infos, DiscovererInfo{
+			Type:            typeDocker,
+			Matchers:        y.Discovery.Docker.Match,
+			MatchExpression: y.Discovery.Docker.MatchExpression,
+		})
 	}
+	if y.Discovery.Fargate != nil {
+		infos = append(infos, DiscovererInfo{
+			Type:            typeFargate,
+			Matchers:        y.Discovery.Fargate.Match,
+			MatchExpression: y.Discovery.Fargate.MatchExpression,
+		})
+	}
+	if y.Discovery.Command != nil {
+		infos = append(infos, DiscovererInfo{
+			Type:            typeCmd,
+			Name:            fmt.Sprintf("%v", y.Discovery.Command.Exec),
+			Matchers:        y.Discovery.Command.Matcher,
+			MatchExpression: y.Discovery.Command.MatchExpression,
+		})
+	}
+	if len(infos) == 0 {
+		return DiscovererInfo{}
+	}
+	res := infos[0]
+	res.Merged = infos[1:]
 	return res
 }
 
 func (y *YAMLConfig) validate() error {
-	sections := 0
 	if y.Discovery.Docker != nil {
-		sections++
 		if err := y.Discovery.Docker.Validate(); err != nil {
 			return err
 		}
 	}
 	if y.Discovery.Fargate != nil {
-		sections++
 		if err := y.Discovery.Fargate.Validate(); err != nil {
 			return err
 		}
 	}
 
 	if y.Discovery.Command != nil {
-		sections++
 		if err := y.Discovery.Command.Validate(); err != nil {
 			return err
 		}
 	}
 
-	if sections > 1 {
-		return errors.New("only one discovery source allowed")
-	}
-
 	return y.YAMLAgentConfig.validate()
 }
 
@@ -0,0 +1,189 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package databind
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// maxIncludeDepth limits the nesting of the included files.
+const maxIncludeDepth = 10
+
+// ReadFileFn reads an included file, ie: expanding its environment variables.
+type ReadFileFn func(path string) ([]byte, error)
+
+// ResolveIncludes merges the variables and discovery of the files matching the include globs into the
+// configuration, clearing them. Relative globs are resolved against baseDir, and the included files can include
+// other files, relative to their own directory. The variables and discovery of the configuration take precedence
+// over the included ones. The discovery sources of several included files are merged, while the same variable or
+// discovery source defined by several included files is an error.
+// Files are read with os.ReadFile when read is nil.
+func (y *YAMLConfig) ResolveIncludes(baseDir string, read ReadFileFn) error {
+	if len(y.Include) == 0 {
+		return nil
+	}
+	if read == nil {
+		read = os.ReadFile
+	}
+
+	inc := includer{read: read, included: map[string]bool{}}
+	merged, err := inc.resolve(y.Include, baseDir, nil)
+	if err != nil {
+		return err
+	}
+	y.Include = nil
+	y.inherit(merged)
+	return nil
+}
+
+// includer reads the included files, each of them once, so files included through several paths are not reported
+// as duplicated definitions.
+type includer struct {
+	read     ReadFileFn
+	included map[string]bool
+}
+
+// resolve returns the definitions of the files matching the globs. The chain holds the files including them, to
+// report include cycles.
+func (i *includer) resolve(globs []string, baseDir string, chain []string) (YAMLConfig, error) {
+	var merged YAMLConfig
+	if len(chain) > maxIncludeDepth {
+		return merged, fmt.Errorf("includes nested deeper than %d files: %v", maxIncludeDepth, chain)
+	}
+
+	for _, glob := range globs {
+		if !filepath.IsAbs(glob) {
+			glob = filepath.Join(baseDir, glob)
+		}
+		files, err := filepath.Glob(glob)
+		if err != nil {
+			return merged, fmt.Errorf("invalid include %q: %w", glob, err)
+		}
+		if len(files) == 0 {
+			return merged, fmt.Errorf("include %q doesn't match any file", glob)
+		}
+
+		for _, file := range files {
+			if abs, err := filepath.Abs(file); err == nil {
+				file = abs
+			}
+			for _, including := range chain {
+				if including == file {
+					return merged, fmt.Errorf("include cycle: %v", append(chain, file))
+				}
+			}
+			if i.included[file] {
+				continue
+			}
+			i.included[file] = true
+
+			content, err := i.load(file, chain)
+			if err != nil {
+				return merged, err
+			}
+			if err = merged.mergeInclude(content, file); err != nil {
+				return merged, err
+			}
+		}
+	}
+	return merged, nil
+}
+
+// load reads an included file, resolving its own includes.
+func (i *includer) load(file string, chain []string) (YAMLConfig, error) {
+	var content YAMLConfig
+	bytes, err := i.read(file)
+	if err != nil {
+		return content, fmt.Errorf("cannot read included file: %w", err)
+	}
+	if err = yaml.Unmarshal(bytes, &content); err != nil {
+		return content, fmt.Errorf("cannot parse included file %s: %w", file, err)
+	}
+	if len(content.Include) == 0 {
+		return content, nil
+	}
+
+	nested, err := i.resolve(content.Include, filepath.Dir(file), append(chain, file))
+	if err != nil {
+		return content, err
+	}
+	content.Include = nil
+	content.inherit(nested)
+	return content, nil
+}
+
+// inherit adds the included definitions not defined by the configuration.
+func (y *YAMLConfig) inherit(included YAMLConfig) {
+	for name, entry := range included.Variables {
+		if _, ok := y.Variables[name]; ok {
+			continue
+		}
+		if y.Variables == nil {
+			y.Variables = map[string]varEntry{}
+		}
+		y.Variables[name] = entry
+	}
+	if !y.hasDiscovery() {
+		y.Discovery = included.Discovery
+	}
+}
+
+// mergeInclude adds the definitions of an included file, which can't be defined by other included files.
+func (y *YAMLConfig) mergeInclude(included YAMLConfig, file string) error {
+	for name, entry := range included.Variables {
+		if _, ok := y.Variables[name]; ok {
+			return fmt.Errorf("variable %q defined by several included files, last one: %s", name, file)
+		}
+		if y.Variables == nil {
+			y.Variables = map[string]varEntry{}
+		}
+		y.Variables[name] = entry
+	}
+	if !included.hasDiscovery() {
+		return nil
+	}
+	if !y.hasDiscovery() {
+		y.Discovery = included.Discovery
+		return nil
+	}
+
+	// the discovery sources of several included files are merged, as long as they don't set the same one
+	discov := &y.Discovery
+	if included.Discovery.TTL != "" {
+		if discov.TTL != "" && discov.TTL != included.Discovery.TTL {
+			return fmt.Errorf("discovery ttl defined by several included files, last one: %s", file)
+		}
+		discov.TTL = included.Discovery.TTL
+	}
+	var duplicated DiscovererType
+	switch {
+	case included.Discovery.Docker != nil && discov.Docker != nil:
+		duplicated = typeDocker
+	case included.Discovery.Fargate != nil && discov.Fargate != nil:
+		duplicated = typeFargate
+	case included.Discovery.Command != nil && discov.Command != nil:
+		duplicated = typeCmd
+	}
+	if duplicated != "" {
+		return fmt.Errorf("%s discovery defined by several included files, last one: %s", duplicated, file)
+	}
+	if included.Discovery.Docker != nil {
+		discov.Docker = included.Discovery.Docker
+	}
+	if included.Discovery.Fargate != nil {
+		discov.Fargate = included.Discovery.Fargate
+	}
+	if included.Discovery.Command != nil {
+		discov.Command = included.Discovery.Command
+	}
+	return nil
+}
+
+func (y *YAMLConfig) hasDiscovery() bool {
+	return y.Discovery.Docker != nil || y.Discovery.Fargate != nil || y.Discovery.Command != nil
+}
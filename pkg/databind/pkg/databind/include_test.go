@@ -0,0 +1,229 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package databind
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	return dir
+}
+
+func parse(t *testing.T, content string) YAMLConfig {
+	t.Helper()
+
+	var y YAMLConfig
+	require.NoError(t, yaml.Unmarshal([]byte(content), &y))
+	return y
+}
+
+func TestResolveIncludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"shared/vault.yml": `
+include:
+  - ../common/*.yml
+variables:
+  dbPassword:
+    vault:
+      http:
+        url: http://vault.example.com/db
+  apiKey:
+    vault:
+      http:
+        url: http://vault.example.com/api
+`,
+		"shared/discovery.yml": `
+discovery:
+  ttl: 30s
+  docker:
+    match:
+      image: /^redis/
+`,
+		"common/token.yml": `
+variables:
+  token:
+    test:
+      value: shared-token
+`,
+	})
+	y := parse(t, `
+include:
+  - shared/*.yml
+variables:
+  apiKey:
+    test:
+      value: local-key
+`)
+
+	require.NoError(t, y.ResolveIncludes(dir, nil))
+
+	assert.Empty(t, y.Include)
+	require.Len(t, y.Variables, 3)
+	// the variables of the including file take precedence
+	require.NotNil(t, y.Variables["apiKey"].Test)
+	assert.Equal(t, "local-key", y.Variables["apiKey"].Test.Value)
+	require.NotNil(t, y.Variables["dbPassword"].Vault)
+	require.NotNil(t, y.Variables["token"].Test)
+	assert.Equal(t, "30s", y.Discovery.TTL)
+	require.NotNil(t, y.Discovery.Docker)
+	assert.Equal(t, "/^redis/", y.Discovery.Docker.Match["image"])
+}
+
+func TestResolveIncludes_LocalDiscovery(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"discovery.yml": `
+discovery:
+  docker:
+    match:
+      image: /^redis/
+`,
+	})
+	y := parse(t, `
+include: [discovery.yml]
+discovery:
+  command:
+    exec: /bin/discover
+    match:
+      label.role: /db/
+`)
+
+	require.NoError(t, y.ResolveIncludes(dir, nil))
+
+	assert.Nil(t, y.Discovery.Docker)
+	assert.NotNil(t, y.Discovery.Command)
+}
+
+func TestResolveIncludes_MergedDiscovery(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"docker.yml": `
+discovery:
+  ttl: 30s
+  docker:
+    match:
+      image: /^redis/
+`,
+		"fargate.yml": `
+discovery:
+  fargate:
+    match:
+      image: /^redis/
+`,
+	})
+	y := parse(t, "include: [docker.yml, fargate.yml]")
+
+	require.NoError(t, y.ResolveIncludes(dir, nil))
+
+	assert.Equal(t, "30s", y.Discovery.TTL)
+	assert.NotNil(t, y.Discovery.Docker)
+	assert.NotNil(t, y.Discovery.Fargate)
+}
+
+func TestResolveIncludes_ReadFn(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"vars.yml": `
+variables:
+  token:
+    test:
+      value: ${TOKEN}
+`,
+	})
+	y := parse(t, "include: [vars.yml]")
+
+	read := func(path string) ([]byte, error) {
+		content, err := os.ReadFile(path)
+		return []byte(strings.ReplaceAll(string(content), "${TOKEN}", "expanded")), err
+	}
+	require.NoError(t, y.ResolveIncludes(dir, read))
+
+	assert.Equal(t, "expanded", y.Variables["token"].Test.Value)
+}
+
+func TestResolveIncludes_Errors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yml": `
+variables:
+  token:
+    test:
+      value: a
+discovery:
+  docker:
+    match:
+      image: /a/
+`,
+		"b.yml": `
+variables:
+  token:
+    test:
+      value: b
+`,
+		"c.yml": `
+discovery:
+  docker:
+    match:
+      image: /c/
+`,
+		"cycle/first.yml":  "include: [second.yml]",
+		"cycle/second.yml": "include: [first.yml]",
+		"invalid.yml":      "variables: [",
+	})
+
+	tests := []struct {
+		name    string
+		include string
+		err     string
+	}{
+		{"no match", "missing/*.yml", "doesn't match any file"},
+		{"duplicated variable", "[ab].yml", `variable "token" defined by several included files`},
+		{"duplicated discovery", "[ac].yml", "docker discovery defined by several included files"},
+		{"cycle", "cycle/first.yml", "include cycle"},
+		{"invalid file", "invalid.yml", "cannot parse included file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			y := YAMLConfig{Include: []string{tt.include}}
+
+			err := y.ResolveIncludes(dir, nil)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestLoadYAML_Anchors(t *testing.T) {
+	// anchors share blocks within a file
+	sources, err := LoadYAML([]byte(`
+vault_http: &vault_http
+  url: http://vault.example.com
+  headers:
+    X-Vault-Token: token
+variables:
+  dbPassword:
+    vault:
+      http:
+        <<: *vault_http
+        url: http://vault.example.com/db
+  apiKey:
+    vault:
+      http: *vault_http
+`))
+	require.NoError(t, err)
+	assert.Len(t, sources.variables, 2)
+}
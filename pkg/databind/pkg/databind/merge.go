@@ -0,0 +1,72 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package databind
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+)
+
+var dlog = log.WithComponent("DatabindDiscovery")
+
+// discoverySource is a discovery source whose matches are merged with other sources ones.
+type discoverySource struct {
+	kind  DiscovererType
+	fetch func() ([]discovery.Discovery, error)
+}
+
+// mergeDiscoveries returns a fetch function merging the matches of several discovery sources, so the same
+// configuration works on different runtimes, ie: while migrating from docker to fargate. The sources failing, as
+// docker when its daemon isn't running, are skipped while any other succeeds. The matches discovered by several
+// sources are kept once, from the first source discovering them.
+func mergeDiscoveries(sources []discoverySource) func() ([]discovery.Discovery, error) {
+	return func() ([]discovery.Discovery, error) {
+		var merged []discovery.Discovery
+		var errs []error
+		found := map[string]bool{}
+		for _, source := range sources {
+			matches, err := source.fetch()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s discovery: %w", source.kind, err))
+				continue
+			}
+			for _, match := range matches {
+				key := matchKey(match)
+				if found[key] {
+					continue
+				}
+				found[key] = true
+				merged = append(merged, match)
+			}
+		}
+
+		if len(errs) == len(sources) {
+			return nil, errors.Join(errs...)
+		}
+		for _, err := range errs {
+			dlog.WithError(err).Debug("Discovery source failed, using the matches of the other sources.")
+		}
+		return merged, nil
+	}
+}
+
+// matchKey identifies a discovered match by its container ID, when discovered, or by all its variables otherwise.
+func matchKey(match discovery.Discovery) string {
+	if id, ok := match.Variables[data.DiscoveryPrefix+data.ContainerID]; ok && id != "" {
+		return data.ContainerID + "=" + id
+	}
+
+	vars := make([]string, 0, len(match.Variables))
+	for name, value := range match.Variables {
+		vars = append(vars, name+"="+value)
+	}
+	sort.Strings(vars)
+	return strings.Join(vars, "\n")
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package databind
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/databind/internal/discovery"
+	"github.com/newrelic/infrastructure-agent/pkg/databind/pkg/data"
+)
+
+func fetchOf(matches ...data.Map) func() ([]discovery.Discovery, error) {
+	return func() ([]discovery.Discovery, error) {
+		var discoveries []discovery.Discovery
+		for _, vars := range matches {
+			discoveries = append(discoveries, NewDiscovery(vars, nil, nil))
+		}
+		return discoveries, nil
+	}
+}
+
+func failing(err error) func() ([]discovery.Discovery, error) {
+	return func() ([]discovery.Discovery, error) {
+		return nil, err
+	}
+}
+
+func TestMergeDiscoveries(t *testing.T) {
+	fetch := mergeDiscoveries([]discoverySource{
+		{kind: typeDocker, fetch: fetchOf(
+			data.Map{"discovery.containerId": "a1", "discovery.image": "redis"},
+			data.Map{"discovery.ip": "10.0.0.2", "discovery.port": "6379"},
+		)},
+		{kind: typeFargate, fetch: fetchOf(
+			// the same container discovered by both sources
+			data.Map{"discovery.containerId": "a1", "discovery.image": "redis", "discovery.label.task": "t1"},
+			data.Map{"discovery.containerId": "b2", "discovery.image": "redis"},
+		)},
+		{kind: typeCmd, fetch: fetchOf(
+			data.Map{"discovery.port": "6379", "discovery.ip": "10.0.0.2"},
+			data.Map{"discovery.ip": "10.0.0.3", "discovery.port": "6379"},
+		)},
+	})
+
+	matches, err := fetch()
+	require.NoError(t, err)
+
+	require.Len(t, matches, 4)
+	// the first source discovering a match wins
+	assert.Equal(t, data.Map{"discovery.containerId": "a1", "discovery.image": "redis"}, matches[0].Variables)
+	assert.Equal(t, "10.0.0.2", matches[1].Variables["discovery.ip"])
+	assert.Equal(t, "b2", matches[2].Variables["discovery.containerId"])
+	assert.Equal(t, "10.0.0.3", matches[3].Variables["discovery.ip"])
+}
+
+func TestMergeDiscoveries_Failures(t *testing.T) {
+	dockerErr := errors.New("cannot connect to the docker daemon")
+
+	fetch := mergeDiscoveries([]discoverySource{
+		{kind: typeDocker, fetch: failing(dockerErr)},
+		{kind: typeFargate, fetch: fetchOf(data.Map{"discovery.containerId": "b2"})},
+	})
+	matches, err := fetch()
+	require.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	// an error is returned only when all the sources fail
+	fetch = mergeDiscoveries([]discoverySource{
+		{kind: typeDocker, fetch: failing(dockerErr)},
+		{kind: typeFargate, fetch: failing(errors.New("metadata endpoint not found"))},
+	})
+	_, err = fetch()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, dockerErr))
+	assert.Contains(t, err.Error(), "fargate discovery: metadata endpoint not found")
+}
+
+func TestLoadYAML_SeveralDiscoverySources(t *testing.T) {
+	sources, err := LoadYAML([]byte(`
+discovery:
+  docker:
+    match:
+      image: /redis/
+  command:
+    exec: /bin/discover-redis
+    match_expression: port == 6379
+`))
+	require.NoError(t, err)
+
+	require.NotNil(t, sources.discoverer)
+	assert.Equal(t, typeDocker, sources.Info.Type)
+	require.Len(t, sources.Info.Merged, 1)
+	assert.Equal(t, typeCmd, sources.Info.Merged[0].Type)
+	assert.Equal(t, "port == 6379", sources.Info.Merged[0].MatchExpression)
+}
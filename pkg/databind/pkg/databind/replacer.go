@@ -8,6 +8,7 @@ import (
 	"errors"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"unsafe"
 
@@ -18,11 +19,20 @@ import (
 
 type replaceConfig struct {
 	onDemand []OnDemand
+	typed    bool
 }
 
 // Option provide extra behaviour configuration to the replacement process.
 type ReplaceOption func(rc *replaceConfig)
 
+// Typed configures the Replace function to keep the type of the values replaced into non-string fields, when they
+// consist of a single variable mark. Without it, all the values are replaced as strings.
+func Typed() ReplaceOption {
+	return func(rc *replaceConfig) {
+		rc.typed = true
+	}
+}
+
 // This regular expression matches any variable mark ${...} with dots, index marks [ ], and /.
 var regex = regexp.MustCompile(`\$\{[\w\d\._\s\[\]\/-]*\}`)
 
@@ -202,6 +212,12 @@ func replaceFields(values []data.Map, val reflect.Value, rc replaceConfig, match
 		}
 		return val.Elem(), nil
 	case reflect.Interface:
+		// with Typed, a value holding a single placeholder keeps the type of the replaced value, ie: ports are numbers
+		if str, ok := val.Interface().(string); ok && rc.typed && val.Type().NumMethod() == 0 {
+			if typed, ok, err := typedVariable(values, str, rc, matches); ok || err != nil {
+				return typed, err
+			}
+		}
 		vals, err := replaceFields(values, reflect.ValueOf(val.Interface()), rc, matches)
 		if err != nil {
 			return reflect.Value{}, err
@@ -282,23 +298,91 @@ func replaceBytes(values []data.Map, template []byte, rc replaceConfig, nMatches
 	return replace, err
 }
 
-// replaces a variable mark from its corresponding variable or discovered item.
-func variable(values []data.Map, match []byte, rc replaceConfig) ([]byte, error) {
-	// removing ${...}
-	varName := string(bytes.Trim(match, "${}\n\r\t "))
+// typedVariable replaces a template consisting of a single variable mark by the typed value of the variable: an int,
+// float or boolean when it's their canonical representation, or a string slice when the variable is a list, ie:
+// discovery.ports from discovery.ports.0, discovery.ports.1, etc. It returns false when the template has any other
+// content or the variable is not found, to be replaced as a string.
+func typedVariable(values []data.Map, template string, rc replaceConfig, nMatches *int) (reflect.Value, bool, error) {
+	loc := regex.FindStringIndex(template)
+	if loc == nil || loc[0] != 0 || loc[1] != len(template) {
+		return reflect.Value{}, false, nil
+	}
+	varName := strings.Trim(template, "${}\n\r\t ")
+
+	if value, ok := lookupVariable(values, varName, rc); ok {
+		*nMatches++
+		return reflect.ValueOf(typedValue(string(value))), true, nil
+	}
+	if list := listVariable(values, varName); len(list) > 0 {
+		*nMatches++
+		return reflect.ValueOf(list), true, nil
+	}
+	return reflect.Value{}, false, nil
+}
+
+// typedValue returns the int, float or boolean represented by the value, or the value itself. Only their canonical
+// representations are converted, so ie: zip codes with leading zeros are kept as strings.
+func typedValue(value string) interface{} {
+	if i, err := strconv.Atoi(value); err == nil && strconv.Itoa(i) == value {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == value {
+		return f
+	}
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return value
+}
 
+// listVariable returns the values of the variables flattened from a list, named after it with their index.
+func listVariable(values []data.Map, varName string) []string {
+	var list []string
+	for i := 0; ; i++ {
+		item, ok := lookupStatic(values, varName+"."+strconv.Itoa(i))
+		if !ok {
+			return list
+		}
+		list = append(list, item)
+	}
+}
+
+// lookupStatic looks for a variable in the discovered/variables static sources.
+func lookupStatic(values []data.Map, varName string) (string, bool) {
 	for _, vmap := range values {
 		if value, ok := vmap[varName]; ok {
-			return []byte(value), nil
+			return value, true
 		}
 	}
+	return "", false
+}
+
+// lookupVariable looks for a variable in the static sources, and dynamically when it's not found.
+func lookupVariable(values []data.Map, varName string, rc replaceConfig) ([]byte, bool) {
+	if value, ok := lookupStatic(values, varName); ok {
+		return []byte(value), true
+	}
 
 	// if not found in the discovered/variables static sources, we ask dynamically for it
 	for _, onDemand := range rc.onDemand {
 		if value, ok := onDemand(varName); ok {
-			return value, nil
+			return value, true
 		}
 	}
+	return nil, false
+}
+
+// replaces a variable mark from its corresponding variable or discovered item.
+func variable(values []data.Map, match []byte, rc replaceConfig) ([]byte, error) {
+	// removing ${...}
+	varName := string(bytes.Trim(match, "${}\n\r\t "))
+
+	if value, ok := lookupVariable(values, varName, rc); ok {
+		return value, nil
+	}
 
 	// if the placeholder is not from discovery and variable was not found, return it as it is
 	if !strings.HasPrefix(varName, "discovery.") {
@@ -258,6 +258,79 @@ func TestReplace_Struct(t *testing.T) {
 	assert.Equal(t, []string{"host: nopuedor", "ip: 5.6.7.8", "port: 1111"}, ret1.Slice)
 }
 
+func TestReplace_TypedValues(t *testing.T) {
+	t.Parallel()
+	// GIVEN a structure whose non-string fields hold single variable marks
+	type testStruct struct {
+		Name    string
+		Port    interface{}
+		Enabled interface{}
+		Ports   interface{}
+		Config  map[string]interface{}
+		Args    []interface{}
+	}
+	myConfig := testStruct{
+		Name:    "${discovery.port}",
+		Port:    "${discovery.port}",
+		Enabled: "${discovery.label.tls}",
+		Ports:   "${discovery.ports}",
+		Config: map[string]interface{}{
+			"ratio":   "${discovery.label.ratio}",
+			"zip":     "${discovery.label.zip}",
+			"address": "${discovery.ip}:${discovery.port}",
+		},
+		Args: []interface{}{"--port", "${discovery.port}"},
+	}
+
+	// WHEN it is replaced by a discovered item, keeping the types
+	vals := &Values{discov: []discovery.Discovery{{Variables: data.Map{
+		"discovery.ip":          "1.2.3.4",
+		"discovery.port":        "8080",
+		"discovery.ports.0":     "8080",
+		"discovery.ports.1":     "8443",
+		"discovery.label.tls":   "true",
+		"discovery.label.ratio": "0.5",
+		"discovery.label.zip":   "08001",
+	}}}}
+	ret, err := Replace(vals, myConfig, Typed())
+	require.NoError(t, err)
+	require.Len(t, ret, 1)
+
+	// THEN the non-string fields keep the type of the replaced values
+	ret0, ok := ret[0].Variables.(testStruct)
+	require.Truef(t, ok, "the returned value must be of type %T. Was: %T", testStruct{}, ret0)
+	assert.Equal(t, "8080", ret0.Name)
+	assert.Equal(t, 8080, ret0.Port)
+	assert.Equal(t, true, ret0.Enabled)
+	assert.Equal(t, []string{"8080", "8443"}, ret0.Ports)
+	assert.Equal(t, 0.5, ret0.Config["ratio"])
+	// AND the values not in their canonical representation, or mixed with other content, are kept as strings
+	assert.Equal(t, "08001", ret0.Config["zip"])
+	assert.Equal(t, "1.2.3.4:8080", ret0.Config["address"])
+	assert.Equal(t, []interface{}{"--port", 8080}, ret0.Args)
+}
+
+func TestReplace_UntypedByDefault(t *testing.T) {
+	t.Parallel()
+	// GIVEN a map of untyped values, as the agent custom attributes
+	attributes := map[string]interface{}{
+		"port":    "${discovery.port}",
+		"enabled": "${discovery.label.tls}",
+	}
+
+	// WHEN it is replaced without keeping the types
+	vals := &Values{discov: []discovery.Discovery{{Variables: data.Map{
+		"discovery.port":      "8080",
+		"discovery.label.tls": "true",
+	}}}}
+	ret, err := Replace(vals, attributes)
+	require.NoError(t, err)
+	require.Len(t, ret, 1)
+
+	// THEN the values are replaced as strings
+	assert.Equal(t, map[string]interface{}{"port": "8080", "enabled": "true"}, ret[0].Variables)
+}
+
 func TestFetchReplace_WithVars(t *testing.T) {
 	t.Parallel()
 	// GIVEN a discovery source that returns 2 matches
@@ -4,9 +4,14 @@ package helpers
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
+
+	"github.com/google/shlex"
 )
 
 type Command struct {
@@ -32,3 +37,31 @@ func (c *Command) Run() (string, error) {
 func RunCommand(command string, stdin string, arguments ...string) (string, error) {
 	return NewCommand(command, arguments...).WithStdin(stdin).Run()
 }
+
+// RunCommandLine runs the command line, split into arguments as a shell does but not run through one, returning its
+// trimmed output. The command is killed when the context is cancelled or the timeout expires, and its standard error
+// is included in the returned error.
+func RunCommandLine(ctx context.Context, timeout time.Duration, commandLine string) (string, error) {
+	args, err := shlex.Split(commandLine)
+	if err != nil {
+		return "", fmt.Errorf("invalid command %q: %w", commandLine, err)
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("command %s failed: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("command %s failed: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
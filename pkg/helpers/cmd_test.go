@@ -6,9 +6,12 @@
 package helpers
 
 import (
+	"context"
+	"testing"
+	"time"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"testing"
 )
 
 func TestRunCommand(t *testing.T) {
@@ -30,3 +33,19 @@ func TestRunCommand(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, "", obtainedOutput[2])
 }
+
+func TestRunCommandLine(t *testing.T) {
+	output, err := RunCommandLine(context.Background(), time.Second, `echo "  quoted   value  "`)
+	require.NoError(t, err)
+	assert.Equal(t, "quoted   value", output)
+
+	_, err = RunCommandLine(context.Background(), time.Second, `sh -c "echo failure >&2; exit 1"`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failure")
+
+	_, err = RunCommandLine(context.Background(), 50*time.Millisecond, "sleep 5")
+	require.Error(t, err)
+
+	_, err = RunCommandLine(context.Background(), time.Second, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dedupe aggregates the occurrences of recurring log entries or events, so only the first one within a
+// window is reported as is and the rest are summarized with their count once the window ends.
+package dedupe
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// maxKeys bounds the tracked keys, so unique values leaking into the keys don't make the memory grow.
+const maxKeys = 10000
+
+var numberRegex = regexp.MustCompile(`\d+`)
+
+// Normalize masks the numbers of a text, so the occurrences differing only on ids, ie: "cannot find process with
+// pid 1234", share the same key.
+func Normalize(text string) string {
+	return numberRegex.ReplaceAllString(text, "N")
+}
+
+// Summary is a key repeated within a window.
+type Summary struct {
+	Key string
+	// Count is the amount of occurrences suppressed after the first one.
+	Count int
+	// Last is the value of the last occurrence.
+	Last   interface{}
+	Window time.Duration
+}
+
+type occurrences struct {
+	since time.Time
+	count int
+	last  interface{}
+}
+
+// Aggregator tracks the occurrences of the keys within a window.
+type Aggregator struct {
+	lock    sync.Mutex
+	window  time.Duration
+	now     func() time.Time
+	entries map[string]*occurrences
+}
+
+// NewAggregator creates an aggregator for the window.
+func NewAggregator(window time.Duration) *Aggregator {
+	return &Aggregator{
+		window:  window,
+		now:     time.Now,
+		entries: make(map[string]*occurrences),
+	}
+}
+
+// Seen records an occurrence of the key, returning true when it's the first one within the window, which must be
+// reported as is. The rest are counted, keeping the last value for the summary.
+func (a *Aggregator) Seen(key string, value interface{}) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := a.now()
+	if o, ok := a.entries[key]; ok && now.Sub(o.since) < a.window {
+		o.count++
+		o.last = value
+		return false
+	}
+	if _, ok := a.entries[key]; !ok && len(a.entries) >= maxKeys {
+		return true
+	}
+	a.entries[key] = &occurrences{since: now}
+	return true
+}
+
+// Expired returns the summaries of the keys repeated within the windows ended, forgetting the ended windows.
+func (a *Aggregator) Expired() []Summary {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := a.now()
+	var summaries []Summary
+	for key, o := range a.entries {
+		if now.Sub(o.since) < a.window {
+			continue
+		}
+		if o.count > 0 {
+			summaries = append(summaries, Summary{Key: key, Count: o.count, Last: o.last, Window: a.window})
+		}
+		delete(a.entries, key)
+	}
+	return summaries
+}
+
+// Run reports the summaries of the windows ended every interval, and the pending ones once the context is done.
+func (a *Aggregator) Run(ctx context.Context, interval time.Duration, report func(Summary)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, s := range a.Expired() {
+				report(s)
+			}
+		case <-ctx.Done():
+			for _, s := range a.Pending() {
+				report(s)
+			}
+			return
+		}
+	}
+}
+
+// Pending returns the summaries of all the repeated keys, regardless of their window, forgetting them.
+func (a *Aggregator) Pending() []Summary {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var summaries []Summary
+	for key, o := range a.entries {
+		if o.count > 0 {
+			summaries = append(summaries, Summary{Key: key, Count: o.count, Last: o.last, Window: a.now().Sub(o.since)})
+		}
+	}
+	a.entries = make(map[string]*occurrences)
+	return summaries
+}
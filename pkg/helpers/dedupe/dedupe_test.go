@@ -0,0 +1,53 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dedupe
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "cannot find process with pid N", Normalize("cannot find process with pid 1234"))
+	assert.Equal(t, "no numbers", Normalize("no numbers"))
+}
+
+func TestAggregator(t *testing.T) {
+	now := time.Now()
+	a := NewAggregator(time.Minute)
+	a.now = func() time.Time { return now }
+
+	assert.True(t, a.Seen("error", 1))
+	assert.False(t, a.Seen("error", 2))
+	assert.False(t, a.Seen("error", 3))
+	assert.True(t, a.Seen("once", 1))
+	assert.Empty(t, a.Expired())
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, []Summary{{Key: "error", Count: 2, Last: 3, Window: time.Minute}}, a.Expired())
+
+	// a new window starts
+	assert.True(t, a.Seen("error", 4))
+	assert.True(t, a.Seen("once", 2))
+	assert.False(t, a.Seen("once", 3))
+
+	now = now.Add(time.Second)
+	assert.Equal(t, []Summary{{Key: "once", Count: 1, Last: 3, Window: time.Second}}, a.Pending())
+	assert.Empty(t, a.Pending())
+	assert.True(t, a.Seen("error", 5))
+}
+
+func TestAggregator_MaxKeys(t *testing.T) {
+	a := NewAggregator(time.Minute)
+	for i := 0; i < maxKeys; i++ {
+		require.True(t, a.Seen("key"+strconv.Itoa(i), nil))
+	}
+	// untracked keys are always reported
+	assert.True(t, a.Seen("new", nil))
+	assert.True(t, a.Seen("new", nil))
+}
@@ -18,4 +18,11 @@ const (
 	OS_UNKNOWN
 
 	LINUX_COREOS
+	LINUX_ALPINE
+)
+
+// Environments the agent runs in which don't expose all the host metrics, as returned by GetEnvironment.
+const (
+	EnvironmentWSL2             = "wsl2"
+	EnvironmentWindowsContainer = "windows_container"
 )
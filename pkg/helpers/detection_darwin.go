@@ -13,3 +13,7 @@ func GetLinuxDistro() int {
 func GetLinuxOSInfo() (info map[string]string, err error) {
 	return
 }
+
+func GetEnvironment() string {
+	return ""
+}
@@ -32,6 +32,8 @@ func GetLinuxDistro() int {
 				return LINUX_COREOS
 			case identity == "sles":
 				return LINUX_SUSE
+			case identity == "alpine":
+				return LINUX_ALPINE
 			}
 		}
 		// Look alikes
@@ -41,6 +43,8 @@ func GetLinuxDistro() int {
 				return LINUX_DEBIAN
 			case strings.Contains(like, "rhel"), strings.Contains(like, "fedora"):
 				return LINUX_REDHAT
+			case strings.Contains(like, "suse"):
+				return LINUX_SUSE
 			}
 		}
 	}
@@ -95,3 +99,30 @@ func IsAmazonOS() bool {
 
 	return false
 }
+
+// GetEnvironment returns EnvironmentWSL2 when running in a Windows Subsystem for Linux 2 distribution, empty otherwise.
+func GetEnvironment() string {
+	osRelease, err := os.ReadFile(HostProc("/sys/kernel/osrelease"))
+	if err != nil {
+		return ""
+	}
+	return environmentFromKernelRelease(string(osRelease))
+}
+
+// WSL2 kernels are released as <version>-microsoft-standard[-WSL2], while WSL1 reports <version>-Microsoft.
+func environmentFromKernelRelease(release string) string {
+	release = strings.ToLower(strings.TrimSpace(release))
+	if strings.Contains(release, "microsoft-standard") || strings.HasSuffix(release, "-wsl2") {
+		return EnvironmentWSL2
+	}
+	return ""
+}
+
+// IsSystemdRunning returns whether systemd is the init process, as it isn't in WSL2 distributions unless enabled.
+func IsSystemdRunning() bool {
+	comm, err := os.ReadFile(HostProc("/1/comm"))
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(comm)) == "systemd"
+}
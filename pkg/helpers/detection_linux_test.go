@@ -49,6 +49,15 @@ ANSI_COLOR="1;32"
 HOME_URL="https://coreos.com/"
 BUG_REPORT_URL="https://github.com/coreos/bugs/issues"`,
 	)
+
+	ALPINE = []byte(`
+NAME="Alpine Linux"
+ID=alpine
+VERSION_ID=3.18.4
+PRETTY_NAME="Alpine Linux v3.18"
+HOME_URL="https://alpinelinux.org/"
+BUG_REPORT_URL="https://gitlab.alpinelinux.org/alpine/aports/-/issues"`,
+	)
 )
 
 func (s *DetectionSuite) TestGetLinuxDistroCoreOS(c *C) {
@@ -67,6 +76,22 @@ func (s *DetectionSuite) TestGetLinuxDistroCoreOS(c *C) {
 	c.Assert(val, Equals, LINUX_COREOS)
 }
 
+func (s *DetectionSuite) TestGetLinuxDistroAlpine(c *C) {
+	tmpEtc, err := ioutil.TempDir("", "/testing")
+	if err != nil {
+		c.Fatal(err)
+	}
+	defer os.RemoveAll(tmpEtc)
+
+	tmpEtc2 := filepath.Join(tmpEtc, "os-release")
+	if err := ioutil.WriteFile(tmpEtc2, ALPINE, 0666); err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("HOST_ETC", tmpEtc)
+	val := GetLinuxDistro()
+	c.Assert(val, Equals, LINUX_ALPINE)
+}
+
 func (s *DetectionSuite) TestGetLinuxDistro(c *C) {
 	tmpEtc, err := ioutil.TempDir("", "/testing")
 	if err != nil {
@@ -118,3 +143,11 @@ func (s *DetectionSuite) TestIsAmazonOS(c *C) {
 	os.Setenv("HOST_ETC", filepath.Dir(tmpEc2))
 	c.Assert(IsAmazonOS(), Equals, true)
 }
+
+func (s *DetectionSuite) TestEnvironmentFromKernelRelease(c *C) {
+	c.Assert(environmentFromKernelRelease("5.15.90.1-microsoft-standard-WSL2\n"), Equals, EnvironmentWSL2)
+	c.Assert(environmentFromKernelRelease("4.19.104-microsoft-standard"), Equals, EnvironmentWSL2)
+	// WSL1 doesn't run a Linux kernel
+	c.Assert(environmentFromKernelRelease("4.4.0-19041-Microsoft"), Equals, "")
+	c.Assert(environmentFromKernelRelease("6.2.0-1018-azure"), Equals, "")
+}
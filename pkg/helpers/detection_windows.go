@@ -2,6 +2,23 @@
 // SPDX-License-Identifier: Apache-2.0
 package helpers
 
+import "golang.org/x/sys/windows/registry"
+
 func GetOS() int {
 	return OS_WINDOWS
 }
+
+// GetEnvironment returns EnvironmentWindowsContainer when running in a Windows container, either process or Hyper-V
+// isolated, empty otherwise. Windows only sets the ContainerType value in containers.
+func GetEnvironment() string {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control`, registry.QUERY_VALUE)
+	if err != nil {
+		return ""
+	}
+	defer key.Close()
+
+	if _, _, err = key.GetIntegerValue("ContainerType"); err != nil {
+		return ""
+	}
+	return EnvironmentWindowsContainer
+}
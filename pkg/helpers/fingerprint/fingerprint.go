@@ -66,6 +66,9 @@ type Fingerprint struct {
 	BootID          string    `json:"bootId"`
 	IpAddresses     Addresses `json:"ipAddresses"`
 	MacAddresses    Addresses `json:"macAddresses"`
+	// application-specific host identifiers, only reported with the clone detection enabled
+	MachineID string `json:"machineId,omitempty"`
+	DMIUUID   string `json:"dmiUuid,omitempty"`
 }
 
 // Addresses will store the nic addresses mapped by the nickname.
@@ -78,6 +81,8 @@ func (f *Fingerprint) Equals(new Fingerprint) bool {
 		f.CloudProviderId == new.CloudProviderId &&
 		f.BootID == new.BootID &&
 		f.DisplayName == new.DisplayName &&
+		f.MachineID == new.MachineID &&
+		f.DMIUUID == new.DMIUUID &&
 		f.IpAddresses.Equals(new.IpAddresses) &&
 		f.MacAddresses.Equals(new.MacAddresses)
 }
@@ -141,6 +146,12 @@ func (ir *harvestor) Harvest() (fp Fingerprint, err error) {
 		}
 	}
 
+	// the cloud instance ID is always reported, as it was before the identity sources were configurable
+	var ids Identifiers
+	if ir.config.CloneDetectionEnabled {
+		ids = HarvestIdentifiers(ir.config.HostIdentitySources, nil)
+	}
+
 	return Fingerprint{
 		FullHostname:    fullHostname,
 		Hostname:        shortHostname,
@@ -149,6 +160,8 @@ func (ir *harvestor) Harvest() (fp Fingerprint, err error) {
 		IpAddresses:     ipAddresses,
 		CloudProviderId: instanceID,
 		MacAddresses:    macAddresses,
+		MachineID:       ids.MachineID,
+		DMIUUID:         ids.DMIUUID,
 	}, nil
 }
 
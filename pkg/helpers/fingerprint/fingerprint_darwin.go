@@ -5,3 +5,11 @@ package fingerprint
 func GetBootId() string {
 	return ""
 }
+
+func GetMachineID() string {
+	return ""
+}
+
+func GetProductUUID() string {
+	return ""
+}
@@ -2,8 +2,37 @@
 // SPDX-License-Identifier: Apache-2.0
 package fingerprint
 
-import "github.com/newrelic/infrastructure-agent/pkg/helpers"
+import (
+	"os"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
 
 func GetBootId() string {
 	return helpers.ReadFirstLine(helpers.HostProc("/sys/kernel/random/boot_id"))
 }
+
+// GetMachineID returns the systemd machine ID, or the D-Bus one in older distributions, empty when there's none.
+func GetMachineID() string {
+	for _, file := range []string{helpers.HostEtc("machine-id"), helpers.HostVar("lib/dbus/machine-id")} {
+		if id := readTrimmed(file); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// GetProductUUID returns the SMBIOS system UUID set by the hypervisor or the firmware, empty when it's not readable,
+// as it requires root privileges.
+func GetProductUUID() string {
+	return strings.ToLower(readTrimmed(helpers.HostSys("class/dmi/id/product_uuid")))
+}
+
+func readTrimmed(file string) string {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
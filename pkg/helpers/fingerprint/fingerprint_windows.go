@@ -2,6 +2,35 @@
 // SPDX-License-Identifier: Apache-2.0
 package fingerprint
 
+import (
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
 func GetBootId() string {
 	return ""
 }
+
+// GetMachineID returns the machine GUID generated when Windows is installed, which sysprep regenerates.
+func GetMachineID() string {
+	return readRegistryString(`SOFTWARE\Microsoft\Cryptography`, "MachineGuid")
+}
+
+// GetProductUUID returns the SMBIOS system UUID set by the hypervisor or the firmware.
+func GetProductUUID() string {
+	return strings.ToLower(readRegistryString(`SYSTEM\HardwareConfig`, "LastConfig"))
+}
+
+func readRegistryString(path, name string) string {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return ""
+	}
+	defer key.Close()
+	value, _, err := key.GetStringValue(name)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(value, "{} ")
+}
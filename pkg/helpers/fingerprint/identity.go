@@ -0,0 +1,133 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package fingerprint
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+)
+
+// Sources of the host identity, by default in their order of precedence.
+const (
+	IdentitySourceCloudInstanceID = "cloud_instance_id"
+	IdentitySourceDMIUUID         = "dmi_uuid"
+	IdentitySourceMachineID       = "machine_id"
+)
+
+// identitiesFile stores the host identifiers of the last agent run in the data directory.
+const identitiesFile = "host_identifiers.json"
+
+// appID identifies the agent in the application-specific host identifiers.
+var appID = [16]byte{0x6e, 0x72, 0x2d, 0x69, 0x6e, 0x66, 0x72, 0x61, 0x9c, 0x1f, 0x4b, 0x7a, 0xd2, 0x53, 0x0e, 0x88}
+
+// Identifiers are the identifiers of the host which aren't expected to change during its life, unless the host is
+// a clone of another one. The DMI UUID and the machine ID are application-specific, as they must be kept confidential.
+type Identifiers struct {
+	CloudInstanceID string `json:"cloudInstanceId,omitempty"`
+	DMIUUID         string `json:"dmiUuid,omitempty"`
+	MachineID       string `json:"machineId,omitempty"`
+}
+
+// HarvestIdentifiers returns the identifiers of the host from the sources, the unknown sources being ignored. The
+// cloud instance ID is empty when the instance metadata isn't available.
+func HarvestIdentifiers(sources []string, cloudHarvester cloud.Harvester) Identifiers {
+	var ids Identifiers
+	for _, source := range sources {
+		switch source {
+		case IdentitySourceCloudInstanceID:
+			if cloudHarvester != nil && cloudHarvester.GetCloudType().IsValidCloud() {
+				ids.CloudInstanceID, _ = cloudHarvester.GetInstanceID()
+			}
+		case IdentitySourceDMIUUID:
+			ids.DMIUUID = appSpecificID(GetProductUUID())
+		case IdentitySourceMachineID:
+			ids.MachineID = appSpecificID(GetMachineID())
+		}
+	}
+	return ids
+}
+
+// appSpecificID derives an identifier of the agent from a host identifier, as sd_id128_get_machine_app_specific does:
+// the HMAC-SHA256 of the application ID keyed by the host identifier, truncated to a version 4 UUID. The host
+// identifier can't be recovered from it, nor correlated with the ones derived by other applications.
+func appSpecificID(id string) string {
+	if id == "" {
+		return ""
+	}
+	key := []byte(id)
+	if raw, err := hex.DecodeString(strings.ReplaceAll(id, "-", "")); err == nil && len(raw) == 16 {
+		key = raw
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(appID[:])
+	sum := mac.Sum(nil)[:16]
+	sum[6] = sum[6]&0x0f | 0x40
+	sum[8] = sum[8]&0x3f | 0x80
+	return hex.EncodeToString(sum)
+}
+
+func (i Identifiers) get(source string) string {
+	switch source {
+	case IdentitySourceCloudInstanceID:
+		return i.CloudInstanceID
+	case IdentitySourceDMIUUID:
+		return i.DMIUUID
+	case IdentitySourceMachineID:
+		return i.MachineID
+	}
+	return ""
+}
+
+// IsClone returns whether the host is a clone of the one with the stored identifiers, and the source telling them
+// apart. The identifiers are compared by the first source, in order of precedence, known for both hosts, so a machine
+// ID left over in a golden image doesn't matter when the cloud instance IDs differ, and the other way around. A
+// stored cloud instance ID not known now, as the instance metadata isn't retrieved yet or failed, isn't conclusive,
+// as the rest of identifiers may change during the life of the instance.
+func IsClone(stored, current Identifiers, sources []string) (source string, cloned bool) {
+	for _, source := range sources {
+		s, c := stored.get(source), current.get(source)
+		if s != "" && c == "" && source == IdentitySourceCloudInstanceID {
+			return "", false
+		}
+		if s == "" || c == "" {
+			continue
+		}
+		return source, s != c
+	}
+	return "", false
+}
+
+// LoadIdentifiers returns the host identifiers stored in the data directory, false when there are none.
+func LoadIdentifiers(dataDir string) (Identifiers, bool, error) {
+	var ids Identifiers
+	content, err := os.ReadFile(filepath.Join(dataDir, identitiesFile))
+	if os.IsNotExist(err) {
+		return ids, false, nil
+	}
+	if err != nil {
+		return ids, false, err
+	}
+	if err = json.Unmarshal(content, &ids); err != nil {
+		return ids, false, err
+	}
+	return ids, true, nil
+}
+
+// StoreIdentifiers stores the host identifiers in the data directory.
+func StoreIdentifiers(dataDir string, ids Identifiers) error {
+	content, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dataDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dataDir, identitiesFile), content, 0o644)
+}
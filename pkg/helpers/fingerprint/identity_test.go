@@ -0,0 +1,77 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package fingerprint
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestIsClone(t *testing.T) {
+	sources := []string{IdentitySourceCloudInstanceID, IdentitySourceDMIUUID, IdentitySourceMachineID}
+	golden := Identifiers{CloudInstanceID: "i-1", DMIUUID: "ec2a-1", MachineID: "golden"}
+
+	tests := []struct {
+		name    string
+		current Identifiers
+		sources []string
+		source  string
+		cloned  bool
+	}{
+		{"Same", golden, sources, IdentitySourceCloudInstanceID, false},
+		{"OtherInstance", Identifiers{CloudInstanceID: "i-2", DMIUUID: "ec2a-2", MachineID: "golden"}, sources, IdentitySourceCloudInstanceID, true},
+		// the machine ID is only compared when the previous identifiers aren't known
+		{"MachineIDRegenerated", Identifiers{CloudInstanceID: "i-1", DMIUUID: "ec2a-1", MachineID: "new"}, sources, IdentitySourceCloudInstanceID, false},
+		// the instance metadata isn't retrieved yet, or failed
+		{"CloudUnavailable", Identifiers{DMIUUID: "ec2a-2", MachineID: "new"}, sources, "", false},
+		{"MachineIDPrecedence", Identifiers{CloudInstanceID: "i-1", MachineID: "new"}, []string{IdentitySourceMachineID, IdentitySourceCloudInstanceID}, IdentitySourceMachineID, true},
+		{"NoneKnown", Identifiers{}, sources, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, cloned := IsClone(golden, tt.current, tt.sources)
+			assert.Equal(t, tt.source, source)
+			assert.Equal(t, tt.cloned, cloned)
+		})
+	}
+}
+
+func TestIsClone_NotInCloud(t *testing.T) {
+	sources := []string{IdentitySourceCloudInstanceID, IdentitySourceDMIUUID, IdentitySourceMachineID}
+	golden := Identifiers{DMIUUID: "vmware-1", MachineID: "golden"}
+
+	source, cloned := IsClone(golden, Identifiers{DMIUUID: "vmware-2", MachineID: "golden"}, sources)
+	assert.Equal(t, IdentitySourceDMIUUID, source)
+	assert.Assert(t, cloned)
+}
+
+func TestAppSpecificID(t *testing.T) {
+	machineID := "b08dfa6083e7567a1921a715000001fb"
+	id := appSpecificID(machineID)
+
+	// a version 4 UUID, not revealing the machine ID
+	assert.Equal(t, 32, len(id))
+	assert.Equal(t, byte('4'), id[12])
+	assert.Assert(t, id != machineID)
+	// stable, and the same for the UUID representation of the identifier
+	assert.Equal(t, id, appSpecificID(machineID))
+	assert.Equal(t, id, appSpecificID("b08dfa60-83e7-567a-1921-a715000001fb"))
+	assert.Assert(t, id != appSpecificID("b08dfa6083e7567a1921a715000001fc"))
+	assert.Equal(t, "", appSpecificID(""))
+}
+
+func TestStoreIdentifiers(t *testing.T) {
+	dataDir := t.TempDir()
+	_, found, err := LoadIdentifiers(dataDir)
+	assert.NilError(t, err)
+	assert.Assert(t, !found)
+
+	ids := Identifiers{CloudInstanceID: "i-1", MachineID: "golden"}
+	assert.NilError(t, StoreIdentifiers(dataDir, ids))
+	loaded, found, err := LoadIdentifiers(dataDir)
+	assert.NilError(t, err)
+	assert.Assert(t, found)
+	assert.Equal(t, ids, loaded)
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package redact hides the secrets, ie: license keys, cloud credentials or tokens, from the data the agent writes or
+// sends: its own logs, the inventory and the events, including the ones of the integrations.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+// builtinPatterns match well-known secrets. When a pattern has a group, only the group is hidden.
+var builtinPatterns = []string{
+	// New Relic license keys and user API keys
+	`\b((?:[a-z]{2}\d{2}x{2,})?[0-9a-f]{32,40}NRAL)\b`,
+	`\b(NRAK-[0-9A-Z]{27})\b`,
+	// AWS access key IDs and secret access keys
+	`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`,
+	`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`,
+	// Bearer tokens, ie: in Authorization headers
+	`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`,
+}
+
+// jsonStringRegex matches the string literals of a JSON document.
+var jsonStringRegex = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+var defaultRedactor atomic.Value
+
+// Redactor hides the matches of a set of patterns.
+type Redactor struct {
+	regexes []*regexp.Regexp
+}
+
+// New creates a redactor for the built-in patterns, the license key and the given regular expressions.
+func New(license string, patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, pattern := range builtinPatterns {
+		r.regexes = append(r.regexes, regexp.MustCompile(pattern))
+	}
+	if license = strings.TrimSpace(license); license != "" {
+		r.regexes = append(r.regexes, regexp.MustCompile(regexp.QuoteMeta(license)))
+	}
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.regexes = append(r.regexes, regex)
+	}
+	return r, nil
+}
+
+// SetDefault sets the redactor applied by the package functions, nil disabling the redaction.
+func SetDefault(r *Redactor) {
+	defaultRedactor.Store(r)
+}
+
+// Default returns the redactor applied by the package functions, nil when the redaction is disabled.
+func Default() *Redactor {
+	r, _ := defaultRedactor.Load().(*Redactor)
+	return r
+}
+
+// String hides the secrets of a string with the default redactor.
+func String(s string) string {
+	return Default().String(s)
+}
+
+// JSON hides the secrets of the string values of a JSON document with the default redactor.
+func JSON(b []byte) []byte {
+	return Default().JSON(b)
+}
+
+// String returns the string with the secrets hidden.
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, regex := range r.regexes {
+		s = hide(regex, s)
+	}
+	return s
+}
+
+// JSON returns the JSON document with the secrets of its string values hidden, so it remains valid whatever the
+// patterns match.
+func (r *Redactor) JSON(b []byte) []byte {
+	if r == nil || !r.matches(b) {
+		return b
+	}
+	return jsonStringRegex.ReplaceAllFunc(b, func(literal []byte) []byte {
+		if !r.matches(literal) {
+			return literal
+		}
+		var value string
+		if err := json.Unmarshal(literal, &value); err != nil {
+			return literal
+		}
+		// without escaping the hidden placeholder as HTML
+		var redacted bytes.Buffer
+		enc := json.NewEncoder(&redacted)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(r.String(value)); err != nil {
+			return literal
+		}
+		return bytes.TrimSuffix(redacted.Bytes(), []byte("\n"))
+	})
+}
+
+func (r *Redactor) matches(b []byte) bool {
+	for _, regex := range r.regexes {
+		if regex.Match(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// hide replaces the first group of the matches, or the whole match if there's no group, with the hidden placeholder.
+func hide(regex *regexp.Regexp, s string) string {
+	matches := regex.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var result strings.Builder
+	last := 0
+	for _, indexes := range matches {
+		start, end := indexes[0], indexes[1]
+		if len(indexes) >= 4 && indexes[2] >= 0 {
+			start, end = indexes[2], indexes[3]
+		}
+		result.WriteString(s[last:start])
+		result.WriteString(helpers.HiddenField)
+		last = end
+	}
+	result.WriteString(s[last:])
+	return result.String()
+}
@@ -5,6 +5,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"runtime"
 	"strings"
 	"time"
 
@@ -24,6 +25,14 @@ type ConfigEntry struct {
 	Labels       map[string]string `yaml:"labels" json:"labels"`
 	Tags         map[string]string `yaml:"tags" json:"tags"`
 	When         EnableConditions  `yaml:"when" json:"when"`
+	Protocol     string            `yaml:"protocol" json:"protocol"` // how the data is sent: stdout (default) or grpc
+	Limits       ResourceLimits    `yaml:"limits" json:"limits"`
+	// SecretsDelivery is how the secret variables reach the integration: args (default), file or fd
+	SecretsDelivery string `yaml:"secrets_delivery" json:"secrets_delivery"`
+	// Schedule runs the integration at cron times, once or within a time window, instead of every interval
+	Schedule Schedule `yaml:"schedule" json:"schedule"`
+	// Restart decides whether the integration runs again after its executions, and stops it when it keeps failing
+	Restart Restart `yaml:"restart" json:"restart"`
 
 	// Legacy definition commands
 	Command         string            `yaml:"command" json:"command"`
@@ -38,6 +47,61 @@ type ConfigEntry struct {
 	LogsQueueSize int    `yaml:"logs_queue_size" json:"logs_queue_size"`
 }
 
+// ResourceLimits bound the resources of every instance of the integration, enforced by cgroups on Linux and by job
+// objects on Windows. The instances killed for exceeding the memory limit or the timeout run again after a backoff.
+type ResourceLimits struct {
+	CPUPercent float64 `yaml:"cpu_percent" json:"cpu_percent"` // percentage of a single CPU, ie: 150 for one and a half
+	MemoryMB   int     `yaml:"memory_mb" json:"memory_mb"`
+	// MaxBackoff bounds the delay before running again a killed instance, which doubles from 10 seconds on every
+	// consecutive kill. 5 minutes when not set.
+	MaxBackoff *time.Duration `yaml:"max_backoff" json:"max_backoff"`
+}
+
+// Schedule defines when the integration executions start. The cron expression and the window are in the local time
+// of the host.
+type Schedule struct {
+	Cron    string `yaml:"cron" json:"cron"`         // 5 fields cron expression or macro, ie: "0 3 * * 0" or "@daily"
+	Window  string `yaml:"window" json:"window"`     // daily time window, ie: "02:00-04:00"
+	RunOnce bool   `yaml:"run_once" json:"run_once"` // a single execution when the integration is loaded
+}
+
+// Restart policies, whether an integration runs again after an execution.
+const (
+	RestartAlways    = "always"
+	RestartOnFailure = "on-failure"
+	RestartNever     = "never"
+)
+
+// Restart defines the restart policy of an integration and its crash-loop breaker, which stops the integration after
+// MaxFailures failed executions within FailuresWindow. A failed execution is an instance exiting with an error, not
+// starting or killed by its limits or timeout.
+type Restart struct {
+	Policy string `yaml:"policy" json:"policy"` // always, on-failure or never. Not set: always, without backoff
+	// Backoff delays running again a failed execution, doubling on every consecutive failure up to the limits
+	// max_backoff. 10 seconds when not set.
+	Backoff        *time.Duration `yaml:"backoff" json:"backoff"`
+	MaxFailures    int            `yaml:"max_failures" json:"max_failures"`       // 0 disables the crash-loop breaker
+	FailuresWindow *time.Duration `yaml:"failures_window" json:"failures_window"` // 10 minutes when not set
+}
+
+// Integration protocols, the way integrations send their data to the agent.
+const (
+	ProtocolStdout = "stdout"
+	ProtocolGRPC   = "grpc"
+)
+
+// Secrets deliveries, the way the secret variables reach the integrations. With file and fd the secrets can't be
+// referenced from the arguments, which are visible to every user of the host.
+const (
+	// SecretsDeliveryArgs replaces the secrets anywhere in the integration configuration.
+	SecretsDeliveryArgs = "args"
+	// SecretsDeliveryFile writes every secret into a file of a private directory, set in NRI_SECRETS_DIR.
+	SecretsDeliveryFile = "file"
+	// SecretsDeliveryFD writes the secrets as a JSON object into an inherited pipe, whose descriptor is set in
+	// NRI_SECRETS_FD. Not supported on Windows.
+	SecretsDeliveryFD = "fd"
+)
+
 // EnableConditions condition the execution of an integration to the trueness of ALL the conditions
 type EnableConditions struct {
 	// Feature allows enabling/disabling the OHI via agent cfg "feature" or cmd-channel Feature Flag
@@ -47,6 +111,9 @@ type EnableConditions struct {
 	// EnvExists conditions the execution of the OHI only if the given
 	// environment variables exists and match the value.
 	EnvExists map[string]string `yaml:"env_exists"`
+	// Leader conditions the execution of the OHI to the agent leading the given group, elected among the agents
+	// sharing the agent cfg "leader_election_dir", so only one of them reports the samples of a shared resource.
+	Leader string `yaml:"leader"`
 }
 
 // ShlexOpt is a wrapper around []string so we can use go-shlex for shell tokenizing
@@ -107,6 +174,39 @@ func (cf *ConfigEntry) Sanitize() error {
 		return fmt.Errorf("only 'config' or 'config_template_path' is allowed, not both at the same time")
 	}
 
+	if cf.Protocol != "" && cf.Protocol != ProtocolStdout && cf.Protocol != ProtocolGRPC {
+		return fmt.Errorf("invalid 'protocol' %q, expected %q or %q", cf.Protocol, ProtocolStdout, ProtocolGRPC)
+	}
+	if cf.Protocol == ProtocolGRPC && cf.IntegrationName != "" {
+		return errors.New("the 'grpc' protocol isn't supported by legacy 'integration_name' entries")
+	}
+
+	switch cf.SecretsDelivery {
+	case "", SecretsDeliveryArgs, SecretsDeliveryFile:
+	case SecretsDeliveryFD:
+		if runtime.GOOS == "windows" {
+			return errors.New("the 'fd' secrets delivery isn't supported on Windows")
+		}
+	default:
+		return fmt.Errorf("invalid 'secrets_delivery' %q, expected %q, %q or %q",
+			cf.SecretsDelivery, SecretsDeliveryArgs, SecretsDeliveryFile, SecretsDeliveryFD)
+	}
+
+	if cf.Limits.CPUPercent < 0 || cf.Limits.MemoryMB < 0 || (cf.Limits.MaxBackoff != nil && *cf.Limits.MaxBackoff < 0) {
+		return errors.New("'limits' can't be negative")
+	}
+
+	switch cf.Restart.Policy {
+	case "", RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		return fmt.Errorf("invalid restart 'policy' %q, expected %q, %q or %q",
+			cf.Restart.Policy, RestartAlways, RestartOnFailure, RestartNever)
+	}
+	if cf.Restart.MaxFailures < 0 || (cf.Restart.Backoff != nil && *cf.Restart.Backoff < 0) ||
+		(cf.Restart.FailuresWindow != nil && *cf.Restart.FailuresWindow <= 0) {
+		return errors.New("'restart' backoff and max_failures can't be negative, nor failures_window zero")
+	}
+
 	// Avoids undefined environment configuration to leak a nil map
 	if cf.Env == nil {
 		cf.Env = map[string]string{}
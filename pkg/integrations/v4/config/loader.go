@@ -117,9 +117,22 @@ func (pl *pathLoader) LoadFile(path string) (YAML, error) {
 	if len(cy.Integrations) == 0 {
 		return cy, explainEmptyIntegrations(bytes)
 	}
+	// the included files are expanded as the integrations file
+	if err := cy.Databind.ResolveIncludes(filepath.Dir(path), readExpanded); err != nil {
+		return cy, err
+	}
 	return cy, nil
 }
 
+// readExpanded reads a file expanding its environment variables.
+func readExpanded(path string) ([]byte, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return envvar.ExpandInContent(bytes)
+}
+
 // returns why a v4 integration is empty: because it's a v3 integration or because it has a wrong format
 func explainEmptyIntegrations(bytes []byte) error {
 	var contents map[string]interface{}
@@ -61,6 +61,7 @@ type emitter struct {
 	maxRetryBo                time.Duration
 	idCache                   entity.KnownIDs
 	metricsSender             MetricsSender
+	metricsRoutes             map[string]MetricsSender
 	agentContext              agent.AgentContext
 	registerClient            identityapi.RegisterClient
 	registerWorkers           int
@@ -75,13 +76,25 @@ type Emitter interface {
 	Send(fwrequest.FwRequest)
 }
 
+// EmitterOption customizes the emitter.
+type EmitterOption func(e *emitter)
+
+// WithMetricsRoutes sends the metrics of the integrations, by the name reported in their payload, through their own
+// sender, ie: to another account.
+func WithMetricsRoutes(routes map[string]MetricsSender) EmitterOption {
+	return func(e *emitter) {
+		e.metricsRoutes = routes
+	}
+}
+
 func NewEmitter(
 	agentContext agent.AgentContext,
 	dmSender MetricsSender,
 	registerClient identityapi.RegisterClient,
 	ffRetriever feature_flags.Retriever,
+	opts ...EmitterOption,
 ) Emitter {
-	return &emitter{
+	e := &emitter{
 		retryBo:                   backoff.NewDefaultBackoff(),
 		maxRetryBo:                time.Duration(agentContext.Config().RegisterMaxRetryBoSecs) * time.Second,
 		reqsQueue:                 make(chan fwrequest.FwRequest, defaultRequestsQueueLen),
@@ -98,6 +111,10 @@ func NewEmitter(
 		verboseLogLevel:           agentContext.Config().Log.VerboseEnabled(),
 		ffRetriever:               ffRetriever,
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 // Send receives data forward requests and queues them while processing them on different goroutine.
@@ -189,7 +206,15 @@ func (e *emitter) emitDataset(req fwrequest.EntityFwRequest) {
 
 	emitEvent(&plugin, req.Definition, req.Data, labels, annos, req.ID())
 
-	emitMetrics(e.metricsSender, req.Definition, req.Data, annos, labels)
+	emitMetrics(e.metricsSenderFor(req.Integration.Name), req.Definition, req.Data, annos, labels)
+}
+
+// metricsSenderFor returns the sender of the route for the integration, or the agent one when it's not routed.
+func (e *emitter) metricsSenderFor(integrationName string) MetricsSender {
+	if s, ok := e.metricsRoutes[integrationName]; ok {
+		return s
+	}
+	return e.metricsSender
 }
 
 func emitMetrics(metricSender MetricsSender,
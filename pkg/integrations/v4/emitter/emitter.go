@@ -79,7 +79,8 @@ func (e *VersionAwareEmitter) Emit(definition integration.Definition, extraLabel
 	// Here then we add CustomAttributes to extraLabels in case we are in that mode.
 	if e.aCtx.Config().IsForwardOnly {
 		extraLabelsCopy := make(map[string]string)
-		customAttributes := e.aCtx.Config().CustomAttributes.DataMap()
+		configured := e.aCtx.Config().GetCustomAttributes()
+		customAttributes := configured.DataMap()
 
 		for k, v := range extraLabels {
 			extraLabelsCopy[k] = v
@@ -0,0 +1,86 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Client sends the messages of a Go integration to the agent, waiting for each one to be acknowledged.
+type Client struct {
+	conn     *grpc.ClientConn
+	stream   grpc.ClientStream
+	cancel   context.CancelFunc
+	lock     sync.Mutex
+	sequence uint64
+}
+
+// Dial opens a stream to the agent listening on the local socket of the path, usually the one of SocketEnvVar.
+func Dial(ctx context.Context, path string) (*Client, error) {
+	conn, err := grpc.DialContext(ctx, "passthrough:///"+path,
+		grpc.WithContextDialer(dial),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{}), grpc.MaxCallSendMsgSize(maxMessageSize)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the agent at %s: %w", path, err)
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stream, err := conn.NewStream(streamCtx, &serviceDesc.Streams[0], streamMethod)
+	if err != nil {
+		cancel()
+		_ = conn.Close()
+		return nil, fmt.Errorf("cannot open the stream to the agent at %s: %w", path, err)
+	}
+	return &Client{conn: conn, stream: stream, cancel: cancel}, nil
+}
+
+// Send sends a line of the stdout protocol, ie: a protocol v4 JSON, returning an *AckError when the agent can't
+// process it.
+func (c *Client) Send(payload []byte) error {
+	return c.send(Message{Payload: payload})
+}
+
+// Ping tells the agent the integration is alive.
+func (c *Client) Ping() error {
+	return c.send(Message{Ping: true})
+}
+
+// ReportError reports an error of the integration to the agent.
+func (c *Client) ReportError(message string, attributes map[string]string) error {
+	return c.send(Message{Error: &Error{Message: message, Attributes: attributes}})
+}
+
+func (c *Client) send(msg Message) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.sequence++
+	msg.Sequence = c.sequence
+	if err := c.stream.SendMsg(&msg); err != nil {
+		return fmt.Errorf("cannot send message %d: %w", msg.Sequence, err)
+	}
+	var ack Ack
+	if err := c.stream.RecvMsg(&ack); err != nil {
+		return fmt.Errorf("cannot receive the ack of message %d: %w", msg.Sequence, err)
+	}
+	if ack.Error != "" {
+		return &AckError{Sequence: ack.Sequence, Message: ack.Error}
+	}
+	return nil
+}
+
+// Close ends the stream and the connection.
+func (c *Client) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_ = c.stream.CloseSend()
+	c.cancel()
+	return c.conn.Close()
+}
@@ -0,0 +1,209 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package grpcapi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the messages, from integration.proto.
+const (
+	fieldMessageSequence = 1 // Message.sequence
+	fieldMessagePayload  = 2 // Message.payload
+	fieldMessagePing     = 3 // Message.ping
+	fieldMessageError    = 4 // Message.error
+	fieldErrorMessage    = 1 // Error.message
+	fieldErrorAttributes = 2 // Error.attributes
+	fieldMapEntryKey     = 1 // map entry key
+	fieldMapEntryValue   = 2 // map entry value
+	fieldAckSequence     = 1 // Ack.sequence
+	fieldAckError        = 2 // Ack.error
+)
+
+var errInvalidMessage = errors.New("invalid protobuf message")
+
+// Message is sent by the integration. Only one of Payload, Ping or Error is set.
+type Message struct {
+	Sequence uint64
+	// Payload is a line of the stdout protocol, ie: a protocol v4 JSON.
+	Payload []byte
+	Ping    bool
+	Error   *Error
+}
+
+// Error is reported by the integration.
+type Error struct {
+	Message    string
+	Attributes map[string]string
+}
+
+// Ack acknowledges a message, with the error processing it, if any.
+type Ack struct {
+	Sequence uint64
+	Error    string
+}
+
+// AckError is returned to the integration when the agent can't process one of its messages.
+type AckError struct {
+	Sequence uint64
+	Message  string
+}
+
+func (e *AckError) Error() string {
+	return fmt.Sprintf("message %d not processed: %s", e.Sequence, e.Message)
+}
+
+// Marshal encodes the message as protobuf.
+func (m *Message) Marshal() []byte {
+	var b []byte
+	b = appendVarint(b, fieldMessageSequence, m.Sequence)
+	switch {
+	case m.Error != nil:
+		var e []byte
+		e = appendString(e, fieldErrorMessage, m.Error.Message)
+		keys := make([]string, 0, len(m.Error.Attributes))
+		for key := range m.Error.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			var entry []byte
+			entry = appendString(entry, fieldMapEntryKey, key)
+			entry = appendString(entry, fieldMapEntryValue, m.Error.Attributes[key])
+			e = appendMessage(e, fieldErrorAttributes, entry)
+		}
+		b = appendMessage(b, fieldMessageError, e)
+	case m.Ping:
+		b = appendMessage(b, fieldMessagePing, nil)
+	default:
+		b = appendMessage(b, fieldMessagePayload, m.Payload)
+	}
+	return b
+}
+
+// Unmarshal decodes a protobuf message, ignoring the unknown fields.
+func (m *Message) Unmarshal(b []byte) error {
+	*m = Message{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+		switch {
+		case num == fieldMessageSequence && typ == protowire.VarintType:
+			m.Sequence = v
+		case num == fieldMessagePayload && typ == protowire.BytesType:
+			m.Payload = append([]byte{}, value...)
+		case num == fieldMessagePing && typ == protowire.BytesType:
+			m.Ping = true
+		case num == fieldMessageError && typ == protowire.BytesType:
+			m.Error = &Error{}
+			return m.Error.unmarshal(value)
+		}
+		return nil
+	})
+}
+
+func (e *Error) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case fieldErrorMessage:
+			e.Message = string(value)
+		case fieldErrorAttributes:
+			var key, val string
+			err := consumeFields(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if typ == protowire.BytesType && num == fieldMapEntryKey {
+					key = string(value)
+				} else if typ == protowire.BytesType && num == fieldMapEntryValue {
+					val = string(value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if e.Attributes == nil {
+				e.Attributes = map[string]string{}
+			}
+			e.Attributes[key] = val
+		}
+		return nil
+	})
+}
+
+// Marshal encodes the ack as protobuf.
+func (a *Ack) Marshal() []byte {
+	var b []byte
+	b = appendVarint(b, fieldAckSequence, a.Sequence)
+	return appendString(b, fieldAckError, a.Error)
+}
+
+// Unmarshal decodes a protobuf ack, ignoring the unknown fields.
+func (a *Ack) Unmarshal(b []byte) error {
+	*a = Ack{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+		switch {
+		case num == fieldAckSequence && typ == protowire.VarintType:
+			a.Sequence = v
+		case num == fieldAckError && typ == protowire.BytesType:
+			a.Error = string(value)
+		}
+		return nil
+	})
+}
+
+// consumeFields calls fn for every field of a protobuf message, with its value as bytes for the length-delimited
+// fields, or as a number for the varint ones.
+func consumeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errInvalidMessage
+		}
+		b = b[n:]
+
+		var value []byte
+		var v uint64
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return errInvalidMessage
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, value, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendVarint(b []byte, field protowire.Number, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, field, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func appendString(b []byte, field protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendMessage(b []byte, field protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package grpcapi implements the gRPC protocol of the v4 integrations, described in integration.proto: instead of
+// writing lines to stdout, the integrations stream their messages to the agent through a local socket and get them
+// acknowledged once processed. The messages are encoded by hand, as the protocol only has a few fields and doesn't need
+// the generated protobuf types.
+package grpcapi
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	// SocketEnvVar is the environment variable with the path of the agent socket, set for the integrations using the
+	// gRPC protocol.
+	SocketEnvVar = "NRI_GRPC_SOCKET"
+	// maxMessageSize bounds the size of the payloads, larger than the gRPC default to fit the payloads of the busiest
+	// integrations.
+	maxMessageSize = 16 << 20
+
+	serviceName  = "newrelic.infra.integration.v1.Integration"
+	streamMethod = "/" + serviceName + "/Stream"
+)
+
+// Handler processes the messages of an integration, one at a time and in order.
+type Handler interface {
+	// Payload processes a line of the stdout protocol. Its error is returned to the integration in the ack.
+	Payload(payload []byte) error
+	// Ping notifies the integration is alive.
+	Ping()
+	// Error notifies an error reported by the integration.
+	Error(err Error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*Handler)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       handleStream,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "integration.proto",
+}
+
+// Server receives the messages of an integration through a local socket: a unix domain socket on Linux and macOS, a
+// named pipe on Windows.
+type Server struct {
+	server *grpc.Server
+	path   string
+	socket os.FileInfo
+}
+
+// Listen starts serving the handler in the local socket of the path, replacing any stale socket file.
+func Listen(path string, handler Handler) (*Server, error) {
+	listener, socket, err := listen(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		server: grpc.NewServer(grpc.ForceServerCodec(codec{}), grpc.MaxRecvMsgSize(maxMessageSize)),
+		path:   path,
+		socket: socket,
+	}
+	s.server.RegisterService(&serviceDesc, handler)
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+	return s, nil
+}
+
+// Close stops serving, ending the open streams, and removes the socket unless another server replaced it.
+func (s *Server) Close() {
+	s.server.Stop()
+	if current, err := os.Stat(s.path); err == nil && s.socket != nil && os.SameFile(current, s.socket) {
+		_ = os.Remove(s.path)
+	}
+}
+
+// handleStream acknowledges the messages once handled, so the integrations are blocked by the flow control of the
+// stream while the agent is busy.
+func handleStream(srv interface{}, stream grpc.ServerStream) error {
+	handler := srv.(Handler)
+	for {
+		var msg Message
+		if err := stream.RecvMsg(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		ack := Ack{Sequence: msg.Sequence}
+		switch {
+		case msg.Error != nil:
+			handler.Error(*msg.Error)
+		case msg.Ping:
+			handler.Ping()
+		case len(msg.Payload) > 0:
+			if err := handler.Payload(msg.Payload); err != nil {
+				ack.Error = err.Error()
+			}
+		default:
+			ack.Error = "empty message"
+		}
+
+		if err := stream.SendMsg(&ack); err != nil {
+			return err
+		}
+	}
+}
+
+// codec encodes the messages of the protocol as protobuf.
+type codec struct{}
+
+type marshaler interface {
+	Marshal() []byte
+}
+
+type unmarshaler interface {
+	Unmarshal(b []byte) error
+}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(marshaler)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return m.Marshal(), nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(unmarshaler)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	return m.Unmarshal(data)
+}
+
+// Name is the content subtype of the requests, the one of the clients generated from integration.proto.
+func (codec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHandler struct {
+	lock     sync.Mutex
+	payloads []string
+	pings    int
+	errors   []Error
+}
+
+func (h *recordingHandler) Payload(payload []byte) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if string(payload) == "invalid" {
+		return errors.New("invalid payload")
+	}
+	h.payloads = append(h.payloads, string(payload))
+	return nil
+}
+
+func (h *recordingHandler) Ping() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.pings++
+}
+
+func (h *recordingHandler) Error(err Error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.errors = append(h.errors, err)
+}
+
+func TestServer_Stream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nri.sock")
+	if runtime.GOOS == "windows" {
+		path = `\\.\pipe\nri-test-stream`
+	}
+	handler := &recordingHandler{}
+	server, err := Listen(path, handler)
+	require.NoError(t, err)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := Dial(ctx, path)
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.Send([]byte(`{"protocol_version":"4","data":[]}`)))
+	require.NoError(t, client.Ping())
+	require.NoError(t, client.ReportError("cannot connect", map[string]string{"host": "db", "port": "3306"}))
+
+	err = client.Send([]byte("invalid"))
+	var ackErr *AckError
+	require.True(t, errors.As(err, &ackErr))
+	assert.Equal(t, &AckError{Sequence: 4, Message: "invalid payload"}, ackErr)
+
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	assert.Equal(t, []string{`{"protocol_version":"4","data":[]}`}, handler.payloads)
+	assert.Equal(t, 1, handler.pings)
+	assert.Equal(t, []Error{{Message: "cannot connect", Attributes: map[string]string{"host": "db", "port": "3306"}}},
+		handler.errors)
+}
+
+func TestMessage_Unmarshal(t *testing.T) {
+	for _, msg := range []Message{
+		{Sequence: 1, Payload: []byte("{}")},
+		{Sequence: 2, Ping: true},
+		{Sequence: 3, Error: &Error{Message: "failed", Attributes: map[string]string{"a": "b"}}},
+	} {
+		var decoded Message
+		require.NoError(t, decoded.Unmarshal(msg.Marshal()))
+		assert.Equal(t, msg, decoded)
+	}
+
+	var decoded Message
+	assert.Error(t, decoded.Unmarshal([]byte{0x0a, 0x05}))
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux || darwin
+// +build linux darwin
+
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// listen listens on the unix socket of the path, returning its file so it's only removed by the same server.
+func listen(path string) (net.Listener, os.FileInfo, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("cannot remove stale socket %s: %w", path, err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot listen on %s: %w", path, err)
+	}
+	// the socket may be replaced by the one of a newer server, ie: after a config reload, so it's removed on Close
+	// only when it's still the same
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	// only the agent user, and the integrations running as it, can connect
+	if err = os.Chmod(path, 0600); err != nil {
+		_ = listener.Close()
+		_ = os.Remove(path)
+		return nil, nil, fmt.Errorf("cannot set the permissions of %s: %w", path, err)
+	}
+	// not available on every platform, then the socket is left for the next server to replace it
+	socket, _ := os.Stat(path)
+	return listener, socket, nil
+}
+
+func dial(ctx context.Context, addr string) (net.Conn, error) {
+	return (&net.Dialer{}).DialContext(ctx, "unix", addr)
+}
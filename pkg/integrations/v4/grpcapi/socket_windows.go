@@ -0,0 +1,28 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// pipeSecurity grants access to the agent user, so the integrations running as it, Administrators and LocalSystem.
+const pipeSecurity = "D:P(A;;GA;;;OW)(A;;GA;;;BA)(A;;GA;;;SY)"
+
+// listen listens on the named pipe of the path, ie: \\.\pipe\nri-<hash>. Pipes don't leave files behind.
+func listen(path string) (net.Listener, os.FileInfo, error) {
+	listener, err := winio.ListenPipe(path, &winio.PipeConfig{SecurityDescriptor: pipeSecurity})
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot listen on %s: %w", path, err)
+	}
+	return listener, nil, nil
+}
+
+func dial(ctx context.Context, addr string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, addr)
+}
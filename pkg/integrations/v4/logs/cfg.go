@@ -5,11 +5,15 @@ package logs
 import (
 	"bytes"
 	"fmt"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
 	"github.com/newrelic/infrastructure-agent/pkg/license"
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 	"github.com/pkg/errors"
 	"io/ioutil"
+	"net/url"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -21,6 +25,7 @@ var cfgLogger = log.WithComponent("integrations.Supervisor.Config").WithField("p
 
 // FluentBit default values.
 const (
+	usEndpoint              = "https://log-api.newrelic.com/log/v1"
 	euEndpoint              = "https://log-api.eu.newrelic.com/log/v1"
 	fedrampEndpoint         = "https://gov-log-api.newrelic.com/log/v1"
 	stagingEndpoint         = "https://staging-log-api.newrelic.com/log/v1"
@@ -28,6 +33,12 @@ const (
 	defaultBufferMaxSize    = 128
 	memBufferLimit          = 16384
 	fluentBitDbName         = "fb.db"
+	journaldDbPrefix        = "journald-"
+	storageTypeFilesystem   = "filesystem"
+	storageChunkSizeMB      = 2 // approximate size of the chunks fluent bit stores the records in
+	generatedParsersName    = "generated_parsers.conf"
+	multilineParserPrefix   = "multiline_"
+	multilineFlushTimeoutMs = 1000
 )
 
 // FluentBit INPUT plugin types
@@ -46,10 +57,14 @@ const (
 	fbFilterTypeRecordModifier = "record_modifier"
 	fbFilterTypeLua            = "lua"
 	fbFilterTypeModify         = "modify"
+	fbFilterTypeParser         = "parser"
 )
 
 // Lua Script calling function
-const fbLuaFnNameWinlogEventFilter = "eventIdFilter"
+const (
+	fbLuaFnNameWinlogEventFilter = "eventIdFilter"
+	fbLuaFnNameMultilineMaxLines = "maxLinesFilter"
+)
 
 // Winlog constants
 const (
@@ -71,11 +86,29 @@ const (
 	rAttHostname   = "hostname"
 )
 
+// winevtlogLevels are the Windows event levels names by their number, starting at 1 as 0 (LogAlways) is an
+// informational one.
+var winevtlogLevels = []string{"critical", "error", "warning", "information", "verbose"}
+
+// journaldPriorities are the syslog priority names by their number, as accepted by journalctl.
+var journaldPriorities = [][]string{
+	{"emerg"},
+	{"alert"},
+	{"crit"},
+	{"err", "error"},
+	{"warning", "warn"},
+	{"notice"},
+	{"info"},
+	{"debug"},
+}
+
 const (
 	fbGrepFieldForTail     = "log"
 	fbGrepFieldForSystemd  = "MESSAGE"
+	fbGrepFieldForPriority = "PRIORITY"
 	fbGrepFieldForSyslog   = "message"
 	fbGrepFieldForTcpPlain = "log"
+	fbGrepFieldForWinEvent = "message" // once renamed by the modify filter
 )
 
 // LogsCfg stores logging product configuration split by block entries.
@@ -99,6 +132,19 @@ type LogCfg struct {
 	Fluentbit  *LogExternalFBCfg `yaml:"fluentbit"`
 	Winlog     *LogWinlogCfg     `yaml:"winlog"`
 	Winevtlog  *LogWinevtlogCfg  `yaml:"winevtlog"`
+	Journald   *LogJournaldCfg   `yaml:"journald"`
+	Multiline  *LogMultilineCfg  `yaml:"multiline"`
+	Parse      *LogParseCfg      `yaml:"parse"`
+	Drop       []LogDropCfg      `yaml:"drop"`
+	Sample     []LogSampleCfg    `yaml:"sample"`
+}
+
+// LogMultilineCfg stitches the lines of a file into a single record, ie: a stack trace, from the line matching the
+// start pattern until the next one.
+type LogMultilineCfg struct {
+	StartPattern string `yaml:"start_pattern"` // regex matching the first line of each record
+	TimeoutMs    int    `yaml:"timeout_ms"`    // time without new lines after which the record is sent
+	MaxLines     int    `yaml:"max_lines"`     // lines kept per record, the rest are dropped, no limit when 0
 }
 
 // LogSyslogCfg logging integration config from customer defined YAML, specific for the Syslog input plugin
@@ -117,11 +163,24 @@ type LogWinlogCfg struct {
 
 type LogWinevtlogCfg struct {
 	Channel         string   `yaml:"channel"`
+	Channels        []string `yaml:"channels"` // read besides channel
 	CollectEventIds []string `yaml:"collect-eventids"`
 	ExcludeEventIds []string `yaml:"exclude-eventids"`
+	Levels          []string `yaml:"levels"`         // levels collected, by name or number (1-5), all when empty
+	Query           string   `yaml:"query"`          // XPath query selecting the events read from the channels
+	StringInserts   bool     `yaml:"string-inserts"` // add the strings inserted into the message as StringInserts
 	UseANSI         string   `yaml:"use-ansi"`
 }
 
+// LogJournaldCfg logging integration config from customer defined YAML, specific for the systemd-journald input.
+// Unlike "systemd", it reads any number of units, filtered by priority, and persists its own journal cursor.
+type LogJournaldCfg struct {
+	Units        []string `yaml:"units"`          // units to read from, all of them when empty
+	Priority     string   `yaml:"priority"`       // lowest priority read, by name or number (0-7)
+	Path         string   `yaml:"path"`           // journal directory, the system default one when empty
+	ReadFromTail bool     `yaml:"read_from_tail"` // skip the existing entries when there's no persisted cursor
+}
+
 type LogTcpCfg struct {
 	Uri       string `yaml:"uri"`
 	Format    string `yaml:"format"`
@@ -135,15 +194,18 @@ type LogExternalFBCfg struct {
 
 // IsValid validates struct as there's no constructor to enforce it.
 func (l *LogCfg) IsValid() bool {
-	return l.Name != "" && (l.File != "" || l.Systemd != "" || l.Syslog != nil || l.Tcp != nil || l.Fluentbit != nil || l.Winlog != nil || l.Winevtlog != nil)
+	return l.Name != "" && (l.File != "" || l.Systemd != "" || l.Syslog != nil || l.Tcp != nil || l.Fluentbit != nil || l.Winlog != nil || l.Winevtlog != nil || l.Journald != nil)
 }
 
 // FBCfg FluentBit automatically generated configuration.
 type FBCfg struct {
-	Inputs      []FBCfgInput
-	Filters     []FBCfgFilter
-	ExternalCfg FBCfgExternal
-	Output      FBCfgOutput
+	Service          FBCfgService
+	Inputs           []FBCfgInput
+	Filters          []FBCfgFilter
+	Parsers          []FBCfgParser
+	MultilineParsers []FBCfgMultilineParser
+	ExternalCfg      FBCfgExternal
+	Output           FBCfgOutput
 }
 
 // Format will return the FBCfg in the fluent bit config file format.
@@ -161,6 +223,33 @@ func (c FBCfg) Format() (result string, externalCfg FBCfgExternal, err error) {
 	return buf.String(), c.ExternalCfg, nil
 }
 
+// FormatParsers will return the parsers and multiline parsers in the fluent bit parsers file format.
+func (c FBCfg) FormatParsers() (string, error) {
+	buf := new(bytes.Buffer)
+	tpl, err := template.New("fb parsers").Parse(fbParsersFormat)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot parse log-forwarder parsers template")
+	}
+	err = tpl.Execute(buf, c)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot write log-forwarder parsers template")
+	}
+	return buf.String(), nil
+}
+
+// FBCfgService FluentBit SERVICE config block, only generated to buffer the records on disk.
+//
+//	[SERVICE]
+//	  storage.path              /var/db/newrelic-infra/newrelic-integrations/logging/buffer
+//	  storage.sync              normal
+//	  storage.max_chunks_up     8
+//	  storage.backlog.mem_limit 16M
+type FBCfgService struct {
+	StoragePath            string
+	StorageMaxChunksUp     int
+	StorageBacklogMemLimit string
+}
+
 // FBCfgInput FluentBit INPUT config block for either "tail", "systemd", "winlog", "winevtlog" or "syslog" plugins.
 // Tail plugin expected shape:
 //
@@ -181,26 +270,49 @@ type FBCfgInput struct {
 	Name                  string
 	Tag                   string
 	DB                    string
-	Path                  string // plugin: tail
-	BufferMaxSize         string // plugin: tail
-	MemBufferLimit        string // plugin: tail
-	PathKey               string // plugin: tail
-	SkipLongLines         string // always on
-	Systemd_Filter        string // plugin: systemd
-	Channels              string // plugin: winlog
-	SyslogMode            string // plugin: syslog
-	SyslogListen          string // plugin: syslog
-	SyslogPort            int    // plugin: syslog
-	SyslogParser          string // plugin: syslog
-	SyslogUnixPath        string // plugin: syslog
-	SyslogUnixPermissions string // plugin: syslog
-	BufferChunkSize       string // plugin: syslog udp/udp_unix
-	TcpListen             string // plugin: tcp
-	TcpPort               int    // plugin: tcp
-	TcpFormat             string // plugin: tcp
-	TcpSeparator          string // plugin: tcp
-	TcpBufferSize         int    // plugin: tcp (note that the "tcp" plugin uses Buffer_Size (without "k"s!) instead of Buffer_Max_Size (with "k"s!))
-	UseANSI               string // plugin: winlog and winevtlog
+	Path                  string   // plugin: tail
+	BufferMaxSize         string   // plugin: tail
+	MemBufferLimit        string   // plugin: tail
+	PathKey               string   // plugin: tail
+	SkipLongLines         string   // always on
+	Systemd_Filter        string   // plugin: systemd
+	SystemdFilters        []string // plugin: systemd (journald)
+	SystemdFilterType     string   // plugin: systemd (journald)
+	ReadFromTail          string   // plugin: systemd (journald)
+	Channels              string   // plugin: winlog
+	EventQuery            string   // plugin: winevtlog
+	StringInserts         string   // plugin: winevtlog
+	SyslogMode            string   // plugin: syslog
+	SyslogListen          string   // plugin: syslog
+	SyslogPort            int      // plugin: syslog
+	SyslogParser          string   // plugin: syslog
+	SyslogUnixPath        string   // plugin: syslog
+	SyslogUnixPermissions string   // plugin: syslog
+	BufferChunkSize       string   // plugin: syslog udp/udp_unix
+	TcpListen             string   // plugin: tcp
+	TcpPort               int      // plugin: tcp
+	TcpFormat             string   // plugin: tcp
+	TcpSeparator          string   // plugin: tcp
+	TcpBufferSize         int      // plugin: tcp (note that the "tcp" plugin uses Buffer_Size (without "k"s!) instead of Buffer_Max_Size (with "k"s!))
+	UseANSI               string   // plugin: winlog and winevtlog
+	MultilineParser       string   // plugin: tail
+	StorageType           string   // any plugin, when buffering on disk
+}
+
+// FBCfgMultilineParser FluentBit MULTILINE_PARSER block, which has to be loaded from a parsers file. A record starts on
+// a line matching the start regex and continues on the ones that don't.
+//
+//	[MULTILINE_PARSER]
+//	  name          multiline_app
+//	  type          regex
+//	  flush_timeout 1000
+//	  rule          "start_state" "/^\d{4}-/" "cont"
+//	  rule          "cont" "/^(?!.*(?:^\d{4}-))/" "cont"
+type FBCfgMultilineParser struct {
+	Name         string
+	FlushTimeout int
+	StartRegex   string
+	ContRegex    string
 }
 
 // FBCfgFilter FluentBit FILTER config block, only "grep" plugin supported.
@@ -213,10 +325,15 @@ type FBCfgFilter struct {
 	Name      string
 	Match     string
 	Regex     string            // plugin: grep
+	Exclude   string            // plugin: grep
 	Records   map[string]string // plugin: record_modifier
 	Script    string            // plugin:lua-Script
 	Call      string            // plugin:lua-Script
 	Modifiers map[string]string //plugin: modify filter
+	KeyName   string            // plugin: parser
+	Parser    string            // plugin: parser
+	Reserve   string            // plugin: parser
+	Preserve  string            // plugin: parser
 }
 
 // FBCfgOutput FluentBit Output config block, supporting NR output plugin.
@@ -232,12 +349,34 @@ type FBCfgOutput struct {
 	CABundleDir       string
 	ValidateCerts     bool
 	Retry_Limit       string
+	StorageTotalLimit string // when buffering on disk
 }
 
 type FBWinlogLuaScript struct {
 	FnName           string
 	ExcludedEventIds string
 	IncludedEventIds string
+	Levels           string // winevtlog only, any level when empty
+}
+
+// FBMultilineLuaScript truncates the records stitched by a multiline parser to their first MaxLines lines.
+type FBMultilineLuaScript struct {
+	FnName   string
+	MaxLines int
+}
+
+// Format will return the formatted lua script that fluent bit config is pointing to.
+func (script FBMultilineLuaScript) Format() (result string, err error) {
+	buf := new(bytes.Buffer)
+	tpl, err := template.New("fb multiline lua").Parse(fbLuaMultilineScriptFormat)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot parse log-forwarder template")
+	}
+	err = tpl.Execute(buf, script)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot write log-forwarder template")
+	}
+	return buf.String(), nil
 }
 
 // Format will return the formatted lua script that fluent bit config is pointing to.
@@ -259,6 +398,8 @@ func (script FBWinlogLuaScript) Format() (result string, err error) {
 type FBCfgExternal struct {
 	CfgFilePath     string
 	ParsersFilePath string
+	// GeneratedParsersFilePath is the parsers file generated for the parsers and multiline parsers of the inputs.
+	GeneratedParsersFilePath string
 }
 
 // FBOSConfig contains additional FluentBit configuration per operating system.
@@ -282,9 +423,23 @@ func NewFBConf(loggingCfgs LogsCfg, logFwdCfg *config.LogForward, entityGUID, ho
 		if err != nil {
 			return
 		}
-		if (input != FBCfgInput{}) {
+		if input.Name != "" {
 			fb.Inputs = append(fb.Inputs, input)
 		}
+		if input.MultilineParser != "" {
+			fb.MultilineParsers = append(fb.MultilineParsers, newMultilineParser(input.MultilineParser, *block.Multiline))
+		}
+
+		if input.Name != "" {
+			processing, parser, err := parseProcessing(block, input.Name)
+			if err != nil {
+				return FBCfg{}, err
+			}
+			fb.Filters = append(fb.Filters, processing...)
+			if parser != nil {
+				fb.Parsers = append(fb.Parsers, *parser)
+			}
+		}
 
 		fb.Filters = append(fb.Filters, filters...)
 
@@ -313,6 +468,14 @@ func NewFBConf(loggingCfgs LogsCfg, logFwdCfg *config.LogForward, entityGUID, ho
 	// Newrelic OUTPUT plugin will send all the collected logs to Vortex
 	fb.Output = newNROutput(logFwdCfg)
 
+	if logFwdCfg.Buffer.Dir != "" {
+		addDiskBuffer(&fb, logFwdCfg.Buffer)
+	}
+
+	if len(fb.Parsers) > 0 || len(fb.MultilineParsers) > 0 {
+		fb.ExternalCfg.GeneratedParsersFilePath, e = saveParsers(fb, logFwdCfg.HomeDir)
+	}
+
 	return
 }
 
@@ -325,8 +488,12 @@ func parseConfigBlock(l LogCfg, logsHomeDir string, fbOSConfig FBOSConfig) (inpu
 
 	dbPath := filepath.Join(logsHomeDir, fluentBitDbName)
 
+	if l.Multiline != nil && l.File == "" {
+		cfgLogger.WithField("name", l.Name).Warn("Multiline is only supported by file inputs, ignoring it.")
+	}
+
 	if l.File != "" {
-		input, filters = parseFileInput(l, dbPath)
+		input, filters, err = parseFileInput(l, dbPath)
 	} else if l.Systemd != "" {
 		input, filters = parseSystemdInput(l, dbPath)
 	} else if l.Syslog != nil {
@@ -337,13 +504,15 @@ func parseConfigBlock(l LogCfg, logsHomeDir string, fbOSConfig FBOSConfig) (inpu
 		input, filters, err = parseWinlogInput(l, dbPath, fbOSConfig)
 	} else if l.Winevtlog != nil {
 		input, filters, err = parseWinevtlogInput(l, dbPath, fbOSConfig)
+	} else if l.Journald != nil {
+		input, filters, err = parseJournaldInput(l, logsHomeDir)
 	}
 
 	if err != nil {
 		return
 	}
 
-	if (input == FBCfgInput{}) {
+	if input.Name == "" {
 		err = fmt.Errorf("invalid log integration config")
 		return
 	} else {
@@ -352,11 +521,78 @@ func parseConfigBlock(l LogCfg, logsHomeDir string, fbOSConfig FBOSConfig) (inpu
 }
 
 // Single file
-func parseFileInput(l LogCfg, dbPath string) (input FBCfgInput, filters []FBCfgFilter) {
+func parseFileInput(l LogCfg, dbPath string) (input FBCfgInput, filters []FBCfgFilter, err error) {
 	input = newFileInput(l.File, dbPath, l.Name, getBufferMaxSize(l))
 	filters = append(filters, newRecordModifierFilterForInput(l.Name, fbInputTypeTail, l.Attributes))
 	filters = parsePattern(l, fbGrepFieldForTail, filters)
-	return input, filters
+	if l.Multiline == nil {
+		return input, filters, nil
+	}
+
+	if err = validateMultiline(*l.Multiline); err != nil {
+		return FBCfgInput{}, nil, err
+	}
+	input.MultilineParser = multilineParserPrefix + helpers.SanitizeFileName(l.Name)
+	if l.Multiline.MaxLines > 0 {
+		scriptContent, err := FBMultilineLuaScript{
+			FnName:   fbLuaFnNameMultilineMaxLines,
+			MaxLines: l.Multiline.MaxLines,
+		}.Format()
+		if err != nil {
+			return FBCfgInput{}, nil, err
+		}
+		scriptName, err := saveToTempFile([]byte(scriptContent))
+		if err != nil {
+			return FBCfgInput{}, nil, err
+		}
+		filters = append(filters, newLuaFilter(l.Name, scriptName, fbLuaFnNameMultilineMaxLines))
+	}
+	return input, filters, nil
+}
+
+// validateMultiline checks the multiline config can be written into a fluent bit multiline parser rule.
+func validateMultiline(m LogMultilineCfg) error {
+	if m.StartPattern == "" {
+		return fmt.Errorf("multiline: start_pattern is required")
+	}
+	if strings.ContainsAny(m.StartPattern, "\"\r\n") {
+		return fmt.Errorf("multiline: start_pattern cannot contain double quotes or line breaks")
+	}
+	if m.TimeoutMs < 0 || m.MaxLines < 0 {
+		return fmt.Errorf("multiline: timeout_ms and max_lines cannot be negative")
+	}
+	return nil
+}
+
+// newMultilineParser creates a parser for which any line not matching the start pattern continues the record.
+func newMultilineParser(name string, m LogMultilineCfg) FBCfgMultilineParser {
+	timeout := m.TimeoutMs
+	if timeout == 0 {
+		timeout = multilineFlushTimeoutMs
+	}
+	return FBCfgMultilineParser{
+		Name:         name,
+		FlushTimeout: timeout,
+		StartRegex:   m.StartPattern,
+		ContRegex:    fmt.Sprintf("^(?!.*(?:%s))", m.StartPattern),
+	}
+}
+
+// saveParsers writes the parsers and multiline parsers into the logging home dir, returning the file path. It's
+// always the same file, as fluent bit only reads it on startup.
+func saveParsers(fb FBCfg, logsHomeDir string) (string, error) {
+	content, err := fb.FormatParsers()
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(logsHomeDir, 0o755); err != nil {
+		return "", errors.Wrap(err, "cannot create log-forwarder home dir")
+	}
+	path := filepath.Join(logsHomeDir, generatedParsersName)
+	if err = os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", errors.Wrap(err, "cannot write log-forwarder parsers")
+	}
+	return path, nil
 }
 
 // Systemd service: "system" plugin input
@@ -367,6 +603,29 @@ func parseSystemdInput(l LogCfg, dbPath string) (input FBCfgInput, filters []FBC
 	return input, filters
 }
 
+// Journald: "systemd" plugin input, with its own DB as the plugin stores a single cursor per DB.
+func parseJournaldInput(l LogCfg, logsHomeDir string) (input FBCfgInput, filters []FBCfgFilter, err error) {
+	dbPath := filepath.Join(logsHomeDir, journaldDbPrefix+helpers.SanitizeFileName(l.Name)+".db")
+	input, err = newJournaldInput(*l.Journald, dbPath, l.Name)
+	if err != nil {
+		return FBCfgInput{}, nil, err
+	}
+	filters = append(filters, newRecordModifierFilterForInput(l.Name, fbInputTypeSystemd, l.Attributes))
+	if l.Journald.Priority != "" {
+		priority, err := journaldPriority(l.Journald.Priority)
+		if err != nil {
+			return FBCfgInput{}, nil, err
+		}
+		filters = append(filters, FBCfgFilter{
+			Name:  fbFilterTypeGrep,
+			Match: l.Name,
+			Regex: fmt.Sprintf("%s ^[0-%d]$", fbGrepFieldForPriority, priority),
+		})
+	}
+	filters = parsePattern(l, fbGrepFieldForSystemd, filters)
+	return input, filters, nil
+}
+
 // Syslog: "syslog" plugin
 func parseSyslogInput(l LogCfg) (input FBCfgInput, filters []FBCfgFilter, err error) {
 	slIn, e := newSyslogInput(*l.Syslog, l.Name, getBufferMaxSize(l))
@@ -400,7 +659,7 @@ func parseTcpInput(l LogCfg) (input FBCfgInput, filters []FBCfgFilter, err error
 func parseWinlogInput(l LogCfg, dbPath string, fbOSConfig FBOSConfig) (input FBCfgInput, filters []FBCfgFilter, err error) {
 	input = newWinlogInput(*l.Winlog, dbPath, l.Name, fbOSConfig)
 	filters = append(filters, newRecordModifierFilterForInput(l.Name, fbInputTypeWinlog, l.Attributes))
-	scriptContent, err := createLuaWindowsFilterScript(l.Winlog.CollectEventIds, l.Winlog.ExcludeEventIds)
+	scriptContent, err := createLuaWindowsFilterScript(l.Winlog.CollectEventIds, l.Winlog.ExcludeEventIds, nil)
 	if err != nil {
 		return FBCfgInput{}, []FBCfgFilter{}, err
 	}
@@ -408,7 +667,7 @@ func parseWinlogInput(l LogCfg, dbPath string, fbOSConfig FBOSConfig) (input FBC
 	if err != nil {
 		return FBCfgInput{}, []FBCfgFilter{}, err
 	}
-	eventIdLuaFilter := newLuaFilter(l.Name, scriptName)
+	eventIdLuaFilter := newLuaFilter(l.Name, scriptName, fbLuaFnNameWinlogEventFilter)
 	filters = append(filters, eventIdLuaFilter)
 	filters = append(filters, newModifyFilter(l.Name))
 	return input, filters, nil
@@ -418,9 +677,12 @@ func parseWinlogInput(l LogCfg, dbPath string, fbOSConfig FBOSConfig) (input FBC
 //
 //nolint:nonamedreturns,varnamelen
 func parseWinevtlogInput(l LogCfg, dbPath string, fbOSConfig FBOSConfig) (input FBCfgInput, filters []FBCfgFilter, err error) {
-	input = newWinevtlogInput(*l.Winevtlog, dbPath, l.Name, fbOSConfig)
+	input, err = newWinevtlogInput(*l.Winevtlog, dbPath, l.Name, fbOSConfig)
+	if err != nil {
+		return FBCfgInput{}, []FBCfgFilter{}, err
+	}
 	filters = append(filters, newRecordModifierFilterForInput(l.Name, fbInputTypeWinevtlog, l.Attributes))
-	scriptContent, err := createLuaWindowsFilterScript(l.Winevtlog.CollectEventIds, l.Winevtlog.ExcludeEventIds)
+	scriptContent, err := createLuaWindowsFilterScript(l.Winevtlog.CollectEventIds, l.Winevtlog.ExcludeEventIds, l.Winevtlog.Levels)
 	if err != nil {
 		return FBCfgInput{}, []FBCfgFilter{}, err
 	}
@@ -428,13 +690,13 @@ func parseWinevtlogInput(l LogCfg, dbPath string, fbOSConfig FBOSConfig) (input
 	if err != nil {
 		return FBCfgInput{}, []FBCfgFilter{}, err
 	}
-	eventIdLuaFilter := newLuaFilter(l.Name, scriptName)
+	eventIdLuaFilter := newLuaFilter(l.Name, scriptName, fbLuaFnNameWinlogEventFilter)
 	filters = append(filters, eventIdLuaFilter)
 	filters = append(filters, newModifyFilter(l.Name))
 	return input, filters, nil
 }
 
-func createLuaWindowsFilterScript(included []string, excluded []string) (scriptContent string, err error) {
+func createLuaWindowsFilterScript(included []string, excluded []string, levels []string) (scriptContent string, err error) {
 	var fbLuaScript FBWinlogLuaScript
 	fbLuaScript.FnName = fbLuaFnNameWinlogEventFilter
 	fbLuaScript.IncludedEventIds, err = createConditions(included, "true")
@@ -445,9 +707,41 @@ func createLuaWindowsFilterScript(included []string, excluded []string) (scriptC
 	if err != nil {
 		return "", err
 	}
+	fbLuaScript.Levels, err = createLevelConditions(levels)
+	if err != nil {
+		return "", err
+	}
 	return fbLuaScript.Format()
 }
 
+// createLevelConditions returns the lua conditions matching the event levels, empty for all of them.
+func createLevelConditions(levels []string) (string, error) {
+	conditions := make([]string, 0, len(levels))
+	for _, level := range levels {
+		number, err := winevtlogLevel(level)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, fmt.Sprintf("level==%d", number))
+		// LogAlways events are informational ones
+		if number == 4 {
+			conditions = append(conditions, "level==0")
+		}
+	}
+	return strings.Join(conditions, " or "), nil
+}
+
+// winevtlogLevel returns the Windows event level number for a level name or number.
+func winevtlogLevel(level string) (int, error) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	for i, name := range winevtlogLevels {
+		if level == name || level == strconv.Itoa(i+1) {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("winevtlog: invalid level %s, expected 1-5 or critical, error, warning, information, verbose", level)
+}
+
 func createConditions(numberRanges []string, defaultIfEmpty string) (conditions string, err error) {
 	if len(numberRanges) > 0 {
 		conditions := make([]string, 0, len(numberRanges))
@@ -530,6 +824,49 @@ func newSystemdInput(service string, dbPath string, tag string) FBCfgInput {
 	}
 }
 
+func newJournaldInput(j LogJournaldCfg, dbPath string, tag string) (FBCfgInput, error) {
+	input := FBCfgInput{
+		Name: fbInputTypeSystemd,
+		Tag:  tag,
+		DB:   dbPath,
+		Path: j.Path,
+	}
+	for _, unit := range j.Units {
+		unit = strings.TrimSpace(unit)
+		if unit == "" {
+			return FBCfgInput{}, fmt.Errorf("journald: empty unit name")
+		}
+		// units without type are services, as in systemctl
+		if !strings.Contains(unit, ".") {
+			unit += ".service"
+		}
+		input.SystemdFilters = append(input.SystemdFilters, "_SYSTEMD_UNIT="+unit)
+	}
+	if len(input.SystemdFilters) > 1 {
+		input.SystemdFilterType = "Or"
+	}
+	if j.ReadFromTail {
+		input.ReadFromTail = "On"
+	}
+	return input, nil
+}
+
+// journaldPriority returns the syslog priority number for a priority name or number.
+func journaldPriority(priority string) (int, error) {
+	priority = strings.ToLower(strings.TrimSpace(priority))
+	for number, names := range journaldPriorities {
+		if priority == strconv.Itoa(number) {
+			return number, nil
+		}
+		for _, name := range names {
+			if priority == name {
+				return number, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("journald: invalid priority %s, expected 0-7 or emerg, alert, crit, err, warning, notice, info, debug", priority)
+}
+
 //nolint:exhaustruct
 func newWinlogInput(winlog LogWinlogCfg, dbPath string, tag string, fbOSConfig FBOSConfig) FBCfgInput {
 	return FBCfgInput{
@@ -542,14 +879,33 @@ func newWinlogInput(winlog LogWinlogCfg, dbPath string, tag string, fbOSConfig F
 }
 
 //nolint:exhaustruct
-func newWinevtlogInput(winlog LogWinevtlogCfg, dbPath string, tag string, fbOSConfig FBOSConfig) FBCfgInput {
-	return FBCfgInput{
-		Name:     fbInputTypeWinevtlog,
-		Channels: winlog.Channel,
-		Tag:      tag,
-		DB:       dbPath,
-		UseANSI:  determineUseAnsiFlagValue(winlog.UseANSI, fbOSConfig.UseANSI),
+func newWinevtlogInput(winlog LogWinevtlogCfg, dbPath string, tag string, fbOSConfig FBOSConfig) (FBCfgInput, error) {
+	channels := winlog.Channels
+	if winlog.Channel != "" {
+		channels = append([]string{winlog.Channel}, channels...)
+	}
+	for _, channel := range channels {
+		// channels are comma separated in the plugin config
+		if strings.TrimSpace(channel) == "" || strings.Contains(channel, ",") {
+			return FBCfgInput{}, fmt.Errorf("winevtlog: invalid channel name %q", channel)
+		}
+	}
+	if strings.ContainsAny(winlog.Query, "\r\n") {
+		return FBCfgInput{}, fmt.Errorf("winevtlog: query cannot contain line breaks")
+	}
+
+	input := FBCfgInput{
+		Name:       fbInputTypeWinevtlog,
+		Channels:   strings.Join(channels, ","),
+		Tag:        tag,
+		DB:         dbPath,
+		EventQuery: strings.TrimSpace(winlog.Query),
+		UseANSI:    determineUseAnsiFlagValue(winlog.UseANSI, fbOSConfig.UseANSI),
+	}
+	if winlog.StringInserts {
+		input.StringInserts = "true"
 	}
+	return input, nil
 }
 
 // determineUseAnsiFlagValue calculates final value of the Use_ANSI flag
@@ -663,12 +1019,12 @@ func newGrepFilter(l LogCfg, fluentBitGrepField string) FBCfgFilter {
 	}
 }
 
-func newLuaFilter(tag string, fileName string) FBCfgFilter {
+func newLuaFilter(tag string, fileName string, fnName string) FBCfgFilter {
 	return FBCfgFilter{
 		Name:   fbFilterTypeLua,
 		Match:  tag,
 		Script: fileName,
-		Call:   fbLuaFnNameWinlogEventFilter,
+		Call:   fnName,
 	}
 }
 
@@ -708,9 +1064,44 @@ func newNROutput(cfg *config.LogForward) FBCfgOutput {
 		ret.Endpoint = euEndpoint
 	}
 
+	if ret.Proxy == config.ProxyDirect || isNoProxyEndpoint(ret.Endpoint, cfg.ProxyCfg.NoProxy) {
+		ret.Proxy = ""
+		ret.IgnoreSystemProxy = true
+	}
+
 	return ret
 }
 
+// addDiskBuffer stores the records of the inputs on disk until they're sent, keeping up to MemoryMB of them in memory,
+// which pauses the inputs when full, and dropping the oldest ones beyond MaxSizeMB.
+func addDiskBuffer(fb *FBCfg, buffer config.LogForwardBuffer) {
+	maxChunksUp := buffer.MemoryMB / storageChunkSizeMB
+	if maxChunksUp < 1 {
+		maxChunksUp = 1
+	}
+	fb.Service = FBCfgService{
+		StoragePath:            buffer.Dir,
+		StorageMaxChunksUp:     maxChunksUp,
+		StorageBacklogMemLimit: fmt.Sprintf("%dM", buffer.MemoryMB),
+	}
+	for i := range fb.Inputs {
+		fb.Inputs[i].StorageType = storageTypeFilesystem
+	}
+	fb.Output.StorageTotalLimit = fmt.Sprintf("%dM", buffer.MaxSizeMB)
+}
+
+// isNoProxyEndpoint returns true when the logs endpoint, the US one when empty, is in the no_proxy list.
+func isNoProxyEndpoint(endpoint, noProxy string) bool {
+	if endpoint == "" {
+		endpoint = usEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	return backendhttp.ParseNoProxy(noProxy).Matches(u)
+}
+
 func getBufferMaxSize(l LogCfg) int {
 	bufferSize := l.MaxLineKb
 	if bufferSize == 0 {
@@ -2,7 +2,15 @@
 // SPDX-License-Identifier: Apache-2.0
 package logs
 
-var fbConfigFormat = `{{- range .Inputs }}
+var fbConfigFormat = `{{- if .Service.StoragePath }}
+[SERVICE]
+    storage.path              {{ .Service.StoragePath }}
+    storage.sync              normal
+    storage.max_chunks_up     {{ .Service.StorageMaxChunksUp }}
+    storage.backlog.mem_limit {{ .Service.StorageBacklogMemLimit }}
+{{ end -}}
+
+{{- range .Inputs }}
 [INPUT]
     Name {{ .Name }}
     {{- if .Path }}
@@ -23,6 +31,9 @@ var fbConfigFormat = `{{- range .Inputs }}
     {{- if .PathKey }}
     Path_Key {{ .PathKey }}
     {{- end }}
+    {{- if .MultilineParser }}
+    multiline.parser {{ .MultilineParser }}
+    {{- end }}
     {{- if .Tag }}
     Tag  {{ .Tag }}
     {{- end }}
@@ -32,9 +43,24 @@ var fbConfigFormat = `{{- range .Inputs }}
     {{- if .Systemd_Filter }}
     Systemd_Filter {{ .Systemd_Filter }}
     {{- end }}
+    {{- range .SystemdFilters }}
+    Systemd_Filter {{ . }}
+    {{- end }}
+    {{- if .SystemdFilterType }}
+    Systemd_Filter_Type {{ .SystemdFilterType }}
+    {{- end }}
+    {{- if .ReadFromTail }}
+    Read_From_Tail {{ .ReadFromTail }}
+    {{- end }}
     {{- if .Channels }}
     Channels {{ .Channels }}
     {{- end }}
+    {{- if .EventQuery }}
+    Event_Query {{ .EventQuery }}
+    {{- end }}
+    {{- if .StringInserts }}
+    String_Inserts {{ .StringInserts }}
+    {{- end }}
     {{- if .SyslogMode }}
     Mode {{ .SyslogMode }}
     {{- end }}
@@ -71,6 +97,9 @@ var fbConfigFormat = `{{- range .Inputs }}
  	{{- if .UseANSI }}
     Use_ANSI {{ .UseANSI }}
     {{- end }}
+    {{- if .StorageType }}
+    storage.type {{ .StorageType }}
+    {{- end }}
 {{ end -}}
 
 {{- range .Filters }}
@@ -84,6 +113,21 @@ var fbConfigFormat = `{{- range .Inputs }}
     {{- if .Regex }}
     Regex {{ .Regex }}
     {{- end }}
+    {{- if .Exclude }}
+    Exclude {{ .Exclude }}
+    {{- end }}
+    {{- if .KeyName }}
+    Key_Name {{ .KeyName }}
+    {{- end }}
+    {{- if .Parser }}
+    Parser {{ .Parser }}
+    {{- end }}
+    {{- if .Reserve }}
+    Reserve_Data {{ .Reserve }}
+    {{- end }}
+    {{- if .Preserve }}
+    Preserve_Key {{ .Preserve }}
+    {{- end }}
     {{- if .Records }}
         {{- range $key, $value := .Records }}
     Record {{ $key }} {{ $value }}
@@ -130,6 +174,9 @@ var fbConfigFormat = `{{- range .Inputs }}
     {{- if .Output.Retry_Limit}}
     Retry_Limit         {{ .Output.Retry_Limit }}
     {{- end}}
+    {{- if .Output.StorageTotalLimit }}
+    storage.total_limit_size {{ .Output.StorageTotalLimit }}
+    {{- end }}
 {{ end -}}
 
 {{- if .ExternalCfg.CfgFilePath }}
@@ -138,6 +185,13 @@ var fbConfigFormat = `{{- range .Inputs }}
 
 var fbLuaScriptFormat = `function {{ .FnName }}(tag, timestamp, record)
     eventId = record["EventID"]
+    {{- if .Levels }}
+    level = record["Level"]
+    -- Discard log records not matching any of these levels
+    if not ({{ .Levels }}) then
+        return -1, 0, 0
+    end
+    {{- end }}
     -- Discard log records matching any of these conditions
     if {{ .ExcludedEventIds }} then
         return -1, 0, 0
@@ -149,3 +203,64 @@ var fbLuaScriptFormat = `function {{ .FnName }}(tag, timestamp, record)
     -- If there is not any matching conditions discard everything
     return -1, 0, 0
  end`
+
+var fbParsersFormat = `{{- range .Parsers }}
+[PARSER]
+    Name   {{ .Name }}
+    Format {{ .Format }}
+    {{- if .Regex }}
+    Regex  {{ .Regex }}
+    {{- end }}
+    {{- if .Types }}
+    Types  {{ .Types }}
+    {{- end }}
+{{ end -}}
+
+{{- range .MultilineParsers }}
+[MULTILINE_PARSER]
+    name          {{ .Name }}
+    type          regex
+    flush_timeout {{ .FlushTimeout }}
+    rule          "start_state" "/{{ .StartRegex }}/" "cont"
+    rule          "cont" "/{{ .ContRegex }}/" "cont"
+{{ end -}}`
+
+var fbLuaMultilineScriptFormat = `function {{ .FnName }}(tag, timestamp, record)
+    local log = record["log"]
+    if log == nil then
+        return 0, 0, 0
+    end
+    -- Find the end of the last line kept
+    local pos = 0
+    for i = 1, {{ .MaxLines }} do
+        pos = string.find(log, "\n", pos + 1, true)
+        if pos == nil or pos == #log then
+            return 0, 0, 0
+        end
+    end
+    record["log"] = string.sub(log, 1, pos - 1)
+    record["multiline.truncated"] = true
+    return 2, timestamp, record
+ end`
+
+var fbLuaSampleScriptFormat = `math.randomseed(os.time())
+
+local rules = {
+    {{- range .Rules }}
+    { attribute = {{ .Attribute }}, rate = {{ .Rate }}, values = { {{- range .Values }}[{{ . }}] = true, {{ end -}} } },
+    {{- end }}
+}
+
+function {{ .FnName }}(tag, timestamp, record)
+    -- The first rule matching the record samples it
+    for _, rule in ipairs(rules) do
+        local value = record[rule.attribute]
+        if value ~= nil and rule.values[tostring(value)] then
+            if math.random() < rule.rate then
+                return 0, 0, 0
+            end
+            return -1, 0, 0
+        end
+    end
+    return 0, 0, 0
+ end`
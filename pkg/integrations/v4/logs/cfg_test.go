@@ -4,15 +4,18 @@ package logs
 
 import (
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 
 	"github.com/shirou/gopsutil/v3/host"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const windowsServer2016BuildNumber = 14393
@@ -223,6 +226,64 @@ func TestNewFBConf(t *testing.T) {
 			},
 			Output: outputBlock,
 		}},
+		{"input journald + priority + filter", logFwdCfg, LogsCfg{
+			{
+				Name: "journal",
+				Journald: &LogJournaldCfg{
+					Units:        []string{"nginx", "backup.timer"},
+					Priority:     "warning",
+					Path:         "/run/log/journal",
+					ReadFromTail: true,
+				},
+				Pattern: "foo",
+			},
+		}, FBCfg{
+			Inputs: []FBCfgInput{
+				{
+					Name:              "systemd",
+					Tag:               "journal",
+					DB:                "/var/db/newrelic-infra/newrelic-integrations/logging/journald-journal.db",
+					Path:              "/run/log/journal",
+					SystemdFilters:    []string{"_SYSTEMD_UNIT=nginx.service", "_SYSTEMD_UNIT=backup.timer"},
+					SystemdFilterType: "Or",
+					ReadFromTail:      "On",
+				},
+			},
+			Filters: []FBCfgFilter{
+				inputRecordModifier("systemd", "journal"),
+				{
+					Name:  "grep",
+					Match: "journal",
+					Regex: "PRIORITY ^[0-4]$",
+				},
+				{
+					Name:  "grep",
+					Match: "journal",
+					Regex: "MESSAGE foo",
+				},
+				filterEntityBlock,
+			},
+			Output: outputBlock,
+		}},
+		{"input journald of all the units", logFwdCfg, LogsCfg{
+			{
+				Name:     "journal",
+				Journald: &LogJournaldCfg{},
+			},
+		}, FBCfg{
+			Inputs: []FBCfgInput{
+				{
+					Name: "systemd",
+					Tag:  "journal",
+					DB:   "/var/db/newrelic-infra/newrelic-integrations/logging/journald-journal.db",
+				},
+			},
+			Filters: []FBCfgFilter{
+				inputRecordModifier("systemd", "journal"),
+				filterEntityBlock,
+			},
+			Output: outputBlock,
+		}},
 		{"single file with attributes", logFwdCfg, LogsCfg{
 			{
 				Name: "one-file",
@@ -1309,6 +1370,90 @@ func TestFBLuaFormat(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
+func TestFBLuaFormat_Levels(t *testing.T) {
+	expected := `function winevtlog_test(tag, timestamp, record)
+    eventId = record["EventID"]
+    level = record["Level"]
+    -- Discard log records not matching any of these levels
+    if not (level==2 or level==3) then
+        return -1, 0, 0
+    end
+    -- Discard log records matching any of these conditions
+    if false then
+        return -1, 0, 0
+    end
+    -- Include log records matching any of these conditions
+    if true then
+        return 0, 0, 0
+    end
+    -- If there is not any matching conditions discard everything
+    return -1, 0, 0
+ end`
+
+	fbLuaScript := FBWinlogLuaScript{
+		FnName:           "winevtlog_test",
+		ExcludedEventIds: "false",
+		IncludedEventIds: "true",
+		Levels:           "level==2 or level==3",
+	}
+
+	result, err := fbLuaScript.Format()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestCreateLevelConditions(t *testing.T) {
+	conditions, err := createLevelConditions(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, conditions)
+
+	conditions, err = createLevelConditions([]string{"Critical", "2", " warning ", "information"})
+	assert.NoError(t, err)
+	assert.Equal(t, "level==1 or level==2 or level==3 or level==4 or level==0", conditions)
+
+	for _, level := range []string{"0", "6", "info", ""} {
+		_, err = createLevelConditions([]string{level})
+		assert.Error(t, err, level)
+	}
+}
+
+func TestNewWinevtlogInput(t *testing.T) {
+	input, err := newWinevtlogInput(LogWinevtlogCfg{
+		Channel:       "Application",
+		Channels:      []string{"System", "Microsoft-Windows-Windows Defender/Operational"},
+		Query:         " *[System[(Level=1 or Level=2)]] ",
+		StringInserts: true,
+		UseANSI:       "false",
+	}, "fb.db", "windows", FBOSConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, FBCfgInput{
+		Name:          "winevtlog",
+		Tag:           "windows",
+		DB:            "fb.db",
+		Channels:      "Application,System,Microsoft-Windows-Windows Defender/Operational",
+		EventQuery:    "*[System[(Level=1 or Level=2)]]",
+		StringInserts: "true",
+		UseANSI:       "false",
+	}, input)
+
+	result, _, err := FBCfg{Inputs: []FBCfgInput{input}}.Format()
+	assert.NoError(t, err)
+	assert.Contains(t, result, `
+    Channels Application,System,Microsoft-Windows-Windows Defender/Operational
+    Event_Query *[System[(Level=1 or Level=2)]]
+    String_Inserts true
+`)
+
+	for _, cfg := range []LogWinevtlogCfg{
+		{Channels: []string{"Application,System"}},
+		{Channel: "Application", Channels: []string{" "}},
+		{Channel: "Application", Query: "*[System[\n(Level=1)]]"},
+	} {
+		_, err = newWinevtlogInput(cfg, "fb.db", "windows", FBOSConfig{})
+		assert.Error(t, err, cfg)
+	}
+}
+
 func TestFBCfgFormatWithHostname(t *testing.T) {
 	expected := `
 [INPUT]
@@ -1470,6 +1615,204 @@ func TestSyslogCorrectFormat(t *testing.T) {
 	}
 }
 
+func TestJournaldPriority(t *testing.T) {
+	for priority, expected := range map[string]int{"0": 0, "emerg": 0, "ERR": 3, "error": 3, " warn ": 4, "7": 7, "debug": 7} {
+		actual, err := journaldPriority(priority)
+		assert.NoError(t, err, priority)
+		assert.Equal(t, expected, actual, priority)
+	}
+	for _, priority := range []string{"8", "-1", "verbose"} {
+		_, err := journaldPriority(priority)
+		assert.Error(t, err, priority)
+	}
+}
+
+func TestNewJournaldInput_EmptyUnit(t *testing.T) {
+	_, err := newJournaldInput(LogJournaldCfg{Units: []string{"nginx", " "}}, "journald.db", "journal")
+	assert.Error(t, err)
+}
+
+func TestFBCfgFormat_Journald(t *testing.T) {
+	fbCfg := FBCfg{
+		Inputs: []FBCfgInput{
+			{
+				Name:              "systemd",
+				Tag:               "journal",
+				DB:                "journald-journal.db",
+				SystemdFilters:    []string{"_SYSTEMD_UNIT=nginx.service", "_SYSTEMD_UNIT=sshd.service"},
+				SystemdFilterType: "Or",
+				ReadFromTail:      "On",
+			},
+		},
+	}
+
+	result, _, err := fbCfg.Format()
+
+	assert.NoError(t, err)
+	assert.Contains(t, result, `
+[INPUT]
+    Name systemd
+    Tag  journal
+    DB   journald-journal.db
+    Systemd_Filter _SYSTEMD_UNIT=nginx.service
+    Systemd_Filter _SYSTEMD_UNIT=sshd.service
+    Systemd_Filter_Type Or
+    Read_From_Tail On
+`)
+}
+
+func TestNewFBConf_Multiline(t *testing.T) {
+	logFwd := logFwdCfg
+	logFwd.HomeDir = t.TempDir()
+	logsCfg := LogsCfg{
+		{
+			Name: "app",
+			File: "/var/log/app.log",
+			Multiline: &LogMultilineCfg{
+				StartPattern: `^\d{4}-\d{2}-\d{2}`,
+				MaxLines:     200,
+			},
+		},
+		{
+			Name: "worker",
+			File: "/var/log/worker.log",
+			Multiline: &LogMultilineCfg{
+				StartPattern: `^(INFO|WARN|ERROR)`,
+				TimeoutMs:    3000,
+			},
+		},
+	}
+
+	fbCfg, err := NewFBConf(logsCfg, &logFwd, "0", "")
+	require.NoError(t, err)
+
+	require.Len(t, fbCfg.Inputs, 2)
+	assert.Equal(t, "multiline_app", fbCfg.Inputs[0].MultilineParser)
+	assert.Equal(t, "multiline_worker", fbCfg.Inputs[1].MultilineParser)
+	assert.Equal(t, []FBCfgMultilineParser{
+		{
+			Name:         "multiline_app",
+			FlushTimeout: 1000,
+			StartRegex:   `^\d{4}-\d{2}-\d{2}`,
+			ContRegex:    `^(?!.*(?:^\d{4}-\d{2}-\d{2}))`,
+		},
+		{
+			Name:         "multiline_worker",
+			FlushTimeout: 3000,
+			StartRegex:   `^(INFO|WARN|ERROR)`,
+			ContRegex:    `^(?!.*(?:^(INFO|WARN|ERROR)))`,
+		},
+	}, fbCfg.MultilineParsers)
+
+	// only the records of the input with max lines are truncated
+	var luaFilters []FBCfgFilter
+	for _, filter := range fbCfg.Filters {
+		if filter.Name == "lua" {
+			luaFilters = append(luaFilters, filter)
+		}
+	}
+	require.Len(t, luaFilters, 1)
+	assert.Equal(t, "app", luaFilters[0].Match)
+	assert.Equal(t, "maxLinesFilter", luaFilters[0].Call)
+	script, err := os.ReadFile(luaFilters[0].Script)
+	require.NoError(t, err)
+	defer os.Remove(luaFilters[0].Script)
+	assert.Contains(t, string(script), "for i = 1, 200 do")
+
+	assert.Equal(t, filepath.Join(logFwd.HomeDir, "generated_parsers.conf"), fbCfg.ExternalCfg.GeneratedParsersFilePath)
+	parsers, err := os.ReadFile(fbCfg.ExternalCfg.GeneratedParsersFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, `
+[MULTILINE_PARSER]
+    name          multiline_app
+    type          regex
+    flush_timeout 1000
+    rule          "start_state" "/^\d{4}-\d{2}-\d{2}/" "cont"
+    rule          "cont" "/^(?!.*(?:^\d{4}-\d{2}-\d{2}))/" "cont"
+
+[MULTILINE_PARSER]
+    name          multiline_worker
+    type          regex
+    flush_timeout 3000
+    rule          "start_state" "/^(INFO|WARN|ERROR)/" "cont"
+    rule          "cont" "/^(?!.*(?:^(INFO|WARN|ERROR)))/" "cont"
+`, string(parsers))
+
+	result, _, err := fbCfg.Format()
+	require.NoError(t, err)
+	assert.Contains(t, result, `
+    Path_Key filePath
+    multiline.parser multiline_app
+`)
+}
+
+func TestNewFBConf_DiskBuffer(t *testing.T) {
+	logFwd := logFwdCfg
+	logFwd.Buffer = config.LogForwardBuffer{
+		Dir:       "/var/db/newrelic-infra/newrelic-integrations/logging/buffer",
+		MaxSizeMB: 512,
+		MemoryMB:  16,
+	}
+	logsCfg := LogsCfg{
+		{Name: "app", File: "/var/log/app.log"},
+		{Name: "nginx", Systemd: "nginx"},
+	}
+
+	fbCfg, err := NewFBConf(logsCfg, &logFwd, "0", "")
+	require.NoError(t, err)
+	assert.Equal(t, FBCfgService{
+		StoragePath:            "/var/db/newrelic-infra/newrelic-integrations/logging/buffer",
+		StorageMaxChunksUp:     8,
+		StorageBacklogMemLimit: "16M",
+	}, fbCfg.Service)
+	for _, input := range fbCfg.Inputs {
+		assert.Equal(t, "filesystem", input.StorageType)
+	}
+	assert.Equal(t, "512M", fbCfg.Output.StorageTotalLimit)
+
+	result, _, err := fbCfg.Format()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(result, `
+[SERVICE]
+    storage.path              /var/db/newrelic-infra/newrelic-integrations/logging/buffer
+    storage.sync              normal
+    storage.max_chunks_up     8
+    storage.backlog.mem_limit 16M
+`), result)
+	assert.Contains(t, result, `
+    Skip_Long_Lines On
+    Path_Key filePath
+    Tag  app
+    DB   /var/db/newrelic-infra/newrelic-integrations/logging/fb.db
+    storage.type filesystem
+`)
+	assert.Contains(t, result, `
+    Retry_Limit         5
+    storage.total_limit_size 512M
+`)
+
+	// no buffer, no storage settings
+	fbCfg, err = NewFBConf(logsCfg, &logFwdCfg, "0", "")
+	require.NoError(t, err)
+	result, _, err = fbCfg.Format()
+	require.NoError(t, err)
+	assert.NotContains(t, result, "storage.")
+}
+
+func TestParseFileInput_InvalidMultiline(t *testing.T) {
+	for name, multiline := range map[string]LogMultilineCfg{
+		"empty start pattern":  {},
+		"quoted start pattern": {StartPattern: `^"`},
+		"multiline pattern":    {StartPattern: "^a\nb"},
+		"negative timeout":     {StartPattern: "^a", TimeoutMs: -1},
+		"negative max lines":   {StartPattern: "^a", MaxLines: -1},
+	} {
+		l := LogCfg{Name: "app", File: "/var/log/app.log", Multiline: &multiline}
+		_, _, err := parseFileInput(l, "fb.db")
+		assert.Error(t, err, name)
+	}
+}
+
 func TestCreateConditions(t *testing.T) {
 	type args struct {
 		numberRanges   []string
@@ -1531,3 +1874,37 @@ func TestDetermineUseAnsiFlagValue(t *testing.T) {
 		})
 	}
 }
+
+func TestNewNROutput_DirectConnection(t *testing.T) {
+	tests := map[string]struct {
+		proxy   string
+		noProxy string
+		license string
+		direct  bool
+	}{
+		"proxy":                   {"https://https-proxy:3129", "", "licenseKey", false},
+		"direct":                  {config.ProxyDirect, "", "licenseKey", true},
+		"no proxy us endpoint":    {"https://https-proxy:3129", "log-api.newrelic.com", "licenseKey", true},
+		"no proxy domain":         {"https://https-proxy:3129", ".newrelic.com", "eu01xxlicenseKey", true},
+		"no proxy other endpoint": {"https://https-proxy:3129", "log-api.newrelic.com", "eu01xxlicenseKey", false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := logFwdCfg
+			cfg.License = tc.license
+			cfg.ProxyCfg.IgnoreSystemProxy = false
+			cfg.ProxyCfg.Proxy = tc.proxy
+			cfg.ProxyCfg.NoProxy = tc.noProxy
+
+			output := newNROutput(&cfg)
+
+			if tc.direct {
+				assert.Empty(t, output.Proxy)
+				assert.True(t, output.IgnoreSystemProxy)
+			} else {
+				assert.Equal(t, tc.proxy, output.Proxy)
+				assert.False(t, output.IgnoreSystemProxy)
+			}
+		})
+	}
+}
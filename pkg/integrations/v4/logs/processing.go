@@ -0,0 +1,285 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/pkg/errors"
+)
+
+// Record parsing formats
+const (
+	parseFormatRegex = "regex"
+	parseFormatGrok  = "grok"
+	parseFormatJSON  = "json"
+)
+
+const (
+	fbLuaFnNameSampleFilter = "sampleFilter"
+	parserPrefix            = "parse_"
+)
+
+// grokPatterns are the grok patterns supported, translated to fluent bit (Onigmo) regular expressions.
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d+)?|\.\d+)`,
+	"IPV4":              `(?:\d{1,3}\.){3}\d{1,3}`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`,
+	"IP":                `(?:(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}|(?:\d{1,3}\.){3}\d{1,3})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"LOGLEVEL":          `(?:[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo|INFO|[Ww]arn(?:ing)?|WARN(?:ING)?|[Ee]rr(?:or)?|ERR(?:OR)?|[Cc]rit(?:ical)?|CRIT(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|[Aa]lert|ALERT|[Ee]merg(?:ency)?|EMERG(?:ENCY)?)`,
+	"TIMESTAMP_ISO8601": `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:[.,]\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`,
+	"HTTPDATE":          `\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+	"QS":                `"(?:[^"\\]|\\.)*"`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"URIPATH":           `/[^\s?#]*`,
+	"URIPATHPARAM":      `/[^\s#]*`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+}
+
+// grokTypes are the grok field types supported, by their fluent bit parser type.
+var grokTypes = map[string]string{
+	"int":   "integer",
+	"float": "float",
+}
+
+var (
+	grokFieldRegex = regexp.MustCompile(`%\{(\w+)(?::(\w+))?(?::(\w+))?\}`)
+	attributeRegex = regexp.MustCompile(`^\S+$`)
+)
+
+// LogParseCfg extracts the fields of the records into attributes.
+type LogParseCfg struct {
+	Format       string `yaml:"format"`        // regex, grok or json
+	Expression   string `yaml:"expression"`    // regex with named groups or grok pattern, for regex and grok formats
+	Key          string `yaml:"key"`           // attribute parsed, the message of the input when empty
+	KeepOriginal *bool  `yaml:"keep_original"` // keep the parsed attribute, true when not set
+}
+
+// LogDropCfg drops the records with an attribute matching a regex.
+type LogDropCfg struct {
+	Attribute string `yaml:"attribute"`
+	Pattern   string `yaml:"pattern"`
+}
+
+// LogSampleCfg keeps a rate of the records with an attribute having any of the values.
+type LogSampleCfg struct {
+	Attribute string   `yaml:"attribute"`
+	Values    []string `yaml:"values"`
+	Rate      float64  `yaml:"rate"` // from 0, dropping all the records, to 1, keeping all of them
+}
+
+// FBCfgParser FluentBit PARSER block, which has to be loaded from a parsers file.
+//
+//	[PARSER]
+//	  Name   parse_nginx
+//	  Format regex
+//	  Regex  ^(?<remote>[^ ]*) (?<status>\d+)$
+//	  Types  status:integer
+type FBCfgParser struct {
+	Name   string
+	Format string
+	Regex  string
+	Types  string
+}
+
+// FBSampleLuaScript drops the records matching the sample rules but for their rate.
+type FBSampleLuaScript struct {
+	FnName string
+	Rules  []FBSampleLuaRule
+}
+
+// FBSampleLuaRule is a sample rule, with its attribute and values quoted as lua strings.
+type FBSampleLuaRule struct {
+	Attribute string
+	Values    []string
+	Rate      string
+}
+
+// Format will return the formatted lua script that fluent bit config is pointing to.
+func (script FBSampleLuaScript) Format() (result string, err error) {
+	buf := new(bytes.Buffer)
+	tpl, err := template.New("fb sample lua").Parse(fbLuaSampleScriptFormat)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot parse log-forwarder template")
+	}
+	err = tpl.Execute(buf, script)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot write log-forwarder template")
+	}
+	return buf.String(), nil
+}
+
+// parseProcessing returns the filters parsing, dropping and sampling the records of the input, in this order, and the
+// parser used, if any.
+func parseProcessing(l LogCfg, inputType string) (filters []FBCfgFilter, parser *FBCfgParser, err error) {
+	if l.Parse != nil {
+		var filter FBCfgFilter
+		filter, parser, err = newParse(l, inputType)
+		if err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	for _, drop := range l.Drop {
+		if !attributeRegex.MatchString(drop.Attribute) || drop.Pattern == "" || strings.ContainsAny(drop.Pattern, "\r\n") {
+			return nil, nil, fmt.Errorf("drop: an attribute and a single line pattern are required")
+		}
+		filters = append(filters, FBCfgFilter{
+			Name:    fbFilterTypeGrep,
+			Match:   l.Name,
+			Exclude: fmt.Sprintf("%s %s", drop.Attribute, drop.Pattern),
+		})
+	}
+
+	if len(l.Sample) > 0 {
+		filter, err := newSampleFilter(l)
+		if err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, parser, nil
+}
+
+func newParse(l LogCfg, inputType string) (FBCfgFilter, *FBCfgParser, error) {
+	p := *l.Parse
+	parser := &FBCfgParser{Name: parserPrefix + helpers.SanitizeFileName(l.Name)}
+	switch strings.ToLower(p.Format) {
+	case parseFormatJSON:
+		parser.Format = parseFormatJSON
+	case parseFormatRegex:
+		parser.Format = parseFormatRegex
+		parser.Regex = p.Expression
+	case parseFormatGrok:
+		regex, types, err := grokToRegex(p.Expression)
+		if err != nil {
+			return FBCfgFilter{}, nil, err
+		}
+		parser.Format = parseFormatRegex
+		parser.Regex = regex
+		parser.Types = types
+	default:
+		return FBCfgFilter{}, nil, fmt.Errorf("parse: invalid format %s, expected regex, grok or json", p.Format)
+	}
+	if parser.Format == parseFormatRegex && (p.Expression == "" || strings.ContainsAny(p.Expression, "\r\n")) {
+		return FBCfgFilter{}, nil, fmt.Errorf("parse: a single line expression is required")
+	}
+
+	key := p.Key
+	if key == "" {
+		key = parseKeyForInput(inputType)
+	}
+	if !attributeRegex.MatchString(key) {
+		return FBCfgFilter{}, nil, fmt.Errorf("parse: invalid key %q", key)
+	}
+	preserve := "On"
+	if p.KeepOriginal != nil && !*p.KeepOriginal {
+		preserve = "Off"
+	}
+
+	return FBCfgFilter{
+		Name:     fbFilterTypeParser,
+		Match:    l.Name,
+		KeyName:  key,
+		Parser:   parser.Name,
+		Reserve:  "On",
+		Preserve: preserve,
+	}, parser, nil
+}
+
+// parseKeyForInput returns the attribute holding the message of the records of an input type.
+func parseKeyForInput(inputType string) string {
+	switch inputType {
+	case fbInputTypeSystemd:
+		return fbGrepFieldForSystemd
+	case fbInputTypeSyslog:
+		return fbGrepFieldForSyslog
+	case fbInputTypeWinlog, fbInputTypeWinevtlog:
+		return fbGrepFieldForWinEvent
+	default:
+		return fbGrepFieldForTail
+	}
+}
+
+// grokToRegex translates a grok pattern into a regex with a named group per field, returning the fluent bit parser
+// types of the fields too.
+func grokToRegex(pattern string) (regex string, types string, err error) {
+	var fieldTypes []string
+	regex = grokFieldRegex.ReplaceAllStringFunc(pattern, func(field string) string {
+		parts := grokFieldRegex.FindStringSubmatch(field)
+		expr, ok := grokPatterns[parts[1]]
+		if !ok {
+			err = fmt.Errorf("parse: unsupported grok pattern %s", parts[1])
+			return field
+		}
+		if parts[2] == "" {
+			return fmt.Sprintf("(?:%s)", expr)
+		}
+		if parts[3] != "" {
+			fbType, ok := grokTypes[parts[3]]
+			if !ok {
+				err = fmt.Errorf("parse: unsupported grok type %s, expected int or float", parts[3])
+				return field
+			}
+			fieldTypes = append(fieldTypes, parts[2]+":"+fbType)
+		}
+		return fmt.Sprintf("(?<%s>%s)", parts[2], expr)
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return regex, strings.Join(fieldTypes, " "), nil
+}
+
+func newSampleFilter(l LogCfg) (FBCfgFilter, error) {
+	script := FBSampleLuaScript{FnName: fbLuaFnNameSampleFilter}
+	for _, sample := range l.Sample {
+		if !attributeRegex.MatchString(sample.Attribute) || len(sample.Values) == 0 {
+			return FBCfgFilter{}, fmt.Errorf("sample: an attribute and its values are required")
+		}
+		if sample.Rate < 0 || sample.Rate > 1 {
+			return FBCfgFilter{}, fmt.Errorf("sample: invalid rate %v, expected 0 to 1", sample.Rate)
+		}
+		values := make([]string, 0, len(sample.Values))
+		for _, value := range sample.Values {
+			values = append(values, luaQuote(value))
+		}
+		sort.Strings(values)
+		script.Rules = append(script.Rules, FBSampleLuaRule{
+			Attribute: luaQuote(sample.Attribute),
+			Values:    values,
+			Rate:      strconv.FormatFloat(sample.Rate, 'f', -1, 64),
+		})
+	}
+
+	scriptContent, err := script.Format()
+	if err != nil {
+		return FBCfgFilter{}, err
+	}
+	scriptName, err := saveToTempFile([]byte(scriptContent))
+	if err != nil {
+		return FBCfgFilter{}, err
+	}
+	return newLuaFilter(l.Name, scriptName, fbLuaFnNameSampleFilter), nil
+}
+
+// luaQuote returns the string as a lua double-quoted string.
+func luaQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(s) + `"`
+}
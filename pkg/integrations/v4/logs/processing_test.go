@@ -0,0 +1,162 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package logs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrokToRegex(t *testing.T) {
+	regex, types, err := grokToRegex(`%{IPV4:client} %{WORD:method} %{URIPATHPARAM:request} %{INT:status:int} %{NUMBER:duration:float}%{SPACE}%{GREEDYDATA}`)
+	require.NoError(t, err)
+	assert.Equal(t, `(?<client>(?:\d{1,3}\.){3}\d{1,3}) (?<method>\b\w+\b) (?<request>/[^\s#]*) (?<status>[+-]?\d+) (?<duration>[+-]?(?:\d+(?:\.\d+)?|\.\d+))(?:\s*)(?:.*)`, regex)
+	assert.Equal(t, "status:integer duration:float", types)
+
+	_, _, err = grokToRegex(`%{COMBINEDAPACHELOG}`)
+	assert.Error(t, err)
+	_, _, err = grokToRegex(`%{INT:status:long}`)
+	assert.Error(t, err)
+}
+
+func TestNewFBConf_Processing(t *testing.T) {
+	logFwd := logFwdCfg
+	logFwd.HomeDir = t.TempDir()
+	keepOriginal := false
+	logsCfg := LogsCfg{
+		{
+			Name: "nginx",
+			File: "/var/log/nginx/access.log",
+			Parse: &LogParseCfg{
+				Format:       "grok",
+				Expression:   `%{IP:client} %{INT:status:int}`,
+				KeepOriginal: &keepOriginal,
+			},
+			Drop: []LogDropCfg{
+				{Attribute: "status", Pattern: `^2\d\d$`},
+			},
+		},
+		{
+			Name:    "app",
+			Systemd: "app",
+			Parse: &LogParseCfg{
+				Format: "json",
+			},
+			Sample: []LogSampleCfg{
+				{Attribute: "level", Values: []string{"DEBUG", `TR"ACE`}, Rate: 0.1},
+				{Attribute: "level", Values: []string{"INFO"}, Rate: 0.5},
+			},
+		},
+	}
+
+	fbCfg, err := NewFBConf(logsCfg, &logFwd, "0", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, []FBCfgParser{
+		{
+			Name:   "parse_nginx",
+			Format: "regex",
+			Regex:  `(?<client>(?:(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}|(?:\d{1,3}\.){3}\d{1,3})) (?<status>[+-]?\d+)`,
+			Types:  "status:integer",
+		},
+		{
+			Name:   "parse_app",
+			Format: "json",
+		},
+	}, fbCfg.Parsers)
+
+	var filters []FBCfgFilter
+	for _, filter := range fbCfg.Filters {
+		if filter.Name != fbFilterTypeRecordModifier {
+			filters = append(filters, filter)
+		}
+	}
+	require.Len(t, filters, 4)
+	assert.Equal(t, FBCfgFilter{
+		Name:     "parser",
+		Match:    "nginx",
+		KeyName:  "log",
+		Parser:   "parse_nginx",
+		Reserve:  "On",
+		Preserve: "Off",
+	}, filters[0])
+	assert.Equal(t, FBCfgFilter{
+		Name:    "grep",
+		Match:   "nginx",
+		Exclude: `status ^2\d\d$`,
+	}, filters[1])
+	assert.Equal(t, FBCfgFilter{
+		Name:     "parser",
+		Match:    "app",
+		KeyName:  "MESSAGE",
+		Parser:   "parse_app",
+		Reserve:  "On",
+		Preserve: "On",
+	}, filters[2])
+	assert.Equal(t, "lua", filters[3].Name)
+	assert.Equal(t, "sampleFilter", filters[3].Call)
+	script, err := os.ReadFile(filters[3].Script)
+	require.NoError(t, err)
+	defer os.Remove(filters[3].Script)
+	assert.Contains(t, string(script), `
+local rules = {
+    { attribute = "level", rate = 0.1, values = {["DEBUG"] = true, ["TR\"ACE"] = true, } },
+    { attribute = "level", rate = 0.5, values = {["INFO"] = true, } },
+}
+`)
+
+	parsers, err := os.ReadFile(fbCfg.ExternalCfg.GeneratedParsersFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, `
+[PARSER]
+    Name   parse_nginx
+    Format regex
+    Regex  (?<client>(?:(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}|(?:\d{1,3}\.){3}\d{1,3})) (?<status>[+-]?\d+)
+    Types  status:integer
+
+[PARSER]
+    Name   parse_app
+    Format json
+`, string(parsers))
+
+	result, _, err := fbCfg.Format()
+	require.NoError(t, err)
+	assert.Contains(t, result, `
+[FILTER]
+    Name  parser
+    Match nginx
+    Key_Name log
+    Parser parse_nginx
+    Reserve_Data On
+    Preserve_Key Off
+`)
+	assert.Contains(t, result, `
+[FILTER]
+    Name  grep
+    Match nginx
+    Exclude status ^2\d\d$
+`)
+}
+
+func TestNewFBConf_InvalidProcessing(t *testing.T) {
+	for name, l := range map[string]LogCfg{
+		"unknown format":    {Parse: &LogParseCfg{Format: "xml"}},
+		"empty regex":       {Parse: &LogParseCfg{Format: "regex"}},
+		"multiline regex":   {Parse: &LogParseCfg{Format: "regex", Expression: "^(?<a>.*)\n$"}},
+		"unknown grok":      {Parse: &LogParseCfg{Format: "grok", Expression: "%{NOPE:a}"}},
+		"invalid key":       {Parse: &LogParseCfg{Format: "json", Key: "a b"}},
+		"drop attribute":    {Drop: []LogDropCfg{{Pattern: "a"}}},
+		"drop pattern":      {Drop: []LogDropCfg{{Attribute: "a"}}},
+		"sample values":     {Sample: []LogSampleCfg{{Attribute: "a", Rate: 0.5}}},
+		"sample rate":       {Sample: []LogSampleCfg{{Attribute: "a", Values: []string{"b"}, Rate: 1.5}}},
+		"negative sampling": {Sample: []LogSampleCfg{{Attribute: "a", Values: []string{"b"}, Rate: -1}}},
+	} {
+		l.Name = "app"
+		l.File = "/var/log/app.log"
+		_, err := NewFBConf(LogsCfg{l}, &logFwdCfg, "0", "")
+		assert.Error(t, err, name)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/newrelic/infrastructure-agent/pkg/entity/host"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 
@@ -106,8 +107,11 @@ type Manager struct {
 // groupContext pairs a runner.Group with its cancellation context
 type groupContext struct {
 	l      sync.RWMutex
-	cancel func() // nil when there's no cancellable context
+	ctx    context.Context // running context of the group, for the integrations started when it's updated
+	cancel func()          // nil when there's no cancellable context
 	runner runner.Group
+	cfg    v4Config.YAML // loaded configuration, to detect changes on reload
+	cmdFF  *runner.CmdFF
 }
 
 func newGroupContext(gr runner.Group) *groupContext {
@@ -127,10 +131,37 @@ func (g *groupContext) start(ctx context.Context) {
 
 	cctx, cancel := context.WithCancel(ctx)
 	if g.runner.Run(cctx) {
-		g.cancel = cancel
+		g.ctx, g.cancel = cctx, cancel
+	} else {
+		cancel()
 	}
 }
 
+// apply updates the group to the configuration of next, only starting, stopping or restarting the integrations
+// added, removed or changed in it.
+func (g *groupContext) apply(ctx context.Context, next *groupContext) {
+	g.l.Lock()
+	defer g.l.Unlock()
+
+	if g.cancel == nil {
+		g.ctx, g.cancel = context.WithCancel(ctx)
+	}
+	if !g.runner.Apply(g.ctx, next.runner) {
+		g.cancel()
+		g.ctx, g.cancel = nil, nil
+	}
+	g.cfg, g.cmdFF = next.cfg, next.cmdFF
+}
+
+// canApply returns whether the group can be updated to the configuration of next without restarting it, as they
+// share their discovery and variables configuration.
+func (g *groupContext) canApply(next *groupContext) bool {
+	g.l.RLock()
+	defer g.l.RUnlock()
+
+	return reflect.DeepEqual(g.cfg.Databind, next.cfg.Databind) && reflect.DeepEqual(g.cmdFF, next.cmdFF)
+}
+
 func (g *groupContext) stop() {
 	g.l.Lock()
 	defer g.l.Unlock()
@@ -276,6 +307,70 @@ func (mgr *Manager) RunOnce(ctx context.Context) {
 	wg.Wait()
 }
 
+// Reload loads again the configuration files from the ConfigPaths, updating the integrations groups whose file
+// changed, starting the new ones and stopping the ones whose file was removed. Only the integrations changed within a
+// file are restarted. It's invoked when the agent configuration is reloaded, as file changes may not be notified, ie:
+// on network file systems.
+func (mgr *Manager) Reload(ctx context.Context) {
+	ctx = contextWithVerbose(ctx, mgr.managerConfig.Verbose)
+
+	found := make(map[string]bool)
+	for _, path := range mgr.managerConfig.ConfigPaths {
+		flog := illog.WithField("path", path)
+
+		configs, err := mgr.configLoader.Load(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				flog.WithError(err).Warn("can't load path. Ignoring")
+			}
+			continue
+		}
+
+		for cfgPath, cfg := range configs {
+			found[cfgPath] = true
+
+			var cmdFF *runner.CmdFF
+			if rc, ok := mgr.runners.Get(cfgPath); ok && rc != nil {
+				if reflect.DeepEqual(rc.cfg, cfg) {
+					continue
+				}
+				cmdFF = rc.cmdFF
+			}
+			if err := mgr.applyRunnerGroup(ctx, cfgPath, cfg, cmdFF); err != nil {
+				illog.WithField("file", cfgPath).WithError(err).Warn("can't instantiate integrations from file")
+				continue
+			}
+			illog.WithField("file", cfgPath).Info("Integrations file reloaded.")
+		}
+	}
+
+	for cfgPath, rc := range mgr.runners.List() {
+		if !found[cfgPath] {
+			illog.WithField("file", cfgPath).Info("Integrations file removed. Stopping running processes, if any")
+			rc.stop()
+			mgr.runners.Remove(cfgPath)
+		}
+	}
+}
+
+// Restart stops all the integrations and starts them again, loading their configuration files again, ie: when it's
+// requested remotely. It returns the number of restarted files.
+func (mgr *Manager) Restart(ctx context.Context) int {
+	ctx = contextWithVerbose(ctx, mgr.managerConfig.Verbose)
+
+	restarted := 0
+	for cfgPath, rc := range mgr.runners.List() {
+		cmdFF := rc.cmdFF
+		rc.stop()
+		mgr.runners.Remove(cfgPath)
+		elog := illog.WithField("file", cfgPath)
+		mgr.runIntegrationFromPath(ctx, cfgPath, false, &elog, cmdFF)
+		restarted++
+	}
+	illog.WithField("files", restarted).Info("Integrations restarted.")
+	return restarted
+}
+
 // EnableOHIFromFF enables an integration coming from CC request.
 func (mgr *Manager) EnableOHIFromFF(ctx context.Context, featureFlag string) error {
 	cfgPath, err := mgr.cfgPathForFF(featureFlag)
@@ -330,7 +425,32 @@ func (mgr *Manager) loadRunnerGroup(path string, cfg v4Config.YAML, cmdFF *runne
 
 	mgr.featuresCache.Update(fc)
 
-	return newGroupContext(gr), nil
+	gc := newGroupContext(gr)
+	gc.cfg, gc.cmdFF = cfg, cmdFF
+	return gc, nil
+}
+
+// applyRunnerGroup runs the integrations of a configuration file. When its group is already running, only the
+// integrations added, removed or changed in the file are started, stopped or restarted, unless its discovery changed.
+func (mgr *Manager) applyRunnerGroup(ctx context.Context, cfgPath string, cfg v4Config.YAML, cmdFF *runner.CmdFF) error {
+	next, err := mgr.loadRunnerGroup(cfgPath, cfg, cmdFF)
+	if err != nil {
+		return err
+	}
+
+	if rc, ok := mgr.runners.Get(cfgPath); ok && rc != nil {
+		if rc.canApply(next) {
+			rc.apply(ctx, next)
+			return nil
+		}
+		illog.WithField("file", cfgPath).Debug("Integrations discovery changed, restarting all the integrations of the file.")
+		rc.stop()
+		mgr.runners.Remove(cfgPath)
+	}
+
+	mgr.runners.Set(cfgPath, next)
+	next.start(ctx)
+	return nil
 }
 
 func (mgr *Manager) handleRequestsQueue(ctx context.Context) {
@@ -432,11 +552,10 @@ func (mgr *Manager) handleFileEvent(ctx context.Context, event *fsnotify.Event)
 		return
 	}
 
-	mgr.stopRunnerGroup(event.Name)
-
 	if isDelete {
 		if _, err := os.Stat(event.Name); os.IsNotExist(err) {
 			// if the file has been deleted, we don't continue trying to load configurations
+			mgr.stopRunnerGroup(event.Name)
 			return
 		}
 
@@ -453,8 +572,12 @@ func (mgr *Manager) handleFileEvent(ctx context.Context, event *fsnotify.Event)
 		}
 
 	}
-	// creating new configuration and starting the new runner.Group instances
-	mgr.runIntegrationFromPath(ctx, event.Name, isCreate, &elog, nil)
+	// applying the new configuration to the running runner.Group instances, if any
+	var cmdFF *runner.CmdFF
+	if rc, ok := mgr.runners.Get(event.Name); ok && rc != nil {
+		cmdFF = rc.cmdFF
+	}
+	mgr.runIntegrationFromPath(ctx, event.Name, isCreate, &elog, cmdFF)
 }
 
 func (mgr *Manager) runIntegrationFromPath(ctx context.Context, cfgPath string, isCreate bool, elog *log.Entry, cmdFF *runner.CmdFF) {
@@ -462,6 +585,7 @@ func (mgr *Manager) runIntegrationFromPath(ctx context.Context, cfgPath string,
 	if err != nil {
 		if err == v4Config.LegacyYAML {
 			elog.Debug("Skipping v3 integration.")
+			mgr.stopRunnerGroup(cfgPath)
 		} else {
 			elog.WithError(err).Warn("can't load integrations file. This may happen if you are editing a file and saving intermediate changes")
 		}
@@ -472,14 +596,9 @@ func (mgr *Manager) runIntegrationFromPath(ctx context.Context, cfgPath string,
 		elog.Debug("New integration file has been created.")
 	}
 
-	rc, err := mgr.loadRunnerGroup(cfgPath, cfg, cmdFF)
-	if err != nil {
+	if err := mgr.applyRunnerGroup(ctx, cfgPath, cfg, cmdFF); err != nil {
 		elog.WithError(err).Warn("can't instantiate integrations from file. This may happen if you are editing a file and saving intermediate changes")
-		return
 	}
-
-	mgr.runners.Set(cfgPath, rc)
-	rc.start(ctx)
 }
 
 func (mgr *Manager) stopRunnerGroup(fileName string) {
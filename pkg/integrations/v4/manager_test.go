@@ -391,6 +391,39 @@ func TestManager_HotReload_Modify(t *testing.T) {
 	require.Equal(t, "modifiedValue", metric["value"])
 }
 
+func TestManager_HotReload_ModifyKeepsUnchangedIntegrations(t *testing.T) {
+	skipIfWindows(t)
+	// GIVEN a file with two integrations
+	dir, err := tempFiles(map[string]string{
+		"integration.yaml": v4LongTimeConfig + strings.TrimPrefix(v4AppendableConfig, "---\nintegrations:\n"),
+	})
+	require.NoError(t, err)
+	defer removeTempFiles(t, dir)
+
+	emitter := &testemit.RecordEmitter{}
+	mgr := NewManager(ManagerConfig{ConfigPaths: []string{dir}, PassthroughEnvironment: passthroughEnv}, config.NewPathLoader(), emitter, integration.ErrLookup, definitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mgr.Start(ctx)
+
+	// THAT are correctly running
+	require.Equal(t, "first", expectOneMetric(t, emitter, "longtime")["value"])
+	require.Equal(t, "first", expectOneMetric(t, emitter, "hotreload-test")["value"])
+
+	// WHEN we modify one of the integrations at runtime
+	require.NoError(t, fileAppend(
+		filepath.Join(dir, "integration.yaml"),
+		"      - modifiedValue\n"))
+
+	// THEN only the modified integration is restarted
+	testhelpers.Eventually(t, 15*time.Second, func(t require.TestingT) {
+		require.Equal(t, "modifiedValue", expectOneMetric(t, emitter, "hotreload-test")["value"])
+	})
+	for i := 0; i < 2*100; i++ {
+		require.Equal(t, "longtime", expectOneMetric(t, emitter, "longtime")["value"])
+	}
+}
+
 // this test is used to make sure we see file changes on K8s
 func TestManager_HotReload_ModifyLinkFile(t *testing.T) {
 	skipIfWindows(t)
@@ -485,6 +518,70 @@ func TestManager_HotReload_Delete(t *testing.T) {
 	require.NoError(t, emitter.ExpectTimeout("longtime", 100*time.Millisecond))
 }
 
+func TestManager_Reload(t *testing.T) {
+	// GIVEN a set of integrations files
+	dir, err := tempFiles(map[string]string{
+		"unchanged.yaml": v4File,
+		"modified.yaml":  v4File,
+		"to-remove.yaml": v4File,
+	})
+	require.NoError(t, err)
+	defer removeTempFiles(t, dir)
+
+	emitter := &testemit.RecordEmitter{}
+	mgr := NewManager(ManagerConfig{ConfigPaths: []string{dir}, PassthroughEnvironment: passthroughEnv}, config.NewPathLoader(), emitter, integration.ErrLookup, definitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{})
+	unchanged, ok := mgr.runners.Get(filepath.Join(dir, "unchanged.yaml"))
+	require.True(t, ok)
+	modified, ok := mgr.runners.Get(filepath.Join(dir, "modified.yaml"))
+	require.True(t, ok)
+
+	// WHEN the files are modified, created and removed
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "modified.yaml"), []byte(v4LongTimeConfig), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "created.yaml"), []byte(v4File), 0o600))
+	require.NoError(t, os.Remove(filepath.Join(dir, "to-remove.yaml")))
+
+	// AND the manager is reloaded
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mgr.Reload(ctx)
+
+	// THEN the groups of the modified files are updated in place
+	runners := mgr.runners.List()
+	assert.Len(t, runners, 3)
+	assert.Same(t, unchanged, runners[filepath.Join(dir, "unchanged.yaml")])
+	assert.Same(t, modified, runners[filepath.Join(dir, "modified.yaml")])
+	assert.Equal(t, "longtime", runners[filepath.Join(dir, "modified.yaml")].cfg.Integrations[0].InstanceName)
+	assert.Contains(t, runners, filepath.Join(dir, "created.yaml"))
+	assert.NotContains(t, runners, filepath.Join(dir, "to-remove.yaml"))
+}
+
+func TestManager_Restart(t *testing.T) {
+	// GIVEN a set of integrations files
+	dir, err := tempFiles(map[string]string{
+		"first.yaml":  v4File,
+		"second.yaml": v4File,
+	})
+	require.NoError(t, err)
+	defer removeTempFiles(t, dir)
+
+	emitter := &testemit.RecordEmitter{}
+	mgr := NewManager(ManagerConfig{ConfigPaths: []string{dir}, PassthroughEnvironment: passthroughEnv}, config.NewPathLoader(), emitter, integration.ErrLookup, definitionQ, configEntryQ, track.NewTracker(nil), host.IDLookup{})
+	first, ok := mgr.runners.Get(filepath.Join(dir, "first.yaml"))
+	require.True(t, ok)
+
+	// WHEN the integrations are restarted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	restarted := mgr.Restart(ctx)
+
+	// THEN the groups of all the files are replaced
+	assert.Equal(t, 2, restarted)
+	runners := mgr.runners.List()
+	assert.Len(t, runners, 2)
+	assert.NotSame(t, first, runners[filepath.Join(dir, "first.yaml")])
+	assert.Contains(t, runners, filepath.Join(dir, "second.yaml"))
+}
+
 func TestManager_PassthroughEnv(t *testing.T) {
 	// GIVEN an integration
 	niDir, err := ioutil.TempDir("", "newrelic-integrations")
@@ -0,0 +1,318 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SchemaError describes why an integration payload doesn't follow the SDK schema of its protocol version.
+type SchemaError struct {
+	Version int    // protocol version declared in the payload, 0 when it can't be determined
+	Path    string // location of the invalid value, ie: data[0].metrics[2].type. Empty for the whole payload
+	Reason  string
+}
+
+func (e *SchemaError) Error() string {
+	var version string
+	if e.Version > 0 {
+		version = fmt.Sprintf(" v%d", e.Version)
+	}
+	if e.Path == "" {
+		return fmt.Sprintf("invalid protocol%s payload: %s", version, e.Reason)
+	}
+	return fmt.Sprintf("invalid protocol%s payload at %s: %s", version, e.Path, e.Reason)
+}
+
+// metricValues are the v4 metric types and whether their value is a number or an object.
+var metricValues = map[string]bool{
+	"gauge":                true,
+	"count":                true,
+	"rate":                 true,
+	"cumulative-count":     true,
+	"cumulative-rate":      true,
+	"summary":              false,
+	"prometheus-summary":   false,
+	"prometheus-histogram": false,
+}
+
+// Validate checks that an integration payload follows the SDK schema of the protocol version it declares, returning
+// a *SchemaError with the first invalid value otherwise. Unknown attributes are allowed.
+func Validate(raw []byte) error {
+	var payload interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&payload); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return &SchemaError{Reason: fmt.Sprintf("malformed JSON at offset %d: %s", syntaxErr.Offset, syntaxErr)}
+		}
+		return &SchemaError{Reason: fmt.Sprintf("malformed JSON: %s", err)}
+	}
+	if dec.More() {
+		return &SchemaError{Reason: fmt.Sprintf("unexpected data after the JSON object at offset %d", dec.InputOffset())}
+	}
+
+	root, ok := payload.(map[string]interface{})
+	if !ok {
+		return &SchemaError{Reason: "expected a JSON object"}
+	}
+	version, err := versionFromParsed(PluginProtocolVersion{RawProtocolVersion: numberToFloat(root["protocol_version"])}, false)
+	if err != nil {
+		return &SchemaError{Path: "protocol_version", Reason: err.Error()}
+	}
+
+	v := validator{version: version}
+	switch version {
+	case V1:
+		v.requiredString(root, "", "name")
+		v.optionalString(root, "", "integration_version")
+		v.dataSetV3(root, "")
+	case V2, V3:
+		v.requiredString(root, "", "name")
+		v.optionalString(root, "", "integration_version")
+		v.array(root, "", "data", true, v.dataSetV3)
+	case V4:
+		v.object(root, "", "integration", true, func(integration map[string]interface{}, path string) {
+			v.requiredString(integration, path, "name")
+			v.optionalString(integration, path, "version")
+		})
+		v.array(root, "", "data", true, v.dataSetV4)
+	}
+	if v.err != nil {
+		return v.err
+	}
+	return nil
+}
+
+// validator keeps the first schema error found.
+type validator struct {
+	version int
+	err     *SchemaError
+}
+
+func (v *validator) fail(path, reason string, args ...interface{}) {
+	if v.err == nil {
+		v.err = &SchemaError{Version: v.version, Path: path, Reason: fmt.Sprintf(reason, args...)}
+	}
+}
+
+func (v *validator) dataSetV3(dataSet map[string]interface{}, path string) {
+	v.entity(dataSet, path)
+	v.array(dataSet, path, "metrics", false, func(metric map[string]interface{}, path string) {
+		v.requiredString(metric, path, "event_type")
+	})
+	v.inventory(dataSet, path)
+	v.array(dataSet, path, "events", false, func(event map[string]interface{}, path string) {
+		v.optionalString(event, path, "summary")
+		v.optionalString(event, path, "category")
+	})
+}
+
+func (v *validator) dataSetV4(dataSet map[string]interface{}, path string) {
+	v.object(dataSet, path, "common", false, func(common map[string]interface{}, path string) {
+		v.optionalNumber(common, path, "timestamp")
+		v.optionalNumber(common, path, "interval.ms")
+		v.object(common, path, "attributes", false, nil)
+	})
+	v.entity(dataSet, path)
+	if ignore, ok := dataSet["ignore_entity"]; ok && ignore != nil {
+		if _, ok := ignore.(bool); !ok {
+			v.fail(join(path, "ignore_entity"), "expected a boolean, got %s", typeName(ignore))
+		}
+	}
+	v.array(dataSet, path, "metrics", false, v.metricV4)
+	v.inventory(dataSet, path)
+	v.array(dataSet, path, "events", false, func(event map[string]interface{}, path string) {
+		v.requiredString(event, path, "summary")
+		v.optionalString(event, path, "category")
+		v.object(event, path, "attributes", false, nil)
+	})
+}
+
+func (v *validator) metricV4(metric map[string]interface{}, path string) {
+	v.requiredString(metric, path, "name")
+	v.optionalNumber(metric, path, "timestamp")
+	v.optionalNumber(metric, path, "interval.ms")
+	v.object(metric, path, "attributes", false, nil)
+
+	metricType, ok := metric["type"].(string)
+	if !ok {
+		v.requiredString(metric, path, "type")
+		return
+	}
+	numeric, ok := metricValues[metricType]
+	if !ok {
+		v.fail(join(path, "type"), "unknown metric type %q", metricType)
+		return
+	}
+	value, ok := metric["value"]
+	if !ok || value == nil {
+		v.fail(join(path, "value"), "required")
+		return
+	}
+	if numeric {
+		if _, ok := value.(json.Number); !ok {
+			v.fail(join(path, "value"), "expected a number for a %s metric, got %s", metricType, typeName(value))
+		}
+		return
+	}
+	summary, ok := value.(map[string]interface{})
+	if !ok {
+		v.fail(join(path, "value"), "expected an object for a %s metric, got %s", metricType, typeName(value))
+		return
+	}
+	if metricType == "summary" {
+		for _, field := range []string{"count", "sum", "min", "max"} {
+			if _, ok := summary[field].(json.Number); !ok {
+				v.fail(join(join(path, "value"), field), "expected a number, got %s", typeName(summary[field]))
+				return
+			}
+		}
+	}
+}
+
+func (v *validator) entity(dataSet map[string]interface{}, path string) {
+	v.object(dataSet, path, "entity", false, func(entity map[string]interface{}, path string) {
+		v.optionalString(entity, path, "name")
+		v.optionalString(entity, path, "type")
+		v.optionalString(entity, path, "displayName")
+		v.object(entity, path, "metadata", false, nil)
+		v.array(entity, path, "id_attributes", false, func(attr map[string]interface{}, path string) {
+			v.optionalString(attr, path, "Key")
+			v.optionalString(attr, path, "Value")
+		})
+	})
+}
+
+func (v *validator) inventory(dataSet map[string]interface{}, path string) {
+	v.object(dataSet, path, "inventory", false, func(inventory map[string]interface{}, path string) {
+		keys := make([]string, 0, len(inventory))
+		for key := range inventory {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, ok := inventory[key].(map[string]interface{}); !ok {
+				v.fail(join(path, key), "expected an object, got %s", typeName(inventory[key]))
+			}
+		}
+	})
+}
+
+// object validates an object field, calling check with it when present.
+func (v *validator) object(parent map[string]interface{}, path, key string, required bool, check func(map[string]interface{}, string)) {
+	value, ok := parent[key]
+	if !ok || value == nil {
+		if required {
+			v.fail(join(path, key), "required")
+		}
+		return
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		v.fail(join(path, key), "expected an object, got %s", typeName(value))
+		return
+	}
+	if check != nil {
+		check(obj, join(path, key))
+	}
+}
+
+// array validates an array of objects field, calling check with every item.
+func (v *validator) array(parent map[string]interface{}, path, key string, required bool, check func(map[string]interface{}, string)) {
+	value, ok := parent[key]
+	if !ok || value == nil {
+		if required {
+			v.fail(join(path, key), "required")
+		}
+		return
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		v.fail(join(path, key), "expected an array, got %s", typeName(value))
+		return
+	}
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s[%d]", join(path, key), i)
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			v.fail(itemPath, "expected an object, got %s", typeName(item))
+			return
+		}
+		check(obj, itemPath)
+		if v.err != nil {
+			return
+		}
+	}
+}
+
+func (v *validator) requiredString(parent map[string]interface{}, path, key string) {
+	value, ok := parent[key].(string)
+	if !ok {
+		if parent[key] == nil {
+			v.fail(join(path, key), "required")
+		} else {
+			v.fail(join(path, key), "expected a string, got %s", typeName(parent[key]))
+		}
+		return
+	}
+	if value == "" {
+		v.fail(join(path, key), "can't be empty")
+	}
+}
+
+func (v *validator) optionalString(parent map[string]interface{}, path, key string) {
+	if value, ok := parent[key]; ok && value != nil {
+		if _, ok := value.(string); !ok {
+			v.fail(join(path, key), "expected a string, got %s", typeName(value))
+		}
+	}
+}
+
+func (v *validator) optionalNumber(parent map[string]interface{}, path, key string) {
+	if value, ok := parent[key]; ok && value != nil {
+		if _, ok := value.(json.Number); !ok {
+			v.fail(join(path, key), "expected a number, got %s", typeName(value))
+		}
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// numberToFloat converts a decoded json.Number into the float64 the protocol version parsing expects.
+func numberToFloat(value interface{}) interface{} {
+	if number, ok := value.(json.Number); ok {
+		if f, err := number.Float64(); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
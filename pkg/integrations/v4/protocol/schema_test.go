@@ -0,0 +1,107 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		payload string
+		err     string
+	}{
+		{
+			name:    "v1",
+			payload: `{"name":"com.nr.foo","protocol_version":"1","integration_version":"1.0","metrics":[{"event_type":"FooSample","value":1}],"inventory":{"foo":{"value":"bar"}},"events":[]}`,
+		},
+		{
+			name:    "v3",
+			payload: `{"name":"com.nr.foo","protocol_version":"3","integration_version":"1.0","data":[{"entity":{"name":"foo","type":"bar","id_attributes":[{"Key":"a","Value":"b"}]},"metrics":[{"event_type":"FooSample"}],"events":[{"summary":"foo"}]}]}`,
+		},
+		{
+			name:    "v4",
+			payload: `{"protocol_version":4,"integration":{"name":"foo","version":"1.0"},"data":[{"common":{"timestamp":1586357933,"interval.ms":10000},"entity":{"name":"foo","type":"bar"},"metrics":[{"name":"a","type":"gauge","value":1},{"name":"b","type":"summary","value":{"count":1,"sum":2,"min":1,"max":1}}],"inventory":{"foo":{"value":"bar"}},"events":[{"summary":"foo"}]}]}`,
+		},
+		{
+			name:    "malformed JSON",
+			payload: `{"protocol_version":"4",`,
+			err:     "invalid protocol payload: malformed JSON: unexpected EOF",
+		},
+		{
+			name:    "trailing data",
+			payload: `{"protocol_version":"4"} {}`,
+			err:     "invalid protocol payload: unexpected data after the JSON object at offset 25",
+		},
+		{
+			name:    "not an object",
+			payload: `["foo"]`,
+			err:     "invalid protocol payload: expected a JSON object",
+		},
+		{
+			name:    "missing version",
+			payload: `{"name":"foo"}`,
+			err:     "invalid protocol payload at protocol_version: protocol_version is not defined",
+		},
+		{
+			name:    "v3 missing data",
+			payload: `{"name":"foo","protocol_version":"3"}`,
+			err:     "invalid protocol v3 payload at data: required",
+		},
+		{
+			name:    "v3 metric without event type",
+			payload: `{"name":"foo","protocol_version":"3","data":[{"metrics":[{"event_type":"FooSample"},{"value":1}]}]}`,
+			err:     "invalid protocol v3 payload at data[0].metrics[1].event_type: required",
+		},
+		{
+			name:    "v3 inventory item",
+			payload: `{"name":"foo","protocol_version":"2","data":[{"inventory":{"foo":"bar"}}]}`,
+			err:     "invalid protocol v2 payload at data[0].inventory.foo: expected an object, got a string",
+		},
+		{
+			name:    "v4 missing integration",
+			payload: `{"protocol_version":"4","data":[]}`,
+			err:     "invalid protocol v4 payload at integration: required",
+		},
+		{
+			name:    "v4 unknown metric type",
+			payload: `{"protocol_version":"4","integration":{"name":"foo"},"data":[{"metrics":[{"name":"a","type":"gauges","value":1}]}]}`,
+			err:     `invalid protocol v4 payload at data[0].metrics[0].type: unknown metric type "gauges"`,
+		},
+		{
+			name:    "v4 metric value",
+			payload: `{"protocol_version":"4","integration":{"name":"foo"},"data":[{"metrics":[{"name":"a","type":"count","value":"1"}]}]}`,
+			err:     "invalid protocol v4 payload at data[0].metrics[0].value: expected a number for a count metric, got a string",
+		},
+		{
+			name:    "v4 summary value",
+			payload: `{"protocol_version":"4","integration":{"name":"foo"},"data":[{"metrics":[{"name":"a","type":"summary","value":{"count":1,"sum":2,"min":1}}]}]}`,
+			err:     "invalid protocol v4 payload at data[0].metrics[0].value.max: expected a number, got null",
+		},
+		{
+			name:    "v4 timestamp",
+			payload: `{"protocol_version":"4","integration":{"name":"foo"},"data":[{"common":{"timestamp":"now"}}]}`,
+			err:     "invalid protocol v4 payload at data[0].common.timestamp: expected a number, got a string",
+		},
+		{
+			name:    "v4 event without summary",
+			payload: `{"protocol_version":"4","integration":{"name":"foo"},"data":[{"events":[{"category":"foo"}]}]}`,
+			err:     "invalid protocol v4 payload at data[0].events[0].summary: required",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate([]byte(tc.payload))
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tc.err, err.Error())
+		})
+	}
+}
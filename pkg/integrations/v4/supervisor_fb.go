@@ -217,6 +217,10 @@ func buildFbExecutor(fbIntCfg fBSupervisorConfig, cfgLoader *logs.CfgLoader) fun
 			args = append(args, "-R", externalCfg.ParsersFilePath)
 		}
 
+		if externalCfg.GeneratedParsersFilePath != "" {
+			args = append(args, "-R", externalCfg.GeneratedParsersFilePath)
+		}
+
 		if fbIntCfg.FluentBitVerbose {
 			args = append(args, "-vv")
 		}
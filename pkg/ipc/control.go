@@ -0,0 +1,109 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package ipc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Control commands served by the agent control socket.
+const (
+	DebugStacks       = "debug stacks"
+	DebugMutex        = "debug mutex"
+	MaintenanceStart  = "maintenance start"
+	MaintenanceStop   = "maintenance stop"
+	MaintenanceStatus = "maintenance status"
+)
+
+const (
+	responseOK    = "ok"
+	responseError = "error: "
+)
+
+// ErrEmptyRequest is returned when a control request carries no command.
+var ErrEmptyRequest = errors.New("empty control request")
+
+// Request is a command sent through the agent control socket.
+// On the wire it's a single line: the command followed by its arguments, space separated.
+type Request struct {
+	Command string
+	Args    []string
+}
+
+// WriteRequest writes a control request for the given command and arguments.
+func WriteRequest(w io.Writer, command string, args ...string) error {
+	line := strings.Join(append([]string{command}, args...), " ")
+	_, err := io.WriteString(w, line+"\n")
+	return err
+}
+
+// ReadRequest reads a control request, matching the longest known command prefix so multi-word
+// commands such as "debug stacks" are not mistaken for arguments.
+func ReadRequest(r *bufio.Reader, commands []string) (Request, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return Request{}, err
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return Request{}, ErrEmptyRequest
+	}
+
+	best := 0
+	for _, c := range commands {
+		cFields := strings.Fields(c)
+		if len(cFields) <= best || len(cFields) > len(fields) {
+			continue
+		}
+		if strings.Join(fields[:len(cFields)], " ") == strings.Join(cFields, " ") {
+			best = len(cFields)
+		}
+	}
+	if best == 0 {
+		best = 1
+	}
+
+	return Request{
+		Command: strings.Join(fields[:best], " "),
+		Args:    fields[best:],
+	}, nil
+}
+
+// WriteResponse writes the control response: a status line followed by the payload on success.
+func WriteResponse(w io.Writer, payload []byte, handlerErr error) error {
+	if handlerErr != nil {
+		msg := strings.ReplaceAll(handlerErr.Error(), "\n", " ")
+		_, err := io.WriteString(w, responseError+msg+"\n")
+		return err
+	}
+
+	if _, err := io.WriteString(w, responseOK+"\n"); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// ReadResponse reads a control response, returning the payload or the error reported by the agent.
+func ReadResponse(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	status, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("cannot read control response status: %w", err)
+	}
+	status = strings.TrimSuffix(status, "\n")
+
+	if strings.HasPrefix(status, responseError) {
+		return nil, errors.New(strings.TrimPrefix(status, responseError))
+	}
+	if status != responseOK {
+		return nil, fmt.Errorf("unexpected control response status: %q", status)
+	}
+
+	return io.ReadAll(br)
+}
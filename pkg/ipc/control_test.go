@@ -0,0 +1,72 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package ipc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRequest(t *testing.T) {
+	commands := []string{"debug", DebugStacks, DebugMutex}
+
+	tests := []struct {
+		name    string
+		line    string
+		want    Request
+		wantErr error
+	}{
+		{"multi word command", "debug stacks\n", Request{Command: DebugStacks, Args: []string{}}, nil},
+		{"command with args", "debug mutex 10\n", Request{Command: DebugMutex, Args: []string{"10"}}, nil},
+		{"shorter command match", "debug other\n", Request{Command: "debug", Args: []string{"other"}}, nil},
+		{"unknown command", "foo bar\n", Request{Command: "foo", Args: []string{"bar"}}, nil},
+		{"no trailing newline", "debug stacks", Request{Command: DebugStacks, Args: []string{}}, nil},
+		{"empty", "\n", Request{}, ErrEmptyRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := ReadRequest(bufio.NewReader(strings.NewReader(tt.line)), commands)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, req)
+		})
+	}
+}
+
+func TestRequestRoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteRequest(buf, DebugMutex, "5"))
+
+	req, err := ReadRequest(bufio.NewReader(buf), []string{DebugMutex})
+	require.NoError(t, err)
+	assert.Equal(t, DebugMutex, req.Command)
+	assert.Equal(t, []string{"5"}, req.Args)
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteResponse(buf, []byte("goroutine 1 [running]:\n"), nil))
+
+	payload, err := ReadResponse(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "goroutine 1 [running]:\n", string(payload))
+}
+
+func TestResponseRoundTrip_Error(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteResponse(buf, []byte("ignored"), errors.New("unknown\ncommand")))
+
+	payload, err := ReadResponse(buf)
+	assert.Nil(t, payload)
+	assert.EqualError(t, err, "unknown command")
+}
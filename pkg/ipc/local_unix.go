@@ -0,0 +1,43 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build linux || darwin
+// +build linux darwin
+
+package ipc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Listen creates a local socket only accessible by the agent user: a unix domain socket at the address path.
+func Listen(address string) (net.Listener, error) {
+	// remove stale socket left by a previous run
+	if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(address), 0755); err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("unix", address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(address, 0600); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Dial connects to the local socket listening at the address.
+func Dial(ctx context.Context, address string) (net.Conn, error) {
+	d := net.Dialer{}
+	return d.DialContext(ctx, "unix", address)
+}
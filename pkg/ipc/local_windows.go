@@ -0,0 +1,26 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package ipc
+
+import (
+	"context"
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// localPipeSecurity grants access to Administrators and LocalSystem only.
+const localPipeSecurity = "D:P(A;;GA;;;BA)(A;;GA;;;SY)"
+
+// Listen creates a local socket only accessible by privileged users: a named pipe at the address, ie:
+// \\.\pipe\newrelic-infra-ctl.
+func Listen(address string) (net.Listener, error) {
+	return winio.ListenPipe(address, &winio.PipeConfig{
+		SecurityDescriptor: localPipeSecurity,
+	})
+}
+
+// Dial connects to the local socket listening at the address.
+func Dial(ctx context.Context, address string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, address)
+}
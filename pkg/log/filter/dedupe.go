@@ -0,0 +1,105 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/dedupe"
+	"github.com/sirupsen/logrus"
+)
+
+// RepeatedField is set in the summaries of the deduplicated log entries, with the amount of entries suppressed.
+const RepeatedField = "repeated"
+
+// minSummaryInterval bounds how often the ended windows are checked.
+const minSummaryInterval = time.Second
+
+// DedupingFormatter decorator implementing logrus.Formatter interface.
+// It only formats the first of the identical warning and error entries logged within a window, numbers aside, so
+// the entries repeated on every harvest don't flood the log. Run logs a summary with the amount of entries
+// suppressed once the window ends.
+type DedupingFormatter struct {
+	window     time.Duration
+	aggregator *dedupe.Aggregator
+	wrapped    logrus.Formatter
+}
+
+// NewDedupingFormatter creates a new DedupingFormatter.
+func NewDedupingFormatter(window time.Duration, wrapped logrus.Formatter) *DedupingFormatter {
+	return &DedupingFormatter{
+		window:     window,
+		aggregator: dedupe.NewAggregator(window),
+		wrapped:    wrapped,
+	}
+}
+
+// Format renders a single log entry.
+func (f *DedupingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level > logrus.WarnLevel {
+		return f.wrapped.Format(entry)
+	}
+	if _, ok := entry.Data[RepeatedField]; ok {
+		return f.wrapped.Format(entry)
+	}
+	if f.aggregator.Seen(entryKey(entry), summaryEntry(entry)) {
+		return f.wrapped.Format(entry)
+	}
+	return nil, nil
+}
+
+// Run logs the summaries of the repeated entries until the context is done.
+func (f *DedupingFormatter) Run(ctx context.Context) {
+	interval := f.window / 5
+	if interval < minSummaryInterval {
+		interval = minSummaryInterval
+	}
+	f.aggregator.Run(ctx, interval, func(s dedupe.Summary) {
+		last, ok := s.Last.(*logrus.Entry)
+		if !ok || last.Logger == nil {
+			return
+		}
+		last.WithField(RepeatedField, s.Count).
+			Logf(last.Level, "%s (repeated %d times in %s)", last.Message, s.Count, s.Window.Round(time.Second))
+	})
+}
+
+// entryKey identifies the identical entries by their level, message and fields, numbers aside.
+func entryKey(entry *logrus.Entry) string {
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(entry.Level.String())
+	b.WriteByte('|')
+	b.WriteString(dedupe.Normalize(entry.Message))
+	for _, key := range keys {
+		b.WriteByte('|')
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(dedupe.Normalize(fmt.Sprint(entry.Data[key])))
+	}
+	return b.String()
+}
+
+// summaryEntry copies the entry, as logrus reuses them.
+func summaryEntry(entry *logrus.Entry) *logrus.Entry {
+	data := make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		data[key] = value
+	}
+	return &logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    data,
+		Level:   entry.Level,
+		Message: entry.Message,
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupingFormatter(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(out)
+	logger.SetLevel(logrus.DebugLevel)
+	formatter := NewDedupingFormatter(time.Minute, &logrus.TextFormatter{DisableTimestamp: true})
+	logger.SetFormatter(formatter)
+
+	for _, pid := range []int{123, 456, 789} {
+		logger.WithField("component", "ProcessSampler").WithError(errors.New("process not found")).
+			Errorf("cannot find process with pid %d", pid)
+	}
+	logger.WithField("component", "ProcessSampler").Warn("another warning")
+	// only warnings and errors are deduplicated
+	logger.Debug("harvesting")
+	logger.Debug("harvesting")
+
+	assert.Equal(t, `level=error msg="cannot find process with pid 123" component=ProcessSampler error="process not found"`+"\n"+
+		`level=warning msg="another warning" component=ProcessSampler`+"\n"+
+		`level=debug msg=harvesting`+"\n"+
+		`level=debug msg=harvesting`+"\n", out.String())
+
+	// the summaries are logged when stopping
+	out.Reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	formatter.Run(ctx)
+	assert.Equal(t, `level=error msg="cannot find process with pid 789 (repeated 2 times in 0s)" component=ProcessSampler error="process not found" repeated=2`+"\n", out.String())
+
+	// a new window starts
+	out.Reset()
+	logger.WithField("component", "ProcessSampler").WithError(errors.New("process not found")).
+		Error("cannot find process with pid 1")
+	assert.NotEmpty(t, out.String())
+}
@@ -0,0 +1,48 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"github.com/newrelic/infrastructure-agent/pkg/helpers/redact"
+	"github.com/sirupsen/logrus"
+)
+
+// RedactingFormatter decorator implementing logrus.Formatter interface.
+// It hides the secrets of the message and the string and error fields of the log entries before formatting them.
+type RedactingFormatter struct {
+	wrapped logrus.Formatter
+}
+
+// NewRedactingFormatter creates a new RedactingFormatter, applying the default redactor.
+func NewRedactingFormatter(wrapped logrus.Formatter) *RedactingFormatter {
+	return &RedactingFormatter{wrapped: wrapped}
+}
+
+// Format renders a single log entry.
+func (f *RedactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	r := redact.Default()
+	if r == nil {
+		return f.wrapped.Format(entry)
+	}
+
+	// the entry data can be shared with other entries, so a copy is redacted
+	redacted := *entry
+	redacted.Message = r.String(entry.Message)
+	redacted.Data = make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			redacted.Data[key] = r.String(v)
+		case error:
+			if s := r.String(v.Error()); s != v.Error() {
+				redacted.Data[key] = s
+			} else {
+				redacted.Data[key] = v
+			}
+		default:
+			redacted.Data[key] = value
+		}
+	}
+	return f.wrapped.Format(&redacted)
+}
@@ -24,6 +24,8 @@ const (
 	defaultDatePattern = "YYYY-MM-DD_hh-mm-ss"
 	// filePerm specified the permissions while opening a file.
 	filePerm = 0o666
+	// agePurgeInterval is how often the rotated files exceeding the max age are purged when the file is not rotated.
+	agePurgeInterval = time.Hour
 )
 
 // ErrFileNotOpened is returned when an operation cannot be performed because the file is not opened.
@@ -36,6 +38,8 @@ type FileWithRotationConfig struct {
 	MaxSizeInBytes  int64
 	Compress        bool
 	MaxFiles        int
+	// MaxAge rotated files are kept for, 0 keeps them regardless of their age.
+	MaxAge time.Duration
 }
 
 // FileWithRotation decorates a file with rotation mechanism.
@@ -52,6 +56,7 @@ type FileWithRotation struct {
 	file *os.File
 
 	writtenBytes int64
+	lastPurge    time.Time
 
 	getTimeFn func() time.Time
 }
@@ -64,12 +69,17 @@ func NewFileWithRotation(cfg FileWithRotationConfig) *FileWithRotation {
 	}
 }
 
-// Open the file to write in. If the file doesn't exist, a new file will be created.
+// Open the file to write in. If the file doesn't exist, a new file will be created. The rotated files exceeding the
+// limits, ie: from previous runs, are purged.
 func (f *FileWithRotation) Open() (*FileWithRotation, error) {
 	f.Lock()
 	defer f.Unlock()
 
-	return f, f.open()
+	if err := f.open(); err != nil {
+		return f, err
+	}
+	f.asyncPurge()
+	return f, nil
 }
 
 // Close the file.
@@ -113,6 +123,9 @@ func (f *FileWithRotation) Write(content []byte) (int, error) {
 		} else {
 			f.asyncPostRotateActions(newFile)
 		}
+	} else if f.cfg.MaxAge > 0 && f.getTimeFn().Sub(f.lastPurge) >= agePurgeInterval {
+		// Rotated files also age while the file is not rotated.
+		f.asyncPurge()
 	}
 
 	if f.file == nil {
@@ -167,7 +180,24 @@ func (f *FileWithRotation) rotate(newFile string) error {
 	return nil
 }
 
+// asyncPurge removes the rotated files exceeding the max files or age.
+func (f *FileWithRotation) asyncPurge() {
+	if f.cfg.MaxFiles < 1 && f.cfg.MaxAge <= 0 {
+		return
+	}
+	f.lastPurge = f.getTimeFn()
+
+	go func() {
+		rLog := WithComponent("LogRotator")
+		if err := f.purgeFiles(rLog); err != nil {
+			rLog.WithError(err).Error("Failed to clean old rotated log files")
+		}
+	}()
+}
+
 func (f *FileWithRotation) asyncPostRotateActions(rotatedFile string) {
+	f.lastPurge = f.getTimeFn()
+
 	go func() {
 		rLog := WithComponent("LogRotator")
 
@@ -239,9 +269,9 @@ func (f *FileWithRotation) compress(file string, log Entry) error {
 	return nil
 }
 
-// purgeFiles will remove older files in case MaxFiles is exceeded.
+// purgeFiles will remove older files in case MaxFiles or MaxAge is exceeded.
 func (f *FileWithRotation) purgeFiles(log Entry) error {
-	if f.cfg.MaxFiles < 1 {
+	if f.cfg.MaxFiles < 1 && f.cfg.MaxAge <= 0 {
 		// Nothing to do.
 		return nil
 	}
@@ -271,18 +301,25 @@ func (f *FileWithRotation) purgeFiles(log Entry) error {
 		}
 	}
 
-	if len(filteredFiles) <= f.cfg.MaxFiles {
-		// Nothing to do.
-		return nil
-	}
-
 	// Sort files by last modification time, the newest first.
 	sort.Slice(filteredFiles, func(i, j int) bool {
 		return filteredFiles[i].ModTime().After(filteredFiles[j].ModTime())
 	})
 
+	// Keep the newest files within MaxFiles and MaxAge.
+	keep := len(filteredFiles)
+	if f.cfg.MaxFiles > 0 && keep > f.cfg.MaxFiles {
+		keep = f.cfg.MaxFiles
+	}
+	if f.cfg.MaxAge > 0 {
+		oldest := f.getTimeFn().Add(-f.cfg.MaxAge)
+		for keep > 0 && filteredFiles[keep-1].ModTime().Before(oldest) {
+			keep--
+		}
+	}
+
 	// Remove older files.
-	for _, file := range filteredFiles[f.cfg.MaxFiles:] {
+	for _, file := range filteredFiles[keep:] {
 		fileName := filepath.Join(dir, file.Name())
 
 		log.Debugf("Purging old file: %s", fileName)
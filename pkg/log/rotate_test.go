@@ -663,6 +663,81 @@ func TestShouldNotPurgeFiles(t *testing.T) {
 	assert.Equal(t, files[1].Name(), filepath.Base(rotatedFile))
 }
 
+func TestPurgeFilesByAge(t *testing.T) {
+	tmp := t.TempDir()
+	logFile := filepath.Join(tmp, "newrelic-infra.log")
+	now := time.Date(2022, time.January, 10, 10, 0, 0, 0, time.Local)
+
+	// GIVEN a log file and 3 rotated files, 1, 3 and 5 days old
+	file, err := disk.OpenFile(logFile, os.O_RDWR|os.O_CREATE, filePerm)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	var rotatedFiles []string
+	for _, days := range []int{1, 3, 5} {
+		rotatedFile := fmt.Sprintf("%s.%d.bk", logFile, days)
+		rotated, err := disk.OpenFile(rotatedFile, os.O_RDWR|os.O_CREATE, filePerm)
+		require.NoError(t, err)
+		require.NoError(t, rotated.Close())
+		modTime := now.Add(-time.Duration(days) * 24 * time.Hour)
+		require.NoError(t, os.Chtimes(rotatedFile, modTime, modTime))
+		rotatedFiles = append(rotatedFiles, rotatedFile)
+	}
+
+	// WITH a MaxAge of 2 days and a MaxFiles allowing all of them
+	rotator := NewFileWithRotation(FileWithRotationConfig{
+		File:            logFile,
+		FileNamePattern: "newrelic-infra.log.hh.bk",
+		MaxFiles:        5,
+		MaxAge:          48 * time.Hour,
+	})
+	rotator.getTimeFn = func() time.Time { return now }
+
+	// WHEN purgeFiles
+	require.NoError(t, rotator.purgeFiles(WithComponent("test")))
+
+	// THEN only the files younger than 2 days remain
+	assert.FileExists(t, logFile)
+	assert.FileExists(t, rotatedFiles[0])
+	assert.NoFileExists(t, rotatedFiles[1])
+	assert.NoFileExists(t, rotatedFiles[2])
+}
+
+func TestOpenPurgesFiles(t *testing.T) {
+	tmp := t.TempDir()
+	logFile := filepath.Join(tmp, "newrelic-infra.log")
+
+	// GIVEN 3 files rotated by a previous run
+	var rotatedFiles []string
+	for i := 1; i <= 3; i++ {
+		rotatedFile := fmt.Sprintf("%s.%d.bk", logFile, i)
+		rotated, err := disk.OpenFile(rotatedFile, os.O_RDWR|os.O_CREATE, filePerm)
+		require.NoError(t, err)
+		require.NoError(t, rotated.Close())
+		modTime := time.Now().Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(rotatedFile, modTime, modTime))
+		rotatedFiles = append(rotatedFiles, rotatedFile)
+	}
+
+	// WHEN the file is opened with a MaxFiles config of 1
+	file, err := NewFileWithRotation(FileWithRotationConfig{
+		File:            logFile,
+		MaxSizeInBytes:  1000,
+		FileNamePattern: "newrelic-infra.log.hh.bk",
+		MaxFiles:        1,
+	}).Open()
+	require.NoError(t, err)
+	defer file.Close()
+
+	// THEN the older rotated files are purged
+	assert.Eventually(t, func() bool {
+		_, err1 := os.Stat(rotatedFiles[0])
+		_, err2 := os.Stat(rotatedFiles[1])
+		return os.IsNotExist(err1) && os.IsNotExist(err2)
+	}, time.Second, 10*time.Millisecond)
+	assert.FileExists(t, rotatedFiles[2])
+}
+
 // TestWithLogger will set a FileWithRotation to the logger and trigger rotation functionality.
 // If global logger will be used inside FileWithRotation it can lead to a deadlock.
 func TestWithLogger(t *testing.T) {
@@ -0,0 +1,360 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Syslog transport protocols.
+const (
+	SyslogProtocolUDP = "udp"
+	SyslogProtocolTCP = "tcp"
+	SyslogProtocolTLS = "tls"
+)
+
+const (
+	defaultSyslogFacility = "daemon"
+	defaultSyslogAppName  = "newrelic-infra"
+	// syslogSDID identifies the structured data element holding the log entry fields.
+	// 32473 is the private enterprise number reserved for documentation (RFC 5612).
+	syslogSDID           = "fields@32473"
+	syslogQueueSize      = 1000
+	syslogDialTimeout    = 5 * time.Second
+	syslogWriteTimeout   = 5 * time.Second
+	syslogReconnectDelay = 10 * time.Second
+	// RFC 5424 PARAM-NAME max length.
+	syslogMaxParamName = 32
+)
+
+var (
+	// ErrSyslogAddressRequired is returned when no remote syslog address is configured.
+	ErrSyslogAddressRequired = errors.New("syslog address is required")
+
+	syslogFacilities = map[string]int{
+		"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+		"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+		"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+		"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+	}
+
+	syslogSeverities = map[logrus.Level]int{
+		logrus.PanicLevel: 0, // emergency
+		logrus.FatalLevel: 2, // critical
+		logrus.ErrorLevel: 3,
+		logrus.WarnLevel:  4,
+		logrus.InfoLevel:  6,
+		logrus.DebugLevel: 7,
+		logrus.TraceLevel: 7,
+	}
+)
+
+// SyslogConfig keeps the configuration for a remote syslog output.
+type SyslogConfig struct {
+	// Address of the remote endpoint as host:port.
+	Address string
+	// Protocol is one of udp, tcp or tls.
+	Protocol string
+	// CAFile validates the endpoint certificate when using tls. System pool is used when empty.
+	CAFile string
+	// InsecureSkipVerify disables the endpoint certificate validation.
+	InsecureSkipVerify bool
+}
+
+// SyslogFormatter renders log entries as RFC 5424 messages. Entry fields are preserved
+// as structured data parameters.
+type SyslogFormatter struct {
+	facility int
+	hostname string
+	appName  string
+	procID   string
+}
+
+// NewSyslogFormatter creates a formatter for the given facility and app name.
+func NewSyslogFormatter(facility, appName string) (*SyslogFormatter, error) {
+	if facility == "" {
+		facility = defaultSyslogFacility
+	}
+	f, ok := syslogFacilities[strings.ToLower(facility)]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility: %s", facility)
+	}
+
+	if appName == "" {
+		appName = defaultSyslogAppName
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &SyslogFormatter{
+		facility: f,
+		hostname: hostname,
+		appName:  appName,
+		procID:   strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+// Format renders a single log entry.
+func (f *SyslogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	msgID := "-"
+	if c, ok := entry.Data["component"].(string); ok && c != "" {
+		msgID = syslogHeaderValue(c)
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "<%d>1 %s %s %s %s %s ",
+		f.facility*8+syslogSeverities[entry.Level],
+		entry.Time.Format(time.RFC3339Nano),
+		f.hostname,
+		f.appName,
+		f.procID,
+		msgID,
+	)
+	writeStructuredData(b, entry.Data)
+	b.WriteByte(' ')
+	b.WriteString(entry.Message)
+
+	return b.Bytes(), nil
+}
+
+func writeStructuredData(b *bytes.Buffer, fields logrus.Fields) {
+	if len(fields) == 0 {
+		b.WriteByte('-')
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b.WriteString("[" + syslogSDID)
+	for _, k := range keys {
+		v := fields[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fmt.Fprintf(b, ` %s="%s"`, syslogParamName(k), syslogParamValue(fmt.Sprint(v)))
+	}
+	b.WriteByte(']')
+}
+
+// syslogParamName drops characters not allowed in RFC 5424 PARAM-NAME.
+func syslogParamName(name string) string {
+	n := strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, name)
+	if len(n) > syslogMaxParamName {
+		n = n[:syslogMaxParamName]
+	}
+	return n
+}
+
+// syslogParamValue escapes characters with special meaning in RFC 5424 PARAM-VALUE.
+func syslogParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+// syslogHeaderValue replaces characters not allowed in RFC 5424 header fields.
+func syslogHeaderValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 {
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// SyslogHook is a logrus hook shipping log entries to a remote syslog endpoint.
+// Entries are queued and written asynchronously so a slow or unreachable endpoint never blocks
+// the agent. Entries are dropped when the queue is full.
+// Entries rendered as empty by the formatter (ie: filtered out) are not sent.
+type SyslogHook struct {
+	cfg       SyslogConfig
+	formatter logrus.Formatter
+	tlsCfg    *tls.Config
+	queue     chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+
+	conn    net.Conn
+	healthy bool
+}
+
+// NewSyslogHook creates a hook shipping entries rendered by formatter to the configured endpoint.
+func NewSyslogHook(cfg SyslogConfig, formatter logrus.Formatter) (*SyslogHook, error) {
+	if cfg.Address == "" {
+		return nil, ErrSyslogAddressRequired
+	}
+	if cfg.Protocol == "" {
+		cfg.Protocol = SyslogProtocolUDP
+	}
+
+	h := &SyslogHook{
+		cfg:       cfg,
+		formatter: formatter,
+		queue:     make(chan []byte, syslogQueueSize),
+		done:      make(chan struct{}),
+		healthy:   true,
+	}
+
+	switch cfg.Protocol {
+	case SyslogProtocolUDP, SyslogProtocolTCP:
+	case SyslogProtocolTLS:
+		tlsCfg, err := syslogTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		h.tlsCfg = tlsCfg
+	default:
+		return nil, fmt.Errorf("unknown syslog protocol: %s", cfg.Protocol)
+	}
+
+	go h.run()
+
+	return h, nil
+}
+
+func syslogTLSConfig(cfg SyslogConfig) (*tls.Config, error) {
+	host, _, err := net.SplitHostPort(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{
+		ServerName:         host,
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify, // nolint:gosec
+	}
+
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read syslog CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in syslog CA file: %s", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	return tlsCfg, nil
+}
+
+// Levels returns all the levels, as level filtering is already applied by the logger.
+func (h *SyslogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire queues the entry to be shipped.
+func (h *SyslogHook) Fire(entry *logrus.Entry) error {
+	msg, err := h.formatter.Format(entry)
+	if err != nil || len(msg) == 0 {
+		return err
+	}
+
+	select {
+	case h.queue <- msg:
+	default:
+		// queue full, drop entry
+	}
+	return nil
+}
+
+// Close stops shipping entries.
+func (h *SyslogHook) Close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+func (h *SyslogHook) run() {
+	defer func() {
+		if h.conn != nil {
+			_ = h.conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-h.done:
+			return
+		case msg := <-h.queue:
+			h.write(msg)
+		}
+	}
+}
+
+func (h *SyslogHook) write(msg []byte) {
+	if h.conn == nil {
+		conn, err := h.dial()
+		if err != nil {
+			h.disconnected(err)
+			return
+		}
+		h.conn = conn
+		h.healthy = true
+	}
+
+	_ = h.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	if _, err := h.conn.Write(h.frame(msg)); err != nil {
+		_ = h.conn.Close()
+		h.conn = nil
+		h.disconnected(err)
+	}
+}
+
+// disconnected reports the first failure only and waits before the next attempt, dropping
+// the entries queued meanwhile. Warnings are logged from this goroutine so they're queued as well.
+func (h *SyslogHook) disconnected(err error) {
+	if h.healthy {
+		h.healthy = false
+		WithComponent("SyslogHook").WithError(err).WithField("address", h.cfg.Address).
+			Warn("Cannot ship logs to remote syslog, retrying.")
+	}
+
+	select {
+	case <-h.done:
+	case <-time.After(syslogReconnectDelay):
+	}
+}
+
+// frame uses octet counting on stream transports (RFC 6587, RFC 5425) and one message per datagram on udp.
+func (h *SyslogHook) frame(msg []byte) []byte {
+	if h.cfg.Protocol == SyslogProtocolUDP {
+		return msg
+	}
+	return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+}
+
+func (h *SyslogHook) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: syslogDialTimeout}
+	switch h.cfg.Protocol {
+	case SyslogProtocolTLS:
+		return tls.DialWithDialer(d, "tcp", h.cfg.Address, h.tlsCfg)
+	default:
+		return d.Dial(h.cfg.Protocol, h.cfg.Address)
+	}
+}
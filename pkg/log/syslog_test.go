@@ -0,0 +1,139 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package log
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogFormatter_Format(t *testing.T) {
+	f, err := NewSyslogFormatter("local0", "")
+	require.NoError(t, err)
+	f.hostname = "host"
+	f.procID = "42"
+
+	entry := &logrus.Entry{
+		Time:    time.Date(2022, 6, 10, 15, 46, 38, 0, time.UTC),
+		Level:   logrus.WarnLevel,
+		Message: "something happened",
+		Data: logrus.Fields{
+			"component":   "integrations.runner.Runner",
+			"error":       errors.New(`bad "quote"]`),
+			"with spaces": 1,
+		},
+	}
+
+	out, err := f.Format(entry)
+	require.NoError(t, err)
+
+	// local0 (16) * 8 + warning (4)
+	expected := `<132>1 2022-06-10T15:46:38Z host newrelic-infra 42 integrations.runner.Runner ` +
+		`[fields@32473 component="integrations.runner.Runner" error="bad \"quote\"\]" with_spaces="1"] something happened`
+	assert.Equal(t, expected, string(out))
+}
+
+func TestSyslogFormatter_NoFields(t *testing.T) {
+	f, err := NewSyslogFormatter("", "agent")
+	require.NoError(t, err)
+	f.hostname = "host"
+	f.procID = "42"
+
+	out, err := f.Format(&logrus.Entry{Time: time.Unix(0, 0).UTC(), Level: logrus.InfoLevel, Message: "hi"})
+	require.NoError(t, err)
+
+	// daemon (3) * 8 + info (6)
+	assert.Equal(t, "<30>1 1970-01-01T00:00:00Z host agent 42 - - hi", string(out))
+}
+
+func TestNewSyslogFormatter_UnknownFacility(t *testing.T) {
+	_, err := NewSyslogFormatter("foo", "")
+	assert.EqualError(t, err, "unknown syslog facility: foo")
+}
+
+func TestNewSyslogHook_InvalidConfig(t *testing.T) {
+	_, err := NewSyslogHook(SyslogConfig{}, &logrus.TextFormatter{})
+	assert.ErrorIs(t, err, ErrSyslogAddressRequired)
+
+	_, err = NewSyslogHook(SyslogConfig{Address: "localhost:514", Protocol: "foo"}, &logrus.TextFormatter{})
+	assert.EqualError(t, err, "unknown syslog protocol: foo")
+}
+
+type staticFormatter string
+
+func (f staticFormatter) Format(_ *logrus.Entry) ([]byte, error) {
+	return []byte(f), nil
+}
+
+func TestSyslogHook_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	hook, err := NewSyslogHook(SyslogConfig{Address: pc.LocalAddr().String()}, staticFormatter("msg"))
+	require.NoError(t, err)
+	defer hook.Close()
+
+	require.NoError(t, hook.Fire(&logrus.Entry{}))
+
+	buf := make([]byte, 64)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "msg", string(buf[:n]))
+}
+
+func TestSyslogHook_TCPOctetCounting(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	hook, err := NewSyslogHook(SyslogConfig{Address: l.Addr().String(), Protocol: SyslogProtocolTCP}, staticFormatter("hello world"))
+	require.NoError(t, err)
+	defer hook.Close()
+
+	require.NoError(t, hook.Fire(&logrus.Entry{}))
+	require.NoError(t, hook.Fire(&logrus.Entry{}))
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	r := bufio.NewReader(conn)
+	for i := 0; i < 2; i++ {
+		length, err := r.ReadString(' ')
+		require.NoError(t, err)
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		require.NoError(t, err)
+
+		msg := make([]byte, n)
+		_, err = io.ReadFull(r, msg)
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(msg))
+	}
+}
+
+func TestSyslogHook_SkipsFilteredEntries(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	hook, err := NewSyslogHook(SyslogConfig{Address: pc.LocalAddr().String()}, staticFormatter(""))
+	require.NoError(t, err)
+	defer hook.Close()
+
+	require.NoError(t, hook.Fire(&logrus.Entry{}))
+	assert.Len(t, hook.queue, 0)
+}
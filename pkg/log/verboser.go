@@ -3,6 +3,7 @@
 package log
 
 import (
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -56,3 +57,40 @@ func shouldRun() bool {
 		return false
 	}
 }
+
+// temporaryLevel tracks the level set by SetLevelFor, to restore the previous one once it ends.
+var temporaryLevel struct {
+	sync.Mutex
+	prev       logrus.Level
+	generation uint64 // increased on every call, so only the last one restores the previous level
+	active     bool
+}
+
+// SetLevelFor sets the log level for the given duration, restoring the previous level afterwards. Setting it again
+// before it ends replaces the level and the duration, still restoring the level previous to the first call.
+func SetLevelFor(level logrus.Level, d time.Duration) {
+	temporaryLevel.Lock()
+	defer temporaryLevel.Unlock()
+
+	if !temporaryLevel.active {
+		temporaryLevel.prev = GetLevel()
+		temporaryLevel.active = true
+	}
+	temporaryLevel.generation++
+	generation := temporaryLevel.generation
+
+	vlog.WithField("level", level.String()).WithField("duration", d.String()).Info("setting temporary log level")
+	SetLevel(level)
+
+	time.AfterFunc(d, func() {
+		temporaryLevel.Lock()
+		defer temporaryLevel.Unlock()
+
+		if generation != temporaryLevel.generation {
+			return
+		}
+		temporaryLevel.active = false
+		SetLevel(temporaryLevel.prev)
+		vlog.WithField("level", temporaryLevel.prev.String()).Info("Temporary log level end, restored previous log level")
+	})
+}
@@ -0,0 +1,29 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package log
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLevelFor(t *testing.T) {
+	SetOutput(ioutil.Discard)
+	SetLevel(logrus.InfoLevel)
+	defer SetLevel(logrus.InfoLevel)
+
+	SetLevelFor(logrus.DebugLevel, time.Hour)
+	assert.Equal(t, logrus.DebugLevel, GetLevel())
+
+	// setting it again replaces the duration, restoring the level previous to the first call
+	SetLevelFor(logrus.TraceLevel, 10*time.Millisecond)
+	assert.Equal(t, logrus.TraceLevel, GetLevel())
+
+	assert.Eventually(t, func() bool {
+		return GetLevel() == logrus.InfoLevel
+	}, time.Second, 5*time.Millisecond)
+}
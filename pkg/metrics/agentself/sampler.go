@@ -0,0 +1,187 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package agentself provides a sampler reporting the resource usage of the agent process itself, so the
+// agent overhead can be tracked across the fleet.
+package agentself
+
+import (
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const sampleEventType = "AgentSelfSample"
+
+var aslog = log.WithComponent("AgentSelfSampler")
+
+// AgentSelfSample reports the agent process resource usage. Values that cannot be retrieved on the
+// running platform are omitted.
+type AgentSelfSample struct {
+	sample.BaseEvent
+
+	AgentVersion        string   `json:"agentVersion"`
+	CPUPercent          *float64 `json:"cpuPercent,omitempty"`
+	MemoryResidentBytes *uint64  `json:"memoryResidentBytes,omitempty"`
+	OpenFileDescriptors *int32   `json:"openFileDescriptors,omitempty"`
+	Goroutines          int      `json:"goroutines"`
+	HeapAllocBytes      uint64   `json:"heapAllocBytes"`
+	GCCount             uint32   `json:"gcCount"`
+	GCPauseLastMs       float64  `json:"gcPauseLastMs"`
+	GCPauseTotalMs      float64  `json:"gcPauseTotalMs"`
+	EventQueueSize      *int     `json:"eventQueueSize,omitempty"`
+	EventQueueCapacity  *int     `json:"eventQueueCapacity,omitempty"`
+	BatchQueueSize      *int     `json:"batchQueueSize,omitempty"`
+	BatchQueueCapacity  *int     `json:"batchQueueCapacity,omitempty"`
+	BackendLatencyMs    *float64 `json:"backendLatencyMs,omitempty"`
+	PayloadSplitCount   *uint64  `json:"payloadSplitCount,omitempty"`
+	DroppedEventCount   *uint64  `json:"droppedEventCount,omitempty"`
+}
+
+// SenderStatsFn provides the agent event sender stats, false when not available.
+type SenderStatsFn func() (agent.SenderStats, bool)
+
+// processStats retrieves the agent process resource usage, implemented by gopsutil process.Process.
+type processStats interface {
+	// Percent returns the CPU usage since the previous call when interval is 0.
+	Percent(interval time.Duration) (float64, error)
+	MemoryInfo() (*process.MemoryInfoStat, error)
+	NumFDs() (int32, error)
+}
+
+// Sampler reports an AgentSelfSample per interval.
+type Sampler struct {
+	enabled     bool
+	interval    time.Duration
+	version     string
+	proc        processStats
+	senderStats SenderStatsFn
+}
+
+// NewSampler creates an agent self sampler, enabled through the EnableAgentSelfSample config option.
+func NewSampler(ctx agent.AgentContext, senderStats SenderStatsFn) *Sampler {
+	s := &Sampler{
+		interval:    time.Second * time.Duration(config.FREQ_DISABLE_SAMPLING),
+		senderStats: senderStats,
+	}
+	if ctx == nil {
+		return s
+	}
+
+	cfg := ctx.Config()
+	s.enabled = cfg.EnableAgentSelfSample
+	s.interval = time.Second * time.Duration(cfg.GetMetricsSystemSampleRate())
+	s.version = ctx.Version()
+
+	proc, err := process.NewProcess(int32(os.Getpid()))
+	if err != nil {
+		aslog.WithError(err).Warn("Cannot retrieve agent process, CPU and memory won't be reported.")
+	} else {
+		s.proc = proc
+	}
+
+	return s
+}
+
+// Sample returns the agent process resource usage.
+func (s *Sampler) Sample() (sample.EventBatch, error) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	as := &AgentSelfSample{
+		AgentVersion:   s.version,
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: memStats.HeapAlloc,
+		GCCount:        memStats.NumGC,
+		GCPauseTotalMs: durationMs(time.Duration(memStats.PauseTotalNs)),
+	}
+	if memStats.NumGC > 0 {
+		// PauseNs is a circular buffer, the most recent pause is at (NumGC+255)%256
+		as.GCPauseLastMs = durationMs(time.Duration(memStats.PauseNs[(memStats.NumGC+255)%256]))
+	}
+
+	s.sampleProcess(as)
+	s.sampleSender(as)
+
+	as.Type(sampleEventType)
+
+	return sample.EventBatch{as}, nil
+}
+
+func (s *Sampler) sampleProcess(as *AgentSelfSample) {
+	if s.proc == nil {
+		return
+	}
+
+	if cpuPercent, err := s.proc.Percent(0); err == nil {
+		as.CPUPercent = &cpuPercent
+	} else {
+		aslog.WithError(err).Debug("Cannot retrieve agent CPU usage.")
+	}
+
+	if mem, err := s.proc.MemoryInfo(); err == nil {
+		as.MemoryResidentBytes = &mem.RSS
+	} else {
+		aslog.WithError(err).Debug("Cannot retrieve agent memory usage.")
+	}
+
+	// not implemented for every platform
+	if fds, err := s.proc.NumFDs(); err == nil {
+		as.OpenFileDescriptors = &fds
+	}
+}
+
+func (s *Sampler) sampleSender(as *AgentSelfSample) {
+	if s.senderStats == nil {
+		return
+	}
+
+	stats, ok := s.senderStats()
+	if !ok {
+		return
+	}
+
+	as.EventQueueSize = &stats.EventQueueSize
+	as.EventQueueCapacity = &stats.EventQueueCapacity
+	as.BatchQueueSize = &stats.BatchQueueSize
+	as.BatchQueueCapacity = &stats.BatchQueueCapacity
+	as.PayloadSplitCount = &stats.SplitPayloads
+	as.DroppedEventCount = &stats.DroppedEvents
+	if stats.LastPostLatency > 0 {
+		latency := durationMs(stats.LastPostLatency)
+		as.BackendLatencyMs = &latency
+	}
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// OnStartup primes the CPU usage calculation, as the first reading has no previous one to compare with.
+func (s *Sampler) OnStartup() {
+	if s.proc != nil {
+		_, _ = s.proc.Percent(0)
+	}
+}
+
+// Name returns the sampler name.
+func (s *Sampler) Name() string {
+	return "AgentSelfSampler"
+}
+
+// Interval returns the sampling interval.
+func (s *Sampler) Interval() time.Duration {
+	return s.interval
+}
+
+// Disabled returns true unless the sampler is enabled through configuration.
+func (s *Sampler) Disabled() bool {
+	return !s.enabled || s.Interval() <= config.FREQ_DISABLE_SAMPLING
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package agentself
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+type fakeProcess struct {
+	fdsErr error
+}
+
+func (f *fakeProcess) Percent(time.Duration) (float64, error) {
+	return 1.5, nil
+}
+
+func (f *fakeProcess) MemoryInfo() (*process.MemoryInfoStat, error) {
+	return &process.MemoryInfoStat{RSS: 1024}, nil
+}
+
+func (f *fakeProcess) NumFDs() (int32, error) {
+	return 12, f.fdsErr
+}
+
+func TestSampler_Sample(t *testing.T) {
+	s := &Sampler{
+		enabled:  true,
+		interval: time.Second,
+		version:  "1.2.3",
+		proc:     &fakeProcess{},
+		senderStats: func() (agent.SenderStats, bool) {
+			return agent.SenderStats{
+				EventQueueSize:     10,
+				EventQueueCapacity: 1000,
+				BatchQueueSize:     1,
+				BatchQueueCapacity: 200,
+				LastPostLatency:    250 * time.Millisecond,
+				SplitPayloads:      3,
+				DroppedEvents:      1,
+			}, true
+		},
+	}
+
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	as, ok := batch[0].(*AgentSelfSample)
+	require.True(t, ok)
+
+	assert.Equal(t, sampleEventType, as.EventType)
+	assert.Equal(t, "1.2.3", as.AgentVersion)
+	assert.Equal(t, 1.5, *as.CPUPercent)
+	assert.Equal(t, uint64(1024), *as.MemoryResidentBytes)
+	assert.Equal(t, int32(12), *as.OpenFileDescriptors)
+	assert.Positive(t, as.Goroutines)
+	assert.Positive(t, as.HeapAllocBytes)
+	assert.Equal(t, 10, *as.EventQueueSize)
+	assert.Equal(t, 1000, *as.EventQueueCapacity)
+	assert.Equal(t, 1, *as.BatchQueueSize)
+	assert.Equal(t, 200, *as.BatchQueueCapacity)
+	assert.Equal(t, 250.0, *as.BackendLatencyMs)
+	assert.Equal(t, uint64(3), *as.PayloadSplitCount)
+	assert.Equal(t, uint64(1), *as.DroppedEventCount)
+}
+
+func TestSampler_Sample_Unavailable(t *testing.T) {
+	s := &Sampler{
+		enabled:  true,
+		interval: time.Second,
+		proc:     &fakeProcess{fdsErr: errors.New("not implemented yet")},
+		senderStats: func() (agent.SenderStats, bool) {
+			return agent.SenderStats{}, false
+		},
+	}
+
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	as := batch[0].(*AgentSelfSample)
+	assert.NotNil(t, as.CPUPercent)
+	assert.Nil(t, as.OpenFileDescriptors)
+	assert.Nil(t, as.EventQueueSize)
+	assert.Nil(t, as.BackendLatencyMs)
+}
+
+func TestSampler_Sample_NoPostYet(t *testing.T) {
+	s := &Sampler{
+		senderStats: func() (agent.SenderStats, bool) {
+			return agent.SenderStats{EventQueueCapacity: 1000}, true
+		},
+	}
+
+	batch, err := s.Sample()
+	require.NoError(t, err)
+
+	as := batch[0].(*AgentSelfSample)
+	assert.Nil(t, as.CPUPercent)
+	assert.Equal(t, 1000, *as.EventQueueCapacity)
+	assert.Nil(t, as.BackendLatencyMs)
+}
+
+func TestSampler_Disabled(t *testing.T) {
+	assert.True(t, NewSampler(nil, nil).Disabled())
+	assert.True(t, (&Sampler{enabled: false, interval: time.Second}).Disabled())
+	assert.True(t, (&Sampler{enabled: true, interval: time.Second * config.FREQ_DISABLE_SAMPLING}).Disabled())
+	assert.False(t, (&Sampler{enabled: true, interval: time.Second}).Disabled())
+}
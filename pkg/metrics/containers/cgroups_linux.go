@@ -0,0 +1,351 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package containers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+const (
+	// maxCgroupDepth limits the walk of the cgroup hierarchy looking for containers, Kubernetes nests them
+	// into slices by QoS class and pod.
+	maxCgroupDepth = 6
+	// userHZ is the unit of the cgroup v1 cpuacct.stat counters.
+	userHZ = 100
+	// cgroup v1 reports an unlimited memory as the max page-aligned int64 value.
+	v1MemoryUnlimited = 1 << 62
+
+	valueMax = "max"
+)
+
+var containerIDRegex = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// cgroupReader reads the container stats from the cgroup filesystem, supporting both cgroup v1 and v2
+// hierarchies. Container cgroups are looked up by the container ID found in their directory name, which
+// covers the cgroupfs (ie: /docker/<id>) and systemd (ie: docker-<id>.scope) drivers.
+type cgroupReader struct {
+	root     string
+	procPath func(combineWith ...string) string
+}
+
+func newCgroupReader() *cgroupReader {
+	return &cgroupReader{
+		root:     helpers.HostSys("fs", "cgroup"),
+		procPath: helpers.HostProc,
+	}
+}
+
+func (r *cgroupReader) read(ids []string) (map[string]Stats, error) {
+	v2 := fileExists(filepath.Join(r.root, "cgroup.controllers"))
+	base := r.root
+	if !v2 {
+		base = filepath.Join(r.root, "memory")
+	}
+
+	paths, err := containerPaths(base, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]Stats, len(paths))
+	for id, rel := range paths {
+		var st Stats
+		var procs string
+		if v2 {
+			st, err = r.readV2(filepath.Join(r.root, rel))
+			procs = filepath.Join(r.root, rel, "cgroup.procs")
+		} else {
+			st, err = r.readV1(rel)
+			procs = filepath.Join(base, rel, "cgroup.procs")
+		}
+		if err != nil {
+			cslog.WithError(err).WithField("container", id).Debug("Cannot read container cgroup.")
+			continue
+		}
+		st.Network = r.network(procs)
+		stats[id] = st
+	}
+
+	return stats, nil
+}
+
+// containerPaths returns the path of the cgroup of each container, relative to the base of the hierarchy.
+func containerPaths(base string, ids []string) (map[string]string, error) {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	paths := make(map[string]string, len(ids))
+	err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// cgroups may vanish while walking the hierarchy
+			if path != base {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() || path == base {
+			return nil
+		}
+		rel, _ := filepath.Rel(base, path)
+		if id := containerIDRegex.FindString(d.Name()); id != "" && wanted[id] {
+			paths[id] = rel
+			return filepath.SkipDir
+		}
+		if strings.Count(rel, string(filepath.Separator)) >= maxCgroupDepth-1 {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot walk the cgroup hierarchy: %w", err)
+	}
+
+	return paths, nil
+}
+
+func (r *cgroupReader) readV2(dir string) (Stats, error) {
+	var st Stats
+
+	cpu, err := keyValues(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return st, err
+	}
+	st.CPUUsage = time.Duration(cpu["usage_usec"]) * time.Microsecond
+	st.CPUUser = time.Duration(cpu["user_usec"]) * time.Microsecond
+	st.CPUSystem = time.Duration(cpu["system_usec"]) * time.Microsecond
+	st.ThrottledPeriods = cpu["nr_throttled"]
+	st.ThrottledTime = time.Duration(cpu["throttled_usec"]) * time.Microsecond
+
+	if fields, err := readFields(filepath.Join(dir, "cpu.max")); err == nil && len(fields) == 2 && fields[0] != valueMax {
+		quota, _ := strconv.ParseFloat(fields[0], 64)
+		period, _ := strconv.ParseFloat(fields[1], 64)
+		if period > 0 {
+			st.CPULimitCores = quota / period
+		}
+	}
+
+	if st.MemoryUsage, err = readUint(filepath.Join(dir, "memory.current")); err != nil {
+		return st, err
+	}
+	if fields, err := readFields(filepath.Join(dir, "memory.max")); err == nil && len(fields) == 1 && fields[0] != valueMax {
+		st.MemoryLimit, _ = strconv.ParseUint(fields[0], 10, 64)
+	}
+	if mem, err := keyValues(filepath.Join(dir, "memory.stat")); err == nil {
+		st.MemoryRSS = mem["anon"]
+		st.MemoryCache = mem["file"]
+	}
+
+	// io.stat lines look like: 8:0 rbytes=1 wbytes=2 rios=3 wios=4 dbytes=0 dios=0
+	if lines, err := readLines(filepath.Join(dir, "io.stat")); err == nil {
+		for _, line := range lines {
+			for _, field := range strings.Fields(line)[1:] {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					continue
+				}
+				n, _ := strconv.ParseUint(value, 10, 64)
+				switch key {
+				case "rbytes":
+					st.IOReadBytes += n
+				case "wbytes":
+					st.IOWriteBytes += n
+				case "rios":
+					st.IOReadCount += n
+				case "wios":
+					st.IOWriteCount += n
+				}
+			}
+		}
+	}
+
+	st.Pids, _ = readUint(filepath.Join(dir, "pids.current"))
+
+	return st, nil
+}
+
+func (r *cgroupReader) readV1(rel string) (Stats, error) {
+	var st Stats
+	controller := func(name, file string) string {
+		return filepath.Join(r.root, name, rel, file)
+	}
+
+	usage, err := readUint(controller("cpuacct", "cpuacct.usage"))
+	if err != nil {
+		return st, err
+	}
+	st.CPUUsage = time.Duration(usage)
+	if cpuacct, err := keyValues(controller("cpuacct", "cpuacct.stat")); err == nil {
+		st.CPUUser = time.Duration(cpuacct["user"]) * time.Second / userHZ
+		st.CPUSystem = time.Duration(cpuacct["system"]) * time.Second / userHZ
+	}
+	if cpu, err := keyValues(controller("cpu", "cpu.stat")); err == nil {
+		st.ThrottledPeriods = cpu["nr_throttled"]
+		st.ThrottledTime = time.Duration(cpu["throttled_time"])
+	}
+	if fields, err := readFields(controller("cpu", "cpu.cfs_quota_us")); err == nil && len(fields) == 1 {
+		quota, _ := strconv.ParseFloat(fields[0], 64)
+		period, _ := readUint(controller("cpu", "cpu.cfs_period_us"))
+		// the quota is -1 when unlimited
+		if quota > 0 && period > 0 {
+			st.CPULimitCores = quota / float64(period)
+		}
+	}
+
+	if st.MemoryUsage, err = readUint(controller("memory", "memory.usage_in_bytes")); err != nil {
+		return st, err
+	}
+	if limit, err := readUint(controller("memory", "memory.limit_in_bytes")); err == nil && limit < v1MemoryUnlimited {
+		st.MemoryLimit = limit
+	}
+	if mem, err := keyValues(controller("memory", "memory.stat")); err == nil {
+		st.MemoryRSS = mem["rss"]
+		st.MemoryCache = mem["cache"]
+	}
+
+	// blkio lines look like: 8:0 Read 4096, with a Total line at the end
+	st.IOReadBytes, st.IOWriteBytes = blkioReadWrite(controller("blkio", "blkio.throttle.io_service_bytes"))
+	st.IOReadCount, st.IOWriteCount = blkioReadWrite(controller("blkio", "blkio.throttle.io_serviced"))
+
+	st.Pids, _ = readUint(controller("pids", "pids.current"))
+
+	return st, nil
+}
+
+func blkioReadWrite(path string) (read, write uint64) {
+	lines, err := readLines(path)
+	if err != nil {
+		return 0, 0
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		n, _ := strconv.ParseUint(fields[2], 10, 64)
+		switch fields[1] {
+		case "Read":
+			read += n
+		case "Write":
+			write += n
+		}
+	}
+	return read, write
+}
+
+// network returns the counters of the network namespace of the first process of the container, nil when they
+// cannot be read.
+func (r *cgroupReader) network(procsPath string) *NetworkStats {
+	procs, err := readLines(procsPath)
+	if err != nil || len(procs) == 0 {
+		return nil
+	}
+	lines, err := readLines(r.procPath(strings.TrimSpace(procs[0]), "net", "dev"))
+	if err != nil {
+		cslog.WithError(err).Debug("Cannot read container network counters.")
+		return nil
+	}
+
+	// the two first lines are headers, each interface line looks like:
+	// eth0: rxBytes rxPackets rxErrs rxDrop fifo frame compressed multicast txBytes txPackets txErrs txDrop ...
+	var n NetworkStats
+	if len(lines) < 2 {
+		return &n
+	}
+	for _, line := range lines[2:] {
+		iface, counters, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(iface) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 12 {
+			continue
+		}
+		v := make([]uint64, 12)
+		for i := range v {
+			v[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		n.RxBytes += v[0]
+		n.RxPackets += v[1]
+		n.RxErrors += v[2]
+		n.RxDropped += v[3]
+		n.TxBytes += v[8]
+		n.TxPackets += v[9]
+		n.TxErrors += v[10]
+		n.TxDropped += v[11]
+	}
+	return &n
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func readFields(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(content)), nil
+}
+
+func readUint(path string) (uint64, error) {
+	fields, err := readFields(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) != 1 {
+		return 0, errors.New("unexpected content in " + path)
+	}
+	return strconv.ParseUint(fields[0], 10, 64)
+}
+
+// keyValues parses files made of "key value" lines, like cpu.stat or memory.stat.
+func keyValues(path string) (map[string]uint64, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if n, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = n
+		}
+	}
+	return values, nil
+}
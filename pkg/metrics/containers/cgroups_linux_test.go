@@ -0,0 +1,118 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package containers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func testReader(t *testing.T, files map[string]string) *cgroupReader {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, files)
+	return &cgroupReader{
+		root: filepath.Join(root, "cgroup"),
+		procPath: func(combineWith ...string) string {
+			return filepath.Join(append([]string{root, "proc"}, combineWith...)...)
+		},
+	}
+}
+
+const netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:     100       1    0    0    0     0          0         0      100       1    0    0    0     0       0          0
+  eth0:    2000      20    1    2    0     0          0         0     3000      30    3    4    0     0       0          0
+`
+
+func TestCgroupReader_V2(t *testing.T) {
+	dir := "cgroup/system.slice/docker-" + containerID + ".scope/"
+	r := testReader(t, map[string]string{
+		"cgroup/cgroup.controllers": "cpu io memory pids",
+		dir + "cpu.stat":            "usage_usec 2000000\nuser_usec 1500000\nsystem_usec 500000\nnr_periods 10\nnr_throttled 2\nthrottled_usec 3000",
+		dir + "cpu.max":             "50000 100000",
+		dir + "memory.current":      "1000",
+		dir + "memory.max":          "max",
+		dir + "memory.stat":         "anon 600\nfile 300\nkernel 100",
+		dir + "io.stat":             "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=10 wbytes=20 rios=3 wios=4 dbytes=0 dios=0",
+		dir + "pids.current":        "2",
+		dir + "cgroup.procs":        "42\n43",
+		"proc/42/net/dev":           netDev,
+	})
+
+	stats, err := r.read([]string{containerID, "unknown"})
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+
+	st := stats[containerID]
+	assert.Equal(t, 2*time.Second, st.CPUUsage)
+	assert.Equal(t, 1500*time.Millisecond, st.CPUUser)
+	assert.Equal(t, 500*time.Millisecond, st.CPUSystem)
+	assert.Equal(t, 0.5, st.CPULimitCores)
+	assert.Equal(t, uint64(2), st.ThrottledPeriods)
+	assert.Equal(t, 3*time.Millisecond, st.ThrottledTime)
+	assert.Equal(t, uint64(1000), st.MemoryUsage)
+	assert.Equal(t, uint64(0), st.MemoryLimit)
+	assert.Equal(t, uint64(600), st.MemoryRSS)
+	assert.Equal(t, uint64(300), st.MemoryCache)
+	assert.Equal(t, uint64(110), st.IOReadBytes)
+	assert.Equal(t, uint64(220), st.IOWriteBytes)
+	assert.Equal(t, uint64(4), st.IOReadCount)
+	assert.Equal(t, uint64(6), st.IOWriteCount)
+	assert.Equal(t, uint64(2), st.Pids)
+	assert.Equal(t, &NetworkStats{
+		RxBytes: 2000, RxPackets: 20, RxErrors: 1, RxDropped: 2,
+		TxBytes: 3000, TxPackets: 30, TxErrors: 3, TxDropped: 4,
+	}, st.Network)
+}
+
+func TestCgroupReader_V1(t *testing.T) {
+	rel := "docker/" + containerID + "/"
+	r := testReader(t, map[string]string{
+		"cgroup/cpuacct/" + rel + "cpuacct.usage":                 "2000000000",
+		"cgroup/cpuacct/" + rel + "cpuacct.stat":                  "user 150\nsystem 50",
+		"cgroup/cpu/" + rel + "cpu.stat":                          "nr_periods 10\nnr_throttled 2\nthrottled_time 3000000",
+		"cgroup/cpu/" + rel + "cpu.cfs_quota_us":                  "-1",
+		"cgroup/cpu/" + rel + "cpu.cfs_period_us":                 "100000",
+		"cgroup/memory/" + rel + "memory.usage_in_bytes":          "1000",
+		"cgroup/memory/" + rel + "memory.limit_in_bytes":          "4000",
+		"cgroup/memory/" + rel + "memory.stat":                    "cache 300\nrss 600\ntotal_rss 600",
+		"cgroup/memory/" + rel + "cgroup.procs":                   "",
+		"cgroup/blkio/" + rel + "blkio.throttle.io_service_bytes": "8:0 Read 100\n8:0 Write 200\n8:0 Total 300\nTotal 300",
+		"cgroup/blkio/" + rel + "blkio.throttle.io_serviced":      "8:0 Read 1\n8:0 Write 2\n8:0 Total 3\nTotal 3",
+	})
+
+	stats, err := r.read([]string{containerID})
+	require.NoError(t, err)
+
+	st := stats[containerID]
+	assert.Equal(t, 2*time.Second, st.CPUUsage)
+	assert.Equal(t, 1500*time.Millisecond, st.CPUUser)
+	assert.Equal(t, 500*time.Millisecond, st.CPUSystem)
+	assert.Equal(t, 0.0, st.CPULimitCores)
+	assert.Equal(t, 3*time.Millisecond, st.ThrottledTime)
+	assert.Equal(t, uint64(4000), st.MemoryLimit)
+	assert.Equal(t, uint64(600), st.MemoryRSS)
+	assert.Equal(t, uint64(300), st.MemoryCache)
+	assert.Equal(t, uint64(100), st.IOReadBytes)
+	assert.Equal(t, uint64(200), st.IOWriteBytes)
+	assert.Equal(t, uint64(1), st.IOReadCount)
+	assert.Equal(t, uint64(2), st.IOWriteCount)
+	// no processes to read the network namespace from
+	assert.Nil(t, st.Network)
+}
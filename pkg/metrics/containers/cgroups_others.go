@@ -0,0 +1,18 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build !linux
+// +build !linux
+
+package containers
+
+import "errors"
+
+type cgroupReader struct{}
+
+func newCgroupReader() *cgroupReader {
+	return &cgroupReader{}
+}
+
+func (r *cgroupReader) read(_ []string) (map[string]Stats, error) {
+	return nil, errors.New("container cgroups are only supported on linux")
+}
@@ -0,0 +1,162 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package containers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+const (
+	runtimeDocker     = "docker"
+	runtimeContainerd = "containerd"
+
+	// runtimeRetries is the number of samples the runtime clients are retried to be initialized, as the
+	// runtimes may start after the agent.
+	runtimeRetries = 100
+)
+
+// Container is a running container as reported by its runtime.
+type Container struct {
+	ID      string
+	Name    string
+	Image   string
+	ImageID string
+	State   string
+	Runtime string
+	Labels  map[string]string
+}
+
+// runtimeLister lists the containers of the Docker and containerd runtimes available in the host. Containers
+// managed by Docker are skipped from the containerd namespace Docker uses, as they're already reported by Docker.
+type runtimeLister struct {
+	apiVersion        string
+	dockerNamespace   string
+	docker            helpers.Docker
+	dockerRetries     int
+	containerd        helpers.ContainerdInterface
+	containerdRetries int
+}
+
+func newRuntimeLister(apiVersion, dockerNamespace string) *runtimeLister {
+	return &runtimeLister{
+		apiVersion:      apiVersion,
+		dockerNamespace: dockerNamespace,
+	}
+}
+
+func (l *runtimeLister) list() ([]Container, error) {
+	var containers []Container
+	seen := make(map[string]bool)
+
+	if l.dockerClient() != nil {
+		dockerContainers, err := l.docker.Containers()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range dockerContainers {
+			name := c.ID
+			if len(c.Names) > 0 {
+				name = strings.TrimPrefix(c.Names[0], "/")
+			}
+			seen[c.ID] = true
+			containers = append(containers, Container{
+				ID:      c.ID,
+				Name:    name,
+				Image:   c.Image,
+				ImageID: c.ImageID,
+				State:   c.State,
+				Runtime: runtimeDocker,
+				Labels:  c.Labels,
+			})
+		}
+	}
+
+	if l.containerdClient() != nil {
+		perNamespace, err := l.containerd.Containers()
+		if err != nil {
+			return nil, err
+		}
+		for ns, nsContainers := range perNamespace {
+			if ns == l.dockerNamespace {
+				continue
+			}
+			for _, c := range nsContainers {
+				if seen[c.ID()] {
+					continue
+				}
+				ctx := namespaces.WithNamespace(context.Background(), ns)
+				task, err := c.Task(ctx, nil)
+				if err != nil {
+					// no task means there is no running instance of the container
+					if !errdefs.IsNotFound(err) {
+						cslog.WithError(err).WithField("container", c.ID()).Debug("Cannot get container task.")
+					}
+					continue
+				}
+				status, err := task.Status(ctx)
+				if err != nil {
+					cslog.WithError(err).WithField("container", c.ID()).Debug("Cannot get container task status.")
+					continue
+				}
+				info, err := helpers.GetContainerdInfo(helpers.ContainerdMetadata{Container: c, Namespace: ns})
+				if err != nil {
+					cslog.WithError(err).WithField("container", c.ID()).Debug("Cannot get container info.")
+				}
+				seen[c.ID()] = true
+				containers = append(containers, Container{
+					ID: c.ID(),
+					// containerd does not distinguish container name and container ID
+					Name:    c.ID(),
+					Image:   info.ImageName,
+					ImageID: info.ImageID,
+					State:   string(status.Status),
+					Runtime: runtimeContainerd,
+					Labels:  info.Labels,
+				})
+			}
+		}
+	}
+
+	return containers, nil
+}
+
+// dockerClient returns the Docker client, nil while Docker is not available.
+func (l *runtimeLister) dockerClient() helpers.Docker { //nolint:ireturn
+	if l.docker != nil || l.dockerRetries > runtimeRetries {
+		return l.docker
+	}
+	l.dockerRetries++
+
+	client := &helpers.DockerClient{}
+	if err := client.Initialize(l.apiVersion); err != nil {
+		cslog.WithError(err).Debug("Unable to initialize docker client.")
+		return nil
+	}
+	l.docker = client
+
+	return l.docker
+}
+
+// containerdClient returns the containerd client, nil while containerd is not available.
+func (l *runtimeLister) containerdClient() helpers.ContainerdInterface { //nolint:ireturn
+	if l.containerd != nil || l.containerdRetries > runtimeRetries {
+		return l.containerd
+	}
+	l.containerdRetries++
+
+	client := &helpers.ContainerdClient{}
+	if err := client.Initialize(); err != nil {
+		cslog.WithError(err).Debug("Unable to initialize containerd client.")
+		return nil
+	}
+	l.containerd = client
+
+	return l.containerd
+}
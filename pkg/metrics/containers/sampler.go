@@ -0,0 +1,313 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package containers provides a sampler reporting the resource usage of the Docker and containerd containers
+// running in the host, read directly from their cgroups.
+package containers
+
+import (
+	"encoding/json"
+	"runtime"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const (
+	sampleEventType = "ContainerSample"
+
+	labelAttrPrefix = "label."
+)
+
+var cslog = log.WithComponent("ContainerSampler")
+
+// NetworkStats are the counters of the container network interfaces, loopback excluded.
+type NetworkStats struct {
+	RxBytes   uint64
+	TxBytes   uint64
+	RxPackets uint64
+	TxPackets uint64
+	RxErrors  uint64
+	TxErrors  uint64
+	RxDropped uint64
+	TxDropped uint64
+}
+
+// Stats are the resource accounting counters of a container cgroup. Limits are zero when unlimited.
+type Stats struct {
+	CPUUsage         time.Duration
+	CPUUser          time.Duration
+	CPUSystem        time.Duration
+	CPULimitCores    float64
+	ThrottledPeriods uint64
+	ThrottledTime    time.Duration
+
+	MemoryUsage uint64
+	MemoryRSS   uint64
+	MemoryCache uint64
+	MemoryLimit uint64
+
+	IOReadBytes  uint64
+	IOWriteBytes uint64
+	IOReadCount  uint64
+	IOWriteCount uint64
+
+	Pids uint64
+
+	Network *NetworkStats
+}
+
+// ContainerSample reports the resource usage of a single container. Rates are only reported from the second
+// sample of the container on.
+type ContainerSample struct {
+	sample.BaseEvent
+
+	ContainerID string            `json:"containerId"`
+	Name        string            `json:"name"`
+	Image       string            `json:"image"`
+	ImageID     string            `json:"imageId"`
+	State       string            `json:"state"`
+	Runtime     string            `json:"runtime"`
+	Labels      map[string]string `json:"-"`
+
+	CPUPercent             *float64 `json:"cpuPercent,omitempty"`
+	CPUUserPercent         *float64 `json:"cpuUserPercent,omitempty"`
+	CPUKernelPercent       *float64 `json:"cpuKernelPercent,omitempty"`
+	CPUUsedCores           *float64 `json:"cpuUsedCores,omitempty"`
+	CPULimitCores          *float64 `json:"cpuLimitCores,omitempty"`
+	CPUUsedCoresPercent    *float64 `json:"cpuUsedCoresPercent,omitempty"`
+	CPUThrottlePeriods     *uint64  `json:"cpuThrottlePeriods,omitempty"`
+	CPUThrottleTimeMs      *float64 `json:"cpuThrottleTimeMs,omitempty"`
+	MemoryUsageBytes       uint64   `json:"memoryUsageBytes"`
+	MemoryResidentBytes    uint64   `json:"memoryResidentSizeBytes"`
+	MemoryCacheBytes       uint64   `json:"memoryCacheBytes"`
+	MemorySizeLimitBytes   *uint64  `json:"memorySizeLimitBytes,omitempty"`
+	MemoryUsageLimitPct    *float64 `json:"memoryUsageLimitPercent,omitempty"`
+	IOTotalReadBytes       uint64   `json:"ioTotalReadBytes"`
+	IOTotalWriteBytes      uint64   `json:"ioTotalWriteBytes"`
+	IOReadBytesPerSecond   *float64 `json:"ioReadBytesPerSecond,omitempty"`
+	IOWriteBytesPerSecond  *float64 `json:"ioWriteBytesPerSecond,omitempty"`
+	IOReadCountPerSecond   *float64 `json:"ioReadCountPerSecond,omitempty"`
+	IOWriteCountPerSecond  *float64 `json:"ioWriteCountPerSecond,omitempty"`
+	ProcessCount           *uint64  `json:"processCount,omitempty"`
+	NetworkRxBytesPerSec   *float64 `json:"networkRxBytesPerSecond,omitempty"`
+	NetworkTxBytesPerSec   *float64 `json:"networkTxBytesPerSecond,omitempty"`
+	NetworkRxPacketsPerSec *float64 `json:"networkRxPacketsPerSecond,omitempty"`
+	NetworkTxPacketsPerSec *float64 `json:"networkTxPacketsPerSecond,omitempty"`
+	NetworkRxErrorsPerSec  *float64 `json:"networkRxErrorsPerSecond,omitempty"`
+	NetworkTxErrorsPerSec  *float64 `json:"networkTxErrorsPerSecond,omitempty"`
+	NetworkRxDroppedPerSec *float64 `json:"networkRxDroppedPerSecond,omitempty"`
+	NetworkTxDroppedPerSec *float64 `json:"networkTxDroppedPerSecond,omitempty"`
+}
+
+// MarshalJSON flattens the container labels as label.<name> attributes.
+func (cs *ContainerSample) MarshalJSON() ([]byte, error) {
+	type plain ContainerSample
+	blob, err := json.Marshal((*plain)(cs))
+	if err != nil || len(cs.Labels) == 0 {
+		return blob, err
+	}
+
+	flat := make(map[string]interface{})
+	if err = json.Unmarshal(blob, &flat); err != nil {
+		return nil, err
+	}
+	for name, value := range cs.Labels {
+		flat[labelAttrPrefix+name] = value
+	}
+
+	return json.Marshal(flat)
+}
+
+type snapshot struct {
+	stats Stats
+	time  time.Time
+}
+
+// Sampler reports a ContainerSample per running Docker or containerd container, without requiring cAdvisor
+// nor the Docker integration.
+type Sampler struct {
+	interval time.Duration
+	numCPU   int
+	listFn   func() ([]Container, error)
+	statsFn  func(ids []string) (map[string]Stats, error)
+	now      func() time.Time
+	previous map[string]snapshot
+}
+
+// NewSampler creates a container sampler.
+func NewSampler(ctx agent.AgentContext) *Sampler {
+	interval := config.FREQ_DISABLE_SAMPLING
+	var apiVersion, dockerNamespace string
+	if ctx != nil {
+		interval = ctx.Config().MetricsContainerSampleRate
+		apiVersion = ctx.Config().DockerApiVersion
+		dockerNamespace = ctx.Config().DockerContainerdNamespace
+	}
+
+	return newSampler(
+		time.Second*time.Duration(interval),
+		newRuntimeLister(apiVersion, dockerNamespace).list,
+		newCgroupReader().read,
+	)
+}
+
+func newSampler(interval time.Duration, listFn func() ([]Container, error), statsFn func([]string) (map[string]Stats, error)) *Sampler {
+	return &Sampler{
+		interval: interval,
+		numCPU:   runtime.NumCPU(),
+		listFn:   listFn,
+		statsFn:  statsFn,
+		now:      time.Now,
+		previous: make(map[string]snapshot),
+	}
+}
+
+// Sample returns the resource usage of the running containers.
+func (s *Sampler) Sample() (sample.EventBatch, error) {
+	containers, err := s.listFn()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(containers))
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+	stats, err := s.statsFn(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	current := make(map[string]snapshot, len(stats))
+	var batch sample.EventBatch
+	for _, c := range containers {
+		st, ok := stats[c.ID]
+		if !ok {
+			cslog.WithField("container", c.ID).Debug("Container cgroup not found.")
+			continue
+		}
+		current[c.ID] = snapshot{stats: st, time: now}
+
+		var prev *snapshot
+		if p, ok := s.previous[c.ID]; ok {
+			prev = &p
+		}
+		batch = append(batch, s.containerSample(c, st, prev, now))
+	}
+	// containers gone are forgotten
+	s.previous = current
+
+	return batch, nil
+}
+
+func (s *Sampler) containerSample(c Container, st Stats, prev *snapshot, now time.Time) *ContainerSample {
+	cs := &ContainerSample{
+		ContainerID:         c.ID,
+		Name:                c.Name,
+		Image:               c.Image,
+		ImageID:             c.ImageID,
+		State:               c.State,
+		Runtime:             c.Runtime,
+		Labels:              c.Labels,
+		MemoryUsageBytes:    st.MemoryUsage,
+		MemoryResidentBytes: st.MemoryRSS,
+		MemoryCacheBytes:    st.MemoryCache,
+		IOTotalReadBytes:    st.IOReadBytes,
+		IOTotalWriteBytes:   st.IOWriteBytes,
+	}
+	cs.Type(sampleEventType)
+
+	if st.CPULimitCores > 0 {
+		cs.CPULimitCores = floatPtr(st.CPULimitCores)
+	}
+	if st.MemoryLimit > 0 {
+		limit := st.MemoryLimit
+		cs.MemorySizeLimitBytes = &limit
+		cs.MemoryUsageLimitPct = floatPtr(float64(st.MemoryUsage) / float64(st.MemoryLimit) * 100)
+	}
+	if st.Pids > 0 {
+		pids := st.Pids
+		cs.ProcessCount = &pids
+	}
+
+	if prev == nil {
+		return cs
+	}
+	elapsed := now.Sub(prev.time).Seconds()
+	if elapsed <= 0 {
+		return cs
+	}
+	p := prev.stats
+
+	usedCores := delta(uint64(st.CPUUsage), uint64(p.CPUUsage)) / float64(time.Second) / elapsed
+	cs.CPUUsedCores = floatPtr(usedCores)
+	cs.CPUPercent = floatPtr(usedCores / float64(s.numCPU) * 100)
+	cs.CPUUserPercent = floatPtr(delta(uint64(st.CPUUser), uint64(p.CPUUser)) / float64(time.Second) / elapsed / float64(s.numCPU) * 100)
+	cs.CPUKernelPercent = floatPtr(delta(uint64(st.CPUSystem), uint64(p.CPUSystem)) / float64(time.Second) / elapsed / float64(s.numCPU) * 100)
+	if st.CPULimitCores > 0 {
+		cs.CPUUsedCoresPercent = floatPtr(usedCores / st.CPULimitCores * 100)
+	}
+	periods := uint64(delta(st.ThrottledPeriods, p.ThrottledPeriods))
+	cs.CPUThrottlePeriods = &periods
+	cs.CPUThrottleTimeMs = floatPtr(delta(uint64(st.ThrottledTime), uint64(p.ThrottledTime)) / float64(time.Millisecond))
+
+	cs.IOReadBytesPerSecond = rate(st.IOReadBytes, p.IOReadBytes, elapsed)
+	cs.IOWriteBytesPerSecond = rate(st.IOWriteBytes, p.IOWriteBytes, elapsed)
+	cs.IOReadCountPerSecond = rate(st.IOReadCount, p.IOReadCount, elapsed)
+	cs.IOWriteCountPerSecond = rate(st.IOWriteCount, p.IOWriteCount, elapsed)
+
+	if st.Network != nil && p.Network != nil {
+		n, pn := st.Network, p.Network
+		cs.NetworkRxBytesPerSec = rate(n.RxBytes, pn.RxBytes, elapsed)
+		cs.NetworkTxBytesPerSec = rate(n.TxBytes, pn.TxBytes, elapsed)
+		cs.NetworkRxPacketsPerSec = rate(n.RxPackets, pn.RxPackets, elapsed)
+		cs.NetworkTxPacketsPerSec = rate(n.TxPackets, pn.TxPackets, elapsed)
+		cs.NetworkRxErrorsPerSec = rate(n.RxErrors, pn.RxErrors, elapsed)
+		cs.NetworkTxErrorsPerSec = rate(n.TxErrors, pn.TxErrors, elapsed)
+		cs.NetworkRxDroppedPerSec = rate(n.RxDropped, pn.RxDropped, elapsed)
+		cs.NetworkTxDroppedPerSec = rate(n.TxDropped, pn.TxDropped, elapsed)
+	}
+
+	return cs
+}
+
+// delta returns the increase of a counter, zero when it was reset.
+func delta(current, previous uint64) float64 {
+	if current < previous {
+		return 0
+	}
+	return float64(current - previous)
+}
+
+func rate(current, previous uint64, elapsedSecs float64) *float64 {
+	return floatPtr(delta(current, previous) / elapsedSecs)
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+// OnStartup logs the sampler start.
+func (s *Sampler) OnStartup() {
+	cslog.Debug("Starting container sampler.")
+}
+
+// Name returns the sampler name.
+func (s *Sampler) Name() string {
+	return "ContainerSampler"
+}
+
+// Interval returns the sampling interval.
+func (s *Sampler) Interval() time.Duration {
+	return s.interval
+}
+
+// Disabled returns true when sampling is disabled.
+func (s *Sampler) Disabled() bool {
+	return s.Interval() <= config.FREQ_DISABLE_SAMPLING
+}
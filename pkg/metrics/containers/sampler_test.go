@@ -0,0 +1,131 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package containers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const containerID = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+type fakeHost struct {
+	containers []Container
+	stats      map[string]Stats
+	ids        []string
+}
+
+func (f *fakeHost) list() ([]Container, error) {
+	return f.containers, nil
+}
+
+func (f *fakeHost) read(ids []string) (map[string]Stats, error) {
+	f.ids = ids
+	return f.stats, nil
+}
+
+func TestSampler_Sample(t *testing.T) {
+	host := &fakeHost{
+		containers: []Container{
+			{ID: containerID, Name: "nginx", Image: "nginx:latest", ImageID: "sha256:abc", State: "running",
+				Runtime: runtimeDocker, Labels: map[string]string{"team": "web"}},
+			// containers without a cgroup are skipped
+			{ID: "gone", Name: "gone", Runtime: runtimeDocker},
+		},
+		stats: map[string]Stats{containerID: {
+			CPUUsage:      2 * time.Second,
+			CPULimitCores: 0.5,
+			MemoryUsage:   100,
+			MemoryRSS:     60,
+			MemoryCache:   40,
+			MemoryLimit:   400,
+			IOReadBytes:   1000,
+			Pids:          3,
+			Network:       &NetworkStats{RxBytes: 500},
+		}},
+	}
+	s := newSampler(15*time.Second, host.list, host.read)
+	s.numCPU = 2
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Equal(t, []string{containerID, "gone"}, host.ids)
+
+	cs := batch[0].(*ContainerSample)
+	assert.Equal(t, sampleEventType, cs.EventType)
+	assert.Equal(t, "nginx", cs.Name)
+	assert.Equal(t, runtimeDocker, cs.Runtime)
+	assert.Equal(t, uint64(100), cs.MemoryUsageBytes)
+	assert.Equal(t, 25.0, *cs.MemoryUsageLimitPct)
+	assert.Equal(t, 0.5, *cs.CPULimitCores)
+	assert.Equal(t, uint64(3), *cs.ProcessCount)
+	// rates need a previous sample
+	assert.Nil(t, cs.CPUPercent)
+	assert.Nil(t, cs.IOReadBytesPerSecond)
+	assert.Nil(t, cs.NetworkRxBytesPerSec)
+
+	now = now.Add(10 * time.Second)
+	host.stats = map[string]Stats{containerID: {
+		CPUUsage:         7 * time.Second,
+		CPULimitCores:    0.5,
+		ThrottledPeriods: 4,
+		ThrottledTime:    20 * time.Millisecond,
+		IOReadBytes:      3000,
+		Network:          &NetworkStats{RxBytes: 1500},
+	}}
+
+	batch, err = s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	cs = batch[0].(*ContainerSample)
+	assert.Equal(t, 0.5, *cs.CPUUsedCores)
+	assert.Equal(t, 25.0, *cs.CPUPercent)
+	assert.Equal(t, 100.0, *cs.CPUUsedCoresPercent)
+	assert.Equal(t, uint64(4), *cs.CPUThrottlePeriods)
+	assert.Equal(t, 20.0, *cs.CPUThrottleTimeMs)
+	assert.Equal(t, 200.0, *cs.IOReadBytesPerSecond)
+	assert.Equal(t, 100.0, *cs.NetworkRxBytesPerSec)
+}
+
+func TestSampler_CounterReset(t *testing.T) {
+	host := &fakeHost{
+		containers: []Container{{ID: containerID}},
+		stats:      map[string]Stats{containerID: {IOWriteBytes: 1000}},
+	}
+	s := newSampler(15*time.Second, host.list, host.read)
+	_, err := s.Sample()
+	require.NoError(t, err)
+
+	host.stats = map[string]Stats{containerID: {IOWriteBytes: 10}}
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, *batch[0].(*ContainerSample).IOWriteBytesPerSecond)
+}
+
+func TestContainerSample_MarshalJSON(t *testing.T) {
+	cs := &ContainerSample{ContainerID: containerID, Labels: map[string]string{"team": "web"}}
+	cs.Type(sampleEventType)
+
+	blob, err := json.Marshal(cs)
+	require.NoError(t, err)
+
+	var attrs map[string]interface{}
+	require.NoError(t, json.Unmarshal(blob, &attrs))
+	assert.Equal(t, sampleEventType, attrs["eventType"])
+	assert.Equal(t, containerID, attrs["containerId"])
+	assert.Equal(t, "web", attrs["label.team"])
+	assert.NotContains(t, attrs, "Labels")
+}
+
+func TestSampler_Disabled(t *testing.T) {
+	assert.True(t, NewSampler(nil).Disabled())
+	assert.False(t, newSampler(15*time.Second, nil, nil).Disabled())
+}
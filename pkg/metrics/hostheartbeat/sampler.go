@@ -0,0 +1,144 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package hostheartbeat provides a sampler reporting a lightweight HostHeartbeat event on its own interval, so the
+// hosts not reporting can be detected sooner than with the heavier SystemSample interval.
+package hostheartbeat
+
+import (
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/agent/maintenance"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/runner"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/agentself"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const eventType = "HostHeartbeat"
+
+// HostHeartbeat carries the agent identity and its health flags.
+type HostHeartbeat struct {
+	sample.BaseEvent
+
+	AgentVersion string `json:"agentVersion"`
+	FullHostname string `json:"fullHostname,omitempty"`
+	EntityID     int64  `json:"entityId,omitempty"`
+	EntityGUID   string `json:"entityGuid,omitempty"`
+	// Sequence increases with every heartbeat since the agent started, so missed heartbeats can be told apart
+	// from agent restarts.
+	Sequence       uint64 `json:"sequence"`
+	AgentUptimeSec int64  `json:"agentUptimeSec"`
+
+	Healthy                  bool `json:"healthy"`
+	BackendFailing           bool `json:"backendFailing"`
+	EventQueueFull           bool `json:"eventQueueFull"`
+	IntegrationsCrashLooping bool `json:"integrationsCrashLooping"`
+	InMaintenance            bool `json:"inMaintenance"`
+}
+
+// identityProvider is implemented by the agent context, providing its identity without waiting for it.
+type identityProvider interface {
+	AgentIdnOrEmpty() entity.Identity
+}
+
+// maintenanceProvider is implemented by the agent context.
+type maintenanceProvider interface {
+	Maintenance() *maintenance.Window
+}
+
+// Sampler reports a HostHeartbeat per interval.
+type Sampler struct {
+	interval time.Duration
+	version  string
+	started  time.Time
+	sequence uint64
+
+	identity           func() entity.Identity
+	hostname           func() string
+	maintenance        func() bool
+	senderStats        agentself.SenderStatsFn
+	backendReports     func() []backendhttp.BackendReport
+	integrationReports func() []runner.IntegrationReport
+}
+
+// NewSampler creates a host heartbeat sampler, disabled when the host_heartbeat_interval_sec option is 0.
+func NewSampler(ctx agent.AgentContext, senderStats agentself.SenderStatsFn) *Sampler {
+	s := &Sampler{
+		started:            time.Now(),
+		identity:           func() entity.Identity { return entity.EmptyIdentity },
+		hostname:           func() string { return "" },
+		maintenance:        func() bool { return false },
+		senderStats:        senderStats,
+		backendReports:     backendhttp.BackendReports,
+		integrationReports: runner.IntegrationReports,
+	}
+	if ctx == nil {
+		return s
+	}
+
+	s.interval = time.Second * time.Duration(ctx.Config().HostHeartbeatIntervalSec)
+	s.version = ctx.Version()
+	if p, ok := ctx.(identityProvider); ok {
+		s.identity = p.AgentIdnOrEmpty
+	}
+	if p, ok := ctx.(maintenanceProvider); ok {
+		s.maintenance = func() bool { return p.Maintenance().Status().Active }
+	}
+	if resolver := ctx.HostnameResolver(); resolver != nil {
+		s.hostname = resolver.Long
+	}
+	return s
+}
+
+// Sample returns the heartbeat.
+func (s *Sampler) Sample() (sample.EventBatch, error) {
+	s.sequence++
+	hb := &HostHeartbeat{
+		AgentVersion:   s.version,
+		FullHostname:   s.hostname(),
+		Sequence:       s.sequence,
+		AgentUptimeSec: int64(time.Since(s.started) / time.Second),
+		InMaintenance:  s.maintenance(),
+	}
+	if idn := s.identity(); !idn.ID.IsEmpty() {
+		hb.EntityID = int64(idn.ID)
+		hb.EntityGUID = string(idn.GUID)
+	}
+
+	for _, b := range s.backendReports() {
+		hb.BackendFailing = hb.BackendFailing || b.Failing
+	}
+	for _, i := range s.integrationReports() {
+		hb.IntegrationsCrashLooping = hb.IntegrationsCrashLooping || i.CrashLoopSince != nil
+	}
+	if s.senderStats != nil {
+		if stats, ok := s.senderStats(); ok {
+			hb.EventQueueFull = stats.EventQueueCapacity > 0 && stats.EventQueueSize >= stats.EventQueueCapacity
+		}
+	}
+	hb.Healthy = !hb.BackendFailing && !hb.EventQueueFull && !hb.IntegrationsCrashLooping
+
+	hb.Type(eventType)
+	return sample.EventBatch{hb}, nil
+}
+
+// OnStartup does nothing.
+func (s *Sampler) OnStartup() {}
+
+// Name returns the sampler name.
+func (s *Sampler) Name() string {
+	return "HostHeartbeatSampler"
+}
+
+// Interval returns the heartbeat interval.
+func (s *Sampler) Interval() time.Duration {
+	return s.interval
+}
+
+// Disabled returns true when the heartbeat interval is 0.
+func (s *Sampler) Disabled() bool {
+	return s.interval <= 0
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package hostheartbeat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/integrations/v4/runner"
+	backendhttp "github.com/newrelic/infrastructure-agent/pkg/backend/http"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+)
+
+func heartbeat(t *testing.T, s *Sampler) *HostHeartbeat {
+	t.Helper()
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	hb, ok := batch[0].(*HostHeartbeat)
+	require.True(t, ok)
+	return hb
+}
+
+func TestSampler_Sample(t *testing.T) {
+	var backendFailing bool
+	s := NewSampler(nil, func() (agent.SenderStats, bool) {
+		return agent.SenderStats{EventQueueSize: 10, EventQueueCapacity: 1000}, true
+	})
+	s.version = "1.2.3"
+	s.started = time.Now().Add(-time.Minute)
+	s.identity = func() entity.Identity { return entity.Identity{ID: 123, GUID: "MXxJTkZSQXxOQXwxMjM"} }
+	s.hostname = func() string { return "my-host.example.com" }
+	s.backendReports = func() []backendhttp.BackendReport {
+		return []backendhttp.BackendReport{{URL: "https://infra-api.newrelic.com", Failing: backendFailing}}
+	}
+	s.integrationReports = func() []runner.IntegrationReport { return []runner.IntegrationReport{{Name: "nri-flex"}} }
+
+	hb := heartbeat(t, s)
+
+	assert.Equal(t, eventType, hb.EventType)
+	assert.Equal(t, "1.2.3", hb.AgentVersion)
+	assert.Equal(t, "my-host.example.com", hb.FullHostname)
+	assert.Equal(t, int64(123), hb.EntityID)
+	assert.Equal(t, "MXxJTkZSQXxOQXwxMjM", hb.EntityGUID)
+	assert.Equal(t, uint64(1), hb.Sequence)
+	assert.GreaterOrEqual(t, hb.AgentUptimeSec, int64(60))
+	assert.True(t, hb.Healthy)
+	assert.False(t, hb.InMaintenance)
+
+	backendFailing = true
+	hb = heartbeat(t, s)
+	assert.Equal(t, uint64(2), hb.Sequence)
+	assert.True(t, hb.BackendFailing)
+	assert.False(t, hb.Healthy)
+}
+
+func TestSampler_Sample_Unhealthy(t *testing.T) {
+	since := time.Now()
+	s := NewSampler(nil, func() (agent.SenderStats, bool) {
+		return agent.SenderStats{EventQueueSize: 1000, EventQueueCapacity: 1000}, true
+	})
+	s.backendReports = func() []backendhttp.BackendReport { return nil }
+	s.integrationReports = func() []runner.IntegrationReport {
+		return []runner.IntegrationReport{{Name: "nri-flex", CrashLoopSince: &since}}
+	}
+	s.maintenance = func() bool { return true }
+
+	hb := heartbeat(t, s)
+
+	assert.True(t, hb.EventQueueFull)
+	assert.True(t, hb.IntegrationsCrashLooping)
+	assert.True(t, hb.InMaintenance)
+	assert.False(t, hb.Healthy)
+	// the identity isn't reported until it's available
+	assert.Zero(t, hb.EntityID)
+}
+
+func TestSampler_Disabled(t *testing.T) {
+	assert.True(t, NewSampler(nil, nil).Disabled())
+}
@@ -2,11 +2,32 @@
 // SPDX-License-Identifier: Apache-2.0
 package metrics
 
+import "runtime"
+
 type LoadSample struct {
 	LoadOne     float64 `json:"loadAverageOneMinute"`
 	LoadFive    float64 `json:"loadAverageFiveMinute"`
 	LoadFifteen float64 `json:"loadAverageFifteenMinute"`
+	// LoadOneNormalized is the one minute load average divided by the number of logical cores.
+	LoadOneNormalized *float64 `json:"loadAverageOneMinuteNormalized,omitempty"`
+	// ProcsRunning is the run-queue length: tasks running or ready to run (linux only).
+	ProcsRunning *uint64 `json:"procsRunning,omitempty"`
+	// ProcsBlocked is the number of tasks blocked waiting for I/O (linux only).
+	ProcsBlocked *uint64 `json:"procsBlocked,omitempty"`
 }
 
 type LoadMonitor struct {
 }
+
+// normalizeLoad divides the load by the number of cores, returning nil when cores are unknown.
+func normalizeLoad(load float64, cores int) *float64 {
+	if cores <= 0 {
+		return nil
+	}
+	normalized := load / float64(cores)
+	return &normalized
+}
+
+func logicalCores() int {
+	return runtime.NumCPU()
+}
@@ -0,0 +1,6 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+// sampleRunQueue is a no-op as the run-queue length is not exposed on macOS.
+func (self *LoadMonitor) sampleRunQueue(_ *LoadSample) {}
@@ -0,0 +1,70 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+// sampleRunQueue decorates the sample with the run-queue and blocked tasks from /proc/stat.
+// Failures are logged and the attributes left out, as load averages are still valid.
+func (self *LoadMonitor) sampleRunQueue(sample *LoadSample) {
+	file, err := os.Open(helpers.HostProc("stat"))
+	if err != nil {
+		syslog.WithError(err).Debug("Cannot read run-queue stats.")
+		return
+	}
+	defer file.Close()
+
+	running, blocked, err := parseProcsStat(file)
+	if err != nil {
+		syslog.WithError(err).Debug("Cannot parse run-queue stats.")
+		return
+	}
+
+	sample.ProcsRunning = &running
+	sample.ProcsBlocked = &blocked
+}
+
+// parseProcsStat reads procs_running and procs_blocked from /proc/stat content.
+func parseProcsStat(r io.Reader) (running, blocked uint64, err error) {
+	var foundRunning, foundBlocked bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "procs_running":
+			running, err = strconv.ParseUint(fields[1], 10, 64)
+			foundRunning = true
+		case "procs_blocked":
+			blocked, err = strconv.ParseUint(fields[1], 10, 64)
+			foundBlocked = true
+		default:
+			continue
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s value: %w", fields[0], err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	if !foundRunning || !foundBlocked {
+		return 0, 0, fmt.Errorf("procs_running or procs_blocked not found")
+	}
+
+	return running, blocked, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const procStat = `cpu  10132153 290696 3084719 46828483 16683 0 25195 0 0 0
+cpu0 1393280 32966 572056 13343292 6130 0 17875 0 0 0
+intr 199292722 31 9 0 0 0 0 3 0 1 0 0 0 0 0 0 0
+ctxt 1990473
+btime 1062191376
+processes 2915
+procs_running 3
+procs_blocked 2
+softirq 183433 0 21755 12 39 1137 231 21459 2263
+`
+
+func TestParseProcsStat(t *testing.T) {
+	running, blocked, err := parseProcsStat(strings.NewReader(procStat))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(3), running)
+	assert.Equal(t, uint64(2), blocked)
+}
+
+func TestParseProcsStat_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"missing", "cpu  1 2 3\nprocs_running 3\n", "procs_running or procs_blocked not found"},
+		{"invalid", "procs_running foo\nprocs_blocked 1\n", `invalid procs_running value: strconv.ParseUint: parsing "foo": invalid syntax`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseProcsStat(strings.NewReader(tt.content))
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoadMonitor_Sample(t *testing.T) {
+	sample, err := NewLoadMonitor().Sample()
+	require.NoError(t, err)
+
+	require.NotNil(t, sample.LoadOneNormalized)
+	assert.InDelta(t, sample.LoadOne/float64(logicalCores()), *sample.LoadOneNormalized, 0.0001)
+	// at least the sampling goroutine is running
+	require.NotNil(t, sample.ProcsRunning)
+	assert.NotZero(t, *sample.ProcsRunning)
+	assert.NotNil(t, sample.ProcsBlocked)
+}
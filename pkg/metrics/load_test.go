@@ -0,0 +1,18 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeLoad(t *testing.T) {
+	normalized := normalizeLoad(3, 4)
+	require.NotNil(t, normalized)
+	assert.Equal(t, 0.75, *normalized)
+
+	assert.Nil(t, normalizeLoad(3, 0))
+}
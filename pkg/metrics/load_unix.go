@@ -28,10 +28,13 @@ func (self *LoadMonitor) Sample() (sample *LoadSample, err error) {
 		return nil, err
 	}
 
-	return &LoadSample{
-		LoadOne:     load.Load1,
-		LoadFive:    load.Load5,
-		LoadFifteen: load.Load15,
-	}, nil
+	sample = &LoadSample{
+		LoadOne:           load.Load1,
+		LoadFive:          load.Load5,
+		LoadFifteen:       load.Load15,
+		LoadOneNormalized: normalizeLoad(load.Load1, logicalCores()),
+	}
+	self.sampleRunQueue(sample)
 
+	return sample, nil
 }
@@ -59,9 +59,10 @@ func (self *LoadMonitor) Sample() (sample *LoadSample, err error) {
 	five := loadFloor(float64(loadFive) / DIV)
 	fifteen := loadFloor(float64(loadFifteen) / DIV)
 	return &LoadSample{
-		LoadOne:     one,
-		LoadFive:    five,
-		LoadFifteen: fifteen,
+		LoadOne:           one,
+		LoadFive:          five,
+		LoadFifteen:       fifteen,
+		LoadOneNormalized: normalizeLoad(one, logicalCores()),
 	}, nil
 }
 
@@ -29,6 +29,11 @@ type MemorySample struct {
 	MemorySharedBytes float64  `json:"memorySharedBytes"`
 	MemoryBuffers     *float64 `json:"memoryBuffers,omitempty"`
 	MemoryKernelFree  *float64 `json:"memoryKernelFree,omitempty"`
+	// only available in macOS
+	MemoryPressureLevel   string   `json:"memoryPressureLevel,omitempty"`
+	MemoryCompressedBytes *float64 `json:"memoryCompressedBytes,omitempty"`
+	MemoryWiredBytes      *float64 `json:"memoryWiredBytes,omitempty"`
+	MemoryAppBytes        *float64 `json:"memoryAppBytes,omitempty"`
 	SwapSample
 }
 
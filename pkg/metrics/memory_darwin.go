@@ -2,7 +2,33 @@
 // SPDX-License-Identifier: Apache-2.0
 package metrics
 
-import "github.com/shirou/gopsutil/v3/mem"
+import (
+	"github.com/shirou/gopsutil/v3/mem"
+	"golang.org/x/sys/unix"
+)
+
+// Values of the kern.memorystatus_vm_pressure_level sysctl.
+const (
+	memoryPressureNormal   = 1
+	memoryPressureWarning  = 2
+	memoryPressureCritical = 4
+)
+
+// darwinVMStats is the macOS memory accounting, in bytes, as shown by Activity Monitor.
+type darwinVMStats struct {
+	// wired memory cannot be compressed nor paged out.
+	wired uint64
+	// compressed is the memory used by the compressor to store compressed pages.
+	compressed uint64
+	// app is the anonymous memory used by processes, excluding purgeable pages.
+	app uint64
+}
+
+var (
+	// replaceable for testing
+	hostVMStats         = hostStatistics64
+	memoryPressureLevel = sysctlMemoryPressureLevel
+)
 
 // NewMemoryMonitor returns a reference to a memory monitor that reads the memory metrics as reported by the system
 func NewMemoryMonitor(_ bool) *MemoryMonitor {
@@ -36,7 +62,7 @@ func swapMemory() (*SwapSample, error) {
 
 // returns the memory metrics.
 func memorySample(memStat *mem.VirtualMemoryStat, swap *SwapSample, memoryFreePercent float64, memoryUsedPercent float64) (*MemorySample, error) {
-	return &MemorySample{
+	sample := &MemorySample{
 		MemoryTotal:       float64(memStat.Total),
 		MemoryFree:        float64(memStat.Available),
 		MemoryUsed:        float64(memStat.Used),
@@ -49,5 +75,53 @@ func memorySample(memStat *mem.VirtualMemoryStat, swap *SwapSample, memoryFreePe
 		MemoryUsedPercent: memoryUsedPercent,
 
 		SwapSample: *swap,
-	}, nil
+	}
+
+	// gopsutil values don't reflect how macOS manages memory, so its own accounting is reported as well
+	if vmStats, err := hostVMStats(); err != nil {
+		syslog.WithError(err).Debug("Cannot retrieve host VM statistics.")
+	} else {
+		sample.MemoryWiredBytes = floatToReference(float64(vmStats.wired))
+		sample.MemoryCompressedBytes = floatToReference(float64(vmStats.compressed))
+		sample.MemoryAppBytes = floatToReference(float64(vmStats.app))
+	}
+
+	if level, err := memoryPressureLevel(); err != nil {
+		syslog.WithError(err).Debug("Cannot retrieve memory pressure level.")
+	} else {
+		sample.MemoryPressureLevel = level
+	}
+
+	return sample, nil
+}
+
+// newDarwinVMStats converts the host_statistics64 page counters into bytes.
+func newDarwinVMStats(pageSize, wiredPages, compressorPages, internalPages, purgeablePages uint64) *darwinVMStats {
+	stats := &darwinVMStats{
+		wired:      wiredPages * pageSize,
+		compressed: compressorPages * pageSize,
+	}
+	if internalPages > purgeablePages {
+		stats.app = (internalPages - purgeablePages) * pageSize
+	}
+	return stats
+}
+
+// sysctlMemoryPressureLevel returns the memory pressure level as reported by the kernel: normal, warning or critical.
+func sysctlMemoryPressureLevel() (string, error) {
+	level, err := unix.SysctlUint32("kern.memorystatus_vm_pressure_level")
+	if err != nil {
+		return "", err
+	}
+
+	switch level {
+	case memoryPressureNormal:
+		return "normal", nil
+	case memoryPressureWarning:
+		return "warning", nil
+	case memoryPressureCritical:
+		return "critical", nil
+	default:
+		return "unknown", nil
+	}
 }
@@ -0,0 +1,40 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package metrics
+
+/*
+#include <mach/mach_host.h>
+#include <mach/vm_page_size.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// machHost is requested once, as every mach_host_self call adds a reference to the port.
+var machHost = C.mach_host_self()
+
+// hostStatistics64 retrieves the virtual memory statistics through the Mach host_statistics64 call.
+func hostStatistics64() (*darwinVMStats, error) {
+	var vmStat C.vm_statistics64_data_t
+	count := C.mach_msg_type_number_t(C.HOST_VM_INFO64_COUNT)
+
+	ret := C.host_statistics64(machHost, C.HOST_VM_INFO64, C.host_info64_t(unsafe.Pointer(&vmStat)), &count)
+	if ret != C.KERN_SUCCESS {
+		return nil, fmt.Errorf("host_statistics64 failed with code %d", ret)
+	}
+
+	return newDarwinVMStats(
+		uint64(C.vm_kernel_page_size),
+		uint64(vmStat.wire_count),
+		uint64(vmStat.compressor_page_count),
+		uint64(vmStat.internal_page_count),
+		uint64(vmStat.purgeable_count),
+	), nil
+}
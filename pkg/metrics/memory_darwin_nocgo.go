@@ -0,0 +1,14 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build darwin && !cgo
+// +build darwin,!cgo
+
+package metrics
+
+import "errors"
+
+// hostStatistics64 requires cgo to call the Mach API.
+func hostStatistics64() (*darwinVMStats, error) {
+	return nil, errors.New("host_statistics64 is not available without cgo")
+}
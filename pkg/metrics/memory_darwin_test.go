@@ -3,8 +3,10 @@
 package metrics
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/shirou/gopsutil/v3/mem"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -18,7 +20,57 @@ func TestMemoryMonitor_SampleDarwin(t *testing.T) {
 
 	// darwin specific values
 	assert.NotZero(t, sample.MemoryKernelFree)
+	assert.NotEmpty(t, sample.MemoryPressureLevel)
 
 	// linux specific values, do not send in windows
 	assert.Nil(t, sample.MemoryBuffers)
 }
+
+func TestNewDarwinVMStats(t *testing.T) {
+	t.Parallel()
+	stats := newDarwinVMStats(16384, 10, 20, 100, 30)
+
+	assert.Equal(t, uint64(10*16384), stats.wired)
+	assert.Equal(t, uint64(20*16384), stats.compressed)
+	assert.Equal(t, uint64(70*16384), stats.app)
+
+	// purgeable pages exceeding internal ones don't underflow
+	assert.Zero(t, newDarwinVMStats(16384, 0, 0, 10, 20).app)
+}
+
+func TestMemorySample_DarwinAccounting(t *testing.T) {
+	origVMStats, origPressure := hostVMStats, memoryPressureLevel
+	defer func() {
+		hostVMStats, memoryPressureLevel = origVMStats, origPressure
+	}()
+
+	hostVMStats = func() (*darwinVMStats, error) {
+		return &darwinVMStats{wired: 1, compressed: 2, app: 3}, nil
+	}
+	memoryPressureLevel = func() (string, error) {
+		return "warning", nil
+	}
+
+	sample, err := memorySample(&mem.VirtualMemoryStat{}, &SwapSample{}, 0, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, "warning", sample.MemoryPressureLevel)
+	assert.Equal(t, 1.0, *sample.MemoryWiredBytes)
+	assert.Equal(t, 2.0, *sample.MemoryCompressedBytes)
+	assert.Equal(t, 3.0, *sample.MemoryAppBytes)
+
+	hostVMStats = func() (*darwinVMStats, error) {
+		return nil, errors.New("not available")
+	}
+	memoryPressureLevel = func() (string, error) {
+		return "", errors.New("not available")
+	}
+
+	sample, err = memorySample(&mem.VirtualMemoryStat{}, &SwapSample{}, 0, 0)
+	require.NoError(t, err)
+
+	assert.Empty(t, sample.MemoryPressureLevel)
+	assert.Nil(t, sample.MemoryWiredBytes)
+	assert.Nil(t, sample.MemoryCompressedBytes)
+	assert.Nil(t, sample.MemoryAppBytes)
+}
@@ -47,7 +47,7 @@ type NetworkSample struct {
 func NewNetworkSampler(context agent.AgentContext) *NetworkSampler {
 	samplerIntervalSec := config.FREQ_INTERVAL_FLOOR_NETWORK_METRICS
 	if context != nil {
-		samplerIntervalSec = context.Config().MetricsNetworkSampleRate
+		samplerIntervalSec = context.Config().GetMetricsNetworkSampleRate()
 	}
 
 	return &NetworkSampler{
@@ -59,7 +59,11 @@ func NewNetworkSampler(context agent.AgentContext) *NetworkSampler {
 
 func (ns *NetworkSampler) Name() string { return "NetworkSampler" }
 
+// Interval is read from the running configuration, as it's modified when the configuration is reloaded.
 func (ns *NetworkSampler) Interval() time.Duration {
+	if ns.context != nil && ns.context.Config() != nil {
+		return time.Second * time.Duration(ns.context.Config().GetMetricsNetworkSampleRate())
+	}
 	return ns.sampleInterval
 }
 
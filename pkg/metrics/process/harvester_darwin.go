@@ -25,8 +25,12 @@ func newHarvester(ctx agent.AgentContext) *darwinHarvester {
 	privileged := cfg == nil || cfg.RunMode == config.ModeRoot || cfg.RunMode == config.ModePrivileged
 	disableZeroRSSFilter := cfg != nil && cfg.DisableZeroRSSFilter
 	stripCommandLine := (cfg != nil && cfg.StripCommandLine) || (cfg == nil && config.DefaultStripCommandLine)
+	diagnosticsDir := ""
+	if cfg != nil {
+		diagnosticsDir = cfg.AgentTempDir
+	}
 	//decouple the process from the harvester
-	s := NewProcessRetrieverCached(time.Second * 10)
+	s := NewProcessRetrieverCachedWithDiagnostics(time.Second*10, diagnosticsDir)
 	processRetriever := s.ProcessById
 
 	return &darwinHarvester{
@@ -35,6 +39,7 @@ func newHarvester(ctx agent.AgentContext) *darwinHarvester {
 		stripCommandLine:     stripCommandLine,
 		serviceForPid:        ctx.GetServiceForPid,
 		processRetriever:     processRetriever,
+		dataAge:              s.Age,
 	}
 }
 
@@ -47,10 +52,19 @@ type darwinHarvester struct {
 	stripCommandLine     bool
 	serviceForPid        func(int) (string, bool)
 	processRetriever     ProcessRetriever
+	dataAge              func() time.Duration
 }
 
 var _ Harvester = (*darwinHarvester)(nil) // static interface assertion
 
+// DataAge returns the age of the ps snapshot the process samples are harvested from.
+func (dh *darwinHarvester) DataAge() time.Duration {
+	if dh.dataAge == nil {
+		return 0
+	}
+	return dh.dataAge()
+}
+
 // Pids returns a slice of process IDs that are running now
 func (*darwinHarvester) Pids() ([]int32, error) {
 	return process.Pids()
@@ -9,6 +9,7 @@ package process
 
 import (
 	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/agent/capabilities"
 	"github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/acquire"
@@ -27,6 +28,8 @@ func newHarvester(ctx agent.AgentContext, cache *cache) *linuxHarvester {
 
 	return &linuxHarvester{
 		privileged:           privileged,
+		ioCounters:           privileged && capabilities.Available(capabilities.ProcessIOCounters),
+		fdCount:              privileged && capabilities.Available(capabilities.ProcessFDCount),
 		disableZeroRSSFilter: disableZeroRSSFilter,
 		stripCommandLine:     stripCommandLine,
 		serviceForPid:        ctx.GetServiceForPid,
@@ -37,6 +40,8 @@ func newHarvester(ctx agent.AgentContext, cache *cache) *linuxHarvester {
 // linuxHarvester is a Harvester implementation that uses various linux sources and manages process caches
 type linuxHarvester struct {
 	privileged           bool
+	ioCounters           bool // whether the IO counters can be read, as probed on startup
+	fdCount              bool // whether the file descriptors can be counted, as probed on startup
 	disableZeroRSSFilter bool
 	stripCommandLine     bool
 	cache                *cache
@@ -88,8 +93,10 @@ func (ps *linuxHarvester) Do(pid int32, elapsedSeconds float64) (*types.ProcessS
 		return nil, errors.Wrap(err, "can't fetch gauge data")
 	}
 
-	if err := ps.populateIOCounters(sample, cached.lastSample, cached.process, elapsedSeconds); err != nil {
-		return nil, errors.Wrap(err, "can't fetch deltas")
+	if ps.ioCounters {
+		if err := ps.populateIOCounters(sample, cached.lastSample, cached.process, elapsedSeconds); err != nil {
+			return nil, errors.Wrap(err, "can't fetch deltas")
+		}
 	}
 
 	// This must happen every time, even if we already had a cached sample for the process, because
@@ -144,7 +151,7 @@ func (ps *linuxHarvester) populateGauges(sample *types.ProcessSample, process Sn
 		sample.CPUSystemPercent = 0
 	}
 
-	if ps.privileged {
+	if ps.fdCount {
 		fds, err := process.NumFDs()
 		if err != nil {
 			return err
@@ -51,6 +51,26 @@ func TestLinuxHarvester_IsPrivileged(t *testing.T) {
 	}
 }
 
+func TestLinuxHarvester_UnavailableCapabilities(t *testing.T) {
+	// Given a process harvester running as root, without access to the IO counters
+	ctx := new(mocks.AgentContext)
+	ctx.On("Config").Return(&config.Config{RunMode: config.ModeRoot})
+	ctx.On("GetServiceForPid", mock.Anything).Return("", false)
+
+	cache := newCache()
+	h := newHarvester(ctx, &cache)
+	h.ioCounters = false
+
+	// When a process is harvested
+	sample, err := h.Do(int32(os.Getpid()), 100)
+
+	// Then only the IO counters are not reported
+	require.NoError(t, err)
+	assert.NotNil(t, sample.FdCount)
+	assert.Nil(t, sample.IOTotalReadCount)
+	assert.Nil(t, sample.IOReadBytesPerSecond)
+}
+
 func TestLinuxHarvester_Pids(t *testing.T) {
 	// Given a process harvester
 	ctx := new(mocks.AgentContext)
@@ -28,11 +28,21 @@ var commandRunner CommandRunner = helpers.RunCommand
 // read information of all processes with just 2 calls to ps
 // it uses c&p parts of code of gopsutil which was the 1st approach but makes too may system calls
 type ProcessRetrieverCached struct {
-	cache cache
+	cache       cache
+	diagnostics *psParseDiagnostics
 }
 
 func NewProcessRetrieverCached(ttl time.Duration) *ProcessRetrieverCached {
-	return &ProcessRetrieverCached{cache: cache{ttl: ttl}}
+	return NewProcessRetrieverCachedWithDiagnostics(ttl, "")
+}
+
+// NewProcessRetrieverCachedWithDiagnostics creates a ProcessRetrieverCached writing ps parse diagnostics
+// into diagnosticsDir the first time ps output cannot be parsed.
+func NewProcessRetrieverCachedWithDiagnostics(ttl time.Duration, diagnosticsDir string) *ProcessRetrieverCached {
+	return &ProcessRetrieverCached{
+		cache:       cache{ttl: ttl},
+		diagnostics: newPsParseDiagnostics(diagnosticsDir),
+	}
 }
 
 // ProcessById returns a process.Process by pid or error if not found
@@ -48,6 +58,17 @@ func (s *ProcessRetrieverCached) ProcessById(pid int32) (Process, error) {
 	return nil, fmt.Errorf("cannot find process with pid %v", pid)
 }
 
+// Age returns how old the cached processes information is.
+func (s *ProcessRetrieverCached) Age() time.Duration {
+	s.cache.Lock()
+	defer s.cache.Unlock()
+
+	if s.cache.createdAt.IsZero() {
+		return 0
+	}
+	return time.Since(s.cache.createdAt)
+}
+
 // processesFromCache returns all processes running. These will be retrieved and cached for cache.ttl time
 func (s *ProcessRetrieverCached) processesFromCache() (map[int32]psItem, error) {
 	s.cache.Lock()
@@ -75,6 +96,7 @@ func (s *ProcessRetrieverCached) processesFromCache() (map[int32]psItem, error)
 		}
 		items = addThreadsAndCmdToPsItems(items, processesThreads, fullCmd)
 		s.cache.update(items)
+		s.diagnostics.refreshed(psBin)
 	}
 
 	return s.cache.items, nil
@@ -106,6 +128,9 @@ func (s *ProcessRetrieverCached) retrieveProcesses(psBin string) (map[int32]psIt
 	lines := strings.Split(out, "\n")
 	items := make(map[int32]psItem)
 	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
 		var lineItems []string
 		for _, lineItem := range strings.Split(line, " ") {
 			if lineItem == "" {
@@ -140,8 +165,9 @@ func (s *ProcessRetrieverCached) retrieveProcesses(psBin string) (map[int32]psIt
 				command:  command,
 			}
 			items[int32(pid)] = item
+			s.diagnostics.parsed()
 		} else {
-			mplog.WithField("ps_output", out).Error("ps output is expected to have >10 columns")
+			s.diagnostics.unparsed("processes", "expected more than 10 columns", line)
 		}
 	}
 	return items, nil
@@ -208,14 +234,15 @@ func (s *ProcessRetrieverCached) getProcessThreads(psBin string) (map[int32]int3
 			}
 			pidAsInt, err := strconv.Atoi(strings.TrimSpace(lineItem))
 			if err != nil {
-				mplog.Warnf("pid %v doesn't look like an int", pidAsInt)
-				continue
+				s.diagnostics.unparsed("threads", "pid is not an integer", line)
+				break
 			}
 			pid := int32(pidAsInt)
 			if _, ok := processThreads[pid]; !ok {
 				processThreads[pid] = 1 //main process already included
 			}
 			processThreads[pid]++
+			s.diagnostics.parsed()
 			//we are only interested in pid so break and process next line
 			break
 		}
@@ -250,6 +277,9 @@ func (s *ProcessRetrieverCached) getProcessFullCmd(psBin string) (map[int32]stri
 			if _, ok := processThreads[pid]; !ok {
 				processThreads[pid] = cmd
 			}
+			s.diagnostics.parsed()
+		} else if len(lineItems) == 1 {
+			s.diagnostics.unparsed("command", "expected pid and command columns", line)
 		}
 	}
 
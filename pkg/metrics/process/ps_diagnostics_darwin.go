@@ -0,0 +1,178 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent/instrumentation"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+const (
+	// maxMalformedLines bounds the amount of offending ps lines captured for diagnostics.
+	maxMalformedLines = 10
+	// maxMalformedLineLength truncates captured lines, as command lines might be huge.
+	maxMalformedLineLength = 512
+	psDiagnosticsFileName  = "ps-parse-diagnostics.json"
+	psParseHealthMetric    = "agent.psParseHealthPercent"
+)
+
+var errNoArtifactDir = errors.New("no directory to write diagnostics")
+
+// localeEnvVars are the environment variables that might change the ps output format.
+var localeEnvVars = []string{"LANG", "LC_ALL", "LC_NUMERIC", "LC_TIME"}
+
+// malformedLine is a ps output line that couldn't be parsed.
+type malformedLine struct {
+	Parser string `json:"parser"`
+	Reason string `json:"reason"`
+	Line   string `json:"line"`
+}
+
+// psDiagnostics is the diagnostic artifact written when ps output cannot be parsed.
+type psDiagnostics struct {
+	Timestamp      time.Time         `json:"timestamp"`
+	PsBin          string            `json:"psBin"`
+	PsVersion      string            `json:"psVersion,omitempty"`
+	OSVersion      string            `json:"osVersion,omitempty"`
+	Locale         map[string]string `json:"locale"`
+	Lines          int               `json:"lines"`
+	MalformedLines []malformedLine   `json:"malformedLines"`
+}
+
+// psParseDiagnostics tracks the health of the ps output parsing. Rather than logging the whole
+// ps output on every refresh, a bounded set of the offending lines is captured along with the
+// environment details (locale, ps and OS versions) into a diagnostic artifact, just once per run.
+type psParseDiagnostics struct {
+	sync.Mutex
+	// artifactDir is where the diagnostic artifact is written, it's only logged when empty.
+	artifactDir string
+	// runner retrieves the ps and OS versions.
+	runner    CommandRunner
+	reported  bool
+	lines     int
+	malformed int
+	captured  []malformedLine
+}
+
+func newPsParseDiagnostics(artifactDir string) *psParseDiagnostics {
+	return &psParseDiagnostics{
+		artifactDir: artifactDir,
+		runner:      helpers.RunCommand,
+	}
+}
+
+// parsed accounts for a successfully parsed line.
+func (d *psParseDiagnostics) parsed() {
+	d.Lock()
+	defer d.Unlock()
+
+	d.lines++
+}
+
+// unparsed accounts for a line that couldn't be parsed, capturing it while there's room.
+func (d *psParseDiagnostics) unparsed(parser, reason, line string) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.lines++
+	d.malformed++
+	if d.reported || len(d.captured) >= maxMalformedLines {
+		return
+	}
+	if len(line) > maxMalformedLineLength {
+		line = line[:maxMalformedLineLength] + "..."
+	}
+	d.captured = append(d.captured, malformedLine{Parser: parser, Reason: reason, Line: line})
+}
+
+// refreshed closes a ps refresh cycle: records the parse health metric and reports the malformed
+// lines, if any, the first time they are found.
+func (d *psParseDiagnostics) refreshed(psBin string) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.lines > 0 {
+		health := 100 * float64(d.lines-d.malformed) / float64(d.lines)
+		instrumentation.SelfInstrumentation.RecordMetric(context.Background(),
+			instrumentation.NewGaugeWithAttributes(psParseHealthMetric, health, map[string]interface{}{
+				"lines":          d.lines,
+				"malformedLines": d.malformed,
+			}))
+	}
+
+	if !d.reported && len(d.captured) > 0 {
+		d.report(psBin)
+		d.reported = true
+		d.captured = nil
+	}
+
+	d.lines = 0
+	d.malformed = 0
+}
+
+func (d *psParseDiagnostics) report(psBin string) {
+	diagnostics := psDiagnostics{
+		Timestamp:      time.Now(),
+		PsBin:          psBin,
+		PsVersion:      firstLine(d.runner("what", "", psBin)),
+		OSVersion:      firstLine(d.runner("sw_vers", "", "-productVersion")),
+		Locale:         make(map[string]string, len(localeEnvVars)),
+		Lines:          d.lines,
+		MalformedLines: d.captured,
+	}
+	for _, env := range localeEnvVars {
+		diagnostics.Locale[env] = os.Getenv(env)
+	}
+
+	logger := mplog.WithField("malformedLines", d.malformed).WithField("lines", d.lines)
+
+	path, err := d.writeArtifact(diagnostics)
+	if err != nil {
+		// fallback to the logs, captured lines are bounded
+		logger.WithError(err).
+			WithField("diagnostics", diagnostics).
+			Warn("Some ps output lines couldn't be parsed and the diagnostics file couldn't be written.")
+		return
+	}
+
+	logger.WithField("file", path).
+		Warn("Some ps output lines couldn't be parsed, process samples might be incomplete. Diagnostics have been written to file.")
+}
+
+func (d *psParseDiagnostics) writeArtifact(diagnostics psDiagnostics) (string, error) {
+	if d.artifactDir == "" {
+		return "", errNoArtifactDir
+	}
+
+	content, err := json.MarshalIndent(diagnostics, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(d.artifactDir, psDiagnosticsFileName)
+	return path, os.WriteFile(path, content, 0o600)
+}
+
+// firstLine returns the first meaningful line from a command output, empty on error. Lines ending
+// with a colon are skipped, as "what" prints the inspected file path before its version strings.
+func firstLine(out string, err error) string {
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasSuffix(line, ":") {
+			return line
+		}
+	}
+	return ""
+}
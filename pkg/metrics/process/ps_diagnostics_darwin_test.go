@@ -0,0 +1,114 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func versionsRunner(command string, _ string, _ ...string) (string, error) {
+	switch command {
+	case "what":
+		return "/bin/ps:\n\tPROGRAM:ps  PROJECT:adv_cmds-199.0.1\n", nil
+	case "sw_vers":
+		return "13.4.1\n", nil
+	}
+	return "", errors.New("unexpected command")
+}
+
+func readDiagnostics(t *testing.T, dir string) psDiagnostics {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(dir, psDiagnosticsFileName))
+	require.NoError(t, err)
+
+	var diagnostics psDiagnostics
+	require.NoError(t, json.Unmarshal(content, &diagnostics))
+	return diagnostics
+}
+
+func Test_psParseDiagnostics_capturesBoundedLines(t *testing.T) {
+	d := newPsParseDiagnostics("")
+
+	for i := 0; i < maxMalformedLines+5; i++ {
+		d.unparsed("processes", "expected more than 10 columns", strings.Repeat("x", maxMalformedLineLength+1))
+	}
+	d.parsed()
+
+	assert.Equal(t, maxMalformedLines+6, d.lines)
+	assert.Equal(t, maxMalformedLines+5, d.malformed)
+	require.Len(t, d.captured, maxMalformedLines)
+	assert.Len(t, d.captured[0].Line, maxMalformedLineLength+len("..."))
+}
+
+func Test_psParseDiagnostics_reportsOnce(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LANG", "de_DE.UTF-8")
+
+	d := newPsParseDiagnostics(dir)
+	d.runner = versionsRunner
+
+	d.parsed()
+	d.unparsed("processes", "expected more than 10 columns", "  1 0 root")
+	d.refreshed("/bin/ps")
+
+	diagnostics := readDiagnostics(t, dir)
+	assert.Equal(t, "/bin/ps", diagnostics.PsBin)
+	assert.Equal(t, "PROGRAM:ps  PROJECT:adv_cmds-199.0.1", diagnostics.PsVersion)
+	assert.Equal(t, "13.4.1", diagnostics.OSVersion)
+	assert.Equal(t, "de_DE.UTF-8", diagnostics.Locale["LANG"])
+	assert.Equal(t, 2, diagnostics.Lines)
+	assert.Equal(t, []malformedLine{{Parser: "processes", Reason: "expected more than 10 columns", Line: "  1 0 root"}}, diagnostics.MalformedLines)
+
+	// counters are reset on every refresh
+	assert.Zero(t, d.lines)
+	assert.Zero(t, d.malformed)
+
+	// artifact is not overwritten on following refreshes
+	require.NoError(t, os.Remove(filepath.Join(dir, psDiagnosticsFileName)))
+	d.unparsed("processes", "expected more than 10 columns", "  2 0 root")
+	d.refreshed("/bin/ps")
+
+	assert.NoFileExists(t, filepath.Join(dir, psDiagnosticsFileName))
+	assert.Empty(t, d.captured)
+}
+
+func Test_psParseDiagnostics_noArtifactWithoutMalformedLines(t *testing.T) {
+	dir := t.TempDir()
+
+	d := newPsParseDiagnostics(dir)
+	d.runner = versionsRunner
+
+	d.parsed()
+	d.refreshed("/bin/ps")
+
+	assert.NoFileExists(t, filepath.Join(dir, psDiagnosticsFileName))
+	assert.False(t, d.reported)
+}
+
+func Test_ProcessRetrieverCached_retrieveProcesses_diagnostics(t *testing.T) {
+	cmdRunMock := &commandRunnerMock{}
+	commandRunner = cmdRunMock.run
+	psArgs := []string{"ax", "-c", "-o", "pid,ppid,user,state,utime,stime,etime,rss,vsize,pagein,command"}
+	// trailing empty lines are not considered malformed
+	out := psOut[0] + "\n    2     1 root   Ss\n"
+	cmdRunMock.ShouldRunCommand("/bin/ps", "", psArgs, out, nil)
+
+	ret := NewProcessRetrieverCached(0)
+	items, err := ret.retrieveProcesses("/bin/ps")
+	require.NoError(t, err)
+
+	assert.Len(t, items, 4)
+	assert.Equal(t, 5, ret.diagnostics.lines)
+	assert.Equal(t, 1, ret.diagnostics.malformed)
+	assert.Equal(t, []malformedLine{{Parser: "processes", Reason: "expected more than 10 columns", Line: "    2     1 root   Ss"}}, ret.diagnostics.captured)
+}
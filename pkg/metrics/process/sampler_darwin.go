@@ -25,27 +25,30 @@ type processSampler struct {
 	lastRun           time.Time
 	hasAlreadyRun     bool
 	interval          time.Duration
+	cfg               *config.Config // running configuration, nil when not provided
 }
 
 var (
-	_                       sampler.Sampler = (*processSampler)(nil) // static interface assertion
-	containerNotRunningErrs                 = map[string]struct{}{}
+	_                       sampler.Sampler       = (*processSampler)(nil) // static interface assertion
+	_                       sampler.CachedSampler = (*processSampler)(nil) // static interface assertion
+	containerNotRunningErrs                       = map[string]struct{}{}
 )
 
 // NewProcessSampler creates and returns a new process Sampler, given an agent context.
 func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 	hasConfig := ctx != nil && ctx.Config() != nil
 
+	var cfg *config.Config
 	ttlSecs := config.DefaultContainerCacheMetadataLimit
 	apiVersion := ""
 	interval := config.FREQ_INTERVAL_FLOOR_PROCESS_METRICS
 	dockerContainerdNamespace := ""
 	if hasConfig {
-		cfg := ctx.Config()
+		cfg = ctx.Config()
 		ttlSecs = cfg.ContainerMetadataCacheLimit
 		apiVersion = cfg.DockerApiVersion
 		dockerContainerdNamespace = cfg.DockerContainerdNamespace
-		interval = cfg.MetricsProcessSampleRate
+		interval = cfg.GetMetricsProcessSampleRate()
 	}
 	harvester := newHarvester(ctx)
 	containerSamplers := metrics.GetContainerSamplers(time.Duration(ttlSecs)*time.Second, apiVersion, dockerContainerdNamespace)
@@ -54,6 +57,7 @@ func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 		harvest:           harvester,
 		containerSamplers: containerSamplers,
 		interval:          time.Second * time.Duration(interval),
+		cfg:               cfg,
 	}
 
 }
@@ -64,7 +68,11 @@ func (ps *processSampler) Name() string {
 	return "ProcessSampler"
 }
 
+// Interval is read from the running configuration, as it's modified when the configuration is reloaded.
 func (ps *processSampler) Interval() time.Duration {
+	if ps.cfg != nil {
+		return time.Second * time.Duration(ps.cfg.GetMetricsProcessSampleRate())
+	}
 	return ps.interval
 }
 
@@ -72,6 +80,14 @@ func (ps *processSampler) Disabled() bool {
 	return ps.Interval() <= config.FREQ_DISABLE_SAMPLING
 }
 
+// DataAge returns the age of the cached processes information served by the harvester, if any.
+func (ps *processSampler) DataAge() time.Duration {
+	if cached, ok := ps.harvest.(sampler.CachedSampler); ok {
+		return cached.DataAge()
+	}
+	return 0
+}
+
 // Sample returns samples for all the running processes, decorated with container runtime information, if applies.
 func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 	var elapsedMs int64
@@ -115,6 +131,7 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 		}
 	}
 
+	processSamples := make([]*types.ProcessSample, 0, len(pids))
 	for _, pid := range pids {
 		var processSample *types.ProcessSample
 		var err error
@@ -136,6 +153,14 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 			}
 		}
 
+		processSamples = append(processSamples, processSample)
+	}
+
+	if ps.cfg != nil {
+		topN, coveragePercent := ps.cfg.GetProcessTopN()
+		processSamples = metrics.TopProcesses(processSamples, topN, coveragePercent)
+	}
+	for _, processSample := range processSamples {
 		results = append(results, ps.normalizeSample(processSample))
 	}
 
@@ -25,6 +25,7 @@ type processSampler struct {
 	lastRun           time.Time
 	hasAlreadyRun     bool
 	interval          time.Duration
+	cfg               *config.Config // running configuration, nil when not provided
 	cache             *cache
 }
 
@@ -37,16 +38,17 @@ var (
 func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 	hasConfig := ctx != nil && ctx.Config() != nil
 
+	var cfg *config.Config
 	ttlSecs := config.DefaultContainerCacheMetadataLimit
 	apiVersion := ""
 	dockerContainerdNamespace := ""
 	interval := config.FREQ_INTERVAL_FLOOR_PROCESS_METRICS
 	if hasConfig {
-		cfg := ctx.Config()
+		cfg = ctx.Config()
 		ttlSecs = cfg.ContainerMetadataCacheLimit
 		apiVersion = cfg.DockerApiVersion
 		dockerContainerdNamespace = cfg.DockerContainerdNamespace
-		interval = cfg.MetricsProcessSampleRate
+		interval = cfg.GetMetricsProcessSampleRate()
 	}
 	cache := newCache()
 	harvest := newHarvester(ctx, &cache)
@@ -57,6 +59,7 @@ func NewProcessSampler(ctx agent.AgentContext) sampler.Sampler {
 		containerSamplers: containerSamplers,
 		cache:             &cache,
 		interval:          time.Second * time.Duration(interval),
+		cfg:               cfg,
 	}
 }
 
@@ -66,7 +69,11 @@ func (ps *processSampler) Name() string {
 	return "ProcessSampler"
 }
 
+// Interval is read from the running configuration, as it's modified when the configuration is reloaded.
 func (ps *processSampler) Interval() time.Duration {
+	if ps.cfg != nil {
+		return time.Second * time.Duration(ps.cfg.GetMetricsProcessSampleRate())
+	}
 	return ps.interval
 }
 
@@ -116,6 +123,7 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 		}
 	}
 
+	processSamples := make([]*types.ProcessSample, 0, len(pids))
 	for _, pid := range pids {
 		var processSample *types.ProcessSample
 		var err error
@@ -137,6 +145,14 @@ func (ps *processSampler) Sample() (results sample.EventBatch, err error) {
 			}
 		}
 
+		processSamples = append(processSamples, processSample)
+	}
+
+	if ps.cfg != nil {
+		topN, coveragePercent := ps.cfg.GetProcessTopN()
+		processSamples = metrics.TopProcesses(processSamples, topN, coveragePercent)
+	}
+	for _, processSample := range processSamples {
 		results = append(results, ps.normalizeSample(processSample))
 	}
 
@@ -0,0 +1,112 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"sort"
+
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+)
+
+// OtherProcessesName is the name of the ProcessSample aggregating the processes out of the top N.
+const OtherProcessesName = "other"
+
+// TopProcesses returns the n samples using the most CPU and the n using the most memory, followed by a sample
+// aggregating the rest, if any. The ranking stops once the samples taken account for the coverage percentage of
+// the total usage, so fewer processes are reported when a few of them use most of it, ie: on idle hosts.
+func TopProcesses(samples []*types.ProcessSample, n int, coveragePercent int) []*types.ProcessSample {
+	if n <= 0 || len(samples) <= n {
+		return samples
+	}
+	if coveragePercent <= 0 || coveragePercent > 100 {
+		coveragePercent = 100
+	}
+
+	selected := make(map[*types.ProcessSample]bool, 2*n)
+	var top []*types.ProcessSample
+	for _, usage := range []func(*types.ProcessSample) float64{
+		func(s *types.ProcessSample) float64 { return s.CPUPercent },
+		func(s *types.ProcessSample) float64 { return float64(s.MemoryRSSBytes) },
+	} {
+		for _, s := range rankProcesses(samples, usage, n, coveragePercent) {
+			if !selected[s] {
+				selected[s] = true
+				top = append(top, s)
+			}
+		}
+	}
+
+	var other *types.ProcessSample
+	for _, s := range samples {
+		if selected[s] {
+			continue
+		}
+		if other == nil {
+			other = &types.ProcessSample{
+				ProcessDisplayName: OtherProcessesName,
+				CommandName:        OtherProcessesName,
+			}
+			other.Type("ProcessSample")
+		}
+		aggregateProcess(other, s)
+	}
+	if other != nil {
+		top = append(top, other)
+	}
+	return top
+}
+
+// rankProcesses returns up to n samples by descending usage, until they account for the coverage percentage of
+// the total. Samples with no usage aren't ranked.
+func rankProcesses(samples []*types.ProcessSample, usage func(*types.ProcessSample) float64, n int, coveragePercent int) []*types.ProcessSample {
+	ranked := make([]*types.ProcessSample, 0, len(samples))
+	var total float64
+	for _, s := range samples {
+		if u := usage(s); u > 0 {
+			ranked = append(ranked, s)
+			total += u
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return usage(ranked[i]) > usage(ranked[j]) })
+
+	threshold := total * float64(coveragePercent) / 100
+	var covered float64
+	for i, s := range ranked {
+		if i == n || covered >= threshold {
+			return ranked[:i]
+		}
+		covered += usage(s)
+	}
+	return ranked
+}
+
+func aggregateProcess(other, s *types.ProcessSample) {
+	other.ProcessCount++
+	other.MemoryRSSBytes += s.MemoryRSSBytes
+	other.MemoryVMSBytes += s.MemoryVMSBytes
+	other.CPUPercent += s.CPUPercent
+	other.CPUUserPercent += s.CPUUserPercent
+	other.CPUSystemPercent += s.CPUSystemPercent
+	other.ThreadCount += s.ThreadCount
+	if s.FdCount != nil {
+		if other.FdCount == nil {
+			other.FdCount = new(int32)
+		}
+		*other.FdCount += *s.FdCount
+	}
+	other.IOReadCountPerSecond = addRate(other.IOReadCountPerSecond, s.IOReadCountPerSecond)
+	other.IOWriteCountPerSecond = addRate(other.IOWriteCountPerSecond, s.IOWriteCountPerSecond)
+	other.IOReadBytesPerSecond = addRate(other.IOReadBytesPerSecond, s.IOReadBytesPerSecond)
+	other.IOWriteBytesPerSecond = addRate(other.IOWriteBytesPerSecond, s.IOWriteBytesPerSecond)
+}
+
+func addRate(total, rate *float64) *float64 {
+	if rate == nil {
+		return total
+	}
+	if total == nil {
+		total = new(float64)
+	}
+	*total += *rate
+	return total
+}
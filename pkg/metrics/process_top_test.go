@@ -0,0 +1,72 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/types"
+)
+
+func processSample(name string, cpuPercent float64, rss int64) *types.ProcessSample {
+	s := &types.ProcessSample{ProcessDisplayName: name, CPUPercent: cpuPercent, MemoryRSSBytes: rss}
+	s.Type("ProcessSample")
+	return s
+}
+
+func names(samples []*types.ProcessSample) []string {
+	var result []string
+	for _, s := range samples {
+		result = append(result, s.ProcessDisplayName)
+	}
+	return result
+}
+
+func TestTopProcesses(t *testing.T) {
+	ioRate := 10.0
+	samples := []*types.ProcessSample{
+		processSample("java", 50, 100),
+		processSample("idle", 0, 10),
+		processSample("postgres", 20, 1000),
+		processSample("nginx", 30, 50),
+		processSample("bash", 0.5, 5),
+	}
+	samples[1].IOReadBytesPerSecond = &ioRate
+
+	top := TopProcesses(samples, 2, 100)
+
+	assert.Equal(t, []string{"java", "nginx", "postgres", OtherProcessesName}, names(top))
+	other := top[3]
+	assert.Equal(t, "ProcessSample", other.EventType)
+	assert.Equal(t, 2, other.ProcessCount)
+	assert.Equal(t, 0.5, other.CPUPercent)
+	assert.Equal(t, int64(15), other.MemoryRSSBytes)
+	require.NotNil(t, other.IOReadBytesPerSecond)
+	assert.Equal(t, 10.0, *other.IOReadBytesPerSecond)
+	assert.Nil(t, other.IOWriteBytesPerSecond)
+}
+
+func TestTopProcesses_Coverage(t *testing.T) {
+	// on an idle host, the few processes using most of the CPU and memory are enough
+	samples := []*types.ProcessSample{
+		processSample("agent", 0.9, 1000),
+		processSample("sshd", 0.1, 10),
+		processSample("cron", 0, 500),
+		processSample("bash", 0, 10),
+	}
+
+	top := TopProcesses(samples, 3, 90)
+
+	assert.Equal(t, []string{"agent", "cron", OtherProcessesName}, names(top))
+	assert.Equal(t, 2, top[2].ProcessCount)
+}
+
+func TestTopProcesses_Disabled(t *testing.T) {
+	samples := []*types.ProcessSample{processSample("java", 50, 100), processSample("bash", 1, 1)}
+
+	assert.Equal(t, samples, TopProcesses(samples, 0, 90))
+	assert.Equal(t, samples, TopProcesses(samples, 2, 90))
+}
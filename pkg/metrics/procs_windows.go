@@ -938,6 +938,21 @@ func (self *ProcsMonitor) Sample() (results sample.EventBatch, err error) {
 	self.hasAlreadyRun = true
 	self.previousSystemTime = self.currentSystemTime
 
+	topN, coveragePercent := 0, 0
+	if self.context != nil {
+		topN, coveragePercent = self.context.Config().GetProcessTopN()
+	}
+	if topN > 0 {
+		processSamples := make([]*types.ProcessSample, 0, len(results))
+		for _, sample := range results {
+			processSamples = append(processSamples, sample.(*types.ProcessSample))
+		}
+		results = make(sample.EventBatch, 0, len(results))
+		for _, sample := range TopProcesses(processSamples, topN, coveragePercent) {
+			results = append(results, sample)
+		}
+	}
+
 	for _, sample := range results {
 		helpers.LogStructureDetails(pslog, sample.(*types.ProcessSample), "ProcessSample", "final", nil)
 	}
@@ -975,7 +990,7 @@ func cpuTotal(t *cpu.TimesStat) float64 {
 
 func (self *ProcsMonitor) intervalSecs() int {
 	if self.context != nil {
-		return self.context.Config().MetricsProcessSampleRate
+		return self.context.Config().GetMetricsProcessSampleRate()
 	}
 
 	return config.FREQ_INTERVAL_FLOOR_PROCESS_METRICS
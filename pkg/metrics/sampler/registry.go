@@ -0,0 +1,73 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+)
+
+// Factory creates a sampler compiled into the agent from its configuration. A nil sampler without error skips it,
+// ie: when it's not configured.
+type Factory func(cfg *config.Config) (Sampler, error)
+
+// Closer is implemented by the samplers releasing resources when the agent stops.
+type Closer interface {
+	Close() error
+}
+
+// Registration of a sampler compiled into the agent.
+type Registration struct {
+	Name    string
+	Factory Factory
+}
+
+var (
+	registryLock sync.Mutex
+	registry     []Registration
+)
+
+// Register makes a sampler compiled into the agent run as the built-in ones: it's created with the agent
+// configuration when the samplers are registered, harvested every Interval, its samples are sent as the events of
+// the host entity and it's closed on shutdown when it implements Closer. It's meant to be called from the init
+// function of the package providing the sampler. It panics if the name is empty, the factory nil or the name was
+// already registered.
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if name == "" || factory == nil {
+		panic("sampler: Register called with an empty name or a nil factory")
+	}
+	for _, r := range registry {
+		if r.Name == name {
+			panic(fmt.Sprintf("sampler: Register called twice for %q", name))
+		}
+	}
+	registry = append(registry, Registration{Name: name, Factory: factory})
+}
+
+// RegisterSampler registers an already created sampler compiled into the agent, by its name.
+func RegisterSampler(s Sampler) {
+	Register(s.Name(), func(*config.Config) (Sampler, error) { return s, nil })
+}
+
+// Registered returns the samplers compiled into the agent, in registration order.
+func Registered() []Registration {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	return append([]Registration(nil), registry...)
+}
+
+// Create returns the sampler of a registration, recovering from the panics of its factory.
+func (r Registration) Create(cfg *config.Config) (s Sampler, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			s, err = nil, fmt.Errorf("sampler %q factory panicked: %v", r.Name, rec)
+		}
+	}()
+	return r.Factory(cfg)
+}
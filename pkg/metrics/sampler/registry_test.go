@@ -0,0 +1,54 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	defer func(previous []Registration) { registry = previous }(registry)
+	registry = nil
+
+	mock := &mockSampler{}
+	RegisterSampler(mock)
+	Register("Configured", func(cfg *config.Config) (Sampler, error) {
+		if cfg.DisplayName == "" {
+			return nil, nil
+		}
+		return mock, nil
+	})
+	Register("Failing", func(*config.Config) (Sampler, error) { return nil, errors.New("boom") })
+	Register("Panicking", func(*config.Config) (Sampler, error) { panic("boom") })
+
+	assert.PanicsWithValue(t, `sampler: Register called twice for "MockSampler"`, func() { RegisterSampler(mock) })
+	assert.Panics(t, func() { Register("Nil", nil) })
+
+	regs := Registered()
+	require.Len(t, regs, 4)
+	assert.Equal(t, "MockSampler", regs[0].Name)
+
+	cfg := &config.Config{}
+	s, err := regs[0].Create(cfg)
+	require.NoError(t, err)
+	assert.Same(t, mock, s)
+
+	s, err = regs[1].Create(cfg)
+	require.NoError(t, err)
+	assert.Nil(t, s)
+	cfg.DisplayName = "host"
+	s, err = regs[1].Create(cfg)
+	require.NoError(t, err)
+	assert.Same(t, mock, s)
+
+	_, err = regs[2].Create(cfg)
+	assert.EqualError(t, err, "boom")
+
+	_, err = regs[3].Create(cfg)
+	assert.EqualError(t, err, `sampler "Panicking" factory panicked: boom`)
+}
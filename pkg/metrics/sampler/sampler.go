@@ -15,3 +15,9 @@ type Sampler interface {
 	Interval() time.Duration
 	Disabled() bool
 }
+
+// CachedSampler is implemented by samplers serving (part of) their data from caches.
+type CachedSampler interface {
+	// DataAge returns the age of the cached data served by the last Sample call.
+	DataAge() time.Duration
+}
@@ -5,10 +5,12 @@ package sampler
 import (
 	"context"
 	"fmt"
-	"github.com/newrelic/infrastructure-agent/internal/agent/instrumentation"
+	"math/rand"
 	"sync"
 	"time"
 
+	"github.com/newrelic/infrastructure-agent/internal/agent/instrumentation"
+
 	"github.com/newrelic/infrastructure-agent/pkg/log"
 	"github.com/newrelic/infrastructure-agent/pkg/sample"
 )
@@ -17,47 +19,81 @@ type SamplerRoutine struct {
 	name           string
 	stopChannel    chan bool
 	waitForCleanup *sync.WaitGroup
+	metaAttributes bool
+	jitter         float64
+}
+
+// RoutineOption customizes a SamplerRoutine.
+type RoutineOption func(*SamplerRoutine)
+
+// WithMetaAttributes decorates samples with collection meta-attributes: collectionDurationMs, and
+// dataAgeMs for samplers serving cached data.
+func WithMetaAttributes() RoutineOption {
+	return func(sr *SamplerRoutine) {
+		sr.metaAttributes = true
+	}
+}
+
+// WithJitter delays the first harvest by a random phase up to the given fraction of the interval, so samplers
+// don't harvest in sync.
+func WithJitter(fraction float64) RoutineOption {
+	return func(sr *SamplerRoutine) {
+		sr.jitter = fraction
+	}
 }
 
 var mslog = log.WithField("component", "Sampler routine")
 
-func StartSamplerRoutine(sampler Sampler, sampleQueue chan sample.EventBatch) *SamplerRoutine {
+// StartSamplerRoutine harvests the sampler on its interval, sending the samples to the queue. Harvests are
+// scheduled at fixed times from the first one: when a harvest takes longer than the interval, the ones missed are
+// skipped rather than run late.
+func StartSamplerRoutine(sampler Sampler, sampleQueue chan sample.EventBatch, opts ...RoutineOption) *SamplerRoutine {
 	sr := &SamplerRoutine{
 		name:           sampler.Name(),
 		stopChannel:    make(chan bool),
 		waitForCleanup: &sync.WaitGroup{},
 	}
+	for _, opt := range opts {
+		opt(sr)
+	}
 
 	sampler.OnStartup()
+	registerRoutine(sr, sampler)
 
 	sr.waitForCleanup.Add(1)
 
 	go func() {
-		ticker := time.NewTicker(sampler.Interval())
+		interval := sampler.Interval()
+		next := time.Now().Add(interval + phase(interval, sr.jitter))
+		timer := time.NewTimer(time.Until(next))
 		defer func() {
-			ticker.Stop()
+			timer.Stop()
 			sr.waitForCleanup.Done()
 		}()
 		mslog.WithField("name", sr.name).Debug("Started sampler routine.")
 		for {
 			select {
-			case <-ticker.C:
-
-				samples, err := func(s Sampler) (sample.EventBatch, error) {
-					_, trx := instrumentation.SelfInstrumentation.StartTransaction(context.Background(), fmt.Sprintf("sampler.%s", s.Name()))
-					defer trx.End()
-					return s.Sample()
-				}(sampler)
-
-				if err != nil {
-					mslog.WithError(err).WithField("samplerName", sr.name).Error("can't get sample from sampler")
-					continue
+			case <-timer.C:
+				// the interval is modified when the configuration is reloaded. While disabled, the previous interval
+				// is kept to check when it's enabled again.
+				if current := sampler.Interval(); current != interval && current > 0 {
+					mslog.WithField("name", sr.name).WithField("interval", current).Info("Sampler interval modified.")
+					interval = current
 				}
-				select {
-				case sampleQueue <- samples:
-				case <-sr.stopChannel:
+				disabled := sampler.Disabled()
+				updateRoutine(sr, interval, disabled)
+				if !disabled && !sr.harvest(sampler, sampleQueue) {
 					return
 				}
+
+				var skipped int
+				next, skipped = nextHarvest(next, interval, time.Now())
+				if skipped > 0 {
+					recordSkipped(sr, skipped)
+					mslog.WithField("name", sr.name).WithField("skipped", skipped).
+						Warn("Sampler harvest took longer than its interval, skipping the late harvests.")
+				}
+				timer.Reset(time.Until(next))
 			case <-sr.stopChannel:
 				return
 			}
@@ -67,9 +103,74 @@ func StartSamplerRoutine(sampler Sampler, sampleQueue chan sample.EventBatch) *S
 	return sr
 }
 
+// harvest samples and queues the samples, returning false when the routine is stopped meanwhile.
+func (sr *SamplerRoutine) harvest(sampler Sampler, sampleQueue chan sample.EventBatch) bool {
+	start := time.Now()
+	samples, err := func(s Sampler) (sample.EventBatch, error) {
+		_, trx := instrumentation.SelfInstrumentation.StartTransaction(context.Background(), fmt.Sprintf("sampler.%s", s.Name()))
+		defer trx.End()
+		return s.Sample()
+	}(sampler)
+	recordHarvest(sr, start, err)
+
+	if err != nil {
+		mslog.WithError(err).WithField("samplerName", sr.name).Error("can't get sample from sampler")
+		return true
+	}
+	if sr.metaAttributes {
+		decorateWithMeta(samples, sampler, time.Since(start))
+	}
+	select {
+	case sampleQueue <- samples:
+		return true
+	case <-sr.stopChannel:
+		return false
+	}
+}
+
+// phase returns a random delay up to the fraction of the interval.
+func phase(interval time.Duration, jitter float64) time.Duration {
+	maxPhase := int64(float64(interval) * jitter)
+	if maxPhase <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(maxPhase))
+}
+
+// nextHarvest returns the time of the harvest following the scheduled one, skipping the ones already past.
+func nextHarvest(scheduled time.Time, interval time.Duration, now time.Time) (next time.Time, skipped int) {
+	next = scheduled.Add(interval)
+	if now.Before(next) {
+		return next, 0
+	}
+	skipped = int(now.Sub(next)/interval) + 1
+	return next.Add(time.Duration(skipped) * interval), skipped
+}
+
+// decorateWithMeta sets the collection meta-attributes on the events supporting them.
+func decorateWithMeta(samples sample.EventBatch, s Sampler, collectionDuration time.Duration) {
+	var dataAge time.Duration
+	cs, cached := s.(CachedSampler)
+	if cached {
+		dataAge = cs.DataAge()
+	}
+
+	for _, e := range samples {
+		me, ok := e.(sample.MetaEvent)
+		if !ok {
+			continue
+		}
+		me.CollectionDuration(collectionDuration)
+		if cached {
+			me.DataAge(dataAge)
+		}
+	}
+}
+
 func (sr *SamplerRoutine) Stop() {
 	close(sr.stopChannel)
 	sr.waitForCleanup.Wait()
 	sr.stopChannel = nil
+	unregisterRoutine(sr)
 	mslog.WithField("name", sr.name).Debug("Stopped sampler routine.")
 }
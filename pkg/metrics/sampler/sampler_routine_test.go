@@ -4,11 +4,13 @@ package sampler
 
 import (
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/newrelic/infrastructure-agent/pkg/sample"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockSampler struct {
@@ -57,3 +59,149 @@ func TestSamplerRoutine(t *testing.T) {
 		}
 	}
 }
+
+type cachedSampler struct {
+	mockSampler
+	batch sample.EventBatch
+}
+
+func (c *cachedSampler) Sample() (sample.EventBatch, error) { return c.batch, nil }
+func (c *cachedSampler) DataAge() time.Duration             { return 3 * time.Second }
+
+func TestSamplerRoutine_MetaAttributes(t *testing.T) {
+	ev := &sample.BaseEvent{}
+	s := &cachedSampler{batch: sample.EventBatch{ev}}
+	sampleQueue := make(chan sample.EventBatch)
+
+	routine := StartSamplerRoutine(s, sampleQueue, WithMetaAttributes())
+	<-sampleQueue
+	routine.Stop()
+
+	require.NotNil(t, ev.CollectionDurationMs)
+	assert.GreaterOrEqual(t, *ev.CollectionDurationMs, int64(0))
+	require.NotNil(t, ev.DataAgeMs)
+	assert.Equal(t, int64(3000), *ev.DataAgeMs)
+}
+
+func TestSamplerRoutine_NoMetaAttributesByDefault(t *testing.T) {
+	ev := &sample.BaseEvent{}
+	s := &cachedSampler{batch: sample.EventBatch{ev}}
+	sampleQueue := make(chan sample.EventBatch)
+
+	routine := StartSamplerRoutine(s, sampleQueue)
+	<-sampleQueue
+	routine.Stop()
+
+	assert.Nil(t, ev.CollectionDurationMs)
+	assert.Nil(t, ev.DataAgeMs)
+}
+
+// reloadedSampler has its interval modified at runtime, as when the configuration is reloaded.
+type reloadedSampler struct {
+	mockSampler
+	interval int64
+}
+
+func (r *reloadedSampler) Sample() (sample.EventBatch, error) { return eventBatch, nil }
+func (r *reloadedSampler) Interval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.interval))
+}
+func (r *reloadedSampler) Disabled() bool { return r.Interval() <= 0 }
+
+func TestSamplerRoutine_IntervalModified(t *testing.T) {
+	s := &reloadedSampler{interval: int64(time.Millisecond)}
+	sampleQueue := make(chan sample.EventBatch)
+
+	routine := StartSamplerRoutine(s, sampleQueue)
+	defer routine.Stop()
+	<-sampleQueue
+
+	// disabled at runtime, the sample in progress is discarded
+	atomic.StoreInt64(&s.interval, int64(-time.Second))
+	select {
+	case <-sampleQueue:
+	case <-time.After(20 * time.Millisecond):
+	}
+	select {
+	case <-sampleQueue:
+		t.Fatal("disabled sampler should not sample")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// enabled again with another interval
+	atomic.StoreInt64(&s.interval, int64(2*time.Millisecond))
+	select {
+	case batch := <-sampleQueue:
+		assert.Equal(t, eventBatch, batch)
+	case <-time.After(time.Second):
+		t.Fatal("enabled sampler should sample")
+	}
+}
+
+func TestSamplerReports(t *testing.T) {
+	sampleQueue := make(chan sample.EventBatch)
+	routine := StartSamplerRoutine(&mockSampler{}, sampleQueue)
+	<-sampleQueue
+	<-sampleQueue
+
+	reports := SamplerReports()
+	require.Len(t, reports, 1)
+	assert.Equal(t, "MockSampler", reports[0].Name)
+	assert.False(t, reports[0].Disabled)
+	assert.GreaterOrEqual(t, reports[0].Harvests, 2)
+	assert.NotNil(t, reports[0].LastHarvest)
+	// the mock sampler fails every other sample
+	assert.GreaterOrEqual(t, reports[0].Errors, 2)
+	assert.Equal(t, "error", reports[0].LastError)
+
+	routine.Stop()
+	assert.Empty(t, SamplerReports())
+}
+
+// slowSampler takes longer to sample than its interval.
+type slowSampler struct {
+	mockSampler
+}
+
+func (s *slowSampler) Sample() (sample.EventBatch, error) {
+	time.Sleep(30 * time.Millisecond)
+	return eventBatch, nil
+}
+func (s *slowSampler) Name() string            { return "SlowSampler" }
+func (s *slowSampler) Interval() time.Duration { return 10 * time.Millisecond }
+
+func TestSamplerRoutine_SkipsOverrunHarvests(t *testing.T) {
+	sampleQueue := make(chan sample.EventBatch, 10)
+	routine := StartSamplerRoutine(&slowSampler{}, sampleQueue)
+	<-sampleQueue
+	<-sampleQueue
+
+	reports := SamplerReports()
+	routine.Stop()
+
+	require.Len(t, reports, 1)
+	assert.GreaterOrEqual(t, reports[0].Skipped, 2)
+}
+
+func TestNextHarvest(t *testing.T) {
+	start := time.Now()
+	interval := 10 * time.Second
+
+	next, skipped := nextHarvest(start, interval, start.Add(time.Second))
+	assert.Equal(t, start.Add(interval), next)
+	assert.Zero(t, skipped)
+
+	// the harvests at 10s and 20s are skipped
+	next, skipped = nextHarvest(start, interval, start.Add(25*time.Second))
+	assert.Equal(t, start.Add(30*time.Second), next)
+	assert.Equal(t, 2, skipped)
+}
+
+func TestPhase(t *testing.T) {
+	assert.Zero(t, phase(time.Minute, 0))
+	for i := 0; i < 100; i++ {
+		p := phase(time.Minute, 0.1)
+		assert.GreaterOrEqual(t, p, time.Duration(0))
+		assert.Less(t, p, 6*time.Second)
+	}
+}
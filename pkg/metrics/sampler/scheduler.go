@@ -0,0 +1,37 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// Scheduler runs every sampler on its own interval, sending their samples to a shared queue. The routine options,
+// ie: WithJitter, apply to all of them.
+type Scheduler struct {
+	sampleQueue chan sample.EventBatch
+	opts        []RoutineOption
+	routines    []*SamplerRoutine
+}
+
+// NewScheduler creates a scheduler sending the harvested samples to the queue.
+func NewScheduler(sampleQueue chan sample.EventBatch, opts ...RoutineOption) *Scheduler {
+	return &Scheduler{
+		sampleQueue: sampleQueue,
+		opts:        opts,
+	}
+}
+
+// Schedule starts harvesting the sampler.
+func (s *Scheduler) Schedule(sampler Sampler) {
+	mslog.WithField("name", sampler.Name()).WithField("interval", sampler.Interval()).Debug("Scheduling sampler.")
+	s.routines = append(s.routines, StartSamplerRoutine(sampler, s.sampleQueue, s.opts...))
+}
+
+// Stop stops harvesting all the samplers, waiting for their routines to finish.
+func (s *Scheduler) Stop() {
+	for _, sr := range s.routines {
+		sr.Stop()
+	}
+	s.routines = nil
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	sampleQueue := make(chan sample.EventBatch, 10)
+	scheduler := NewScheduler(sampleQueue, WithJitter(0.5))
+	scheduler.Schedule(&reloadedSampler{interval: int64(time.Millisecond)})
+	scheduler.Schedule(&slowSampler{})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case batch := <-sampleQueue:
+			assert.Equal(t, eventBatch, batch)
+		case <-time.After(time.Second):
+			t.Fatal("scheduled samplers should sample")
+		}
+	}
+	assert.Len(t, SamplerReports(), 2)
+
+	scheduler.Stop()
+	assert.Empty(t, SamplerReports())
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package sampler
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	routinesLock sync.Mutex
+	routines     = map[*SamplerRoutine]*routineStatus{}
+)
+
+// SamplerReport represents the harvests of a running sampler and its last error.
+type SamplerReport struct {
+	Name           string     `json:"name"`
+	IntervalSec    int        `json:"interval_sec"`
+	Disabled       bool       `json:"disabled"`
+	Started        time.Time  `json:"started"`
+	Harvests       int        `json:"harvests"`
+	Skipped        int        `json:"skipped"`
+	LastHarvest    *time.Time `json:"last_harvest,omitempty"`
+	LastDurationMs float64    `json:"last_duration_ms"`
+	Errors         int        `json:"errors"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorTime  *time.Time `json:"last_error_time,omitempty"`
+}
+
+// routineStatus is updated by the sampler routine on every tick.
+type routineStatus struct {
+	name         string
+	interval     time.Duration
+	disabled     bool
+	started      time.Time
+	harvests     int
+	skipped      int
+	lastHarvest  time.Time
+	lastDuration time.Duration
+	errors       int
+	lastErr      string
+	lastErrTime  time.Time
+}
+
+func registerRoutine(sr *SamplerRoutine, s Sampler) {
+	routinesLock.Lock()
+	defer routinesLock.Unlock()
+
+	routines[sr] = &routineStatus{
+		name:     sr.name,
+		interval: s.Interval(),
+		disabled: s.Disabled(),
+		started:  time.Now(),
+	}
+}
+
+func unregisterRoutine(sr *SamplerRoutine) {
+	routinesLock.Lock()
+	defer routinesLock.Unlock()
+
+	delete(routines, sr)
+}
+
+// updateRoutine records the settings of the sampler on a tick.
+func updateRoutine(sr *SamplerRoutine, interval time.Duration, disabled bool) {
+	routinesLock.Lock()
+	defer routinesLock.Unlock()
+
+	if st, ok := routines[sr]; ok {
+		st.interval, st.disabled = interval, disabled
+	}
+}
+
+// recordHarvest records the result of sampling, started at the given time.
+func recordHarvest(sr *SamplerRoutine, start time.Time, err error) {
+	routinesLock.Lock()
+	defer routinesLock.Unlock()
+
+	st, ok := routines[sr]
+	if !ok {
+		return
+	}
+	if err != nil {
+		st.errors++
+		st.lastErr, st.lastErrTime = err.Error(), start
+		return
+	}
+	st.harvests++
+	st.lastHarvest, st.lastDuration = start, time.Since(start)
+}
+
+// recordSkipped records the harvests skipped because the previous one took longer than the interval.
+func recordSkipped(sr *SamplerRoutine, skipped int) {
+	routinesLock.Lock()
+	defer routinesLock.Unlock()
+
+	if st, ok := routines[sr]; ok {
+		st.skipped += skipped
+	}
+}
+
+// SamplerReports returns the running samplers, sorted by name.
+func SamplerReports() []SamplerReport {
+	routinesLock.Lock()
+	defer routinesLock.Unlock()
+
+	reports := make([]SamplerReport, 0, len(routines))
+	for _, st := range routines {
+		report := SamplerReport{
+			Name:           st.name,
+			IntervalSec:    int(st.interval / time.Second),
+			Disabled:       st.disabled,
+			Started:        st.started,
+			Harvests:       st.harvests,
+			Skipped:        st.skipped,
+			LastDurationMs: float64(st.lastDuration) / float64(time.Millisecond),
+			Errors:         st.errors,
+		}
+		if !st.lastHarvest.IsZero() {
+			lastHarvest := st.lastHarvest
+			report.LastHarvest = &lastHarvest
+		}
+		if st.lastErr != "" {
+			errTime := st.lastErrTime
+			report.LastError, report.LastErrorTime = st.lastErr, &errTime
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Name < reports[j].Name
+	})
+	return reports
+}
@@ -0,0 +1,181 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package securitymodules
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxAuditReadBytes bounds the audit log read on every sample, the rest being read on the next ones.
+const maxAuditReadBytes = 4 * 1024 * 1024
+
+var (
+	auditIDRegex     = regexp.MustCompile(`audit\((\d+\.\d+:\d+)\)`)
+	selinuxPermRegex = regexp.MustCompile(`avc:\s+denied\s+\{\s*([^}]*?)\s*\}`)
+)
+
+// Fields the audit subsystem hex encodes when they contain spaces, quotes or control characters.
+var auditEncodedFields = map[string]bool{"comm": true, "exe": true, "name": true, "path": true, "profile": true}
+
+// Denial is an access denied by a security module, parsed from an audit record.
+type Denial struct {
+	Module      string
+	AuditID     string
+	ProcessName string
+	ProcessID   int
+	Executable  string
+	Operation   string
+	Permissions string
+	Name        string
+	// SourceLabel is the SELinux context or the AppArmor profile of the process.
+	SourceLabel string
+	TargetLabel string
+	TargetClass string
+	Permissive  bool
+}
+
+// parseDenial parses a SELinux AVC denial or an AppArmor DENIED or ALLOWED record, either from the audit log or
+// from the kernel log.
+func parseDenial(line string) (Denial, bool) {
+	var d Denial
+	if m := auditIDRegex.FindStringSubmatch(line); m != nil {
+		d.AuditID = m[1]
+	}
+
+	switch {
+	case strings.Contains(line, `apparmor="DENIED"`), strings.Contains(line, `apparmor="ALLOWED"`):
+		fields := auditFields(line)
+		d.Module = ModuleAppArmor
+		d.Operation = fields["operation"]
+		d.Permissions = fields["denied_mask"]
+		d.SourceLabel = fields["profile"]
+		d.TargetClass = fields["class"]
+		d.Permissive = fields["apparmor"] == "ALLOWED"
+		d.Name = fields["name"]
+		d.ProcessName = fields["comm"]
+		d.ProcessID, _ = strconv.Atoi(fields["pid"])
+	case selinuxPermRegex.MatchString(line):
+		fields := auditFields(line)
+		d.Module = ModuleSELinux
+		d.Permissions = selinuxPermRegex.FindStringSubmatch(line)[1]
+		d.SourceLabel = fields["scontext"]
+		d.TargetLabel = fields["tcontext"]
+		d.TargetClass = fields["tclass"]
+		d.Permissive = fields["permissive"] == "1"
+		d.Executable = fields["exe"]
+		d.Name = fields["name"]
+		if d.Name == "" {
+			d.Name = fields["path"]
+		}
+		d.ProcessName = fields["comm"]
+		d.ProcessID, _ = strconv.Atoi(fields["pid"])
+	default:
+		return d, false
+	}
+
+	return d, true
+}
+
+// auditFields returns the key=value fields of an audit record, unquoting the quoted values and decoding the
+// hex encoded ones.
+func auditFields(line string) map[string]string {
+	fields := make(map[string]string)
+	for i := 0; i < len(line); {
+		eq := strings.IndexByte(line[i:], '=')
+		if eq < 0 {
+			break
+		}
+		key := line[i : i+eq]
+		if sp := strings.LastIndexAny(key, " \t"); sp >= 0 {
+			key = key[sp+1:]
+		}
+		i += eq + 1
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			end := strings.IndexByte(line[i+1:], '"')
+			if end < 0 {
+				break
+			}
+			value = line[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			end := strings.IndexAny(line[i:], " \t")
+			if end < 0 {
+				end = len(line) - i
+			}
+			value = line[i : i+end]
+			i += end
+			if auditEncodedFields[key] {
+				if decoded, err := hex.DecodeString(value); err == nil {
+					value = string(decoded)
+				}
+			}
+		}
+		if key != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// auditTailer reads the lines appended to the audit log since the previous read. The first read starts from the
+// end of the file, so only the new records are reported, and it starts over when the file is rotated or truncated.
+type auditTailer struct {
+	path    string
+	file    os.FileInfo
+	offset  int64
+	started bool
+}
+
+func newAuditTailer(path string) *auditTailer {
+	return &auditTailer{path: path}
+}
+
+func (t *auditTailer) read() ([]string, error) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return nil, err
+	}
+	if !t.started {
+		t.started = true
+		t.file, t.offset = info, info.Size()
+		return nil, nil
+	}
+	if !os.SameFile(t.file, info) || info.Size() < t.offset {
+		t.offset = 0
+	}
+	t.file = info
+	if info.Size() == t.offset {
+		return nil, nil
+	}
+
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err = f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	r := bufio.NewReader(io.LimitReader(f, maxAuditReadBytes))
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			// an incomplete line is read again on the next call
+			break
+		}
+		t.offset += int64(len(line))
+		lines = append(lines, strings.TrimRight(line, "\r\n"))
+	}
+	return lines, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package securitymodules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDenial(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   Denial
+		denial bool
+	}{
+		{
+			name: "selinux avc",
+			line: `type=AVC msg=audit(1610000000.123:456): avc:  denied  { read open } for  pid=1234 comm="nri-mysql" ` +
+				`path="/root/.my.cnf" dev="sda1" ino=123 scontext=system_u:system_r:unconfined_service_t:s0 ` +
+				`tcontext=unconfined_u:object_r:admin_home_t:s0 tclass=file permissive=0`,
+			want: Denial{
+				Module: ModuleSELinux, AuditID: "1610000000.123:456", ProcessName: "nri-mysql", ProcessID: 1234,
+				Permissions: "read open", Name: "/root/.my.cnf",
+				SourceLabel: "system_u:system_r:unconfined_service_t:s0", TargetLabel: "unconfined_u:object_r:admin_home_t:s0",
+				TargetClass: "file",
+			},
+			denial: true,
+		},
+		{
+			name: "selinux permissive with hex encoded name",
+			line: `type=AVC msg=audit(1610000001.000:457): avc:  denied  { getattr } for  pid=99 comm="newrelic-infra" ` +
+				`name=6D7920636F6E66 exe="/usr/bin/newrelic-infra" scontext=a tcontext=b tclass=dir permissive=1`,
+			want: Denial{
+				Module: ModuleSELinux, AuditID: "1610000001.000:457", ProcessName: "newrelic-infra", ProcessID: 99,
+				Executable: "/usr/bin/newrelic-infra", Permissions: "getattr", Name: "my conf",
+				SourceLabel: "a", TargetLabel: "b", TargetClass: "dir", Permissive: true,
+			},
+			denial: true,
+		},
+		{
+			name: "apparmor from the kernel log",
+			line: `Jan 1 00:00:00 host kernel: [ 12.345] audit: type=1400 audit(1610000002.000:458): apparmor="DENIED" ` +
+				`operation="open" profile="/usr/bin/newrelic-infra" name="/etc/shadow" pid=7 comm="newrelic-infra" ` +
+				`requested_mask="r" denied_mask="r" fsuid=0 ouid=0`,
+			want: Denial{
+				Module: ModuleAppArmor, AuditID: "1610000002.000:458", ProcessName: "newrelic-infra", ProcessID: 7,
+				Operation: "open", Permissions: "r", Name: "/etc/shadow", SourceLabel: "/usr/bin/newrelic-infra",
+			},
+			denial: true,
+		},
+		{
+			name: "apparmor complain",
+			line: `type=AVC msg=audit(1610000003.000:459): apparmor="ALLOWED" operation="exec" profile="nri" ` +
+				`name="/bin/sh" pid=8 comm="nri-flex" requested_mask="x" denied_mask="x"`,
+			want: Denial{
+				Module: ModuleAppArmor, AuditID: "1610000003.000:459", ProcessName: "nri-flex", ProcessID: 8,
+				Operation: "exec", Permissions: "x", Name: "/bin/sh", SourceLabel: "nri", Permissive: true,
+			},
+			denial: true,
+		},
+		{
+			name: "other records",
+			line: `type=SYSCALL msg=audit(1610000000.123:456): arch=c000003e syscall=2 success=no exit=-13 comm="nri-mysql"`,
+		},
+		{
+			name: "apparmor status",
+			line: `type=AVC msg=audit(1610000004.000:460): apparmor="STATUS" operation="profile_load" name="nri"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseDenial(tt.line)
+			assert.Equal(t, tt.denial, ok)
+			if tt.denial {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuditTailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, []byte("old record\n"), 0o600))
+
+	tailer := newAuditTailer(path)
+	// records logged before the first read are skipped
+	lines, err := tailer.read()
+	require.NoError(t, err)
+	assert.Empty(t, lines)
+
+	appendTo(t, path, "first\nsecond\nincompl")
+	lines, err = tailer.read()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, lines)
+
+	appendTo(t, path, "ete\n")
+	lines, err = tailer.read()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incomplete"}, lines)
+
+	// rotated
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("rotated\n"), 0o600))
+	lines, err = tailer.read()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rotated"}, lines)
+
+	// truncated
+	require.NoError(t, os.WriteFile(path, []byte("new\n"), 0o600))
+	lines, err = tailer.read()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new"}, lines)
+}
+
+func appendTo(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
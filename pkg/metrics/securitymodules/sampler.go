@@ -0,0 +1,238 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package securitymodules provides a sampler reporting the status of the SELinux and AppArmor security modules,
+// along with the denials they logged into the audit log for the agent and its integrations.
+package securitymodules
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const (
+	sampleEventType = "SecurityModuleSample"
+	denialEventType = "SecurityModuleDenial"
+
+	ModuleSELinux  = "selinux"
+	ModuleAppArmor = "apparmor"
+
+	// maxDenialsPerSample caps the events reported on every sample, so a process hammering a denied resource
+	// doesn't flood the event queue.
+	maxDenialsPerSample = 100
+)
+
+// defaultDenialProcesses matches the agent, its integrations and the log forwarder. Process names are
+// truncated to 15 characters by the kernel.
+var defaultDenialProcesses = []string{"newrelic-infra*", "nri-*", "fluent-bit"}
+
+var smlog = log.WithComponent("SecurityModuleSampler")
+
+// Status is the state of a security module. Profile counts are nil when they can't be read.
+type Status struct {
+	Module         string
+	Enabled        bool
+	Mode           string
+	ConfiguredMode string
+	Policy         string
+	PolicyVersion  string
+	// AgentLabel is the SELinux context or the AppArmor profile confining the agent.
+	AgentLabel       string
+	AgentLabelMode   string
+	ProfilesEnforced *int
+	ProfilesComplain *int
+}
+
+// SecurityModuleSample reports the status of a single security module.
+type SecurityModuleSample struct {
+	sample.BaseEvent
+
+	SecurityModule   string `json:"securityModule"`
+	Enabled          bool   `json:"enabled"`
+	Mode             string `json:"mode,omitempty"`
+	ConfiguredMode   string `json:"configuredMode,omitempty"`
+	Policy           string `json:"policy,omitempty"`
+	PolicyVersion    string `json:"policyVersion,omitempty"`
+	AgentLabel       string `json:"agentLabel,omitempty"`
+	AgentLabelMode   string `json:"agentLabelMode,omitempty"`
+	ProfilesEnforced *int   `json:"profilesEnforced,omitempty"`
+	ProfilesComplain *int   `json:"profilesComplain,omitempty"`
+}
+
+// SecurityModuleDenial is emitted for every access denied by a security module to a watched process. Accesses
+// only logged by SELinux permissive domains or AppArmor complain profiles are reported as permissive.
+type SecurityModuleDenial struct {
+	sample.BaseEvent
+
+	SecurityModule string `json:"securityModule"`
+	AuditID        string `json:"auditId,omitempty"`
+	ProcessName    string `json:"processName"`
+	ProcessID      int    `json:"processId,omitempty"`
+	Executable     string `json:"executable,omitempty"`
+	Operation      string `json:"operation,omitempty"`
+	Permissions    string `json:"permissions,omitempty"`
+	Name           string `json:"name,omitempty"`
+	SourceLabel    string `json:"sourceLabel,omitempty"`
+	TargetLabel    string `json:"targetLabel,omitempty"`
+	TargetClass    string `json:"targetClass,omitempty"`
+	Permissive     bool   `json:"permissive"`
+}
+
+// Sampler reports a SecurityModuleSample per security module available in the kernel and, when the denials are
+// enabled, a SecurityModuleDenial event per denial appended to the audit log since the previous sample.
+type Sampler struct {
+	interval  time.Duration
+	statusFn  func() []Status
+	auditLog  *auditTailer
+	processes []string
+}
+
+// NewSampler creates a security modules sampler.
+func NewSampler(ctx agent.AgentContext) *Sampler {
+	interval := config.FREQ_DISABLE_SAMPLING
+	var auditLog *auditTailer
+	var processes []string
+	if ctx != nil {
+		cfg := ctx.Config()
+		interval = cfg.MetricsSecurityModuleSampleRate
+		if cfg.SecurityDenialsEnabled {
+			path := cfg.SecurityDenialsAuditLog
+			if path == "" {
+				path = helpers.HostVar("log", "audit", "audit.log")
+			}
+			auditLog = newAuditTailer(path)
+		}
+		processes = cfg.SecurityDenialsProcesses
+	}
+
+	return newSampler(time.Second*time.Duration(interval), readStatus, auditLog, processes)
+}
+
+func newSampler(interval time.Duration, statusFn func() []Status, auditLog *auditTailer, processes []string) *Sampler {
+	return &Sampler{
+		interval:  interval,
+		statusFn:  statusFn,
+		auditLog:  auditLog,
+		processes: append(append([]string{}, defaultDenialProcesses...), processes...),
+	}
+}
+
+// Sample returns the status of the security modules, followed by the new denials.
+func (s *Sampler) Sample() (sample.EventBatch, error) {
+	var batch sample.EventBatch
+	for _, st := range s.statusFn() {
+		batch = append(batch, moduleSample(st))
+	}
+
+	if s.auditLog == nil {
+		return batch, nil
+	}
+
+	lines, err := s.auditLog.read()
+	if err != nil {
+		smlog.WithError(err).WithField("path", s.auditLog.path).Debug("Cannot read the audit log.")
+		return batch, nil
+	}
+	var reported, dropped int
+	for _, line := range lines {
+		d, ok := parseDenial(line)
+		if !ok || !s.watched(d) {
+			continue
+		}
+		if reported >= maxDenialsPerSample {
+			dropped++
+			continue
+		}
+		reported++
+		batch = append(batch, denialEvent(d))
+	}
+	if dropped > 0 {
+		smlog.WithField("dropped", dropped).Warn("Too many security module denials, some were not reported.")
+	}
+
+	return batch, nil
+}
+
+func moduleSample(st Status) *SecurityModuleSample {
+	ms := &SecurityModuleSample{
+		SecurityModule:   st.Module,
+		Enabled:          st.Enabled,
+		Mode:             st.Mode,
+		ConfiguredMode:   st.ConfiguredMode,
+		Policy:           st.Policy,
+		PolicyVersion:    st.PolicyVersion,
+		AgentLabel:       st.AgentLabel,
+		AgentLabelMode:   st.AgentLabelMode,
+		ProfilesEnforced: st.ProfilesEnforced,
+		ProfilesComplain: st.ProfilesComplain,
+	}
+	ms.Type(sampleEventType)
+
+	return ms
+}
+
+func denialEvent(d Denial) *SecurityModuleDenial {
+	e := &SecurityModuleDenial{
+		SecurityModule: d.Module,
+		AuditID:        d.AuditID,
+		ProcessName:    d.ProcessName,
+		ProcessID:      d.ProcessID,
+		Executable:     d.Executable,
+		Operation:      d.Operation,
+		Permissions:    d.Permissions,
+		Name:           d.Name,
+		SourceLabel:    d.SourceLabel,
+		TargetLabel:    d.TargetLabel,
+		TargetClass:    d.TargetClass,
+		Permissive:     d.Permissive,
+	}
+	e.Type(denialEventType)
+
+	return e
+}
+
+// watched returns whether the denial affects one of the watched processes, matched by process or executable name.
+func (s *Sampler) watched(d Denial) bool {
+	names := []string{d.ProcessName}
+	if d.Executable != "" {
+		names = append(names, filepath.Base(d.Executable))
+	}
+	for _, pattern := range s.processes {
+		for _, name := range names {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OnStartup logs whether the denials are watched.
+func (s *Sampler) OnStartup() {
+	entry := smlog.WithField("denials", s.auditLog != nil)
+	if s.auditLog != nil {
+		entry = entry.WithField("auditLog", s.auditLog.path).WithField("processes", s.processes)
+	}
+	entry.Debug("Starting security modules sampler.")
+}
+
+// Name returns the sampler name.
+func (s *Sampler) Name() string {
+	return "SecurityModuleSampler"
+}
+
+// Interval returns the sampling interval.
+func (s *Sampler) Interval() time.Duration {
+	return s.interval
+}
+
+// Disabled returns true when sampling is disabled.
+func (s *Sampler) Disabled() bool {
+	return s.Interval() <= config.FREQ_DISABLE_SAMPLING
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package securitymodules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampler_Sample(t *testing.T) {
+	enforced, complain := 12, 1
+	status := func() []Status {
+		return []Status{
+			{Module: ModuleSELinux, Enabled: true, Mode: "enforcing", ConfiguredMode: "enforcing", Policy: "targeted",
+				PolicyVersion: "33", AgentLabel: "system_u:system_r:unconfined_service_t:s0"},
+			{Module: ModuleAppArmor, Enabled: true, Mode: "enabled", ProfilesEnforced: &enforced, ProfilesComplain: &complain},
+		}
+	}
+
+	s := newSampler(30*time.Second, status, nil, nil)
+	assert.False(t, s.Disabled())
+
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+
+	selinux := batch[0].(*SecurityModuleSample)
+	assert.Equal(t, sampleEventType, selinux.EventType)
+	assert.Equal(t, ModuleSELinux, selinux.SecurityModule)
+	assert.Equal(t, "enforcing", selinux.Mode)
+	assert.Equal(t, "targeted", selinux.Policy)
+	assert.Equal(t, "system_u:system_r:unconfined_service_t:s0", selinux.AgentLabel)
+	assert.Nil(t, selinux.ProfilesEnforced)
+
+	apparmor := batch[1].(*SecurityModuleSample)
+	assert.Equal(t, ModuleAppArmor, apparmor.SecurityModule)
+	assert.Equal(t, &enforced, apparmor.ProfilesEnforced)
+	assert.Equal(t, &complain, apparmor.ProfilesComplain)
+}
+
+func TestSampler_Denials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	s := newSampler(30*time.Second, func() []Status { return nil }, newAuditTailer(path), []string{"mysqld*"})
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	assert.Empty(t, batch)
+
+	appendTo(t, path, strings.Join([]string{
+		`type=AVC msg=audit(1610000000.123:1): avc:  denied  { read } for  pid=1 comm="nri-mysql" name="f" scontext=a tcontext=b tclass=file permissive=0`,
+		`type=AVC msg=audit(1610000000.123:2): avc:  denied  { read } for  pid=2 comm="sshd" name="f" scontext=a tcontext=b tclass=file permissive=0`,
+		`type=AVC msg=audit(1610000000.123:3): apparmor="DENIED" operation="open" profile="mysqld" name="/data" pid=3 comm="mysqld"`,
+		`type=AVC msg=audit(1610000000.123:4): avc:  denied  { write } for  pid=4 comm="java" exe="/usr/bin/newrelic-infra-service" scontext=a tcontext=b tclass=dir permissive=0`,
+		`type=SYSCALL msg=audit(1610000000.123:1): arch=c000003e syscall=2 success=no comm="nri-mysql"`,
+	}, "\n")+"\n")
+
+	batch, err = s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+
+	first := batch[0].(*SecurityModuleDenial)
+	assert.Equal(t, denialEventType, first.EventType)
+	assert.Equal(t, ModuleSELinux, first.SecurityModule)
+	assert.Equal(t, "nri-mysql", first.ProcessName)
+	assert.Equal(t, "1610000000.123:1", first.AuditID)
+	assert.Equal(t, "mysqld", batch[1].(*SecurityModuleDenial).ProcessName)
+	assert.Equal(t, "/usr/bin/newrelic-infra-service", batch[2].(*SecurityModuleDenial).Executable)
+}
+
+func TestSampler_DenialsCapped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	status := func() []Status { return []Status{{Module: ModuleSELinux}} }
+	s := newSampler(30*time.Second, status, newAuditTailer(path), nil)
+	_, err := s.Sample()
+	require.NoError(t, err)
+
+	line := `type=AVC msg=audit(1.0:1): avc:  denied  { read } for  pid=1 comm="nri-flex" scontext=a tcontext=b tclass=file` + "\n"
+	appendTo(t, path, strings.Repeat(line, maxDenialsPerSample+10))
+
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	assert.Len(t, batch, maxDenialsPerSample+1)
+}
+
+func TestSampler_Disabled(t *testing.T) {
+	assert.True(t, NewSampler(nil).Disabled())
+}
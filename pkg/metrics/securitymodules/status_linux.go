@@ -0,0 +1,154 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package securitymodules
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+// statusReader reads the security modules status from their kernel interfaces and configuration files.
+type statusReader struct {
+	sysPath  string
+	etcPath  string
+	procSelf string
+}
+
+func readStatus() []Status {
+	r := statusReader{sysPath: helpers.HostSys(), etcPath: helpers.HostEtc(), procSelf: "/proc/self"}
+	return r.read()
+}
+
+func (r statusReader) read() []Status {
+	var statuses []Status
+	selinux, ok := r.selinux()
+	if ok {
+		statuses = append(statuses, selinux)
+	}
+	if apparmor, ok := r.apparmor(selinux.Enabled); ok {
+		statuses = append(statuses, apparmor)
+	}
+	return statuses
+}
+
+// selinux reports SELinux when its filesystem is mounted or it's configured, ie: disabled on boot.
+func (r statusReader) selinux() (Status, bool) {
+	st := Status{Module: ModuleSELinux}
+
+	config, configErr := readKeyValues(filepath.Join(r.etcPath, "selinux", "config"))
+	st.ConfiguredMode = config["SELINUX"]
+	st.Policy = config["SELINUXTYPE"]
+
+	fs := filepath.Join(r.sysPath, "fs", "selinux")
+	enforce, err := readTrimmed(filepath.Join(fs, "enforce"))
+	if err != nil {
+		if configErr != nil {
+			return st, false
+		}
+		st.Mode = "disabled"
+		return st, true
+	}
+
+	st.Enabled = true
+	st.Mode = "permissive"
+	if enforce == "1" {
+		st.Mode = "enforcing"
+	}
+	st.PolicyVersion, _ = readTrimmed(filepath.Join(fs, "policyvers"))
+	st.AgentLabel, _ = readTrimmed(filepath.Join(r.procSelf, "attr", "current"))
+	return st, true
+}
+
+// apparmor reports AppArmor when its kernel module is present. The profile confining the agent is read from the
+// generic LSM attribute when the kernel doesn't provide the AppArmor one and SELinux doesn't own the generic one.
+func (r statusReader) apparmor(selinuxEnabled bool) (Status, bool) {
+	st := Status{Module: ModuleAppArmor}
+
+	enabled, err := readTrimmed(filepath.Join(r.sysPath, "module", "apparmor", "parameters", "enabled"))
+	if err != nil {
+		return st, false
+	}
+	st.Enabled = enabled == "Y"
+	if !st.Enabled {
+		st.Mode = "disabled"
+		return st, true
+	}
+	st.Mode = "enabled"
+
+	if enforced, complain, err := countProfiles(filepath.Join(r.sysPath, "kernel", "security", "apparmor", "profiles")); err == nil {
+		st.ProfilesEnforced, st.ProfilesComplain = &enforced, &complain
+	}
+
+	label, err := readTrimmed(filepath.Join(r.procSelf, "attr", "apparmor", "current"))
+	if err != nil && !selinuxEnabled {
+		label, err = readTrimmed(filepath.Join(r.procSelf, "attr", "current"))
+	}
+	if err == nil {
+		st.AgentLabel, st.AgentLabelMode = splitProfile(label)
+	}
+	return st, true
+}
+
+// countProfiles counts the loaded profiles in enforce and complain mode, listed as "name (mode)".
+func countProfiles(path string) (enforced, complain int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		switch _, mode := splitProfile(scanner.Text()); mode {
+		case "enforce":
+			enforced++
+		case "complain":
+			complain++
+		}
+	}
+	return enforced, complain, scanner.Err()
+}
+
+// splitProfile splits an AppArmor label as "name (mode)". Unconfined processes are labeled "unconfined".
+func splitProfile(label string) (name, mode string) {
+	label = strings.TrimSpace(label)
+	if i := strings.LastIndex(label, " ("); i >= 0 && strings.HasSuffix(label, ")") {
+		return label[:i], label[i+2 : len(label)-1]
+	}
+	return label, ""
+}
+
+// readKeyValues reads a KEY=value file, skipping comments.
+func readKeyValues(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return values, nil
+}
+
+// readTrimmed reads a kernel attribute, which may be NUL terminated.
+func readTrimmed(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimRight(string(content), "\x00")), nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package securitymodules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestStatusReader_SELinux(t *testing.T) {
+	root := t.TempDir()
+	r := statusReader{sysPath: filepath.Join(root, "sys"), etcPath: filepath.Join(root, "etc"), procSelf: filepath.Join(root, "self")}
+	writeFile(t, filepath.Join(r.etcPath, "selinux", "config"), "# comment\nSELINUX=enforcing\nSELINUXTYPE=targeted\n")
+	writeFile(t, filepath.Join(r.sysPath, "fs", "selinux", "enforce"), "0")
+	writeFile(t, filepath.Join(r.sysPath, "fs", "selinux", "policyvers"), "33\n")
+	writeFile(t, filepath.Join(r.procSelf, "attr", "current"), "system_u:system_r:unconfined_service_t:s0\x00")
+
+	assert.Equal(t, []Status{{
+		Module:         ModuleSELinux,
+		Enabled:        true,
+		Mode:           "permissive",
+		ConfiguredMode: "enforcing",
+		Policy:         "targeted",
+		PolicyVersion:  "33",
+		AgentLabel:     "system_u:system_r:unconfined_service_t:s0",
+	}}, r.read())
+
+	// disabled on boot
+	require.NoError(t, os.RemoveAll(filepath.Join(r.sysPath, "fs")))
+	assert.Equal(t, []Status{{
+		Module:         ModuleSELinux,
+		Mode:           "disabled",
+		ConfiguredMode: "enforcing",
+		Policy:         "targeted",
+	}}, r.read())
+}
+
+func TestStatusReader_AppArmor(t *testing.T) {
+	root := t.TempDir()
+	r := statusReader{sysPath: filepath.Join(root, "sys"), etcPath: filepath.Join(root, "etc"), procSelf: filepath.Join(root, "self")}
+	writeFile(t, filepath.Join(r.sysPath, "module", "apparmor", "parameters", "enabled"), "Y\n")
+	writeFile(t, filepath.Join(r.sysPath, "kernel", "security", "apparmor", "profiles"),
+		"/usr/sbin/cupsd (enforce)\nnri-flex (complain)\n/usr/bin/man (enforce)\nlibreoffice-oopslash (complain)\n")
+	writeFile(t, filepath.Join(r.procSelf, "attr", "current"), "/usr/bin/newrelic-infra (complain)\n")
+
+	enforced, complain := 2, 2
+	assert.Equal(t, []Status{{
+		Module:           ModuleAppArmor,
+		Enabled:          true,
+		Mode:             "enabled",
+		AgentLabel:       "/usr/bin/newrelic-infra",
+		AgentLabelMode:   "complain",
+		ProfilesEnforced: &enforced,
+		ProfilesComplain: &complain,
+	}}, r.read())
+
+	// the AppArmor attribute is preferred
+	writeFile(t, filepath.Join(r.procSelf, "attr", "apparmor", "current"), "unconfined\n")
+	st := r.read()
+	require.Len(t, st, 1)
+	assert.Equal(t, "unconfined", st[0].AgentLabel)
+	assert.Empty(t, st[0].AgentLabelMode)
+
+	writeFile(t, filepath.Join(r.sysPath, "module", "apparmor", "parameters", "enabled"), "N\n")
+	assert.Equal(t, []Status{{Module: ModuleAppArmor, Mode: "disabled"}}, r.read())
+}
+
+func TestStatusReader_None(t *testing.T) {
+	root := t.TempDir()
+	r := statusReader{sysPath: filepath.Join(root, "sys"), etcPath: filepath.Join(root, "etc"), procSelf: filepath.Join(root, "self")}
+	assert.Empty(t, r.read())
+}
@@ -0,0 +1,11 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build !linux
+// +build !linux
+
+package securitymodules
+
+// readStatus returns no status, as SELinux and AppArmor are only available on linux.
+func readStatus() []Status {
+	return nil
+}
@@ -66,8 +66,9 @@ func (s *Sender) Start() (err error) {
 	return
 }
 
-// Stop will gracefully shut down all sending processes and reset the state of the sender.
-// After Stop() returns, it is safe to call Start() again on the same sender instance.
+// Stop will gracefully shut down all sending processes and reset the state of the sender, closing the samplers
+// implementing sampler.Closer. After Stop() returns, it is safe to call Start() again on the same sender instance,
+// as long as no sampler implements sampler.Closer.
 func (s *Sender) Stop() (err error) {
 	if s.stopChannel == nil {
 		return fmt.Errorf("Cannot stop sender: The sender is not running. (stopChannel is nil)")
@@ -77,34 +78,71 @@ func (s *Sender) Stop() (err error) {
 	s.internalRoutineWaits.Wait()
 	s.stopChannel = nil
 
+	for _, t := range s.samplers {
+		if closer, ok := t.(sampler.Closer); ok {
+			if cErr := closer.Close(); cErr != nil {
+				slog.WithError(cErr).WithField("sampler", t.Name()).Warn("can't close sampler")
+			}
+		}
+	}
+
 	return
 }
 
 // Periodically gather all samples and send them to Insights
 func (s *Sender) scheduleSamplers() {
-	var samplerRoutines []*sampler.SamplerRoutine
+	var opts []sampler.RoutineOption
+	if s.ctx != nil && s.ctx.Config() != nil {
+		cfg := s.ctx.Config()
+		if cfg.EnableSampleMetaAttributes {
+			opts = append(opts, sampler.WithMetaAttributes())
+		}
+		opts = append(opts, sampler.WithJitter(float64(cfg.MetricsSampleJitterPercent)/100))
+	}
 
+	scheduler := sampler.NewScheduler(s.sampleQueue, opts...)
 	for _, t := range s.samplers {
 		slog.WithField("sampler", t.Name()).Debug("Starting sampler")
-		sr := sampler.StartSamplerRoutine(t, s.sampleQueue)
-		samplerRoutines = append(samplerRoutines, sr)
+		scheduler.Schedule(t)
 	}
 
 	for {
 		select {
 		case samples := <-s.sampleQueue:
-			now := time.Now().Unix()
-			for _, e := range samples {
-				e.Timestamp(now)
-				s.ctx.SendEvent(e, "")
-			}
+			s.sendSamples(samples)
 
 		case <-s.stopChannel:
 			// Stop channel has been closed - exit.
-			for _, sr := range samplerRoutines {
-				sr.Stop()
+			scheduler.Stop()
+			// the samples already harvested are sent, so they can be flushed on shutdown
+			for len(s.sampleQueue) > 0 {
+				s.sendSamples(<-s.sampleQueue)
 			}
 			return
 		}
 	}
 }
+
+// SampleOnce harvests every registered sampler one time, sending their samples, and returns the number of samplers
+// failing. It's used by the dry-run mode instead of running the sender.
+func (s *Sender) SampleOnce() (failed int) {
+	for _, t := range s.samplers {
+		t.OnStartup()
+		samples, err := t.Sample()
+		if err != nil {
+			slog.WithError(err).WithField("sampler", t.Name()).Error("can't get sample from sampler")
+			failed++
+			continue
+		}
+		s.sendSamples(samples)
+	}
+	return failed
+}
+
+func (s *Sender) sendSamples(samples sample.EventBatch) {
+	now := time.Now().Unix()
+	for _, e := range samples {
+		e.Timestamp(now)
+		s.ctx.SendEvent(e, "")
+	}
+}
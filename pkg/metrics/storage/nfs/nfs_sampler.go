@@ -166,7 +166,11 @@ func (s *Sampler) Name() string {
 	return "NFSSampler"
 }
 
+// Interval is read from the running configuration, as it's modified when the configuration is reloaded.
 func (s *Sampler) Interval() time.Duration {
+	if s.context != nil && s.context.Config() != nil {
+		return time.Second * time.Duration(s.context.Config().GetMetricsNFSSampleRate())
+	}
 	return s.sampleRate
 }
 
@@ -200,7 +204,7 @@ func NewSampler(context agent.AgentContext) *Sampler {
 	sampleRateSec := config.DefaultMetricsNFSSampleRate
 	detailed := false
 	if context != nil {
-		sampleRateSec = context.Config().MetricsNFSSampleRate
+		sampleRateSec = context.Config().GetMetricsNFSSampleRate()
 		detailed = context.Config().DetailedNFS
 	}
 
@@ -52,6 +52,7 @@ type BaseSample struct {
 	ReadWriteBytesPerSecond *float64 `json:"readWriteBytesPerSecond,omitempty"`
 	ReadsPerSec             *float64 `json:"readIoPerSecond,omitempty"`
 	WritesPerSec            *float64 `json:"writeIoPerSecond,omitempty"`
+	DiskFullInHours         *float64 `json:"diskFullInHours,omitempty"`
 	IOTimeDelta             uint64   `json:"-"`
 	ReadTimeDelta           uint64   `json:"-"`
 	WriteTimeDelta          uint64   `json:"-"`
@@ -84,6 +85,7 @@ type Sampler struct {
 	waitForCleanup   *sync.WaitGroup
 	storageUtilities SampleWrapper
 	sampleRate       time.Duration
+	trend            *trendCalculator
 }
 
 type SampleWrapper interface {
@@ -96,7 +98,7 @@ type SampleWrapper interface {
 func NewSampler(context agent.AgentContext) *Sampler {
 	sampleRateSec := config.DefaultStorageSamplerRateSecs
 	if context != nil {
-		sampleRateSec = context.Config().MetricsStorageSampleRate
+		sampleRateSec = context.Config().GetMetricsStorageSampleRate()
 	}
 
 	return &Sampler{
@@ -104,9 +106,25 @@ func NewSampler(context agent.AgentContext) *Sampler {
 		waitForCleanup:   &sync.WaitGroup{},
 		storageUtilities: NewStorageSampleWrapper(context.Config()),
 		sampleRate:       time.Second * time.Duration(sampleRateSec),
+		trend:            newTrendCalculatorFromConfig(context),
 	}
 }
 
+// newTrendCalculatorFromConfig returns nil when disk full estimations are disabled.
+func newTrendCalculatorFromConfig(context agent.AgentContext) *trendCalculator {
+	if context == nil || context.Config().StorageTrendWindow == "" {
+		return nil
+	}
+
+	window, err := time.ParseDuration(context.Config().StorageTrendWindow)
+	if err != nil || window <= 0 {
+		// should never happen, as the format is checked during NormalizeConfig
+		return nil
+	}
+
+	return newTrendCalculator(window)
+}
+
 func (ss *Sampler) useCustomSupportedFileSystems() {
 	if ss.context != nil {
 		customSupportedFileSystems := ss.context.Config().CustomSupportedFileSystems
@@ -137,7 +155,11 @@ func (p *PartitionStat) IsReadOnly() bool {
 	return false
 }
 
+// Interval is read from the running configuration, as it's modified when the configuration is reloaded.
 func (ss *Sampler) Interval() time.Duration {
+	if ss.context != nil && ss.context.Config() != nil {
+		return time.Second * time.Duration(ss.context.Config().GetMetricsStorageSampleRate())
+	}
 	return ss.sampleRate
 }
 
@@ -319,6 +341,10 @@ func (ss *Sampler) Sample() (samples sample.EventBatch, err error) {
 			samples = append(samples, s)
 		}
 	}
+
+	if ss.trend != nil {
+		ss.trend.apply(now, samples)
+	}
 	ss.lastSamples = samples
 
 	for _, s := range samples {
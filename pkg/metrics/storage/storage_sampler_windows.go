@@ -12,6 +12,7 @@ import (
 	"unsafe"
 
 	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
 	"github.com/pkg/errors"
 	"github.com/shirou/gopsutil/v3/disk"
 )
@@ -39,6 +40,8 @@ type WinStorageSampleWrapper struct {
 	legacy      bool
 	partitions  PartitionsCache
 	pdhCounters PdhIoCounters
+	// Windows containers expose neither the disk performance counters nor their WMI classes.
+	noIOCounters bool
 }
 
 func (ssw *WinStorageSampleWrapper) Partitions() ([]PartitionStat, error) {
@@ -50,6 +53,9 @@ func (ssw *WinStorageSampleWrapper) Usage(path string) (*disk.UsageStat, error)
 }
 
 func (ssw *WinStorageSampleWrapper) IOCounters() (map[string]IOCountersStat, error) {
+	if ssw.noIOCounters {
+		return map[string]IOCountersStat{}, nil
+	}
 	// This will be removed in future agent versions. By now, pdh can be optionally disabled
 	if !ssw.legacy {
 		partitions, err := ssw.partitions.Get()
@@ -94,7 +100,8 @@ func NewStorageSampleWrapper(cfg *config.Config) SampleWrapper {
 			isContainerized: cfg != nil && cfg.IsContainerized,
 			partitionsFunc:  fetchPartitions(cfg.WinRemovableDrives),
 		},
-		pdhCounters: PdhIoCounters{},
+		pdhCounters:  PdhIoCounters{},
+		noIOCounters: cfg.HostEnvironment == helpers.EnvironmentWindowsContainer,
 	}
 	return &ssw
 }
@@ -0,0 +1,108 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package storage
+
+import (
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+// minTrendPoints is the minimum amount of samples in the window to estimate a trend.
+const minTrendPoints = 3
+
+type usagePoint struct {
+	time      time.Time
+	usedBytes float64
+}
+
+// trendCalculator estimates when mount points will run out of space, by fitting a linear
+// regression on the used bytes within a rolling window of samples.
+type trendCalculator struct {
+	window time.Duration
+	// key: mount point
+	points map[string][]usagePoint
+}
+
+func newTrendCalculator(window time.Duration) *trendCalculator {
+	return &trendCalculator{
+		window: window,
+		points: make(map[string][]usagePoint),
+	}
+}
+
+// apply records the usage of the storage samples and decorates them with the diskFullInHours
+// estimation. Estimations are only provided for mount points whose usage grows within the window.
+func (t *trendCalculator) apply(now time.Time, samples sample.EventBatch) {
+	seen := make(map[string]bool, len(samples))
+
+	for _, s := range samples {
+		ss, ok := s.(*Sample)
+		if !ok || ss.UsedBytes == nil {
+			continue
+		}
+		seen[ss.MountPoint] = true
+
+		points := append(t.points[ss.MountPoint], usagePoint{time: now, usedBytes: *ss.UsedBytes})
+		t.points[ss.MountPoint] = t.prune(now, points)
+
+		if ss.FreeBytes != nil {
+			ss.DiskFullInHours = t.estimate(t.points[ss.MountPoint], *ss.FreeBytes)
+		}
+	}
+
+	// forget unmounted partitions
+	for mountPoint := range t.points {
+		if !seen[mountPoint] {
+			delete(t.points, mountPoint)
+		}
+	}
+}
+
+// prune drops the points out of the window.
+func (t *trendCalculator) prune(now time.Time, points []usagePoint) []usagePoint {
+	from := now.Add(-t.window)
+	i := 0
+	for i < len(points) && points[i].time.Before(from) {
+		i++
+	}
+	return points[i:]
+}
+
+// estimate returns the hours left until freeBytes are used, nil if usage is not growing or there
+// is not enough data.
+func (t *trendCalculator) estimate(points []usagePoint, freeBytes float64) *float64 {
+	if len(points) < minTrendPoints {
+		return nil
+	}
+
+	bytesPerSecond, ok := growthRate(points)
+	if !ok || bytesPerSecond <= 0 {
+		return nil
+	}
+
+	hours := freeBytes / bytesPerSecond / time.Hour.Seconds()
+	return asValidFloatPtr(&hours)
+}
+
+// growthRate returns the least squares slope of the used bytes, in bytes per second.
+func growthRate(points []usagePoint) (float64, bool) {
+	origin := points[0].time
+	n := float64(len(points))
+
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := p.time.Sub(origin).Seconds()
+		sumX += x
+		sumY += p.usedBytes
+		sumXY += x * p.usedBytes
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+func storageSample(mountPoint string, used, free float64) *Sample {
+	s := &Sample{}
+	s.MountPoint = mountPoint
+	s.UsedBytes = &used
+	s.FreeBytes = &free
+	return s
+}
+
+func TestTrendCalculator_Growing(t *testing.T) {
+	tc := newTrendCalculator(time.Hour)
+	now := time.Date(2022, 6, 10, 15, 0, 0, 0, time.UTC)
+
+	// 1000 bytes per minute growth
+	var s *Sample
+	for i := 0; i < 3; i++ {
+		s = storageSample("/", float64(1000*i), 120000-float64(1000*i))
+		tc.apply(now.Add(time.Duration(i)*time.Minute), sample.EventBatch{s})
+	}
+
+	require.NotNil(t, s.DiskFullInHours)
+	// 118000 free bytes at 1000 bytes per minute
+	assert.InDelta(t, 118.0/60, *s.DiskFullInHours, 0.0001)
+}
+
+func TestTrendCalculator_NotEnoughPoints(t *testing.T) {
+	tc := newTrendCalculator(time.Hour)
+	now := time.Now()
+
+	s := storageSample("/", 1, 100)
+	tc.apply(now, sample.EventBatch{s})
+	assert.Nil(t, s.DiskFullInHours)
+
+	s = storageSample("/", 2, 99)
+	tc.apply(now.Add(time.Minute), sample.EventBatch{s})
+	assert.Nil(t, s.DiskFullInHours)
+}
+
+func TestTrendCalculator_NotGrowing(t *testing.T) {
+	tests := []struct {
+		name string
+		used []float64
+	}{
+		{"flat", []float64{10, 10, 10}},
+		{"shrinking", []float64{30, 20, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTrendCalculator(time.Hour)
+			now := time.Now()
+
+			var s *Sample
+			for i, used := range tt.used {
+				s = storageSample("/", used, 100)
+				tc.apply(now.Add(time.Duration(i)*time.Minute), sample.EventBatch{s})
+			}
+			assert.Nil(t, s.DiskFullInHours)
+		})
+	}
+}
+
+func TestTrendCalculator_RollingWindow(t *testing.T) {
+	tc := newTrendCalculator(10 * time.Minute)
+	now := time.Now()
+
+	for i := 0; i < 20; i++ {
+		tc.apply(now.Add(time.Duration(i)*time.Minute), sample.EventBatch{storageSample("/", float64(i), 100)})
+	}
+
+	// points older than 10 minutes are dropped
+	assert.Len(t, tc.points["/"], 11)
+}
+
+func TestTrendCalculator_ForgetsUnmounted(t *testing.T) {
+	tc := newTrendCalculator(time.Hour)
+	now := time.Now()
+
+	tc.apply(now, sample.EventBatch{storageSample("/", 1, 100), storageSample("/data", 1, 100)})
+	tc.apply(now.Add(time.Minute), sample.EventBatch{storageSample("/", 2, 100)})
+
+	assert.Contains(t, tc.points, "/")
+	assert.NotContains(t, tc.points, "/data")
+}
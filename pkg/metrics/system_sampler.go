@@ -57,7 +57,7 @@ func NewSystemSampler(context agent.AgentContext, storageSampler *storage.Sample
 
 func (s *SystemSampler) sampleInterval() int {
 	if s.context != nil {
-		return s.context.Config().MetricsSystemSampleRate
+		return s.context.Config().GetMetricsSystemSampleRate()
 	}
 	return config.FREQ_INTERVAL_FLOOR_SYSTEM_METRICS
 }
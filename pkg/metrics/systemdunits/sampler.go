@@ -0,0 +1,185 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package systemdunits provides a sampler reporting the state and resource accounting of selected
+// systemd units, retrieved through D-Bus.
+package systemdunits
+
+import (
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const (
+	sampleEventType = "SystemdUnitSample"
+	failedEventType = "SystemdUnitFailed"
+
+	activeStateFailed = "failed"
+)
+
+var sslog = log.WithComponent("SystemdUnitSampler")
+
+// Unit is the status of a systemd unit. Accounting values are nil when not available for the unit.
+type Unit struct {
+	Name        string
+	Description string
+	LoadState   string
+	ActiveState string
+	SubState    string
+	MainPID     uint32
+	// NRestarts is only available for service units.
+	NRestarts     *uint32
+	MemoryCurrent *uint64
+	CPUUsage      *time.Duration
+}
+
+// SystemdUnitSample reports the state of a single systemd unit.
+type SystemdUnitSample struct {
+	sample.BaseEvent
+
+	UnitName           string   `json:"unitName"`
+	Description        string   `json:"description"`
+	LoadState          string   `json:"loadState"`
+	ActiveState        string   `json:"activeState"`
+	SubState           string   `json:"subState"`
+	MainPID            uint32   `json:"mainPid,omitempty"`
+	RestartCount       *uint32  `json:"restartCount,omitempty"`
+	MemoryCurrentBytes *uint64  `json:"memoryCurrentBytes,omitempty"`
+	CPUUsageSeconds    *float64 `json:"cpuUsageSeconds,omitempty"`
+}
+
+// SystemdUnitFailed is emitted when a unit enters the failed state.
+type SystemdUnitFailed struct {
+	sample.BaseEvent
+
+	UnitName     string  `json:"unitName"`
+	Description  string  `json:"description"`
+	SubState     string  `json:"subState"`
+	RestartCount *uint32 `json:"restartCount,omitempty"`
+}
+
+// Sampler reports a SystemdUnitSample per unit matching the configured patterns, plus a
+// SystemdUnitFailed event whenever one of them enters the failed state.
+type Sampler struct {
+	interval time.Duration
+	patterns []string
+	failed   map[string]bool
+	listFn   func(patterns []string) ([]Unit, error)
+}
+
+// NewSampler creates a systemd units sampler.
+func NewSampler(ctx agent.AgentContext) *Sampler {
+	interval := config.FREQ_DISABLE_SAMPLING
+	var patterns []string
+	if ctx != nil {
+		interval = ctx.Config().MetricsSystemdUnitSampleRate
+		patterns = ctx.Config().SystemdUnits
+	}
+
+	return newSampler(time.Second*time.Duration(interval), patterns, newDbusLister().list)
+}
+
+func newSampler(interval time.Duration, patterns []string, listFn func([]string) ([]Unit, error)) *Sampler {
+	return &Sampler{
+		interval: interval,
+		patterns: patterns,
+		failed:   make(map[string]bool),
+		listFn:   listFn,
+	}
+}
+
+// Sample returns the current state of the selected units, followed by the failure events.
+func (s *Sampler) Sample() (sample.EventBatch, error) {
+	units, err := s.listFn(s.patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var batch sample.EventBatch
+	listed := make(map[string]struct{}, len(units))
+	for _, u := range units {
+		listed[u.Name] = struct{}{}
+		batch = append(batch, unitSample(u))
+
+		if e := s.failure(u); e != nil {
+			batch = append(batch, e)
+		}
+	}
+
+	// forget the units no longer listed, ie: transient ones, so the failed states don't grow unbounded
+	for name := range s.failed {
+		if _, ok := listed[name]; !ok {
+			delete(s.failed, name)
+		}
+	}
+
+	return batch, nil
+}
+
+func unitSample(u Unit) *SystemdUnitSample {
+	us := &SystemdUnitSample{
+		UnitName:           u.Name,
+		Description:        u.Description,
+		LoadState:          u.LoadState,
+		ActiveState:        u.ActiveState,
+		SubState:           u.SubState,
+		MainPID:            u.MainPID,
+		RestartCount:       u.NRestarts,
+		MemoryCurrentBytes: u.MemoryCurrent,
+	}
+	if u.CPUUsage != nil {
+		secs := u.CPUUsage.Seconds()
+		us.CPUUsageSeconds = &secs
+	}
+	us.Type(sampleEventType)
+
+	return us
+}
+
+// failure returns an event when the unit enters the failed state, units already failed
+// when first seen are reported as well.
+func (s *Sampler) failure(u Unit) *SystemdUnitFailed {
+	isFailed := u.ActiveState == activeStateFailed
+	wasFailed := s.failed[u.Name]
+	s.failed[u.Name] = isFailed
+
+	if !isFailed || wasFailed {
+		return nil
+	}
+
+	sslog.WithField("unit", u.Name).WithField("subState", u.SubState).Debug("Unit failed.")
+
+	e := &SystemdUnitFailed{
+		UnitName:     u.Name,
+		Description:  u.Description,
+		SubState:     u.SubState,
+		RestartCount: u.NRestarts,
+	}
+	e.Type(failedEventType)
+
+	return e
+}
+
+// OnStartup logs the selected units.
+func (s *Sampler) OnStartup() {
+	sslog.WithField("units", s.patterns).Debug("Starting systemd units sampler.")
+}
+
+// Name returns the sampler name.
+func (s *Sampler) Name() string {
+	return "SystemdUnitSampler"
+}
+
+// Interval returns the sampling interval.
+func (s *Sampler) Interval() time.Duration {
+	return s.interval
+}
+
+// Disabled returns true when sampling is disabled or there are no units to sample.
+func (s *Sampler) Disabled() bool {
+	return s.Interval() <= config.FREQ_DISABLE_SAMPLING || len(s.patterns) == 0
+}
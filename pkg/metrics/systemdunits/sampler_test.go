@@ -0,0 +1,124 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdunits
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSystemd struct {
+	units    []Unit
+	err      error
+	patterns []string
+}
+
+func (f *fakeSystemd) list(patterns []string) ([]Unit, error) {
+	f.patterns = patterns
+	return f.units, f.err
+}
+
+func TestSampler_Sample(t *testing.T) {
+	restarts := uint32(3)
+	mem := uint64(1024)
+	cpu := 1500 * time.Millisecond
+	systemd := &fakeSystemd{units: []Unit{
+		{Name: "nginx.service", Description: "nginx", LoadState: "loaded", ActiveState: "active", SubState: "running",
+			MainPID: 42, NRestarts: &restarts, MemoryCurrent: &mem, CPUUsage: &cpu},
+		{Name: "docker.socket", LoadState: "loaded", ActiveState: "inactive", SubState: "dead"},
+	}}
+
+	s := newSampler(30*time.Second, []string{"nginx.service", "docker*"}, systemd.list)
+
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, []string{"nginx.service", "docker*"}, systemd.patterns)
+
+	nginx := batch[0].(*SystemdUnitSample)
+	assert.Equal(t, sampleEventType, nginx.EventType)
+	assert.Equal(t, "nginx.service", nginx.UnitName)
+	assert.Equal(t, "active", nginx.ActiveState)
+	assert.Equal(t, "running", nginx.SubState)
+	assert.Equal(t, uint32(42), nginx.MainPID)
+	assert.Equal(t, &restarts, nginx.RestartCount)
+	assert.Equal(t, &mem, nginx.MemoryCurrentBytes)
+	require.NotNil(t, nginx.CPUUsageSeconds)
+	assert.Equal(t, 1.5, *nginx.CPUUsageSeconds)
+
+	socket := batch[1].(*SystemdUnitSample)
+	assert.Nil(t, socket.RestartCount)
+	assert.Nil(t, socket.MemoryCurrentBytes)
+	assert.Nil(t, socket.CPUUsageSeconds)
+}
+
+func TestSampler_FailedEvents(t *testing.T) {
+	systemd := &fakeSystemd{units: []Unit{
+		{Name: "nginx.service", ActiveState: "active", SubState: "running"},
+		{Name: "broken.service", ActiveState: "failed", SubState: "failed"},
+	}}
+	s := newSampler(30*time.Second, []string{"*.service"}, systemd.list)
+
+	// already failed units are reported the first time
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	failed := batch[2].(*SystemdUnitFailed)
+	assert.Equal(t, failedEventType, failed.EventType)
+	assert.Equal(t, "broken.service", failed.UnitName)
+
+	// still failed, no new event
+	batch, err = s.Sample()
+	require.NoError(t, err)
+	assert.Len(t, batch, 2)
+
+	systemd.units[0].ActiveState = "failed"
+	systemd.units[1].ActiveState = "active"
+	batch, err = s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "nginx.service", batch[1].(*SystemdUnitFailed).UnitName)
+
+	// failing again after recovering
+	systemd.units[1].ActiveState = "failed"
+	batch, err = s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "broken.service", batch[2].(*SystemdUnitFailed).UnitName)
+}
+
+func TestSampler_ForgetsUnlistedUnits(t *testing.T) {
+	systemd := &fakeSystemd{units: []Unit{
+		{Name: "nginx.service", ActiveState: "active"},
+		{Name: "run-r1.service", ActiveState: "failed"},
+	}}
+	s := newSampler(30*time.Second, []string{"*.service"}, systemd.list)
+
+	_, err := s.Sample()
+	require.NoError(t, err)
+	assert.Len(t, s.failed, 2)
+
+	// the transient unit is gone
+	systemd.units = systemd.units[:1]
+	_, err = s.Sample()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"nginx.service": false}, s.failed)
+}
+
+func TestSampler_Error(t *testing.T) {
+	s := newSampler(30*time.Second, []string{"*"}, (&fakeSystemd{err: errors.New("no bus")}).list)
+
+	_, err := s.Sample()
+	assert.EqualError(t, err, "no bus")
+}
+
+func TestSampler_Disabled(t *testing.T) {
+	assert.True(t, newSampler(-1*time.Second, []string{"*"}, nil).Disabled())
+	assert.True(t, newSampler(30*time.Second, nil, nil).Disabled(), "no units selected")
+	assert.False(t, newSampler(30*time.Second, []string{"*"}, nil).Disabled())
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdunits
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+)
+
+const (
+	systemBusAddressFormat     = "unix:path=%s"
+	systemBusDefaultPath       = "/run/dbus/system_bus_socket"
+	dbusSystemBusAddressEnvVar = "DBUS_SYSTEM_BUS_ADDRESS"
+	dbusTimeout                = 10 * time.Second
+	// systemd reports unset accounting values as max uint64
+	accountingNotSet = ^uint64(0)
+)
+
+// dbusLister retrieves the units through a systemd D-Bus connection, reconnecting on failures.
+type dbusLister struct {
+	lock sync.Mutex
+	conn *dbus.Conn
+}
+
+func newDbusLister() *dbusLister {
+	return &dbusLister{}
+}
+
+func (l *dbusLister) list(patterns []string) ([]Unit, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbusTimeout)
+	defer cancel()
+
+	if l.conn == nil || !l.conn.Connected() {
+		if _, fnd := os.LookupEnv(dbusSystemBusAddressEnvVar); !fnd {
+			_ = os.Setenv(dbusSystemBusAddressEnvVar, fmt.Sprintf(systemBusAddressFormat, helpers.HostVar(systemBusDefaultPath)))
+		}
+		conn, err := dbus.NewWithContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to systemd through D-Bus: %w", err)
+		}
+		l.conn = conn
+	}
+
+	statuses, err := l.conn.ListUnitsByPatternsContext(ctx, nil, patterns)
+	if err != nil {
+		l.conn.Close()
+		l.conn = nil
+		return nil, fmt.Errorf("cannot list systemd units: %w", err)
+	}
+
+	units := make([]Unit, 0, len(statuses))
+	for _, st := range statuses {
+		u := Unit{
+			Name:        st.Name,
+			Description: st.Description,
+			LoadState:   st.LoadState,
+			ActiveState: st.ActiveState,
+			SubState:    st.SubState,
+		}
+		if props, err := l.conn.GetUnitTypePropertiesContext(ctx, st.Name, unitType(st.Name)); err != nil {
+			sslog.WithError(err).WithField("unit", st.Name).Debug("Cannot get unit accounting properties.")
+		} else {
+			decorateWithProperties(&u, props)
+		}
+		units = append(units, u)
+	}
+
+	return units, nil
+}
+
+// unitType returns the D-Bus interface suffix for the unit, ie: nginx.service -> Service.
+func unitType(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i < 0 || i == len(name)-1 {
+		return ""
+	}
+	t := name[i+1:]
+	return strings.ToUpper(t[:1]) + t[1:]
+}
+
+func decorateWithProperties(u *Unit, props map[string]interface{}) {
+	if pid, ok := props["MainPID"].(uint32); ok {
+		u.MainPID = pid
+	}
+	if restarts, ok := props["NRestarts"].(uint32); ok {
+		u.NRestarts = &restarts
+	}
+	if mem, ok := props["MemoryCurrent"].(uint64); ok && mem != accountingNotSet {
+		u.MemoryCurrent = &mem
+	}
+	if cpu, ok := props["CPUUsageNSec"].(uint64); ok && cpu != accountingNotSet {
+		d := time.Duration(cpu)
+		u.CPUUsage = &d
+	}
+}
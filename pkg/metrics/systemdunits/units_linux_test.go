@@ -0,0 +1,49 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package systemdunits
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitType(t *testing.T) {
+	assert.Equal(t, "Service", unitType("nginx.service"))
+	assert.Equal(t, "Socket", unitType("docker.socket"))
+	assert.Equal(t, "", unitType("invalid"))
+	assert.Equal(t, "", unitType("invalid."))
+}
+
+func TestDecorateWithProperties(t *testing.T) {
+	u := Unit{}
+	decorateWithProperties(&u, map[string]interface{}{
+		"MainPID":       uint32(42),
+		"NRestarts":     uint32(2),
+		"MemoryCurrent": uint64(2048),
+		"CPUUsageNSec":  uint64(time.Second),
+	})
+
+	assert.Equal(t, uint32(42), u.MainPID)
+	require.NotNil(t, u.NRestarts)
+	assert.Equal(t, uint32(2), *u.NRestarts)
+	require.NotNil(t, u.MemoryCurrent)
+	assert.Equal(t, uint64(2048), *u.MemoryCurrent)
+	require.NotNil(t, u.CPUUsage)
+	assert.Equal(t, time.Second, *u.CPUUsage)
+}
+
+func TestDecorateWithProperties_AccountingNotSet(t *testing.T) {
+	u := Unit{}
+	decorateWithProperties(&u, map[string]interface{}{
+		"MemoryCurrent": accountingNotSet,
+		"CPUUsageNSec":  accountingNotSet,
+	})
+
+	assert.Nil(t, u.NRestarts)
+	assert.Nil(t, u.MemoryCurrent)
+	assert.Nil(t, u.CPUUsage)
+}
@@ -0,0 +1,18 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build !linux
+// +build !linux
+
+package systemdunits
+
+import "errors"
+
+type dbusLister struct{}
+
+func newDbusLister() *dbusLister {
+	return &dbusLister{}
+}
+
+func (l *dbusLister) list(_ []string) ([]Unit, error) {
+	return nil, errors.New("systemd units are only supported on linux")
+}
@@ -4,6 +4,8 @@
 package types
 
 import (
+	"time"
+
 	"github.com/shirou/gopsutil/v3/process"
 
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
@@ -41,6 +43,8 @@ type ProcessSample struct {
 	IOTotalWriteCount     *uint64  `json:"ioTotalWriteCount,omitempty"`
 	IOTotalReadBytes      *uint64  `json:"ioTotalReadBytes,omitempty"`
 	IOTotalWriteBytes     *uint64  `json:"ioTotalWriteBytes,omitempty"`
+	// ProcessCount is only reported by the sample aggregating the processes out of the top N.
+	ProcessCount int `json:"processCount,omitempty"`
 	// Auxiliary values, not to be reported
 	LastIOCounters  *process.IOCountersStat `json:"-"`
 	ContainerLabels map[string]string       `json:"-"`
@@ -49,7 +53,10 @@ type ProcessSample struct {
 // FlatProcessSample stores the process sampling information as a map
 type FlatProcessSample map[string]interface{}
 
-var _ sample.Event = &FlatProcessSample{} // FlatProcessSample implements sample.Event
+var (
+	_ sample.Event     = &FlatProcessSample{} // FlatProcessSample implements sample.Event
+	_ sample.MetaEvent = &FlatProcessSample{} // FlatProcessSample implements sample.MetaEvent
+)
 
 func (f *FlatProcessSample) Type(eventType string) {
 	(*f)["eventType"] = eventType
@@ -62,3 +69,11 @@ func (f *FlatProcessSample) Entity(key entity.Key) {
 func (f *FlatProcessSample) Timestamp(timestamp int64) {
 	(*f)["timestamp"] = timestamp
 }
+
+func (f *FlatProcessSample) CollectionDuration(d time.Duration) {
+	(*f)["collectionDurationMs"] = d.Milliseconds()
+}
+
+func (f *FlatProcessSample) DataAge(age time.Duration) {
+	(*f)["dataAgeMs"] = age.Milliseconds()
+}
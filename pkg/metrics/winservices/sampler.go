@@ -0,0 +1,181 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package winservices provides a sampler reporting the state of the Windows services registered
+// in the Service Control Manager (SCM).
+package winservices
+
+import (
+	"strings"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+)
+
+const (
+	sampleEventType      = "WindowsServiceSample"
+	stateChangeEventType = "WindowsServiceStateChange"
+)
+
+var sslog = log.WithComponent("WindowsServiceSampler")
+
+// Service is the status of a Windows service as reported by the SCM.
+type Service struct {
+	Name        string
+	DisplayName string
+	State       string
+	StartMode   string
+	PID         uint32
+	// StartTime of the service process, zero when not running.
+	StartTime time.Time
+}
+
+// WindowsServiceSample reports the state of a single Windows service.
+type WindowsServiceSample struct {
+	sample.BaseEvent
+
+	ServiceName   string   `json:"serviceName"`
+	DisplayName   string   `json:"displayName"`
+	State         string   `json:"state"`
+	StartMode     string   `json:"startMode"`
+	ProcessID     uint32   `json:"processId,omitempty"`
+	UptimeSeconds *float64 `json:"uptimeSeconds,omitempty"`
+}
+
+// WindowsServiceStateChange is emitted when a watched service transitions between states.
+type WindowsServiceStateChange struct {
+	sample.BaseEvent
+
+	ServiceName   string `json:"serviceName"`
+	DisplayName   string `json:"displayName"`
+	StartMode     string `json:"startMode"`
+	PreviousState string `json:"previousState"`
+	State         string `json:"state"`
+}
+
+// Sampler enumerates the Windows services, reporting a WindowsServiceSample per service plus a
+// WindowsServiceStateChange event whenever a service from the watch-list changes its state.
+type Sampler struct {
+	interval   time.Duration
+	watchList  map[string]struct{}
+	lastStates map[string]string
+	listFn     func() ([]Service, error)
+	nowFn      func() time.Time
+}
+
+// NewSampler creates a Windows services sampler.
+func NewSampler(ctx agent.AgentContext) *Sampler {
+	interval := config.FREQ_DISABLE_SAMPLING
+	var watchList []string
+	if ctx != nil {
+		interval = ctx.Config().MetricsWindowsServiceSampleRate
+		watchList = ctx.Config().WindowsServiceWatchList
+	}
+
+	return newSampler(time.Second*time.Duration(interval), watchList, listServices)
+}
+
+func newSampler(interval time.Duration, watchList []string, listFn func() ([]Service, error)) *Sampler {
+	watched := make(map[string]struct{}, len(watchList))
+	for _, name := range watchList {
+		// service names are case-insensitive
+		watched[strings.ToLower(name)] = struct{}{}
+	}
+
+	return &Sampler{
+		interval:   interval,
+		watchList:  watched,
+		lastStates: make(map[string]string),
+		listFn:     listFn,
+		nowFn:      time.Now,
+	}
+}
+
+// Sample returns the current state of the services, each followed by its state change event, if any.
+func (s *Sampler) Sample() (sample.EventBatch, error) {
+	services, err := s.listFn()
+	if err != nil {
+		return nil, err
+	}
+
+	var batch sample.EventBatch
+	now := s.nowFn()
+	for _, svc := range services {
+		batch = append(batch, s.serviceSample(svc, now))
+
+		if change := s.stateChange(svc); change != nil {
+			batch = append(batch, change)
+		}
+	}
+
+	return batch, nil
+}
+
+func (s *Sampler) serviceSample(svc Service, now time.Time) *WindowsServiceSample {
+	ss := &WindowsServiceSample{
+		ServiceName: svc.Name,
+		DisplayName: svc.DisplayName,
+		State:       svc.State,
+		StartMode:   svc.StartMode,
+		ProcessID:   svc.PID,
+	}
+	if !svc.StartTime.IsZero() {
+		uptime := now.Sub(svc.StartTime).Seconds()
+		ss.UptimeSeconds = &uptime
+	}
+	ss.Type(sampleEventType)
+
+	return ss
+}
+
+// stateChange tracks the state of watched services, returning an event on transitions.
+// The first time a service is seen its state is just recorded.
+func (s *Sampler) stateChange(svc Service) *WindowsServiceStateChange {
+	key := strings.ToLower(svc.Name)
+	if _, watched := s.watchList[key]; !watched {
+		return nil
+	}
+
+	prev, seen := s.lastStates[key]
+	s.lastStates[key] = svc.State
+	if !seen || prev == svc.State {
+		return nil
+	}
+
+	sslog.WithField("service", svc.Name).WithField("from", prev).WithField("to", svc.State).
+		Debug("Service state changed.")
+
+	e := &WindowsServiceStateChange{
+		ServiceName:   svc.Name,
+		DisplayName:   svc.DisplayName,
+		StartMode:     svc.StartMode,
+		PreviousState: prev,
+		State:         svc.State,
+	}
+	e.Type(stateChangeEventType)
+
+	return e
+}
+
+// OnStartup logs the watched services.
+func (s *Sampler) OnStartup() {
+	sslog.WithField("watched", len(s.watchList)).Debug("Starting Windows services sampler.")
+}
+
+// Name returns the sampler name.
+func (s *Sampler) Name() string {
+	return "WindowsServiceSampler"
+}
+
+// Interval returns the sampling interval.
+func (s *Sampler) Interval() time.Duration {
+	return s.interval
+}
+
+// Disabled returns true when sampling is disabled.
+func (s *Sampler) Disabled() bool {
+	return s.Interval() <= config.FREQ_DISABLE_SAMPLING
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package winservices
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSCM struct {
+	services []Service
+	err      error
+}
+
+func (f *fakeSCM) list() ([]Service, error) {
+	return f.services, f.err
+}
+
+func TestSampler_Sample(t *testing.T) {
+	now := time.Date(2022, 6, 10, 15, 0, 0, 0, time.UTC)
+	scm := &fakeSCM{services: []Service{
+		{Name: "W32Time", DisplayName: "Windows Time", State: "Running", StartMode: "Auto", PID: 42, StartTime: now.Add(-time.Minute)},
+		{Name: "Spooler", DisplayName: "Print Spooler", State: "Stopped", StartMode: "Manual"},
+	}}
+
+	s := newSampler(30*time.Second, nil, scm.list)
+	s.nowFn = func() time.Time { return now }
+
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+
+	running := batch[0].(*WindowsServiceSample)
+	assert.Equal(t, sampleEventType, running.EventType)
+	assert.Equal(t, "W32Time", running.ServiceName)
+	assert.Equal(t, "Windows Time", running.DisplayName)
+	assert.Equal(t, "Running", running.State)
+	assert.Equal(t, "Auto", running.StartMode)
+	assert.Equal(t, uint32(42), running.ProcessID)
+	require.NotNil(t, running.UptimeSeconds)
+	assert.Equal(t, 60.0, *running.UptimeSeconds)
+
+	stopped := batch[1].(*WindowsServiceSample)
+	assert.Equal(t, "Stopped", stopped.State)
+	assert.Nil(t, stopped.UptimeSeconds)
+}
+
+func TestSampler_StateChangeEvents(t *testing.T) {
+	scm := &fakeSCM{services: []Service{
+		{Name: "W32Time", State: "Running", StartMode: "Auto"},
+		{Name: "Spooler", State: "Running", StartMode: "Manual"},
+	}}
+
+	// watch-list is case-insensitive
+	s := newSampler(30*time.Second, []string{"w32time"}, scm.list)
+
+	// first run only records the state
+	batch, err := s.Sample()
+	require.NoError(t, err)
+	assert.Len(t, batch, 2)
+
+	scm.services[0].State = "Stopped"
+	scm.services[1].State = "Stopped"
+
+	batch, err = s.Sample()
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+
+	change, ok := batch[1].(*WindowsServiceStateChange)
+	require.True(t, ok, "state change expected after the service sample")
+	assert.Equal(t, stateChangeEventType, change.EventType)
+	assert.Equal(t, "W32Time", change.ServiceName)
+	assert.Equal(t, "Running", change.PreviousState)
+	assert.Equal(t, "Stopped", change.State)
+
+	// no transition, no event
+	batch, err = s.Sample()
+	require.NoError(t, err)
+	assert.Len(t, batch, 2)
+}
+
+func TestSampler_Error(t *testing.T) {
+	s := newSampler(30*time.Second, nil, (&fakeSCM{err: errors.New("access denied")}).list)
+
+	_, err := s.Sample()
+	assert.EqualError(t, err, "access denied")
+}
+
+func TestSampler_Disabled(t *testing.T) {
+	assert.True(t, newSampler(-1*time.Second, nil, nil).Disabled())
+	assert.False(t, newSampler(15*time.Second, nil, nil).Disabled())
+}
@@ -0,0 +1,12 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+//go:build !windows
+// +build !windows
+
+package winservices
+
+import "errors"
+
+func listServices() ([]Service, error) {
+	return nil, errors.New("windows services are not supported on this platform")
+}
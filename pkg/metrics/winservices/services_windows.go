@@ -0,0 +1,145 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package winservices
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// initial buffer size for the services configuration, it's increased when not enough
+const serviceConfigBufSize = 1024
+
+// Service state names, matching the ones used by Win32_Service.
+var serviceStates = map[uint32]string{
+	windows.SERVICE_STOPPED:          "Stopped",
+	windows.SERVICE_START_PENDING:    "StartPending",
+	windows.SERVICE_STOP_PENDING:     "StopPending",
+	windows.SERVICE_RUNNING:          "Running",
+	windows.SERVICE_CONTINUE_PENDING: "ContinuePending",
+	windows.SERVICE_PAUSE_PENDING:    "PausePending",
+	windows.SERVICE_PAUSED:           "Paused",
+}
+
+// Service start mode names, matching the ones used by Win32_Service.
+var startModes = map[uint32]string{
+	windows.SERVICE_BOOT_START:   "Boot",
+	windows.SERVICE_SYSTEM_START: "System",
+	windows.SERVICE_AUTO_START:   "Auto",
+	windows.SERVICE_DEMAND_START: "Manual",
+	windows.SERVICE_DISABLED:     "Disabled",
+}
+
+// listServices enumerates the Win32 services through the SCM.
+func listServices() ([]Service, error) {
+	mgr, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT|windows.SC_MANAGER_ENUMERATE_SERVICE)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open service control manager: %w", err)
+	}
+	defer windows.CloseServiceHandle(mgr)
+
+	entries, err := enumServices(mgr)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]Service, 0, len(entries))
+	for _, e := range entries {
+		svc := Service{
+			Name:        windows.UTF16PtrToString(e.ServiceName),
+			DisplayName: windows.UTF16PtrToString(e.DisplayName),
+			State:       serviceStates[e.ServiceStatusProcess.CurrentState],
+			PID:         e.ServiceStatusProcess.ProcessId,
+		}
+
+		if svc.StartMode, err = startMode(mgr, svc.Name); err != nil {
+			sslog.WithError(err).WithField("service", svc.Name).Debug("Cannot get service start mode.")
+		}
+
+		if svc.PID != 0 {
+			if svc.StartTime, err = processStartTime(svc.PID); err != nil {
+				sslog.WithError(err).WithField("service", svc.Name).Debug("Cannot get service process start time.")
+			}
+		}
+
+		services = append(services, svc)
+	}
+
+	return services, nil
+}
+
+func enumServices(mgr windows.Handle) ([]windows.ENUM_SERVICE_STATUS_PROCESS, error) {
+	var bytesNeeded, servicesReturned, resumeHandle uint32
+	var buf []byte
+	var entries []windows.ENUM_SERVICE_STATUS_PROCESS
+
+	for {
+		var p *byte
+		if len(buf) > 0 {
+			p = &buf[0]
+		}
+		err := windows.EnumServicesStatusEx(mgr, windows.SC_ENUM_PROCESS_INFO, windows.SERVICE_WIN32,
+			windows.SERVICE_STATE_ALL, p, uint32(len(buf)), &bytesNeeded, &servicesReturned, &resumeHandle, nil)
+
+		if servicesReturned > 0 {
+			chunk := unsafe.Slice((*windows.ENUM_SERVICE_STATUS_PROCESS)(unsafe.Pointer(&buf[0])), servicesReturned)
+			entries = append(entries, chunk...)
+		}
+
+		if err == nil {
+			return entries, nil
+		}
+		if !errors.Is(err, windows.ERROR_MORE_DATA) {
+			return nil, fmt.Errorf("cannot enumerate services: %w", err)
+		}
+		if bytesNeeded > uint32(len(buf)) {
+			buf = make([]byte, bytesNeeded)
+		}
+	}
+}
+
+func startMode(mgr windows.Handle, name string) (string, error) {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return "", err
+	}
+
+	svc, err := windows.OpenService(mgr, namePtr, windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseServiceHandle(svc)
+
+	bufSize := uint32(serviceConfigBufSize)
+	for {
+		buf := make([]byte, bufSize)
+		cfg := (*windows.QUERY_SERVICE_CONFIG)(unsafe.Pointer(&buf[0]))
+		err = windows.QueryServiceConfig(svc, cfg, bufSize, &bufSize)
+		if err == nil {
+			return startModes[cfg.StartType], nil
+		}
+		if !errors.Is(err, windows.ERROR_INSUFFICIENT_BUFFER) {
+			return "", err
+		}
+	}
+}
+
+func processStartTime(pid uint32) (time.Time, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer windows.CloseHandle(h)
+
+	var creation, exit, kernel, user windows.Filetime
+	if err = windows.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
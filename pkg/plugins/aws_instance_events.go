@@ -0,0 +1,160 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package plugins
+
+import (
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+)
+
+const awsInstanceEventType = "AwsInstanceEvent"
+
+// Kinds of the AwsInstanceEvent
+const (
+	AWSEventLifecycle               = "lifecycle"
+	AWSEventSpotInterruption        = "spotInterruption"
+	AWSEventRebalanceRecommendation = "rebalanceRecommendation"
+	AWSEventScheduledMaintenance    = "scheduledMaintenance"
+)
+
+const awsInstanceLifecycleSpot = "spot"
+
+// AWSInstanceEventsSource provides the AWS instance life-cycle and its upcoming interruptions.
+type AWSInstanceEventsSource interface {
+	GetInstanceLifecycle() (string, error)
+	GetSpotInstanceAction() (*cloud.SpotInstanceAction, error)
+	GetRebalanceRecommendation() (*cloud.RebalanceRecommendation, error)
+	GetScheduledEvents() ([]cloud.ScheduledEvent, error)
+}
+
+// AWSInstanceEventsPlugin sends an AwsInstanceEvent with the instance life-cycle (on-demand, spot or scheduled) when
+// the agent starts, and one for every spot interruption notice, rebalance recommendation and active scheduled
+// maintenance event found while polling the instance metadata service. Each notice is only sent once.
+type AWSInstanceEventsPlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+	harvester cloud.Harvester
+	source    AWSInstanceEventsSource
+	lifecycle string
+	sent      map[string]bool
+}
+
+func NewAWSInstanceEventsPlugin(id ids.PluginID, ctx agent.AgentContext, harvester cloud.Harvester) *AWSInstanceEventsPlugin {
+	cfg := ctx.Config()
+	return &AWSInstanceEventsPlugin{
+		PluginCommon: agent.PluginCommon{ID: id, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.AWSInstanceEventsIntervalSec,
+			config.FREQ_INTERVAL_FLOOR_METRICS,
+			config.FREQ_PLUGIN_AWS_INSTANCE_EVENTS,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		harvester: harvester,
+		sent:      make(map[string]bool),
+	}
+}
+
+// eventsSource returns the AWS harvester detected, nil until it's available.
+func (self *AWSInstanceEventsPlugin) eventsSource() AWSInstanceEventsSource {
+	if self.source != nil {
+		return self.source
+	}
+	h, err := self.harvester.GetHarvester()
+	if err != nil {
+		return nil
+	}
+	self.source, _ = h.(AWSInstanceEventsSource)
+	return self.source
+}
+
+func (self *AWSInstanceEventsPlugin) emit(key string, event map[string]interface{}) {
+	if self.sent[key] {
+		return
+	}
+	self.sent[key] = true
+	event["eventType"] = awsInstanceEventType
+	event["instanceLifecycle"] = self.lifecycle
+	self.EmitEvent(event, entity.Key(self.Context.EntityKey()))
+}
+
+func (self *AWSInstanceEventsPlugin) harvest() {
+	source := self.eventsSource()
+	if source == nil {
+		slog.WithPlugin(self.Id().String()).Debug("AWS harvester not available.")
+		return
+	}
+
+	if self.lifecycle == "" {
+		lifecycle, err := source.GetInstanceLifecycle()
+		if err != nil {
+			slog.WithError(err).WithPlugin(self.Id().String()).Debug("fetching instance life-cycle")
+			return
+		}
+		self.lifecycle = lifecycle
+		self.emit(AWSEventLifecycle, map[string]interface{}{"kind": AWSEventLifecycle})
+	}
+
+	if self.lifecycle == awsInstanceLifecycleSpot {
+		action, err := source.GetSpotInstanceAction()
+		if err != nil {
+			slog.WithError(err).WithPlugin(self.Id().String()).Debug("fetching spot instance action")
+		} else if action != nil {
+			self.emit(AWSEventSpotInterruption+"/"+action.Action+"/"+action.Time, map[string]interface{}{
+				"kind":   AWSEventSpotInterruption,
+				"action": action.Action,
+				"time":   action.Time,
+			})
+		}
+
+		recommendation, err := source.GetRebalanceRecommendation()
+		if err != nil {
+			slog.WithError(err).WithPlugin(self.Id().String()).Debug("fetching rebalance recommendation")
+		} else if recommendation != nil {
+			self.emit(AWSEventRebalanceRecommendation+"/"+recommendation.NoticeTime, map[string]interface{}{
+				"kind":       AWSEventRebalanceRecommendation,
+				"noticeTime": recommendation.NoticeTime,
+			})
+		}
+	}
+
+	events, err := source.GetScheduledEvents()
+	if err != nil {
+		slog.WithError(err).WithPlugin(self.Id().String()).Debug("fetching scheduled events")
+		return
+	}
+	for _, e := range events {
+		if e.State != "active" {
+			continue
+		}
+		self.emit(AWSEventScheduledMaintenance+"/"+e.EventID, map[string]interface{}{
+			"kind":        AWSEventScheduledMaintenance,
+			"code":        e.Code,
+			"description": e.Description,
+			"eventId":     e.EventID,
+			"notBefore":   e.NotBefore,
+			"notAfter":    e.NotAfter,
+		})
+	}
+}
+
+func (self *AWSInstanceEventsPlugin) Run() {
+	if self.frequency <= config.FREQ_DISABLE_SAMPLING {
+		slog.WithPlugin(self.Id().String()).Debug("Disabled.")
+		return
+	}
+
+	ticker := time.NewTicker(1)
+	for {
+		select {
+		case <-ticker.C:
+			ticker.Stop()
+			ticker = time.NewTicker(self.frequency)
+			self.harvest()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package plugins
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testing2 "github.com/newrelic/infrastructure-agent/internal/plugins/testing"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+	"github.com/newrelic/infrastructure-agent/pkg/sample"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+)
+
+type eventsRecorder struct {
+	*testing2.MockAgent
+	events []map[string]interface{}
+}
+
+func (r *eventsRecorder) SendEvent(event sample.Event, _ entity.Key) {
+	blob, _ := json.Marshal(event)
+	var m map[string]interface{}
+	_ = json.Unmarshal(blob, &m)
+	r.events = append(r.events, m)
+}
+
+type fakeAWSEventsSource struct {
+	lifecycle      string
+	action         *cloud.SpotInstanceAction
+	recommendation *cloud.RebalanceRecommendation
+	scheduled      []cloud.ScheduledEvent
+}
+
+func (f *fakeAWSEventsSource) GetInstanceLifecycle() (string, error) {
+	return f.lifecycle, nil
+}
+
+func (f *fakeAWSEventsSource) GetSpotInstanceAction() (*cloud.SpotInstanceAction, error) {
+	return f.action, nil
+}
+
+func (f *fakeAWSEventsSource) GetRebalanceRecommendation() (*cloud.RebalanceRecommendation, error) {
+	return f.recommendation, nil
+}
+
+func (f *fakeAWSEventsSource) GetScheduledEvents() ([]cloud.ScheduledEvent, error) {
+	return f.scheduled, nil
+}
+
+func TestAWSInstanceEvents(t *testing.T) {
+	ctx := &eventsRecorder{MockAgent: testing2.NewMockAgent()}
+	source := &fakeAWSEventsSource{
+		lifecycle: "spot",
+		scheduled: []cloud.ScheduledEvent{
+			{Code: "system-reboot", EventID: "instance-event-1", State: "completed"},
+		},
+	}
+	p := NewAWSInstanceEventsPlugin(ids.PluginID{Category: "metadata", Term: "aws_instance_events"}, ctx, nil)
+	p.source = source
+
+	p.harvest()
+	require.Len(t, ctx.events, 1)
+	assert.Equal(t, "AwsInstanceEvent", ctx.events[0]["eventType"])
+	assert.Equal(t, "lifecycle", ctx.events[0]["kind"])
+	assert.Equal(t, "spot", ctx.events[0]["instanceLifecycle"])
+
+	source.action = &cloud.SpotInstanceAction{Action: "terminate", Time: "2017-09-18T08:22:00Z"}
+	source.recommendation = &cloud.RebalanceRecommendation{NoticeTime: "2017-09-18T08:20:00Z"}
+	source.scheduled = append(source.scheduled, cloud.ScheduledEvent{
+		Code: "instance-retirement", EventID: "instance-event-2", State: "active", NotBefore: "21 Jan 2019 09:00:43 GMT",
+	})
+	p.harvest()
+	// notices already sent are not sent again
+	p.harvest()
+	require.Len(t, ctx.events, 4)
+
+	assert.Equal(t, "spotInterruption", ctx.events[1]["kind"])
+	assert.Equal(t, "terminate", ctx.events[1]["action"])
+	assert.Equal(t, "2017-09-18T08:22:00Z", ctx.events[1]["time"])
+	assert.Equal(t, "rebalanceRecommendation", ctx.events[2]["kind"])
+	assert.Equal(t, "scheduledMaintenance", ctx.events[3]["kind"])
+	assert.Equal(t, "instance-event-2", ctx.events[3]["eventId"])
+	assert.Equal(t, "spot", ctx.events[3]["instanceLifecycle"])
+}
+
+func TestAWSInstanceEvents_OnDemand(t *testing.T) {
+	ctx := &eventsRecorder{MockAgent: testing2.NewMockAgent()}
+	p := NewAWSInstanceEventsPlugin(ids.PluginID{Category: "metadata", Term: "aws_instance_events"}, ctx, nil)
+	p.source = &fakeAWSEventsSource{
+		lifecycle: "on-demand",
+		// spot notices are not queried for on-demand instances
+		action: &cloud.SpotInstanceAction{Action: "stop", Time: "2017-09-18T08:22:00Z"},
+	}
+
+	p.harvest()
+	require.Len(t, ctx.events, 1)
+	assert.Equal(t, "on-demand", ctx.events[0]["instanceLifecycle"])
+}
@@ -12,7 +12,6 @@ import (
 
 type CustomAttrsPlugin struct {
 	agent.PluginCommon
-	customAttributes map[string]interface{}
 }
 
 type CustomAttrs map[string]interface{}
@@ -27,20 +26,21 @@ func NewCustomAttrsPlugin(ctx agent.AgentContext) agent.Plugin {
 			ID:      ids.CustomAttrsID,
 			Context: ctx,
 		},
-		customAttributes: ctx.Config().CustomAttributes,
 	}
 }
 
 // This plugin is pretty simple - it simply returns once with the object containing current custom attributes.
+// They are read from the running configuration, so the ones reloaded are reported when the plugin is reconnected.
 func (self *CustomAttrsPlugin) Run() {
 	self.Context.AddReconnecting(self)
 
-	data := types.PluginInventoryDataset{CustomAttrs(self.customAttributes)}
+	customAttributes := self.Context.Config().GetCustomAttributes()
+	data := types.PluginInventoryDataset{CustomAttrs(customAttributes)}
 	entityKey := self.Context.EntityKey()
 
 	aclog.
 		WithField(config.TracesFieldName, config.FeatureTrace).
-		Tracef("run, entity: %s, data: %+v", entityKey, self.customAttributes)
+		Tracef("run, entity: %s, data: %+v", entityKey, customAttributes)
 
 	self.EmitInventory(data, entity.NewFromNameWithoutID(entityKey))
 }
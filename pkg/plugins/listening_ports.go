@@ -0,0 +1,231 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package plugins
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	gnet "github.com/shirou/gopsutil/v3/net"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/internal/agent/types"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+const (
+	listeningPortEventType = "ListeningPortEvent"
+
+	// socket types, the same on every supported OS
+	sockStream = 1
+	sockDgram  = 2
+)
+
+// Actions of the ListeningPortEvent
+const (
+	PortOpened = "opened"
+	PortClosed = "closed"
+)
+
+// ListeningPort is a listening TCP socket or unconnected UDP socket, and the process owning it. As all inventory
+// output, the numbers are reported as strings.
+type ListeningPort struct {
+	ID          string `json:"id"`
+	Protocol    string `json:"protocol"`
+	Address     string `json:"address"`
+	Port        string `json:"port"`
+	Pid         string `json:"pid,omitempty"`
+	ProcessName string `json:"process_name,omitempty"`
+	User        string `json:"user,omitempty"`
+	BinaryPath  string `json:"binary_path,omitempty"`
+	port        uint32
+	pid         int32
+}
+
+func (p ListeningPort) SortKey() string {
+	return p.ID
+}
+
+// ConnectionsProvider returns the sockets of the host.
+type ConnectionsProvider func() ([]gnet.ConnectionStat, error)
+
+// ProcessProvider returns the name, user and binary path of a process. Unknown values are returned empty.
+type ProcessProvider func(pid int32) (name, user, binaryPath string)
+
+// ListeningPortsPlugin reports the listening sockets of the host, with their owning process, sending a
+// ListeningPortEvent when a port is opened or closed since the previous run. Processes owned by other users are only
+// resolved when the agent runs as root/Administrator.
+type ListeningPortsPlugin struct {
+	agent.PluginCommon
+	frequency      time.Duration
+	getConnections ConnectionsProvider
+	getProcess     ProcessProvider
+	previous       map[string]ListeningPort
+}
+
+func NewListeningPortsPlugin(id ids.PluginID, ctx agent.AgentContext) *ListeningPortsPlugin {
+	cfg := ctx.Config()
+	return &ListeningPortsPlugin{
+		PluginCommon: agent.PluginCommon{ID: id, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.ListeningPortsIntervalSec,
+			config.FREQ_MINIMUM_INVENTORY_SAMPLE_RATE,
+			config.FREQ_PLUGIN_LISTENING_PORTS,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		getConnections: func() ([]gnet.ConnectionStat, error) {
+			return gnet.ConnectionsWithoutUids("inet")
+		},
+		getProcess: gopsutilProcess,
+	}
+}
+
+func gopsutilProcess(pid int32) (name, user, binaryPath string) {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return
+	}
+	name, _ = proc.Name()
+	user, _ = proc.Username()
+	binaryPath, _ = proc.Exe()
+	return
+}
+
+// listeningPorts returns the listening sockets, by ID. A socket shared by several processes, ie: the workers of a
+// server, is reported for the one with the lowest pid.
+func (self *ListeningPortsPlugin) listeningPorts() (map[string]ListeningPort, error) {
+	conns, err := self.getConnections()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(conns, func(i, j int) bool {
+		// sockets whose process couldn't be resolved last
+		if conns[i].Pid == 0 || conns[j].Pid == 0 {
+			return conns[j].Pid == 0 && conns[i].Pid != 0
+		}
+		return conns[i].Pid < conns[j].Pid
+	})
+
+	type procInfo struct{ name, user, binaryPath string }
+	procs := make(map[int32]procInfo)
+	ports := make(map[string]ListeningPort)
+	for _, conn := range conns {
+		var protocol string
+		switch {
+		case conn.Type == sockStream && conn.Status == "LISTEN":
+			protocol = "tcp"
+		case conn.Type == sockDgram && conn.Raddr.Port == 0:
+			protocol = "udp"
+		default:
+			continue
+		}
+		if strings.Contains(conn.Laddr.IP, ":") {
+			protocol += "6"
+		}
+
+		id := protocol + "/" + net.JoinHostPort(conn.Laddr.IP, strconv.FormatUint(uint64(conn.Laddr.Port), 10))
+		if _, ok := ports[id]; ok {
+			continue
+		}
+		port := ListeningPort{
+			ID:       id,
+			Protocol: protocol,
+			Address:  conn.Laddr.IP,
+			Port:     strconv.FormatUint(uint64(conn.Laddr.Port), 10),
+			port:     conn.Laddr.Port,
+			pid:      conn.Pid,
+		}
+		if conn.Pid > 0 {
+			port.Pid = strconv.Itoa(int(conn.Pid))
+			info, ok := procs[conn.Pid]
+			if !ok {
+				info.name, info.user, info.binaryPath = self.getProcess(conn.Pid)
+				procs[conn.Pid] = info
+			}
+			port.ProcessName, port.User, port.BinaryPath = info.name, info.user, info.binaryPath
+		}
+		ports[id] = port
+	}
+	return ports, nil
+}
+
+// portChanges returns the ports opened and closed between two runs.
+func portChanges(previous, current map[string]ListeningPort) (opened, closed []ListeningPort) {
+	for id, port := range current {
+		if _, ok := previous[id]; !ok {
+			opened = append(opened, port)
+		}
+	}
+	for id, port := range previous {
+		if _, ok := current[id]; !ok {
+			closed = append(closed, port)
+		}
+	}
+	sort.Slice(opened, func(i, j int) bool { return opened[i].ID < opened[j].ID })
+	sort.Slice(closed, func(i, j int) bool { return closed[i].ID < closed[j].ID })
+	return
+}
+
+func listeningPortEvent(action string, port ListeningPort) map[string]interface{} {
+	return map[string]interface{}{
+		"eventType":   listeningPortEventType,
+		"action":      action,
+		"protocol":    port.Protocol,
+		"address":     port.Address,
+		"port":        port.port,
+		"pid":         port.pid,
+		"processName": port.ProcessName,
+		"user":        port.User,
+		"binaryPath":  port.BinaryPath,
+	}
+}
+
+func (self *ListeningPortsPlugin) harvest() {
+	ports, err := self.listeningPorts()
+	if err != nil {
+		slog.WithError(err).WithPlugin(self.Id().String()).Error("fetching listening ports")
+		return
+	}
+
+	// the ports found on the first run are the baseline, so restarting the agent doesn't report them as opened
+	if self.previous != nil {
+		opened, closed := portChanges(self.previous, ports)
+		for _, port := range opened {
+			self.EmitEvent(listeningPortEvent(PortOpened, port), entity.Key(self.Context.EntityKey()))
+		}
+		for _, port := range closed {
+			self.EmitEvent(listeningPortEvent(PortClosed, port), entity.Key(self.Context.EntityKey()))
+		}
+	}
+	self.previous = ports
+
+	dataset := make(types.PluginInventoryDataset, 0, len(ports))
+	for _, port := range ports {
+		dataset = append(dataset, port)
+	}
+	self.EmitInventory(dataset, entity.NewFromNameWithoutID(self.Context.EntityKey()))
+}
+
+func (self *ListeningPortsPlugin) Run() {
+	if self.frequency <= config.FREQ_DISABLE_SAMPLING {
+		slog.WithPlugin(self.Id().String()).Debug("Disabled.")
+		return
+	}
+
+	ticker := time.NewTicker(1)
+	for {
+		select {
+		case <-ticker.C:
+			ticker.Stop()
+			ticker = time.NewTicker(self.frequency)
+			self.harvest()
+		}
+	}
+}
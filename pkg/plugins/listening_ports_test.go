@@ -0,0 +1,72 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package plugins
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testing2 "github.com/newrelic/infrastructure-agent/internal/plugins/testing"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+)
+
+func TestListeningPorts(t *testing.T) {
+	p := NewListeningPortsPlugin(ids.PluginID{Category: "services", Term: "listening_ports"}, testing2.NewMockAgent())
+	p.getConnections = func() ([]net.ConnectionStat, error) {
+		return []net.ConnectionStat{
+			{Type: sockStream, Status: "LISTEN", Laddr: net.Addr{IP: "0.0.0.0", Port: 80}, Pid: 0},
+			{Type: sockStream, Status: "LISTEN", Laddr: net.Addr{IP: "0.0.0.0", Port: 80}, Pid: 12},
+			{Type: sockStream, Status: "LISTEN", Laddr: net.Addr{IP: "0.0.0.0", Port: 80}, Pid: 10},
+			{Type: sockStream, Status: "LISTEN", Laddr: net.Addr{IP: "::", Port: 22}, Pid: 1},
+			{Type: sockStream, Status: "ESTABLISHED", Laddr: net.Addr{IP: "10.0.0.1", Port: 22}, Raddr: net.Addr{IP: "10.0.0.2", Port: 5555}, Pid: 1},
+			{Type: sockDgram, Status: "NONE", Laddr: net.Addr{IP: "127.0.0.53", Port: 53}},
+			{Type: sockDgram, Status: "NONE", Laddr: net.Addr{IP: "10.0.0.1", Port: 40000}, Raddr: net.Addr{IP: "10.0.0.2", Port: 53}},
+		}, nil
+	}
+	var resolved []int32
+	p.getProcess = func(pid int32) (string, string, string) {
+		resolved = append(resolved, pid)
+		return "proc", "root", "/usr/sbin/proc"
+	}
+
+	ports, err := p.listeningPorts()
+	require.NoError(t, err)
+	require.Len(t, ports, 3)
+
+	assert.Equal(t, ListeningPort{
+		ID:          "tcp/0.0.0.0:80",
+		Protocol:    "tcp",
+		Address:     "0.0.0.0",
+		Port:        "80",
+		Pid:         "10",
+		ProcessName: "proc",
+		User:        "root",
+		BinaryPath:  "/usr/sbin/proc",
+		port:        80,
+		pid:         10,
+	}, ports["tcp/0.0.0.0:80"])
+	assert.Equal(t, "tcp6", ports["tcp6/[::]:22"].Protocol)
+	assert.Equal(t, ListeningPort{ID: "udp/127.0.0.53:53", Protocol: "udp", Address: "127.0.0.53", Port: "53", port: 53}, ports["udp/127.0.0.53:53"])
+	assert.ElementsMatch(t, []int32{1, 10}, resolved)
+}
+
+func TestPortChanges(t *testing.T) {
+	ssh := ListeningPort{ID: "tcp/0.0.0.0:22"}
+	http := ListeningPort{ID: "tcp/0.0.0.0:80"}
+	dns := ListeningPort{ID: "udp/127.0.0.53:53"}
+
+	opened, closed := portChanges(
+		map[string]ListeningPort{ssh.ID: ssh, dns.ID: dns},
+		map[string]ListeningPort{ssh.ID: ssh, http.ID: http},
+	)
+	assert.Equal(t, []ListeningPort{http}, opened)
+	assert.Equal(t, []ListeningPort{dns}, closed)
+
+	event := listeningPortEvent(PortOpened, ListeningPort{Protocol: "tcp", Address: "0.0.0.0", port: 80, pid: 10})
+	assert.Equal(t, "ListeningPortEvent", event["eventType"])
+	assert.Equal(t, "opened", event["action"])
+	assert.Equal(t, uint32(80), event["port"])
+}
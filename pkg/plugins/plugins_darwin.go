@@ -7,6 +7,8 @@ import (
 	"github.com/newrelic/infrastructure-agent/internal/plugins/common"
 	"github.com/newrelic/infrastructure-agent/internal/plugins/darwin"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/agentself"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/hostheartbeat"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/network"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/process"
 	metricsSender "github.com/newrelic/infrastructure-agent/pkg/metrics/sender"
@@ -32,24 +34,38 @@ func RegisterPlugins(a *agent.Agent) error {
 	}
 
 	sender := metricsSender.NewSender(a.Context)
-	procSampler := process.NewProcessSampler(a.Context)
-	storageSampler := storage.NewSampler(a.Context)
-	// nfsSampler := nfs.NewSampler(a.Context)
-	networkSampler := network.NewNetworkSampler(a.Context)
+	RegisterSamplers(a.Context, sender, a.SenderStats)
+	a.RegisterMetricsSender(sender)
+
+	return nil
+}
+
+// RegisterSamplers registers the enabled host samplers into the sender.
+func RegisterSamplers(ctx agent.AgentContext, sender *metricsSender.Sender, senderStats agentself.SenderStatsFn) {
+	config := ctx.Config()
+	procSampler := process.NewProcessSampler(ctx)
+	storageSampler := storage.NewSampler(ctx)
+	// nfsSampler := nfs.NewSampler(ctx)
+	networkSampler := network.NewNetworkSampler(ctx)
 
 	var ntpMonitor metrics.NtpMonitor
 	if config.NtpMetrics.Enabled {
 		ntpMonitor = metrics.NewNtp(config.NtpMetrics.Pool, config.NtpMetrics.Timeout, config.NtpMetrics.Interval)
 	}
-	systemSampler := metrics.NewSystemSampler(a.Context, storageSampler, ntpMonitor)
+	systemSampler := metrics.NewSystemSampler(ctx, storageSampler, ntpMonitor)
 
 	sender.RegisterSampler(systemSampler)
 	sender.RegisterSampler(storageSampler)
 	// sender.RegisterSampler(nfsSampler)
 	sender.RegisterSampler(networkSampler)
 	sender.RegisterSampler(procSampler)
+	// opt-in sampler, avoid warning about it being disabled
+	if selfSampler := agentself.NewSampler(ctx, senderStats); !selfSampler.Disabled() {
+		sender.RegisterSampler(selfSampler)
+	}
+	if heartbeatSampler := hostheartbeat.NewSampler(ctx, senderStats); !heartbeatSampler.Disabled() {
+		sender.RegisterSampler(heartbeatSampler)
+	}
 
-	a.RegisterMetricsSender(sender)
-
-	return nil
+	registerCompiledSamplers(ctx.Config(), sender)
 }
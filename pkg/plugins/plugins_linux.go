@@ -9,11 +9,16 @@ import (
 	config2 "github.com/newrelic/infrastructure-agent/pkg/config"
 	"github.com/newrelic/infrastructure-agent/pkg/helpers"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/agentself"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/containers"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/hostheartbeat"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/network"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/process"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/securitymodules"
 	metricsSender "github.com/newrelic/infrastructure-agent/pkg/metrics/sender"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage/nfs"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/systemdunits"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/proxy"
 	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
@@ -65,7 +70,9 @@ func RegisterPlugins(agent *agnt.Agent) error {
 	if !config.IsContainerized {
 		// register our plugins
 		agent.RegisterPlugin(pluginsLinux.NewUpstartPlugin(ids.PluginID{"services", "upstart"}, agent.Context))
-		agent.RegisterPlugin(pluginsLinux.NewSystemdPlugin(agent.Context))
+		if systemdAvailable(config) {
+			agent.RegisterPlugin(pluginsLinux.NewSystemdPlugin(agent.Context))
+		}
 		agent.RegisterPlugin(pluginsLinux.NewFacterPlugin(agent.Context))
 		if config.FilesConfigOn {
 			agent.RegisterPlugin(NewConfigFilePlugin(ids.PluginID{"files", "config"}, agent.Context))
@@ -74,6 +81,9 @@ func RegisterPlugins(agent *agnt.Agent) error {
 		agent.RegisterPlugin(pluginsLinux.NewDaemontoolsPlugin(ids.PluginID{"services", "daemontools"}, agent.Context))
 		agent.RegisterPlugin(pluginsLinux.NewSupervisorPlugin(ids.PluginID{"services", "supervisord"}, agent.Context))
 		agent.RegisterPlugin(NewNetworkInterfacePlugin(ids.PluginID{"system", "network_interfaces"}, agent.Context))
+		if config.ListeningPortsEnabled {
+			agent.RegisterPlugin(NewListeningPortsPlugin(ids.PluginID{"services", "listening_ports"}, agent.Context))
+		}
 
 		if config.RunMode == config2.ModeRoot || config.RunMode == config2.ModePrivileged {
 			id := ids.PluginID{"kernel", "sysctl"}
@@ -88,6 +98,9 @@ func RegisterPlugins(agent *agnt.Agent) error {
 				agent.RegisterPlugin(pluginsLinux.NewSysctlPollingMonitor(id, agent.Context))
 			}
 			agent.RegisterPlugin(pluginsLinux.NewKernelModulesPlugin(ids.PluginID{"kernel", "modules"}, agent.Context))
+			if config.KernelBootEnabled {
+				agent.RegisterPlugin(pluginsLinux.NewKernelBootPlugin(ids.PluginID{"kernel", "boot"}, agent.Context))
+			}
 			agent.RegisterPlugin(pluginsLinux.NewSysvInitPlugin(ids.PluginID{"services", "pidfile"}, agent.Context))
 			agent.RegisterPlugin(pluginsLinux.NewSshdConfigPlugin(ids.PluginID{"config", "sshd"}, agent.Context))
 
@@ -100,6 +113,10 @@ func RegisterPlugins(agent *agnt.Agent) error {
 			case helpers.LINUX_REDHAT, helpers.LINUX_AWS_REDHAT, helpers.LINUX_SUSE:
 				slog.Debug("Registering RPM plugins.")
 				agent.RegisterPlugin(pluginsLinux.NewRpmPlugin(agent.Context))
+
+			case helpers.LINUX_ALPINE:
+				slog.Debug("Registering Alpine plugins.")
+				agent.RegisterPlugin(pluginsLinux.NewApkPlugin(ids.PluginID{"packages", "apk"}, agent.Context))
 			}
 		}
 
@@ -109,20 +126,34 @@ func RegisterPlugins(agent *agnt.Agent) error {
 
 		if agent.GetCloudHarvester().GetCloudType() == cloud.TypeAWS {
 			agent.RegisterPlugin(pluginsLinux.NewCloudSecurityGroupsPlugin(ids.PluginID{"metadata", "cloud_security_groups"}, agent.Context, agent.GetCloudHarvester()))
+			agent.RegisterPlugin(NewAWSInstanceEventsPlugin(ids.PluginID{"metadata", "aws_instance_events"}, agent.Context, agent.GetCloudHarvester()))
+		}
+		switch agent.GetCloudHarvester().GetCloudType() {
+		case cloud.TypeAWS, cloud.TypeGCP, cloud.TypeAzure:
+			agent.RegisterPlugin(NewTerminationNoticePlugin(ids.PluginID{"metadata", "termination_notice"}, agent.Context, agent.GetCloudHarvester(), agent.FlushQueuedData))
 		}
 	}
 
 	sender := metricsSender.NewSender(agent.Context)
-	procSampler := process.NewProcessSampler(agent.Context)
-	storageSampler := storage.NewSampler(agent.Context)
-	nfsSampler := nfs.NewSampler(agent.Context)
-	networkSampler := network.NewNetworkSampler(agent.Context)
+	RegisterSamplers(agent.Context, sender, agent.SenderStats)
+	agent.RegisterMetricsSender(sender)
+
+	return nil
+}
+
+// RegisterSamplers registers the enabled host samplers into the sender.
+func RegisterSamplers(ctx agnt.AgentContext, sender *metricsSender.Sender, senderStats agentself.SenderStatsFn) {
+	config := ctx.Config()
+	procSampler := process.NewProcessSampler(ctx)
+	storageSampler := storage.NewSampler(ctx)
+	nfsSampler := nfs.NewSampler(ctx)
+	networkSampler := network.NewNetworkSampler(ctx)
 
 	var ntpMonitor metrics.NtpMonitor
 	if config.NtpMetrics.Enabled {
 		ntpMonitor = metrics.NewNtp(config.NtpMetrics.Pool, config.NtpMetrics.Timeout, config.NtpMetrics.Interval)
 	}
-	systemSampler := metrics.NewSystemSampler(agent.Context, storageSampler, ntpMonitor)
+	systemSampler := metrics.NewSystemSampler(ctx, storageSampler, ntpMonitor)
 
 	// Prime Storage Sampler, ignoring results
 	if !storageSampler.Disabled() {
@@ -146,7 +177,31 @@ func RegisterPlugins(agent *agnt.Agent) error {
 	sender.RegisterSampler(networkSampler)
 	sender.RegisterSampler(procSampler)
 
-	agent.RegisterMetricsSender(sender)
+	// opt-in samplers, avoid warning about them being disabled
+	if unitSampler := systemdunits.NewSampler(ctx); !unitSampler.Disabled() && systemdAvailable(ctx.Config()) {
+		sender.RegisterSampler(unitSampler)
+	}
+	if containerSampler := containers.NewSampler(ctx); !containerSampler.Disabled() {
+		sender.RegisterSampler(containerSampler)
+	}
+	if securitySampler := securitymodules.NewSampler(ctx); !securitySampler.Disabled() {
+		sender.RegisterSampler(securitySampler)
+	}
+	if selfSampler := agentself.NewSampler(ctx, senderStats); !selfSampler.Disabled() {
+		sender.RegisterSampler(selfSampler)
+	}
+	if heartbeatSampler := hostheartbeat.NewSampler(ctx, senderStats); !heartbeatSampler.Disabled() {
+		sender.RegisterSampler(heartbeatSampler)
+	}
 
-	return nil
+	registerCompiledSamplers(ctx.Config(), sender)
+}
+
+// systemdAvailable returns false in WSL2 distributions not booted with systemd, where querying it just fails.
+func systemdAvailable(cfg *config2.Config) bool {
+	if cfg.HostEnvironment != helpers.EnvironmentWSL2 || helpers.IsSystemdRunning() {
+		return true
+	}
+	slog.WithField("environment", cfg.HostEnvironment).Debug("Systemd is not running, skipping its plugin and sampler.")
+	return false
 }
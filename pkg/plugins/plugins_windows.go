@@ -4,11 +4,16 @@ package plugins
 
 import (
 	"github.com/newrelic/infrastructure-agent/internal/plugins/common"
+	"github.com/newrelic/infrastructure-agent/pkg/helpers"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/agentself"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/hostheartbeat"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/network"
 	metricsSender "github.com/newrelic/infrastructure-agent/pkg/metrics/sender"
 	"github.com/newrelic/infrastructure-agent/pkg/metrics/storage"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/winservices"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
 	"github.com/newrelic/infrastructure-agent/pkg/plugins/proxy"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
 
 	"github.com/newrelic/infrastructure-agent/internal/agent"
 	pluginsWindows "github.com/newrelic/infrastructure-agent/internal/plugins/windows"
@@ -43,9 +48,24 @@ func RegisterPlugins(a *agent.Agent) error {
 	}
 
 	a.RegisterPlugin(NewNetworkInterfacePlugin(ids.PluginID{"system", "network_interfaces"}, a.Context))
+	if config.ListeningPortsEnabled {
+		a.RegisterPlugin(NewListeningPortsPlugin(ids.PluginID{"services", "listening_ports"}, a.Context))
+	}
+	if a.GetCloudHarvester().GetCloudType() == cloud.TypeAWS {
+		a.RegisterPlugin(NewAWSInstanceEventsPlugin(ids.PluginID{"metadata", "aws_instance_events"}, a.Context, a.GetCloudHarvester()))
+	}
+	switch a.GetCloudHarvester().GetCloudType() {
+	case cloud.TypeAWS, cloud.TypeGCP, cloud.TypeAzure:
+		a.RegisterPlugin(NewTerminationNoticePlugin(ids.PluginID{"metadata", "termination_notice"}, a.Context, a.GetCloudHarvester(), a.FlushQueuedData))
+	}
 	a.RegisterPlugin(pluginsWindows.NewServicesPlugin(ids.PluginID{"services", "windows_services"}, a.Context))
-	if config.EnableWinUpdatePlugin {
-		a.RegisterPlugin(pluginsWindows.NewUpdatesPlugin(ids.PluginID{"packages", "windows_updates"}, a.Context))
+	a.RegisterPlugin(pluginsWindows.NewSoftwarePlugin(ids.PluginID{"packages", "windows_software"}, a.Context))
+	// containers are neither rebooted nor updated, their image is replaced
+	if config.HostEnvironment != helpers.EnvironmentWindowsContainer {
+		a.RegisterPlugin(pluginsWindows.NewPendingRebootPlugin(ids.PluginID{"system", "pending_reboot"}, a.Context))
+		if config.EnableWinUpdatePlugin {
+			a.RegisterPlugin(pluginsWindows.NewUpdatesPlugin(ids.PluginID{"packages", "windows_updates"}, a.Context))
+		}
 	}
 
 	if config.FilesConfigOn {
@@ -53,15 +73,24 @@ func RegisterPlugins(a *agent.Agent) error {
 	}
 
 	sender := metricsSender.NewSender(a.Context)
-	procSampler := metrics.NewProcsMonitor(a.Context)
-	storageSampler := storage.NewSampler(a.Context)
+	RegisterSamplers(a.Context, sender, a.SenderStats)
+	a.RegisterMetricsSender(sender)
+
+	return nil
+}
+
+// RegisterSamplers registers the enabled host samplers into the sender.
+func RegisterSamplers(ctx agent.AgentContext, sender *metricsSender.Sender, senderStats agentself.SenderStatsFn) {
+	config := ctx.Config()
+	procSampler := metrics.NewProcsMonitor(ctx)
+	storageSampler := storage.NewSampler(ctx)
 	// Prime Storage Sampler, ignoring results
 	slog.Debug("Prewarming Sampler Cache.")
 	if _, err := storageSampler.Sample(); err != nil {
 		slog.WithError(err).Debug("Warming up Storage Sampler Cache.")
 	}
 
-	networkSampler := network.NewNetworkSampler(a.Context)
+	networkSampler := network.NewNetworkSampler(ctx)
 	// Prime Network Sampler, ignoring results
 	slog.Debug("Prewarming NetworkSampler Cache.")
 	if _, err := networkSampler.Sample(); err != nil {
@@ -72,12 +101,21 @@ func RegisterPlugins(a *agent.Agent) error {
 	if config.NtpMetrics.Enabled {
 		ntpMonitor = metrics.NewNtp(config.NtpMetrics.Pool, config.NtpMetrics.Timeout, config.NtpMetrics.Interval)
 	}
-	systemSampler := metrics.NewSystemSampler(a.Context, storageSampler, ntpMonitor)
+	systemSampler := metrics.NewSystemSampler(ctx, storageSampler, ntpMonitor)
 	sender.RegisterSampler(systemSampler)
 	sender.RegisterSampler(storageSampler)
 	sender.RegisterSampler(networkSampler)
 	sender.RegisterSampler(procSampler)
-	a.RegisterMetricsSender(sender)
+	// opt-in samplers, avoid warning about them being disabled
+	if serviceSampler := winservices.NewSampler(ctx); !serviceSampler.Disabled() {
+		sender.RegisterSampler(serviceSampler)
+	}
+	if selfSampler := agentself.NewSampler(ctx, senderStats); !selfSampler.Disabled() {
+		sender.RegisterSampler(selfSampler)
+	}
+	if heartbeatSampler := hostheartbeat.NewSampler(ctx, senderStats); !heartbeatSampler.Disabled() {
+		sender.RegisterSampler(heartbeatSampler)
+	}
 
-	return nil
+	registerCompiledSamplers(ctx.Config(), sender)
 }
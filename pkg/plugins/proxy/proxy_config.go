@@ -75,6 +75,17 @@ func ConfigPlugin(ctx agent.AgentContext) agent.Plugin {
 		e.Id = "proxy"
 		proxyConfig = append(proxyConfig, e)
 	}
+	for id, endpointProxy := range map[string]string{
+		"metrics_proxy":     cfg.MetricsProxy,
+		"inventory_proxy":   cfg.InventoryProxy,
+		"command_api_proxy": cfg.CommandAPIProxy,
+		"logs_proxy":        cfg.LogsProxy,
+	} {
+		if e := urlEntry(endpointProxy); e != nil {
+			e.Id = id
+			proxyConfig = append(proxyConfig, e)
+		}
+	}
 	if e := pathEntry(cfg.CABundleDir); e != nil {
 		e.Id = "ca_bundle_dir"
 		proxyConfig = append(proxyConfig, e)
@@ -0,0 +1,26 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package plugins
+
+import (
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/metrics/sampler"
+	metricsSender "github.com/newrelic/infrastructure-agent/pkg/metrics/sender"
+)
+
+// registerCompiledSamplers registers the samplers compiled into the agent through sampler.Register. The samplers
+// failing to be created are skipped.
+func registerCompiledSamplers(cfg *config.Config, sender *metricsSender.Sender) {
+	for _, r := range sampler.Registered() {
+		s, err := r.Create(cfg)
+		if err != nil {
+			slog.WithError(err).WithField("sampler", r.Name).Error("can't create sampler, it will not run")
+			continue
+		}
+		if s == nil {
+			slog.WithField("sampler", r.Name).Debug("Sampler not configured.")
+			continue
+		}
+		sender.RegisterSampler(s)
+	}
+}
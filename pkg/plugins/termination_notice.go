@@ -0,0 +1,111 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package plugins
+
+import (
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/internal/agent"
+	"github.com/newrelic/infrastructure-agent/pkg/config"
+	"github.com/newrelic/infrastructure-agent/pkg/entity"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+)
+
+const hostTerminationImminentEventType = "HostTerminationImminent"
+
+// TerminationNoticePlugin polls the termination notices of the cloud provider. On notice, it sends a
+// HostTerminationImminent event and flushes the queued data, so it reaches New Relic before the host is gone.
+// The notice is only handled once, as the host won't outlive it.
+type TerminationNoticePlugin struct {
+	agent.PluginCommon
+	frequency time.Duration
+	harvester cloud.Harvester
+	source    cloud.TerminationNoticeHarvester
+	flush     func()
+	notified  bool
+}
+
+// NewTerminationNoticePlugin creates the plugin, which calls flush after sending the termination event.
+func NewTerminationNoticePlugin(id ids.PluginID, ctx agent.AgentContext, harvester cloud.Harvester, flush func()) *TerminationNoticePlugin {
+	cfg := ctx.Config()
+	return &TerminationNoticePlugin{
+		PluginCommon: agent.PluginCommon{ID: id, Context: ctx},
+		frequency: config.ValidateConfigFrequencySetting(
+			cfg.TerminationNoticeIntervalSec,
+			config.FREQ_INTERVAL_FLOOR_METRICS,
+			config.FREQ_PLUGIN_TERMINATION_NOTICE,
+			cfg.DisableAllPlugins,
+		) * time.Second,
+		harvester: harvester,
+		flush:     flush,
+	}
+}
+
+// noticeSource returns the cloud harvester detected, nil until it's available.
+func (self *TerminationNoticePlugin) noticeSource() cloud.TerminationNoticeHarvester {
+	if self.source != nil {
+		return self.source
+	}
+	h, err := self.harvester.GetHarvester()
+	if err != nil {
+		return nil
+	}
+	self.source, _ = h.(cloud.TerminationNoticeHarvester)
+	return self.source
+}
+
+// harvest returns whether the termination notice was received.
+func (self *TerminationNoticePlugin) harvest() bool {
+	if self.notified {
+		return true
+	}
+	source := self.noticeSource()
+	if source == nil {
+		slog.WithPlugin(self.Id().String()).Debug("Cloud harvester not available.")
+		return false
+	}
+
+	notice, err := source.GetTerminationNotice()
+	if err != nil {
+		slog.WithError(err).WithPlugin(self.Id().String()).Debug("fetching termination notice")
+		return false
+	}
+	if notice == nil {
+		return false
+	}
+	self.notified = true
+
+	slog.WithPlugin(self.Id().String()).WithField("action", notice.Action).WithField("time", notice.Time).
+		Warn("Host termination notice received, flushing queued data.")
+	self.EmitEvent(map[string]interface{}{
+		"eventType":       hostTerminationImminentEventType,
+		"provider":        string(self.harvester.GetCloudType()),
+		"action":          notice.Action,
+		"terminationTime": notice.Time,
+		"description":     notice.Description,
+	}, entity.Key(self.Context.EntityKey()))
+	if self.flush != nil {
+		self.flush()
+	}
+	return true
+}
+
+func (self *TerminationNoticePlugin) Run() {
+	if self.frequency <= config.FREQ_DISABLE_SAMPLING {
+		slog.WithPlugin(self.Id().String()).Debug("Disabled.")
+		return
+	}
+
+	ticker := time.NewTicker(1)
+	for {
+		select {
+		case <-ticker.C:
+			ticker.Stop()
+			if self.harvest() {
+				return
+			}
+			ticker = time.NewTicker(self.frequency)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	testing2 "github.com/newrelic/infrastructure-agent/internal/plugins/testing"
+	"github.com/newrelic/infrastructure-agent/pkg/plugins/ids"
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo/cloud"
+)
+
+type fakeTerminationHarvester struct {
+	cloud.Harvester
+	notice *cloud.TerminationNotice
+}
+
+func (f *fakeTerminationHarvester) GetCloudType() cloud.Type {
+	return cloud.TypeGCP
+}
+
+func (f *fakeTerminationHarvester) GetHarvester() (cloud.Harvester, error) {
+	return f, nil
+}
+
+func (f *fakeTerminationHarvester) GetTerminationNotice() (*cloud.TerminationNotice, error) {
+	return f.notice, nil
+}
+
+func TestTerminationNotice(t *testing.T) {
+	ctx := &eventsRecorder{MockAgent: testing2.NewMockAgent()}
+	harvester := &fakeTerminationHarvester{}
+	flushes := 0
+	p := NewTerminationNoticePlugin(ids.PluginID{Category: "metadata", Term: "termination_notice"}, ctx, harvester, func() {
+		flushes++
+	})
+
+	assert.False(t, p.harvest())
+	assert.Empty(t, ctx.events)
+	assert.Zero(t, flushes)
+
+	harvester.notice = &cloud.TerminationNotice{Action: cloud.TerminationActionPreempt, Description: "VM preemption"}
+	assert.True(t, p.harvest())
+	// the notice is only handled once
+	assert.True(t, p.harvest())
+
+	require.Len(t, ctx.events, 1)
+	assert.Equal(t, "HostTerminationImminent", ctx.events[0]["eventType"])
+	assert.Equal(t, "gcp", ctx.events[0]["provider"])
+	assert.Equal(t, "preempt", ctx.events[0]["action"])
+	assert.Equal(t, "VM preemption", ctx.events[0]["description"])
+	assert.Equal(t, 1, flushes)
+}
@@ -3,6 +3,8 @@
 package sample
 
 import (
+	"time"
+
 	"github.com/newrelic/infrastructure-agent/pkg/entity"
 )
 
@@ -16,6 +18,14 @@ type Event interface {
 	Timestamp(timestamp int64)
 }
 
+// MetaEvent is implemented by events able to carry collection meta-attributes.
+type MetaEvent interface {
+	// CollectionDuration sets the "collectionDurationMs" marshallable field
+	CollectionDuration(d time.Duration)
+	// DataAge sets the "dataAgeMs" marshallable field
+	DataAge(age time.Duration)
+}
+
 // EventBatch is a slice of Event
 type EventBatch []Event
 
@@ -25,9 +35,15 @@ type BaseEvent struct {
 	EventType string `json:"eventType"`
 	Timestmp  int64  `json:"timestamp"`
 	EntityKey string `json:"entityKey"`
+	// Collection meta-attributes, only reported when enabled
+	CollectionDurationMs *int64 `json:"collectionDurationMs,omitempty"`
+	DataAgeMs            *int64 `json:"dataAgeMs,omitempty"`
 }
 
-var _ Event = (*BaseEvent)(nil) // BaseEvent implements sample.Event
+var (
+	_ Event     = (*BaseEvent)(nil) // BaseEvent implements sample.Event
+	_ MetaEvent = (*BaseEvent)(nil) // BaseEvent implements sample.MetaEvent
+)
 
 // Type sets the event type
 func (bse *BaseEvent) Type(eventType string) {
@@ -43,3 +59,15 @@ func (bse *BaseEvent) Entity(key entity.Key) {
 func (bse *BaseEvent) Timestamp(timestamp int64) {
 	bse.Timestmp = timestamp
 }
+
+// CollectionDuration sets the time taken to collect the event
+func (bse *BaseEvent) CollectionDuration(d time.Duration) {
+	ms := d.Milliseconds()
+	bse.CollectionDurationMs = &ms
+}
+
+// DataAge sets the age of the event data when served from a cache
+func (bse *BaseEvent) DataAge(age time.Duration) {
+	ms := age.Milliseconds()
+	bse.DataAgeMs = &ms
+}
@@ -21,6 +21,8 @@ const (
 	TypeAzure      Type = "azure"       // This instance is running in Azure.
 	TypeGCP        Type = "gcp"         // This instance is running in gcp.
 	TypeAlibaba    Type = "alibaba"     // This instance is running in alibaba.
+	TypeOCI        Type = "oci"         // This instance is running in Oracle Cloud Infrastructure.
+	TypeHetzner    Type = "hetzner"     // This instance is running in Hetzner Cloud.
 )
 
 var dlog = log.WithComponent("CloudDetector")
@@ -34,7 +36,9 @@ func (t Type) IsValidCloud() bool {
 	return t == TypeAWS ||
 		t == TypeAzure ||
 		t == TypeGCP ||
-		t == TypeAlibaba
+		t == TypeAlibaba ||
+		t == TypeOCI ||
+		t == TypeHetzner
 }
 
 var (
@@ -68,6 +72,15 @@ type Harvester interface {
 	GetHarvester() (Harvester, error)
 }
 
+// CapacityHarvester is implemented by the harvesters of the clouds grouping instances in scale sets (Azure) or
+// managed instance groups (GCP), and offering evictable spot or preemptible instances.
+type CapacityHarvester interface {
+	// GetScaleGroup returns the scale set or instance group of the instance, empty when it's not in one.
+	GetScaleGroup() (string, error)
+	// IsSpot returns whether the instance is a spot or preemptible one, which can be evicted at any time.
+	IsSpot() (bool, error)
+}
+
 // Detector is used to detect the cloud type on which the instance is running
 // and can be queried in order to get the information needed.
 type Detector struct {
@@ -80,6 +93,15 @@ type Detector struct {
 	initialized          bool          // Flag to determine when the Detector is initialized.
 	inProgress           bool          // Flag to determine when Detector initialization is in progress.
 	disableKeepAlive     bool          // Disables HTTP keep-alives and will only use the connection to the server for a single HTTP request.
+	imdsV2Only           bool          // Requires IMDSv2 session tokens to query the AWS metadata service.
+}
+
+// MetadataReport describes the outcome of the cloud detection, reported by the agent status.
+type MetadataReport struct {
+	Type Type `json:"type"`
+	// IMDSVersion is the AWS instance metadata service version in use, v2 unless falling back to v1.
+	IMDSVersion string `json:"imds_version,omitempty"`
+	Warning     string `json:"warning,omitempty"`
 }
 
 // NewDetector returns a new Detector instance.
@@ -95,11 +117,19 @@ func NewDetector(disableCloudMetadata bool, maxRetriesNumber, retryBackOffSec, e
 
 type DetectorOption func(*Detector)
 
+// WithIMDSv2Only prevents the AWS harvester from falling back to IMDSv1 when the IMDSv2 session token cannot be
+// retrieved. It must precede WithProvider.
+func WithIMDSv2Only(enabled bool) DetectorOption {
+	return func(detector *Detector) {
+		detector.imdsV2Only = enabled
+	}
+}
+
 func WithProvider(cloudType Type) DetectorOption {
 	return func(detector *Detector) {
 		switch cloudType {
 		case TypeAWS:
-			detector.setHarvester(NewAWSHarvester(detector.disableKeepAlive))
+			detector.setHarvester(detector.newAWSHarvester())
 			detector.finishInit()
 		case TypeAzure:
 			detector.setHarvester(NewAzureHarvester(detector.disableKeepAlive))
@@ -110,6 +140,12 @@ func WithProvider(cloudType Type) DetectorOption {
 		case TypeAlibaba:
 			detector.setHarvester(NewAlibabaHarvester(detector.disableKeepAlive))
 			detector.finishInit()
+		case TypeOCI:
+			detector.setHarvester(NewOCIHarvester(detector.disableKeepAlive))
+			detector.finishInit()
+		case TypeHetzner:
+			detector.setHarvester(NewHetznerHarvester(detector.disableKeepAlive))
+			detector.finishInit()
 		case TypeNoCloud:
 		case TypeInProgress:
 		default:
@@ -129,14 +165,23 @@ func (d *Detector) Initialize(opts ...DetectorOption) {
 	}
 
 	harvesters := []Harvester{
-		NewAWSHarvester(d.disableKeepAlive),
+		d.newAWSHarvester(),
 		NewAzureHarvester(d.disableKeepAlive),
 		NewGCPHarvester(d.disableKeepAlive),
 		NewAlibabaHarvester(d.disableKeepAlive),
+		NewOCIHarvester(d.disableKeepAlive),
+		NewHetznerHarvester(d.disableKeepAlive),
 	}
 	d.initialize(harvesters...)
 }
 
+func (d *Detector) newAWSHarvester() *AWSHarvester {
+	if d.imdsV2Only {
+		return NewAWSHarvesterIMDSv2Only(d.disableKeepAlive)
+	}
+	return NewAWSHarvester(d.disableKeepAlive)
+}
+
 // initialize should be called in order to Detect the cloud harvester.
 func (d *Detector) initialize(harvesters ...Harvester) {
 	if d.isInitialized() || d.isInProgress() {
@@ -241,6 +286,15 @@ func (d *Detector) GetCloudSource() string {
 	return cloudHarvester.GetCloudSource()
 }
 
+// Report returns the detected cloud type and, on AWS, the metadata service version in use.
+func (d *Detector) Report() MetadataReport {
+	report := MetadataReport{Type: d.GetCloudType()}
+	if aws, ok := d.getHarvester().(*AWSHarvester); ok {
+		report.IMDSVersion, report.Warning = aws.IMDSStatus()
+	}
+	return report
+}
+
 // isInitialized will check if the detector is Initialized.
 func (d *Detector) isInitialized() bool {
 	d.lock.RLock()
@@ -351,3 +405,8 @@ func (t *Timeout) HasExpired() bool {
 	}
 	return false
 }
+
+// restart makes the timeout expire an interval after the provided time.
+func (t *Timeout) restart(from time.Time) {
+	t.expiry = from.Add(t.interval)
+}
@@ -0,0 +1,42 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAlibabaMetadata(t *testing.T) {
+	response := &http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(bytes.NewBufferString(`{
+			"zone-id": "ap-southeast-2b",
+			"serial-number": "d54da90b-fdde-46a9-bb0b-703946c53411",
+			"instance-id": "i-p0we7kj126dhd52fh5w8",
+			"region-id": "ap-southeast-2",
+			"private-ipv4": "172.27.17.70",
+			"owner-account-id": "5075089599391873",
+			"mac": "00:16:3e:00:0f:de",
+			"image-id": "ubuntu_18_04_64_20G_alibase_20190223.vhd",
+			"instance-type": "ecs.t5-lc1m1.small"
+		}`)),
+	}
+
+	metadata, err := parseAlibabaMetadataResponse(response)
+	require.NoError(t, err)
+	assert.Equal(t, &AlibabaMetadata{
+		RegionID:        "ap-southeast-2",
+		InstanceID:      "i-p0we7kj126dhd52fh5w8",
+		InstanceType:    "ecs.t5-lc1m1.small",
+		InstanceImageID: "ubuntu_18_04_64_20G_alibase_20190223.vhd",
+		AccountID:       "5075089599391873",
+		Zone:            "ap-southeast-2b",
+	}, metadata)
+}
@@ -3,13 +3,18 @@
 package cloud
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"regexp"
 	"strings"
 
@@ -35,8 +40,31 @@ const (
 	awsMetaDataPath             = "/latest/meta-data/"
 	instanceIdentityDocumentURL = "/latest/dynamic/instance-identity/document"
 	defaultTimeout              = 600
+
+	// IMDS versions used to query the AWS instance metadata service.
+	IMDSVersion2 = "v2"
+	IMDSVersion1 = "v1"
+
+	// defaultTokenRequestTimeout bounds the IMDSv2 token request. When the PUT response hop limit is lower than the
+	// network hops to the agent, as with the default limit of 1 inside containers, the response never arrives.
+	defaultTokenRequestTimeout = 2 * time.Second
+
+	hopLimitWarning = "IMDSv2 token request timed out, the instance metadata PUT response hop limit is probably too low " +
+		"for the agent to run in a container. Raise it with: aws ec2 modify-instance-metadata-options " +
+		"--instance-id <instance-id> --http-put-response-hop-limit 2"
+)
+
+// Instance metadata paths, relative to awsMetaDataPath, of the instance life-cycle and its upcoming interruptions.
+const (
+	instanceLifecyclePath       = "instance-life-cycle"
+	spotInstanceActionPath      = "spot/instance-action"
+	rebalanceRecommendationPath = "events/recommendations/rebalance"
+	scheduledEventsPath         = "events/maintenance/scheduled"
 )
 
+// errTokenTimeout is returned when the metadata service accepted the connection but didn't answer the token request.
+var errTokenTimeout = errors.New("IMDSv2 token request timed out")
+
 // AWSHarvester is used to fetch data from AWS api.
 type AWSHarvester struct {
 	timeout                *Timeout
@@ -45,6 +73,11 @@ type AWSHarvester struct {
 	instanceID             string // Cache the amazon instance ID.
 	awsEC2MetadataHostname string
 	awsEC2MetadataToken    atomic.Value
+	tokenLock              sync.Mutex    // Serializes the token requests.
+	tokenRequestTimeout    time.Duration // Bounds the token request, which times out when the hop limit is too low.
+	imdsV2Only             bool          // Don't fall back to IMDSv1 when a token cannot be retrieved.
+	imdsVersion            atomic.Value
+	imdsWarning            atomic.Value
 	instanceIdentityCache  *instanceIdentity
 	httpClient             *http.Client
 }
@@ -63,12 +96,33 @@ func NewAWSHarvester(disableKeepAlive bool) *AWSHarvester {
 	return &AWSHarvester{
 		timeout:                NewTimeout(defaultTimeout),
 		tokenTimeout:           NewTimeout(defaultTimeout),
+		tokenRequestTimeout:    defaultTokenRequestTimeout,
 		disableKeepAlive:       disableKeepAlive,
 		awsEC2MetadataHostname: awsEC2MetadataHostname,
 		httpClient:             clientWithFastTimeout(disableKeepAlive),
 	}
 }
 
+// NewAWSHarvesterIMDSv2Only returns a new AWSHarvester which won't fall back to IMDSv1 when
+// the IMDSv2 session token cannot be retrieved.
+func NewAWSHarvesterIMDSv2Only(disableKeepAlive bool) *AWSHarvester {
+	h := NewAWSHarvester(disableKeepAlive)
+	h.imdsV2Only = true
+	return h
+}
+
+// IMDSStatus returns the metadata service version used by the last requests, empty until the first one,
+// and the reason why IMDSv2 couldn't be used, if any.
+func (a *AWSHarvester) IMDSStatus() (version string, warning string) {
+	if v, ok := a.imdsVersion.Load().(string); ok {
+		version = v
+	}
+	if w, ok := a.imdsWarning.Load().(string); ok {
+		warning = w
+	}
+	return
+}
+
 // GetHarvester returns instance of the Harvester detected (or instance of themselves)
 func (a *AWSHarvester) GetHarvester() (Harvester, error) {
 	return a, nil
@@ -179,23 +233,10 @@ func formatURL(baseUrl string, query string) string {
 //	    "region" : "us-west-2"
 //	}
 func (a *AWSHarvester) getInstanceIdentity() (*instanceIdentity, error) {
-	token, err := a.getToken()
+	response, err := a.doRequest(a.httpClient, a.awsEC2MetadataHostname+instanceIdentityDocumentURL)
 	if err != nil {
 		return nil, err
 	}
-
-	documentURL := a.awsEC2MetadataHostname + instanceIdentityDocumentURL
-	request, err := http.NewRequest(http.MethodGet, documentURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("unable to prepare AWS metadata request: %v", request)
-	}
-
-	request.Header.Add("X-aws-ec2-metadata-token", token)
-
-	response, err := a.httpClient.Do(request)
-	if err != nil {
-		return nil, fmt.Errorf("unable to fetch AWS metadata: %s", err)
-	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
@@ -218,14 +259,91 @@ func (a *AWSHarvester) getInstanceIdentity() (*instanceIdentity, error) {
 	return &i, nil
 }
 
+// doRequest sends a GET request to the metadata service with the IMDSv2 session token, when available. A request
+// rejected as unauthorized with a cached token is retried once with a new one, as the token may have been revoked.
+func (a *AWSHarvester) doRequest(client *http.Client, url string) (*http.Response, error) {
+	for retried := false; ; retried = true {
+		token, err := a.getToken()
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to prepare AWS metadata request: %v", request)
+		}
+		if token != "" {
+			request.Header.Add(tokenHeader, token)
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch AWS metadata: %s", err)
+		}
+		if response.StatusCode != http.StatusUnauthorized || token == "" || retried {
+			return response, nil
+		}
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+		a.invalidateToken()
+	}
+}
+
+// getToken returns the cached IMDSv2 session token, requesting a new one once it expires. An empty token is
+// returned, falling back to IMDSv1, when the token request times out and IMDSv2 is not enforced.
 func (a *AWSHarvester) getToken() (string, error) {
+	a.tokenLock.Lock()
+	defer a.tokenLock.Unlock()
 
 	if token := a.awsEC2MetadataToken.Load(); token != nil && !a.tokenTimeout.HasExpired() {
 		return token.(string), nil
 	}
 
+	requestedAt := time.Now()
+	token, err := a.requestToken()
+	if err == nil {
+		a.awsEC2MetadataToken.Store(token)
+		// the token TTL starts counting when it's issued, not when the cache is checked
+		a.tokenTimeout.restart(requestedAt)
+		a.imdsVersion.Store(IMDSVersion2)
+		a.imdsWarning.Store("")
+		return token, nil
+	}
+
+	if a.imdsV2Only || !errors.Is(err, errTokenTimeout) {
+		return "", err
+	}
+
+	if w, _ := a.imdsWarning.Load().(string); w == "" {
+		dlog.Warn(hopLimitWarning + ". Falling back to IMDSv1.")
+	}
+	a.imdsWarning.Store(hopLimitWarning)
+	a.imdsVersion.Store(IMDSVersion1)
+	// IMDSv2 is retried once the token would have expired, not paying the request timeout on every request
+	a.awsEC2MetadataToken.Store("")
+	a.tokenTimeout.restart(time.Now())
+	return "", nil
+}
+
+// invalidateToken forces a new token request on the next metadata request.
+func (a *AWSHarvester) invalidateToken() {
+	a.tokenLock.Lock()
+	defer a.tokenLock.Unlock()
+	a.tokenTimeout.expiry = time.Time{}
+}
+
+func (a *AWSHarvester) requestToken() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.tokenRequestTimeout)
+	defer cancel()
+
+	// a timeout once connected means the response was dropped on its way, otherwise the service is not reachable
+	var connected int32
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { atomic.StoreInt32(&connected, 1) },
+	})
+
 	tokenURL := a.awsEC2MetadataHostname + tokenEndpoint
-	request, err := http.NewRequest(http.MethodPut, tokenURL, nil)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, tokenURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("unable to prepare AWS metadata request: %v", request)
 	}
@@ -237,8 +355,13 @@ func (a *AWSHarvester) getToken() (string, error) {
 	}
 	response, err := a.httpClient.Do(request)
 	if err != nil {
+		if atomic.LoadInt32(&connected) == 1 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", errTokenTimeout
+		}
 		return "", fmt.Errorf("unable to fetch AWS metadata: %s", err)
 	}
+	defer response.Body.Close()
+
 	if response.StatusCode != http.StatusOK {
 		_, _ = io.Copy(ioutil.Discard, response.Body)
 		return "", fmt.Errorf("cloud metadata request returned non-OK response: %d %s", response.StatusCode, response.Status)
@@ -246,32 +369,20 @@ func (a *AWSHarvester) getToken() (string, error) {
 
 	bs, err := ioutil.ReadAll(response.Body)
 	if err != nil {
+		if atomic.LoadInt32(&connected) == 1 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", errTokenTimeout
+		}
 		return "", fmt.Errorf("unable to read cloud metadata response: %s", err)
 	}
-	token := string(bs)
-	a.awsEC2MetadataToken.Store(token)
-	return token, nil
+	return string(bs), nil
 }
 
 // GetAWSMetadataValue is used to request metadata from aws API.
 func (a *AWSHarvester) GetAWSMetadataValue(fieldName string, disableKeepAlive bool) (data string, err error) {
 	url := formatURL(a.awsEC2MetadataHostname, fieldName)
 
-	var request *http.Request
-	client := clientWithFastTimeout(disableKeepAlive)
-	if request, err = http.NewRequest(http.MethodGet, url, nil); err != nil {
-		err = fmt.Errorf("unable to prepare AWS metadata request: %v", request)
-		return
-	}
-	token, err := a.getToken()
-	if err != nil {
-		return "", err
-	}
-	request.Header.Add(tokenHeader, token)
-
 	var response *http.Response
-	if response, err = client.Do(request); err != nil {
-		err = fmt.Errorf("unable to fetch AWS metadata: %s", err)
+	if response, err = a.doRequest(clientWithFastTimeout(disableKeepAlive), url); err != nil {
 		return
 	}
 	defer response.Body.Close()
@@ -283,6 +394,108 @@ func (a *AWSHarvester) GetAWSMetadataValue(fieldName string, disableKeepAlive bo
 	return
 }
 
+// SpotInstanceAction is the action, and its time, about to be taken on an interrupted spot instance.
+type SpotInstanceAction struct {
+	// Action is one of stop, terminate or hibernate.
+	Action string `json:"action"`
+	Time   string `json:"time"`
+}
+
+// RebalanceRecommendation notifies the spot instance is at an elevated risk of interruption.
+type RebalanceRecommendation struct {
+	NoticeTime string `json:"noticeTime"`
+}
+
+// ScheduledEvent is a maintenance event, like a reboot or a retirement, scheduled for the instance.
+type ScheduledEvent struct {
+	Code        string `json:"Code"`
+	Description string `json:"Description"`
+	EventID     string `json:"EventId"`
+	NotBefore   string `json:"NotBefore"`
+	NotAfter    string `json:"NotAfter"`
+	// State is one of active, completed or canceled.
+	State string `json:"State"`
+}
+
+// GetInstanceLifecycle returns the purchasing option of the instance: on-demand, spot or scheduled.
+func (a *AWSHarvester) GetInstanceLifecycle() (string, error) {
+	return a.GetAWSMetadataValue(instanceLifecyclePath, a.disableKeepAlive)
+}
+
+// GetSpotInstanceAction returns the action scheduled on the spot instance about two minutes before
+// it's interrupted, nil when no interruption is scheduled.
+func (a *AWSHarvester) GetSpotInstanceAction() (*SpotInstanceAction, error) {
+	var action SpotInstanceAction
+	found, err := a.getMetadataJSON(spotInstanceActionPath, &action)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// GetTerminationNotice returns the interruption notice of the spot instance, nil when there's none.
+func (a *AWSHarvester) GetTerminationNotice() (*TerminationNotice, error) {
+	action, err := a.GetSpotInstanceAction()
+	if err != nil || action == nil {
+		return nil, err
+	}
+	return &TerminationNotice{
+		Action:      action.Action,
+		Time:        action.Time,
+		Description: "spot instance interruption",
+	}, nil
+}
+
+// GetRebalanceRecommendation returns the rebalance recommendation of the spot instance, nil when there's none.
+func (a *AWSHarvester) GetRebalanceRecommendation() (*RebalanceRecommendation, error) {
+	var recommendation RebalanceRecommendation
+	found, err := a.getMetadataJSON(rebalanceRecommendationPath, &recommendation)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &recommendation, nil
+}
+
+// GetScheduledEvents returns the maintenance events scheduled for the instance, including the completed
+// and canceled ones.
+func (a *AWSHarvester) GetScheduledEvents() ([]ScheduledEvent, error) {
+	var events []ScheduledEvent
+	_, err := a.getMetadataJSON(scheduledEventsPath, &events)
+	return events, err
+}
+
+// getMetadataJSON decodes the JSON value of a metadata path, returning false when the path is not found, as
+// the metadata service does for the notices which aren't issued.
+func (a *AWSHarvester) getMetadataJSON(path string, v interface{}) (found bool, err error) {
+	url := formatURL(a.awsEC2MetadataHostname, path)
+	response, err := a.doRequest(a.httpClient, url)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		return false, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		return false, fmt.Errorf("cloud metadata request returned non-OK response: %d %s", response.StatusCode, response.Status)
+	}
+
+	blob, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return false, fmt.Errorf("unable to read cloud metadata response: %s", err)
+	}
+	if len(strings.TrimSpace(string(blob))) == 0 {
+		return false, nil
+	}
+	if err = json.Unmarshal(blob, v); err != nil {
+		return false, fmt.Errorf("can't decode response from %s: %s", url, err)
+	}
+	return true, nil
+}
+
 // parseAWSMetaResponse is used to parse the value required from AWS response.
 func parseAWSMetaResponse(response *http.Response) (value string, err error) {
 	if response.StatusCode != http.StatusOK {
@@ -209,3 +209,144 @@ func assertGetInstanceID(t *testing.T, h *AWSHarvester) {
 	assert.NoError(t, err)
 	assert.Equal(t, "i-1234567890abcdef0", instanceID)
 }
+
+// newHopLimitTestServer returns a server which never answers the IMDSv2 token requests, as happens when the PUT
+// response hop limit is exceeded, and only serves IMDSv1 requests.
+func newHopLimitTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/latest/dynamic/instance-identity/document", func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("X-aws-ec2-metadata-token"))
+		_, _ = fmt.Fprint(w, `{"instanceId" : "i-1234567890abcdef0"}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestAWSHarvester_IMDSv2(t *testing.T) {
+	t.Parallel()
+	var tokenCounter int32
+	ts := newTestServer(t, getRandomToken(), &tokenCounter, "600", "")
+	defer ts.Close()
+
+	h := NewAWSHarvester(true)
+	h.awsEC2MetadataHostname = ts.URL
+
+	version, warning := h.IMDSStatus()
+	assert.Empty(t, version)
+	assertGetInstanceID(t, h)
+	version, warning = h.IMDSStatus()
+	assert.Equal(t, IMDSVersion2, version)
+	assert.Empty(t, warning)
+}
+
+func TestAWSHarvester_IMDSv1Fallback(t *testing.T) {
+	t.Parallel()
+	ts := newHopLimitTestServer(t)
+	defer ts.Close()
+
+	h := NewAWSHarvester(true)
+	h.awsEC2MetadataHostname = ts.URL
+	h.tokenRequestTimeout = 100 * time.Millisecond
+
+	assertGetInstanceID(t, h)
+	version, warning := h.IMDSStatus()
+	assert.Equal(t, IMDSVersion1, version)
+	assert.Contains(t, warning, "--http-put-response-hop-limit 2")
+}
+
+func TestAWSHarvester_IMDSv2Only(t *testing.T) {
+	t.Parallel()
+	ts := newHopLimitTestServer(t)
+	defer ts.Close()
+
+	h := NewAWSHarvesterIMDSv2Only(true)
+	h.awsEC2MetadataHostname = ts.URL
+	h.tokenRequestTimeout = 100 * time.Millisecond
+
+	_, err := h.GetInstanceID()
+	assert.Error(t, err)
+}
+
+func TestAWSHarvester_revokedToken(t *testing.T) {
+	t.Parallel()
+	var tokenCounter int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "token-%d", atomic.AddInt32(&tokenCounter, 1))
+	})
+	mux.HandleFunc("/latest/meta-data/instance-life-cycle", func(w http.ResponseWriter, r *http.Request) {
+		// only the latest token is valid
+		if r.Header.Get("X-aws-ec2-metadata-token") != fmt.Sprintf("token-%d", atomic.LoadInt32(&tokenCounter)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-type", "text/plain")
+		_, _ = fmt.Fprint(w, "spot")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	h := NewAWSHarvester(true)
+	h.awsEC2MetadataHostname = ts.URL
+
+	lifecycle, err := h.GetInstanceLifecycle()
+	require.NoError(t, err)
+	assert.Equal(t, "spot", lifecycle)
+
+	// revoke the cached token
+	atomic.AddInt32(&tokenCounter, 1)
+	lifecycle, err = h.GetInstanceLifecycle()
+	require.NoError(t, err)
+	assert.Equal(t, "spot", lifecycle)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&tokenCounter))
+}
+
+func TestAWSHarvester_InstanceEvents(t *testing.T) {
+	t.Parallel()
+	var tokenCounter int32
+	token := getRandomToken()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokenCounter, 1)
+		_, _ = fmt.Fprint(w, token)
+	})
+	mux.HandleFunc("/latest/meta-data/spot/instance-action", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, token, r.Header.Get("X-aws-ec2-metadata-token"))
+		_, _ = fmt.Fprint(w, `{"action": "terminate", "time": "2017-09-18T08:22:00Z"}`)
+	})
+	mux.HandleFunc("/latest/meta-data/events/maintenance/scheduled", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[{
+			"NotBefore" : "21 Jan 2019 09:00:43 GMT",
+			"Code" : "system-reboot",
+			"Description" : "scheduled reboot",
+			"EventId" : "instance-event-0d59937288b749b32",
+			"NotAfter" : "21 Jan 2019 09:17:23 GMT",
+			"State" : "active"
+		}]`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	h := NewAWSHarvester(true)
+	h.awsEC2MetadataHostname = ts.URL
+
+	action, err := h.GetSpotInstanceAction()
+	require.NoError(t, err)
+	assert.Equal(t, &SpotInstanceAction{Action: "terminate", Time: "2017-09-18T08:22:00Z"}, action)
+
+	// no recommendation is found
+	recommendation, err := h.GetRebalanceRecommendation()
+	require.NoError(t, err)
+	assert.Nil(t, recommendation)
+
+	events, err := h.GetScheduledEvents()
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "system-reboot", events[0].Code)
+	assert.Equal(t, "instance-event-0d59937288b749b32", events[0].EventID)
+	assert.Equal(t, "active", events[0].State)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenCounter))
+}
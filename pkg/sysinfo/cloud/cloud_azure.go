@@ -16,7 +16,18 @@ import (
 
 const (
 	// azureEndpoint is the URL used for requesting Azure metadata.
-	azureEndpoint = "http://169.254.169.254/metadata/instance?api-version=2017-12-01"
+	azureEndpoint = "http://169.254.169.254/metadata/instance?api-version=2021-02-01"
+
+	// azureScheduledEventsEndpoint is the URL of the maintenance events scheduled for the VMs.
+	azureScheduledEventsEndpoint = "http://169.254.169.254/metadata/scheduledevents?api-version=2020-07-01"
+
+	// Priorities of the evictable VMs, low priority being the scale sets predecessor of spot.
+	azurePrioritySpot = "Spot"
+	azurePriorityLow  = "Low"
+
+	// Types of the scheduled events removing the VM: spot evictions, with 30 seconds notice, and deletions.
+	azureEventPreempt   = "Preempt"
+	azureEventTerminate = "Terminate"
 )
 
 // AzureHarvester is used to fetch data from Azure api.
@@ -29,6 +40,7 @@ type AzureHarvester struct {
 	zone             string
 	subscriptionID   string
 	imageID          string
+	metadata         *azureMetadata // Cache the metadata for the values which may be empty.
 }
 
 // AzureHarvester returns a new instance of AzureHarvester.
@@ -131,14 +143,119 @@ func (a *AzureHarvester) GetInstanceImageID() (string, error) {
 	return a.imageID, nil
 }
 
+func (a *AzureHarvester) loadMetadata() (*azureMetadata, error) {
+	if a.metadata != nil && !a.timeout.HasExpired() {
+		return a.metadata, nil
+	}
+	metadata, err := GetAzureMetadata(a.disableKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+	a.metadata = metadata
+	return a.metadata, nil
+}
+
+// GetScaleGroup returns the virtual machine scale set of the instance, empty when it's not in one.
+func (a *AzureHarvester) GetScaleGroup() (string, error) {
+	metadata, err := a.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	return metadata.Compute.VmScaleSetName, nil
+}
+
+// IsSpot returns whether the instance is a spot or low priority VM, which can be evicted at any time.
+func (a *AzureHarvester) IsSpot() (bool, error) {
+	metadata, err := a.loadMetadata()
+	if err != nil {
+		return false, err
+	}
+	return metadata.Compute.Priority == azurePrioritySpot || metadata.Compute.Priority == azurePriorityLow, nil
+}
+
+// GetTerminationNotice returns the scheduled eviction or deletion of the VM, nil when there's none.
+func (a *AzureHarvester) GetTerminationNotice() (*TerminationNotice, error) {
+	metadata, err := a.loadMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(http.MethodGet, azureScheduledEventsEndpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare Azure scheduled events request: %v", err)
+	}
+	request.Header.Add("Metadata", "true")
+
+	response, err := clientWithFastTimeout(a.disableKeepAlive).Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch Azure scheduled events: %s", err)
+	}
+	defer response.Body.Close()
+
+	return parseAzureScheduledEventsResponse(response, metadata.Compute.Name)
+}
+
+// parseAzureScheduledEventsResponse returns the first scheduled eviction or deletion of the named VM.
+func parseAzureScheduledEventsResponse(response *http.Response, vmName string) (*TerminationNotice, error) {
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Azure scheduled events request returned non-OK response: %d %s", response.StatusCode, response.Status)
+	}
+
+	var scheduled struct {
+		Events []struct {
+			EventType   string   `json:"EventType"`
+			Resources   []string `json:"Resources"`
+			NotBefore   string   `json:"NotBefore"`
+			Description string   `json:"Description"`
+		} `json:"Events"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&scheduled); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal Azure scheduled events response body: %v", err)
+	}
+
+	for _, event := range scheduled.Events {
+		var action string
+		switch event.EventType {
+		case azureEventPreempt:
+			action = TerminationActionPreempt
+		case azureEventTerminate:
+			action = TerminationActionTerminate
+		default:
+			continue
+		}
+		// the events of the scale set or availability set may affect other VMs
+		if vmName != "" && !containsString(event.Resources, vmName) {
+			continue
+		}
+		return &TerminationNotice{
+			Action:      action,
+			Time:        event.NotBefore,
+			Description: event.Description,
+		}, nil
+	}
+	return nil, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // Captures the fields we care about from the Azure metadata API
 type azureMetadata struct {
 	Compute struct {
+		Name           string `json:"name"`
 		Location       string `json:"location"`
 		VmId           string `json:"vmId"`
 		VmSize         string `json:"vmSize"`
 		SubscriptionID string `json:"subscriptionId"`
 		Zone           string `json:"zone"`
+		VmScaleSetName string `json:"vmScaleSetName"`
+		Priority       string `json:"priority"`
 		StorageProfile struct {
 			ImageReference struct {
 				ID string `json:"id"`
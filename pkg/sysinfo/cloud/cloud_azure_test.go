@@ -70,3 +70,76 @@ func TestParseAzureMetadata(t *testing.T) {
 	assert.Equal(t, metadata.Compute.VmSize, "Standard_B2s")
 	assert.Equal(t, metadata.Compute.VmId, "aaaaaa-bbbbb-cccc-dddd-aaaaaaa3a749")
 }
+
+func TestParseAzureMetadata_ScaleSetSpot(t *testing.T) {
+	response := &http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(bytes.NewBuffer([]byte(`{
+			"compute": {
+				"location": "westeurope",
+				"priority": "Spot",
+				"vmId": "aaaaaa-bbbbb-cccc-dddd-aaaaaaa3a749",
+				"vmScaleSetName": "web-vmss",
+				"vmSize": "Standard_D2s_v3",
+				"zone": "2"
+			}
+		}`))),
+	}
+
+	metadata, err := parseAzureMetadataResponse(response)
+	assert.NoError(t, err)
+	assert.Equal(t, "web-vmss", metadata.Compute.VmScaleSetName)
+	assert.Equal(t, "Spot", metadata.Compute.Priority)
+
+	h := NewAzureHarvester(true)
+	h.metadata = metadata
+	scaleSet, err := h.GetScaleGroup()
+	assert.NoError(t, err)
+	assert.Equal(t, "web-vmss", scaleSet)
+	spot, err := h.IsSpot()
+	assert.NoError(t, err)
+	assert.True(t, spot)
+}
+
+// sample from: https://learn.microsoft.com/en-us/azure/virtual-machines/linux/scheduled-events
+func TestParseAzureScheduledEvents(t *testing.T) {
+	events := `{
+		"DocumentIncarnation": 3,
+		"Events": [
+			{
+				"EventId": "A123BC45-1234-5678-AB90-ABCDEF123456",
+				"EventStatus": "Scheduled",
+				"EventType": "Freeze",
+				"ResourceType": "VirtualMachine",
+				"Resources": ["web-1"],
+				"NotBefore": "Mon, 11 Apr 2022 22:26:58 GMT",
+				"Description": "Virtual machine is being paused because of a memory-preserving Live Migration operation.",
+				"EventSource": "Platform",
+				"DurationInSeconds": 5
+			},
+			{
+				"EventId": "B234CD56-1234-5678-AB90-ABCDEF123456",
+				"EventStatus": "Scheduled",
+				"EventType": "Preempt",
+				"ResourceType": "VirtualMachine",
+				"Resources": ["web-2"],
+				"NotBefore": "Mon, 11 Apr 2022 22:27:28 GMT",
+				"Description": "",
+				"EventSource": "Platform",
+				"DurationInSeconds": -1
+			}
+		]
+	}`
+	response := func() *http.Response {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(events))}
+	}
+
+	notice, err := parseAzureScheduledEventsResponse(response(), "web-2")
+	assert.NoError(t, err)
+	assert.Equal(t, &TerminationNotice{Action: TerminationActionPreempt, Time: "Mon, 11 Apr 2022 22:27:28 GMT"}, notice)
+
+	// the eviction of other VMs in the scale set is ignored
+	notice, err = parseAzureScheduledEventsResponse(response(), "web-1")
+	assert.NoError(t, err)
+	assert.Nil(t, notice)
+}
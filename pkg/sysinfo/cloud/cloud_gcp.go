@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"path"
+	"strings"
 
 	"github.com/newrelic/infrastructure-agent/pkg/sysinfo"
 )
@@ -15,6 +16,14 @@ import (
 const (
 	// gcpEndpoint is the URL used for requesting GCP metadata.
 	gcpEndpoint = "http://metadata.google.internal/computeMetadata/v1/instance/?recursive=true"
+
+	// gcpPreemptedEndpoint returns TRUE once the spot or preemptible VM is being preempted, about 30 seconds before
+	// it's stopped.
+	gcpPreemptedEndpoint = "http://metadata.google.internal/computeMetadata/v1/instance/preempted"
+
+	// gcpInstanceGroupManagers precedes the managed instance group name in the created-by attribute, ie:
+	// projects/123/zones/us-central1-a/instanceGroupManagers/my-group
+	gcpInstanceGroupManagers = "/instanceGroupManagers/"
 )
 
 // GCPHarvester is used to fetch data from GCP API.
@@ -24,6 +33,7 @@ type GCPHarvester struct {
 	instanceID       string // Cache the gcp instance ID.
 	hostType         string // Cache the gcp instance Type.
 	zone             string
+	metadata         *gcpMetadata // Cache the metadata for the values which may be empty.
 }
 
 // NewGCPHarvester return a new GCPHarvester instance.
@@ -69,9 +79,74 @@ func (gcp *GCPHarvester) GetAccountID() (string, error) {
 	return "", ErrMethodNotImplemented
 }
 
-// GetZone returns the cloud instance zone
+// GetZone returns the cloud instance zone, the same value reported as region.
 func (gcp *GCPHarvester) GetZone() (string, error) {
-	return "", ErrMethodNotImplemented
+	return gcp.GetRegion()
+}
+
+func (gcp *GCPHarvester) loadMetadata() (*gcpMetadata, error) {
+	if gcp.metadata != nil && !gcp.timeout.HasExpired() {
+		return gcp.metadata, nil
+	}
+	metadata, err := GetGCPMetadata(gcp.disableKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+	gcp.metadata = metadata
+	return gcp.metadata, nil
+}
+
+// GetScaleGroup returns the managed instance group of the instance, empty when it's not in one.
+func (gcp *GCPHarvester) GetScaleGroup() (string, error) {
+	metadata, err := gcp.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	return metadata.InstanceGroup, nil
+}
+
+// IsSpot returns whether the instance is a spot or preemptible VM, which can be preempted at any time.
+func (gcp *GCPHarvester) IsSpot() (bool, error) {
+	metadata, err := gcp.loadMetadata()
+	if err != nil {
+		return false, err
+	}
+	return metadata.Preemptible, nil
+}
+
+// GetTerminationNotice returns the preemption notice of the spot or preemptible VM, nil when there's none.
+func (gcp *GCPHarvester) GetTerminationNotice() (*TerminationNotice, error) {
+	request, err := http.NewRequest(http.MethodGet, gcpPreemptedEndpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare GCP preempted request: %v", err)
+	}
+	request.Header.Add("Metadata-Flavor", "Google")
+
+	response, err := clientWithFastTimeout(gcp.disableKeepAlive).Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch GCP preempted status: %s", err)
+	}
+	defer response.Body.Close()
+
+	return parseGCPPreemptedResponse(response)
+}
+
+// parseGCPPreemptedResponse returns the preemption notice when the response is TRUE.
+func parseGCPPreemptedResponse(response *http.Response) (*TerminationNotice, error) {
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GCP preempted request returned non-OK response: %d %s", response.StatusCode, response.Status)
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read GCP preempted response body: %v", err)
+	}
+	if !strings.EqualFold(strings.TrimSpace(string(body)), "true") {
+		return nil, nil
+	}
+	return &TerminationNotice{
+		Action:      TerminationActionPreempt,
+		Description: "VM preemption",
+	}, nil
 }
 
 // GetInstanceImageID returns the cloud instance image ID
@@ -104,9 +179,11 @@ func (gcp *GCPHarvester) GetRegion() (string, error) {
 
 // Captures the fields we care about from the GCP metadata API.
 type gcpMetadata struct {
-	Zone        string
-	Id          string
-	MachineType string
+	Zone          string
+	Id            string
+	MachineType   string
+	InstanceGroup string
+	Preemptible   bool
 }
 
 // GetGCPMetadata is used to request metadata from GCP API.
@@ -147,6 +224,13 @@ func parseGCPMetaResponse(response *http.Response) (result *gcpMetadata, err err
 		Zone        string      `json:"zone"`
 		Id          json.Number `json:"id,Number"`
 		MachineType string      `json:"machineType"`
+		Attributes  struct {
+			CreatedBy string `json:"created-by"`
+		} `json:"attributes"`
+		Scheduling struct {
+			Preemptible       string `json:"preemptible"`
+			ProvisioningModel string `json:"provisioningModel"`
+		} `json:"scheduling"`
 	}{}
 
 	if err = json.Unmarshal(responseBody, &tmpRep); err != nil {
@@ -158,6 +242,12 @@ func parseGCPMetaResponse(response *http.Response) (result *gcpMetadata, err err
 		Zone:        path.Base(tmpRep.Zone),
 		Id:          "gcp-" + string(tmpRep.Id),
 		MachineType: path.Base(tmpRep.MachineType),
+		// spot VMs are also preemptible, but newer ones may only report their provisioning model
+		Preemptible: strings.EqualFold(tmpRep.Scheduling.Preemptible, "true") ||
+			strings.EqualFold(tmpRep.Scheduling.ProvisioningModel, "spot"),
+	}
+	if i := strings.Index(tmpRep.Attributes.CreatedBy, gcpInstanceGroupManagers); i >= 0 {
+		result.InstanceGroup = tmpRep.Attributes.CreatedBy[i+len(gcpInstanceGroupManagers):]
 	}
 
 	return
@@ -0,0 +1,79 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// output reduced from: curl -H Metadata-Flavor:Google "http://metadata.google.internal/computeMetadata/v1/instance/?recursive=true"
+func TestParseGCPMetadata(t *testing.T) {
+	response := &http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(bytes.NewBufferString(`{
+			"attributes": {
+				"created-by": "projects/123456789012/zones/us-central1-a/instanceGroupManagers/web-mig",
+				"instance-template": "projects/123456789012/global/instanceTemplates/web-template"
+			},
+			"hostname": "web-mig-x7q2.c.project.internal",
+			"id": 4520031799277581759,
+			"machineType": "projects/123456789012/machineTypes/e2-medium",
+			"scheduling": {
+				"automaticRestart": "FALSE",
+				"onHostMaintenance": "TERMINATE",
+				"preemptible": "TRUE"
+			},
+			"zone": "projects/123456789012/zones/us-central1-a"
+		}`)),
+	}
+
+	metadata, err := parseGCPMetaResponse(response)
+	require.NoError(t, err)
+	assert.Equal(t, &gcpMetadata{
+		Zone:          "us-central1-a",
+		Id:            "gcp-4520031799277581759",
+		MachineType:   "e2-medium",
+		InstanceGroup: "web-mig",
+		Preemptible:   true,
+	}, metadata)
+}
+
+func TestParseGCPMetadata_NotInGroup(t *testing.T) {
+	response := &http.Response{
+		StatusCode: 200,
+		Body: ioutil.NopCloser(bytes.NewBufferString(`{
+			"id": 4520031799277581759,
+			"machineType": "projects/123456789012/machineTypes/e2-medium",
+			"scheduling": {"preemptible": "FALSE", "provisioningModel": "SPOT"},
+			"zone": "projects/123456789012/zones/us-central1-a"
+		}`)),
+	}
+
+	metadata, err := parseGCPMetaResponse(response)
+	require.NoError(t, err)
+	assert.Empty(t, metadata.InstanceGroup)
+	assert.True(t, metadata.Preemptible)
+}
+
+func TestParseGCPPreempted(t *testing.T) {
+	notice, err := parseGCPPreemptedResponse(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewBufferString("FALSE")),
+	})
+	require.NoError(t, err)
+	assert.Nil(t, notice)
+
+	notice, err = parseGCPPreemptedResponse(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewBufferString("TRUE")),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &TerminationNotice{Action: TerminationActionPreempt, Description: "VM preemption"}, notice)
+}
@@ -0,0 +1,161 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo"
+)
+
+// Metadata sample: https://docs.hetzner.cloud/#server-metadata
+
+const (
+	// hetznerEndpoint is the URL used for requesting Hetzner Cloud metadata.
+	hetznerEndpoint = "http://169.254.169.254/hetzner/v1/metadata"
+)
+
+// HetznerHarvester is used to fetch data from the Hetzner Cloud metadata service. The server type
+// is not part of the metadata, so the host type is not available.
+type HetznerHarvester struct {
+	timeout          *Timeout
+	disableKeepAlive bool
+	endpoint         string
+	metadata         *HetznerMetadata // Cache the Hetzner server metadata.
+}
+
+// NewHetznerHarvester returns a new instance of HetznerHarvester.
+func NewHetznerHarvester(disableKeepAlive bool) *HetznerHarvester {
+	return &HetznerHarvester{
+		timeout:          NewTimeout(600),
+		disableKeepAlive: disableKeepAlive,
+		endpoint:         hetznerEndpoint,
+	}
+}
+
+// GetHarvester returns instance of the Harvester detected (or instance of themselves)
+func (h *HetznerHarvester) GetHarvester() (Harvester, error) {
+	return h, nil
+}
+
+func (h *HetznerHarvester) loadMetadata() (*HetznerMetadata, error) {
+	if h.metadata != nil && !h.timeout.HasExpired() {
+		return h.metadata, nil
+	}
+	metadata, err := getHetznerMetadata(h.endpoint, h.disableKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+	h.metadata = metadata
+	return h.metadata, nil
+}
+
+// GetInstanceID returns the Hetzner server ID, prefixed by "hetzner-" as it's a plain number.
+func (h *HetznerHarvester) GetInstanceID() (string, error) {
+	metadata, err := h.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	return "hetzner-" + strconv.FormatInt(metadata.InstanceID, 10), nil
+}
+
+// GetHostType is not implemented, the server type is not provided by the metadata service.
+func (h *HetznerHarvester) GetHostType() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// GetCloudType returns the type of the cloud.
+func (h *HetznerHarvester) GetCloudType() Type {
+	return TypeHetzner
+}
+
+// GetCloudSource returns a string key which will be used as a HostSource (see host_aliases plugin).
+func (h *HetznerHarvester) GetCloudSource() string {
+	return sysinfo.HOST_SOURCE_HETZNER_VM_ID
+}
+
+// GetRegion will return the network zone of the server, ie: eu-central.
+func (h *HetznerHarvester) GetRegion() (string, error) {
+	metadata, err := h.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	return metadata.Region, nil
+}
+
+// GetAccountID returns the cloud account
+func (h *HetznerHarvester) GetAccountID() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// GetZone will return the datacenter of the server, ie: fsn1-dc14.
+func (h *HetznerHarvester) GetZone() (string, error) {
+	metadata, err := h.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	return metadata.AvailabilityZone, nil
+}
+
+// GetInstanceImageID returns the cloud instance image ID
+func (h *HetznerHarvester) GetInstanceImageID() (string, error) {
+	return "", ErrMethodNotImplemented
+}
+
+// HetznerMetadata captures the fields we care about from the Hetzner metadata API.
+type HetznerMetadata struct {
+	InstanceID       int64  `yaml:"instance-id"`
+	Hostname         string `yaml:"hostname"`
+	Region           string `yaml:"region"`
+	AvailabilityZone string `yaml:"availability-zone"`
+}
+
+// getHetznerMetadata is used to request metadata from the Hetzner API.
+func getHetznerMetadata(endpoint string, disableKeepAlive bool) (result *HetznerMetadata, err error) {
+	var request *http.Request
+	if request, err = http.NewRequest(http.MethodGet, endpoint, nil); err != nil {
+		err = fmt.Errorf("unable to prepare Hetzner metadata request: %v", request)
+		return
+	}
+
+	var response *http.Response
+	if response, err = clientWithFastTimeout(disableKeepAlive).Do(request); err != nil {
+		err = fmt.Errorf("unable to fetch Hetzner metadata: %s", err)
+		return
+	}
+	defer response.Body.Close()
+
+	return parseHetznerMetadataResponse(response)
+}
+
+// parseHetznerMetadataResponse is used to parse the value required from the Hetzner YAML response.
+func parseHetznerMetadataResponse(response *http.Response) (result *HetznerMetadata, err error) {
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("cloud metadata request returned non-OK response: %d %s", response.StatusCode, response.Status)
+		return
+	}
+
+	var responseBody []byte
+	if responseBody, err = ioutil.ReadAll(response.Body); err != nil {
+		err = fmt.Errorf("unable to read Hetzner metadata response body: %v", err)
+		return
+	}
+
+	if err = yaml.Unmarshal(responseBody, &result); err != nil {
+		err = fmt.Errorf("unable to unmarshal Hetzner metadata response body: %v", err)
+		return
+	}
+
+	if result == nil || result.InstanceID == 0 {
+		err = fmt.Errorf("Hetzner metadata response doesn't contain the instance ID")
+		result = nil
+	}
+
+	return
+}
@@ -0,0 +1,76 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// output generated with: curl http://169.254.169.254/hetzner/v1/metadata
+const hetznerMetadataSample = `availability-zone: fsn1-dc14
+hostname: my-server
+instance-id: 42424242
+local-ipv4: ''
+public-ipv4: 116.203.0.1
+public-keys: []
+region: eu-central
+vendor_data: "#cloud-config\nbootcmd:\n- [cloud-init-per, once, set-hostname, hostname, my-server]\n"
+`
+
+func TestParseHetznerMetadata(t *testing.T) {
+	response := &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(hetznerMetadataSample)),
+	}
+
+	metadata, err := parseHetznerMetadataResponse(response)
+	require.NoError(t, err)
+	assert.Equal(t, &HetznerMetadata{
+		InstanceID:       42424242,
+		Hostname:         "my-server",
+		Region:           "eu-central",
+		AvailabilityZone: "fsn1-dc14",
+	}, metadata)
+}
+
+func TestParseHetznerMetadata_NotHetzner(t *testing.T) {
+	_, err := parseHetznerMetadataResponse(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewBufferString("ami-id\nhostname\n")),
+	})
+	assert.Error(t, err)
+}
+
+func TestHetznerHarvester(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, hetznerMetadataSample)
+	}))
+	defer ts.Close()
+
+	h := NewHetznerHarvester(true)
+	h.endpoint = ts.URL
+
+	id, err := h.GetInstanceID()
+	require.NoError(t, err)
+	assert.Equal(t, "hetzner-42424242", id)
+
+	region, err := h.GetRegion()
+	require.NoError(t, err)
+	assert.Equal(t, "eu-central", region)
+
+	zone, err := h.GetZone()
+	require.NoError(t, err)
+	assert.Equal(t, "fsn1-dc14", zone)
+
+	_, err = h.GetHostType()
+	assert.Equal(t, ErrMethodNotImplemented, err)
+}
@@ -0,0 +1,179 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/newrelic/infrastructure-agent/pkg/sysinfo"
+)
+
+// Metadata sample: https://docs.oracle.com/en-us/iaas/Content/Compute/Tasks/gettingmetadata.htm
+
+const (
+	// ociEndpoint is the URL used for requesting Oracle Cloud Infrastructure metadata.
+	ociEndpoint = "http://169.254.169.254/opc/v2/instance/"
+)
+
+// OCIHarvester is used to fetch data from the Oracle Cloud Infrastructure instance metadata service.
+type OCIHarvester struct {
+	timeout          *Timeout
+	disableKeepAlive bool
+	endpoint         string
+	metadata         *OCIMetadata // Cache the OCI instance metadata.
+}
+
+// NewOCIHarvester returns a new instance of OCIHarvester.
+func NewOCIHarvester(disableKeepAlive bool) *OCIHarvester {
+	return &OCIHarvester{
+		timeout:          NewTimeout(600),
+		disableKeepAlive: disableKeepAlive,
+		endpoint:         ociEndpoint,
+	}
+}
+
+// GetHarvester returns instance of the Harvester detected (or instance of themselves)
+func (o *OCIHarvester) GetHarvester() (Harvester, error) {
+	return o, nil
+}
+
+func (o *OCIHarvester) loadMetadata() (*OCIMetadata, error) {
+	if o.metadata != nil && !o.timeout.HasExpired() {
+		return o.metadata, nil
+	}
+	metadata, err := getOCIMetadata(o.endpoint, o.disableKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+	o.metadata = metadata
+	return o.metadata, nil
+}
+
+// GetInstanceID returns the OCID of the instance.
+func (o *OCIHarvester) GetInstanceID() (string, error) {
+	metadata, err := o.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	return metadata.ID, nil
+}
+
+// GetHostType will return the instance shape.
+func (o *OCIHarvester) GetHostType() (string, error) {
+	metadata, err := o.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	return metadata.Shape, nil
+}
+
+// GetCloudType returns the type of the cloud.
+func (o *OCIHarvester) GetCloudType() Type {
+	return TypeOCI
+}
+
+// GetCloudSource returns a string key which will be used as a HostSource (see host_aliases plugin).
+func (o *OCIHarvester) GetCloudSource() string {
+	return sysinfo.HOST_SOURCE_OCI_VM_ID
+}
+
+// GetRegion will return the canonical name of the instance region, ie: us-phoenix-1.
+func (o *OCIHarvester) GetRegion() (string, error) {
+	metadata, err := o.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	if metadata.CanonicalRegionName != "" {
+		return metadata.CanonicalRegionName, nil
+	}
+	return metadata.Region, nil
+}
+
+// GetAccountID will return the compartment of the instance.
+func (o *OCIHarvester) GetAccountID() (string, error) {
+	metadata, err := o.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	return metadata.CompartmentID, nil
+}
+
+// GetZone will return the availability domain of the instance.
+func (o *OCIHarvester) GetZone() (string, error) {
+	metadata, err := o.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	return metadata.AvailabilityDomain, nil
+}
+
+// GetInstanceImageID will return the image OCID of the instance.
+func (o *OCIHarvester) GetInstanceImageID() (string, error) {
+	metadata, err := o.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+	return metadata.Image, nil
+}
+
+// OCIMetadata captures the fields we care about from the OCI metadata API.
+type OCIMetadata struct {
+	ID                  string `json:"id"`
+	Shape               string `json:"shape"`
+	Region              string `json:"region"`
+	CanonicalRegionName string `json:"canonicalRegionName"`
+	AvailabilityDomain  string `json:"availabilityDomain"`
+	FaultDomain         string `json:"faultDomain"`
+	CompartmentID       string `json:"compartmentId"`
+	Image               string `json:"image"`
+}
+
+// getOCIMetadata is used to request metadata from the OCI API.
+func getOCIMetadata(endpoint string, disableKeepAlive bool) (result *OCIMetadata, err error) {
+	var request *http.Request
+	if request, err = http.NewRequest(http.MethodGet, endpoint, nil); err != nil {
+		err = fmt.Errorf("unable to prepare OCI metadata request: %v", request)
+		return
+	}
+	// required by the v2 endpoint
+	request.Header.Add("Authorization", "Bearer Oracle")
+
+	var response *http.Response
+	if response, err = clientWithFastTimeout(disableKeepAlive).Do(request); err != nil {
+		err = fmt.Errorf("unable to fetch OCI metadata: %s", err)
+		return
+	}
+	defer response.Body.Close()
+
+	return parseOCIMetadataResponse(response)
+}
+
+// parseOCIMetadataResponse is used to parse the value required from the OCI response.
+func parseOCIMetadataResponse(response *http.Response) (result *OCIMetadata, err error) {
+	if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("cloud metadata request returned non-OK response: %d %s", response.StatusCode, response.Status)
+		return
+	}
+
+	var responseBody []byte
+	if responseBody, err = ioutil.ReadAll(response.Body); err != nil {
+		err = fmt.Errorf("unable to read OCI metadata response body: %v", err)
+		return
+	}
+
+	if err = json.Unmarshal(responseBody, &result); err != nil {
+		err = fmt.Errorf("unable to unmarshal OCI metadata response body: %v", err)
+		return
+	}
+
+	if result.ID == "" {
+		err = fmt.Errorf("OCI metadata response doesn't contain the instance ID")
+		result = nil
+	}
+
+	return
+}
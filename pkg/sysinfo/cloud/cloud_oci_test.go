@@ -0,0 +1,94 @@
+// Copyright New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ociMetadataSample = `{
+	"availabilityDomain": "EMIr:PHX-AD-1",
+	"faultDomain": "FAULT-DOMAIN-3",
+	"compartmentId": "ocid1.tenancy.oc1..exampleuniqueID",
+	"displayName": "my-example-instance",
+	"hostname": "my-hostname",
+	"id": "ocid1.instance.oc1.phx.exampleuniqueID",
+	"image": "ocid1.image.oc1.phx.exampleuniqueID",
+	"region": "phx",
+	"canonicalRegionName": "us-phoenix-1",
+	"ociAdName": "phx-ad-1",
+	"shape": "VM.Standard2.1",
+	"state": "Running",
+	"timeCreated": 1600381928581
+}`
+
+func TestParseOCIMetadata(t *testing.T) {
+	response := &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(ociMetadataSample)),
+	}
+
+	metadata, err := parseOCIMetadataResponse(response)
+	require.NoError(t, err)
+	assert.Equal(t, &OCIMetadata{
+		ID:                  "ocid1.instance.oc1.phx.exampleuniqueID",
+		Shape:               "VM.Standard2.1",
+		Region:              "phx",
+		CanonicalRegionName: "us-phoenix-1",
+		AvailabilityDomain:  "EMIr:PHX-AD-1",
+		FaultDomain:         "FAULT-DOMAIN-3",
+		CompartmentID:       "ocid1.tenancy.oc1..exampleuniqueID",
+		Image:               "ocid1.image.oc1.phx.exampleuniqueID",
+	}, metadata)
+}
+
+func TestParseOCIMetadata_NotOCI(t *testing.T) {
+	_, err := parseOCIMetadataResponse(&http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"compute": {}}`)),
+	})
+	assert.Error(t, err)
+
+	_, err = parseOCIMetadataResponse(&http.Response{
+		StatusCode: 404,
+		Status:     "404 Not Found",
+		Body:       ioutil.NopCloser(bytes.NewBufferString("")),
+	})
+	assert.Error(t, err)
+}
+
+func TestOCIHarvester(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer Oracle", r.Header.Get("Authorization"))
+		_, _ = fmt.Fprint(w, ociMetadataSample)
+	}))
+	defer ts.Close()
+
+	h := NewOCIHarvester(true)
+	h.endpoint = ts.URL
+
+	id, err := h.GetInstanceID()
+	require.NoError(t, err)
+	assert.Equal(t, "ocid1.instance.oc1.phx.exampleuniqueID", id)
+
+	hostType, err := h.GetHostType()
+	require.NoError(t, err)
+	assert.Equal(t, "VM.Standard2.1", hostType)
+
+	region, err := h.GetRegion()
+	require.NoError(t, err)
+	assert.Equal(t, "us-phoenix-1", region)
+
+	zone, err := h.GetZone()
+	require.NoError(t, err)
+	assert.Equal(t, "EMIr:PHX-AD-1", zone)
+}
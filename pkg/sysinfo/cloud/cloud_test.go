@@ -382,6 +382,18 @@ func (s *CloudDetectionSuite) TestDetectWithProvider(chk *C) {
 			expected:    TypeAlibaba,
 			initialized: true,
 		},
+		{
+			provider:    "oci",
+			harvester:   NewOCIHarvester(false),
+			expected:    TypeOCI,
+			initialized: true,
+		},
+		{
+			provider:    "hetzner",
+			harvester:   NewHetznerHarvester(false),
+			expected:    TypeHetzner,
+			initialized: true,
+		},
 		// Invalid provider values should keep the detector waiting in progress.
 	}
 
@@ -0,0 +1,25 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+package cloud
+
+// Actions of the termination notices.
+const (
+	TerminationActionTerminate = "terminate"
+	TerminationActionStop      = "stop"
+	TerminationActionHibernate = "hibernate"
+	TerminationActionPreempt   = "preempt"
+)
+
+// TerminationNotice is the notice sent by the cloud provider before it terminates, stops or preempts the instance.
+type TerminationNotice struct {
+	Action      string // Action taken on the instance.
+	Time        string // When the action is taken, as reported by the provider. Empty when it's unknown.
+	Description string
+}
+
+// TerminationNoticeHarvester is implemented by the harvesters of the clouds notifying the instance termination,
+// which the AWS spot instances, the GCP spot and preemptible VMs and the Azure VMs do.
+type TerminationNoticeHarvester interface {
+	// GetTerminationNotice returns the pending termination notice, nil when there's none.
+	GetTerminationNotice() (*TerminationNotice, error)
+}